@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/plugins"
+	"github.com/grove/generic-proxy/internal/proxy"
+)
+
+const usageText = `Usage: generic-proxy <command> [flags]
+
+Commands:
+  serve        Run the gateway (default); serve -check runs the startup self-test
+  validate     Check proxy.yaml, optionally against the live NocoDB schema
+  gen-config   Generate a proxy.yaml scaffold from the NocoDB base
+  seed         Create demo users and sample records from a seed file
+  user         Manage user accounts (list, add, create-admin, set-role, set-password, delete)
+  state        Export or import the gateway's state (users, tokens, proxy.yaml) as an encrypted archive
+  help         Show this help
+
+Run "generic-proxy <command> -h" for the flags of a command.
+`
+
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		flags := flag.NewFlagSet("serve", flag.ExitOnError)
+		check := flags.Bool("check", false, "check configuration and dependencies, print a report and exit")
+		verbose := flags.Bool("v", false, "with -check, show log output")
+		flags.Parse(args)
+		if *check {
+			os.Exit(runCheck(*verbose))
+		}
+		serve()
+	case "validate":
+		os.Exit(runValidate(args))
+	case "gen-config":
+		os.Exit(runGenConfig(args))
+	case "seed":
+		os.Exit(runSeed(args))
+	case "user":
+		os.Exit(runUser(args))
+	case "state":
+		os.Exit(runState(args))
+	case "help":
+		fmt.Print(usageText)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usageText)
+		os.Exit(2)
+	}
+}
+
+// quietLogs hides the gateway's startup logging unless verbose output was requested
+func quietLogs(verbose bool) {
+	if !verbose {
+		log.SetOutput(io.Discard)
+	}
+}
+
+// loadMetaCache loads the metadata of the configured NocoDB base
+func loadMetaCache(cfg *config.Config) (*proxy.MetaCache, error) {
+	if cfg.NocoDBURL == "" || cfg.NocoDBBaseID == "" {
+		return nil, fmt.Errorf("NOCODB_URL and NOCODB_BASE_ID must be set")
+	}
+	metaCache := proxy.NewMetaCache(deriveMetaBaseURL(cfg.NocoDBURL), cfg.NocoDBBaseID, cfg.NocoDBToken)
+	if err := metaCache.Refresh(); err != nil {
+		return nil, err
+	}
+	return metaCache, nil
+}
+
+// runValidate checks proxy.yaml and reports every problem found
+func runValidate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	path := flags.String("config", defaultProxyConfigPath(), "path to proxy.yaml")
+	resolve := flags.Bool("resolve", false, "also check table and field names against NocoDB")
+	verbose := flags.Bool("v", false, "show log output")
+	flags.Parse(args)
+	quietLogs(*verbose)
+
+	proxyConfig, err := config.LoadProxyConfig(*path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %s: %v\n", *path, err)
+		return 1
+	}
+	fmt.Printf("✓ %s: %d tables, %d bases, %d tenants, %d upstreams\n",
+		*path, len(proxyConfig.Tables), len(proxyConfig.Bases), len(proxyConfig.Tenants), len(proxyConfig.Upstreams))
+	if len(proxyConfig.Plugins) > 0 {
+		chain, err := plugins.Load(proxyConfig.Plugins)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v (compiled in: %s)\n", err, strings.Join(plugins.Registered(), ", "))
+			return 1
+		}
+		fmt.Printf("✓ plugins: %s\n", strings.Join(chain.Names(), ", "))
+	}
+	if modules := wasmModules(proxyConfig); len(modules) > 0 {
+		runtime, err := newWasmRuntime(context.Background(), config.Load(), proxyConfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			return 1
+		}
+		runtime.Close(context.Background())
+		fmt.Printf("✓ WebAssembly filters: %s\n", strings.Join(modules, ", "))
+	}
+	if tables := encryptedTables(proxyConfig); tables > 0 {
+		if _, err := newFieldCipher(config.Load(), proxyConfig); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			return 1
+		}
+		fmt.Printf("✓ field encryption: %d table(s)\n", tables)
+	}
+	if proxyConfig.GeoIP != nil {
+		filter, err := newGeoIPFilter(config.Load(), proxyConfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			return 1
+		}
+		fmt.Printf("✓ geo-ip: %s\n", filter.Description())
+		filter.Close()
+	}
+	if !*resolve {
+		return 0
+	}
+
+	metaCache, err := loadMetaCache(config.Load())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ cannot load NocoDB metadata: %v\n", err)
+		return 1
+	}
+	metaCache.SetAliases(proxyConfig.Aliases)
+
+	problems, skipped := resolveNames(proxyConfig, metaCache)
+	for _, note := range skipped {
+		fmt.Printf("- %s\n", note)
+	}
+	for _, problem := range problems {
+		fmt.Printf("✗ %s\n", problem)
+	}
+
+	if len(problems) > 0 {
+		fmt.Printf("%d problem(s) found\n", len(problems))
+		return 1
+	}
+	fmt.Println("✓ all table and field names resolve")
+	return 0
+}
+
+// resolveNames checks every table, field and link name of proxy.yaml against the
+// NocoDB metadata. Tables routed to another upstream are skipped.
+func resolveNames(proxyConfig *config.ProxyConfig, metaCache *proxy.MetaCache) (problems, skipped []string) {
+	for key, table := range proxyConfig.Tables {
+		if table.Upstream != "" {
+			skipped = append(skipped, fmt.Sprintf("%s: routed to upstream '%s', not checked", key, table.Upstream))
+			continue
+		}
+		tableID, ok := metaCache.ResolveTable(table.Name)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: table '%s' not found in NocoDB", key, table.Name))
+			continue
+		}
+		for field := range table.Fields {
+			if _, ok := metaCache.ResolveField(tableID, field); !ok {
+				problems = append(problems, fmt.Sprintf("%s: field '%s' not found in '%s'", key, field, table.Name))
+			}
+		}
+		for name, link := range table.Links {
+			if _, ok := metaCache.ResolveLinkField(tableID, link.Field); !ok {
+				problems = append(problems, fmt.Sprintf("%s: link '%s' field '%s' is not a link field of '%s'", key, name, link.Field, table.Name))
+			}
+			if _, ok := proxyConfig.Tables[link.TargetTable]; !ok {
+				if _, ok := metaCache.ResolveTable(link.TargetTable); !ok {
+					problems = append(problems, fmt.Sprintf("%s: link '%s' targets unknown table '%s'", key, name, link.TargetTable))
+				}
+			}
+		}
+	}
+	sort.Strings(problems)
+	sort.Strings(skipped)
+	return problems, skipped
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/proxy"
+	"github.com/grove/generic-proxy/internal/vault"
+)
+
+// checkReport prints the outcome of each startup check and counts failures
+type checkReport struct {
+	failed int
+}
+
+func (r *checkReport) pass(name, format string, args ...interface{}) {
+	fmt.Printf("✓ %-12s %s\n", name, fmt.Sprintf(format, args...))
+}
+
+// warn reports a setting that works but should not reach production
+func (r *checkReport) warn(name, format string, args ...interface{}) {
+	fmt.Printf("! %-12s %s\n", name, fmt.Sprintf(format, args...))
+}
+
+func (r *checkReport) fail(name, format string, args ...interface{}) {
+	fmt.Printf("✗ %-12s %s\n", name, fmt.Sprintf(format, args...))
+	r.failed++
+}
+
+func (r *checkReport) skip(name, format string, args ...interface{}) {
+	fmt.Printf("- %-12s %s\n", name, fmt.Sprintf(format, args...))
+}
+
+// runCheck performs the startup checks of serve without starting the server:
+// configuration, NocoDB connectivity, proxy.yaml resolution, the user database
+// and the OAuth providers. It exits non-zero when any check fails, so it can
+// gate a container start or a CI job.
+func runCheck(verbose bool) int {
+	quietLogs(verbose)
+	report := &checkReport{}
+	cfg := config.Load()
+	if cfg.MockNocoDBFixtures != "" {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		if err := startMockNocoDB(ctx, cfg); err != nil {
+			report.fail("mock", "%v", err)
+		} else {
+			report.warn("mock", "NocoDB is the in-memory mock from %s", cfg.MockNocoDBFixtures)
+		}
+	}
+
+	checkSettings(report, cfg)
+
+	var proxyConfig *config.ProxyConfig
+	path := defaultProxyConfigPath()
+	if _, err := os.Stat(path); err != nil {
+		report.skip("proxy.yaml", "%s not found, legacy mode", path)
+	} else if proxyConfig, err = config.LoadProxyConfig(path); err != nil {
+		report.fail("proxy.yaml", "%s: %v", path, err)
+	} else {
+		report.pass("proxy.yaml", "%s: %d tables", path, len(proxyConfig.Tables))
+	}
+
+	if cfg.NocoDBTokenFile != "" {
+		token, err := os.ReadFile(cfg.NocoDBTokenFile)
+		if err != nil {
+			report.fail("nocodb", "cannot read NOCODB_TOKEN_FILE: %v", err)
+		} else {
+			cfg.NocoDBToken = strings.TrimSpace(string(token))
+		}
+	}
+	var metaCache *proxy.MetaCache
+	var err error
+	if cfg.NocoDBURL == "" || cfg.NocoDBBaseID == "" {
+		report.skip("nocodb", "not configured")
+	} else if metaCache, err = loadMetaCache(cfg); err != nil {
+		report.fail("nocodb", "cannot load metadata of %s: %v", cfg.NocoDBBaseID, err)
+	} else {
+		report.pass("nocodb", "base %s: %d tables", cfg.NocoDBBaseID, len(metaCache.TableTitles()))
+	}
+
+	switch {
+	case proxyConfig == nil || metaCache == nil:
+		report.skip("resolve", "needs proxy.yaml and NocoDB metadata")
+	default:
+		metaCache.SetAliases(proxyConfig.Aliases)
+		problems, skipped := resolveNames(proxyConfig, metaCache)
+		for _, problem := range problems {
+			report.fail("resolve", "%s", problem)
+		}
+		if len(problems) == 0 {
+			report.pass("resolve", "all table and field names resolve")
+		}
+		if len(skipped) > 0 {
+			report.skip("resolve", "%d table(s) on other upstreams not checked", len(skipped))
+		}
+	}
+
+	checkUserDatabase(report, cfg, config.ResolveFlags(proxyConfig, cfg))
+	checkOAuth(report, "google", cfg.GoogleClientID, cfg.GoogleClientSecret, cfg.GoogleCallbackURL)
+	checkOAuth(report, "github", cfg.GitHubClientID, cfg.GitHubClientSecret, cfg.GitHubCallbackURL)
+
+	if strings.EqualFold(cfg.MetaStore, "redis") {
+		if client, err := proxy.NewRedisClient(cfg.RedisURL); err != nil {
+			report.fail("redis", "%v", err)
+		} else {
+			client.Close()
+			report.pass("redis", "metadata store reachable")
+		}
+	}
+
+	if report.failed > 0 {
+		fmt.Printf("\n%d check(s) failed\n", report.failed)
+		return 1
+	}
+	fmt.Println("\n✓ all checks passed")
+	return 0
+}
+
+// checkSettings flags environment settings that are missing or left at their defaults
+func checkSettings(report *checkReport, cfg *config.Config) {
+	if cfg.NocoDBURL == "" || cfg.NocoDBBaseID == "" {
+		report.fail("config", "NOCODB_URL and NOCODB_BASE_ID must be set")
+	} else {
+		report.pass("config", "port %s, NocoDB %s", cfg.Port, cfg.NocoDBURL)
+	}
+	if cfg.JWTSecret == "" {
+		report.fail("jwt", "JWT_SECRET is empty")
+	} else if cfg.JWTSecret == "myjwtsecret" {
+		report.warn("jwt", "JWT_SECRET is the built-in default")
+	}
+	if cfg.NocoDBToken == "secret123" && cfg.NocoDBTokenFile == "" {
+		report.warn("nocodb", "NOCODB_TOKEN is the built-in default")
+	}
+}
+
+// checkUserDatabase opens the user database, the token vault, and reports whether
+// anyone can log in as admin
+func checkUserDatabase(report *checkReport, cfg *config.Config, flags config.Flags) {
+	database, err := db.NewDatabase(cfg.DatabasePath)
+	if err != nil {
+		report.fail("users", "cannot open %s: %v", cfg.DatabasePath, err)
+		return
+	}
+	defer database.Close()
+
+	admins, err := database.CountUsersWithRole("admin")
+	switch {
+	case err != nil:
+		report.fail("users", "cannot query %s: %v", cfg.DatabasePath, err)
+	case admins > 0:
+		report.pass("users", "%s: %d admin account(s)", cfg.DatabasePath, admins)
+	case cfg.BootstrapAdminEmail != "" && cfg.BootstrapAdminPassword != "":
+		report.pass("users", "%s: no admin yet, %s is created on start", cfg.DatabasePath, cfg.BootstrapAdminEmail)
+	case flags.DemoUsers:
+		report.warn("users", "%s: no admin account, only the demo admin can log in", cfg.DatabasePath)
+	default:
+		report.fail("users", "%s: no admin account and demo users are disabled", cfg.DatabasePath)
+	}
+	if flags.DemoUsers {
+		report.warn("users", "demo logins are enabled (set flags.demo_users: false)")
+	}
+
+	if cfg.TokenVaultKey != "" {
+		if _, err := vault.New(database, cfg.TokenVaultKey); err != nil {
+			report.fail("vault", "%v", err)
+		} else {
+			report.pass("vault", "token vault opened")
+		}
+	}
+}
+
+// checkOAuth validates the settings of one OAuth provider
+func checkOAuth(report *checkReport, name, clientID, clientSecret, callbackURL string) {
+	name = "oauth/" + name
+	switch {
+	case clientID == "" && clientSecret == "":
+		report.skip(name, "not configured")
+	case clientID == "" || clientSecret == "":
+		report.fail(name, "client ID and secret must both be set")
+	default:
+		callback, err := url.Parse(callbackURL)
+		if err != nil || callback.Scheme == "" || callback.Host == "" {
+			report.fail(name, "invalid callback URL '%s'", callbackURL)
+			return
+		}
+		report.pass(name, "callback %s", callbackURL)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// runGenConfig writes a proxy.yaml scaffold with every table of the NocoDB base
+// (read-only) and the links between them
+func runGenConfig(args []string) int {
+	flags := flag.NewFlagSet("gen-config", flag.ExitOnError)
+	out := flags.String("out", "", "write to this file instead of stdout")
+	force := flags.Bool("force", false, "overwrite an existing output file")
+	verbose := flags.Bool("v", false, "show log output")
+	flags.Parse(args)
+	quietLogs(*verbose)
+
+	cfg := config.Load()
+	metaCache, err := loadMetaCache(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ cannot load NocoDB metadata: %v\n", err)
+		return 1
+	}
+
+	titles := metaCache.TableTitles()
+	keys := make(map[string]string, len(titles)) // table ID -> key
+	used := make(map[string]bool, len(titles))
+	for tableID, title := range titles {
+		key := configKey(title)
+		for i := 2; used[key]; i++ {
+			key = fmt.Sprintf("%s_%d", configKey(title), i)
+		}
+		used[key] = true
+		keys[tableID] = key
+	}
+
+	generated := config.ProxyConfig{
+		NocoDB: config.NocoDBConfig{BaseID: cfg.NocoDBBaseID},
+		Tables: make(map[string]config.TableConfig, len(titles)),
+	}
+	for tableID, title := range titles {
+		table := config.TableConfig{Name: title, Operations: []string{"read"}}
+		for _, relation := range metaCache.Relations(tableID) {
+			target, ok := keys[relation.TargetTableID]
+			if !ok {
+				continue
+			}
+			if table.Links == nil {
+				table.Links = make(map[string]config.Link)
+			}
+			table.Links[configKey(relation.Field)] = config.Link{Field: relation.Field, TargetTable: target}
+		}
+		generated.Tables[keys[tableID]] = table
+	}
+
+	data, err := yaml.Marshal(generated)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ failed to encode configuration: %v\n", err)
+		return 1
+	}
+	data = append([]byte("# Generated by generic-proxy gen-config. Tables are read-only;\n# widen operations as needed.\n"), data...)
+
+	if *out == "" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if _, err := os.Stat(*out); err == nil && !*force {
+		fmt.Fprintf(os.Stderr, "✗ %s already exists (use -force to overwrite)\n", *out)
+		return 1
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+		return 1
+	}
+	fmt.Printf("✓ wrote %d tables to %s\n", len(generated.Tables), *out)
+	return 0
+}
+
+// configKey turns a NocoDB title into a snake_case proxy.yaml key
+func configKey(title string) string {
+	var b strings.Builder
+	var previous rune
+	for _, r := range strings.TrimSpace(title) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			// Split camelCase words ("ProductsForQuotes"), but not acronyms ("FINAL")
+			if unicode.IsUpper(r) && (unicode.IsLower(previous) || unicode.IsDigit(previous)) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		case b.Len() > 0 && previous != '_':
+			b.WriteByte('_')
+			r = '_'
+		default:
+			continue
+		}
+		previous = r
+	}
+	key := strings.TrimSuffix(b.String(), "_")
+	if key == "" {
+		key = "table"
+	}
+	return key
+}
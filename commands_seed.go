@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/proxy"
+	"gopkg.in/yaml.v3"
+)
+
+// seedFile is the format of a seed file
+type seedFile struct {
+	Users   []seedUser    `yaml:"users"`
+	Records []seedRecords `yaml:"records"`
+}
+
+type seedUser struct {
+	Email    string   `yaml:"email"`
+	Password string   `yaml:"password"`
+	Name     string   `yaml:"name"`
+	Role     string   `yaml:"role"`    // user (default) or admin
+	Tenants  []string `yaml:"tenants"` // tenant memberships
+}
+
+// seedRecords are rows created in one table, in order
+type seedRecords struct {
+	Table string                   `yaml:"table"` // proxy.yaml table key (or NocoDB title in legacy mode)
+	Rows  []map[string]interface{} `yaml:"rows"`
+}
+
+// runSeed creates the users and sample records of a seed file. Records are sent
+// through the proxy handler, so proxy.yaml operations and name resolution apply
+// exactly as for API clients. Existing accounts are left unchanged; records are
+// created on every run.
+func runSeed(args []string) int {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	file := flags.String("file", "", "seed file (YAML)")
+	path := flags.String("config", defaultProxyConfigPath(), "path to proxy.yaml")
+	verbose := flags.Bool("v", false, "show log output")
+	flags.Parse(args)
+	quietLogs(*verbose)
+
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, "✗ -file is required")
+		return 2
+	}
+	seed, err := loadSeedFile(*file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+		return 1
+	}
+
+	cfg := config.Load()
+	failed := 0
+	if len(seed.Users) > 0 {
+		failed += seedUsers(cfg, seed.Users)
+	}
+	if len(seed.Records) > 0 {
+		failed += seedRecordsThroughProxy(cfg, *path, seed.Records)
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d item(s) failed\n", failed)
+		return 1
+	}
+	fmt.Println("✓ seed complete")
+	return 0
+}
+
+// loadSeedFile reads a seed file, rejecting unknown keys so typos are not silently ignored
+func loadSeedFile(path string) (*seedFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read seed file: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var seed seedFile
+	if err := decoder.Decode(&seed); err != nil {
+		return nil, fmt.Errorf("invalid seed file %s: %w", path, err)
+	}
+	return &seed, nil
+}
+
+// seedUsers creates missing accounts and returns the number of failures
+func seedUsers(cfg *config.Config, users []seedUser) int {
+	database, err := db.NewDatabase(cfg.DatabasePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ cannot open user database: %v\n", err)
+		return len(users)
+	}
+	defer database.Close()
+
+	failed := 0
+	for _, seeded := range users {
+		if existing, err := database.GetUserByEmail(seeded.Email); err == nil && existing != nil {
+			fmt.Printf("- user %s exists, unchanged\n", seeded.Email)
+			continue
+		}
+		role := seeded.Role
+		if role == "" {
+			role = "user"
+		}
+		if role != "user" && role != "admin" {
+			fmt.Printf("✗ user %s: invalid role '%s'\n", seeded.Email, role)
+			failed++
+			continue
+		}
+		if err := checkPassword(seeded.Password); err != nil {
+			fmt.Printf("✗ user %s: %v\n", seeded.Email, err)
+			failed++
+			continue
+		}
+
+		user, err := database.CreateLocalUser(seeded.Email, seeded.Password, seeded.Name)
+		if err == nil && role != "user" {
+			err = database.SetUserRole(user.ID, role)
+		}
+		for _, tenant := range seeded.Tenants {
+			if err == nil {
+				err = database.AddUserTenant(fmt.Sprintf("%d", user.ID), tenant)
+			}
+		}
+		if err != nil {
+			fmt.Printf("✗ user %s: %v\n", seeded.Email, err)
+			failed++
+			continue
+		}
+		fmt.Printf("✓ user %s (role %s)\n", seeded.Email, role)
+	}
+	return failed
+}
+
+// seedRecordsThroughProxy posts every row to an in-process proxy handler and
+// returns the number of failures
+func seedRecordsThroughProxy(cfg *config.Config, proxyConfigPath string, batches []seedRecords) int {
+	rows := 0
+	for _, batch := range batches {
+		rows += len(batch.Rows)
+	}
+
+	metaCache, err := loadMetaCache(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ cannot load NocoDB metadata: %v\n", err)
+		return rows
+	}
+	dialect := proxy.ResolveDialect(context.Background(), cfg.NocoDBAPIVersion, cfg.NocoDBURL, cfg.NocoDBBaseID, cfg.NocoDBToken)
+	handler := proxy.NewProxyHandler(cfg.NocoDBURL, cfg.NocoDBToken, metaCache)
+	handler.SetDialect(dialect)
+
+	var proxyConfig *config.ProxyConfig
+	if _, err := os.Stat(proxyConfigPath); err == nil {
+		if proxyConfig, err = config.LoadProxyConfig(proxyConfigPath); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s: %v\n", proxyConfigPath, err)
+			return rows
+		}
+		metaCache.SetAliases(proxyConfig.Aliases)
+		resolvedConfig, err := config.NewResolver(metaCache).Resolve(proxyConfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ failed to resolve %s: %v\n", proxyConfigPath, err)
+			return rows
+		}
+		handler.SetResolvedConfig(resolvedConfig)
+	}
+
+	failed := 0
+	for _, batch := range batches {
+		if proxyConfig != nil && proxyConfig.Tables[batch.Table].Upstream != "" {
+			fmt.Printf("✗ %s: routed to upstream '%s', seeding is not supported\n", batch.Table, proxyConfig.Tables[batch.Table].Upstream)
+			failed += len(batch.Rows)
+			continue
+		}
+		created := 0
+		for i, fields := range batch.Rows {
+			var record interface{} = fields
+			if dialect.IsV3() {
+				record = map[string]interface{}{"fields": fields}
+			}
+			body, err := json.Marshal(record)
+			if err != nil {
+				fmt.Printf("✗ %s row %d: %v\n", batch.Table, i+1, err)
+				failed++
+				continue
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/proxy/"+batch.Table+"/records", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code >= 300 {
+				fmt.Printf("✗ %s row %d: status %d: %s\n", batch.Table, i+1, rec.Code, strings.TrimSpace(rec.Body.String()))
+				failed++
+				continue
+			}
+			created++
+		}
+		mark := "✓"
+		if created < len(batch.Rows) {
+			mark = "!"
+		}
+		fmt.Printf("%s %s: %d of %d record(s) created\n", mark, batch.Table, created, len(batch.Rows))
+	}
+	return failed
+}
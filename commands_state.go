@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/grove/generic-proxy/internal/backup"
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/db"
+)
+
+const stateUsageText = `Usage: generic-proxy state <action> [flags]
+
+Actions:
+  export -out FILE            Write the user database and proxy.yaml to an encrypted archive
+  import -in FILE [-force]    Restore an archive on this host (stop the gateway first)
+
+Archives are encrypted with STATE_ARCHIVE_KEY, which must be the same on both
+hosts. Secrets such as JWT_SECRET and TOKEN_VAULT_KEY are not included: copy
+them to the new host as well. import refuses to replace an existing database
+or proxy.yaml unless -force is given; -no-config leaves proxy.yaml alone.
+
+  STATE_ARCHIVE_KEY=... generic-proxy state export -out gateway.gpstate
+  STATE_ARCHIVE_KEY=... generic-proxy state import -in gateway.gpstate
+`
+
+// runState exports and imports the gateway's state (DATABASE_PATH and proxy.yaml)
+func runState(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		fmt.Print(stateUsageText)
+		return 2
+	}
+	action, args := args[0], args[1:]
+
+	flags := flag.NewFlagSet("state "+action, flag.ExitOnError)
+	out := flags.String("out", "", "archive to write (export)")
+	in := flags.String("in", "", "archive to read (import)")
+	configPath := flags.String("config", defaultProxyConfigPath(), "path to proxy.yaml")
+	noConfig := flags.Bool("no-config", false, "do not restore proxy.yaml (import)")
+	force := flags.Bool("force", false, "replace an existing database and proxy.yaml (import)")
+	verbose := flags.Bool("v", false, "show log output")
+	flags.Parse(args)
+	quietLogs(*verbose)
+
+	cfg := config.Load()
+	if cfg.StateArchiveKey == "" {
+		fmt.Fprintln(os.Stderr, "✗ STATE_ARCHIVE_KEY must be set")
+		return 2
+	}
+
+	switch action {
+	case "export":
+		if *out == "" {
+			fmt.Fprintln(os.Stderr, "✗ -out is required")
+			return 2
+		}
+		database, err := db.NewDatabase(cfg.DatabasePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ cannot open user database: %v\n", err)
+			return 1
+		}
+		defer database.Close()
+
+		file, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			return 1
+		}
+		manifest, err := backup.Export(context.Background(), file, database, *configPath, cfg.StateArchiveKey)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(*out)
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			return 1
+		}
+		printManifest("exported to "+*out, manifest)
+		return 0
+
+	case "import":
+		if *in == "" {
+			fmt.Fprintln(os.Stderr, "✗ -in is required")
+			return 2
+		}
+		file, err := os.Open(*in)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			return 1
+		}
+		defer file.Close()
+
+		restoreConfig := *configPath
+		if *noConfig {
+			restoreConfig = ""
+		}
+		manifest, err := backup.Restore(file, cfg.StateArchiveKey, cfg.DatabasePath, restoreConfig, *force)
+		if errors.Is(err, backup.ErrExists) {
+			fmt.Fprintf(os.Stderr, "✗ %v (use -force to replace it)\n", err)
+			return 1
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			return 1
+		}
+
+		// Opening the restored database also migrates it to this version's schema
+		database, err := db.NewDatabase(cfg.DatabasePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ restored database cannot be opened: %v\n", err)
+			return 1
+		}
+		defer database.Close()
+		users, err := database.GetAllUsers()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ restored database cannot be read: %v\n", err)
+			return 1
+		}
+		printManifest(fmt.Sprintf("restored %s (%d accounts)", cfg.DatabasePath, len(users)), manifest)
+		return 0
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown action %q\n\n%s", action, stateUsageText)
+		return 2
+	}
+}
+
+// printManifest reports what an archive holds
+func printManifest(summary string, manifest backup.Manifest) {
+	fmt.Printf("✓ %s\n", summary)
+	fmt.Printf("  archive created %s\n", manifest.CreatedAt.Format("2006-01-02 15:04:05 UTC"))
+	for _, name := range []string{"users.db", "proxy.yaml"} {
+		if size, ok := manifest.Files[name]; ok {
+			fmt.Printf("  %-12s %d bytes\n", name, size)
+		}
+	}
+}
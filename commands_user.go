@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/grove/generic-proxy/internal/auth"
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/events"
+	"github.com/grove/generic-proxy/internal/webhooks"
+)
+
+const userUsageText = `Usage: generic-proxy user <action> [flags]
+
+Actions:
+  list                                  List all accounts
+  add -email E [-name N] [-role R]      Create a password account (password read from stdin)
+  create-admin -email E [-name N]       Create an admin account (password read from stdin)
+  set-role -email E -role R             Change an account's role (user or admin)
+  set-password -email E                 Set an account's password (read from stdin)
+  delete -email E                       Delete an account
+
+Passwords are read from the first line of stdin. Pass -password-stdin to skip
+the prompt when piping one in:
+
+  echo "$ADMIN_PASSWORD" | generic-proxy user create-admin -email ops@example.com -password-stdin
+`
+
+// runUser manages accounts in the user database (DATABASE_PATH)
+func runUser(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		fmt.Print(userUsageText)
+		return 2
+	}
+	action, args := args[0], args[1:]
+
+	flags := flag.NewFlagSet("user "+action, flag.ExitOnError)
+	email := flags.String("email", "", "account email")
+	name := flags.String("name", "", "display name")
+	role := flags.String("role", "user", "role: user or admin")
+	passwordStdin := flags.Bool("password-stdin", false, "read the password from stdin without prompting")
+	verbose := flags.Bool("v", false, "show log output")
+	flags.Parse(args)
+	quietLogs(*verbose)
+
+	if *role != "user" && *role != "admin" {
+		fmt.Fprintf(os.Stderr, "✗ invalid role '%s' (expected user or admin)\n", *role)
+		return 2
+	}
+	if action != "list" && *email == "" {
+		fmt.Fprintln(os.Stderr, "✗ -email is required")
+		return 2
+	}
+
+	cfg := config.Load()
+	breaches := newBreachChecker(cfg)
+	database, err := db.NewDatabase(cfg.DatabasePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ cannot open user database: %v\n", err)
+		return 1
+	}
+	defer database.Close()
+
+	switch action {
+	case "list":
+		return listUsers(database)
+
+	case "add":
+		password, err := readPassword(!*passwordStdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			return 1
+		}
+		if !passwordAllowed(breaches, password) {
+			return 1
+		}
+		user, err := database.CreateLocalUser(*email, password, *name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ cannot create %s (does it already exist?)\n", *email)
+			return 1
+		}
+		if *role != "user" {
+			if err := database.SetUserRole(user.ID, *role); err != nil {
+				fmt.Fprintf(os.Stderr, "✗ created %s but could not set role: %v\n", *email, err)
+				return 1
+			}
+		}
+		fmt.Printf("✓ created %s (id %d, role %s)\n", *email, user.ID, *role)
+		if *role != "user" {
+			deliverAuthEvent(cfg, roleChangedEvent(user, "", *role))
+		}
+		return 0
+
+	case "create-admin":
+		password, err := readPassword(!*passwordStdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			return 1
+		}
+		if !passwordAllowed(breaches, password) {
+			return 1
+		}
+		user, err := createAdmin(database, *email, password, *name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			return 1
+		}
+		fmt.Printf("✓ created admin %s (id %d)\n", *email, user.ID)
+		deliverAuthEvent(cfg, roleChangedEvent(user, "", "admin"))
+		return 0
+	}
+
+	user, err := database.GetUserByEmail(*email)
+	if err != nil || user == nil {
+		fmt.Fprintf(os.Stderr, "✗ no account with email %s\n", *email)
+		return 1
+	}
+
+	switch action {
+	case "set-role":
+		roleSet := false
+		flags.Visit(func(f *flag.Flag) { roleSet = roleSet || f.Name == "role" })
+		if !roleSet {
+			fmt.Fprintln(os.Stderr, "✗ -role is required")
+			return 2
+		}
+		if err := database.SetUserRole(user.ID, *role); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			return 1
+		}
+		fmt.Printf("✓ %s is now %s\n", *email, *role)
+		if user.Role != *role {
+			deliverAuthEvent(cfg, roleChangedEvent(user, user.Role, *role))
+		}
+
+	case "set-password":
+		password, err := readPassword(!*passwordStdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			return 1
+		}
+		if !passwordAllowed(breaches, password) {
+			return 1
+		}
+		if err := database.SetUserPassword(user.ID, password); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			return 1
+		}
+		fmt.Printf("✓ password of %s updated\n", *email)
+
+	case "delete":
+		// Same local cascade as POST /admin/users/delete; owned NocoDB rows need the endpoint
+		userID := fmt.Sprintf("%d", user.ID)
+		if err := database.RevokeUserTokens(userID); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			return 1
+		}
+		if err := database.RemoveAllUserTenants(userID); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			return 1
+		}
+		if _, err := database.ScrubUserOutbox(userID); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			return 1
+		}
+		if err := database.DeleteUser(user.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			return 1
+		}
+		fmt.Printf("✓ deleted %s\n", *email)
+		// Like the endpoint's event, without the deleted account's email
+		deliverAuthEvent(cfg, events.Event{Type: events.TypeAuthDeleted, Source: events.SourceAuth, UserID: userID})
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown action %q\n\n%s", action, userUsageText)
+		return 2
+	}
+	return 0
+}
+
+// passwordAllowed reports whether a new password passes the breached password
+// check (PASSWORD_BREACH_CHECK); a failed lookup only warns
+func passwordAllowed(breaches *auth.BreachChecker, password string) bool {
+	rejected, err := breaches.CheckPassword(context.Background(), password)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "! breached password check failed, continuing: %v\n", err)
+	}
+	if rejected != nil {
+		fmt.Fprintf(os.Stderr, "✗ %v\n", rejected)
+		return false
+	}
+	return true
+}
+
+// listUsers prints every account as a table
+func listUsers(database *db.Database) int {
+	users, err := database.GetAllUsers()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+		return 1
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tEMAIL\tROLE\tPROVIDER\tCREATED")
+	for _, user := range users {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", user.ID, user.Email, user.Role, user.Provider, user.CreatedAt.Format("2006-01-02"))
+	}
+	w.Flush()
+	return 0
+}
+
+// roleChangedEvent describes a role change made from the command line;
+// previous is empty for new accounts
+func roleChangedEvent(user *db.User, previous, role string) events.Event {
+	return events.Event{
+		Type:   events.TypeAuthRoleChanged,
+		Source: events.SourceAuth,
+		UserID: fmt.Sprintf("%d", user.ID),
+		Metadata: map[string]string{
+			"email":         user.Email,
+			"role":          role,
+			"previous_role": previous,
+			"changed_by":    "cli",
+		},
+	}
+}
+
+// deliverAuthEvent sends an auth event to the auth_webhooks targets of
+// proxy.yaml. The command runs outside the gateway's event bus, so it delivers
+// the event itself and waits for the delivery (or its dead letter).
+func deliverAuthEvent(cfg *config.Config, event events.Event) {
+	path := defaultProxyConfigPath()
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+	proxyConfig, err := config.LoadProxyConfig(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "! %s not reported to auth webhooks: %v\n", event.Type, err)
+		return
+	}
+	if len(proxyConfig.AuthWebhooks) == 0 {
+		return
+	}
+	dispatcher := webhooks.NewDispatcher(nil, nil, cfg.WebhookDeadLetterPath)
+	dispatcher.SetAuthTargets(proxyConfig.AuthWebhooks)
+	dispatcher.Deliver(event)
+}
+
+// createAdmin creates a password account with the admin role
+func createAdmin(database *db.Database, email, password, name string) (*db.User, error) {
+	if err := checkPassword(password); err != nil {
+		return nil, err
+	}
+	user, err := database.CreateLocalUser(email, password, name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create %s (does it already exist?)", email)
+	}
+	if err := database.SetUserRole(user.ID, "admin"); err != nil {
+		return nil, fmt.Errorf("created %s but could not make it admin: %v", email, err)
+	}
+	user.Role = "admin"
+	return user, nil
+}
+
+// readPassword reads a password from the first line of stdin, so it never
+// appears in the shell history or process list
+func readPassword(prompt bool) (string, error) {
+	if prompt {
+		fmt.Fprint(os.Stderr, "Password: ")
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("no password given on stdin")
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if err := checkPassword(password); err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
+// checkPassword applies the same minimum length as /signup
+func checkPassword(password string) error {
+	if len(password) < 6 {
+		return fmt.Errorf("password must be at least 6 characters")
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+//go:build wasip1
+
+// Command wasm-filter is an example WebAssembly filter for wasm_filters in
+// proxy.yaml. It rejects requests from users without a role listed in the
+// "roles" config setting and tags every response with X-Filtered-By.
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o filter.wasm ./examples/wasm-filter
+package main
+
+import (
+	"encoding/json"
+	"unsafe"
+)
+
+type message struct {
+	Phase  string `json:"phase"`
+	Table  string `json:"table"`
+	Method string `json:"method"`
+	User   struct {
+		Role string `json:"role"`
+	} `json:"user"`
+	Config struct {
+		Roles []string `json:"roles"`
+	} `json:"config"`
+}
+
+type verdict struct {
+	Action  string             `json:"action,omitempty"`
+	Status  int                `json:"status,omitempty"`
+	Message string             `json:"message,omitempty"`
+	Headers map[string]*string `json:"headers,omitempty"`
+}
+
+// buffers keeps memory handed to the host alive until the instance is discarded
+var buffers [][]byte
+
+//go:wasmexport proxy_on_memory_allocate
+func allocate(size uint32) uint32 {
+	buffer := make([]byte, size)
+	buffers = append(buffers, buffer)
+	return uint32(uintptr(unsafe.Pointer(unsafe.SliceData(buffer))))
+}
+
+//go:wasmexport filter_on_request
+func onRequest(ptr, size uint32) uint64 {
+	msg := read(ptr, size)
+	if len(msg.Config.Roles) == 0 {
+		return 0
+	}
+	for _, role := range msg.Config.Roles {
+		if role == msg.User.Role {
+			return 0
+		}
+	}
+	return write(verdict{Action: "reject", Status: 403, Message: "role '" + msg.User.Role + "' may not access " + msg.Table})
+}
+
+//go:wasmexport filter_on_response
+func onResponse(ptr, size uint32) uint64 {
+	name := "wasm-filter"
+	return write(verdict{Headers: map[string]*string{"X-Filtered-By": &name}})
+}
+
+func read(ptr, size uint32) message {
+	var msg message
+	json.Unmarshal(unsafe.Slice((*byte)(unsafe.Pointer(uintptr(ptr))), size), &msg)
+	return msg
+}
+
+func write(v verdict) uint64 {
+	data, _ := json.Marshal(v)
+	buffers = append(buffers, data)
+	ptr := uint64(uintptr(unsafe.Pointer(unsafe.SliceData(data))))
+	return ptr<<32 | uint64(len(data))
+}
+
+func main() {}
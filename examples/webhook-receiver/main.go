@@ -0,0 +1,45 @@
+// Command webhook-receiver is an example endpoint for the gateway's outbound
+// webhooks. It accepts a delivery only when its X-Gateway-Signature-V1 was made
+// with the target's secret, is at most five minutes old and was not seen before.
+//
+//	WEBHOOK_SECRET=... go run ./examples/webhook-receiver
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/grove/generic-proxy/internal/events"
+	"github.com/grove/generic-proxy/internal/signing"
+)
+
+func main() {
+	// List the old secret as well while rotating it
+	verifier := signing.NewVerifier([]string{os.Getenv("WEBHOOK_SECRET")}, signing.DefaultTolerance)
+
+	http.HandleFunc("/hooks/quotes", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 5<<20))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+		if err := verifier.VerifyBody(r.Header, body); err != nil {
+			log.Printf("rejected delivery %s: %v", r.Header.Get("X-Gateway-Delivery"), err)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		var event events.Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			http.Error(w, "invalid event", http.StatusBadRequest)
+			return
+		}
+		log.Printf("%s on %s (%d record(s))", event.Type, event.TableName, len(event.Records))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	log.Fatal(http.ListenAndServe(":9000", nil))
+}
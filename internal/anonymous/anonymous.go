@@ -0,0 +1,116 @@
+// Package anonymous serves reads of the tables listed in the anonymous block
+// of proxy.yaml to requests without a token, under the anonymous role and a
+// per-client rate limit, e.g. for a published catalog.
+package anonymous
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/geoip"
+	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/grove/generic-proxy/internal/proxy"
+	"github.com/grove/generic-proxy/internal/ratelimit"
+)
+
+const (
+	defaultRateLimit = 30 // per minute
+	defaultBurst     = 10
+)
+
+// Access decides which requests without a token are served and rate limits
+// them by client address
+type Access struct {
+	tables  map[string]config.AnonymousTable
+	geo     *geoip.Filter
+	limiter *ratelimit.Limiter
+}
+
+// New creates the anonymous tier, nil when proxy.yaml has no anonymous block.
+// The geo-IP filter, when set, resolves client addresses behind trusted proxies.
+func New(cfg *config.AnonymousConfig, geo *geoip.Filter) *Access {
+	if cfg == nil {
+		return nil
+	}
+	perMinute, burst := cfg.RateLimit, cfg.Burst
+	if perMinute == 0 {
+		perMinute = defaultRateLimit
+	}
+	if burst == 0 {
+		burst = defaultBurst
+	}
+	return &Access{tables: cfg.Tables, geo: geo, limiter: ratelimit.New(perMinute, burst)}
+}
+
+// Tables returns the names of the tables open to anonymous reads
+func (a *Access) Tables() []string {
+	names := make([]string, 0, len(a.tables))
+	for name := range a.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Handler sends requests without an Authorization header that read records of
+// an anonymous table to data, as the anonymous user. Every other request goes
+// to authenticated, which rejects a missing token as before. A nil Access
+// sends everything to authenticated.
+func (a *Access) Handler(authenticated, data http.Handler) http.Handler {
+	if a == nil {
+		return authenticated
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			authenticated.ServeHTTP(w, r)
+			return
+		}
+		table, ok := a.match(r)
+		if !ok {
+			authenticated.ServeHTTP(w, r)
+			return
+		}
+
+		client := ratelimit.ClientAddr(r, a.geo)
+		if wait := a.limiter.Take(client); wait > 0 {
+			log.Printf("[ANONYMOUS] Rate limit exceeded by %s on %s", client, r.URL.Path)
+			ratelimit.Reject(w, wait)
+			return
+		}
+
+		query := r.URL.Query()
+		if table.View != "" {
+			query.Set("viewId", table.View)
+		}
+		if len(table.Fields) > 0 {
+			query.Set("fields", strings.Join(table.Fields, ","))
+		}
+		r.URL.RawQuery = query.Encode()
+
+		ctx := context.WithValue(r.Context(), middleware.UserIDKey, config.AnonymousRole)
+		ctx = context.WithValue(ctx, middleware.RoleKey, config.AnonymousRole)
+		if len(table.Fields) > 0 {
+			// Filters and sorts, date filter shorthands included, may only name published fields
+			ctx = proxy.WithPublishedFields(ctx, table.Fields)
+		}
+		data.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// match accepts GET /proxy/{table}/records, /proxy/{table}/records/{id} and
+// /proxy/{table}/records/count of an anonymous table. Links, events and changes stay behind authentication.
+func (a *Access) match(r *http.Request) (config.AnonymousTable, bool) {
+	if r.Method != http.MethodGet {
+		return config.AnonymousTable{}, false
+	}
+	route := proxy.ParseRoute(strings.TrimPrefix(r.URL.Path, "/proxy/"))
+	if route.Kind != proxy.RouteRecords && route.Kind != proxy.RouteCount {
+		return config.AnonymousTable{}, false
+	}
+	table, ok := a.tables[route.Table]
+	return table, ok
+}
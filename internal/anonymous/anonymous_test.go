@@ -0,0 +1,84 @@
+package anonymous
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/mocknocodb"
+	"github.com/grove/generic-proxy/internal/proxy"
+)
+
+// anonymousGateway serves a products table whose anonymous reads publish
+// Title and Released, but not LaunchCost or Embargo
+func anonymousGateway(t *testing.T) http.Handler {
+	t.Helper()
+	mock, err := mocknocodb.New(&mocknocodb.Fixtures{
+		BaseID: "pbase",
+		Tables: []mocknocodb.TableFixture{{
+			Title: "Products",
+			Fields: []mocknocodb.FieldFixture{
+				{Title: "Title"},
+				{Title: "Released", Type: "Date"},
+				{Title: "LaunchCost", Type: "Number"},
+				{Title: "Embargo", Type: "Date"},
+			},
+			Records: []map[string]interface{}{{"Title": "Lamp", "Released": "2024-01-10", "LaunchCost": 1200, "Embargo": "2024-03-01"}},
+		}},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nocodb := httptest.NewServer(mock)
+	t.Cleanup(nocodb.Close)
+
+	meta := proxy.NewMetaCache(nocodb.URL+"/api/v2/", "pbase", "")
+	if err := meta.LoadInitial(); err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := config.NewResolver(meta).Resolve(&config.ProxyConfig{
+		NocoDB: config.NocoDBConfig{BaseID: "pbase"},
+		Tables: map[string]config.TableConfig{"products": {Name: "Products", Operations: []string{"read"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := proxy.NewProxyHandler(nocodb.URL+"/api/v3/data/pbase/", "", meta)
+	data.SetResolvedConfig(resolved)
+
+	access := New(&config.AnonymousConfig{
+		Tables: map[string]config.AnonymousTable{"products": {Fields: []string{"Title", "Released"}}},
+	}, nil)
+	authenticated := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	return access.Handler(authenticated, data)
+}
+
+func TestAnonymousFiltersOnlyPublishedFields(t *testing.T) {
+	gateway := anonymousGateway(t)
+
+	for _, tc := range []struct {
+		query  string
+		status int
+	}{
+		{"where=(Title,eq,Lamp)", http.StatusOK},
+		{"sort=-Title", http.StatusOK},
+		{"Released=last_7_days", http.StatusOK},
+		{"where=(LaunchCost,gt,1000)", http.StatusBadRequest},
+		{"where=(Title,eq,Lamp)~or(LaunchCost,gt,1000)", http.StatusBadRequest},
+		{"sort=-LaunchCost", http.StatusBadRequest},
+		// Date filter shorthands become where clauses inside the pipeline
+		{"Embargo=before:2024-06-01", http.StatusBadRequest},
+		{"Embargo=between:2024-01-01,2024-12-31", http.StatusBadRequest},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/proxy/products/records?"+tc.query, nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		w := httptest.NewRecorder()
+		gateway.ServeHTTP(w, r)
+		if w.Code != tc.status {
+			t.Errorf("%s: got %d, want %d: %s", tc.query, w.Code, tc.status, w.Body.String())
+		}
+	}
+}
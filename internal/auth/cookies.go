@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/utils"
+)
+
+// rememberCookie carries a ?remember_me=true across the OAuth round trip
+const rememberCookie = "post_login_remember"
+
+// CookieOptions are the security attributes of the cookies the gateway sets:
+// the OAuth session and the cookies that carry login choices through it
+type CookieOptions struct {
+	Secure   bool
+	SameSite http.SameSite
+	Domain   string
+}
+
+// ParseSameSite reads a SameSite setting: lax, strict or none
+func ParseSameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+	return 0, fmt.Errorf("invalid SameSite '%s', expected lax, strict or none", value)
+}
+
+// Validate rejects combinations browsers refuse: SameSite=None needs Secure
+func (o CookieOptions) Validate() error {
+	if o.SameSite == http.SameSiteNoneMode && !o.Secure {
+		return fmt.Errorf("SameSite=None cookies must be Secure")
+	}
+	return nil
+}
+
+// SetSessionOptions sets the attributes of the cookies set during OAuth logins
+// and the token lifetime of remember-me logins
+func (h *Handler) SetSessionOptions(cookies CookieOptions, rememberTTL time.Duration) {
+	h.cookies = cookies
+	h.rememberTTL = rememberTTL
+}
+
+// setCookie sets a short-lived cookie for the OAuth round trip
+func (h *Handler) setCookie(w http.ResponseWriter, name, value string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/auth",
+		Domain:   h.cookies.Domain,
+		MaxAge:   int((10 * time.Minute).Seconds()),
+		HttpOnly: true,
+		Secure:   h.cookies.Secure,
+		SameSite: h.cookies.SameSite,
+	})
+}
+
+// takeCookie returns a cookie set by setCookie (empty when none) and clears it
+func (h *Handler) takeCookie(w http.ResponseWriter, r *http.Request, name string) string {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Path:     "/auth",
+		Domain:   h.cookies.Domain,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   h.cookies.Secure,
+		SameSite: h.cookies.SameSite,
+	})
+	return cookie.Value
+}
+
+// tokenTTL is the lifetime of the token of a login, longer for remember-me
+func (h *Handler) tokenTTL(remember bool) time.Duration {
+	if remember && h.rememberTTL > 0 {
+		return h.rememberTTL
+	}
+	return utils.TokenTTL
+}
@@ -0,0 +1,334 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/events"
+	"github.com/grove/generic-proxy/internal/geoip"
+	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/grove/generic-proxy/internal/utils"
+)
+
+// DeviceCodeGrant is the grant_type of device token requests
+const DeviceCodeGrant = "urn:ietf:params:oauth:grant-type:device_code"
+
+const (
+	deviceCodeTTL      = 10 * time.Minute
+	devicePollInterval = 5 * time.Second
+	// No vowels or look-alike characters, so codes spell no words and survive being read aloud
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength   = 8
+)
+
+// DeviceFlow implements the OAuth 2.0 device authorization grant (RFC 8628)
+// for CLI tools and headless devices. The device asks for a code pair, the
+// user approves the user code with their own token, typically on a frontend
+// page, and the device polls until it receives a gateway JWT.
+type DeviceFlow struct {
+	database        *db.Database
+	jwtSecret       string
+	verificationURI string
+	events          *events.Bus
+}
+
+// NewDeviceFlow creates the device flow. verificationURI is the page where
+// users enter their code.
+func NewDeviceFlow(database *db.Database, jwtSecret, verificationURI string) *DeviceFlow {
+	return &DeviceFlow{database: database, jwtSecret: jwtSecret, verificationURI: verificationURI}
+}
+
+// SetEventBus sets the bus that device logins are published to
+func (f *DeviceFlow) SetEventBus(bus *events.Bus) {
+	f.events = bus
+}
+
+// ServeCode handles POST /auth/device/code: it starts a request and returns
+// the device code, the user code and where to enter it
+func (f *DeviceFlow) ServeCode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	params := requestParams(r)
+
+	deviceCode, err := randomHex(32)
+	if err != nil {
+		oauthError(w, http.StatusInternalServerError, "server_error", "failed to create device code")
+		return
+	}
+	userCode, err := newUserCode()
+	if err != nil {
+		oauthError(w, http.StatusInternalServerError, "server_error", "failed to create user code")
+		return
+	}
+	err = f.database.CreateDeviceCode(db.DeviceCode{
+		DeviceCodeHash: hashDeviceCode(deviceCode),
+		UserCode:       userCode,
+		ClientID:       params.Get("client_id"),
+		ExpiresAt:      time.Now().Add(deviceCodeTTL),
+	})
+	if err != nil {
+		oauthError(w, http.StatusInternalServerError, "server_error", "failed to store device code")
+		return
+	}
+	log.Printf("[DEVICE] Authorization requested by client '%s' from %s", params.Get("client_id"), r.RemoteAddr)
+
+	display := formatUserCode(userCode)
+	complete := f.verificationURI
+	if u, err := url.Parse(f.verificationURI); err == nil {
+		query := u.Query()
+		query.Set("user_code", display)
+		u.RawQuery = query.Encode()
+		complete = u.String()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"device_code":               deviceCode,
+		"user_code":                 display,
+		"verification_uri":          f.verificationURI,
+		"verification_uri_complete": complete,
+		"expires_in":                int(deviceCodeTTL.Seconds()),
+		"interval":                  int(devicePollInterval.Seconds()),
+	})
+}
+
+// ServeApprove handles /auth/device/approve for a signed-in user (wrap it in
+// middleware.AuthMiddleware). GET ?user_code= shows what is being approved;
+// POST {"user_code", "approve"} approves or denies it. The device receives a
+// token with the approver's user ID, role and tenant.
+func (f *DeviceFlow) ServeApprove(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+	tenant, _ := r.Context().Value(middleware.TenantKey).(string)
+
+	switch r.Method {
+	case http.MethodGet:
+		request, ok := f.pendingRequest(w, r.URL.Query().Get("user_code"))
+		if !ok {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"user_code":  formatUserCode(request.UserCode),
+			"client_id":  request.ClientID,
+			"expires_at": request.ExpiresAt.UTC(),
+		})
+
+	case http.MethodPost:
+		var req struct {
+			UserCode string `json:"user_code"`
+			Approve  bool   `json:"approve"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondJSONError(w, http.StatusBadRequest, "user_code and approve are required")
+			return
+		}
+		request, ok := f.pendingRequest(w, req.UserCode)
+		if !ok {
+			return
+		}
+		status := db.DeviceCodeDenied
+		if req.Approve {
+			status = db.DeviceCodeApproved
+		}
+		decided, err := f.database.DecideDeviceCode(request.UserCode, status, userID, role, tenant)
+		if err != nil {
+			respondJSONError(w, http.StatusInternalServerError, "failed to update device code")
+			return
+		}
+		if !decided {
+			respondJSONError(w, http.StatusConflict, "device code was already used")
+			return
+		}
+		log.Printf("[DEVICE] User %s %s client '%s'", userID, status, request.ClientID)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": status})
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// pendingRequest looks up an unexpired, undecided request by user code and
+// answers 404 when there is none
+func (f *DeviceFlow) pendingRequest(w http.ResponseWriter, userCode string) (*db.DeviceCode, bool) {
+	request, err := f.database.GetDeviceCodeByUserCode(normalizeUserCode(userCode))
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, "failed to read device code")
+		return nil, false
+	}
+	if request == nil || request.Status != db.DeviceCodePending || time.Now().After(request.ExpiresAt) {
+		respondJSONError(w, http.StatusNotFound, "unknown or expired code")
+		return nil, false
+	}
+	return request, true
+}
+
+// ServeToken handles POST /auth/device/token, which the device polls every
+// interval seconds. Errors follow RFC 8628: authorization_pending, slow_down,
+// access_denied and expired_token.
+func (f *DeviceFlow) ServeToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	params := requestParams(r)
+	if params.Get("grant_type") != DeviceCodeGrant {
+		oauthError(w, http.StatusBadRequest, "unsupported_grant_type", "grant_type must be "+DeviceCodeGrant)
+		return
+	}
+	hash := hashDeviceCode(params.Get("device_code"))
+	request, err := f.database.GetDeviceCode(hash)
+	if err != nil {
+		oauthError(w, http.StatusInternalServerError, "server_error", "failed to read device code")
+		return
+	}
+	if request == nil {
+		oauthError(w, http.StatusBadRequest, "invalid_grant", "unknown device code")
+		return
+	}
+
+	now := time.Now()
+	switch {
+	case now.After(request.ExpiresAt):
+		f.database.DeleteDeviceCode(hash)
+		oauthError(w, http.StatusBadRequest, "expired_token", "the device code expired, request a new one")
+		return
+	case request.Status == db.DeviceCodeDenied:
+		f.database.DeleteDeviceCode(hash)
+		oauthError(w, http.StatusBadRequest, "access_denied", "the user denied the request")
+		return
+	case request.Status == db.DeviceCodePending:
+		f.database.TouchDeviceCode(hash, now)
+		if now.Sub(request.PolledAt) < devicePollInterval {
+			oauthError(w, http.StatusBadRequest, "slow_down", "poll less often")
+			return
+		}
+		oauthError(w, http.StatusBadRequest, "authorization_pending", "the user has not approved the request yet")
+		return
+	}
+
+	// Approved: the device code is good for one token only
+	if deleted, err := f.database.DeleteDeviceCode(hash); err != nil || !deleted {
+		oauthError(w, http.StatusBadRequest, "invalid_grant", "unknown device code")
+		return
+	}
+	token, err := utils.GenerateTenantJWT(request.UserID, request.Role, request.Tenant, f.jwtSecret)
+	if err != nil {
+		log.Printf("[DEVICE ERROR] Failed to generate JWT: %v", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "failed to generate token")
+		return
+	}
+	log.Printf("[DEVICE] Token issued to client '%s' for user %s", request.ClientID, request.UserID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(utils.TokenTTL.Seconds()),
+		"user_id":      request.UserID,
+		"role":         request.Role,
+	})
+	f.publishLogin(r, request)
+}
+
+func (f *DeviceFlow) publishLogin(r *http.Request, request *db.DeviceCode) {
+	if f.events == nil {
+		return
+	}
+	metadata := map[string]string{
+		"grant":       "device_code",
+		"client_id":   request.ClientID,
+		"role":        request.Role,
+		"remote_addr": r.RemoteAddr,
+		"user_agent":  r.UserAgent(),
+	}
+	if id, err := strconv.ParseInt(request.UserID, 10, 64); err == nil {
+		if user, _ := f.database.GetUserByID(id); user != nil {
+			metadata["email"] = user.Email
+		}
+	}
+	if country := geoip.Country(r.Context()); country != "" {
+		metadata["country"] = country
+	}
+	f.events.Publish(events.Event{
+		Type:     events.TypeAuthLogin,
+		Source:   events.SourceAuth,
+		UserID:   request.UserID,
+		Metadata: metadata,
+	})
+}
+
+// requestParams reads a form-encoded body (as RFC 8628 specifies) or a JSON object
+func requestParams(r *http.Request) url.Values {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		params := url.Values{}
+		for key, value := range body {
+			params.Set(key, value)
+		}
+		return params
+	}
+	r.ParseForm()
+	return r.PostForm
+}
+
+func newUserCode() (string, error) {
+	code := make([]byte, userCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(userCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = userCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// formatUserCode splits a user code for display, e.g. "BCDF-GHJK"
+func formatUserCode(code string) string {
+	return code[:userCodeLength/2] + "-" + code[userCodeLength/2:]
+}
+
+// normalizeUserCode accepts user codes typed with any case, dashes or spaces
+func normalizeUserCode(code string) string {
+	return strings.Map(func(c rune) rune {
+		if c == '-' || c == ' ' {
+			return -1
+		}
+		return c
+	}, strings.ToUpper(code))
+}
+
+func hashDeviceCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+func oauthError(w http.ResponseWriter, code int, errorCode, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": errorCode, "error_description": description})
+}
+
+func respondJSONError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -6,15 +6,24 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
 	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/events"
+	"github.com/grove/generic-proxy/internal/geoip"
 	"github.com/markbates/goth/gothic"
 )
 
 type Handler struct {
-	database    *db.Database
-	jwtSecret   string
-	frontendURL string
+	database       *db.Database
+	jwtSecret      string
+	frontendURL    string          // base URL of the frontend, without trailing slash
+	allowedOrigins map[string]bool // further origins ?redirect= may point to
+	events         *events.Bus
+	logins         *LoginMonitor
+	cookies        CookieOptions
+	rememberTTL    time.Duration // token lifetime of remember-me logins
 }
 
 type AuthResponse struct {
@@ -29,14 +38,41 @@ func NewHandler(database *db.Database, jwtSecret, frontendURL string) *Handler {
 	return &Handler{
 		database:    database,
 		jwtSecret:   jwtSecret,
-		frontendURL: frontendURL,
+		frontendURL: strings.TrimRight(frontendURL, "/"),
+		cookies:     CookieOptions{SameSite: http.SameSiteLaxMode},
 	}
 }
 
-// BeginAuth initiates OAuth flow
+// SetEventBus sets the bus that authentication events are published to
+func (h *Handler) SetEventBus(bus *events.Bus) {
+	h.events = bus
+}
+
+// SetLoginMonitor enables suspicious login detection for OAuth logins. The
+// provider has authenticated the user, so verify mode only notifies.
+func (h *Handler) SetLoginMonitor(monitor *LoginMonitor) {
+	h.logins = monitor
+}
+
+// BeginAuth initiates OAuth flow. An optional ?redirect= selects where the
+// token is delivered after the callback, and ?remember_me=true asks for a
+// long-lived token.
 func (h *Handler) BeginAuth(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[AUTH] Beginning OAuth flow for provider: %s", r.URL.Query().Get("provider"))
 
+	if redirect := r.URL.Query().Get("redirect"); redirect != "" {
+		target, err := h.RedirectTarget(redirect)
+		if err != nil {
+			log.Printf("[AUTH WARN] Rejected post-login redirect: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.rememberRedirect(w, target)
+	}
+	if r.URL.Query().Get("remember_me") == "true" {
+		h.setCookie(w, rememberCookie, "1")
+	}
+
 	// Goth's gothic package handles the OAuth redirect
 	gothic.BeginAuthHandler(w, r)
 }
@@ -80,7 +116,8 @@ func (h *Handler) CallbackAuth(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate JWT token
-	token, err := GenerateJWT(user.ID, user.Email, user.Provider, role, h.jwtSecret)
+	remember := h.takeCookie(w, r, rememberCookie) == "1"
+	token, err := GenerateJWTWithTTL(user.ID, user.Email, user.Provider, role, h.jwtSecret, h.tokenTTL(remember))
 	if err != nil {
 		log.Printf("[AUTH ERROR] Failed to generate JWT: %v", err)
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
@@ -88,18 +125,58 @@ func (h *Handler) CallbackAuth(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("[AUTH] JWT generated successfully for user: %s", user.Email)
+
+	assessment := h.logins.Assess(r, fmt.Sprintf("%d", user.ID))
+	if assessment.Suspicious() {
+		log.Printf("[AUTH] Suspicious login for %s (%s)", user.Email, assessment.Flags())
+		if action := h.logins.Action(); action == SuspiciousNotify || action == SuspiciousVerify {
+			h.logins.Notify(user.Email, assessment, r)
+		}
+	}
+	h.logins.Remember(assessment)
+
+	if h.events != nil {
+		metadata := map[string]string{
+			"email":       user.Email,
+			"provider":    user.Provider,
+			"role":        role,
+			"remote_addr": r.RemoteAddr,
+			"user_agent":  r.UserAgent(),
+		}
+		if country := geoip.Country(r.Context()); country != "" {
+			metadata["country"] = country
+		}
+		if assessment.Suspicious() {
+			metadata["suspicious"] = assessment.Flags()
+		}
+		h.events.Publish(events.Event{
+			Type:     events.TypeAuthLogin,
+			Source:   events.SourceAuth,
+			UserID:   fmt.Sprintf("%d", user.ID),
+			Metadata: metadata,
+		})
+	}
 	log.Printf("[AUTH] Token preview: %s...%s (length: %d)", token[:20], token[len(token)-20:], len(token))
 
-	// Redirect to frontend callback page with token in URL
-	callbackURL := fmt.Sprintf("%s/auth/callback?token=%s&user_id=%d&email=%s&role=%s",
-		h.frontendURL,
-		url.QueryEscape(token),
-		user.ID,
-		url.QueryEscape(user.Email),
-		url.QueryEscape(role),
-	)
+	// Redirect to the frontend (or the requested, allowlisted page) with the token in the URL
+	target, err := h.RedirectTarget(h.takeRedirect(w, r))
+	if err != nil {
+		log.Printf("[AUTH WARN] Ignoring stored post-login redirect: %v", err)
+		target, _ = h.RedirectTarget("")
+	}
+	callbackURL, err := withToken(target, url.Values{
+		"token":   {token},
+		"user_id": {fmt.Sprintf("%d", user.ID)},
+		"email":   {user.Email},
+		"role":    {role},
+	})
+	if err != nil {
+		log.Printf("[AUTH ERROR] Invalid redirect URL: %v", err)
+		http.Error(w, "Invalid redirect URL", http.StatusInternalServerError)
+		return
+	}
 
-	log.Printf("[AUTH] Redirect URL: %s", callbackURL[:100]+"...")
+	log.Printf("[AUTH] Redirecting to: %s", target)
 	http.Redirect(w, r, callbackURL, http.StatusTemporaryRedirect)
 	log.Printf("[AUTH] Authentication complete for user: %s (ID: %d), redirecting to frontend", user.Email, user.ID)
 }
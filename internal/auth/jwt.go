@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/grove/generic-proxy/internal/utils"
 )
 
 type JWTClaims struct {
@@ -19,13 +20,18 @@ type JWTClaims struct {
 
 // GenerateJWT creates a new JWT token with user claims
 func GenerateJWT(userID int64, email, provider, role, secret string) (string, error) {
+	return GenerateJWTWithTTL(userID, email, provider, role, secret, utils.TokenTTL)
+}
+
+// GenerateJWTWithTTL creates a JWT token with user claims that is valid for ttl
+func GenerateJWTWithTTL(userID int64, email, provider, role, secret string, ttl time.Duration) (string, error) {
 	claims := JWTClaims{
 		UserID:   strconv.FormatInt(userID, 10),
 		Email:    email,
 		Provider: provider,
 		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Subject:   email,
 		},
@@ -54,6 +60,9 @@ func ValidateJWT(tokenString, secret string) (*JWTClaims, error) {
 	}
 
 	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
+		if utils.TokenRevoked(claims.UserID, claims.IssuedAt) {
+			return nil, utils.ErrTokenRevoked
+		}
 		return claims, nil
 	}
 
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// callbackPath is the frontend page that receives the token when no redirect is requested
+	callbackPath = "/auth/callback"
+	// redirectCookie carries the validated ?redirect= target across the OAuth round trip
+	redirectCookie = "post_login_redirect"
+)
+
+// SetRedirectAllowlist sets the origins (scheme://host[:port]) that ?redirect= may
+// point to besides the frontend URL's own origin
+func (h *Handler) SetRedirectAllowlist(origins []string) error {
+	h.allowedOrigins = make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		parsed, err := url.Parse(origin)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("invalid redirect origin '%s', expected scheme://host[:port]", origin)
+		}
+		h.allowedOrigins[originOf(origin)] = true
+	}
+	return nil
+}
+
+// RedirectTarget validates a post-login redirect. A path is resolved against the
+// frontend URL; an absolute URL must use the frontend's origin or an allowlisted
+// one. An empty redirect selects the frontend's /auth/callback page.
+func (h *Handler) RedirectTarget(redirect string) (string, error) {
+	if redirect == "" {
+		return h.frontendURL + callbackPath, nil
+	}
+
+	// "//host" and "\host" are treated as absolute by browsers
+	if strings.HasPrefix(redirect, "/") && !strings.HasPrefix(redirect, "//") && !strings.Contains(redirect, "\\") {
+		return h.frontendURL + redirect, nil
+	}
+
+	parsed, err := url.Parse(redirect)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || parsed.User != nil {
+		return "", fmt.Errorf("invalid redirect '%s'", redirect)
+	}
+	origin := originOf(redirect)
+	if origin != originOf(h.frontendURL) && !h.allowedOrigins[origin] {
+		return "", fmt.Errorf("redirect origin '%s' is not allowed", origin)
+	}
+	return redirect, nil
+}
+
+// originOf returns scheme://host of a URL, empty when it has none
+func originOf(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	return parsed.Scheme + "://" + strings.ToLower(parsed.Host)
+}
+
+// withToken adds the login result to the query of a redirect target
+func withToken(target string, params url.Values) (string, error) {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	query := parsed.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), nil
+}
+
+// rememberRedirect stores the redirect target until the OAuth callback
+func (h *Handler) rememberRedirect(w http.ResponseWriter, target string) {
+	h.setCookie(w, redirectCookie, url.QueryEscape(target))
+}
+
+// takeRedirect returns the stored redirect target (empty when none) and clears it
+func (h *Handler) takeRedirect(w http.ResponseWriter, r *http.Request) string {
+	target, err := url.QueryUnescape(h.takeCookie(w, r, redirectCookie))
+	if err != nil {
+		return ""
+	}
+	return target
+}
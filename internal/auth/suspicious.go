@@ -0,0 +1,279 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/geoip"
+	"github.com/grove/generic-proxy/internal/mailer"
+)
+
+// What happens on a login from a new device or location
+const (
+	SuspiciousOff    = "off"    // not tracked
+	SuspiciousFlag   = "flag"   // flagged in the login event only
+	SuspiciousNotify = "notify" // flagged and the user is emailed
+	SuspiciousVerify = "verify" // the login must be confirmed with a code sent by email
+)
+
+const (
+	challengeTTL         = 10 * time.Minute
+	maxChallengeAttempts = 5
+)
+
+// Login challenge errors
+var (
+	ErrChallengeFailed = errors.New("invalid or expired verification")     // unknown, expired or wrong code
+	ErrChallengeLocked = errors.New("too many wrong codes, sign in again") // the last attempt was used up
+)
+
+// versionPattern strips version numbers, so browser updates do not make a known device look new
+var versionPattern = regexp.MustCompile(`[0-9]+([._][0-9]+)*`)
+
+// LoginAssessment describes how a login compares to the user's history
+type LoginAssessment struct {
+	UserID      string
+	Device      string // normalized user agent fingerprint
+	Location    string // country:XX, or the client network when no country is known
+	NewDevice   bool
+	NewLocation bool
+}
+
+// Suspicious reports whether the login came from a new device or location
+func (a LoginAssessment) Suspicious() bool {
+	return a.NewDevice || a.NewLocation
+}
+
+// Flags lists why a login is suspicious, e.g. "new_device,new_location"
+func (a LoginAssessment) Flags() string {
+	var flags []string
+	if a.NewDevice {
+		flags = append(flags, "new_device")
+	}
+	if a.NewLocation {
+		flags = append(flags, "new_location")
+	}
+	return strings.Join(flags, ",")
+}
+
+// LoginMonitor compares logins of database users against the devices and
+// locations they signed in from before
+type LoginMonitor struct {
+	database *db.Database
+	mailer   *mailer.Mailer
+	geo      *geoip.Filter
+	action   string
+}
+
+// NewLoginMonitor creates a monitor for one of the Suspicious* actions. It
+// returns nil for SuspiciousOff; notify and verify need a mailer.
+func NewLoginMonitor(database *db.Database, action string, m *mailer.Mailer, geo *geoip.Filter) (*LoginMonitor, error) {
+	switch action {
+	case SuspiciousOff:
+		return nil, nil
+	case SuspiciousFlag:
+	case SuspiciousNotify, SuspiciousVerify:
+		if m == nil {
+			return nil, fmt.Errorf("action '%s' sends email but SMTP_HOST is not set", action)
+		}
+	default:
+		return nil, fmt.Errorf("unknown action '%s' (expected off, flag, notify or verify)", action)
+	}
+	return &LoginMonitor{database: database, mailer: m, geo: geo, action: action}, nil
+}
+
+// Action returns the configured action; "off" for a nil monitor
+func (m *LoginMonitor) Action() string {
+	if m == nil {
+		return SuspiciousOff
+	}
+	return m.action
+}
+
+// Assess compares a login to the user's history without recording it. The
+// first login of a user is never suspicious.
+func (m *LoginMonitor) Assess(r *http.Request, userID string) LoginAssessment {
+	if m == nil {
+		return LoginAssessment{UserID: userID}
+	}
+	a := LoginAssessment{UserID: userID, Device: deviceOf(r.UserAgent()), Location: m.locationOf(r)}
+
+	known, err := m.database.HasLoginHistory(userID)
+	if err != nil || !known {
+		return a
+	}
+	if seen, err := m.database.IsKnownLogin(userID, db.LoginDevice, a.Device); err == nil {
+		a.NewDevice = !seen
+	}
+	if seen, err := m.database.IsKnownLogin(userID, db.LoginLocation, a.Location); err == nil {
+		a.NewLocation = !seen
+	}
+	return a
+}
+
+// Remember records the device and location of a completed login
+func (m *LoginMonitor) Remember(a LoginAssessment) {
+	if m == nil || a.Device == "" {
+		return
+	}
+	m.database.RememberLogin(a.UserID, db.LoginDevice, a.Device)
+	m.database.RememberLogin(a.UserID, db.LoginLocation, a.Location)
+}
+
+// Notify emails the user about a suspicious login. Delivery happens in the
+// background; failures are logged.
+func (m *LoginMonitor) Notify(email string, a LoginAssessment, r *http.Request) {
+	if m == nil || m.mailer == nil {
+		return
+	}
+	body := fmt.Sprintf(`Your account was just signed in to from a %s.
+
+Time:       %s
+Location:   %s
+Device:     %s
+
+If this was you, you can ignore this email. If not, change your password
+right away and contact your administrator.
+`, describeFlags(a), time.Now().UTC().Format(time.RFC1123), a.Location, r.UserAgent())
+	go func() {
+		if err := m.mailer.Send(email, "New sign-in to your account", body); err != nil {
+			log.Printf("[AUTH ERROR] Suspicious login notification for user %s: %v", a.UserID, err)
+		}
+	}()
+}
+
+// Challenge holds back a login and emails the user a one-time code. The
+// returned ID is exchanged together with the code for a token.
+func (m *LoginMonitor) Challenge(email, tenant string, a LoginAssessment) (string, error) {
+	id, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+
+	err = m.database.CreateLoginChallenge(db.LoginChallenge{
+		ID:        id,
+		UserID:    a.UserID,
+		CodeHash:  challengeHash(id, code),
+		Tenant:    tenant,
+		Flags:     a.Flags(),
+		ExpiresAt: time.Now().Add(challengeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	body := fmt.Sprintf(`Someone signed in to your account from a %s.
+
+To confirm it was you, enter this code: %s
+
+The code expires in %d minutes. If this was not you, do not share the code;
+change your password and contact your administrator.
+`, describeFlags(a), code, int(challengeTTL.Minutes()))
+	if err := m.mailer.Send(email, "Confirm your sign-in", body); err != nil {
+		m.database.DeleteLoginChallenge(id)
+		return "", err
+	}
+	return id, nil
+}
+
+// CompleteChallenge checks the code of a login challenge. On success the
+// challenge is consumed and returned, and the login's device and location
+// are remembered from r. The wrong code that uses up the last attempt deletes
+// the challenge and returns it with ErrChallengeLocked.
+func (m *LoginMonitor) CompleteChallenge(id, code string, r *http.Request) (*db.LoginChallenge, error) {
+	if m == nil {
+		return nil, ErrChallengeFailed
+	}
+	challenge, err := m.database.GetLoginChallenge(id)
+	if err != nil {
+		return nil, err
+	}
+	if challenge == nil || time.Now().After(challenge.ExpiresAt) || challenge.Attempts >= maxChallengeAttempts {
+		if challenge != nil {
+			m.database.DeleteLoginChallenge(id)
+		}
+		return nil, ErrChallengeFailed
+	}
+	if subtle.ConstantTimeCompare([]byte(challengeHash(id, code)), []byte(challenge.CodeHash)) != 1 {
+		if challenge.Attempts+1 >= maxChallengeAttempts {
+			m.database.DeleteLoginChallenge(id)
+			return challenge, ErrChallengeLocked
+		}
+		m.database.CountLoginChallengeAttempt(id)
+		return nil, ErrChallengeFailed
+	}
+
+	if err := m.database.DeleteLoginChallenge(id); err != nil {
+		return nil, err
+	}
+	m.Remember(LoginAssessment{UserID: challenge.UserID, Device: deviceOf(r.UserAgent()), Location: m.locationOf(r)})
+	return challenge, nil
+}
+
+// locationOf returns the client's country, or its network (/24 or /48) when
+// the country is unknown
+func (m *LoginMonitor) locationOf(r *http.Request) string {
+	if country := geoip.Country(r.Context()); country != "" {
+		return "country:" + country
+	}
+	var ip net.IP
+	if m.geo != nil {
+		ip = m.geo.ClientIP(r)
+	} else if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ip = net.ParseIP(host)
+	}
+	if ip == nil {
+		return "unknown"
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return "net:" + v4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+	}
+	return "net:" + ip.Mask(net.CIDRMask(48, 128)).String() + "/48"
+}
+
+// deviceOf fingerprints a user agent without its version numbers
+func deviceOf(userAgent string) string {
+	sum := sha256.Sum256([]byte(versionPattern.ReplaceAllString(strings.ToLower(userAgent), "")))
+	return hex.EncodeToString(sum[:8])
+}
+
+func describeFlags(a LoginAssessment) string {
+	switch {
+	case a.NewDevice && a.NewLocation:
+		return "new device and location"
+	case a.NewDevice:
+		return "new device"
+	default:
+		return "new location"
+	}
+}
+
+func challengeHash(id, code string) string {
+	sum := sha256.Sum256([]byte(id + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,282 @@
+// Package backup exports the gateway's state to an encrypted archive and
+// restores it on another instance, for disaster recovery and host migration.
+//
+// An archive holds a snapshot of the user database (accounts and roles,
+// tenant memberships, revoked sessions, known logins, usage counters, vault
+// tokens and queued writes) and a copy of proxy.yaml. It is a gzipped tar,
+// sealed with AES-256-GCM under a key derived from STATE_ARCHIVE_KEY with
+// scrypt and a random salt, so that a passphrase is costly to guess offline:
+//
+//	"GPSTATE2" | salt | nonce | ciphertext
+//
+// Secrets kept in the environment (JWT_SECRET, TOKEN_VAULT_KEY,
+// FIELD_ENCRYPTION_KEY, ...) are not part of it; the restored instance needs
+// the same values to read vault tokens and encrypted fields.
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/db"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	magic         = "GPSTATE2"
+	formatVersion = 1
+	saltSize      = 16
+
+	// scrypt cost parameters of the archive key
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+
+	manifestName = "manifest.json"
+	databaseName = "users.db"
+	configName   = "proxy.yaml"
+
+	// maxArchiveBytes bounds the archives Restore reads
+	maxArchiveBytes = 1 << 30
+)
+
+// ErrExists is returned by Restore when a target file exists and force is not set
+var ErrExists = errors.New("target exists")
+
+// Manifest describes an archive
+type Manifest struct {
+	Version   int              `json:"version"`
+	CreatedAt time.Time        `json:"created_at"`
+	Files     map[string]int64 `json:"files"` // name -> size in bytes
+}
+
+// Export writes an archive of the database and, when configPath names an
+// existing file, of proxy.yaml
+func Export(ctx context.Context, w io.Writer, database *db.Database, configPath, key string) (Manifest, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return Manifest{}, err
+	}
+	aead, err := newAEAD(key, salt)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	dir, err := os.MkdirTemp("", "gateway-state-")
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer os.RemoveAll(dir)
+	snapshot := filepath.Join(dir, databaseName)
+	if err := database.Snapshot(ctx, snapshot); err != nil {
+		return Manifest{}, fmt.Errorf("cannot snapshot the database: %w", err)
+	}
+
+	files := make(map[string][]byte, 2)
+	if files[databaseName], err = os.ReadFile(snapshot); err != nil {
+		return Manifest{}, err
+	}
+	if configPath != "" {
+		data, err := os.ReadFile(configPath)
+		switch {
+		case err == nil:
+			files[configName] = data
+		case !errors.Is(err, os.ErrNotExist):
+			return Manifest{}, fmt.Errorf("cannot read %s: %w", configPath, err)
+		}
+	}
+
+	manifest := Manifest{Version: formatVersion, CreatedAt: time.Now().UTC().Truncate(time.Second), Files: make(map[string]int64, len(files))}
+	for name, data := range files {
+		manifest.Files[name] = int64(len(data))
+	}
+	encoded, err := json.Marshal(manifest)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	var plain bytes.Buffer
+	zw := gzip.NewWriter(&plain)
+	tw := tar.NewWriter(zw)
+	names := []string{manifestName}
+	files[manifestName] = encoded
+	for name := range manifest.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	for _, name := range names {
+		header := &tar.Header{Name: name, Mode: 0600, Size: int64(len(files[name])), ModTime: manifest.CreatedAt}
+		if err := tw.WriteHeader(header); err != nil {
+			return Manifest{}, err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return Manifest{}, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return Manifest{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return Manifest{}, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return Manifest{}, err
+	}
+	header := append([]byte(magic), salt...)
+	sealed := aead.Seal(nil, nonce, plain.Bytes(), header)
+	for _, part := range [][]byte{header, nonce, sealed} {
+		if _, err := w.Write(part); err != nil {
+			return Manifest{}, err
+		}
+	}
+	return manifest, nil
+}
+
+// Restore unpacks an archive: the database to databasePath and, when
+// configPath is not empty and the archive has one, proxy.yaml to configPath.
+// Existing files are only replaced with force. The gateway must not be
+// running while its database is replaced.
+func Restore(r io.Reader, key, databasePath, configPath string, force bool) (Manifest, error) {
+	files, err := open(r, key)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(files[manifestName], &manifest); err != nil {
+		return Manifest{}, fmt.Errorf("archive has no valid manifest")
+	}
+	if manifest.Version != formatVersion {
+		return Manifest{}, fmt.Errorf("unsupported archive version %d", manifest.Version)
+	}
+	if _, ok := files[databaseName]; !ok {
+		return Manifest{}, fmt.Errorf("archive has no database")
+	}
+	if _, ok := files[configName]; !ok {
+		configPath = ""
+	}
+
+	if !force {
+		for _, path := range []string{databasePath, configPath} {
+			if _, err := os.Stat(path); path != "" && err == nil {
+				return Manifest{}, fmt.Errorf("%w: %s", ErrExists, path)
+			}
+		}
+	}
+
+	if err := writeFile(databasePath, files[databaseName]); err != nil {
+		return Manifest{}, err
+	}
+	// Journal files of the replaced database would be applied to the restored one
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		os.Remove(databasePath + suffix)
+	}
+	if configPath != "" {
+		if err := writeFile(configPath, files[configName]); err != nil {
+			return Manifest{}, err
+		}
+	}
+	return manifest, nil
+}
+
+// open decrypts an archive and returns its files by name
+func open(r io.Reader, key string) (map[string][]byte, error) {
+	if key == "" {
+		return nil, errors.New("STATE_ARCHIVE_KEY is required")
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxArchiveBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxArchiveBytes {
+		return nil, fmt.Errorf("archive is larger than %d bytes", maxArchiveBytes)
+	}
+	headerSize := len(magic) + saltSize
+	if bytes.HasPrefix(data, []byte("GPSTATE1")) {
+		return nil, fmt.Errorf("archive has an unsalted key from an earlier release; export it again")
+	}
+	if len(data) < headerSize || string(data[:len(magic)]) != magic {
+		return nil, fmt.Errorf("not a gateway state archive")
+	}
+	aead, err := newAEAD(key, data[len(magic):headerSize])
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < headerSize+aead.NonceSize() {
+		return nil, fmt.Errorf("not a gateway state archive")
+	}
+	nonce := data[headerSize : headerSize+aead.NonceSize()]
+	plain, err := aead.Open(nil, nonce, data[headerSize+aead.NonceSize():], data[:headerSize])
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt archive (wrong STATE_ARCHIVE_KEY?)")
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(plain))
+	if err != nil {
+		return nil, fmt.Errorf("corrupt archive: %w", err)
+	}
+	files := make(map[string][]byte)
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("corrupt archive: %w", err)
+		}
+		if files[header.Name], err = io.ReadAll(tr); err != nil {
+			return nil, fmt.Errorf("corrupt archive: %w", err)
+		}
+	}
+	return files, nil
+}
+
+// writeFile replaces a file atomically, through a temporary file next to it
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".restore-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// newAEAD derives the archive cipher from STATE_ARCHIVE_KEY and the salt of
+// the archive
+func newAEAD(key string, salt []byte) (cipher.AEAD, error) {
+	if key == "" {
+		return nil, errors.New("STATE_ARCHIVE_KEY is required")
+	}
+	derived, err := scrypt.Key([]byte(key), salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
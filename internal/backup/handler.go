@@ -0,0 +1,63 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+// Handler serves GET /admin/state/export, which downloads an archive of the
+// gateway's state. Archives are restored with "generic-proxy state import"
+// while the gateway is stopped.
+type Handler struct {
+	database   *db.Database
+	configPath string
+	key        string
+}
+
+// NewHandler creates the export handler
+func NewHandler(database *db.Database, configPath, key string) *Handler {
+	return &Handler{database: database, configPath: configPath, key: key}
+}
+
+// ServeHTTP handles GET /admin/state/export. Must run after middleware.AuthMiddleware.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if role, _ := r.Context().Value(middleware.RoleKey).(string); role != "admin" {
+		respondError(w, http.StatusForbidden, "admin role required")
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var archive bytes.Buffer
+	manifest, err := Export(r.Context(), &archive, h.database, h.configPath, h.key)
+	if err != nil {
+		log.Printf("[STATE ERROR] Export failed: %v", err)
+		respondError(w, http.StatusInternalServerError, "failed to export state")
+		return
+	}
+	adminID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	log.Printf("[STATE] Admin %s exported the gateway state (%d bytes)", adminID, archive.Len())
+
+	filename := fmt.Sprintf("gateway-state-%s.gpstate", manifest.CreatedAt.Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(archive.Len()))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(archive.Bytes())
+}
+
+func respondError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,278 @@
+// Package captcha puts a bot challenge in front of the public auth endpoints.
+//
+// Three providers are supported: hCaptcha and Cloudflare Turnstile, whose
+// widget tokens are checked against the provider's siteverify API, and a
+// built-in proof-of-work challenge that needs no third party (see pow.go).
+package captcha
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	ProviderHCaptcha  = "hcaptcha"
+	ProviderTurnstile = "turnstile"
+	ProviderPoW       = "pow"
+
+	// TokenHeader carries the widget token or proof-of-work solution; a
+	// "captcha_token" field in the JSON body is accepted as well
+	TokenHeader = "X-Captcha-Token"
+
+	// maxPeekBytes bounds how much of a request body is read to find captcha_token
+	maxPeekBytes = 1 << 20
+)
+
+// Endpoints that can be protected
+var knownEndpoints = map[string]bool{
+	"login":  true,
+	"signup": true,
+}
+
+var verifyURLs = map[string]string{
+	ProviderHCaptcha:  "https://api.hcaptcha.com/siteverify",
+	ProviderTurnstile: "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+}
+
+// ErrMissing is returned when a protected request carries no token
+var ErrMissing = errors.New("captcha token required")
+
+// ErrFailed is returned when a token is invalid, expired or already used
+var ErrFailed = errors.New("captcha verification failed")
+
+// Config selects the provider and the endpoints it protects
+type Config struct {
+	Provider   string   // hcaptcha, turnstile or pow; empty disables the challenge
+	Secret     string   // hcaptcha/turnstile secret key; pow: HMAC key shared by all instances
+	SiteKey    string   // public widget key handed to the frontend (hcaptcha/turnstile)
+	Endpoints  []string // login, signup
+	Difficulty int      // pow: leading zero bits of the solution hash
+}
+
+// Guard verifies challenge tokens on the configured endpoints. A nil Guard
+// protects nothing.
+type Guard struct {
+	provider   string
+	secret     string
+	siteKey    string
+	endpoints  map[string]bool
+	pow        *powIssuer
+	httpClient *http.Client
+}
+
+// New validates the configuration and returns a Guard, or nil when no provider is set
+func New(cfg Config) (*Guard, error) {
+	if cfg.Provider == "" {
+		return nil, nil
+	}
+
+	g := &Guard{
+		provider:   cfg.Provider,
+		secret:     cfg.Secret,
+		siteKey:    cfg.SiteKey,
+		endpoints:  make(map[string]bool, len(cfg.Endpoints)),
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+	for _, endpoint := range cfg.Endpoints {
+		if !knownEndpoints[endpoint] {
+			return nil, fmt.Errorf("unknown endpoint '%s' (expected login or signup)", endpoint)
+		}
+		g.endpoints[endpoint] = true
+	}
+	if len(g.endpoints) == 0 {
+		return nil, fmt.Errorf("no endpoints to protect")
+	}
+
+	switch cfg.Provider {
+	case ProviderHCaptcha, ProviderTurnstile:
+		if cfg.Secret == "" {
+			return nil, fmt.Errorf("%s requires a secret key", cfg.Provider)
+		}
+	case ProviderPoW:
+		pow, err := newPowIssuer(cfg.Secret, cfg.Difficulty)
+		if err != nil {
+			return nil, err
+		}
+		g.pow = pow
+	default:
+		return nil, fmt.Errorf("unknown provider '%s' (expected hcaptcha, turnstile or pow)", cfg.Provider)
+	}
+	return g, nil
+}
+
+// Provider returns the configured provider
+func (g *Guard) Provider() string {
+	return g.provider
+}
+
+// Endpoints returns the protected endpoints, sorted
+func (g *Guard) Endpoints() []string {
+	endpoints := make([]string, 0, len(g.endpoints))
+	for endpoint := range g.endpoints {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	return endpoints
+}
+
+// Protect requires a valid token on requests to next when endpoint is protected
+func (g *Guard) Protect(endpoint string, next http.HandlerFunc) http.HandlerFunc {
+	if g == nil || !g.endpoints[endpoint] {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			// Let the handler report the wrong method
+			next(w, r)
+			return
+		}
+
+		err := g.Verify(r.Context(), requestToken(r), clientIP(r))
+		switch {
+		case err == nil:
+			next(w, r)
+		case errors.Is(err, ErrMissing):
+			writeError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, ErrFailed):
+			log.Printf("[CAPTCHA] Rejected %s request from %s: %v", endpoint, r.RemoteAddr, err)
+			writeError(w, http.StatusForbidden, ErrFailed.Error())
+		default:
+			// Fail closed: an unreachable provider must not open the endpoint to bots
+			log.Printf("[CAPTCHA ERROR] Verification of %s request failed: %v", endpoint, err)
+			writeError(w, http.StatusServiceUnavailable, "captcha verification unavailable")
+		}
+	}
+}
+
+// Verify checks a token. Errors wrap ErrMissing or ErrFailed when the token
+// is at fault; any other error means the provider could not be asked.
+func (g *Guard) Verify(ctx context.Context, token, remoteIP string) error {
+	if token == "" {
+		return ErrMissing
+	}
+	if g.pow != nil {
+		return g.pow.verify(token)
+	}
+	return g.siteverify(ctx, token, remoteIP)
+}
+
+// siteverify asks hCaptcha or Turnstile whether a widget token is valid
+func (g *Guard) siteverify(ctx context.Context, token, remoteIP string) error {
+	form := url.Values{"secret": {g.secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, verifyURLs[g.provider], strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s siteverify returned status %d", g.provider, resp.StatusCode)
+	}
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("invalid %s siteverify response: %w", g.provider, err)
+	}
+	if !result.Success {
+		return fmt.Errorf("%w: %s", ErrFailed, strings.Join(result.ErrorCodes, ", "))
+	}
+	return nil
+}
+
+// ChallengeResponse tells the frontend which challenge to present
+type ChallengeResponse struct {
+	Provider   string   `json:"provider"` // empty when no challenge is required
+	Endpoints  []string `json:"endpoints"`
+	SiteKey    string   `json:"site_key,omitempty"`
+	Challenge  string   `json:"challenge,omitempty"`
+	Difficulty int      `json:"difficulty,omitempty"`
+	ExpiresAt  string   `json:"expires_at,omitempty"`
+}
+
+// ServeChallenge handles GET /auth/challenge. With the pow provider every call
+// issues a fresh challenge; otherwise it returns the widget's site key.
+func (g *Guard) ServeChallenge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := ChallengeResponse{Endpoints: []string{}}
+	if g != nil {
+		response.Provider = g.provider
+		response.Endpoints = g.Endpoints()
+		response.SiteKey = g.siteKey
+		if g.pow != nil {
+			challenge, expires := g.pow.issue()
+			response.Challenge = challenge
+			response.Difficulty = g.pow.difficulty
+			response.ExpiresAt = expires.UTC().Format(time.RFC3339)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[CAPTCHA ERROR] Failed to encode challenge response: %v", err)
+	}
+}
+
+// requestToken returns the token from the X-Captcha-Token header or the
+// captcha_token field of a JSON body, leaving the body readable for the handler
+func requestToken(r *http.Request) string {
+	if token := r.Header.Get(TokenHeader); token != "" {
+		return token
+	}
+	if r.Body == nil {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPeekBytes))
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	var fields struct {
+		CaptchaToken string `json:"captcha_token"`
+	}
+	json.Unmarshal(body, &fields)
+	return fields.CaptchaToken
+}
+
+// clientIP returns the host part of the peer address
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,126 @@
+package captcha
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDifficulty costs a browser roughly a quarter million hashes
+	DefaultDifficulty = 18
+	maxDifficulty     = 32
+
+	// challengeTTL is how long an issued challenge can be solved and redeemed
+	challengeTTL = 5 * time.Minute
+)
+
+// powIssuer hands out signed proof-of-work challenges and checks solutions.
+//
+// A challenge is "<expiry unix>.<random hex>.<hmac hex>". The client searches
+// for a nonce such that sha256("<challenge>:<nonce>") starts with difficulty
+// zero bits and sends "<challenge>:<nonce>" as its token. Challenges are
+// stateless until redeemed; redeemed ones are remembered until they expire so
+// a solution cannot be replayed.
+type powIssuer struct {
+	key        []byte
+	difficulty int
+
+	mu   sync.Mutex
+	used map[string]time.Time // challenge -> expiry
+}
+
+func newPowIssuer(secret string, difficulty int) (*powIssuer, error) {
+	if difficulty == 0 {
+		difficulty = DefaultDifficulty
+	}
+	if difficulty < 1 || difficulty > maxDifficulty {
+		return nil, fmt.Errorf("difficulty must be between 1 and %d", maxDifficulty)
+	}
+
+	key := []byte(secret)
+	if len(key) == 0 {
+		// Challenges are then only valid on the instance that issued them
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate challenge key: %w", err)
+		}
+	}
+	return &powIssuer{key: key, difficulty: difficulty, used: make(map[string]time.Time)}, nil
+}
+
+// issue creates a new challenge
+func (p *powIssuer) issue() (string, time.Time) {
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	expires := time.Now().Add(challengeTTL)
+	payload := strconv.FormatInt(expires.Unix(), 10) + "." + hex.EncodeToString(nonce)
+	return payload + "." + p.sign(payload), expires
+}
+
+// verify checks a "<challenge>:<nonce>" solution and marks the challenge used
+func (p *powIssuer) verify(token string) error {
+	challenge, _, ok := strings.Cut(token, ":")
+	if !ok {
+		return fmt.Errorf("%w: malformed solution", ErrFailed)
+	}
+	parts := strings.Split(challenge, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("%w: malformed challenge", ErrFailed)
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(p.sign(payload))) {
+		return fmt.Errorf("%w: challenge was not issued by this gateway", ErrFailed)
+	}
+	unix, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: malformed challenge", ErrFailed)
+	}
+	expires := time.Unix(unix, 0)
+	if time.Now().After(expires) {
+		return fmt.Errorf("%w: challenge expired", ErrFailed)
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	if leadingZeroBits(sum[:]) < p.difficulty {
+		return fmt.Errorf("%w: insufficient proof of work", ErrFailed)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	for used, expiry := range p.used {
+		if now.After(expiry) {
+			delete(p.used, used)
+		}
+	}
+	if _, replayed := p.used[challenge]; replayed {
+		return fmt.Errorf("%w: challenge already used", ErrFailed)
+	}
+	p.used[challenge] = expires
+	return nil
+}
+
+func (p *powIssuer) sign(payload string) string {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func leadingZeroBits(sum []byte) int {
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			return zeros + bits.LeadingZeros8(b)
+		}
+		zeros += 8
+	}
+	return zeros
+}
@@ -0,0 +1,280 @@
+package cdc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/events"
+	"github.com/grove/generic-proxy/internal/proxy"
+	"github.com/grove/generic-proxy/internal/scheduler"
+)
+
+const (
+	defaultUpdatedField = "UpdatedAt"
+	defaultInterval     = 30 * time.Second
+	pageSize            = 100
+	maxPages            = 1000
+)
+
+// Poller periodically queries CDC-enabled tables and emits change events
+type Poller struct {
+	client *proxy.UpstreamClient
+	store  *db.Database
+	bus    *events.Bus
+	config *config.ResolvedConfig
+}
+
+// NewPoller creates a new change-data-capture poller
+func NewPoller(client *proxy.UpstreamClient, store *db.Database, bus *events.Bus, resolvedConfig *config.ResolvedConfig) *Poller {
+	return &Poller{
+		client: client,
+		store:  store,
+		bus:    bus,
+		config: resolvedConfig,
+	}
+}
+
+// Start schedules one polling job per CDC-enabled table.
+// It returns the number of tables being polled.
+func (p *Poller) Start(jobs *scheduler.Scheduler) int {
+	if p.config == nil {
+		return 0
+	}
+
+	started := 0
+	for tableKey, table := range p.config.Tables {
+		if table.CDC == nil || !table.CDC.Enabled {
+			continue
+		}
+		if err := jobs.Add(p.pollJob(tableKey, table)); err != nil {
+			log.Printf("[CDC ERROR] Cannot schedule polling of table '%s': %v", tableKey, err)
+			continue
+		}
+		started++
+	}
+
+	if started > 0 {
+		log.Printf("[CDC] Started change-data-capture polling for %d table(s)", started)
+	}
+	return started
+}
+
+// pollJob polls a single table at its configured interval
+func (p *Poller) pollJob(tableKey string, table config.ResolvedTable) scheduler.Job {
+	interval := defaultInterval
+	if table.CDC.Interval != "" {
+		if d, err := time.ParseDuration(table.CDC.Interval); err == nil {
+			interval = d
+		}
+	}
+
+	log.Printf("[CDC] Polling table '%s' (%s) every %v", tableKey, table.TableID, interval)
+
+	return scheduler.Job{
+		Name:       "cdc:" + tableKey,
+		Schedule:   scheduler.Every(interval),
+		RunAtStart: true,
+		Run: func(ctx context.Context) error {
+			err := p.PollTable(ctx, tableKey, table)
+			if err != nil {
+				log.Printf("[CDC ERROR] Poll of table '%s' failed: %v", tableKey, err)
+			}
+			return err
+		},
+	}
+}
+
+// PollTable fetches records changed since the stored cursor and emits events.
+// The first poll of a table records a silent baseline without emitting events.
+func (p *Poller) PollTable(ctx context.Context, tableKey string, table config.ResolvedTable) error {
+	updatedField := table.CDC.UpdatedField
+	if updatedField == "" {
+		updatedField = defaultUpdatedField
+	}
+
+	cursor, err := p.store.GetCDCCursor(tableKey)
+	if err != nil {
+		return fmt.Errorf("failed to load cursor: %w", err)
+	}
+	known, err := p.store.GetCDCRecords(tableKey)
+	if err != nil {
+		return fmt.Errorf("failed to load known records: %w", err)
+	}
+
+	baseline := cursor == "" && len(known) == 0
+	cursorAt, hasCursor := parseVersion(cursor)
+	var newest time.Time
+	created, updated := 0, 0
+
+	// Walk records newest-first and stop once we reach versions older than the cursor
+	params := url.Values{}
+	params.Set("sort", "-"+updatedField)
+
+	for page := 1; page <= maxPages; page++ {
+		records, hasMore, err := p.client.ListRecords(ctx, table.TableID, params, page, pageSize)
+		if err != nil {
+			return err
+		}
+
+		reachedCursor := false
+		for _, record := range records {
+			id := proxy.RecordID(record)
+			if id == "" {
+				continue
+			}
+			version := ""
+			if value := record[updatedField]; value != nil {
+				version = fmt.Sprintf("%v", value)
+			}
+
+			// Records without a readable timestamp (e.g. never updated) are
+			// compared with the known versions only, wherever they sort
+			if at, ok := parseVersion(version); ok {
+				if !baseline && hasCursor && at.Before(cursorAt) {
+					reachedCursor = true
+					break
+				}
+				if at.After(newest) {
+					newest = at
+				}
+			}
+
+			previous, exists := known[id]
+			if exists && previous == version {
+				continue
+			}
+			if err := p.store.UpsertCDCRecord(tableKey, id, version); err != nil {
+				return err
+			}
+			known[id] = version
+
+			if baseline {
+				continue
+			}
+			eventType := events.TypeRecordUpdated
+			if !exists {
+				eventType = events.TypeRecordCreated
+				created++
+			} else {
+				updated++
+			}
+			p.emit(eventType, tableKey, table.TableID, id, record)
+		}
+
+		if reachedCursor || !hasMore {
+			break
+		}
+	}
+
+	deleted := 0
+	if table.CDC.DetectDeletes && !baseline {
+		deleted, err = p.detectDeletes(ctx, tableKey, table, known)
+		if err != nil {
+			return fmt.Errorf("delete detection failed: %w", err)
+		}
+	}
+
+	newCursor := cursor
+	if !newest.IsZero() && (!hasCursor || newest.After(cursorAt)) {
+		newCursor = newest.UTC().Format(time.RFC3339Nano)
+		if err := p.store.SetCDCCursor(tableKey, newCursor); err != nil {
+			return err
+		}
+	}
+
+	if baseline {
+		log.Printf("[CDC] Recorded baseline for table '%s': %d record(s), cursor=%s", tableKey, len(known), newCursor)
+	} else if created+updated+deleted > 0 {
+		log.Printf("[CDC] Table '%s': %d created, %d updated, %d deleted (cursor=%s)", tableKey, created, updated, deleted, newCursor)
+	}
+	return nil
+}
+
+// versionLayouts are the timestamp formats NocoDB returns, depending on its
+// version and database; timestamps without an offset are UTC
+var versionLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+}
+
+// parseVersion reads a record's updated_at value, or a stored cursor, as an
+// instant. Cursors are stored in RFC 3339; those of earlier releases are
+// NocoDB's own format.
+func parseVersion(version string) (time.Time, bool) {
+	if version == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range versionLayouts {
+		if at, err := time.Parse(layout, version); err == nil {
+			return at, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// detectDeletes scans all current record IDs and emits deletes for known IDs
+// that vanished. A table larger than maxPages pages is not scanned completely,
+// so nothing is deleted for it.
+func (p *Poller) detectDeletes(ctx context.Context, tableKey string, table config.ResolvedTable, known map[string]string) (int, error) {
+	current := make(map[string]bool, len(known))
+	complete := false
+	for page := 1; page <= maxPages; page++ {
+		records, hasMore, err := p.client.ListRecords(ctx, table.TableID, url.Values{}, page, pageSize)
+		if err != nil {
+			return 0, err
+		}
+		for _, record := range records {
+			if id := proxy.RecordID(record); id != "" {
+				current[id] = true
+			}
+		}
+		if !hasMore {
+			complete = true
+			break
+		}
+	}
+	if !complete {
+		log.Printf("[CDC WARN] Table '%s' has more than %d records, skipping delete detection", tableKey, maxPages*pageSize)
+		return 0, nil
+	}
+
+	deleted := 0
+	for id := range known {
+		if current[id] {
+			continue
+		}
+		if err := p.store.DeleteCDCRecord(tableKey, id); err != nil {
+			return deleted, err
+		}
+		delete(known, id)
+		p.emit(events.TypeRecordDeleted, tableKey, table.TableID, id, nil)
+		deleted++
+	}
+	return deleted, nil
+}
+
+// emit publishes a change event on the bus
+func (p *Poller) emit(eventType, tableKey, tableID, recordID string, record map[string]interface{}) {
+	if p.bus == nil {
+		return
+	}
+	event := events.Event{
+		Type:      eventType,
+		Source:    events.SourceCDC,
+		TableID:   tableID,
+		TableName: tableKey,
+		RecordID:  recordID,
+	}
+	if record != nil {
+		event.Records = []map[string]interface{}{record}
+	}
+	p.bus.Publish(event)
+}
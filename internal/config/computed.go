@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// ComputedFields is a table's computed block, compiled once when the
+// configuration is resolved. Expressions (expr-lang syntax) see the fields of
+// the record by name (qty * price), and all of them as record, for names that
+// are not identifiers (record["First Name"]). A field the response does not
+// carry is nil, so that first_name ?? "" gives a fallback.
+type ComputedFields struct {
+	fields []compiledDefault // sorted by field for a stable evaluation order
+}
+
+// CompileComputed compiles the expressions of a computed block
+func CompileComputed(computed map[string]string) (*ComputedFields, error) {
+	fields := make([]string, 0, len(computed))
+	for field := range computed {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	compiled := &ComputedFields{}
+	for _, field := range fields {
+		if field == "" {
+			return nil, fmt.Errorf("computed: field name is required")
+		}
+		if computed[field] == "" {
+			return nil, fmt.Errorf("computed.%s: expression is required", field)
+		}
+		program, err := expr.Compile(computed[field], expr.AllowUndefinedVariables())
+		if err != nil {
+			return nil, fmt.Errorf("computed.%s: %w", field, err)
+		}
+		compiled.fields = append(compiled.fields, compiledDefault{field: field, program: program})
+	}
+	return compiled, nil
+}
+
+// Fields returns the names of the computed fields
+func (c *ComputedFields) Fields() []string {
+	names := make([]string, len(c.fields))
+	for i, field := range c.fields {
+		names[i] = field.field
+	}
+	return names
+}
+
+// Apply sets every computed field of a record. Each expression sees the
+// record as read, not the other computed fields. A field whose expression
+// cannot be evaluated (e.g. multiplying a missing field) is set to null.
+func (c *ComputedFields) Apply(record map[string]interface{}) {
+	vars := make(map[string]interface{}, len(record)+1)
+	for field, value := range record {
+		vars[field] = value
+	}
+	vars["record"] = record
+	values := make([]interface{}, len(c.fields))
+	for i, field := range c.fields {
+		values[i] = evalComputed(field.program, vars)
+	}
+	for i, field := range c.fields {
+		record[field.field] = values[i]
+	}
+}
+
+func evalComputed(program *vm.Program, vars map[string]interface{}) interface{} {
+	value, err := expr.Run(program, vars)
+	if err != nil {
+		return nil
+	}
+	return value
+}
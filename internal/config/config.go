@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -16,9 +17,73 @@ type Config struct {
 	NocoDBToken  string
 	NocoDBBaseID string
 
+	// File holding the NocoDB token; reloaded when it changes (optional)
+	NocoDBTokenFile string
+
+	// Data API dialect: auto (probe the upstream), v2 or v3
+	NocoDBAPIVersion string
+
+	// Fixtures file for the in-memory mock NocoDB (development only; replaces NOCODB_URL)
+	MockNocoDBFixtures string
+
+	// Metadata refresh: parallel table detail requests and per-request timeout
+	MetaFetchConcurrency string
+	MetaFetchTimeout     string
+	MetaSnapshotPath     string
+	MetaRefreshInterval  string
+	// Shared metadata cache for multiple gateway instances (file or redis)
+	MetaStore string
+	RedisURL  string
+	// Where cached responses live (memory or redis, shared by every instance)
+	ResponseCacheStore string
+
+	// Standby NocoDB instance for health-based failover (optional)
+	NocoDBStandbyURL     string
+	NocoDBHealthPath     string
+	NocoDBHealthInterval string
+
+	// Readiness (GET /readyz): component weights, the score needed, and the oldest metadata accepted
+	ReadyWeights    string
+	ReadyMinScore   string
+	ReadyMetaMaxAge string
+
+	// Longest upstream timeout a client may ask for with X-Budget-Ms
+	LatencyBudgetMax string
+
+	// Largest request body accepted on /proxy/, in KB (0 = no limit)
+	MaxRequestKB string
+
+	// Gzip compression of /proxy/ responses: on or off, smallest body, media types and level
+	Compression         string
+	CompressionMinBytes string
+	CompressionTypes    string
+	CompressionLevel    string
+
+	// Connection pool to NocoDB (and every other upstream of the default transport)
+	UpstreamMaxIdleConns          string
+	UpstreamMaxIdleConnsPerHost   string
+	UpstreamMaxConnsPerHost       string
+	UpstreamIdleConnTimeout       string
+	UpstreamDialTimeout           string
+	UpstreamKeepAlive             string
+	UpstreamTLSHandshakeTimeout   string
+	UpstreamResponseHeaderTimeout string
+
+	// Read replica that GET traffic is sent to (optional)
+	ReadNocoDBURL string
+
+	// Shadow instance that a sample of requests is mirrored to (optional)
+	ShadowNocoDBURL   string
+	ShadowNocoDBToken string
+	ShadowPercent     string
+	ShadowWrites      string
+
 	// JWT
 	JWTSecret string
 
+	// Share links (defaults to JWT_SECRET)
+	ShareLinkSecret string
+
 	// OAuth - Google
 	GoogleClientID     string
 	GoogleClientSecret string
@@ -29,11 +94,84 @@ type Config struct {
 	GitHubClientSecret string
 	GitHubCallbackURL  string
 
+	// Time limit of one WebAssembly filter call (wasm_filters in proxy.yaml)
+	WasmFilterTimeout string
+
+	// Frontend that receives OAuth logins, and further origins a ?redirect= may point to
+	FrontendURL               string
+	FrontendRedirectAllowlist []string
+	// Page where users enter device flow codes (default: FRONTEND_URL/device)
+	DeviceVerificationURL string
+
+	// Bot challenge on /login and /signup: hcaptcha, turnstile or pow (optional)
+	CaptchaProvider   string
+	CaptchaSecret     string
+	CaptchaSiteKey    string
+	CaptchaEndpoints  []string
+	CaptchaDifficulty string
+
+	// Reject passwords found in the Pwned Passwords range API ("true" or "false")
+	PasswordBreachCheck string
+	PwnedPasswordsURL   string
+
+	// Logins from new devices or locations: off, flag, notify or verify
+	SuspiciousLogin string
+
+	// Outgoing email (suspicious login notifications)
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
 	// Database
 	DatabasePath string
 
-	// Session
-	SessionSecret string
+	// First-run admin account, created when the user database has no admin yet
+	BootstrapAdminEmail    string
+	BootstrapAdminPassword string
+	// Whether the built-in demo accounts may log in ("true" or "false");
+	// superseded by flags.demo_users in proxy.yaml
+	DemoUsers string
+
+	// Token vault (encrypts per-tenant/per-base NocoDB tokens at rest)
+	TokenVaultKey string
+
+	// Encrypts state archives (/admin/state/export, generic-proxy state)
+	StateArchiveKey string
+
+	// Key of the columns listed under encrypt in proxy.yaml, and retired keys still accepted for reading
+	FieldEncryptionKey          string
+	FieldEncryptionPreviousKeys []string
+
+	// Signs requests to NocoDB (X-Gateway-Signature-V1), for signature-checking proxies in front of it
+	UpstreamSigningSecret string
+
+	// MaxMind country database used by the geoip block in proxy.yaml
+	GeoIPDatabase string
+
+	// Local mirror
+	MirrorDatabasePath    string
+	MirrorChangeRetention string
+
+	// Session cookie attributes; SessionMaxAge is also the token lifetime of remember-me logins
+	SessionSecret         string
+	SessionCookieSecure   string
+	SessionCookieSameSite string
+	SessionCookieDomain   string
+	SessionMaxAge         string
+
+	// Webhooks
+	NocoDBWebhookSecret   string
+	WebhookFanoutURLs     []string
+	WebhookDeadLetterPath string
+	SchemaWebhookURLs     []string
+
+	// Event publishing
+	EventsBackend       string
+	EventsURL           string
+	EventsTopicTemplate string
+	EventsSchema        string
 }
 
 func Load() *Config {
@@ -53,9 +191,67 @@ func Load() *Config {
 		NocoDBToken:  getEnv("NOCODB_TOKEN", "secret123"),
 		NocoDBBaseID: getEnv("NOCODB_BASE_ID", ""),
 
+		NocoDBTokenFile:  getEnv("NOCODB_TOKEN_FILE", ""),
+		NocoDBAPIVersion: getEnv("NOCODB_API_VERSION", "auto"),
+
+		MockNocoDBFixtures: getEnv("MOCK_NOCODB_FIXTURES", ""),
+
+		// Metadata refresh
+		MetaFetchConcurrency: getEnv("META_FETCH_CONCURRENCY", "8"),
+		MetaFetchTimeout:     getEnv("META_FETCH_TIMEOUT", "10s"),
+		MetaSnapshotPath:     getEnv("META_SNAPSHOT_PATH", "./metacache.json"),
+		MetaRefreshInterval:  getEnv("META_REFRESH_INTERVAL", "10m"),
+		MetaStore:            getEnv("META_STORE", "file"),
+		RedisURL:             getEnv("REDIS_URL", ""),
+		ResponseCacheStore:   getEnv("RESPONSE_CACHE_STORE", "memory"),
+
+		// Failover
+		NocoDBStandbyURL:     getEnv("NOCODB_STANDBY_URL", ""),
+		NocoDBHealthPath:     getEnv("NOCODB_HEALTH_PATH", "/api/v1/health"),
+		NocoDBHealthInterval: getEnv("NOCODB_HEALTH_INTERVAL", "10s"),
+
+		// Readiness
+		ReadyWeights:    getEnv("READY_WEIGHTS", ""),
+		ReadyMinScore:   getEnv("READY_MIN_SCORE", "100"),
+		ReadyMetaMaxAge: getEnv("READY_META_MAX_AGE", "30m"),
+
+		// Latency budgets
+		LatencyBudgetMax: getEnv("LATENCY_BUDGET_MAX", "30s"),
+
+		// Request body size limit
+		MaxRequestKB: getEnv("MAX_REQUEST_KB", "10240"),
+
+		// Response compression
+		Compression:         getEnv("COMPRESSION", "true"),
+		CompressionMinBytes: getEnv("COMPRESSION_MIN_BYTES", "1024"),
+		CompressionTypes:    getEnv("COMPRESSION_TYPES", "application/json,text/csv,text/plain"),
+		CompressionLevel:    getEnv("COMPRESSION_LEVEL", "5"),
+
+		// Upstream connection pool
+		UpstreamMaxIdleConns:          getEnv("UPSTREAM_MAX_IDLE_CONNS", "256"),
+		UpstreamMaxIdleConnsPerHost:   getEnv("UPSTREAM_MAX_IDLE_CONNS_PER_HOST", "64"),
+		UpstreamMaxConnsPerHost:       getEnv("UPSTREAM_MAX_CONNS_PER_HOST", "0"),
+		UpstreamIdleConnTimeout:       getEnv("UPSTREAM_IDLE_CONN_TIMEOUT", "90s"),
+		UpstreamDialTimeout:           getEnv("UPSTREAM_DIAL_TIMEOUT", "10s"),
+		UpstreamKeepAlive:             getEnv("UPSTREAM_KEEPALIVE", "30s"),
+		UpstreamTLSHandshakeTimeout:   getEnv("UPSTREAM_TLS_HANDSHAKE_TIMEOUT", "10s"),
+		UpstreamResponseHeaderTimeout: getEnv("UPSTREAM_RESPONSE_HEADER_TIMEOUT", "0"),
+
+		// Read replica
+		ReadNocoDBURL: getEnv("READ_NOCODB_URL", ""),
+
+		// Shadow traffic
+		ShadowNocoDBURL:   getEnv("SHADOW_NOCODB_URL", ""),
+		ShadowNocoDBToken: getEnv("SHADOW_NOCODB_TOKEN", ""),
+		ShadowPercent:     getEnv("SHADOW_PERCENT", "10"),
+		ShadowWrites:      getEnv("SHADOW_WRITES", "false"),
+
 		// JWT
 		JWTSecret: getEnv("JWT_SECRET", "myjwtsecret"),
 
+		// Share links
+		ShareLinkSecret: getEnv("SHARE_LINK_SECRET", ""),
+
 		// OAuth - Google
 		GoogleClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
 		GoogleClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
@@ -66,11 +262,78 @@ func Load() *Config {
 		GitHubClientSecret: getEnv("GITHUB_CLIENT_SECRET", ""),
 		GitHubCallbackURL:  getEnv("GITHUB_CALLBACK_URL", "http://localhost:8080/auth/github/callback"),
 
+		// WebAssembly filters
+		WasmFilterTimeout: getEnv("WASM_FILTER_TIMEOUT", "100ms"),
+
+		// Frontend
+		FrontendURL:               getEnv("FRONTEND_URL", "http://localhost:4321"),
+		FrontendRedirectAllowlist: getEnvList("FRONTEND_REDIRECT_ALLOWLIST"),
+		DeviceVerificationURL:     getEnv("DEVICE_VERIFICATION_URL", ""),
+
+		// Bot challenge
+		CaptchaProvider:   getEnv("CAPTCHA_PROVIDER", ""),
+		CaptchaSecret:     getEnv("CAPTCHA_SECRET", ""),
+		CaptchaSiteKey:    getEnv("CAPTCHA_SITE_KEY", ""),
+		CaptchaEndpoints:  getEnvList("CAPTCHA_ENDPOINTS"),
+		CaptchaDifficulty: getEnv("CAPTCHA_POW_DIFFICULTY", "18"),
+
+		// Breached password check
+		PasswordBreachCheck: getEnv("PASSWORD_BREACH_CHECK", "false"),
+		PwnedPasswordsURL:   getEnv("PWNED_PASSWORDS_URL", "https://api.pwnedpasswords.com"),
+
+		// Suspicious login detection
+		SuspiciousLogin: getEnv("SUSPICIOUS_LOGIN", "flag"),
+
+		// Outgoing email
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:     getEnv("SMTP_FROM", ""),
+
 		// Database
 		DatabasePath: getEnv("DATABASE_PATH", "./users.db"),
 
+		// Bootstrap admin
+		BootstrapAdminEmail:    getEnv("BOOTSTRAP_ADMIN_EMAIL", ""),
+		BootstrapAdminPassword: getEnv("BOOTSTRAP_ADMIN_PASSWORD", ""),
+		DemoUsers:              getEnv("DEMO_USERS", "true"),
+
+		// Token vault
+		TokenVaultKey: getEnv("TOKEN_VAULT_KEY", ""),
+
+		// State archives
+		StateArchiveKey: getEnv("STATE_ARCHIVE_KEY", ""),
+
+		FieldEncryptionKey:          getEnv("FIELD_ENCRYPTION_KEY", ""),
+		FieldEncryptionPreviousKeys: getEnvList("FIELD_ENCRYPTION_PREVIOUS_KEYS"),
+
+		UpstreamSigningSecret: getEnv("UPSTREAM_SIGNING_SECRET", ""),
+
+		GeoIPDatabase: getEnv("GEOIP_DATABASE", ""),
+
+		// Local mirror
+		MirrorDatabasePath:    getEnv("MIRROR_DATABASE_PATH", "./mirror.db"),
+		MirrorChangeRetention: getEnv("MIRROR_CHANGE_RETENTION", "720h"),
+
 		// Session
-		SessionSecret: getEnv("SESSION_SECRET", "session-secret-key"),
+		SessionSecret:         getEnv("SESSION_SECRET", "session-secret-key"),
+		SessionCookieSecure:   getEnv("SESSION_COOKIE_SECURE", "false"),
+		SessionCookieSameSite: getEnv("SESSION_COOKIE_SAMESITE", "lax"),
+		SessionCookieDomain:   getEnv("SESSION_COOKIE_DOMAIN", ""),
+		SessionMaxAge:         getEnv("SESSION_MAX_AGE", "720h"),
+
+		// Webhooks
+		NocoDBWebhookSecret:   getEnv("NOCODB_WEBHOOK_SECRET", ""),
+		WebhookFanoutURLs:     getEnvList("NOCODB_WEBHOOK_FANOUT_URLS"),
+		WebhookDeadLetterPath: getEnv("WEBHOOK_DEAD_LETTER_PATH", "./webhooks_deadletter.log"),
+		SchemaWebhookURLs:     getEnvList("SCHEMA_WEBHOOK_URLS"),
+
+		// Event publishing
+		EventsBackend:       getEnv("EVENTS_BACKEND", ""),
+		EventsURL:           getEnv("EVENTS_URL", "nats://localhost:4222"),
+		EventsTopicTemplate: getEnv("EVENTS_TOPIC_TEMPLATE", "gateway.{type}.{table}"),
+		EventsSchema:        getEnv("EVENTS_SCHEMA", "gateway"),
 	}
 }
 
@@ -81,6 +344,17 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList reads a comma-separated environment variable into a slice
+func getEnvList(key string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
 func (c *Config) MaskSecret(secret string) string {
 	if len(secret) <= 8 {
 		return "****"
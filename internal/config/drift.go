@@ -0,0 +1,80 @@
+package config
+
+import (
+	"sort"
+	"time"
+)
+
+// Drift lists what proxy.yaml references but NocoDB's schema does not have,
+// e.g. after a table or column was renamed in NocoDB
+type Drift struct {
+	MissingTables []string            `json:"missing_tables,omitempty"` // table keys whose NocoDB table was not found
+	MissingFields map[string][]string `json:"missing_fields,omitempty"` // table key -> fields, link fields and owner_field not found
+	CheckedAt     time.Time           `json:"checked_at"`
+}
+
+// FieldCount returns the number of missing fields across tables
+func (d Drift) FieldCount() int {
+	count := 0
+	for _, fields := range d.MissingFields {
+		count += len(fields)
+	}
+	return count
+}
+
+// Drift compares a configuration with the current metadata. Unlike Resolve it
+// does not stop at the first missing name, and it ignores strict mode.
+func (r *Resolver) Drift(config *ProxyConfig) Drift {
+	drift := Drift{MissingFields: make(map[string][]string), CheckedAt: time.Now()}
+
+	for tableKey, tableConfig := range config.Tables {
+		metaCache := r.metaCache
+		if tableConfig.Upstream != "" {
+			metaCache = r.upstreams[tableConfig.Upstream]
+		}
+		if metaCache == nil {
+			drift.MissingTables = append(drift.MissingTables, tableKey)
+			continue
+		}
+		tableID, ok := metaCache.ResolveTable(tableConfig.Name)
+		if !ok {
+			drift.MissingTables = append(drift.MissingTables, tableKey)
+			continue
+		}
+
+		referenced := make([]string, 0, len(tableConfig.Fields)+len(tableConfig.Links)+1)
+		for fieldName := range tableConfig.Fields {
+			referenced = append(referenced, fieldName)
+		}
+		for _, link := range tableConfig.Links {
+			referenced = append(referenced, link.Field)
+		}
+		if tableConfig.OwnerField != "" {
+			referenced = append(referenced, tableConfig.OwnerField)
+		}
+
+		var missing []string
+		for _, fieldName := range referenced {
+			if _, ok := metaCache.ResolveField(tableID, fieldName); !ok && !containsString(missing, fieldName) {
+				missing = append(missing, fieldName)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			drift.MissingFields[tableKey] = missing
+		}
+	}
+
+	sort.Strings(drift.MissingTables)
+	return drift
+}
+
+// containsString reports whether values holds value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
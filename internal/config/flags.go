@@ -0,0 +1,53 @@
+package config
+
+// FlagsConfig is the flags block of proxy.yaml. Unset flags take their default
+// (or the legacy environment variable that used to control them).
+type FlagsConfig struct {
+	// Treat proxy.yaml as the complete contract: field and link names that do not
+	// resolve fail resolution instead of being passed through as-is, and link
+	// requests must use links declared in proxy.yaml (default false)
+	StrictMode *bool `yaml:"strict_mode,omitempty"`
+	// Allow the built-in demo logins (default true, or DEMO_USERS)
+	DemoUsers *bool `yaml:"demo_users,omitempty"`
+	// Allow self-service account creation at /signup (default true)
+	Signup *bool `yaml:"signup,omitempty"`
+	// Require an admin token for /__proxy/status, /__proxy/schema, /__proxy/startup
+	// and /metrics (default false)
+	IntrospectionAuth *bool `yaml:"introspection_auth,omitempty"`
+	// Run proxy.yaml validation, query checks and rules without enforcing them:
+	// requests they would refuse are logged, counted and forwarded as in legacy
+	// mode (default false)
+	DryRun *bool `yaml:"dry_run,omitempty"`
+}
+
+// Flags are the resolved feature switches of the gateway
+type Flags struct {
+	StrictMode        bool `json:"strict_mode"`
+	DemoUsers         bool `json:"demo_users"`
+	Signup            bool `json:"signup"`
+	IntrospectionAuth bool `json:"introspection_auth"`
+	DryRun            bool `json:"dry_run"`
+}
+
+// ResolveFlags applies defaults and legacy environment settings to the flags
+// block. proxyConfig may be nil when the gateway runs without proxy.yaml.
+func ResolveFlags(proxyConfig *ProxyConfig, env *Config) Flags {
+	var block FlagsConfig
+	if proxyConfig != nil {
+		block = proxyConfig.Flags
+	}
+	return Flags{
+		StrictMode:        flagValue(block.StrictMode, false),
+		DemoUsers:         flagValue(block.DemoUsers, env.DemoUsers != "false"),
+		Signup:            flagValue(block.Signup, true),
+		IntrospectionAuth: flagValue(block.IntrospectionAuth, false),
+		DryRun:            flagValue(block.DryRun, false),
+	}
+}
+
+func flagValue(value *bool, fallback bool) bool {
+	if value == nil {
+		return fallback
+	}
+	return *value
+}
@@ -3,7 +3,10 @@ package config
 import (
 	"fmt"
 	"log"
+	"net"
 	"os"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -40,7 +43,251 @@ func validateConfig(config *ProxyConfig) error {
 		return fmt.Errorf("at least one table must be defined")
 	}
 
-	for tableName, table := range config.Tables {
+	for upstreamName, upstream := range config.Upstreams {
+		if upstream.URL == "" || upstream.BaseID == "" {
+			return fmt.Errorf("upstream '%s': url and base_id are required", upstreamName)
+		}
+	}
+
+	if !isValidEnvelope(config.Envelope) {
+		return fmt.Errorf("invalid envelope '%s' (expected nocodb, data or array)", config.Envelope)
+	}
+
+	if err := validateErrorMessages(config.Errors); err != nil {
+		return fmt.Errorf("errors: %w", err)
+	}
+
+	if err := validateHeaders(config.Headers); err != nil {
+		return fmt.Errorf("headers: %w", err)
+	}
+
+	if err := validateBodyLogging(config.BodyLogging); err != nil {
+		return fmt.Errorf("body_logging: %w", err)
+	}
+
+	if config.MaxResponse < 0 {
+		return fmt.Errorf("max_response_mb cannot be negative")
+	}
+
+	if config.MaxRequest < 0 {
+		return fmt.Errorf("max_request_kb cannot be negative")
+	}
+
+	if err := validateRetry(config.Retry); err != nil {
+		return fmt.Errorf("retry: %w", err)
+	}
+
+	if err := validateTimeouts(config.Timeouts); err != nil {
+		return fmt.Errorf("timeouts: %w", err)
+	}
+
+	if err := validateTables(config.Tables, config.Upstreams); err != nil {
+		return err
+	}
+
+	for baseName, base := range config.Bases {
+		if base.BaseID == "" {
+			return fmt.Errorf("base '%s': base_id is required", baseName)
+		}
+		if _, ok := config.Tables[baseName]; ok {
+			return fmt.Errorf("base '%s': name conflicts with a table of the default base", baseName)
+		}
+		if len(base.Tables) == 0 {
+			return fmt.Errorf("base '%s': at least one table must be defined", baseName)
+		}
+		if err := validateTables(base.Tables, nil); err != nil {
+			return fmt.Errorf("base '%s': %w", baseName, err)
+		}
+	}
+
+	for tenantName, tenant := range config.Tenants {
+		if tenant.BaseID == "" {
+			return fmt.Errorf("tenant '%s': base_id is required", tenantName)
+		}
+		if err := validateTables(tenant.Tables, nil); err != nil {
+			return fmt.Errorf("tenant '%s': %w", tenantName, err)
+		}
+	}
+
+	if len(config.Tenants) > 0 && len(config.Bases) > 0 {
+		return fmt.Errorf("bases and tenants cannot be combined")
+	}
+
+	if err := validateAliases(config.Aliases); err != nil {
+		return err
+	}
+
+	if err := validateQuotas(config.Quotas); err != nil {
+		return err
+	}
+
+	if err := validateGeoIP(config.GeoIP); err != nil {
+		return err
+	}
+
+	if err := validateAuthWebhooks(config.AuthWebhooks); err != nil {
+		return err
+	}
+
+	if err := validateAnonymous(config); err != nil {
+		return err
+	}
+
+	if err := validateCircuitBreaker(config.Breaker); err != nil {
+		return fmt.Errorf("circuit_breaker: %w", err)
+	}
+
+	if err := validateConcurrency(config); err != nil {
+		return err
+	}
+
+	if err := validateRecordCache(config); err != nil {
+		return err
+	}
+
+	if err := validateTableRates(config); err != nil {
+		return err
+	}
+
+	if err := validateRoleTokens(config.RoleTokens); err != nil {
+		return err
+	}
+
+	for alias, view := range config.PublicViews {
+		if alias == "" || strings.Contains(alias, "/") {
+			return fmt.Errorf("public view '%s': alias cannot be empty or contain '/'", alias)
+		}
+		if view.SharedView == "" {
+			return fmt.Errorf("public view '%s': shared_view is required", alias)
+		}
+		if view.CacheTTL != "" {
+			if ttl, err := time.ParseDuration(view.CacheTTL); err != nil || ttl < 0 {
+				return fmt.Errorf("public view '%s': invalid cache_ttl '%s'", alias, view.CacheTTL)
+			}
+		}
+		if view.RateLimit < 0 || view.Burst < 0 {
+			return fmt.Errorf("public view '%s': rate_limit and burst cannot be negative", alias)
+		}
+	}
+
+	enabled := make(map[string]bool, len(config.Plugins))
+	for i, plugin := range config.Plugins {
+		if plugin.Name == "" {
+			return fmt.Errorf("plugin %d: name is required", i)
+		}
+		if enabled[plugin.Name] {
+			return fmt.Errorf("plugin '%s' is listed twice", plugin.Name)
+		}
+		enabled[plugin.Name] = true
+	}
+
+	return nil
+}
+
+// validateQuotas rejects negative limits
+func validateQuotas(quotas *QuotaConfig) error {
+	if quotas == nil {
+		return nil
+	}
+	check := func(name string, q Quota) error {
+		if q.DailyRequests < 0 || q.MonthlyRequests < 0 || q.DailyBytes < 0 || q.MonthlyBytes < 0 {
+			return fmt.Errorf("quotas: %s: limits cannot be negative", name)
+		}
+		return nil
+	}
+	if quotas.Default != nil {
+		if err := check("default", *quotas.Default); err != nil {
+			return err
+		}
+	}
+	for role, q := range quotas.Roles {
+		if err := check("role '"+role+"'", q); err != nil {
+			return err
+		}
+	}
+	for user, q := range quotas.Users {
+		if err := check("user '"+user+"'", q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateGeoIP checks country codes, route groups and trusted proxy addresses
+func validateGeoIP(geo *GeoIPConfig) error {
+	if geo == nil {
+		return nil
+	}
+	check := func(name string, rule GeoIPRule) error {
+		for _, code := range append(append([]string{}, rule.Allow...), rule.Deny...) {
+			if len(code) != 2 {
+				return fmt.Errorf("geoip: %s: '%s' is not a two-letter country code", name, code)
+			}
+		}
+		if rule.Unknown != "" && rule.Unknown != "allow" && rule.Unknown != "deny" {
+			return fmt.Errorf("geoip: %s: unknown must be allow or deny", name)
+		}
+		return nil
+	}
+	if err := check("global", geo.GeoIPRule); err != nil {
+		return err
+	}
+	for group, rule := range geo.Groups {
+		if group != GeoIPGroupAuth && group != GeoIPGroupProxy && group != GeoIPGroupAdmin {
+			return fmt.Errorf("geoip: unknown route group '%s' (expected auth, proxy or admin)", group)
+		}
+		if err := check("group '"+group+"'", rule); err != nil {
+			return err
+		}
+	}
+	for _, proxy := range geo.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("geoip: trusted proxy '%s' is not an address or CIDR", proxy)
+		}
+	}
+	return nil
+}
+
+// validateAliases checks that every alias has a target and public names are unique
+func validateAliases(aliases AliasConfig) error {
+	seen := make(map[string]string)
+	for alias, title := range aliases.Tables {
+		if alias == "" || title == "" {
+			return fmt.Errorf("aliases.tables: alias and table title are required")
+		}
+		key := strings.ToLower(alias)
+		if other, ok := seen[key]; ok {
+			return fmt.Errorf("aliases.tables: '%s' and '%s' differ only in case", other, alias)
+		}
+		seen[key] = alias
+	}
+
+	for table, fields := range aliases.Fields {
+		seen := make(map[string]string)
+		for alias, title := range fields {
+			if alias == "" || title == "" {
+				return fmt.Errorf("aliases.fields.%s: alias and field title are required", table)
+			}
+			key := strings.ToLower(alias)
+			if other, ok := seen[key]; ok {
+				return fmt.Errorf("aliases.fields.%s: '%s' and '%s' differ only in case", table, other, alias)
+			}
+			seen[key] = alias
+		}
+	}
+	return nil
+}
+
+// validateTables validates the table definitions of a single base. Only the
+// default base may route tables to other upstreams.
+func validateTables(tables map[string]TableConfig, upstreams map[string]UpstreamConfig) error {
+	for tableName, table := range tables {
+		if table.Upstream != "" {
+			if _, ok := upstreams[table.Upstream]; !ok {
+				return fmt.Errorf("table '%s': unknown upstream '%s'", tableName, table.Upstream)
+			}
+		}
+
 		if table.Name == "" {
 			return fmt.Errorf("table '%s': name is required", tableName)
 		}
@@ -62,12 +309,621 @@ func validateConfig(config *ProxyConfig) error {
 			if link.TargetTable == "" {
 				return fmt.Errorf("table '%s', link '%s': target_table is required", tableName, linkName)
 			}
+			// Records are looked up by key through the target table's own route
+			if _, ok := tables[link.TargetTable]; link.KeyField != "" && !ok {
+				return fmt.Errorf("table '%s', link '%s': key_field needs target_table to be a table of this configuration, not '%s'", tableName, linkName, link.TargetTable)
+			}
+		}
+
+		if !isValidEnvelope(table.Envelope) {
+			return fmt.Errorf("table '%s': invalid envelope '%s' (expected nocodb, data or array)", tableName, table.Envelope)
+		}
+
+		if err := validateErrorMessages(table.Errors); err != nil {
+			return fmt.Errorf("table '%s': errors: %w", tableName, err)
+		}
+
+		if err := validateHeaders(table.Headers); err != nil {
+			return fmt.Errorf("table '%s': headers: %w", tableName, err)
+		}
+
+		if err := validateBodyLogging(table.BodyLogging); err != nil {
+			return fmt.Errorf("table '%s': body_logging: %w", tableName, err)
+		}
+
+		if table.MaxResponse < 0 {
+			return fmt.Errorf("table '%s': max_response_mb cannot be negative", tableName)
+		}
+
+		if table.MaxRequest < 0 {
+			return fmt.Errorf("table '%s': max_request_kb cannot be negative", tableName)
+		}
+
+		if err := validateRetry(table.Retry); err != nil {
+			return fmt.Errorf("table '%s': retry: %w", tableName, err)
+		}
+
+		if err := validateTimeouts(table.Timeouts); err != nil {
+			return fmt.Errorf("table '%s': timeouts: %w", tableName, err)
+		}
+
+		switch table.Mode {
+		case "", TableModeReadOnly, TableModeWriteOnly, TableModeDisabled:
+		default:
+			return fmt.Errorf("table '%s': invalid mode '%s' (expected read_only, write_only or disabled)", tableName, table.Mode)
+		}
+
+		if err := validateQuery(table.Query); err != nil {
+			return fmt.Errorf("table '%s': query: %w", tableName, err)
+		}
+
+		if table.Export && table.OwnerField == "" {
+			return fmt.Errorf("table '%s': export requires owner_field", tableName)
+		}
+
+		if table.Erase != nil {
+			if table.OwnerField == "" {
+				return fmt.Errorf("table '%s': erase requires owner_field", tableName)
+			}
+			switch table.Erase.Mode {
+			case EraseDelete:
+			case EraseAnonymize:
+				if len(table.Erase.Fields) == 0 {
+					return fmt.Errorf("table '%s': erase mode anonymize requires fields", tableName)
+				}
+			default:
+				return fmt.Errorf("table '%s': invalid erase.mode '%s' (expected anonymize or delete)", tableName, table.Erase.Mode)
+			}
+		}
+
+		if table.Encrypt != nil {
+			if len(table.Encrypt.Fields) == 0 {
+				return fmt.Errorf("table '%s': encrypt requires fields", tableName)
+			}
+			for _, field := range table.Encrypt.Fields {
+				if field == table.OwnerField {
+					return fmt.Errorf("table '%s': owner_field '%s' cannot be encrypted (rows are filtered by it)", tableName, field)
+				}
+			}
+		}
+
+		if table.CDC != nil && table.CDC.Interval != "" {
+			if _, err := time.ParseDuration(table.CDC.Interval); err != nil {
+				return fmt.Errorf("table '%s': invalid cdc.interval '%s': %w", tableName, table.CDC.Interval, err)
+			}
+		}
+
+		if table.Mirror != nil && table.Mirror.Interval != "" {
+			if _, err := time.ParseDuration(table.Mirror.Interval); err != nil {
+				return fmt.Errorf("table '%s': invalid mirror.interval '%s': %w", tableName, table.Mirror.Interval, err)
+			}
+		}
+
+		if table.Cache != nil {
+			if table.Cache.TTL != "" {
+				if ttl, err := time.ParseDuration(table.Cache.TTL); err != nil || ttl <= 0 {
+					return fmt.Errorf("table '%s': invalid cache.ttl '%s'", tableName, table.Cache.TTL)
+				}
+			}
+			if len(table.Cache.Warm) > 0 && !isReadable(table) {
+				return fmt.Errorf("table '%s': cache.warm requires the read operation", tableName)
+			}
+			for _, query := range table.Cache.Warm {
+				if !strings.HasPrefix(strings.TrimPrefix(query, "/"), "records") {
+					return fmt.Errorf("table '%s': cache.warm entry '%s' must start with records", tableName, query)
+				}
+			}
+		}
+
+		if table.Outbox != nil && table.Outbox.Mode != "" &&
+			table.Outbox.Mode != OutboxAlways && table.Outbox.Mode != OutboxOnOutage {
+			return fmt.Errorf("table '%s': invalid outbox.mode '%s'", tableName, table.Outbox.Mode)
+		}
+
+		if table.Search != nil && table.Search.Enabled && (table.Mirror == nil || !table.Mirror.Enabled) {
+			return fmt.Errorf("table '%s': search requires mirror to be enabled", tableName)
+		}
+
+		if err := validateShareLinks(table); err != nil {
+			return fmt.Errorf("table '%s': share_links: %w", tableName, err)
+		}
+
+		if table.Rules != nil {
+			if _, err := table.Rules.Compile(); err != nil {
+				return fmt.Errorf("table '%s': %w", tableName, err)
+			}
+		}
+
+		if _, err := CompileComputed(table.Computed); err != nil {
+			return fmt.Errorf("table '%s': %w", tableName, err)
+		}
+
+		for i, filter := range table.Filters {
+			if filter.Module == "" {
+				return fmt.Errorf("table '%s', wasm filter %d: module is required", tableName, i)
+			}
+		}
+
+		if err := validatePipeline(table); err != nil {
+			return fmt.Errorf("table '%s', %w", tableName, err)
+		}
+
+		if err := validateDefaultFields(table.DefaultFields); err != nil {
+			return fmt.Errorf("table '%s': default_fields: %w", tableName, err)
+		}
+
+		for i, hook := range table.Webhooks {
+			if hook.URL == "" {
+				return fmt.Errorf("table '%s', webhook %d: url is required", tableName, i)
+			}
+			for _, event := range hook.Events {
+				if event != "create" && event != "update" && event != "delete" {
+					return fmt.Errorf("table '%s', webhook %d: invalid event '%s'", tableName, i, event)
+				}
+			}
+			if !validWebhookFormat(hook.Format) {
+				return fmt.Errorf("table '%s', webhook %d: format must be event or slack", tableName, i)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateAnonymous only lets anonymous requests read tables that allow reads
+func validateAnonymous(config *ProxyConfig) error {
+	anonymous := config.Anonymous
+	if anonymous == nil {
+		return nil
+	}
+	if len(config.Tenants) > 0 {
+		return fmt.Errorf("anonymous: cannot be combined with tenants")
+	}
+	if len(anonymous.Tables) == 0 {
+		return fmt.Errorf("anonymous: at least one table must be listed")
+	}
+	if anonymous.RateLimit < 0 || anonymous.Burst < 0 {
+		return fmt.Errorf("anonymous: rate_limit and burst cannot be negative")
+	}
+	for name, access := range anonymous.Tables {
+		table, ok := config.Tables[name]
+		if !ok {
+			return fmt.Errorf("anonymous: table '%s' is not defined", name)
+		}
+		if !isReadable(table) {
+			return fmt.Errorf("anonymous: table '%s' does not allow read", name)
+		}
+		// The view and fields of anonymous reads are query parameters; dropping them would widen the reads
+		if table.Query != nil {
+			if access.View != "" && !queryAllows(table.Query, "viewId") {
+				return fmt.Errorf("anonymous: table '%s' pins a view, so its query block must allow viewId", name)
+			}
+			if len(access.Fields) > 0 && !queryAllows(table.Query, "fields") {
+				return fmt.Errorf("anonymous: table '%s' lists fields, so its query block must allow fields", name)
+			}
+		}
+	}
+	return nil
+}
+
+// validateCircuitBreaker checks the circuit breaker thresholds
+func validateCircuitBreaker(breaker *CircuitBreakerConfig) error {
+	if breaker == nil {
+		return nil
+	}
+	if breaker.ErrorRate <= 0 || breaker.ErrorRate > 100 {
+		return fmt.Errorf("error_rate must be a percentage above 0, up to 100")
+	}
+	if breaker.MinRequests < 0 {
+		return fmt.Errorf("min_requests cannot be negative")
+	}
+	for name, value := range map[string]string{"window": breaker.Window, "open_for": breaker.OpenFor, "slow_call": breaker.SlowCall} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return fmt.Errorf("invalid %s '%s'", name, value)
+		}
+	}
+	return nil
+}
+
+// validateConcurrency checks the upstream concurrency caps. Table caps apply
+// to the table of that name in every base.
+func validateConcurrency(config *ProxyConfig) error {
+	concurrency := config.Concurrency
+	if concurrency == nil {
+		return nil
+	}
+	if concurrency.MaxInFlight < 0 || concurrency.MaxQueue < 0 {
+		return fmt.Errorf("concurrency: max_in_flight and max_queue cannot be negative")
+	}
+	if concurrency.MaxInFlight == 0 && len(concurrency.Tables) == 0 {
+		return fmt.Errorf("concurrency: set max_in_flight or list tables")
+	}
+	if concurrency.QueueTimeout != "" {
+		if timeout, err := time.ParseDuration(concurrency.QueueTimeout); err != nil || timeout <= 0 {
+			return fmt.Errorf("concurrency: invalid queue_timeout '%s'", concurrency.QueueTimeout)
+		}
+	}
+	for name, limit := range concurrency.Tables {
+		if limit < 1 {
+			return fmt.Errorf("concurrency: table '%s' must allow at least 1 request", name)
+		}
+		if !tableDefined(config, name) {
+			return fmt.Errorf("concurrency: table '%s' is not defined", name)
+		}
+	}
+	return nil
+}
+
+// validateTableRates checks the request budgets of tables. Listed tables apply
+// to the table of that name in every base.
+func validateTableRates(config *ProxyConfig) error {
+	for name, rate := range config.TableRates {
+		if rate.RateLimit < 1 {
+			return fmt.Errorf("table_rate_limits: table '%s' must allow at least 1 request per minute", name)
+		}
+		if rate.Burst < 0 {
+			return fmt.Errorf("table_rate_limits: table '%s' cannot have a negative burst", name)
+		}
+		if !tableDefined(config, name) {
+			return fmt.Errorf("table_rate_limits: table '%s' is not defined", name)
+		}
+	}
+	return nil
+}
+
+// validateShareLinks checks a table's share links. Encrypted fields are never
+// shown through a link.
+func validateShareLinks(table TableConfig) error {
+	links := table.ShareLinks
+	if links == nil || !links.Enabled {
+		return nil
+	}
+	if !isReadable(table) {
+		return fmt.Errorf("requires the read operation")
+	}
+	if links.MaxTTL != "" {
+		if ttl, err := time.ParseDuration(links.MaxTTL); err != nil || ttl <= 0 {
+			return fmt.Errorf("invalid max_ttl '%s'", links.MaxTTL)
+		}
+	}
+	for _, field := range links.Fields {
+		if field == "" {
+			return fmt.Errorf("field names cannot be empty")
+		}
+		if table.Encrypt != nil && containsString(table.Encrypt.Fields, field) {
+			return fmt.Errorf("field '%s' is encrypted and cannot be shared", field)
+		}
+	}
+	return nil
+}
+
+// validatePipeline checks the steps of a table's pipeline. Search would find
+// records by the values of hidden fields, so they must be left out of it.
+func validatePipeline(table TableConfig) error {
+	for i, step := range table.Pipeline {
+		if err := validatePipelineStep(step); err != nil {
+			return fmt.Errorf("pipeline step %d: %w", i, err)
+		}
+		if !step.IsResponseStep() || table.Search == nil || !table.Search.Enabled {
+			continue
+		}
+		for _, field := range step.Fields {
+			if len(table.Search.Fields) == 0 || containsString(table.Search.Fields, field) {
+				return fmt.Errorf("pipeline step %d: search would match the hidden field '%s'; list search.fields without it", i, field)
+			}
+		}
+	}
+	return nil
+}
+
+// validatePipelineStep checks a step of a table's pipeline
+func validatePipelineStep(step PipelineStep) error {
+	switch step.Step {
+	case StepDefaults, StepSet:
+		if len(step.Values) == 0 {
+			return fmt.Errorf("%s requires values", step.Step)
+		}
+		if len(step.Fields) > 0 || step.KeepLast != 0 {
+			return fmt.Errorf("%s takes values, not fields", step.Step)
+		}
+		for field := range step.Values {
+			if field == "" {
+				return fmt.Errorf("field names cannot be empty")
+			}
+		}
+	case StepMask, StepRemove:
+		if len(step.Fields) == 0 {
+			return fmt.Errorf("%s requires fields", step.Step)
+		}
+		if len(step.Values) > 0 {
+			return fmt.Errorf("%s takes fields, not values", step.Step)
+		}
+		if step.KeepLast < 0 || (step.KeepLast > 0 && step.Step != StepMask) {
+			return fmt.Errorf("keep_last must be a positive number of characters and is only valid for mask")
+		}
+		for _, field := range step.Fields {
+			if field == "" {
+				return fmt.Errorf("field names cannot be empty")
+			}
+		}
+	case "":
+		return fmt.Errorf("step is required")
+	default:
+		return fmt.Errorf("unknown step '%s' (expected defaults, set, mask or remove)", step.Step)
+	}
+	return nil
+}
+
+// validateDefaultFields checks the default field sets of a table's roles
+func validateDefaultFields(defaults map[string][]string) error {
+	for role, fields := range defaults {
+		if role == "" {
+			return fmt.Errorf("role names cannot be empty")
+		}
+		if len(fields) == 0 {
+			return fmt.Errorf("role '%s' lists no fields; leave it out to read every field", role)
+		}
+		for _, field := range fields {
+			if field == "" || strings.Contains(field, ",") {
+				return fmt.Errorf("role '%s': invalid field name '%s'", role, field)
+			}
 		}
 	}
+	return nil
+}
 
+// validateRoleTokens checks the role -> token mapping. The token itself is
+// looked up at startup, since it may live in the token vault.
+func validateRoleTokens(tokens map[string]RoleToken) error {
+	for role, token := range tokens {
+		if role == "" {
+			return fmt.Errorf("role_tokens: role cannot be empty")
+		}
+		if strings.TrimSpace(token.TokenEnv) != token.TokenEnv {
+			return fmt.Errorf("role_tokens: role '%s' has an invalid token_env '%s'", role, token.TokenEnv)
+		}
+	}
 	return nil
 }
 
+// validateRecordCache checks the single-record cache. Listed tables apply to
+// the table of that name in every base.
+func validateRecordCache(config *ProxyConfig) error {
+	cache := config.RecordCache
+	if cache == nil {
+		return nil
+	}
+	if cache.MaxMemoryMB < 0 {
+		return fmt.Errorf("record_cache: max_memory_mb cannot be negative")
+	}
+	if cache.TTL != "" {
+		if ttl, err := time.ParseDuration(cache.TTL); err != nil || ttl <= 0 {
+			return fmt.Errorf("record_cache: invalid ttl '%s'", cache.TTL)
+		}
+	}
+	for _, name := range cache.Tables {
+		if !tableDefined(config, name) {
+			return fmt.Errorf("record_cache: table '%s' is not defined", name)
+		}
+	}
+	return nil
+}
+
+// validateErrorMessages checks the kinds and translations of an errors block
+func validateErrorMessages(messages ErrorMessages) error {
+	for kind, message := range messages {
+		known := false
+		for _, name := range ErrorKinds {
+			known = known || kind == name
+		}
+		if !known {
+			return fmt.Errorf("unknown kind '%s' (expected %s)", kind, strings.Join(ErrorKinds, ", "))
+		}
+		if len(message) == 0 {
+			return fmt.Errorf("%s: message is empty", kind)
+		}
+		for language, text := range message {
+			if text == "" {
+				return fmt.Errorf("%s: empty %s message", kind, language)
+			}
+		}
+	}
+	return nil
+}
+
+// validateHeaders checks the header names of a header policy
+func validateHeaders(headers *HeadersConfig) error {
+	if headers == nil {
+		return nil
+	}
+	for direction, rules := range map[string]*HeaderRules{"request": headers.Request, "response": headers.Response} {
+		if rules == nil {
+			continue
+		}
+		for _, name := range append(append([]string{}, rules.Allow...), rules.Deny...) {
+			if strings.TrimSuffix(name, "*") == "" || strings.ContainsAny(name, " :\t") {
+				return fmt.Errorf("%s: invalid header name '%s'", direction, name)
+			}
+		}
+	}
+	return nil
+}
+
+// validateBodyLogging checks a body logging policy
+func validateBodyLogging(logging *BodyLoggingConfig) error {
+	if logging == nil {
+		return nil
+	}
+	modes := map[string]string{"mode": logging.Mode}
+	for class, mode := range logging.Status {
+		known := false
+		for _, name := range BodyLogStatusClasses {
+			known = known || class == name
+		}
+		if !known {
+			return fmt.Errorf("unknown status class '%s' (expected 2xx, 3xx, 4xx or 5xx)", class)
+		}
+		modes["status "+class] = mode
+	}
+	for field, mode := range modes {
+		switch mode {
+		case "", BodyLogOff, BodyLogSampled, BodyLogFull:
+		default:
+			return fmt.Errorf("%s: invalid mode '%s' (expected off, sampled or full)", field, mode)
+		}
+	}
+	if logging.SampleRate < 0 || logging.SampleRate > 1 {
+		return fmt.Errorf("sample_rate must be between 0 and 1")
+	}
+	if logging.MaxBytes < 0 {
+		return fmt.Errorf("max_bytes cannot be negative")
+	}
+	for _, field := range logging.Redact {
+		if field == "" {
+			return fmt.Errorf("redact: field names cannot be empty")
+		}
+	}
+	return nil
+}
+
+// validateRetry checks a retry policy
+func validateRetry(retry *RetryConfig) error {
+	if retry == nil {
+		return nil
+	}
+	if retry.Attempts < 0 || retry.Attempts > 10 {
+		return fmt.Errorf("attempts must be between 1 and 10")
+	}
+	for name, value := range map[string]string{"backoff": retry.Backoff, "max_backoff": retry.MaxBackoff} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return fmt.Errorf("invalid %s '%s'", name, value)
+		}
+	}
+	for _, status := range retry.Status {
+		if status < 500 && status != 408 && status != 429 {
+			return fmt.Errorf("status %d cannot be retried (expected 408, 429 or 5xx)", status)
+		}
+		if status > 599 {
+			return fmt.Errorf("invalid status %d", status)
+		}
+	}
+	return nil
+}
+
+// validateTimeouts checks upstream timeouts
+func validateTimeouts(timeouts *TimeoutConfig) error {
+	if timeouts == nil {
+		return nil
+	}
+	for name, value := range map[string]string{"default": timeouts.Default, "read": timeouts.Read, "create": timeouts.Create,
+		"update": timeouts.Update, "delete": timeouts.Delete, "link": timeouts.Link} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return fmt.Errorf("invalid %s timeout '%s'", name, value)
+		}
+	}
+	return nil
+}
+
+// validateQuery checks a table's query block
+func validateQuery(query *QueryConfig) error {
+	if query == nil {
+		return nil
+	}
+	if len(query.Allow) == 0 {
+		return fmt.Errorf("allow is required")
+	}
+	switch query.Unknown {
+	case "", QueryUnknownReject, QueryUnknownStrip:
+	default:
+		return fmt.Errorf("invalid unknown '%s' (expected reject or strip)", query.Unknown)
+	}
+	for _, operator := range query.Operators {
+		known := false
+		for _, name := range WhereOperators {
+			known = known || operator == name
+		}
+		if !known {
+			return fmt.Errorf("unknown where operator '%s'", operator)
+		}
+	}
+	return nil
+}
+
+func queryAllows(query *QueryConfig, param string) bool {
+	for _, name := range query.Allow {
+		if name == param {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidEnvelope reports whether an envelope setting is known; empty selects the default
+func isValidEnvelope(envelope string) bool {
+	switch envelope {
+	case "", EnvelopeNocoDB, EnvelopeData, EnvelopeArray:
+		return true
+	}
+	return false
+}
+
+// tableDefined reports whether a table of that name exists in any base
+func tableDefined(config *ProxyConfig, name string) bool {
+	if _, ok := config.Tables[name]; ok {
+		return true
+	}
+	for _, base := range config.Bases {
+		if _, ok := base.Tables[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isReadable(table TableConfig) bool {
+	for _, op := range table.Operations {
+		if op == "read" {
+			return true
+		}
+	}
+	return false
+}
+
+// validateAuthWebhooks checks the targets notified of auth events
+func validateAuthWebhooks(targets []WebhookTarget) error {
+	known := make(map[string]bool, len(AuthEvents))
+	for _, event := range AuthEvents {
+		known[event] = true
+	}
+	for i, hook := range targets {
+		if hook.URL == "" {
+			return fmt.Errorf("auth webhook %d: url is required", i)
+		}
+		for _, event := range hook.Events {
+			if !known[event] {
+				return fmt.Errorf("auth webhook %d: invalid event '%s' (expected one of %s)", i, event, strings.Join(AuthEvents, ", "))
+			}
+		}
+		if !validWebhookFormat(hook.Format) {
+			return fmt.Errorf("auth webhook %d: format must be event or slack", i)
+		}
+	}
+	return nil
+}
+
+func validWebhookFormat(format string) bool {
+	return format == "" || format == WebhookFormatEvent || format == WebhookFormatSlack
+}
+
 // isValidOperation checks if an operation is valid
 func isValidOperation(op string) bool {
 	validOps := map[string]bool{
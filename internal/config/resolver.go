@@ -14,15 +14,22 @@ type MetaCacheInterface interface {
 // Resolver resolves human-readable names to NocoDB IDs using MetaCache
 type Resolver struct {
 	metaCache MetaCacheInterface
+	upstreams map[string]MetaCacheInterface
 }
 
 // NewResolver creates a new resolver with the given MetaCache
 func NewResolver(metaCache MetaCacheInterface) *Resolver {
 	return &Resolver{
 		metaCache: metaCache,
+		upstreams: make(map[string]MetaCacheInterface),
 	}
 }
 
+// SetUpstreamMetaCache sets the MetaCache used for tables routed to a named upstream
+func (r *Resolver) SetUpstreamMetaCache(name string, metaCache MetaCacheInterface) {
+	r.upstreams[name] = metaCache
+}
+
 // Resolve takes a ProxyConfig and resolves all names to IDs
 func (r *Resolver) Resolve(config *ProxyConfig) (*ResolvedConfig, error) {
 	log.Printf("[RESOLVER] Starting resolution of proxy configuration...")
@@ -30,12 +37,25 @@ func (r *Resolver) Resolve(config *ProxyConfig) (*ResolvedConfig, error) {
 	resolved := &ResolvedConfig{
 		BaseID: config.NocoDB.BaseID,
 		Tables: make(map[string]ResolvedTable),
+		Strict: flagValue(config.Flags.StrictMode, false),
+		Errors: config.Errors,
+
+		MaxRequest: config.MaxRequest,
 	}
 
 	for tableKey, tableConfig := range config.Tables {
 		log.Printf("[RESOLVER] Resolving table: %s (name: %s)", tableKey, tableConfig.Name)
 
-		tableID, ok := r.metaCache.ResolveTable(tableConfig.Name)
+		metaCache := r.metaCache
+		if tableConfig.Upstream != "" {
+			upstreamCache, ok := r.upstreams[tableConfig.Upstream]
+			if !ok {
+				return nil, fmt.Errorf("no metadata available for upstream '%s' of table '%s'", tableConfig.Upstream, tableKey)
+			}
+			metaCache = upstreamCache
+		}
+
+		tableID, ok := metaCache.ResolveTable(tableConfig.Name)
 		if !ok {
 			return nil, fmt.Errorf("failed to resolve table '%s' to ID", tableConfig.Name)
 		}
@@ -48,11 +68,75 @@ func (r *Resolver) Resolve(config *ProxyConfig) (*ResolvedConfig, error) {
 			Operations: tableConfig.Operations,
 			Fields:     make(map[string]string),
 			Links:      make(map[string]ResolvedLink),
+			Webhooks:   tableConfig.Webhooks,
+			CDC:        tableConfig.CDC,
+			OwnerField: tableConfig.OwnerField,
+			Export:     tableConfig.Export,
+			Erase:      tableConfig.Erase,
+			Encrypt:    tableConfig.Encrypt,
+			Mirror:     tableConfig.Mirror,
+			Cache:      tableConfig.Cache,
+			Outbox:     tableConfig.Outbox,
+			Search:     tableConfig.Search,
+			ShareLinks: tableConfig.ShareLinks,
+			Upstream:   tableConfig.Upstream,
+			Filters:    tableConfig.Filters,
+			Pipeline:   tableConfig.Pipeline,
+			Envelope:   tableConfig.Envelope,
+			Query:      tableConfig.Query,
+			Mode:       tableConfig.Mode,
+		}
+		if resolvedTable.Envelope == "" {
+			resolvedTable.Envelope = config.Envelope
+		}
+		resolvedTable.MaxResponse = tableConfig.MaxResponse
+		if resolvedTable.MaxResponse == 0 {
+			resolvedTable.MaxResponse = config.MaxResponse
+		}
+		resolvedTable.MaxRequest = tableConfig.MaxRequest
+		if resolvedTable.MaxRequest == 0 {
+			resolvedTable.MaxRequest = config.MaxRequest
+		}
+		if len(tableConfig.Errors) > 0 {
+			resolvedTable.Errors = make(ErrorMessages, len(config.Errors)+len(tableConfig.Errors))
+			for kind, message := range config.Errors {
+				resolvedTable.Errors[kind] = message
+			}
+			for kind, message := range tableConfig.Errors {
+				resolvedTable.Errors[kind] = message
+			}
+		} else {
+			resolvedTable.Errors = config.Errors
+		}
+		resolvedTable.Headers = mergeHeaders(config.Headers, tableConfig.Headers)
+		resolvedTable.BodyLogging = mergeBodyLogging(config.BodyLogging, tableConfig.BodyLogging)
+		resolvedTable.Timeouts = mergeTimeouts(config.Timeouts, tableConfig.Timeouts)
+		resolvedTable.DefaultFields = tableConfig.DefaultFields
+		resolvedTable.Retry = tableConfig.Retry
+		if resolvedTable.Retry == nil {
+			resolvedTable.Retry = config.Retry
+		}
+		if tableConfig.Rules != nil {
+			rules, err := tableConfig.Rules.Compile()
+			if err != nil {
+				return nil, fmt.Errorf("table '%s': %w", tableKey, err)
+			}
+			resolvedTable.Rules = rules
+		}
+		if len(tableConfig.Computed) > 0 {
+			computed, err := CompileComputed(tableConfig.Computed)
+			if err != nil {
+				return nil, fmt.Errorf("table '%s': %w", tableKey, err)
+			}
+			resolvedTable.Computed = computed
 		}
 
 		// Resolve field names to IDs
 		for fieldName, fieldAlias := range tableConfig.Fields {
-			fieldID, ok := r.metaCache.ResolveField(tableID, fieldName)
+			fieldID, ok := metaCache.ResolveField(tableID, fieldName)
+			if !ok && resolved.Strict {
+				return nil, fmt.Errorf("failed to resolve field '%s' in table '%s' (strict mode)", fieldName, tableConfig.Name)
+			}
 			if !ok {
 				log.Printf("[RESOLVER WARN] Failed to resolve field '%s' in table '%s', using as-is", fieldName, tableConfig.Name)
 				fieldID = fieldName
@@ -64,7 +148,10 @@ func (r *Resolver) Resolve(config *ProxyConfig) (*ResolvedConfig, error) {
 
 		// Resolve link field names to IDs
 		for linkName, link := range tableConfig.Links {
-			fieldID, ok := r.metaCache.ResolveField(tableID, link.Field)
+			fieldID, ok := metaCache.ResolveField(tableID, link.Field)
+			if !ok && resolved.Strict {
+				return nil, fmt.Errorf("failed to resolve link field '%s' in table '%s' (strict mode)", link.Field, tableConfig.Name)
+			}
 			if !ok {
 				log.Printf("[RESOLVER WARN] Failed to resolve link field '%s' in table '%s', using as-is", link.Field, tableConfig.Name)
 				fieldID = link.Field
@@ -75,6 +162,7 @@ func (r *Resolver) Resolve(config *ProxyConfig) (*ResolvedConfig, error) {
 			resolvedTable.Links[linkName] = ResolvedLink{
 				FieldID:     fieldID,
 				TargetTable: link.TargetTable,
+				KeyField:    link.KeyField,
 			}
 		}
 
@@ -84,3 +172,82 @@ func (r *Resolver) Resolve(config *ProxyConfig) (*ResolvedConfig, error) {
 	log.Printf("[RESOLVER] Successfully resolved %d tables", len(resolved.Tables))
 	return resolved, nil
 }
+
+// mergeHeaders returns a table's header policy, taking each direction the table
+// does not set from the top-level policy
+func mergeHeaders(global, table *HeadersConfig) *HeadersConfig {
+	if table == nil {
+		return global
+	}
+	if global == nil {
+		return table
+	}
+	merged := *table
+	if merged.Request == nil {
+		merged.Request = global.Request
+	}
+	if merged.Response == nil {
+		merged.Response = global.Response
+	}
+	return &merged
+}
+
+// mergeTimeouts returns a table's upstream timeouts: the operations the table
+// sets override the top-level ones. A table's default replaces every top-level
+// timeout, so that an operation falls back to the table's default first.
+func mergeTimeouts(global, table *TimeoutConfig) *TimeoutConfig {
+	if table == nil {
+		return global
+	}
+	if global == nil || table.Default != "" {
+		return table
+	}
+	merged := *global
+	if table.Read != "" {
+		merged.Read = table.Read
+	}
+	if table.Create != "" {
+		merged.Create = table.Create
+	}
+	if table.Update != "" {
+		merged.Update = table.Update
+	}
+	if table.Delete != "" {
+		merged.Delete = table.Delete
+	}
+	if table.Link != "" {
+		merged.Link = table.Link
+	}
+	return &merged
+}
+
+// mergeBodyLogging returns a table's body logging policy: the fields and status
+// classes the table sets override the top-level ones, and the redacted fields of
+// both apply
+func mergeBodyLogging(global, table *BodyLoggingConfig) *BodyLoggingConfig {
+	if table == nil {
+		return global
+	}
+	if global == nil {
+		return table
+	}
+	merged := *global
+	if table.Mode != "" {
+		merged.Mode = table.Mode
+	}
+	if table.SampleRate != 0 {
+		merged.SampleRate = table.SampleRate
+	}
+	if table.MaxBytes != 0 {
+		merged.MaxBytes = table.MaxBytes
+	}
+	merged.Status = make(map[string]string, len(global.Status)+len(table.Status))
+	for class, mode := range global.Status {
+		merged.Status[class] = mode
+	}
+	for class, mode := range table.Status {
+		merged.Status[class] = mode
+	}
+	merged.Redact = append(append([]string{}, global.Redact...), table.Redact...)
+	return &merged
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+	"github.com/grove/generic-proxy/internal/i18n"
+)
+
+// RuleEnv is what rule expressions can see
+type RuleEnv struct {
+	UserID    string
+	Role      string
+	Tenant    string
+	Method    string
+	Operation string // read, create, update, delete or link
+	Table     string // proxy.yaml table key
+	Query     map[string]string
+	Body      map[string]interface{} // fields of one record; nil for requests without a body
+}
+
+func (e RuleEnv) vars() map[string]interface{} {
+	body := e.Body
+	if body == nil {
+		body = map[string]interface{}{}
+	}
+	query := e.Query
+	if query == nil {
+		query = map[string]string{}
+	}
+	return map[string]interface{}{
+		"user":      map[string]string{"id": e.UserID, "role": e.Role, "tenant": e.Tenant},
+		"method":    e.Method,
+		"operation": e.Operation,
+		"table":     e.Table,
+		"query":     query,
+		"body":      body,
+	}
+}
+
+// RuleError is a request refused by a reject rule
+type RuleError struct {
+	Status       int
+	Message      string    // the default message
+	Translations i18n.Text // the rule's message in every configured language; nil when the rule has none
+}
+
+func (e *RuleError) Error() string { return e.Message }
+
+// CompiledRules is a table's rules block, compiled once when the configuration
+// is resolved
+type CompiledRules struct {
+	reject   []compiledReject
+	defaults []compiledDefault // sorted by field for a stable evaluation order
+	filter   *vm.Program
+}
+
+type compiledReject struct {
+	rule    RejectRule
+	program *vm.Program
+}
+
+type compiledDefault struct {
+	field   string
+	program *vm.Program
+}
+
+// Compile type-checks and compiles every expression of the rules block
+func (r *RulesConfig) Compile() (*CompiledRules, error) {
+	env := RuleEnv{}.vars()
+	compiled := &CompiledRules{}
+
+	for i, rule := range r.Reject {
+		if rule.When == "" {
+			return nil, fmt.Errorf("rules.reject %d: when is required", i)
+		}
+		if rule.Status != 0 && (rule.Status < 400 || rule.Status > 599) {
+			return nil, fmt.Errorf("rules.reject %d: status must be 4xx or 5xx", i)
+		}
+		program, err := expr.Compile(rule.When, expr.Env(env), expr.AsBool())
+		if err != nil {
+			return nil, fmt.Errorf("rules.reject %d: %w", i, err)
+		}
+		compiled.reject = append(compiled.reject, compiledReject{rule: rule, program: program})
+	}
+
+	fields := make([]string, 0, len(r.Defaults))
+	for field := range r.Defaults {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		program, err := expr.Compile(r.Defaults[field], expr.Env(env))
+		if err != nil {
+			return nil, fmt.Errorf("rules.defaults.%s: %w", field, err)
+		}
+		compiled.defaults = append(compiled.defaults, compiledDefault{field: field, program: program})
+	}
+
+	if r.Filter != "" {
+		program, err := expr.Compile(r.Filter, expr.Env(env), expr.AsKind(reflect.String))
+		if err != nil {
+			return nil, fmt.Errorf("rules.filter: %w", err)
+		}
+		compiled.filter = program
+	}
+	return compiled, nil
+}
+
+// Check returns a *RuleError for the first reject rule that matches. A rule
+// that cannot be evaluated (e.g. comparing a missing field) also rejects.
+func (c *CompiledRules) Check(env RuleEnv) error {
+	vars := env.vars()
+	for _, reject := range c.reject {
+		matched, err := expr.Run(reject.program, vars)
+		if err != nil {
+			return &RuleError{Status: http.StatusBadRequest, Message: fmt.Sprintf("rule '%s' could not be evaluated: %v", reject.rule.When, err)}
+		}
+		if matched == true {
+			status := reject.rule.Status
+			if status == 0 {
+				status = http.StatusForbidden
+			}
+			message, _ := reject.rule.Message.Localize("")
+			if message == "" {
+				message = "request rejected by rule: " + reject.rule.When
+			}
+			return &RuleError{Status: status, Message: message, Translations: reject.rule.Message}
+		}
+	}
+	return nil
+}
+
+// HasDefaults reports whether created records get computed defaults
+func (c *CompiledRules) HasDefaults() bool {
+	return len(c.defaults) > 0
+}
+
+// ApplyDefaults sets every missing (or null) field of env.Body that has a default
+func (c *CompiledRules) ApplyDefaults(env RuleEnv) error {
+	for _, def := range c.defaults {
+		if value, ok := env.Body[def.field]; ok && value != nil {
+			continue
+		}
+		value, err := expr.Run(def.program, env.vars())
+		if err != nil {
+			return fmt.Errorf("default for '%s': %w", def.field, err)
+		}
+		env.Body[def.field] = value
+	}
+	return nil
+}
+
+// Filter returns the where clause to add to reads, empty when none applies
+func (c *CompiledRules) Filter(env RuleEnv) (string, error) {
+	if c.filter == nil {
+		return "", nil
+	}
+	where, err := expr.Run(c.filter, env.vars())
+	if err != nil {
+		return "", fmt.Errorf("filter: %w", err)
+	}
+	return where.(string), nil
+}
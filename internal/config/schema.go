@@ -1,9 +1,347 @@
 package config
 
+import (
+	"time"
+
+	"github.com/grove/generic-proxy/internal/i18n"
+)
+
 // ProxyConfig represents the complete schema-driven configuration
 type ProxyConfig struct {
-	NocoDB NocoDBConfig           `yaml:"nocodb"`
-	Tables map[string]TableConfig `yaml:"tables"`
+	NocoDB    NocoDBConfig              `yaml:"nocodb"`
+	Tables    map[string]TableConfig    `yaml:"tables"`
+	Bases     map[string]BaseConfig     `yaml:"bases,omitempty"`
+	Tenants   map[string]TenantConfig   `yaml:"tenants,omitempty"`
+	Upstreams map[string]UpstreamConfig `yaml:"upstreams,omitempty"`
+	Aliases   AliasConfig               `yaml:"aliases,omitempty"`
+	Flags     FlagsConfig               `yaml:"flags,omitempty"`
+	Plugins   []PluginConfig            `yaml:"plugins,omitempty"`
+	Quotas    *QuotaConfig              `yaml:"quotas,omitempty"`
+	GeoIP     *GeoIPConfig              `yaml:"geoip,omitempty"`
+	Anonymous *AnonymousConfig          `yaml:"anonymous,omitempty"`
+
+	Concurrency *ConcurrencyConfig    `yaml:"concurrency,omitempty"`
+	Breaker     *CircuitBreakerConfig `yaml:"circuit_breaker,omitempty"`
+	TableRates  map[string]TableRate  `yaml:"table_rate_limits,omitempty"` // table -> request budget shared by all users
+	RoleTokens  map[string]RoleToken  `yaml:"role_tokens,omitempty"`       // role -> NocoDB token its requests are sent with
+	RecordCache *RecordCacheConfig    `yaml:"record_cache,omitempty"`
+	Envelope    string                `yaml:"envelope,omitempty"`        // default response envelope of every table
+	Errors      ErrorMessages         `yaml:"errors,omitempty"`          // default error messages of every table
+	Headers     *HeadersConfig        `yaml:"headers,omitempty"`         // default header policy of every table
+	BodyLogging *BodyLoggingConfig    `yaml:"body_logging,omitempty"`    // default body logging policy of every table
+	MaxResponse int                   `yaml:"max_response_mb,omitempty"` // default upstream response size limit of every table (0 = none)
+	MaxRequest  int                   `yaml:"max_request_kb,omitempty"`  // default request body size limit of every table (0 = none)
+	Retry       *RetryConfig          `yaml:"retry,omitempty"`           // default retry policy of every table
+	Timeouts    *TimeoutConfig        `yaml:"timeouts,omitempty"`        // default upstream timeouts of every table
+
+	PublicViews map[string]PublicView `yaml:"public_views,omitempty"` // alias -> shared view
+
+	AuthWebhooks []WebhookTarget `yaml:"auth_webhooks,omitempty"`
+}
+
+// Events of auth_webhooks targets
+const (
+	AuthEventSignup          = "signup"
+	AuthEventLogin           = "login" // every successful login, admin ones included
+	AuthEventAdminLogin      = "admin_login"
+	AuthEventLoginFailed     = "login_failed"
+	AuthEventLoginChallenged = "login_challenged"
+	AuthEventLockout         = "lockout"
+	AuthEventRoleChange      = "role_change"
+	AuthEventAccountDeleted  = "account_deleted"
+)
+
+// AuthEvents lists the events an auth_webhooks target can subscribe to
+var AuthEvents = []string{
+	AuthEventSignup, AuthEventLogin, AuthEventAdminLogin, AuthEventLoginFailed,
+	AuthEventLoginChallenged, AuthEventLockout, AuthEventRoleChange, AuthEventAccountDeleted,
+}
+
+// AnonymousRole is the role (and user ID) of requests served without a token
+const AnonymousRole = "anonymous"
+
+// AnonymousConfig lets requests without a token read the listed tables of the
+// default base (see internal/anonymous). Expression rules and quotas see them
+// as the anonymous role.
+type AnonymousConfig struct {
+	Tables    map[string]AnonymousTable `yaml:"tables"`
+	RateLimit int                       `yaml:"rate_limit,omitempty"` // requests per minute per client address (default 30)
+	Burst     int                       `yaml:"burst,omitempty"`      // requests a client may make at once (default 10)
+}
+
+// AnonymousTable narrows what anonymous requests may read from a table
+type AnonymousTable struct {
+	View   string   `yaml:"view,omitempty"`   // NocoDB view ID every anonymous read is pinned to
+	Fields []string `yaml:"fields,omitempty"` // the only fields returned (NocoDB field titles)
+}
+
+// PublicView exposes a NocoDB shared view at /public/{alias} without gateway
+// authentication (see internal/publicview)
+type PublicView struct {
+	SharedView  string   `yaml:"shared_view"`            // UUID from the view's NocoDB share link
+	PasswordEnv string   `yaml:"password_env,omitempty"` // env var holding the share password, if any
+	Fields      []string `yaml:"fields,omitempty"`       // only these fields are returned (default: all the view shows)
+	CacheTTL    string   `yaml:"cache_ttl,omitempty"`    // default: 60s, "0s" disables caching
+	RateLimit   int      `yaml:"rate_limit,omitempty"`   // requests per minute per client address (default 60)
+	Burst       int      `yaml:"burst,omitempty"`        // default: 20
+}
+
+// ConcurrencyConfig caps the requests in flight to NocoDB (see
+// proxy.ConcurrencyLimiter). Requests beyond the caps wait in a bounded queue
+// and are answered 503 when it is full or their wait times out.
+type ConcurrencyConfig struct {
+	MaxInFlight  int            `yaml:"max_in_flight,omitempty"` // across all tables; 0 limits only the listed tables
+	Tables       map[string]int `yaml:"tables,omitempty"`        // table -> max in flight
+	MaxQueue     int            `yaml:"max_queue,omitempty"`     // waiting requests (default 100)
+	QueueTimeout string         `yaml:"queue_timeout,omitempty"` // longest wait for a slot (default 5s)
+}
+
+// CircuitBreakerConfig stops requests to a NocoDB host while too many of them
+// fail (see proxy.CircuitBreaker); they are answered 503 without waiting
+type CircuitBreakerConfig struct {
+	ErrorRate   float64 `yaml:"error_rate"`             // percent of failed requests in a window that opens the breaker
+	MinRequests int     `yaml:"min_requests,omitempty"` // requests in a window before the rate counts (default 20)
+	Window      string  `yaml:"window,omitempty"`       // length of a counting window (default 30s)
+	OpenFor     string  `yaml:"open_for,omitempty"`     // time before a trial request is let through (default 15s)
+	SlowCall    string  `yaml:"slow_call,omitempty"`    // answers slower than this count as failures (default: none)
+}
+
+// TableRate is a request budget of one table, shared by every user, base and
+// tenant (see proxy.TableRateLimiter). Requests beyond it are answered 429.
+type TableRate struct {
+	RateLimit int `yaml:"rate_limit"`      // requests per minute on average
+	Burst     int `yaml:"burst,omitempty"` // requests at once (default: one second's worth)
+}
+
+// RecordCacheConfig keeps reads of single records ({table}/records/{id}) in a
+// memory-bounded LRU (see proxy.RecordCache). A write to a record drops its
+// cached reads.
+type RecordCacheConfig struct {
+	MaxMemoryMB int      `yaml:"max_memory_mb,omitempty"` // default 32
+	TTL         string   `yaml:"ttl,omitempty"`           // default 5m
+	Tables      []string `yaml:"tables,omitempty"`        // default: every table
+}
+
+// Response envelopes of record reads (see proxy.normalizeEnvelope)
+const (
+	EnvelopeNocoDB = "nocodb" // NocoDB's own shape, which differs between API versions (default)
+	EnvelopeData   = "data"   // {"data": [...], "meta": {...}} for lists, {"data": {...}} for records
+	EnvelopeArray  = "array"  // bare arrays, paging in X-Total-Count and X-Has-More headers
+)
+
+// HeadersConfig filters the headers passed between clients and NocoDB. Hop-by-hop
+// headers, the client's credentials and NocoDB's cookies are always removed.
+type HeadersConfig struct {
+	Request  *HeaderRules `yaml:"request,omitempty"`  // client -> NocoDB
+	Response *HeaderRules `yaml:"response,omitempty"` // NocoDB -> client
+}
+
+// HeaderRules lists header names; a trailing * matches a prefix ("X-Custom-*").
+// With allow only the listed headers pass; deny wins over allow.
+type HeaderRules struct {
+	Allow []string `yaml:"allow,omitempty"`
+	Deny  []string `yaml:"deny,omitempty"`
+}
+
+// Body logging modes of a status class (see proxy.logResponseBody)
+const (
+	BodyLogOff     = "off"     // only the size of bodies is logged
+	BodyLogSampled = "sampled" // a sample_rate share of bodies is logged
+	BodyLogFull    = "full"    // every body is logged
+)
+
+// BodyLogStatusClasses are the keys of BodyLoggingConfig.Status
+var BodyLogStatusClasses = []string{"2xx", "3xx", "4xx", "5xx"}
+
+// BodyLoggingConfig decides which NocoDB response bodies are written to the log.
+// Without one, error bodies (4xx and 5xx) are logged in full and others are not.
+type BodyLoggingConfig struct {
+	Mode       string            `yaml:"mode,omitempty"`        // mode of status classes not listed in status
+	Status     map[string]string `yaml:"status,omitempty"`      // status class ("2xx".."5xx") -> mode
+	SampleRate float64           `yaml:"sample_rate,omitempty"` // share of bodies logged in sampled mode (default 0.01)
+	MaxBytes   int               `yaml:"max_bytes,omitempty"`   // longer bodies are cut (default 2048)
+	Redact     []string          `yaml:"redact,omitempty"`      // JSON fields whose values are masked, at any depth
+}
+
+// RetryConfig retries idempotent requests (GET, PUT, DELETE) that NocoDB
+// failed transiently, waiting longer before every further attempt
+type RetryConfig struct {
+	Attempts   int    `yaml:"attempts,omitempty"`    // tries in total, the first included (default 3; 1 turns retries off)
+	Backoff    string `yaml:"backoff,omitempty"`     // wait before the first retry, doubled for each further one (default 100ms)
+	MaxBackoff string `yaml:"max_backoff,omitempty"` // longest wait between tries (default 2s)
+	Status     []int  `yaml:"status,omitempty"`      // statuses retried (default 502, 503, 504); failed connections always are
+}
+
+// TimeoutConfig bounds the wait for NocoDB's answer to a table's requests, by
+// operation (durations such as "10s"; none: no timeout)
+type TimeoutConfig struct {
+	Default string `yaml:"default,omitempty"` // operations without their own timeout
+	Read    string `yaml:"read,omitempty"`
+	Create  string `yaml:"create,omitempty"`
+	Update  string `yaml:"update,omitempty"`
+	Delete  string `yaml:"delete,omitempty"`
+	Link    string `yaml:"link,omitempty"` // link and unlink
+}
+
+// For returns the timeout of an operation, 0 for none
+func (t *TimeoutConfig) For(operation string) time.Duration {
+	if t == nil {
+		return 0
+	}
+	value := map[string]string{"read": t.Read, "create": t.Create, "update": t.Update, "delete": t.Delete, "link": t.Link}[operation]
+	if value == "" {
+		value = t.Default
+	}
+	// Validated by the config loader
+	timeout, _ := time.ParseDuration(value)
+	return timeout
+}
+
+// Table modes freeze a table without editing its operations
+const (
+	TableModeReadOnly  = "read_only"  // only reads are accepted
+	TableModeWriteOnly = "write_only" // reads are refused, e.g. for inbound forms
+	TableModeDisabled  = "disabled"   // every request is refused
+)
+
+// ModeAllows reports whether a table mode permits an operation. The table's
+// operations apply as well.
+func ModeAllows(mode, operation string) bool {
+	switch mode {
+	case TableModeDisabled:
+		return false
+	case TableModeReadOnly:
+		return operation == "read"
+	case TableModeWriteOnly:
+		return operation != "read"
+	}
+	return true
+}
+
+// Handling of query parameters a query block does not allow
+const (
+	QueryUnknownReject = "reject" // answer 400 (default)
+	QueryUnknownStrip  = "strip"  // drop the parameter and pass the rest on
+)
+
+// WhereOperators are the comparison operators of NocoDB where clauses
+var WhereOperators = []string{
+	"eq", "neq", "not", "gt", "lt", "gte", "ge", "lte", "le", "like", "nlike",
+	"is", "isnot", "in", "btw", "nbtw", "blank", "notblank", "null", "notnull",
+	"empty", "notempty", "checked", "notchecked", "allof", "anyof", "nallof", "nanyof",
+	"isWithin",
+}
+
+// QueryConfig restricts the query parameters of a table's requests (see
+// proxy.Validator.SanitizeQuery), so crafted parameters cannot change what a
+// read returns beyond what the table is meant to offer
+type QueryConfig struct {
+	Allow     []string `yaml:"allow"`               // permitted parameters; "nested" covers every nested[...] parameter
+	Operators []string `yaml:"operators,omitempty"` // comparison operators permitted in where (default: any)
+	Unknown   string   `yaml:"unknown,omitempty"`   // reject (default) or strip parameters that are not allowed
+}
+
+// Kinds of errors whose messages proxy.yaml can replace, by response status
+const (
+	ErrorBadRequest = "bad_request" // 400 and 422: invalid requests, refused by the gateway or NocoDB
+	ErrorForbidden  = "forbidden"   // 401 and 403: operations or tables that are not allowed
+	ErrorNotFound   = "not_found"   // 404: records NocoDB does not know
+	ErrorUpstream   = "upstream"    // 5xx: NocoDB failed or could not be reached
+)
+
+// ErrorKinds lists the keys of an errors block
+var ErrorKinds = []string{ErrorBadRequest, ErrorForbidden, ErrorNotFound, ErrorUpstream}
+
+// ErrorMessages replaces the text of errors by kind (see ErrorKinds). Each
+// message may be translated; the client's Accept-Language picks one.
+type ErrorMessages map[string]i18n.Text
+
+// ErrorKind returns the kind of an error status, "" for other statuses
+func ErrorKind(status int) string {
+	switch {
+	case status == 400 || status == 422:
+		return ErrorBadRequest
+	case status == 401 || status == 403:
+		return ErrorForbidden
+	case status == 404:
+		return ErrorNotFound
+	case status >= 500:
+		return ErrorUpstream
+	}
+	return ""
+}
+
+// Geo-IP route groups
+const (
+	GeoIPGroupAuth  = "auth"  // /login, /login/*, /signup, /auth/*
+	GeoIPGroupProxy = "proxy" // /proxy/*, /ws, /search, /outbox/*
+	GeoIPGroupAdmin = "admin" // /admin/*, /__proxy/*
+)
+
+// GeoIPConfig allows or denies requests by the country of the client address,
+// looked up in the MaxMind database at GEOIP_DATABASE (see internal/geoip)
+type GeoIPConfig struct {
+	GeoIPRule      `yaml:",inline"`
+	TrustedProxies []string             `yaml:"trusted_proxies,omitempty"` // addresses or CIDRs whose X-Forwarded-For is honored
+	Groups         map[string]GeoIPRule `yaml:"groups,omitempty"`          // route group -> rule replacing the global one
+}
+
+// GeoIPRule lists ISO 3166-1 alpha-2 country codes. With an allow list every
+// other country is denied; deny wins over allow.
+type GeoIPRule struct {
+	Allow   []string `yaml:"allow,omitempty"`
+	Deny    []string `yaml:"deny,omitempty"`
+	Unknown string   `yaml:"unknown,omitempty"` // allow or deny addresses without a country (default: deny when allow is set)
+}
+
+// QuotaConfig limits the data API traffic of each user (see internal/usage).
+// The most specific entry applies: users, then roles, then default. Admins are
+// unlimited unless roles.admin is set.
+type QuotaConfig struct {
+	Default *Quota           `yaml:"default,omitempty"`
+	Roles   map[string]Quota `yaml:"roles,omitempty"`
+	Users   map[string]Quota `yaml:"users,omitempty"` // user ID -> quota
+}
+
+// Quota holds per-UTC-day and per-calendar-month limits; 0 means unlimited.
+// Bytes count request and response bodies together.
+type Quota struct {
+	DailyRequests   int64 `yaml:"daily_requests,omitempty"`
+	MonthlyRequests int64 `yaml:"monthly_requests,omitempty"`
+	DailyBytes      int64 `yaml:"daily_bytes,omitempty"`
+	MonthlyBytes    int64 `yaml:"monthly_bytes,omitempty"`
+}
+
+// For returns the quota of a user, false when the user is unlimited
+func (q *QuotaConfig) For(userID, role string) (Quota, bool) {
+	if q == nil {
+		return Quota{}, false
+	}
+	if quota, ok := q.Users[userID]; ok {
+		return quota, true
+	}
+	if quota, ok := q.Roles[role]; ok {
+		return quota, true
+	}
+	if q.Default != nil && role != "admin" {
+		return *q.Default, true
+	}
+	return Quota{}, false
+}
+
+// PluginConfig enables a compiled-in plugin (see internal/plugins). Plugins run
+// in the listed order.
+type PluginConfig struct {
+	Name   string                 `yaml:"name"`
+	Config map[string]interface{} `yaml:"config,omitempty"` // passed to the plugin's Configure
+}
+
+// AliasConfig exposes NocoDB tables and columns under different public names.
+// Aliases are honored wherever a table or field name is resolved, and reverse
+// lookups report the public name.
+type AliasConfig struct {
+	Tables map[string]string            `yaml:"tables,omitempty"` // public name -> NocoDB table title
+	Fields map[string]map[string]string `yaml:"fields,omitempty"` // table (public name or title) -> public field name -> NocoDB field title
 }
 
 // NocoDBConfig holds NocoDB connection details
@@ -11,24 +349,320 @@ type NocoDBConfig struct {
 	BaseID string `yaml:"base_id"`
 }
 
+// BaseConfig defines an additional NocoDB base served under /proxy/{base}/...
+type BaseConfig struct {
+	BaseID string                 `yaml:"base_id"`
+	Tables map[string]TableConfig `yaml:"tables"`
+}
+
+// UpstreamConfig defines a separate NocoDB instance or base that individual
+// tables can be routed to with `upstream: <name>`
+type UpstreamConfig struct {
+	URL      string `yaml:"url"` // data API URL, e.g. http://analytics:8090/api/v3/data/
+	BaseID   string `yaml:"base_id"`
+	TokenEnv string `yaml:"token_env,omitempty"` // env var holding the upstream's NocoDB token
+}
+
+// RoleToken names the NocoDB token sent for the requests of a role, in place of
+// the default token. A token stored in the vault under "role:{name}" takes
+// precedence over token_env.
+type RoleToken struct {
+	TokenEnv string `yaml:"token_env,omitempty"` // env var holding the role's NocoDB token
+}
+
+// TenantConfig selects the upstream base, token and table overlay for a tenant
+type TenantConfig struct {
+	BaseID   string                 `yaml:"base_id"`
+	TokenEnv string                 `yaml:"token_env,omitempty"` // env var holding the tenant's NocoDB token
+	Tables   map[string]TableConfig `yaml:"tables,omitempty"`    // overrides or extends the default tables
+}
+
+// ForTenant returns the configuration for a tenant: its own base with the
+// default tables, overlaid by the tenant's table definitions
+func (c *ProxyConfig) ForTenant(name string) (*ProxyConfig, bool) {
+	tenant, ok := c.Tenants[name]
+	if !ok {
+		return nil, false
+	}
+
+	tables := make(map[string]TableConfig, len(c.Tables)+len(tenant.Tables))
+	for key, table := range c.Tables {
+		// Tables routed to other upstreams are never shared with tenants
+		if table.Upstream == "" {
+			tables[key] = table
+		}
+	}
+	for key, table := range tenant.Tables {
+		tables[key] = table
+	}
+
+	return &ProxyConfig{
+		NocoDB:   NocoDBConfig{BaseID: tenant.BaseID},
+		Tables:   tables,
+		Aliases:  c.Aliases,
+		Flags:    c.Flags,
+		Envelope: c.Envelope,
+		Errors:   c.Errors,
+		Headers:  c.Headers,
+
+		BodyLogging: c.BodyLogging,
+		MaxResponse: c.MaxResponse,
+		MaxRequest:  c.MaxRequest,
+		Retry:       c.Retry,
+		Timeouts:    c.Timeouts,
+	}, true
+}
+
+// ForBase returns a single-base configuration for an additional base
+func (c *ProxyConfig) ForBase(name string) (*ProxyConfig, bool) {
+	base, ok := c.Bases[name]
+	if !ok {
+		return nil, false
+	}
+	return &ProxyConfig{
+		NocoDB:   NocoDBConfig{BaseID: base.BaseID},
+		Tables:   base.Tables,
+		Aliases:  c.Aliases,
+		Flags:    c.Flags,
+		Envelope: c.Envelope,
+		Errors:   c.Errors,
+		Headers:  c.Headers,
+
+		BodyLogging: c.BodyLogging,
+		MaxResponse: c.MaxResponse,
+		MaxRequest:  c.MaxRequest,
+		Retry:       c.Retry,
+		Timeouts:    c.Timeouts,
+	}, true
+}
+
 // TableConfig defines configuration for a single table
 type TableConfig struct {
 	Name       string            `yaml:"name"`
 	Operations []string          `yaml:"operations"`
 	Fields     map[string]string `yaml:"fields,omitempty"`
 	Links      map[string]Link   `yaml:"links,omitempty"`
+	Webhooks   []WebhookTarget   `yaml:"webhooks,omitempty"`
+	CDC        *CDCConfig        `yaml:"cdc,omitempty"`
+	OwnerField string            `yaml:"owner_field,omitempty"` // column holding the owning user ID
+	Export     bool              `yaml:"export,omitempty"`      // include owned rows in /auth/me/export (requires owner_field)
+	Erase      *EraseConfig      `yaml:"erase,omitempty"`       // what happens to owned rows when the owner's account is deleted
+	Encrypt    *EncryptConfig    `yaml:"encrypt,omitempty"`
+	Mirror     *MirrorConfig     `yaml:"mirror,omitempty"`
+	Cache      *CacheConfig      `yaml:"cache,omitempty"`
+	Outbox     *OutboxConfig     `yaml:"outbox,omitempty"`
+	Search     *SearchConfig     `yaml:"search,omitempty"`
+	ShareLinks *ShareLinkConfig  `yaml:"share_links,omitempty"`
+	Upstream   string            `yaml:"upstream,omitempty"` // name of an entry in upstreams
+	Rules      *RulesConfig      `yaml:"rules,omitempty"`
+	Filters    []WasmFilter      `yaml:"wasm_filters,omitempty"`
+	Pipeline   []PipelineStep    `yaml:"pipeline,omitempty"` // steps run on the table's requests and responses, in order
+	Computed   map[string]string `yaml:"computed,omitempty"` // field -> expression over the record's fields, added to records read
+	Envelope   string            `yaml:"envelope,omitempty"` // overrides the top-level envelope
+	Errors     ErrorMessages     `yaml:"errors,omitempty"`   // override the top-level messages by kind
+	Headers    *HeadersConfig    `yaml:"headers,omitempty"`  // each direction replaces the top-level one
+	Query      *QueryConfig      `yaml:"query,omitempty"`
+	Mode       string            `yaml:"mode,omitempty"` // read_only, write_only or disabled; narrows operations
+
+	BodyLogging *BodyLoggingConfig `yaml:"body_logging,omitempty"`    // set fields override the top-level policy
+	MaxResponse int                `yaml:"max_response_mb,omitempty"` // overrides the top-level limit
+	MaxRequest  int                `yaml:"max_request_kb,omitempty"`  // overrides the top-level limit
+	Retry       *RetryConfig       `yaml:"retry,omitempty"`           // replaces the top-level policy
+	Timeouts    *TimeoutConfig     `yaml:"timeouts,omitempty"`        // set operations override the top-level timeouts
+
+	DefaultFields map[string][]string `yaml:"default_fields,omitempty"` // role -> fields read when the client passes no fields ("*": other roles)
+}
+
+// WasmFilter runs a WebAssembly module (see internal/wasmfilter) on a table's
+// requests and responses. Filters run in the listed order.
+type WasmFilter struct {
+	Module string                 `yaml:"module"`           // path to the .wasm file; reloaded when it changes
+	Config map[string]interface{} `yaml:"config,omitempty"` // sent to the module with every call
+}
+
+// Pipeline steps. Request steps change written records before they are sent
+// to NocoDB; response steps change records before they reach the client.
+const (
+	StepDefaults = "defaults" // request: values set on created records that lack them
+	StepSet      = "set"      // request: values written on every create and update
+	StepMask     = "mask"     // response: values replaced by asterisks
+	StepRemove   = "remove"   // response: fields left out
+)
+
+// PipelineStep is a built-in transformation in a table's pipeline
+type PipelineStep struct {
+	Step        string                 `yaml:"step"`                   // defaults, set, mask or remove
+	Values      map[string]interface{} `yaml:"values,omitempty"`       // defaults, set: field -> value (any YAML value)
+	Fields      []string               `yaml:"fields,omitempty"`       // mask, remove: the fields
+	KeepLast    int                    `yaml:"keep_last,omitempty"`    // mask: characters left visible at the end
+	ExceptRoles []string               `yaml:"except_roles,omitempty"` // roles the step does not apply to
+}
+
+// IsResponseStep reports whether a step runs on responses
+func (s PipelineStep) IsResponseStep() bool {
+	return s.Step == StepMask || s.Step == StepRemove
+}
+
+// AppliesTo reports whether a step applies to a role
+func (s PipelineStep) AppliesTo(role string) bool {
+	for _, except := range s.ExceptRoles {
+		if except == role {
+			return false
+		}
+	}
+	return true
+}
+
+// RulesConfig holds expressions (expr-lang syntax) evaluated for requests to a
+// table. Expressions see user.id, user.role, user.tenant, method, operation,
+// table, query (first value of each parameter) and body (the record's fields,
+// evaluated once per record of a bulk write).
+type RulesConfig struct {
+	Reject   []RejectRule      `yaml:"reject,omitempty"`
+	Defaults map[string]string `yaml:"defaults,omitempty"` // field -> expression, set on create when the field is missing
+	Filter   string            `yaml:"filter,omitempty"`   // expression returning a where clause ANDed to reads ("" adds none)
 }
 
+// RejectRule refuses a request when its expression is true
+type RejectRule struct {
+	When    string    `yaml:"when"`
+	Message i18n.Text `yaml:"message,omitempty"` // a message or its translations; default: the table's message for the status
+	Status  int       `yaml:"status,omitempty"`  // default 403
+}
+
+// WebhookTarget defines a downstream URL notified after successful writes, or
+// of auth events when listed under auth_webhooks
+type WebhookTarget struct {
+	URL        string   `yaml:"url"`
+	SecretEnv  string   `yaml:"secret_env,omitempty"`  // env var holding the signing secret
+	Events     []string `yaml:"events,omitempty"`      // create, update, delete, or AuthEvents (default: all)
+	MaxRetries int      `yaml:"max_retries,omitempty"` // default: 5
+	Format     string   `yaml:"format,omitempty"`      // event (default, the JSON event) or slack
+}
+
+// Webhook payload formats
+const (
+	WebhookFormatEvent = "event"
+	WebhookFormatSlack = "slack" // {"text": ...} for Slack and compatible incoming webhooks
+)
+
 // Link defines a relationship between tables
 type Link struct {
 	Field       string `yaml:"field"`
 	TargetTable string `yaml:"target_table"`
+	KeyField    string `yaml:"key_field,omitempty"` // unique field of the target table that link bodies may reference records by
+}
+
+// CDCConfig enables change-data-capture polling for a table
+type CDCConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	UpdatedField  string `yaml:"updated_field,omitempty"`  // default: UpdatedAt
+	Interval      string `yaml:"interval,omitempty"`       // default: 30s
+	DetectDeletes bool   `yaml:"detect_deletes,omitempty"` // full ID scan on every poll
+}
+
+// MirrorConfig enables a local read-through copy of a table for upstream outages
+type MirrorConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Interval string `yaml:"interval,omitempty"` // full resync interval, default: 5m
+}
+
+// CacheConfig keeps a table's read responses in the gateway's response cache
+// until a write to the table or the TTL ends them
+type CacheConfig struct {
+	TTL  string   `yaml:"ttl,omitempty"`  // default: 60s
+	Warm []string `yaml:"warm,omitempty"` // paths below the table, e.g. "records?limit=25", fetched at startup and after invalidation
+}
+
+// Outbox delivery modes
+const (
+	OutboxAlways   = "always"    // every write is queued and acknowledged with 202
+	OutboxOnOutage = "on_outage" // writes are queued only when NocoDB is unreachable
+)
+
+// OutboxConfig enables durable, asynchronous delivery of writes to NocoDB
+type OutboxConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Mode    string `yaml:"mode,omitempty"` // always (default) or on_outage
+}
+
+// Erase modes
+const (
+	EraseAnonymize = "anonymize" // overwrite fields of the owned rows
+	EraseDelete    = "delete"    // delete the owned rows
+)
+
+// EraseConfig handles a table's owned rows (via owner_field) when the owning
+// account is deleted
+type EraseConfig struct {
+	Mode   string                 `yaml:"mode"`             // anonymize or delete
+	Fields map[string]interface{} `yaml:"fields,omitempty"` // anonymize: field -> value written to each row
+}
+
+// EncryptConfig lists columns whose values the gateway encrypts before they
+// reach NocoDB (FIELD_ENCRYPTION_KEY)
+type EncryptConfig struct {
+	Fields []string `yaml:"fields"`
+	Roles  []string `yaml:"roles,omitempty"` // roles that read plaintext (default: admin); others read null
+}
+
+// CanDecrypt reports whether a role reads the plaintext of encrypted fields
+func (e *EncryptConfig) CanDecrypt(role string) bool {
+	if len(e.Roles) == 0 {
+		return role == "admin"
+	}
+	for _, allowed := range e.Roles {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchConfig enables full-text search over a mirrored table
+type SearchConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Fields  []string `yaml:"fields,omitempty"` // defaults to every text field
+}
+
+// ShareLinkConfig lets users create signed, expiring links that show one
+// record of a table without authentication (POST /share, GET /shared/{token})
+type ShareLinkConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	MaxTTL  string   `yaml:"max_ttl,omitempty"` // longest lifetime a link may have (default: 24h)
+	Fields  []string `yaml:"fields,omitempty"`  // fields a link may show (default: all but encrypted ones)
+	Roles   []string `yaml:"roles,omitempty"`   // roles that may create links (default: all)
+}
+
+// MaxLifetime returns the longest lifetime a link may have
+func (s *ShareLinkConfig) MaxLifetime() time.Duration {
+	if ttl, err := time.ParseDuration(s.MaxTTL); err == nil && ttl > 0 {
+		return ttl
+	}
+	return 24 * time.Hour
+}
+
+// CanShare reports whether a role may create links
+func (s *ShareLinkConfig) CanShare(role string) bool {
+	if len(s.Roles) == 0 {
+		return true
+	}
+	for _, allowed := range s.Roles {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
 }
 
 // ResolvedConfig contains runtime-resolved IDs from MetaCache
 type ResolvedConfig struct {
 	BaseID string
 	Tables map[string]ResolvedTable
+	Strict bool          // strict_mode: only links declared in proxy.yaml may be used
+	Errors ErrorMessages // top-level messages, for requests to unknown tables
+
+	MaxRequest int // top-level request body limit in KB, for requests to unknown tables and batches (0 = none)
 }
 
 // ResolvedTable contains resolved IDs for a table
@@ -38,10 +672,40 @@ type ResolvedTable struct {
 	Operations []string
 	Fields     map[string]string // field name -> field ID
 	Links      map[string]ResolvedLink
+	Webhooks   []WebhookTarget
+	CDC        *CDCConfig
+	OwnerField string
+	Export     bool
+	Erase      *EraseConfig
+	Encrypt    *EncryptConfig
+	Mirror     *MirrorConfig
+	Cache      *CacheConfig
+	Outbox     *OutboxConfig
+	Search     *SearchConfig
+	ShareLinks *ShareLinkConfig
+	Upstream   string
+	Rules      *CompiledRules // nil when the table has no rules
+	Filters    []WasmFilter
+	Pipeline   []PipelineStep
+	Computed   *ComputedFields // nil when the table has no computed fields
+	Envelope   string          // the table's envelope, or the top-level one
+	Errors     ErrorMessages   // the table's messages merged over the top-level ones
+	Headers    *HeadersConfig  // the table's policy, each direction falling back to the top-level one
+	Query      *QueryConfig
+	Mode       string
+
+	BodyLogging *BodyLoggingConfig // the table's policy merged over the top-level one
+	MaxResponse int                // the table's limit in MB, or the top-level one (0 = none)
+	MaxRequest  int                // the table's request body limit in KB, or the top-level one (0 = none)
+	Retry       *RetryConfig       // the table's policy, or the top-level one (nil = no retries)
+	Timeouts    *TimeoutConfig     // the table's timeouts merged over the top-level ones
+
+	DefaultFields map[string][]string
 }
 
 // ResolvedLink contains resolved IDs for a link
 type ResolvedLink struct {
 	FieldID     string
 	TargetTable string
+	KeyField    string
 }
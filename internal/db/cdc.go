@@ -0,0 +1,75 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+)
+
+// GetCDCCursor returns the stored change cursor for a table ("" if none)
+func (d *Database) GetCDCCursor(tableKey string) (string, error) {
+	var cursor string
+	err := d.db.QueryRow("SELECT cursor FROM cdc_cursors WHERE table_key = ?", tableKey).Scan(&cursor)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to get CDC cursor for %s: %v", tableKey, err)
+		return "", err
+	}
+	return cursor, nil
+}
+
+// SetCDCCursor stores the change cursor for a table
+func (d *Database) SetCDCCursor(tableKey, cursor string) error {
+	_, err := d.db.Exec(
+		`INSERT INTO cdc_cursors (table_key, cursor, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP)
+		 ON CONFLICT(table_key) DO UPDATE SET cursor = excluded.cursor, updated_at = CURRENT_TIMESTAMP`,
+		tableKey, cursor,
+	)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to set CDC cursor for %s: %v", tableKey, err)
+	}
+	return err
+}
+
+// GetCDCRecords returns the known record versions (record ID -> updated_at) for a table
+func (d *Database) GetCDCRecords(tableKey string) (map[string]string, error) {
+	rows, err := d.db.Query("SELECT record_id, version FROM cdc_records WHERE table_key = ?", tableKey)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to get CDC records for %s: %v", tableKey, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make(map[string]string)
+	for rows.Next() {
+		var id, version string
+		if err := rows.Scan(&id, &version); err != nil {
+			return nil, err
+		}
+		records[id] = version
+	}
+	return records, rows.Err()
+}
+
+// UpsertCDCRecord stores the last seen version of a record
+func (d *Database) UpsertCDCRecord(tableKey, recordID, version string) error {
+	_, err := d.db.Exec(
+		`INSERT INTO cdc_records (table_key, record_id, version) VALUES (?, ?, ?)
+		 ON CONFLICT(table_key, record_id) DO UPDATE SET version = excluded.version`,
+		tableKey, recordID, version,
+	)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to upsert CDC record %s/%s: %v", tableKey, recordID, err)
+	}
+	return err
+}
+
+// DeleteCDCRecord forgets a record that no longer exists upstream
+func (d *Database) DeleteCDCRecord(tableKey, recordID string) error {
+	_, err := d.db.Exec("DELETE FROM cdc_records WHERE table_key = ? AND record_id = ?", tableKey, recordID)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to delete CDC record %s/%s: %v", tableKey, recordID, err)
+	}
+	return err
+}
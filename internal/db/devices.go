@@ -0,0 +1,121 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// States of a device authorization request
+const (
+	DeviceCodePending  = "pending"
+	DeviceCodeApproved = "approved"
+	DeviceCodeDenied   = "denied"
+)
+
+// DeviceCode is a pending or decided device authorization request (RFC 8628).
+// Only a hash of the device code is stored; the user code is what the user
+// types in.
+type DeviceCode struct {
+	DeviceCodeHash string
+	UserCode       string
+	ClientID       string
+	Status         string
+	UserID         string // who approved or denied the request
+	Role           string
+	Tenant         string
+	ExpiresAt      time.Time
+	PolledAt       time.Time
+}
+
+const deviceCodeColumns = "device_code_hash, user_code, client_id, status, user_id, role, tenant, expires_at, polled_at"
+
+// CreateDeviceCode stores a new device authorization request. Expired requests are removed on the way.
+func (d *Database) CreateDeviceCode(c DeviceCode) error {
+	if _, err := d.db.Exec("DELETE FROM device_codes WHERE expires_at < ?", time.Now().Unix()); err != nil {
+		log.Printf("[DB ERROR] Failed to remove expired device codes: %v", err)
+	}
+	_, err := d.db.Exec(
+		"INSERT INTO device_codes (device_code_hash, user_code, client_id, status, expires_at) VALUES (?, ?, ?, ?, ?)",
+		c.DeviceCodeHash, c.UserCode, c.ClientID, DeviceCodePending, c.ExpiresAt.Unix(),
+	)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to create device code: %v", err)
+	}
+	return err
+}
+
+// GetDeviceCode returns a request by the hash of its device code, nil when it does not exist
+func (d *Database) GetDeviceCode(deviceCodeHash string) (*DeviceCode, error) {
+	return d.scanDeviceCode(d.db.QueryRow("SELECT "+deviceCodeColumns+" FROM device_codes WHERE device_code_hash = ?", deviceCodeHash))
+}
+
+// GetDeviceCodeByUserCode returns a request by its user code, nil when it does not exist
+func (d *Database) GetDeviceCodeByUserCode(userCode string) (*DeviceCode, error) {
+	return d.scanDeviceCode(d.db.QueryRow("SELECT "+deviceCodeColumns+" FROM device_codes WHERE user_code = ?", userCode))
+}
+
+func (d *Database) scanDeviceCode(row *sql.Row) (*DeviceCode, error) {
+	var c DeviceCode
+	var clientID, userID, role, tenant sql.NullString
+	var expiresAt, polledAt int64
+	err := row.Scan(&c.DeviceCodeHash, &c.UserCode, &clientID, &c.Status, &userID, &role, &tenant, &expiresAt, &polledAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to read device code: %v", err)
+		return nil, err
+	}
+	c.ClientID, c.UserID, c.Role, c.Tenant = clientID.String, userID.String, role.String, tenant.String
+	c.ExpiresAt = time.Unix(expiresAt, 0)
+	if polledAt > 0 {
+		c.PolledAt = time.Unix(polledAt, 0)
+	}
+	return &c, nil
+}
+
+// DecideDeviceCode approves or denies a pending request on behalf of a user.
+// It reports false when the request is no longer pending.
+func (d *Database) DecideDeviceCode(userCode, status, userID, role, tenant string) (bool, error) {
+	result, err := d.db.Exec(
+		"UPDATE device_codes SET status = ?, user_id = ?, role = ?, tenant = ? WHERE user_code = ? AND status = ?",
+		status, userID, role, tenant, userCode, DeviceCodePending,
+	)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to update device code: %v", err)
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// TouchDeviceCode records when a device last polled for its token
+func (d *Database) TouchDeviceCode(deviceCodeHash string, at time.Time) error {
+	_, err := d.db.Exec("UPDATE device_codes SET polled_at = ? WHERE device_code_hash = ?", at.Unix(), deviceCodeHash)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to update device code: %v", err)
+	}
+	return err
+}
+
+// DeleteDeviceCode removes a request, so its device code cannot be used again.
+// It reports false when the request was already gone.
+func (d *Database) DeleteDeviceCode(deviceCodeHash string) (bool, error) {
+	result, err := d.db.Exec("DELETE FROM device_codes WHERE device_code_hash = ?", deviceCodeHash)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to delete device code: %v", err)
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// DeleteUserDeviceCodes removes the requests a user approved or denied
+func (d *Database) DeleteUserDeviceCodes(userID string) error {
+	_, err := d.db.Exec("DELETE FROM device_codes WHERE user_id = ?", userID)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to delete device codes of user %s: %v", userID, err)
+	}
+	return err
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// Kinds of known login attributes
+const (
+	LoginDevice   = "device"
+	LoginLocation = "location"
+)
+
+// KnownLogin is a device or location a user has signed in from before
+type KnownLogin struct {
+	Kind      string    `json:"kind"`
+	Value     string    `json:"value"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// LoginChallenge is a login held back until the user confirms it with the
+// code sent by email
+type LoginChallenge struct {
+	ID        string
+	UserID    string
+	CodeHash  string
+	Tenant    string
+	Flags     string // why the login was held back, e.g. "new_device,new_location"
+	Attempts  int
+	ExpiresAt time.Time
+}
+
+// HasLoginHistory reports whether any device or location is known for a user
+func (d *Database) HasLoginHistory(userID string) (bool, error) {
+	var count int
+	err := d.db.QueryRow("SELECT COUNT(*) FROM known_logins WHERE user_id = ?", userID).Scan(&count)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to read login history of user %s: %v", userID, err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// IsKnownLogin reports whether a user has signed in with a device or location before
+func (d *Database) IsKnownLogin(userID, kind, value string) (bool, error) {
+	var count int
+	err := d.db.QueryRow(
+		"SELECT COUNT(*) FROM known_logins WHERE user_id = ? AND kind = ? AND value = ?",
+		userID, kind, value,
+	).Scan(&count)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to check login history of user %s: %v", userID, err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// RememberLogin records a device or location a user signed in from
+func (d *Database) RememberLogin(userID, kind, value string) error {
+	_, err := d.db.Exec(`
+		INSERT INTO known_logins (user_id, kind, value) VALUES (?, ?, ?)
+		ON CONFLICT(user_id, kind, value) DO UPDATE SET last_seen = CURRENT_TIMESTAMP`,
+		userID, kind, value,
+	)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to record login of user %s: %v", userID, err)
+	}
+	return err
+}
+
+// ListKnownLogins returns the devices and locations a user has signed in from
+func (d *Database) ListKnownLogins(userID string) ([]KnownLogin, error) {
+	rows, err := d.db.Query(
+		"SELECT kind, value, first_seen, last_seen FROM known_logins WHERE user_id = ? ORDER BY kind, first_seen",
+		userID,
+	)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to list login history of user %s: %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	logins := []KnownLogin{}
+	for rows.Next() {
+		var k KnownLogin
+		if err := rows.Scan(&k.Kind, &k.Value, &k.FirstSeen, &k.LastSeen); err != nil {
+			return nil, err
+		}
+		logins = append(logins, k)
+	}
+	return logins, rows.Err()
+}
+
+// DeleteUserLogins removes a user's login history and pending login challenges
+func (d *Database) DeleteUserLogins(userID string) error {
+	if _, err := d.db.Exec("DELETE FROM known_logins WHERE user_id = ?", userID); err != nil {
+		log.Printf("[DB ERROR] Failed to delete login history of user %s: %v", userID, err)
+		return err
+	}
+	_, err := d.db.Exec("DELETE FROM login_challenges WHERE user_id = ?", userID)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to delete login challenges of user %s: %v", userID, err)
+	}
+	return err
+}
+
+// CreateLoginChallenge stores a held-back login. Expired challenges are removed on the way.
+func (d *Database) CreateLoginChallenge(c LoginChallenge) error {
+	if _, err := d.db.Exec("DELETE FROM login_challenges WHERE expires_at < ?", time.Now().Unix()); err != nil {
+		log.Printf("[DB ERROR] Failed to remove expired login challenges: %v", err)
+	}
+	_, err := d.db.Exec(
+		"INSERT INTO login_challenges (id, user_id, code_hash, tenant, flags, expires_at) VALUES (?, ?, ?, ?, ?, ?)",
+		c.ID, c.UserID, c.CodeHash, c.Tenant, c.Flags, c.ExpiresAt.Unix(),
+	)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to create login challenge for user %s: %v", c.UserID, err)
+	}
+	return err
+}
+
+// GetLoginChallenge returns a login challenge, nil when it does not exist
+func (d *Database) GetLoginChallenge(id string) (*LoginChallenge, error) {
+	var c LoginChallenge
+	var tenant, flags sql.NullString
+	var expiresAt int64
+	err := d.db.QueryRow(
+		"SELECT id, user_id, code_hash, tenant, flags, attempts, expires_at FROM login_challenges WHERE id = ?", id,
+	).Scan(&c.ID, &c.UserID, &c.CodeHash, &tenant, &flags, &c.Attempts, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to read login challenge: %v", err)
+		return nil, err
+	}
+	c.Tenant, c.Flags = tenant.String, flags.String
+	c.ExpiresAt = time.Unix(expiresAt, 0)
+	return &c, nil
+}
+
+// CountLoginChallengeAttempt records a wrong code for a challenge
+func (d *Database) CountLoginChallengeAttempt(id string) error {
+	_, err := d.db.Exec("UPDATE login_challenges SET attempts = attempts + 1 WHERE id = ?", id)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to update login challenge: %v", err)
+	}
+	return err
+}
+
+// DeleteLoginChallenge removes a login challenge
+func (d *Database) DeleteLoginChallenge(id string) error {
+	_, err := d.db.Exec("DELETE FROM login_challenges WHERE id = ?", id)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to delete login challenge: %v", err)
+	}
+	return err
+}
@@ -0,0 +1,170 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// Outbox entry statuses
+const (
+	OutboxPending   = "pending"
+	OutboxDelivered = "delivered"
+	OutboxFailed    = "failed"
+)
+
+// OutboxEntry is a write accepted by the gateway and queued for delivery to NocoDB
+type OutboxEntry struct {
+	Seq            int64
+	ID             string
+	TableKey       string
+	TableID        string
+	Method         string
+	Path           string // original proxy path (after /proxy/)
+	TargetPath     string // resolved upstream path including query string
+	ContentType    string
+	Body           []byte
+	UserID         string
+	Role           string // role of the user, which picks the NocoDB token on delivery
+	Status         string
+	Attempts       int
+	LastError      string
+	ResponseStatus int
+	ResponseBody   string
+	NextAttemptAt  time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+const outboxColumns = `seq, id, table_key, table_id, method, path, target_path, content_type, body, user_id,
+	role, status, attempts, last_error, response_status, response_body, next_attempt_at, created_at, updated_at`
+
+// EnqueueOutbox persists a new pending write
+func (d *Database) EnqueueOutbox(entry *OutboxEntry) error {
+	result, err := d.db.Exec(
+		`INSERT INTO outbox (id, table_key, table_id, method, path, target_path, content_type, body, user_id, role, status, next_attempt_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.ID, entry.TableKey, entry.TableID, entry.Method, entry.Path, entry.TargetPath,
+		entry.ContentType, entry.Body, entry.UserID, entry.Role, OutboxPending, time.Now().UTC(),
+	)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to enqueue outbox entry: %v", err)
+		return err
+	}
+	entry.Seq, _ = result.LastInsertId()
+	entry.Status = OutboxPending
+	return nil
+}
+
+// NextPendingOutbox returns the oldest pending write (nil if the queue is empty)
+func (d *Database) NextPendingOutbox() (*OutboxEntry, error) {
+	row := d.db.QueryRow("SELECT "+outboxColumns+" FROM outbox WHERE status = ? ORDER BY seq LIMIT 1", OutboxPending)
+	return scanOutbox(row)
+}
+
+// GetOutboxEntry returns a queued write by ID (nil if not found)
+func (d *Database) GetOutboxEntry(id string) (*OutboxEntry, error) {
+	row := d.db.QueryRow("SELECT "+outboxColumns+" FROM outbox WHERE id = ?", id)
+	return scanOutbox(row)
+}
+
+// CountPendingOutbox returns the number of writes waiting for delivery
+func (d *Database) CountPendingOutbox() (int, error) {
+	var count int
+	err := d.db.QueryRow("SELECT COUNT(*) FROM outbox WHERE status = ?", OutboxPending).Scan(&count)
+	return count, err
+}
+
+// CompleteOutbox records the final outcome (delivered or failed) of a queued write
+func (d *Database) CompleteOutbox(id, status string, responseStatus int, responseBody, lastError string) error {
+	_, err := d.db.Exec(
+		`UPDATE outbox SET status = ?, attempts = attempts + 1, response_status = ?, response_body = ?,
+		 last_error = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		status, responseStatus, responseBody, lastError, id,
+	)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to complete outbox entry %s: %v", id, err)
+	}
+	return err
+}
+
+// RetryOutbox records a failed attempt and schedules the next one
+func (d *Database) RetryOutbox(id, lastError string, nextAttempt time.Time) error {
+	_, err := d.db.Exec(
+		`UPDATE outbox SET attempts = attempts + 1, last_error = ?, next_attempt_at = ?,
+		 updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		lastError, nextAttempt.UTC(), id,
+	)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to schedule outbox retry for %s: %v", id, err)
+	}
+	return err
+}
+
+// ListUserOutbox returns the writes a user made through the outbox, newest first
+func (d *Database) ListUserOutbox(userID string) ([]*OutboxEntry, error) {
+	rows, err := d.db.Query("SELECT "+outboxColumns+" FROM outbox WHERE user_id = ? ORDER BY seq DESC", userID)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to list outbox entries of user %s: %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*OutboxEntry{}
+	for rows.Next() {
+		entry, err := scanOutbox(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// ScrubUserOutbox removes a user from the outbox: finished writes are deleted
+// and pending ones, which still have to be delivered, lose their user ID
+func (d *Database) ScrubUserOutbox(userID string) (int, error) {
+	result, err := d.db.Exec("DELETE FROM outbox WHERE user_id = ? AND status != ?", userID, OutboxPending)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to delete outbox entries of user %s: %v", userID, err)
+		return 0, err
+	}
+	deleted, _ := result.RowsAffected()
+
+	result, err = d.db.Exec("UPDATE outbox SET user_id = NULL WHERE user_id = ?", userID)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to detach pending outbox entries of user %s: %v", userID, err)
+		return int(deleted), err
+	}
+	detached, _ := result.RowsAffected()
+	return int(deleted + detached), nil
+}
+
+// scanOutbox scans a single outbox row (*sql.Row or the current row of *sql.Rows)
+func scanOutbox(row interface{ Scan(...interface{}) error }) (*OutboxEntry, error) {
+	entry := &OutboxEntry{}
+	var tableID, contentType, userID, role, lastError, responseBody sql.NullString
+	var responseStatus sql.NullInt64
+	var nextAttempt sql.NullTime
+
+	err := row.Scan(&entry.Seq, &entry.ID, &entry.TableKey, &tableID, &entry.Method, &entry.Path, &entry.TargetPath,
+		&contentType, &entry.Body, &userID, &role, &entry.Status, &entry.Attempts, &lastError, &responseStatus,
+		&responseBody, &nextAttempt, &entry.CreatedAt, &entry.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to scan outbox entry: %v", err)
+		return nil, err
+	}
+
+	entry.TableID = tableID.String
+	entry.ContentType = contentType.String
+	entry.UserID = userID.String
+	entry.Role = role.String
+	entry.LastError = lastError.String
+	entry.ResponseStatus = int(responseStatus.Int64)
+	entry.ResponseBody = responseBody.String
+	entry.NextAttemptAt = nextAttempt.Time
+	return entry, nil
+}
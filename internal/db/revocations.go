@@ -0,0 +1,37 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// RevokeUserTokens invalidates every token issued to a user so far
+func (d *Database) RevokeUserTokens(userID string) error {
+	_, err := d.db.Exec(
+		"INSERT INTO revoked_tokens (user_id, revoked_at) VALUES (?, ?) ON CONFLICT(user_id) DO UPDATE SET revoked_at = excluded.revoked_at",
+		userID, time.Now().Unix(),
+	)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to revoke tokens of user %s: %v", userID, err)
+	}
+	return err
+}
+
+// TokenRevoked reports whether a token issued to userID at issuedAt has been
+// revoked. A failed lookup counts as revoked: a deleted account's tokens must
+// not come back while the database is failing.
+func (d *Database) TokenRevoked(userID string, issuedAt time.Time) bool {
+	var revokedAt int64
+	err := d.db.QueryRow("SELECT revoked_at FROM revoked_tokens WHERE user_id = ?", userID).Scan(&revokedAt)
+	if err == sql.ErrNoRows {
+		return false
+	}
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to check token revocation of user %s, denying the token: %v", userID, err)
+		return true
+	}
+	// Token timestamps have one-second resolution: a token issued in the second
+	// of the revocation is revoked as well
+	return issuedAt.Unix() <= revokedAt
+}
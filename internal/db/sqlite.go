@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"time"
@@ -63,6 +64,103 @@ func (d *Database) initSchema() error {
 
 	CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
 	CREATE INDEX IF NOT EXISTS idx_users_provider ON users(provider);
+
+	CREATE TABLE IF NOT EXISTS cdc_cursors (
+		table_key TEXT PRIMARY KEY,
+		cursor TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS cdc_records (
+		table_key TEXT NOT NULL,
+		record_id TEXT NOT NULL,
+		version TEXT NOT NULL,
+		PRIMARY KEY (table_key, record_id)
+	);
+
+	CREATE TABLE IF NOT EXISTS outbox (
+		seq INTEGER PRIMARY KEY AUTOINCREMENT,
+		id TEXT UNIQUE NOT NULL,
+		table_key TEXT NOT NULL,
+		table_id TEXT,
+		method TEXT NOT NULL,
+		path TEXT NOT NULL,
+		target_path TEXT NOT NULL,
+		content_type TEXT,
+		body BLOB,
+		user_id TEXT,
+		role TEXT,
+		status TEXT NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT,
+		response_status INTEGER,
+		response_body TEXT,
+		next_attempt_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_outbox_status ON outbox(status, seq);
+
+	CREATE TABLE IF NOT EXISTS user_tenants (
+		user_id TEXT NOT NULL,
+		tenant TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, tenant)
+	);
+
+	CREATE TABLE IF NOT EXISTS revoked_tokens (
+		user_id TEXT PRIMARY KEY,
+		revoked_at INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS usage_daily (
+		user_id TEXT NOT NULL,
+		day TEXT NOT NULL,
+		requests INTEGER NOT NULL DEFAULT 0,
+		bytes_in INTEGER NOT NULL DEFAULT 0,
+		bytes_out INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (user_id, day)
+	);
+
+	CREATE TABLE IF NOT EXISTS known_logins (
+		user_id TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		value TEXT NOT NULL,
+		first_seen DATETIME DEFAULT CURRENT_TIMESTAMP,
+		last_seen DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, kind, value)
+	);
+
+	CREATE TABLE IF NOT EXISTS login_challenges (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		code_hash TEXT NOT NULL,
+		tenant TEXT,
+		flags TEXT,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		expires_at INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS device_codes (
+		device_code_hash TEXT PRIMARY KEY,
+		user_code TEXT NOT NULL UNIQUE,
+		client_id TEXT,
+		status TEXT NOT NULL DEFAULT 'pending',
+		user_id TEXT,
+		role TEXT,
+		tenant TEXT,
+		expires_at INTEGER NOT NULL,
+		polled_at INTEGER NOT NULL DEFAULT 0
+	);
+
+	CREATE TABLE IF NOT EXISTS upstream_tokens (
+		scope TEXT PRIMARY KEY,
+		ciphertext BLOB NOT NULL,
+		hint TEXT,
+		updated_by TEXT,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
 	`
 
 	_, err := d.db.Exec(schema)
@@ -134,10 +232,41 @@ func (d *Database) runMigrations() error {
 		log.Println("[DB] Updated existing users with default role")
 	}
 
+	// Queued writes remember the role of their author, whose NocoDB token may differ
+	err = d.db.QueryRow(`
+		SELECT COUNT(*) FROM pragma_table_info('outbox') WHERE name='role'
+	`).Scan(&columnExists)
+
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to check for outbox role column: %v", err)
+		return err
+	}
+
+	if columnExists == 0 {
+		log.Println("[DB] Adding role column to outbox table...")
+		_, err = d.db.Exec(`ALTER TABLE outbox ADD COLUMN role TEXT`)
+		if err != nil {
+			log.Printf("[DB ERROR] Failed to add outbox role column: %v", err)
+			return err
+		}
+		log.Println("[DB] outbox role column added successfully")
+	}
+
 	log.Println("[DB] Migrations completed successfully")
 	return nil
 }
 
+// Ping checks that the database can still be reached
+func (d *Database) Ping(ctx context.Context) error {
+	return d.db.PingContext(ctx)
+}
+
+// Snapshot writes a consistent copy of the database to a new file at path
+func (d *Database) Snapshot(ctx context.Context, path string) error {
+	_, err := d.db.ExecContext(ctx, "VACUUM INTO ?", path)
+	return err
+}
+
 func (d *Database) Close() error {
 	log.Println("[DB] Closing database connection")
 	return d.db.Close()
@@ -268,6 +397,16 @@ func (d *Database) GetAllUsers() ([]*User, error) {
 	return users, nil
 }
 
+// CountUsersWithRole returns the number of users that have a role
+func (d *Database) CountUsersWithRole(role string) (int, error) {
+	var count int
+	if err := d.db.QueryRow("SELECT COUNT(*) FROM users WHERE role = ?", role).Scan(&count); err != nil {
+		log.Printf("[DB ERROR] Failed to count users with role %s: %v", role, err)
+		return 0, err
+	}
+	return count, nil
+}
+
 // UpdateUser updates user information
 func (d *Database) UpdateUser(id int64, name, avatarURL string) error {
 	_, err := d.db.Exec(
@@ -295,6 +434,36 @@ func (d *Database) DeleteUser(id int64) error {
 	return nil
 }
 
+// SetUserRole changes a user's role (user or admin)
+func (d *Database) SetUserRole(id int64, role string) error {
+	_, err := d.db.Exec("UPDATE users SET role = ? WHERE id = ?", role, id)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to set user role: %v", err)
+		return err
+	}
+
+	log.Printf("[DB] User role updated: ID=%d, role=%s", id, role)
+	return nil
+}
+
+// SetUserPassword replaces a user's password (making it a password-capable account)
+func (d *Database) SetUserPassword(id int64, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to hash password: %v", err)
+		return err
+	}
+
+	_, err = d.db.Exec("UPDATE users SET password_hash = ? WHERE id = ?", string(hashedPassword), id)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to set user password: %v", err)
+		return err
+	}
+
+	log.Printf("[DB] User password updated: ID=%d", id)
+	return nil
+}
+
 // CreateLocalUser creates a new user with email/password authentication
 func (d *Database) CreateLocalUser(email, password, name string) (*User, error) {
 	log.Printf("[DB] Creating local user: email=%s", email)
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"log"
+)
+
+// AddUserTenant grants a user access to a tenant
+func (d *Database) AddUserTenant(userID, tenant string) error {
+	_, err := d.db.Exec("INSERT OR IGNORE INTO user_tenants (user_id, tenant) VALUES (?, ?)", userID, tenant)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to add user %s to tenant %s: %v", userID, tenant, err)
+	}
+	return err
+}
+
+// RemoveUserTenant revokes a user's access to a tenant
+func (d *Database) RemoveUserTenant(userID, tenant string) error {
+	_, err := d.db.Exec("DELETE FROM user_tenants WHERE user_id = ? AND tenant = ?", userID, tenant)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to remove user %s from tenant %s: %v", userID, tenant, err)
+	}
+	return err
+}
+
+// RemoveAllUserTenants revokes all of a user's tenant memberships
+func (d *Database) RemoveAllUserTenants(userID string) error {
+	_, err := d.db.Exec("DELETE FROM user_tenants WHERE user_id = ?", userID)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to remove tenant memberships of user %s: %v", userID, err)
+	}
+	return err
+}
+
+// UserHasTenant reports whether a user is a member of a tenant
+func (d *Database) UserHasTenant(userID, tenant string) (bool, error) {
+	var count int
+	err := d.db.QueryRow("SELECT COUNT(*) FROM user_tenants WHERE user_id = ? AND tenant = ?", userID, tenant).Scan(&count)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to check tenant membership for user %s: %v", userID, err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetUserTenants returns the tenants a user belongs to
+func (d *Database) GetUserTenants(userID string) ([]string, error) {
+	rows, err := d.db.Query("SELECT tenant FROM user_tenants WHERE user_id = ? ORDER BY tenant", userID)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to get tenants for user %s: %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tenants := []string{}
+	for rows.Next() {
+		var tenant string
+		if err := rows.Scan(&tenant); err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, rows.Err()
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// UpstreamToken is an encrypted NocoDB token stored for a tenant or base
+type UpstreamToken struct {
+	Scope      string
+	Ciphertext []byte
+	Hint       string // last characters of the plaintext token, for identification
+	UpdatedBy  string
+	UpdatedAt  time.Time
+}
+
+// GetUpstreamToken returns the stored token for a scope (nil if none)
+func (d *Database) GetUpstreamToken(scope string) (*UpstreamToken, error) {
+	token := &UpstreamToken{}
+	var hint, updatedBy sql.NullString
+	err := d.db.QueryRow(
+		"SELECT scope, ciphertext, hint, updated_by, updated_at FROM upstream_tokens WHERE scope = ?", scope,
+	).Scan(&token.Scope, &token.Ciphertext, &hint, &updatedBy, &token.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to get upstream token for %s: %v", scope, err)
+		return nil, err
+	}
+	token.Hint = hint.String
+	token.UpdatedBy = updatedBy.String
+	return token, nil
+}
+
+// ListUpstreamTokens returns all stored tokens
+func (d *Database) ListUpstreamTokens() ([]*UpstreamToken, error) {
+	rows, err := d.db.Query("SELECT scope, ciphertext, hint, updated_by, updated_at FROM upstream_tokens ORDER BY scope")
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to list upstream tokens: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*UpstreamToken
+	for rows.Next() {
+		token := &UpstreamToken{}
+		var hint, updatedBy sql.NullString
+		if err := rows.Scan(&token.Scope, &token.Ciphertext, &hint, &updatedBy, &token.UpdatedAt); err != nil {
+			return nil, err
+		}
+		token.Hint = hint.String
+		token.UpdatedBy = updatedBy.String
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// SetUpstreamToken stores (or rotates) the encrypted token for a scope
+func (d *Database) SetUpstreamToken(scope string, ciphertext []byte, hint, updatedBy string) error {
+	_, err := d.db.Exec(
+		`INSERT INTO upstream_tokens (scope, ciphertext, hint, updated_by, updated_at) VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		 ON CONFLICT(scope) DO UPDATE SET ciphertext = excluded.ciphertext, hint = excluded.hint,
+		 updated_by = excluded.updated_by, updated_at = CURRENT_TIMESTAMP`,
+		scope, ciphertext, hint, updatedBy,
+	)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to store upstream token for %s: %v", scope, err)
+	}
+	return err
+}
+
+// DeleteUpstreamToken removes the stored token for a scope
+func (d *Database) DeleteUpstreamToken(scope string) error {
+	_, err := d.db.Exec("DELETE FROM upstream_tokens WHERE scope = ?", scope)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to delete upstream token for %s: %v", scope, err)
+	}
+	return err
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"log"
+)
+
+// Usage is the data API traffic of one user, per UTC day or summed over a period
+type Usage struct {
+	UserID   string `json:"user_id,omitempty"`
+	Day      string `json:"day,omitempty"` // YYYY-MM-DD, empty for totals
+	Requests int64  `json:"requests"`
+	BytesIn  int64  `json:"bytes_in"`
+	BytesOut int64  `json:"bytes_out"`
+}
+
+// AddUsage adds traffic to a user's counters for a day
+func (d *Database) AddUsage(u Usage) error {
+	_, err := d.db.Exec(`
+		INSERT INTO usage_daily (user_id, day, requests, bytes_in, bytes_out) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, day) DO UPDATE SET
+			requests = requests + excluded.requests,
+			bytes_in = bytes_in + excluded.bytes_in,
+			bytes_out = bytes_out + excluded.bytes_out`,
+		u.UserID, u.Day, u.Requests, u.BytesIn, u.BytesOut,
+	)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to record usage of user %s: %v", u.UserID, err)
+	}
+	return err
+}
+
+// UserUsageTotal sums a user's traffic for the days from (inclusive) to (exclusive)
+func (d *Database) UserUsageTotal(userID, from, to string) (Usage, error) {
+	total := Usage{UserID: userID}
+	err := d.db.QueryRow(`
+		SELECT COALESCE(SUM(requests), 0), COALESCE(SUM(bytes_in), 0), COALESCE(SUM(bytes_out), 0)
+		FROM usage_daily WHERE user_id = ? AND day >= ? AND day < ?`,
+		userID, from, to,
+	).Scan(&total.Requests, &total.BytesIn, &total.BytesOut)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to read usage of user %s: %v", userID, err)
+	}
+	return total, err
+}
+
+// ListUserUsage returns a user's daily counters for the days from (inclusive) to (exclusive)
+func (d *Database) ListUserUsage(userID, from, to string) ([]Usage, error) {
+	rows, err := d.db.Query(
+		"SELECT user_id, day, requests, bytes_in, bytes_out FROM usage_daily WHERE user_id = ? AND day >= ? AND day < ? ORDER BY day",
+		userID, from, to,
+	)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to list usage of user %s: %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []Usage{}
+	for rows.Next() {
+		var u Usage
+		if err := rows.Scan(&u.UserID, &u.Day, &u.Requests, &u.BytesIn, &u.BytesOut); err != nil {
+			return nil, err
+		}
+		days = append(days, u)
+	}
+	return days, rows.Err()
+}
+
+// UsageByUser sums the traffic of every user for the days from (inclusive) to
+// (exclusive), busiest users first
+func (d *Database) UsageByUser(from, to string) ([]Usage, error) {
+	rows, err := d.db.Query(`
+		SELECT user_id, SUM(requests), SUM(bytes_in), SUM(bytes_out)
+		FROM usage_daily WHERE day >= ? AND day < ?
+		GROUP BY user_id ORDER BY SUM(requests) DESC, user_id`,
+		from, to,
+	)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to list usage: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := []Usage{}
+	for rows.Next() {
+		var u Usage
+		if err := rows.Scan(&u.UserID, &u.Requests, &u.BytesIn, &u.BytesOut); err != nil {
+			return nil, err
+		}
+		totals = append(totals, u)
+	}
+	return totals, rows.Err()
+}
+
+// DeleteUserUsage removes a user's usage history
+func (d *Database) DeleteUserUsage(userID string) error {
+	_, err := d.db.Exec("DELETE FROM usage_daily WHERE user_id = ?", userID)
+	if err != nil {
+		log.Printf("[DB ERROR] Failed to delete usage of user %s: %v", userID, err)
+	}
+	return err
+}
@@ -0,0 +1,144 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event types emitted by the gateway
+const (
+	TypeRecordCreated = "record.created"
+	TypeRecordUpdated = "record.updated"
+	TypeRecordDeleted = "record.deleted"
+	TypeSchemaChanged = "schema.changed"
+
+	TypeAuthLogin           = "auth.login"
+	TypeAuthLoginFailed     = "auth.login_failed"
+	TypeAuthLoginChallenged = "auth.login_challenged" // held back until confirmed by email
+	TypeAuthSignup          = "auth.signup"
+	TypeAuthDeleted         = "auth.account_deleted"
+	TypeAuthRoleChanged     = "auth.role_changed"
+	TypeAuthLockout         = "auth.lockout" // a held-back login ran out of verification attempts
+)
+
+// Event sources
+const (
+	SourceNocoDBWebhook = "nocodb-webhook"
+	SourceProxy         = "proxy"
+	SourceAuth          = "auth"
+	SourceCDC           = "cdc"
+)
+
+// Event represents a change that happened in (or passed through) the gateway
+type Event struct {
+	ID        string                   `json:"id"`
+	Type      string                   `json:"type"`
+	Source    string                   `json:"source"`
+	TableID   string                   `json:"table_id,omitempty"`
+	TableName string                   `json:"table_name,omitempty"`
+	RecordID  string                   `json:"record_id,omitempty"`
+	UserID    string                   `json:"user_id,omitempty"`
+	Records   []map[string]interface{} `json:"records,omitempty"`
+	Previous  []map[string]interface{} `json:"previous,omitempty"`
+	Metadata  map[string]string        `json:"metadata,omitempty"`
+	Timestamp time.Time                `json:"timestamp"`
+}
+
+// Bus is a thread-safe in-process publish/subscribe hub for gateway events
+type Bus struct {
+	mu      sync.RWMutex
+	subs    map[int]*subscriber
+	nextID  int
+	dropped atomic.Uint64
+}
+
+// subscriber is a subscription's channel and what to do when it is full
+type subscriber struct {
+	ch       chan Event
+	overflow func(Event)
+}
+
+// NewBus creates a new event bus
+func NewBus() *Bus {
+	return &Bus{
+		subs: make(map[int]*subscriber),
+	}
+}
+
+// Subscribe registers a new subscriber with the given buffer size.
+// It returns the event channel and a function that cancels the subscription.
+func (b *Bus) Subscribe(buffer int) (<-chan Event, func()) {
+	return b.SubscribeOverflow(buffer, nil)
+}
+
+// SubscribeOverflow is Subscribe for subscribers that must not miss events,
+// such as cache invalidation: when the buffer is full, overflow is called with
+// the event from the publishing goroutine instead of dropping it. overflow
+// must be quick and must not publish.
+func (b *Bus) SubscribeOverflow(buffer int, overflow func(Event)) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = &subscriber{ch: ch, overflow: overflow}
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+
+	return ch, cancel
+}
+
+// Publish delivers an event to all subscribers without blocking.
+// Subscribers whose buffers are full miss the event, unless they subscribed
+// with an overflow function; the misses are counted.
+func (b *Bus) Publish(e Event) {
+	if e.ID == "" {
+		e.ID = NewID()
+	}
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now().UTC()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for id, sub := range b.subs {
+		select {
+		case sub.ch <- e:
+		default:
+			if sub.overflow != nil {
+				sub.overflow(e)
+				continue
+			}
+			dropped := b.dropped.Add(1)
+			log.Printf("[EVENTS WARN] Subscriber %d is full, dropping event %s (%s); %d dropped so far", id, e.ID, e.Type, dropped)
+		}
+	}
+}
+
+// Dropped returns the number of deliveries missed by full subscribers
+func (b *Bus) Dropped() uint64 {
+	return b.dropped.Load()
+}
+
+// NewID generates a random event identifier
+func NewID() string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return time.Now().UTC().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(buf)
+}
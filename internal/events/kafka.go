@@ -0,0 +1,42 @@
+package events
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaPublisher publishes events to Kafka topics
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher creates a Kafka writer for the given brokers
+func NewKafkaPublisher(brokers []string) (*KafkaPublisher, error) {
+	writer := &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+		BatchTimeout:           50 * time.Millisecond,
+	}
+
+	log.Printf("[EVENTS] Kafka publisher configured for brokers: %v", brokers)
+	return &KafkaPublisher{writer: writer}, nil
+}
+
+// Publish writes the payload to the topic, keyed for per-record ordering
+func (p *KafkaPublisher) Publish(ctx context.Context, topic, key string, payload []byte) error {
+	return p.writer.WriteMessages(ctx, kafka.Message{
+		Topic: topic,
+		Key:   []byte(key),
+		Value: payload,
+	})
+}
+
+// Close flushes pending messages and closes the writer
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
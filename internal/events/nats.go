@@ -0,0 +1,49 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSPublisher publishes events to NATS JetStream
+type NATSPublisher struct {
+	conn *nats.Conn
+	js   nats.JetStreamContext
+}
+
+// NewNATSPublisher connects to a NATS server and obtains a JetStream context
+func NewNATSPublisher(url string) (*NATSPublisher, error) {
+	conn, err := nats.Connect(url, nats.Name("nocodb-gateway"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	log.Printf("[EVENTS] Connected to NATS at %s", conn.ConnectedUrl())
+	return &NATSPublisher{conn: conn, js: js}, nil
+}
+
+// Publish sends the payload to the subject and waits for the JetStream ack
+func (p *NATSPublisher) Publish(ctx context.Context, subject, key string, payload []byte) error {
+	msg := nats.NewMsg(subject)
+	msg.Data = payload
+	if key != "" {
+		msg.Header.Set("Gateway-Event-Key", key)
+	}
+
+	_, err := p.js.PublishMsg(msg, nats.Context(ctx))
+	return err
+}
+
+// Close drains and closes the NATS connection
+func (p *NATSPublisher) Close() error {
+	return p.conn.Drain()
+}
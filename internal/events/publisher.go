@@ -0,0 +1,156 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Envelope schemas supported by the external publisher
+const (
+	SchemaGateway     = "gateway"
+	SchemaCloudEvents = "cloudevents"
+)
+
+// Publisher sends encoded events to an external broker
+type Publisher interface {
+	Publish(ctx context.Context, topic, key string, payload []byte) error
+	Close() error
+}
+
+// PublisherConfig configures the external event publisher
+type PublisherConfig struct {
+	Backend       string // nats or kafka
+	URL           string // NATS server URL or comma-separated Kafka brokers
+	TopicTemplate string // e.g. "gateway.{type}.{table}"
+	Schema        string // gateway or cloudevents
+}
+
+// NewPublisher creates a publisher for the configured backend
+func NewPublisher(cfg PublisherConfig) (Publisher, error) {
+	switch strings.ToLower(cfg.Backend) {
+	case "nats":
+		return NewNATSPublisher(cfg.URL)
+	case "kafka":
+		return NewKafkaPublisher(strings.Split(cfg.URL, ","))
+	default:
+		return nil, fmt.Errorf("unknown events backend '%s' (expected nats or kafka)", cfg.Backend)
+	}
+}
+
+// Forwarder relays events from the in-process bus to an external publisher
+type Forwarder struct {
+	bus       *Bus
+	publisher Publisher
+	cfg       PublisherConfig
+	cancel    func()
+}
+
+// NewForwarder creates a new bus-to-broker forwarder
+func NewForwarder(bus *Bus, publisher Publisher, cfg PublisherConfig) *Forwarder {
+	if cfg.TopicTemplate == "" {
+		cfg.TopicTemplate = "gateway.{type}"
+	}
+	if cfg.Schema == "" {
+		cfg.Schema = SchemaGateway
+	}
+	return &Forwarder{
+		bus:       bus,
+		publisher: publisher,
+		cfg:       cfg,
+	}
+}
+
+// Start subscribes to the bus and publishes events in the background
+func (f *Forwarder) Start() {
+	ch, cancel := f.bus.Subscribe(1000)
+	f.cancel = cancel
+
+	go func() {
+		log.Printf("[EVENTS] Forwarding events to %s (topic: %s, schema: %s)", f.cfg.Backend, f.cfg.TopicTemplate, f.cfg.Schema)
+		for event := range ch {
+			f.forward(event)
+		}
+	}()
+}
+
+// Stop unsubscribes from the bus and closes the publisher
+func (f *Forwarder) Stop() error {
+	if f.cancel != nil {
+		f.cancel()
+	}
+	return f.publisher.Close()
+}
+
+// forward encodes and publishes a single event
+func (f *Forwarder) forward(event Event) {
+	payload, err := Encode(event, f.cfg.Schema)
+	if err != nil {
+		log.Printf("[EVENTS ERROR] Failed to encode event %s: %v", event.ID, err)
+		return
+	}
+
+	topic := Topic(f.cfg.TopicTemplate, event)
+	key := event.TableName
+	if event.RecordID != "" {
+		key += ":" + event.RecordID
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := f.publisher.Publish(ctx, topic, key, payload); err != nil {
+		log.Printf("[EVENTS ERROR] Failed to publish %s to '%s': %v", event.ID, topic, err)
+		return
+	}
+	log.Printf("[EVENTS] Published %s (%s) to '%s'", event.Type, event.ID, topic)
+}
+
+// Topic expands {type}, {table} and {source} placeholders in a topic template
+func Topic(template string, event Event) string {
+	table := event.TableName
+	if table == "" {
+		table = "_"
+	}
+	return strings.NewReplacer(
+		"{type}", event.Type,
+		"{table}", sanitizeTopicPart(table),
+		"{source}", event.Source,
+	).Replace(template)
+}
+
+// sanitizeTopicPart replaces characters that are not valid in NATS subjects or Kafka topics
+func sanitizeTopicPart(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// Encode serializes an event using the given envelope schema
+func Encode(event Event, schema string) ([]byte, error) {
+	switch schema {
+	case SchemaCloudEvents:
+		return json.Marshal(map[string]interface{}{
+			"specversion":     "1.0",
+			"id":              event.ID,
+			"type":            event.Type,
+			"source":          "nocodb-gateway/" + event.Source,
+			"subject":         event.TableName,
+			"time":            event.Timestamp.Format(time.RFC3339Nano),
+			"datacontenttype": "application/json",
+			"data":            event,
+		})
+	case SchemaGateway, "":
+		return json.Marshal(event)
+	default:
+		return nil, fmt.Errorf("unknown event schema '%s'", schema)
+	}
+}
@@ -0,0 +1,227 @@
+// Package geoip allows or denies requests by the country of the client
+// address, looked up in a MaxMind (GeoLite2/GeoIP2) country database.
+package geoip
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+type contextKey string
+
+// CountryKey holds the client's ISO country code in the request context ("" when unknown)
+const CountryKey contextKey = "geoip_country"
+
+// Country returns the country resolved for a request by the filter's middleware
+func Country(ctx context.Context) string {
+	country, _ := ctx.Value(CountryKey).(string)
+	return country
+}
+
+type rule struct {
+	allow, deny map[string]bool
+	denyUnknown bool
+}
+
+func newRule(r config.GeoIPRule) rule {
+	compiled := rule{allow: codes(r.Allow), deny: codes(r.Deny)}
+	switch r.Unknown {
+	case "deny":
+		compiled.denyUnknown = true
+	case "":
+		compiled.denyUnknown = len(compiled.allow) > 0
+	}
+	return compiled
+}
+
+func codes(list []string) map[string]bool {
+	set := make(map[string]bool, len(list))
+	for _, code := range list {
+		set[strings.ToUpper(code)] = true
+	}
+	return set
+}
+
+// permits reports whether a country ("" when unknown) may pass
+func (r rule) permits(country string) bool {
+	if country == "" {
+		return !r.denyUnknown
+	}
+	if r.deny[country] {
+		return false
+	}
+	return len(r.allow) == 0 || r.allow[country]
+}
+
+// Filter resolves client countries and applies the geoip rules of proxy.yaml
+type Filter struct {
+	reader  *maxminddb.Reader
+	global  rule
+	groups  map[string]rule
+	trusted []*net.IPNet
+}
+
+// Open loads the country database at path. The rules in cfg are validated by
+// the config loader.
+func Open(path string, cfg *config.GeoIPConfig) (*Filter, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+	f := &Filter{reader: reader, global: newRule(cfg.GeoIPRule), groups: make(map[string]rule)}
+	for group, r := range cfg.Groups {
+		f.groups[group] = newRule(r)
+	}
+	for _, proxy := range cfg.TrustedProxies {
+		if ip := net.ParseIP(proxy); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			f.trusted = append(f.trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			reader.Close()
+			return nil, fmt.Errorf("invalid trusted proxy '%s': %w", proxy, err)
+		}
+		f.trusted = append(f.trusted, network)
+	}
+	return f, nil
+}
+
+// Close releases the database
+func (f *Filter) Close() error {
+	return f.reader.Close()
+}
+
+// Description describes the database, e.g. "GeoLite2-Country (2026-10-14)"
+func (f *Filter) Description() string {
+	meta := f.reader.Metadata
+	return fmt.Sprintf("%s (%s)", meta.DatabaseType, time.Unix(int64(meta.BuildEpoch), 0).UTC().Format("2006-01-02"))
+}
+
+// Lookup returns the ISO country code of an address, "" when it has none
+// (private ranges, addresses missing from the database)
+func (f *Filter) Lookup(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	var record struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+		// Anycast and satellite providers have no country, only a registered one
+		RegisteredCountry struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"registered_country"`
+	}
+	if err := f.reader.Lookup(ip, &record); err != nil {
+		log.Printf("[GEOIP ERROR] Lookup of %s failed: %v", ip, err)
+		return ""
+	}
+	if record.Country.ISOCode != "" {
+		return record.Country.ISOCode
+	}
+	return record.RegisteredCountry.ISOCode
+}
+
+// ClientIP returns the address of the client. X-Forwarded-For is honored only
+// when the peer is a trusted proxy; the rightmost untrusted hop is the client.
+func (f *Filter) ClientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if !f.isTrusted(ip) {
+		return ip
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !f.isTrusted(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+func (f *Filter) isTrusted(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range f.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RouteGroup returns the route group of a path, "" for paths that are never
+// restricted: /health (load balancer checks) and the NocoDB webhook receiver,
+// which authenticates its caller by signature
+func RouteGroup(path string) string {
+	switch {
+	case path == "/health" || strings.HasPrefix(path, "/__proxy/webhooks/"):
+		return ""
+	case strings.HasPrefix(path, "/admin/") || strings.HasPrefix(path, "/__proxy/"):
+		return config.GeoIPGroupAdmin
+	case path == "/login" || strings.HasPrefix(path, "/login/") || path == "/signup" || path == "/auth" || strings.HasPrefix(path, "/auth/"):
+		return config.GeoIPGroupAuth
+	default:
+		return config.GeoIPGroupProxy
+	}
+}
+
+// Middleware resolves the client's country, adds it to the request context and
+// rejects requests its route group's rule (or the global one) does not permit.
+// A nil filter passes every request through.
+func (f *Filter) Middleware(next http.Handler) http.Handler {
+	if f == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		group := RouteGroup(r.URL.Path)
+		if group == "" || middleware.IsPreflight(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ip := f.ClientIP(r)
+		country := f.Lookup(ip)
+		active, ok := f.groups[group]
+		if !ok {
+			active = f.global
+		}
+		if !active.permits(country) {
+			if country == "" {
+				country = "unknown"
+			}
+			log.Printf("[GEOIP] Denied %s %s from %s (country: %s, group: %s)", r.Method, r.URL.Path, ip, country, group)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": "access from your location is not permitted"})
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), CountryKey, country)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,107 @@
+// Package i18n picks the translation of a configured message that best
+// matches a client's Accept-Language header.
+//
+// Translations are keyed by language tag ("en", "de", "pt-br"). A client asking
+// for "de-CH" gets "de-ch" if present, then "de", then any other "de-" variant.
+// When none of the client's languages is available the "default" translation
+// is used, then "en".
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Default is the key of the translation used when no language matches
+const Default = "default"
+
+// Text is a message in one or more languages, keyed by lowercase language tag.
+// In YAML it is either a plain string (the default) or a map of translations.
+type Text map[string]string
+
+// UnmarshalYAML accepts a string or a language -> text map
+func (t *Text) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*t = Text{Default: value.Value}
+		return nil
+	}
+	var translations map[string]string
+	if err := value.Decode(&translations); err != nil {
+		return fmt.Errorf("expected a message or a map of language -> message: %w", err)
+	}
+	*t = make(Text, len(translations))
+	for language, text := range translations {
+		(*t)[strings.ToLower(language)] = text
+	}
+	return nil
+}
+
+// Localize returns the translation for an Accept-Language header and its
+// language, "" when the default was used. Both are empty for an empty Text.
+func (t Text) Localize(acceptLanguage string) (string, string) {
+	for _, language := range Languages(acceptLanguage) {
+		if text, ok := t[language]; ok {
+			return text, language
+		}
+		base, _, _ := strings.Cut(language, "-")
+		if text, ok := t[base]; ok {
+			return text, base
+		}
+		variants := make([]string, 0)
+		for candidate := range t {
+			if strings.HasPrefix(candidate, base+"-") {
+				variants = append(variants, candidate)
+			}
+		}
+		if len(variants) > 0 {
+			sort.Strings(variants)
+			return t[variants[0]], variants[0]
+		}
+	}
+	if text, ok := t[Default]; ok {
+		return text, ""
+	}
+	if text, ok := t["en"]; ok {
+		return text, "en"
+	}
+	return "", ""
+}
+
+// Languages returns the lowercase language tags of an Accept-Language header,
+// most preferred first. Tags with q=0 and the "*" wildcard are left out.
+func Languages(header string) []string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality > 0 {
+			tags = append(tags, weighted{tag: tag, quality: quality})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].quality > tags[j].quality })
+
+	languages := make([]string, len(tags))
+	for i, tag := range tags {
+		languages[i] = tag.tag
+	}
+	return languages
+}
@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/events"
 	"github.com/grove/generic-proxy/internal/proxy"
+	"github.com/grove/generic-proxy/internal/scheduler"
 )
 
 // Handler provides runtime introspection endpoints
@@ -16,6 +18,21 @@ type Handler struct {
 	resolvedConfig  *config.ResolvedConfig
 	proxyConfigPath string
 	mode            string
+	failover        *proxy.Failover
+	replica         *proxy.ReadReplica
+	shadow          *proxy.Shadow
+	concurrency     *proxy.ConcurrencyLimiter
+	breaker         *proxy.CircuitBreaker
+	upstreamPool    *proxy.PooledTransport
+	tableRates      *proxy.TableRateLimiter
+	dryRun          *proxy.DryRun
+	responses       *proxy.ResponseCache
+	records         *proxy.RecordCache
+	events          *events.Bus
+	flags           *config.Flags
+	jobs            *scheduler.Scheduler
+	drift           *DriftMonitor
+	startup         *StartupReport
 }
 
 // NewHandler creates a new introspection handler
@@ -33,6 +50,66 @@ func NewHandler(metaCache *proxy.MetaCache, resolvedConfig *config.ResolvedConfi
 	}
 }
 
+// SetFailover includes primary/standby upstream health in the status response
+func (h *Handler) SetFailover(failover *proxy.Failover) {
+	h.failover = failover
+}
+
+// SetReadReplica includes read replica health in the status response
+func (h *Handler) SetReadReplica(replica *proxy.ReadReplica) {
+	h.replica = replica
+}
+
+// SetConcurrencyLimiter includes upstream concurrency usage in the status response
+func (h *Handler) SetConcurrencyLimiter(limiter *proxy.ConcurrencyLimiter) {
+	h.concurrency = limiter
+}
+
+// SetUpstreamPool includes the connections to NocoDB in the status response and /metrics
+func (h *Handler) SetUpstreamPool(pool *proxy.PooledTransport) {
+	h.upstreamPool = pool
+}
+
+// SetCircuitBreaker includes the circuit breaker of every upstream host in the status response
+func (h *Handler) SetCircuitBreaker(breaker *proxy.CircuitBreaker) {
+	h.breaker = breaker
+}
+
+// SetTableRateLimiter includes the request budgets of tables in the status response
+func (h *Handler) SetTableRateLimiter(limiter *proxy.TableRateLimiter) {
+	h.tableRates = limiter
+}
+
+// SetDryRun includes the requests dry-run mode would have refused in the status response
+func (h *Handler) SetDryRun(dryRun *proxy.DryRun) {
+	h.dryRun = dryRun
+}
+
+// SetResponseCache includes response cache counters in the status response
+func (h *Handler) SetResponseCache(cache *proxy.ResponseCache) {
+	h.responses = cache
+}
+
+// SetRecordCache includes record cache memory use and counters in the status response
+func (h *Handler) SetRecordCache(cache *proxy.RecordCache) {
+	h.records = cache
+}
+
+// SetEventBus includes the events dropped by full subscribers in /metrics
+func (h *Handler) SetEventBus(bus *events.Bus) {
+	h.events = bus
+}
+
+// SetFlags reports the gateway's feature flags in the status response
+func (h *Handler) SetFlags(flags config.Flags) {
+	h.flags = &flags
+}
+
+// SetShadow includes shadow traffic counters in the status response
+func (h *Handler) SetShadow(shadow *proxy.Shadow) {
+	h.shadow = shadow
+}
+
 // SchemaResponse represents the schema introspection response
 type SchemaResponse struct {
 	Mode           string               `json:"mode"`
@@ -40,6 +117,8 @@ type SchemaResponse struct {
 	Tables         map[string]TableInfo `json:"tables"`
 	MetaCacheReady bool                 `json:"metacache_ready"`
 	LastRefresh    string               `json:"last_refresh,omitempty"`
+	// Relations maps each NocoDB table ID to its link fields and their target tables
+	Relations map[string][]proxy.Relation `json:"relations,omitempty"`
 }
 
 // TableInfo contains resolved table information
@@ -47,23 +126,45 @@ type TableInfo struct {
 	LogicalName string              `json:"logical_name"`
 	TableID     string              `json:"table_id"`
 	Operations  []string            `json:"operations,omitempty"`
+	Mode        string              `json:"mode,omitempty"` // read_only, write_only or disabled
 	Fields      map[string]string   `json:"fields,omitempty"`
 	Links       map[string]LinkInfo `json:"links,omitempty"`
+	// Column that identifies a row and the column shown as its label (NocoDB titles)
+	PrimaryKey   string `json:"primary_key,omitempty"`
+	DisplayField string `json:"display_field,omitempty"`
 }
 
 // LinkInfo contains resolved link information
 type LinkInfo struct {
-	FieldID     string `json:"field_id"`
-	TargetTable string `json:"target_table"`
+	FieldID       string `json:"field_id"`
+	TargetTable   string `json:"target_table"`
+	TargetTableID string `json:"target_table_id,omitempty"`
+	RelationType  string `json:"relation_type,omitempty"`
+	KeyField      string `json:"key_field,omitempty"`
 }
 
 // StatusResponse represents the status endpoint response
 type StatusResponse struct {
-	MetaCacheReady bool   `json:"metacache_ready"`
-	SchemaResolved bool   `json:"schema_resolved"`
-	TablesResolved int    `json:"tables_resolved"`
-	LastRefresh    string `json:"last_refresh,omitempty"`
-	Mode           string `json:"mode"`
+	MetaCacheReady bool                            `json:"metacache_ready"`
+	MetadataStale  bool                            `json:"metadata_stale,omitempty"`
+	SchemaResolved bool                            `json:"schema_resolved"`
+	TablesResolved int                             `json:"tables_resolved"`
+	LastRefresh    string                          `json:"last_refresh,omitempty"`
+	RefreshMS      int64                           `json:"refresh_duration_ms,omitempty"`
+	Refresh        *proxy.RefreshHealth            `json:"refresh,omitempty"`
+	Mode           string                          `json:"mode"`
+	Upstream       *proxy.UpstreamStatus           `json:"upstream,omitempty"`
+	ReadReplica    *proxy.ReplicaStatus            `json:"read_replica,omitempty"`
+	Shadow         *proxy.ShadowStats              `json:"shadow,omitempty"`
+	Concurrency    *proxy.ConcurrencyStats         `json:"concurrency,omitempty"`
+	CircuitBreaker map[string]proxy.BreakerStats   `json:"circuit_breaker,omitempty"` // host:port -> breaker
+	UpstreamPool   *proxy.PoolStats                `json:"upstream_pool,omitempty"`
+	TableRates     map[string]proxy.TableRateStats `json:"table_rate_limits,omitempty"`
+	DryRun         *proxy.DryRunStats              `json:"dry_run,omitempty"`
+	ResponseCache  *proxy.ResponseCacheStats       `json:"response_cache,omitempty"`
+	RecordCache    *proxy.RecordCacheStats         `json:"record_cache,omitempty"`
+	Flags          *config.Flags                   `json:"flags,omitempty"`
+	Drift          map[string]config.Drift         `json:"drift,omitempty"` // scope -> what proxy.yaml references but NocoDB lacks
 }
 
 // ServeSchema handles GET /__proxy/schema
@@ -87,6 +188,7 @@ func (h *Handler) ServeSchema(w http.ResponseWriter, r *http.Request) {
 		if !lastRefresh.IsZero() {
 			response.LastRefresh = lastRefresh.Format(time.RFC3339)
 		}
+		response.Relations = h.metaCache.AllRelations()
 	}
 
 	// If schema-driven mode, include resolved configuration
@@ -96,10 +198,20 @@ func (h *Handler) ServeSchema(w http.ResponseWriter, r *http.Request) {
 				LogicalName: table.Name,
 				TableID:     table.TableID,
 				Operations:  table.Operations,
+				Mode:        table.Mode,
 				Fields:      make(map[string]string),
 				Links:       make(map[string]LinkInfo),
 			}
 
+			if h.metaCache != nil {
+				if field, ok := h.metaCache.PrimaryKey(table.TableID); ok {
+					tableInfo.PrimaryKey = field.Title
+				}
+				if field, ok := h.metaCache.DisplayField(table.TableID); ok {
+					tableInfo.DisplayField = field.Title
+				}
+			}
+
 			// Add field mappings
 			for fieldAlias, fieldID := range table.Fields {
 				tableInfo.Fields[fieldAlias] = fieldID
@@ -107,10 +219,18 @@ func (h *Handler) ServeSchema(w http.ResponseWriter, r *http.Request) {
 
 			// Add link mappings
 			for linkName, link := range table.Links {
-				tableInfo.Links[linkName] = LinkInfo{
+				linkInfo := LinkInfo{
 					FieldID:     link.FieldID,
 					TargetTable: link.TargetTable,
+					KeyField:    link.KeyField,
 				}
+				if h.metaCache != nil {
+					if relation, ok := h.metaCache.ResolveLinkTarget(table.TableID, link.FieldID); ok {
+						linkInfo.TargetTableID = relation.TargetTableID
+						linkInfo.RelationType = relation.Type
+					}
+				}
+				tableInfo.Links[linkName] = linkInfo
 			}
 
 			response.Tables[tableKey] = tableInfo
@@ -136,6 +256,7 @@ func (h *Handler) ServeStatus(w http.ResponseWriter, r *http.Request) {
 
 	response := StatusResponse{
 		MetaCacheReady: h.metaCache != nil && h.metaCache.IsReady(),
+		MetadataStale:  h.metaCache.IsStale(),
 		SchemaResolved: h.resolvedConfig != nil,
 		TablesResolved: 0,
 		Mode:           h.mode,
@@ -145,11 +266,68 @@ func (h *Handler) ServeStatus(w http.ResponseWriter, r *http.Request) {
 		response.TablesResolved = len(h.resolvedConfig.Tables)
 	}
 
+	if h.failover != nil {
+		upstream := h.failover.Status()
+		response.Upstream = &upstream
+	}
+
+	if h.replica != nil {
+		replica := h.replica.Status()
+		response.ReadReplica = &replica
+	}
+
+	if h.shadow != nil {
+		shadow := h.shadow.Stats()
+		response.Shadow = &shadow
+	}
+
+	if h.concurrency != nil {
+		concurrency := h.concurrency.Stats()
+		response.Concurrency = &concurrency
+	}
+
+	if h.breaker != nil {
+		response.CircuitBreaker = h.breaker.Stats()
+	}
+
+	if h.upstreamPool != nil {
+		pool := h.upstreamPool.Stats()
+		response.UpstreamPool = &pool
+	}
+
+	if drift := h.drift.Drift(); len(drift) > 0 {
+		response.Drift = drift
+	}
+
+	if h.dryRun != nil {
+		dryRun := h.dryRun.Stats()
+		response.DryRun = &dryRun
+	}
+
+	if h.tableRates != nil {
+		response.TableRates = h.tableRates.Stats()
+	}
+
+	if h.responses != nil {
+		responses := h.responses.Stats()
+		response.ResponseCache = &responses
+	}
+
+	if h.records != nil {
+		records := h.records.Stats()
+		response.RecordCache = &records
+	}
+
+	response.Flags = h.flags
+
 	if h.metaCache != nil && h.metaCache.IsReady() {
 		lastRefresh := h.metaCache.GetLastRefreshTime()
 		if !lastRefresh.IsZero() {
 			response.LastRefresh = lastRefresh.Format(time.RFC3339)
 		}
+		response.RefreshMS = h.metaCache.GetLastRefreshDuration().Milliseconds()
+		refresh := h.metaCache.GetRefreshHealth()
+		response.Refresh = &refresh
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,39 @@
+package introspect
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/grove/generic-proxy/internal/scheduler"
+)
+
+// JobsResponse lists the scheduled background jobs
+type JobsResponse struct {
+	Jobs []scheduler.JobStatus `json:"jobs"`
+}
+
+// SetScheduler exposes the background job scheduler at /__proxy/jobs
+func (h *Handler) SetScheduler(jobs *scheduler.Scheduler) {
+	h.jobs = jobs
+}
+
+// ServeJobs handles GET /__proxy/jobs (admin only)
+func (h *Handler) ServeJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := JobsResponse{Jobs: []scheduler.JobStatus{}}
+	if h.jobs != nil {
+		response.Jobs = h.jobs.Status()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[INTROSPECT ERROR] Failed to encode jobs response: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
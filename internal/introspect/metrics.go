@@ -0,0 +1,127 @@
+package introspect
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/proxy"
+)
+
+// DriftMonitor keeps the configuration drift of every scope ("default",
+// "base:{name}" or "tenant:{name}"), recomputed after every metadata refresh
+type DriftMonitor struct {
+	mu    sync.Mutex
+	drift map[string]config.Drift
+}
+
+// NewDriftMonitor creates an empty drift monitor
+func NewDriftMonitor() *DriftMonitor {
+	return &DriftMonitor{drift: make(map[string]config.Drift)}
+}
+
+// Update records the drift of a scope
+func (m *DriftMonitor) Update(scope string, drift config.Drift) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.drift[scope] = drift
+}
+
+// Drift returns a copy of the drift of every scope
+func (m *DriftMonitor) Drift() map[string]config.Drift {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	drift := make(map[string]config.Drift, len(m.drift))
+	for scope, d := range m.drift {
+		drift[scope] = d
+	}
+	return drift
+}
+
+// SetDriftMonitor includes configuration drift in the status response and /metrics
+func (h *Handler) SetDriftMonitor(monitor *DriftMonitor) {
+	h.drift = monitor
+}
+
+// ServeMetrics handles GET /metrics in the Prometheus text format. The drift
+// gauges are meant for alerts such as gateway_config_missing_fields > 0; the
+// upstream pool metrics show how many requests reuse a connection.
+func (h *Handler) ServeMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	drift := h.drift.Drift()
+	scopes := make([]string, 0, len(drift))
+	for scope := range drift {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+
+	var b strings.Builder
+	gauge := func(name, help string, value func(scope string, d config.Drift)) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, scope := range scopes {
+			value(scope, drift[scope])
+		}
+	}
+	gauge("gateway_config_missing_tables", "Tables in proxy.yaml not found in NocoDB.", func(scope string, d config.Drift) {
+		fmt.Fprintf(&b, "gateway_config_missing_tables{scope=%q} %d\n", scope, len(d.MissingTables))
+	})
+	gauge("gateway_config_missing_fields", "Fields referenced in proxy.yaml but missing from their NocoDB table.", func(scope string, d config.Drift) {
+		fmt.Fprintf(&b, "gateway_config_missing_fields{scope=%q} %d\n", scope, d.FieldCount())
+	})
+	gauge("gateway_config_missing_table", "1 for each table in proxy.yaml not found in NocoDB.", func(scope string, d config.Drift) {
+		for _, table := range d.MissingTables {
+			fmt.Fprintf(&b, "gateway_config_missing_table{scope=%q,table=%q} 1\n", scope, table)
+		}
+	})
+	gauge("gateway_config_missing_field", "1 for each field referenced in proxy.yaml but missing from its NocoDB table.", func(scope string, d config.Drift) {
+		tables := make([]string, 0, len(d.MissingFields))
+		for table := range d.MissingFields {
+			tables = append(tables, table)
+		}
+		sort.Strings(tables)
+		for _, table := range tables {
+			for _, field := range d.MissingFields[table] {
+				fmt.Fprintf(&b, "gateway_config_missing_field{scope=%q,table=%q,field=%q} 1\n", scope, table, field)
+			}
+		}
+	})
+	gauge("gateway_config_drift_checked_timestamp_seconds", "When the drift of a scope was last computed.", func(scope string, d config.Drift) {
+		fmt.Fprintf(&b, "gateway_config_drift_checked_timestamp_seconds{scope=%q} %d\n", scope, d.CheckedAt.Unix())
+	})
+
+	// A pool too small for the traffic dials far more connections than it reuses
+	if h.upstreamPool != nil {
+		pool := h.upstreamPool.Stats()
+		hosts := pool.HostNames()
+		metric := func(name, kind, help string, value func(s proxy.HostPoolStats) int64) {
+			fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+			for _, host := range hosts {
+				fmt.Fprintf(&b, "%s{host=%q} %d\n", name, host, value(pool.Hosts[host]))
+			}
+		}
+		metric("gateway_upstream_open_connections", "gauge", "Connections to an upstream host currently open.", func(s proxy.HostPoolStats) int64 { return int64(s.Open) })
+		metric("gateway_upstream_dials_total", "counter", "Connections dialed to an upstream host.", func(s proxy.HostPoolStats) int64 { return s.Dials })
+		metric("gateway_upstream_dial_errors_total", "counter", "Failed dials to an upstream host.", func(s proxy.HostPoolStats) int64 { return s.DialErrors })
+		metric("gateway_upstream_requests_total", "counter", "Requests sent to an upstream host.", func(s proxy.HostPoolStats) int64 { return s.Requests })
+		metric("gateway_upstream_reused_connections_total", "counter", "Requests sent to an upstream host on a kept-alive connection.", func(s proxy.HostPoolStats) int64 { return s.Reused })
+	}
+
+	// Slow SSE, WebSocket, webhook or mirror consumers miss events
+	if h.events != nil {
+		fmt.Fprintf(&b, "# HELP gateway_events_dropped_total Events missed by subscribers whose queue was full.\n# TYPE gateway_events_dropped_total counter\n")
+		fmt.Fprintf(&b, "gateway_events_dropped_total %d\n", h.events.Dropped())
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
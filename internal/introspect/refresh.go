@@ -0,0 +1,170 @@
+package introspect
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/grove/generic-proxy/internal/proxy"
+)
+
+// CacheRefreshResponse reports the MetaCache contents after a forced refresh
+type CacheRefreshResponse struct {
+	Table       string `json:"table,omitempty"`
+	TableID     string `json:"table_id,omitempty"`
+	Tables      int    `json:"tables"`
+	Fields      int    `json:"fields"`
+	LinkFields  int    `json:"link_fields"`
+	RefreshedAt string `json:"refreshed_at"`
+	DurationMS  int64  `json:"duration_ms"`
+}
+
+// ServeCacheRefresh handles POST /__proxy/cache/refresh[?table=name]. It refreshes
+// the MetaCache synchronously (all tables, or a single one) and returns the new counts.
+// Must run after middleware.AuthMiddleware and middleware.RequireAdmin.
+func (h *Handler) ServeCacheRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.metaCache == nil {
+		http.Error(w, "metadata cache is disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	started := time.Now()
+	response := CacheRefreshResponse{}
+
+	if table := r.URL.Query().Get("table"); table != "" {
+		// Accept the logical key from proxy.yaml as well as the NocoDB table name
+		name := table
+		if h.resolvedConfig != nil {
+			if resolved, ok := h.resolvedConfig.Tables[table]; ok {
+				name = resolved.Name
+			}
+		}
+		tableID, err := h.metaCache.RefreshTable(name)
+		if err != nil {
+			log.Printf("[INTROSPECT ERROR] Refresh of table '%s' failed: %v", name, err)
+			http.Error(w, "refresh failed: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		response.Table = name
+		response.TableID = tableID
+	} else if err := h.metaCache.Refresh(); err != nil {
+		log.Printf("[INTROSPECT ERROR] MetaCache refresh failed: %v", err)
+		http.Error(w, "refresh failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	response.Tables = h.metaCache.GetTableCount()
+	response.Fields, response.LinkFields = h.metaCache.GetFieldCount()
+	response.RefreshedAt = time.Now().Format(time.RFC3339)
+	response.DurationMS = time.Since(started).Milliseconds()
+
+	adminID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	log.Printf("[INTROSPECT] Admin %s refreshed MetaCache (%d tables) in %dms", adminID, response.Tables, response.DurationMS)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[INTROSPECT ERROR] Failed to encode refresh response: %v", err)
+	}
+}
+
+// ServeSchemaChanges handles GET /__proxy/schema/changes[?limit=N] with the most
+// recent schema diffs found by MetaCache refreshes, newest first
+func (h *Handler) ServeSchemaChanges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	diffs := []proxy.SchemaDiff{}
+	if h.metaCache != nil {
+		diffs = h.metaCache.SchemaDiffs()
+	}
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit >= 0 && limit < len(diffs) {
+		diffs = diffs[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"changes": diffs}); err != nil {
+		log.Printf("[INTROSPECT ERROR] Failed to encode schema changes: %v", err)
+	}
+}
+
+// CacheResponse exposes the MetaCache contents and resolution counters
+type CacheResponse struct {
+	Ready       bool                             `json:"ready"`
+	Stale       bool                             `json:"stale"`
+	Hash        string                           `json:"hash,omitempty"`
+	LastRefresh string                           `json:"last_refresh,omitempty"`
+	Tables      map[string]string                `json:"tables"`      // lowercase name -> table ID
+	Fields      map[string]map[string]string     `json:"fields"`      // table ID -> lowercase field name -> field ID
+	LinkFields  map[string]map[string]string     `json:"link_fields"` // table ID -> lowercase link field name -> field ID
+	Stats       map[string]proxy.ResolutionStats `json:"stats"`       // lowercase requested table name -> counters
+}
+
+// ServeCache handles GET /__proxy/cache[?table=name|id]. It returns the cached name -> ID
+// mappings and per-table hit/miss counters, optionally narrowed to one table.
+// Must run after middleware.AuthMiddleware and middleware.RequireAdmin.
+func (h *Handler) ServeCache(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.metaCache == nil {
+		http.Error(w, "metadata cache is disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	mappings := h.metaCache.Mappings()
+	response := CacheResponse{
+		Ready:      h.metaCache.IsReady(),
+		Stale:      h.metaCache.IsStale(),
+		Hash:       mappings.Hash,
+		Tables:     mappings.TableByName,
+		Fields:     mappings.FieldsByTable,
+		LinkFields: mappings.LinkFieldsByTable,
+		Stats:      h.metaCache.ResolutionStats(),
+	}
+	if !mappings.SavedAt.IsZero() {
+		response.LastRefresh = mappings.SavedAt.Format(time.RFC3339)
+	}
+
+	if table := strings.ToLower(r.URL.Query().Get("table")); table != "" {
+		// Look the table up in the copied mappings so inspection does not count as a resolution
+		tableID, ok := response.Tables[table]
+		if !ok {
+			tableID = r.URL.Query().Get("table") // a table ID
+		}
+		response.Tables = filterMapping(response.Tables, func(name, id string) bool { return name == table || id == tableID })
+		response.Fields = map[string]map[string]string{tableID: response.Fields[tableID]}
+		response.LinkFields = map[string]map[string]string{tableID: response.LinkFields[tableID]}
+		stats := make(map[string]proxy.ResolutionStats)
+		if tableStats, ok := response.Stats[table]; ok {
+			stats[table] = tableStats
+		}
+		response.Stats = stats
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("[INTROSPECT ERROR] Failed to encode cache response: %v", err)
+	}
+}
+
+// filterMapping keeps the entries of a mapping that match
+func filterMapping(mapping map[string]string, keep func(key, value string) bool) map[string]string {
+	result := make(map[string]string)
+	for key, value := range mapping {
+		if keep(key, value) {
+			result[key] = value
+		}
+	}
+	return result
+}
@@ -0,0 +1,116 @@
+package introspect
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+// StartupReport records the decisions made while the gateway started, so
+// questions like "why is it in legacy mode" are answered by GET
+// /__proxy/startup instead of interleaved log lines. It is filled in by main
+// before the server starts and not changed afterwards.
+type StartupReport struct {
+	StartedAt    time.Time               `json:"started_at"`
+	Mode         string                  `json:"mode"`                    // schema-driven or legacy
+	LegacyReason string                  `json:"legacy_reason,omitempty"` // why schema-driven mode is off
+	Config       StartupConfig           `json:"config"`
+	Metadata     StartupMetadata         `json:"metadata"`
+	Resolution   StartupResolution       `json:"resolution"`
+	Bases        map[string]StartupScope `json:"bases,omitempty"`
+	Tenants      map[string]StartupScope `json:"tenants,omitempty"`
+	Features     []string                `json:"features"` // optional providers and subsystems that are enabled
+	Warnings     []string                `json:"warnings,omitempty"`
+}
+
+// StartupConfig describes how proxy.yaml was found and loaded
+type StartupConfig struct {
+	Path   string `json:"path"`
+	Found  bool   `json:"found"`
+	Loaded bool   `json:"loaded"`
+	Error  string `json:"error,omitempty"`
+	Tables int    `json:"tables"`
+}
+
+// StartupMetadata describes the initial metadata load of the default base
+type StartupMetadata struct {
+	Enabled bool   `json:"enabled"` // NOCODB_BASE_ID is set
+	Loaded  bool   `json:"loaded"`
+	Stale   bool   `json:"stale,omitempty"` // loaded from a snapshot, not from NocoDB
+	BaseID  string `json:"base_id,omitempty"`
+}
+
+// StartupResolution describes the resolution of proxy.yaml against the metadata
+type StartupResolution struct {
+	Attempted bool         `json:"attempted"`
+	Resolved  bool         `json:"resolved"`
+	Error     string       `json:"error,omitempty"`
+	Tables    int          `json:"tables"` // tables resolved
+	Drift     config.Drift `json:"drift"`  // every missing table and field, not only the first
+}
+
+// StartupScope describes an additional base or a tenant
+type StartupScope struct {
+	Enabled bool   `json:"enabled"`
+	Error   string `json:"error,omitempty"`
+}
+
+// NewStartupReport creates a report in legacy mode until resolution succeeds
+func NewStartupReport() *StartupReport {
+	return &StartupReport{
+		StartedAt: time.Now(),
+		Mode:      "legacy",
+		Bases:     make(map[string]StartupScope),
+		Tenants:   make(map[string]StartupScope),
+	}
+}
+
+// Legacy records why schema-driven mode is off; the first reason wins
+func (s *StartupReport) Legacy(reason string) {
+	s.Mode = "legacy"
+	if s.LegacyReason == "" {
+		s.LegacyReason = reason
+	}
+}
+
+// Enable lists an enabled optional feature
+func (s *StartupReport) Enable(feature string) {
+	s.Features = append(s.Features, feature)
+}
+
+// Warn records a problem that did not stop startup
+func (s *StartupReport) Warn(warning string) {
+	s.Warnings = append(s.Warnings, warning)
+}
+
+// SetStartupReport exposes the startup report at /__proxy/startup
+func (h *Handler) SetStartupReport(report *StartupReport) {
+	h.startup = report
+}
+
+// ServeStartup handles GET /__proxy/startup
+func (h *Handler) ServeStartup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.startup == nil {
+		http.Error(w, "startup report not available", http.StatusNotFound)
+		return
+	}
+
+	report := *h.startup
+	report.Features = append([]string{}, report.Features...)
+	sort.Strings(report.Features)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("[INTROSPECT ERROR] Failed to encode startup report: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
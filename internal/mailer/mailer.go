@@ -0,0 +1,75 @@
+// Package mailer sends plain-text notification emails over SMTP.
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Config holds the SMTP server settings
+type Config struct {
+	Host     string
+	Port     string // default 587
+	Username string // no authentication when empty
+	Password string
+	From     string
+}
+
+// Mailer sends emails through one SMTP server. STARTTLS is used whenever the
+// server offers it; credentials are only sent over TLS or to localhost (see
+// smtp.PlainAuth).
+type Mailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// New creates a mailer, nil when no SMTP host is configured
+func New(cfg Config) (*Mailer, error) {
+	if cfg.Host == "" {
+		return nil, nil
+	}
+	if cfg.From == "" {
+		return nil, fmt.Errorf("a sender address is required")
+	}
+	port := cfg.Port
+	if port == "" {
+		port = "587"
+	}
+	m := &Mailer{addr: net.JoinHostPort(cfg.Host, port), from: cfg.From}
+	if cfg.Username != "" {
+		m.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	return m, nil
+}
+
+// Addr returns the SMTP server address
+func (m *Mailer) Addr() string {
+	return m.addr
+}
+
+// Send delivers a plain-text message to one recipient
+func (m *Mailer) Send(to, subject, body string) error {
+	if strings.ContainsAny(to, "\r\n") {
+		return fmt.Errorf("invalid recipient address")
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{to}, msg.Bytes()); err != nil {
+		return fmt.Errorf("failed to send mail to %s: %w", to, err)
+	}
+	return nil
+}
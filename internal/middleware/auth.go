@@ -15,6 +15,7 @@ type contextKey string
 const (
 	UserIDKey contextKey = "user_id"
 	RoleKey   contextKey = "role"
+	TenantKey contextKey = "tenant"
 )
 
 // AuthMiddleware validates JWT tokens and extracts user claims
@@ -54,6 +55,9 @@ func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
 			// Add claims to request context
 			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
 			ctx = context.WithValue(ctx, RoleKey, claims.Role)
+			if claims.Tenant != "" {
+				ctx = context.WithValue(ctx, TenantKey, claims.Tenant)
+			}
 			log.Printf("[AUTH] Authentication successful, proceeding to next handler")
 
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -66,3 +70,14 @@ func respondWithError(w http.ResponseWriter, code int, message string) {
 	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
+
+// RequireAdmin rejects requests whose token (validated by AuthMiddleware) is not an admin's
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if role, _ := r.Context().Value(RoleKey).(string); role != "admin" {
+			respondWithError(w, http.StatusForbidden, "admin role required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
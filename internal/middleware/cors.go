@@ -35,12 +35,14 @@ func CORSMiddleware(next http.Handler) http.Handler {
 
 		// Set other CORS headers
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, xc-token")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, xc-token, X-Budget-Ms, X-Timezone")
+		w.Header().Set("Access-Control-Expose-Headers", "Server-Timing, X-Budget-Ms")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Max-Age", "3600") // Cache preflight for 1 hour
 
-		// Handle preflight (OPTIONS) requests directly
-		if r.Method == http.MethodOptions {
+		// Handle preflight requests directly; other OPTIONS requests reach the
+		// handlers, which advertise the methods a route allows
+		if IsPreflight(r) {
 			log.Printf("[CORS] Handling preflight request for: %s", r.URL.Path)
 			w.WriteHeader(http.StatusOK)
 			return
@@ -50,3 +52,8 @@ func CORSMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// IsPreflight reports whether a request is a CORS preflight request
+func IsPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
@@ -0,0 +1,87 @@
+package mirror
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/proxy"
+)
+
+// Change types recorded in the change log
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+)
+
+// Changes returns up to limit changes for a table with a sequence number greater than since.
+// expired is true when since predates the retained change log and the client must resync.
+func (s *Store) Changes(tableKey string, since int64, limit int) ([]proxy.Change, bool, error) {
+	if since > 0 && since < s.prunedThrough() {
+		return nil, true, nil
+	}
+
+	rows, err := s.db.Query(
+		`SELECT seq, record_id, change_type, data, changed_at FROM mirror_changes
+		 WHERE table_key = ? AND seq > ? ORDER BY seq LIMIT ?`,
+		tableKey, since, limit,
+	)
+	if err != nil {
+		log.Printf("[MIRROR ERROR] Failed to read changes for %s: %v", tableKey, err)
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	var changes []proxy.Change
+	for rows.Next() {
+		var change proxy.Change
+		var data sql.NullString
+		if err := rows.Scan(&change.Seq, &change.RecordID, &change.Type, &data, &change.ChangedAt); err != nil {
+			return nil, false, err
+		}
+		if data.Valid && data.String != "" {
+			json.Unmarshal([]byte(data.String), &change.Record)
+		}
+		changes = append(changes, change)
+	}
+	return changes, false, rows.Err()
+}
+
+// PruneChanges deletes change log entries older than the retention period
+func (s *Store) PruneChanges(retention time.Duration) (int64, error) {
+	cutoff := time.Now().UTC().Add(-retention)
+
+	var maxSeq sql.NullInt64
+	if err := s.db.QueryRow("SELECT MAX(seq) FROM mirror_changes WHERE changed_at < ?", cutoff).Scan(&maxSeq); err != nil {
+		return 0, err
+	}
+	if !maxSeq.Valid {
+		return 0, nil
+	}
+
+	result, err := s.db.Exec("DELETE FROM mirror_changes WHERE seq <= ?", maxSeq.Int64)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := s.db.Exec(
+		`INSERT INTO mirror_meta (key, value) VALUES ('pruned_through', ?)
+		 ON CONFLICT(key) DO UPDATE SET value = excluded.value`,
+		strconv.FormatInt(maxSeq.Int64, 10),
+	); err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// prunedThrough returns the highest sequence number removed by pruning
+func (s *Store) prunedThrough() int64 {
+	var value string
+	if err := s.db.QueryRow("SELECT value FROM mirror_meta WHERE key = 'pruned_through'").Scan(&value); err != nil {
+		return 0
+	}
+	seq, _ := strconv.ParseInt(value, 10, 64)
+	return seq
+}
@@ -0,0 +1,287 @@
+package mirror
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Store persists mirrored table records in a local SQLite database
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore opens (or creates) the mirror database
+func NewStore(path string) (*Store, error) {
+	log.Printf("[MIRROR] Opening mirror database at: %s", path)
+
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	schema := `
+	CREATE TABLE IF NOT EXISTS mirror_records (
+		table_key TEXT NOT NULL,
+		record_id TEXT NOT NULL,
+		data TEXT NOT NULL,
+		synced_at DATETIME NOT NULL,
+		PRIMARY KEY (table_key, record_id)
+	);
+
+	CREATE TABLE IF NOT EXISTS mirror_state (
+		table_key TEXT PRIMARY KEY,
+		last_sync DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS mirror_changes (
+		seq INTEGER PRIMARY KEY AUTOINCREMENT,
+		table_key TEXT NOT NULL,
+		record_id TEXT NOT NULL,
+		change_type TEXT NOT NULL,
+		data TEXT,
+		changed_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_mirror_changes_table ON mirror_changes(table_key, seq);
+
+	CREATE TABLE IF NOT EXISTS mirror_meta (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
+	`
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("failed to initialize mirror schema: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the mirror database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// ReplaceTable reconciles the mirrored copy of a table with a full upstream snapshot,
+// recording created/updated/deleted entries in the change log
+func (s *Store) ReplaceTable(tableKey string, records map[string]map[string]interface{}) error {
+	existing, err := s.tableData(tableKey)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	for id, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to encode record %s: %w", id, err)
+		}
+		if err := upsertTx(tx, tableKey, id, string(data), existing, now); err != nil {
+			return err
+		}
+	}
+
+	for id, data := range existing {
+		if _, ok := records[id]; ok {
+			continue
+		}
+		if err := deleteTx(tx, tableKey, id, data, now); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO mirror_state (table_key, last_sync) VALUES (?, ?)
+		 ON CONFLICT(table_key) DO UPDATE SET last_sync = excluded.last_sync`,
+		tableKey, now,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Upsert stores a single record and logs the change
+func (s *Store) Upsert(tableKey, recordID string, record map[string]interface{}) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]string)
+	var current string
+	err = s.db.QueryRow("SELECT data FROM mirror_records WHERE table_key = ? AND record_id = ?", tableKey, recordID).Scan(&current)
+	if err == nil {
+		existing[recordID] = current
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := upsertTx(tx, tableKey, recordID, string(data), existing, time.Now().UTC()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete removes a single record and logs the change
+func (s *Store) Delete(tableKey, recordID string) error {
+	var current string
+	err := s.db.QueryRow("SELECT data FROM mirror_records WHERE table_key = ? AND record_id = ?", tableKey, recordID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteTx(tx, tableKey, recordID, current, time.Now().UTC()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// tableData returns the raw stored JSON of every mirrored record in a table
+func (s *Store) tableData(tableKey string) (map[string]string, error) {
+	rows, err := s.db.Query("SELECT record_id, data FROM mirror_records WHERE table_key = ?", tableKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := make(map[string]string)
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		existing[id] = data
+	}
+	return existing, rows.Err()
+}
+
+// upsertTx writes a record if it is new or changed, appending to the change log
+func upsertTx(tx *sql.Tx, tableKey, recordID, data string, existing map[string]string, now time.Time) error {
+	previous, exists := existing[recordID]
+	if exists && previous == data {
+		return nil
+	}
+
+	changeType := ChangeUpdated
+	if !exists {
+		changeType = ChangeCreated
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO mirror_records (table_key, record_id, data, synced_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(table_key, record_id) DO UPDATE SET data = excluded.data, synced_at = excluded.synced_at`,
+		tableKey, recordID, data, now,
+	); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(
+		"INSERT INTO mirror_changes (table_key, record_id, change_type, data, changed_at) VALUES (?, ?, ?, ?, ?)",
+		tableKey, recordID, changeType, data, now,
+	)
+	return err
+}
+
+// deleteTx removes a record, keeping its last known data in the change log
+// so row-level permissions can still be applied to the deletion
+func deleteTx(tx *sql.Tx, tableKey, recordID, lastData string, now time.Time) error {
+	if _, err := tx.Exec("DELETE FROM mirror_records WHERE table_key = ? AND record_id = ?", tableKey, recordID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(
+		"INSERT INTO mirror_changes (table_key, record_id, change_type, data, changed_at) VALUES (?, ?, ?, ?, ?)",
+		tableKey, recordID, ChangeDeleted, lastData, now,
+	)
+	return err
+}
+
+// Get returns a mirrored record and the time its table was last fully synced
+func (s *Store) Get(tableKey, recordID string) (map[string]interface{}, time.Time, bool) {
+	lastSync, ok := s.LastSync(tableKey)
+	if !ok {
+		return nil, time.Time{}, false
+	}
+
+	var data string
+	err := s.db.QueryRow("SELECT data FROM mirror_records WHERE table_key = ? AND record_id = ?", tableKey, recordID).Scan(&data)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("[MIRROR ERROR] Failed to read record %s/%s: %v", tableKey, recordID, err)
+		}
+		return nil, lastSync, false
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		return nil, lastSync, false
+	}
+	return record, lastSync, true
+}
+
+// List returns all mirrored records of a table and the time it was last fully synced
+func (s *Store) List(tableKey string) ([]map[string]interface{}, time.Time, bool) {
+	lastSync, ok := s.LastSync(tableKey)
+	if !ok {
+		return nil, time.Time{}, false
+	}
+
+	rows, err := s.db.Query("SELECT data FROM mirror_records WHERE table_key = ? ORDER BY rowid", tableKey)
+	if err != nil {
+		log.Printf("[MIRROR ERROR] Failed to list records for %s: %v", tableKey, err)
+		return nil, lastSync, false
+	}
+	defer rows.Close()
+
+	var records []map[string]interface{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, lastSync, false
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &record); err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, lastSync, true
+}
+
+// LastSync returns when a table was last fully synced
+func (s *Store) LastSync(tableKey string) (time.Time, bool) {
+	var lastSync time.Time
+	err := s.db.QueryRow("SELECT last_sync FROM mirror_state WHERE table_key = ?", tableKey).Scan(&lastSync)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return lastSync, true
+}
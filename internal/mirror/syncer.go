@@ -0,0 +1,241 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/events"
+	"github.com/grove/generic-proxy/internal/proxy"
+	"github.com/grove/generic-proxy/internal/scheduler"
+)
+
+const (
+	defaultSyncInterval = 5 * time.Minute
+	defaultRetention    = 30 * 24 * time.Hour
+	pruneInterval       = 1 * time.Hour
+	syncPageSize        = 200
+	maxSyncPages        = 5000
+)
+
+// Indexer receives mirrored records as they are synced (e.g. a search index)
+type Indexer interface {
+	IndexTable(tableKey string, records map[string]map[string]interface{})
+	IndexRecord(tableKey, recordID string, record map[string]interface{})
+	DeleteRecord(tableKey, recordID string)
+}
+
+// Syncer keeps the local mirror up to date with full resyncs and incremental events
+type Syncer struct {
+	store     *Store
+	client    *proxy.UpstreamClient
+	bus       *events.Bus
+	config    *config.ResolvedConfig
+	retention time.Duration
+	indexer   Indexer
+}
+
+// NewSyncer creates a new mirror syncer
+func NewSyncer(store *Store, client *proxy.UpstreamClient, bus *events.Bus, resolvedConfig *config.ResolvedConfig) *Syncer {
+	return &Syncer{
+		store:     store,
+		client:    client,
+		bus:       bus,
+		config:    resolvedConfig,
+		retention: defaultRetention,
+	}
+}
+
+// SetChangeRetention sets how long change log entries are kept for delta sync
+func (s *Syncer) SetChangeRetention(retention time.Duration) {
+	if retention > 0 {
+		s.retention = retention
+	}
+}
+
+// SetIndexer sets an indexer that is kept in step with the mirror
+func (s *Syncer) SetIndexer(indexer Indexer) {
+	s.indexer = indexer
+}
+
+// Start schedules periodic full syncs for mirrored tables and applies bus events incrementally.
+// It returns the number of mirrored tables.
+func (s *Syncer) Start(ctx context.Context, jobs *scheduler.Scheduler) int {
+	if s.config == nil {
+		return 0
+	}
+
+	mirrored := 0
+	for tableKey, table := range s.config.Tables {
+		if table.Mirror == nil || !table.Mirror.Enabled {
+			continue
+		}
+		if err := jobs.Add(s.syncJob(tableKey, table)); err != nil {
+			log.Printf("[MIRROR ERROR] Cannot schedule sync of table '%s': %v", tableKey, err)
+			continue
+		}
+		mirrored++
+	}
+
+	if mirrored > 0 && s.bus != nil {
+		ch, cancel := s.bus.Subscribe(1000)
+		go func() {
+			defer cancel()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case event, ok := <-ch:
+					if !ok {
+						return
+					}
+					s.apply(event)
+				}
+			}
+		}()
+	}
+
+	if mirrored > 0 {
+		if err := jobs.Add(s.pruneJob()); err != nil {
+			log.Printf("[MIRROR ERROR] Cannot schedule change log pruning: %v", err)
+		}
+		log.Printf("[MIRROR] Mirroring %d table(s) locally", mirrored)
+	}
+	return mirrored
+}
+
+// syncJob periodically performs a full resync of one table
+func (s *Syncer) syncJob(tableKey string, table config.ResolvedTable) scheduler.Job {
+	interval := defaultSyncInterval
+	if table.Mirror.Interval != "" {
+		if d, err := time.ParseDuration(table.Mirror.Interval); err == nil {
+			interval = d
+		}
+	}
+
+	return scheduler.Job{
+		Name:       "mirror:" + tableKey,
+		Schedule:   scheduler.Every(interval),
+		RunAtStart: true,
+		Run: func(ctx context.Context) error {
+			err := s.SyncTable(ctx, tableKey, table)
+			if err != nil {
+				log.Printf("[MIRROR ERROR] Sync of table '%s' failed, keeping previous mirror: %v", tableKey, err)
+			}
+			return err
+		},
+	}
+}
+
+// pruneJob periodically trims the change log to the retention period
+func (s *Syncer) pruneJob() scheduler.Job {
+	return scheduler.Job{
+		Name:     "mirror:prune-changes",
+		Schedule: scheduler.Every(pruneInterval),
+		Run: func(context.Context) error {
+			pruned, err := s.store.PruneChanges(s.retention)
+			if err != nil {
+				log.Printf("[MIRROR ERROR] Failed to prune change log: %v", err)
+				return err
+			}
+			if pruned > 0 {
+				log.Printf("[MIRROR] Pruned %d change log entries older than %v", pruned, s.retention)
+			}
+			return nil
+		},
+	}
+}
+
+// SyncTable fetches every record of a table and replaces the mirrored copy
+func (s *Syncer) SyncTable(ctx context.Context, tableKey string, table config.ResolvedTable) error {
+	start := time.Now()
+	records := make(map[string]map[string]interface{})
+
+	for page := 1; page <= maxSyncPages; page++ {
+		batch, hasMore, err := s.client.ListRecords(ctx, table.TableID, url.Values{}, page, syncPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to fetch page %d: %w", page, err)
+		}
+		for _, record := range batch {
+			if id := proxy.RecordID(record); id != "" {
+				records[id] = record
+			}
+		}
+		if !hasMore {
+			break
+		}
+	}
+
+	if err := s.store.ReplaceTable(tableKey, records); err != nil {
+		return fmt.Errorf("failed to store mirror: %w", err)
+	}
+	if s.indexer != nil {
+		s.indexer.IndexTable(tableKey, records)
+	}
+
+	log.Printf("[MIRROR] Synced table '%s': %d record(s) in %v", tableKey, len(records), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// apply keeps mirrored tables fresh between full syncs using change events
+func (s *Syncer) apply(event events.Event) {
+	tableKey := s.tableKeyFor(event)
+	if tableKey == "" {
+		return
+	}
+
+	switch event.Type {
+	case events.TypeRecordCreated, events.TypeRecordUpdated:
+		for _, record := range event.Records {
+			if id := proxy.RecordID(record); id != "" {
+				if err := s.store.Upsert(tableKey, id, record); err != nil {
+					log.Printf("[MIRROR ERROR] Failed to apply %s to %s/%s: %v", event.Type, tableKey, id, err)
+					continue
+				}
+				if s.indexer != nil {
+					s.indexer.IndexRecord(tableKey, id, record)
+				}
+			}
+		}
+	case events.TypeRecordDeleted:
+		ids := []string{}
+		if event.RecordID != "" {
+			ids = append(ids, event.RecordID)
+		}
+		for _, record := range event.Records {
+			if id := proxy.RecordID(record); id != "" && id != event.RecordID {
+				ids = append(ids, id)
+			}
+		}
+		for _, id := range ids {
+			if err := s.store.Delete(tableKey, id); err != nil {
+				log.Printf("[MIRROR ERROR] Failed to delete %s/%s: %v", tableKey, id, err)
+				continue
+			}
+			if s.indexer != nil {
+				s.indexer.DeleteRecord(tableKey, id)
+			}
+		}
+	}
+}
+
+// tableKeyFor maps an event to a mirrored table key, matching by NocoDB table
+// ID, or by key for events without one: other bases and tenants publish to the
+// same bus under the same keys
+func (s *Syncer) tableKeyFor(event events.Event) string {
+	for tableKey, table := range s.config.Tables {
+		if table.Mirror == nil || !table.Mirror.Enabled {
+			continue
+		}
+		if event.TableID != "" && table.TableID == event.TableID {
+			return tableKey
+		}
+		if event.TableID == "" && tableKey == event.TableName {
+			return tableKey
+		}
+	}
+	return ""
+}
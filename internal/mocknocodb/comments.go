@@ -0,0 +1,90 @@
+package mocknocodb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// comment is a row comment of api/v2/meta/comments
+type comment struct {
+	ID        string `json:"id"`
+	TableID   string `json:"fk_model_id"`
+	RowID     string `json:"row_id"`
+	Comment   string `json:"comment"`
+	CreatedAt string `json:"created_at"`
+}
+
+// auditEntry is a row change of api/v2/meta/audits
+type auditEntry struct {
+	ID          string `json:"id"`
+	TableID     string `json:"fk_model_id"`
+	RowID       string `json:"row_id"`
+	OpType      string `json:"op_type"` // DATA_INSERT, DATA_UPDATE or DATA_DELETE
+	Description string `json:"description"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// audit records a change of a row. Callers hold mu.
+func (s *Server) audit(t *table, recordID int, method string, now time.Time) {
+	opType := map[string]string{
+		http.MethodPost:   "DATA_INSERT",
+		http.MethodPatch:  "DATA_UPDATE",
+		http.MethodDelete: "DATA_DELETE",
+	}[method]
+	s.audits = append(s.audits, auditEntry{
+		ID:          fmt.Sprintf("adt%06d", len(s.audits)+1),
+		TableID:     t.id,
+		RowID:       strconv.Itoa(recordID),
+		OpType:      opType,
+		Description: fmt.Sprintf("%s of record %d in %s", opType, recordID, t.title),
+		CreatedAt:   now.Format(timeLayout),
+	})
+}
+
+// serveComments lists the comments of a row (GET ?fk_model_id=&row_id=) or adds one (POST)
+func (s *Server) serveComments(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		list := make([]comment, 0)
+		for _, c := range s.comments {
+			if c.TableID == query.Get("fk_model_id") && c.RowID == query.Get("row_id") {
+				list = append(list, c)
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"list": list})
+
+	case http.MethodPost:
+		var c comment
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil || c.TableID == "" || c.RowID == "" || c.Comment == "" {
+			writeError(w, http.StatusBadRequest, "INVALID_REQUEST_BODY", "fk_model_id, row_id and comment are required")
+			return
+		}
+		c.ID = fmt.Sprintf("cmt%06d", len(s.comments)+1)
+		c.CreatedAt = time.Now().Format(timeLayout)
+		s.comments = append(s.comments, c)
+		writeJSON(w, http.StatusOK, c)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", r.Method+" is not supported")
+	}
+}
+
+// serveAudits lists the changes of a row, newest first (GET ?fk_model_id=&row_id=)
+func (s *Server) serveAudits(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "audits are read-only")
+		return
+	}
+	query := r.URL.Query()
+	list := make([]auditEntry, 0)
+	for i := len(s.audits) - 1; i >= 0; i-- {
+		if entry := s.audits[i]; entry.TableID == query.Get("fk_model_id") && entry.RowID == query.Get("row_id") {
+			list = append(list, entry)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"list": list})
+}
@@ -0,0 +1,180 @@
+package mocknocodb
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// predicate reports whether a record (field title -> value) matches a filter
+type predicate func(values map[string]interface{}) bool
+
+// parseWhere compiles a NocoDB where clause such as
+// "(Status,eq,Open)~and((Total,gt,100)~or(Priority,eq,High))". Groups are
+// evaluated left to right, as NocoDB does. Field names match titles case-insensitively.
+func parseWhere(where string) (predicate, error) {
+	if strings.TrimSpace(where) == "" {
+		return func(map[string]interface{}) bool { return true }, nil
+	}
+	p, rest, err := parseExpr(strings.TrimSpace(where))
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("unexpected '%s' in where clause", rest)
+	}
+	return p, nil
+}
+
+// parseExpr parses "term {~and|~or term}" and returns the unparsed remainder
+func parseExpr(s string) (predicate, string, error) {
+	result, s, err := parseTerm(s)
+	if err != nil {
+		return nil, "", err
+	}
+	for s != "" && !strings.HasPrefix(s, ")") {
+		var join string
+		switch {
+		case strings.HasPrefix(s, "~and"):
+			join, s = "and", s[len("~and"):]
+		case strings.HasPrefix(s, "~or"):
+			join, s = "or", s[len("~or"):]
+		default:
+			return nil, "", fmt.Errorf("expected ~and or ~or at '%s'", s)
+		}
+		next, rest, err := parseTerm(s)
+		if err != nil {
+			return nil, "", err
+		}
+		left := result
+		if join == "and" {
+			result = func(v map[string]interface{}) bool { return left(v) && next(v) }
+		} else {
+			result = func(v map[string]interface{}) bool { return left(v) || next(v) }
+		}
+		s = rest
+	}
+	return result, s, nil
+}
+
+// parseTerm parses "~not(...)", a nested "((...)...)" group or a "(field,op,value)" condition
+func parseTerm(s string) (predicate, string, error) {
+	if strings.HasPrefix(s, "~not") {
+		inner, rest, err := parseTerm(s[len("~not"):])
+		if err != nil {
+			return nil, "", err
+		}
+		return func(v map[string]interface{}) bool { return !inner(v) }, rest, nil
+	}
+	if !strings.HasPrefix(s, "(") {
+		return nil, "", fmt.Errorf("expected '(' at '%s'", s)
+	}
+	if strings.HasPrefix(s, "((") || strings.HasPrefix(s, "(~not") {
+		group, rest, err := parseExpr(s[1:])
+		if err != nil {
+			return nil, "", err
+		}
+		if !strings.HasPrefix(rest, ")") {
+			return nil, "", fmt.Errorf("unclosed group in where clause")
+		}
+		return group, rest[1:], nil
+	}
+
+	end := strings.Index(s, ")")
+	if end < 0 {
+		return nil, "", fmt.Errorf("unclosed condition '%s'", s)
+	}
+	parts := strings.SplitN(s[1:end], ",", 3)
+	if len(parts) < 2 {
+		return nil, "", fmt.Errorf("condition '%s' needs a field and an operator", s[:end+1])
+	}
+	value := ""
+	if len(parts) == 3 {
+		value = parts[2]
+	}
+	p, err := condition(strings.ToLower(strings.TrimSpace(parts[0])), strings.TrimSpace(parts[1]), value)
+	if err != nil {
+		return nil, "", err
+	}
+	return p, s[end+1:], nil
+}
+
+// condition compiles one comparison. Values compare numerically when both sides are numbers.
+func condition(field, op, value string) (predicate, error) {
+	get := func(v map[string]interface{}) interface{} { return v[field] }
+	switch op {
+	case "eq":
+		return func(v map[string]interface{}) bool { return compare(get(v), value) == 0 }, nil
+	case "neq", "ne":
+		return func(v map[string]interface{}) bool { return compare(get(v), value) != 0 }, nil
+	case "gt":
+		return func(v map[string]interface{}) bool { return get(v) != nil && compare(get(v), value) > 0 }, nil
+	case "ge", "gte":
+		return func(v map[string]interface{}) bool { return get(v) != nil && compare(get(v), value) >= 0 }, nil
+	case "lt":
+		return func(v map[string]interface{}) bool { return get(v) != nil && compare(get(v), value) < 0 }, nil
+	case "le", "lte":
+		return func(v map[string]interface{}) bool { return get(v) != nil && compare(get(v), value) <= 0 }, nil
+	case "blank":
+		return func(v map[string]interface{}) bool { return text(get(v)) == "" }, nil
+	case "notblank":
+		return func(v map[string]interface{}) bool { return text(get(v)) != "" }, nil
+	case "in":
+		options := strings.Split(value, ",")
+		return func(v map[string]interface{}) bool {
+			for _, option := range options {
+				if compare(get(v), strings.TrimSpace(option)) == 0 {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case "like", "nlike":
+		pattern := strings.ToLower(value)
+		if !strings.Contains(pattern, "%") {
+			pattern = "%" + pattern + "%"
+		}
+		re, err := regexp.Compile("^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), "%", ".*") + "$")
+		if err != nil {
+			return nil, err
+		}
+		negate := op == "nlike"
+		return func(v map[string]interface{}) bool {
+			return re.MatchString(strings.ToLower(text(get(v)))) != negate
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported comparison operator '%s'", op)
+	}
+}
+
+// compare orders a stored value against a filter value
+func compare(actual interface{}, value string) int {
+	if a, err := strconv.ParseFloat(text(actual), 64); err == nil {
+		if b, err := strconv.ParseFloat(value, 64); err == nil {
+			switch {
+			case a < b:
+				return -1
+			case a > b:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+	return strings.Compare(strings.ToLower(text(actual)), strings.ToLower(value))
+}
+
+// text renders a stored value the way it appears in a query string
+func text(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,79 @@
+package mocknocodb
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixtures describes the tables and initial records of the mock base
+type Fixtures struct {
+	BaseID string         `yaml:"base_id"`
+	Tables []TableFixture `yaml:"tables"`
+}
+
+// TableFixture describes one table. Every table also gets the system fields
+// Id, CreatedAt and UpdatedAt, which the mock maintains itself.
+type TableFixture struct {
+	ID      string                   `yaml:"id"` // generated from the position when empty
+	Title   string                   `yaml:"title"`
+	Fields  []FieldFixture           `yaml:"fields"`
+	Records []map[string]interface{} `yaml:"records"` // field title -> value; link fields take a list of record IDs
+}
+
+// FieldFixture describes one column
+type FieldFixture struct {
+	ID       string   `yaml:"id"`
+	Title    string   `yaml:"title"`
+	Type     string   `yaml:"type"` // NocoDB UI type; SingleLineText when empty
+	Required bool     `yaml:"required"`
+	Choices  []string `yaml:"choices"`  // SingleSelect / MultiSelect options
+	Target   string   `yaml:"target"`   // Links: title of the linked table
+	Relation string   `yaml:"relation"` // Links: hm, bt or mm (default)
+}
+
+// LoadFixtures reads a fixtures file (YAML or JSON) and checks that links point
+// at defined tables
+func LoadFixtures(path string) (*Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	var fixtures Fixtures
+	if err := yaml.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures: %w", err)
+	}
+	if err := fixtures.validate(); err != nil {
+		return nil, fmt.Errorf("invalid fixtures %s: %w", path, err)
+	}
+	return &fixtures, nil
+}
+
+func (f *Fixtures) validate() error {
+	titles := make(map[string]bool, len(f.Tables))
+	for _, table := range f.Tables {
+		if table.Title == "" {
+			return fmt.Errorf("table without title")
+		}
+		if titles[strings.ToLower(table.Title)] {
+			return fmt.Errorf("duplicate table '%s'", table.Title)
+		}
+		titles[strings.ToLower(table.Title)] = true
+	}
+	for _, table := range f.Tables {
+		for _, field := range table.Fields {
+			if field.Title == "" {
+				return fmt.Errorf("table '%s': field without title", table.Title)
+			}
+			if isSystemField(field.Title) {
+				return fmt.Errorf("table '%s': field '%s' is maintained by the mock", table.Title, field.Title)
+			}
+			if field.Type == "Links" && !titles[strings.ToLower(field.Target)] {
+				return fmt.Errorf("table '%s': link '%s' targets unknown table '%s'", table.Title, field.Title, field.Target)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package mocknocodb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// columnInput is a column of a create table or add column request
+type columnInput struct {
+	Title string `json:"title"`
+	Type  string `json:"uidt"`
+	Rqd   bool   `json:"rqd"`
+}
+
+// viewCollections are the view kinds of the meta API, by collection name
+var viewCollections = map[string]string{
+	"grids":     "grid",
+	"forms":     "form",
+	"galleries": "gallery",
+	"kanbans":   "kanban",
+}
+
+// createTable adds a table ({"title": "...", "columns": [{"title": "...", "uidt": "..."}]})
+func (s *Server) createTable(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Title   string        `json:"title"`
+		Columns []columnInput `json:"columns"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || strings.TrimSpace(input.Title) == "" {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST_BODY", "title is required")
+		return
+	}
+	if s.findTable(input.Title) != nil {
+		writeError(w, http.StatusBadRequest, "DUPLICATE_TABLE", fmt.Sprintf("table '%s' already exists", input.Title))
+		return
+	}
+
+	id := ""
+	for n := len(s.tables) + 1; id == "" || s.findTable(id) != nil; n++ {
+		id = fmt.Sprintf("mtable%03d", n)
+	}
+	t := &table{id: id, title: input.Title, rows: make(map[int]*row), nextID: 1}
+	t.fields = append(t.fields,
+		&field{id: t.id + "_id", title: "Id", typ: "ID", system: true},
+		&field{id: t.id + "_created", title: "CreatedAt", typ: "CreatedTime", system: true},
+		&field{id: t.id + "_updated", title: "UpdatedAt", typ: "LastModifiedTime", system: true},
+	)
+	for _, column := range input.Columns {
+		if column.Type == "ID" || t.field(column.Title) != nil {
+			continue
+		}
+		t.addField(column)
+	}
+	s.tables = append(s.tables, t)
+	writeJSON(w, http.StatusOK, s.tableV2(t))
+}
+
+// addColumn adds a field to a table ({"title": "...", "uidt": "..."})
+func (s *Server) addColumn(w http.ResponseWriter, r *http.Request, t *table) {
+	var input columnInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || strings.TrimSpace(input.Title) == "" {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST_BODY", "title is required")
+		return
+	}
+	if input.Type == "Links" {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST_BODY", "the mock cannot add link fields")
+		return
+	}
+	if t.field(input.Title) != nil {
+		writeError(w, http.StatusBadRequest, "DUPLICATE_COLUMN", fmt.Sprintf("field '%s' already exists", input.Title))
+		return
+	}
+	t.addField(input)
+	writeJSON(w, http.StatusOK, s.tableV2(t))
+}
+
+// addField appends a field with the next free ID
+func (t *table) addField(column columnInput) {
+	f := &field{id: fmt.Sprintf("%s_c%02d", t.id, len(t.fields)-2), title: column.Title, typ: column.Type, required: column.Rqd}
+	for t.field(f.id) != nil {
+		f.id += "x"
+	}
+	if f.typ == "" {
+		f.typ = "SingleLineText"
+	}
+	t.fields = append(t.fields, f)
+}
+
+// createView acknowledges a new view; the mock does not keep views
+func (s *Server) createView(w http.ResponseWriter, r *http.Request, t *table, collection string) {
+	kind, ok := viewCollections[collection]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("unknown meta route '%s'", collection))
+		return
+	}
+	var input struct {
+		Title string `json:"title"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || strings.TrimSpace(input.Title) == "" {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST_BODY", "title is required")
+		return
+	}
+	s.views++
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":          fmt.Sprintf("vw%06d", s.views),
+		"title":       input.Title,
+		"type":        kind,
+		"fk_model_id": t.id,
+	})
+}
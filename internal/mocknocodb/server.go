@@ -0,0 +1,880 @@
+// Package mocknocodb serves an in-memory NocoDB base for local development and
+// integration tests. It implements the subset of the NocoDB API the gateway
+// uses: the v2/v3 table metadata routes, creating tables, columns and views, the
+// v3 records and links routes, row comments and audits, and the health and
+// version endpoints. Any base ID in a URL addresses the same tables.
+package mocknocodb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timeLayout is the timestamp format NocoDB uses for CreatedAt and UpdatedAt
+const timeLayout = "2006-01-02 15:04:05-07:00"
+
+const (
+	defaultPageSize = 25
+	maxPageSize     = 1000
+)
+
+// Server is an in-memory NocoDB base
+type Server struct {
+	mu     sync.Mutex
+	baseID string
+	token  string // required xc-token; empty accepts any request
+	tables []*table
+
+	comments []comment
+	audits   []auditEntry
+	views    int // views created through the meta API
+}
+
+type table struct {
+	id     string
+	title  string
+	fields []*field
+	rows   map[int]*row
+	nextID int
+}
+
+type field struct {
+	id       string
+	title    string
+	typ      string
+	required bool
+	choices  []string
+	relation string
+	target   *table // link fields only
+	system   bool   // maintained by the mock
+}
+
+type row struct {
+	values map[string]interface{} // field ID -> value, link fields excluded
+	links  map[string][]int       // link field ID -> linked record IDs
+}
+
+// New builds a mock base from fixtures. Requests must carry token as xc-token
+// unless token is empty.
+func New(fixtures *Fixtures, token string) (*Server, error) {
+	if err := fixtures.validate(); err != nil {
+		return nil, err
+	}
+	s := &Server{baseID: fixtures.BaseID, token: token}
+	if s.baseID == "" {
+		s.baseID = "pmock"
+	}
+
+	for i, fixture := range fixtures.Tables {
+		t := &table{id: fixture.ID, title: fixture.Title, rows: make(map[int]*row), nextID: 1}
+		if t.id == "" {
+			t.id = fmt.Sprintf("mtable%03d", i+1)
+		}
+		t.fields = append(t.fields,
+			&field{id: t.id + "_id", title: "Id", typ: "ID", system: true},
+			&field{id: t.id + "_created", title: "CreatedAt", typ: "CreatedTime", system: true},
+			&field{id: t.id + "_updated", title: "UpdatedAt", typ: "LastModifiedTime", system: true},
+		)
+		for j, fieldFixture := range fixture.Fields {
+			f := &field{
+				id:       fieldFixture.ID,
+				title:    fieldFixture.Title,
+				typ:      fieldFixture.Type,
+				required: fieldFixture.Required,
+				choices:  fieldFixture.Choices,
+				relation: fieldFixture.Relation,
+			}
+			if f.id == "" {
+				f.id = fmt.Sprintf("%s_c%02d", t.id, j+1)
+			}
+			if f.typ == "" {
+				f.typ = "SingleLineText"
+			}
+			if f.typ == "Links" && f.relation == "" {
+				f.relation = "mm"
+			}
+			t.fields = append(t.fields, f)
+		}
+		s.tables = append(s.tables, t)
+	}
+
+	for i, fixture := range fixtures.Tables {
+		t := s.tables[i]
+		for _, f := range t.fields {
+			if f.typ == "Links" {
+				f.target = s.findTable(fixtureTarget(fixture, f.title))
+			}
+		}
+		for _, values := range fixture.Records {
+			if _, err := t.insert(values, time.Now()); err != nil {
+				return nil, fmt.Errorf("table '%s': %w", t.title, err)
+			}
+		}
+	}
+	return s, nil
+}
+
+// fixtureTarget returns the target table title of a link field fixture
+func fixtureTarget(fixture TableFixture, fieldTitle string) string {
+	for _, f := range fixture.Fields {
+		if f.Title == fieldTitle {
+			return f.Target
+		}
+	}
+	return ""
+}
+
+// BaseID returns the base ID the mock reports in its metadata
+func (s *Server) BaseID() string {
+	return s.baseID
+}
+
+// findTable looks a table up by ID or case-insensitive title. Callers hold mu
+// or call it during construction.
+func (s *Server) findTable(name string) *table {
+	for _, t := range s.tables {
+		if t.id == name || strings.EqualFold(t.title, name) {
+			return t
+		}
+	}
+	return nil
+}
+
+// field looks a field up by ID or case-insensitive title
+func (t *table) field(name string) *field {
+	for _, f := range t.fields {
+		if f.id == name || strings.EqualFold(f.title, name) {
+			return f
+		}
+	}
+	return nil
+}
+
+// displayField is the first non-system field, which NocoDB shows as the row label
+func (t *table) displayField() *field {
+	for _, f := range t.fields {
+		if !f.system && f.typ != "Links" {
+			return f
+		}
+	}
+	return t.fields[0]
+}
+
+// ServeHTTP routes a NocoDB API request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	path := strings.Join(segments, "/")
+
+	switch {
+	case path == "api/v1/health":
+		writeJSON(w, http.StatusOK, map[string]string{"message": "OK"})
+		return
+	case path == "api/v1/version":
+		writeJSON(w, http.StatusOK, map[string]string{"currentVersion": "mock"})
+		return
+	}
+
+	if s.token != "" && r.Header.Get("xc-token") != s.token {
+		writeError(w, http.StatusUnauthorized, "AUTHENTICATION_REQUIRED", "invalid or missing xc-token")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// api/{v2|v3}/meta/bases/{base}/tables[/{table}]
+	if len(segments) >= 6 && segments[0] == "api" && segments[2] == "meta" && segments[3] == "bases" && segments[5] == "tables" {
+		if r.Method == http.MethodPost && len(segments) == 6 && segments[1] == "v2" {
+			s.createTable(w, r)
+			return
+		}
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "metadata is read-only")
+			return
+		}
+		switch {
+		case len(segments) == 6 && segments[1] == "v2":
+			s.serveTablesV2(w)
+		case len(segments) == 6 && segments[1] == "v3":
+			s.serveTablesV3(w)
+		case len(segments) == 7 && segments[1] == "v3":
+			s.serveTableV3(w, segments[6])
+		default:
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "unknown metadata route")
+		}
+		return
+	}
+
+	// api/v2/meta/tables/{table}/{columns|grids|forms|galleries|kanbans}
+	if len(segments) == 6 && segments[0] == "api" && segments[1] == "v2" && segments[2] == "meta" && segments[3] == "tables" {
+		t := s.findTable(segments[4])
+		if t == nil {
+			writeError(w, http.StatusNotFound, "TABLE_NOT_FOUND", fmt.Sprintf("table '%s' not found", segments[4]))
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", r.Method+" is not supported")
+			return
+		}
+		if segments[5] == "columns" {
+			s.addColumn(w, r, t)
+		} else {
+			s.createView(w, r, t, segments[5])
+		}
+		return
+	}
+
+	// api/v2/meta/comments and api/v2/meta/audits
+	if len(segments) == 4 && segments[0] == "api" && segments[1] == "v2" && segments[2] == "meta" {
+		switch segments[3] {
+		case "comments":
+			s.serveComments(w, r)
+			return
+		case "audits":
+			s.serveAudits(w, r)
+			return
+		}
+	}
+
+	// api/v3/data/{base}/{table}/records[/{id}] and api/v3/data/{base}/{table}/links/{field}/{id}
+	if len(segments) >= 6 && segments[0] == "api" && segments[1] == "v3" && segments[2] == "data" {
+		t := s.findTable(segments[4])
+		if t == nil {
+			writeError(w, http.StatusNotFound, "TABLE_NOT_FOUND", fmt.Sprintf("table '%s' not found", segments[4]))
+			return
+		}
+		switch {
+		case segments[5] == "records" && len(segments) <= 7:
+			id := ""
+			if len(segments) == 7 {
+				id = segments[6]
+			}
+			s.serveRecords(w, r, t, id)
+		case segments[5] == "links" && len(segments) == 8:
+			s.serveLinks(w, r, t, segments[6], segments[7])
+		default:
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "unknown data route")
+		}
+		return
+	}
+
+	writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("mock NocoDB does not serve %s", r.URL.Path))
+}
+
+// serveTablesV2 lists tables with their columns in the v2 shape
+func (s *Server) serveTablesV2(w http.ResponseWriter) {
+	list := make([]map[string]interface{}, 0, len(s.tables))
+	for _, t := range s.tables {
+		list = append(list, s.tableV2(t))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"list": list})
+}
+
+// tableV2 renders a table with its columns in the v2 shape
+func (s *Server) tableV2(t *table) map[string]interface{} {
+	display := t.displayField()
+	columns := make([]map[string]interface{}, 0, len(t.fields))
+	for _, f := range t.fields {
+		column := map[string]interface{}{
+			"id":    f.id,
+			"title": f.title,
+			"uidt":  f.typ,
+			"type":  f.typ,
+			"rqd":   f.required,
+			"pk":    f.typ == "ID",
+			"pv":    f == display,
+		}
+		if f.target != nil {
+			column["colOptions"] = map[string]interface{}{"fk_related_model_id": f.target.id, "type": f.relation}
+		}
+		if len(f.choices) > 0 {
+			column["colOptions"] = map[string]interface{}{"options": f.choices}
+		}
+		columns = append(columns, column)
+	}
+	return map[string]interface{}{
+		"id":         t.id,
+		"title":      t.title,
+		"table_name": t.title,
+		"base_id":    s.baseID,
+		"columns":    columns,
+	}
+}
+
+// serveTablesV3 lists tables without fields, like the v3 meta API
+func (s *Server) serveTablesV3(w http.ResponseWriter) {
+	list := make([]map[string]interface{}, 0, len(s.tables))
+	for _, t := range s.tables {
+		list = append(list, map[string]interface{}{"id": t.id, "title": t.title, "base_id": s.baseID})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"list": list})
+}
+
+// serveTableV3 returns one table with its fields in the v3 shape
+func (s *Server) serveTableV3(w http.ResponseWriter, tableID string) {
+	t := s.findTable(tableID)
+	if t == nil {
+		writeError(w, http.StatusNotFound, "TABLE_NOT_FOUND", fmt.Sprintf("table '%s' not found", tableID))
+		return
+	}
+	fields := make([]map[string]interface{}, 0, len(t.fields))
+	for _, f := range t.fields {
+		options := map[string]interface{}{}
+		if f.required {
+			options["required"] = true
+		}
+		if f.target != nil {
+			options["related_table_id"] = f.target.id
+			options["relation_type"] = f.relation
+		}
+		if len(f.choices) > 0 {
+			options["choices"] = f.choices
+		}
+		fields = append(fields, map[string]interface{}{
+			"id":          f.id,
+			"title":       f.title,
+			"type":        f.typ,
+			"primary_key": f.typ == "ID",
+			"options":     options,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":               t.id,
+		"title":            t.title,
+		"base_id":          s.baseID,
+		"display_field_id": t.displayField().id,
+		"fields":           fields,
+	})
+}
+
+// serveRecords handles the v3 records route
+func (s *Server) serveRecords(w http.ResponseWriter, r *http.Request, t *table, id string) {
+	switch r.Method {
+	case http.MethodGet:
+		if id != "" {
+			rec, ok := t.get(id)
+			if !ok {
+				writeError(w, http.StatusNotFound, "RECORD_NOT_FOUND", fmt.Sprintf("record '%s' not found", id))
+				return
+			}
+			writeJSON(w, http.StatusOK, t.render(rec.id, rec.row, nil))
+			return
+		}
+		s.listRecords(w, r, t)
+
+	case http.MethodPost, http.MethodPatch, http.MethodDelete:
+		items, err := decodeItems(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_REQUEST_BODY", err.Error())
+			return
+		}
+		if id != "" && r.Method == http.MethodDelete && len(items) == 0 {
+			items = []map[string]interface{}{{"id": id}}
+		}
+		now := time.Now()
+		records := make([]map[string]interface{}, 0, len(items))
+		for _, item := range items {
+			var recordID int
+			switch r.Method {
+			case http.MethodPost:
+				recordID, err = t.insert(itemFields(item), now)
+			case http.MethodPatch:
+				recordID, err = t.update(item, now)
+			default:
+				recordID, err = t.remove(item)
+			}
+			if err != nil {
+				writeError(w, statusOf(err), codeOf(err), err.Error())
+				return
+			}
+			s.audit(t, recordID, r.Method, now)
+			if r.Method == http.MethodDelete {
+				records = append(records, map[string]interface{}{"id": recordID})
+			} else {
+				records = append(records, t.render(recordID, t.rows[recordID], nil))
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"records": records})
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", r.Method+" is not supported")
+	}
+}
+
+// listRecords serves a filtered, sorted page of records
+func (s *Server) listRecords(w http.ResponseWriter, r *http.Request, t *table) {
+	query := r.URL.Query()
+	match, err := parseWhere(query.Get("where"))
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "INVALID_FILTER", err.Error())
+		return
+	}
+
+	page := atoiDefault(query.Get("page"), 1)
+	pageSize := atoiDefault(query.Get("pageSize"), atoiDefault(query.Get("limit"), defaultPageSize))
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	if offset := query.Get("offset"); offset != "" {
+		page = atoiDefault(offset, 0)/pageSize + 1
+	}
+	var only map[string]bool
+	if selected := query.Get("fields"); selected != "" {
+		only = make(map[string]bool)
+		for _, name := range strings.Split(selected, ",") {
+			only[strings.ToLower(strings.TrimSpace(name))] = true
+		}
+	}
+
+	ids := make([]int, 0, len(t.rows))
+	for id, rec := range t.rows {
+		if match(t.filterValues(id, rec)) {
+			ids = append(ids, id)
+		}
+	}
+	if err := t.sortIDs(ids, query.Get("sort")); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "INVALID_SORT", err.Error())
+		return
+	}
+
+	start := (page - 1) * pageSize
+	if start > len(ids) {
+		start = len(ids)
+	}
+	end := start + pageSize
+	if end > len(ids) {
+		end = len(ids)
+	}
+	records := make([]map[string]interface{}, 0, end-start)
+	for _, id := range ids[start:end] {
+		records = append(records, t.render(id, t.rows[id], only))
+	}
+
+	response := map[string]interface{}{"records": records, "next": nil}
+	if end < len(ids) {
+		next := r.URL.Query()
+		next.Set("page", strconv.Itoa(page+1))
+		next.Del("offset")
+		response["next"] = r.URL.Path + "?" + next.Encode()
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// serveLinks lists, adds or removes the records linked through a link field
+func (s *Server) serveLinks(w http.ResponseWriter, r *http.Request, t *table, fieldName, id string) {
+	f := t.field(fieldName)
+	if f == nil || f.target == nil {
+		writeError(w, http.StatusNotFound, "FIELD_NOT_FOUND", fmt.Sprintf("link field '%s' not found", fieldName))
+		return
+	}
+	rec, ok := t.get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "RECORD_NOT_FOUND", fmt.Sprintf("record '%s' not found", id))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		display := f.target.displayField()
+		records := make([]map[string]interface{}, 0, len(rec.row.links[f.id]))
+		for _, linkedID := range rec.row.links[f.id] {
+			if linked, ok := f.target.rows[linkedID]; ok {
+				records = append(records, map[string]interface{}{
+					"id":     linkedID,
+					"fields": map[string]interface{}{display.title: linked.values[display.id]},
+				})
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"records": records, "next": nil})
+
+	case http.MethodPost, http.MethodDelete:
+		items, err := decodeItems(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_REQUEST_BODY", err.Error())
+			return
+		}
+		var linkedIDs []int
+		for _, item := range items {
+			linkedID, ok := toInt(item["id"])
+			if !ok || f.target.rows[linkedID] == nil {
+				writeError(w, http.StatusNotFound, "RECORD_NOT_FOUND", fmt.Sprintf("record '%v' not found in '%s'", item["id"], f.target.title))
+				return
+			}
+			linkedIDs = append(linkedIDs, linkedID)
+		}
+		if r.Method == http.MethodPost {
+			rec.row.links[f.id] = union(rec.row.links[f.id], linkedIDs)
+		} else {
+			rec.row.links[f.id] = subtract(rec.row.links[f.id], linkedIDs)
+		}
+		t.touch(rec.row, time.Now())
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", r.Method+" is not supported")
+	}
+}
+
+type foundRow struct {
+	id  int
+	row *row
+}
+
+// get looks a record up by its ID as given in a URL
+func (t *table) get(id string) (foundRow, bool) {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return foundRow{}, false
+	}
+	rec, ok := t.rows[n]
+	return foundRow{id: n, row: rec}, ok
+}
+
+// insert adds a record. Values are keyed by field title or ID; an "Id" value
+// keeps the given record ID.
+func (t *table) insert(values map[string]interface{}, now time.Time) (int, error) {
+	id := t.nextID
+	if given, ok := toInt(values["Id"]); ok {
+		if _, exists := t.rows[given]; exists {
+			return 0, &apiError{http.StatusBadRequest, "DUPLICATE_RECORD", fmt.Sprintf("record %d already exists", given)}
+		}
+		id = given
+	}
+	rec := &row{values: make(map[string]interface{}), links: make(map[string][]int)}
+	if err := t.assign(rec, values, true); err != nil {
+		return 0, err
+	}
+	rec.values[t.fields[1].id] = now.UTC().Format(timeLayout)
+	t.touch(rec, now)
+	t.rows[id] = rec
+	if id >= t.nextID {
+		t.nextID = id + 1
+	}
+	return id, nil
+}
+
+// update changes the fields of the record named by item's "id"
+func (t *table) update(item map[string]interface{}, now time.Time) (int, error) {
+	id, ok := toInt(item["id"])
+	if !ok {
+		id, ok = toInt(item["Id"])
+	}
+	rec := t.rows[id]
+	if !ok || rec == nil {
+		return 0, &apiError{http.StatusNotFound, "RECORD_NOT_FOUND", fmt.Sprintf("record '%v' not found", item["id"])}
+	}
+	if err := t.assign(rec, itemFields(item), false); err != nil {
+		return 0, err
+	}
+	t.touch(rec, now)
+	return id, nil
+}
+
+// touch sets a record's UpdatedAt. The system fields are the first three of
+// every table: Id, CreatedAt, UpdatedAt.
+func (t *table) touch(rec *row, now time.Time) {
+	rec.values[t.fields[2].id] = now.UTC().Format(timeLayout)
+}
+
+// remove deletes the record named by item's "id"
+func (t *table) remove(item map[string]interface{}) (int, error) {
+	id, ok := toInt(item["id"])
+	if !ok {
+		id, ok = toInt(item["Id"])
+	}
+	if !ok || t.rows[id] == nil {
+		return 0, &apiError{http.StatusNotFound, "RECORD_NOT_FOUND", fmt.Sprintf("record '%v' not found", item["id"])}
+	}
+	delete(t.rows, id)
+	return id, nil
+}
+
+// assign validates and stores field values on a record
+func (t *table) assign(rec *row, values map[string]interface{}, create bool) error {
+	for name, value := range values {
+		if strings.EqualFold(name, "id") {
+			continue
+		}
+		f := t.field(name)
+		if f == nil {
+			return &apiError{http.StatusUnprocessableEntity, "FIELD_NOT_FOUND", fmt.Sprintf("field '%s' not found in '%s'", name, t.title)}
+		}
+		if f.system {
+			return &apiError{http.StatusUnprocessableEntity, "FIELD_READ_ONLY", fmt.Sprintf("field '%s' is read-only", f.title)}
+		}
+		if f.target != nil {
+			ids, ok := linkIDs(value)
+			if !ok {
+				return &apiError{http.StatusUnprocessableEntity, "INVALID_VALUE", fmt.Sprintf("link field '%s' takes a list of record IDs", f.title)}
+			}
+			rec.links[f.id] = ids
+			continue
+		}
+		if f.typ == "SingleSelect" && len(f.choices) > 0 && value != nil && !contains(f.choices, text(value)) {
+			return &apiError{http.StatusUnprocessableEntity, "INVALID_VALUE", fmt.Sprintf("'%v' is not an option of '%s'", value, f.title)}
+		}
+		rec.values[f.id] = value
+	}
+	if create {
+		for _, f := range t.fields {
+			if f.required && text(rec.values[f.id]) == "" && len(rec.links[f.id]) == 0 {
+				return &apiError{http.StatusUnprocessableEntity, "REQUIRED_FIELD_MISSING", fmt.Sprintf("field '%s' is required", f.title)}
+			}
+		}
+	}
+	return nil
+}
+
+// render returns a record in the v3 {"id", "fields"} shape. Link fields show the
+// number of linked records. only limits the fields returned (lowercase titles).
+func (t *table) render(id int, rec *row, only map[string]bool) map[string]interface{} {
+	fields := make(map[string]interface{})
+	for _, f := range t.fields {
+		if f.typ == "ID" || (only != nil && !only[strings.ToLower(f.title)]) {
+			continue
+		}
+		if f.target != nil {
+			fields[f.title] = len(rec.links[f.id])
+			continue
+		}
+		if value, ok := rec.values[f.id]; ok {
+			fields[f.title] = value
+		}
+	}
+	return map[string]interface{}{"id": id, "fields": fields}
+}
+
+// filterValues returns a record keyed by lowercase field title, for where clauses
+func (t *table) filterValues(id int, rec *row) map[string]interface{} {
+	values := map[string]interface{}{"id": id}
+	for _, f := range t.fields {
+		if f.target != nil {
+			values[strings.ToLower(f.title)] = len(rec.links[f.id])
+		} else if f.typ != "ID" {
+			values[strings.ToLower(f.title)] = rec.values[f.id]
+		}
+	}
+	return values
+}
+
+// sortIDs orders record IDs by a sort parameter: "-Field,Other" or the v3 JSON form
+// [{"field": "Field", "direction": "desc"}]. Without one, records are ordered by ID.
+func (t *table) sortIDs(ids []int, sortParam string) error {
+	type key struct {
+		field *field
+		desc  bool
+	}
+	var keys []key
+	if strings.HasPrefix(strings.TrimSpace(sortParam), "[") {
+		var spec []struct {
+			Field     string `json:"field"`
+			Direction string `json:"direction"`
+		}
+		if err := json.Unmarshal([]byte(sortParam), &spec); err != nil {
+			return fmt.Errorf("invalid sort: %w", err)
+		}
+		for _, s := range spec {
+			f := t.field(s.Field)
+			if f == nil {
+				return fmt.Errorf("sort field '%s' not found", s.Field)
+			}
+			keys = append(keys, key{f, strings.EqualFold(s.Direction, "desc")})
+		}
+	} else if sortParam != "" {
+		for _, name := range strings.Split(sortParam, ",") {
+			name = strings.TrimSpace(name)
+			desc := strings.HasPrefix(name, "-")
+			f := t.field(strings.TrimPrefix(name, "-"))
+			if f == nil {
+				return fmt.Errorf("sort field '%s' not found", strings.TrimPrefix(name, "-"))
+			}
+			keys = append(keys, key{f, desc})
+		}
+	}
+
+	sort.SliceStable(ids, func(i, j int) bool {
+		for _, k := range keys {
+			var c int
+			if k.field.typ == "ID" {
+				c = ids[i] - ids[j]
+			} else {
+				c = compare(t.rows[ids[i]].values[k.field.id], text(t.rows[ids[j]].values[k.field.id]))
+			}
+			if c != 0 {
+				return (c < 0) != k.desc
+			}
+		}
+		return ids[i] < ids[j]
+	})
+	return nil
+}
+
+// apiError is a NocoDB-style error response
+type apiError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *apiError) Error() string { return e.message }
+
+func statusOf(err error) int {
+	if apiErr, ok := err.(*apiError); ok {
+		return apiErr.status
+	}
+	return http.StatusBadRequest
+}
+
+func codeOf(err error) string {
+	if apiErr, ok := err.(*apiError); ok {
+		return apiErr.code
+	}
+	return "INVALID_REQUEST"
+}
+
+// decodeItems reads a write body: a single object or a list of objects
+func decodeItems(r *http.Request) ([]map[string]interface{}, error) {
+	var body interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("invalid JSON body: %w", err)
+	}
+	switch v := body.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{v}, nil
+	case []interface{}:
+		items := make([]map[string]interface{}, 0, len(v))
+		for _, element := range v {
+			item, ok := element.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected a list of objects")
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("expected an object or a list of objects")
+	}
+}
+
+// itemFields returns the field values of a v3 {"fields": {...}} item, or the item
+// itself for flat v2-style bodies
+func itemFields(item map[string]interface{}) map[string]interface{} {
+	if fields, ok := item["fields"].(map[string]interface{}); ok {
+		return fields
+	}
+	return item
+}
+
+// linkIDs reads link values given as an ID, a list of IDs or a list of {"id": ...} objects
+func linkIDs(value interface{}) ([]int, bool) {
+	list, ok := value.([]interface{})
+	if !ok {
+		if id, ok := toInt(value); ok {
+			return []int{id}, true
+		}
+		return nil, value == nil
+	}
+	ids := make([]int, 0, len(list))
+	for _, element := range list {
+		if object, ok := element.(map[string]interface{}); ok {
+			element = object["id"]
+		}
+		id, ok := toInt(element)
+		if !ok {
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return union(nil, ids), true
+}
+
+func toInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), v == float64(int(v))
+	case string:
+		n, err := strconv.Atoi(v)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func atoiDefault(value string, fallback int) int {
+	if n, err := strconv.Atoi(value); err == nil && n > 0 {
+		return n
+	}
+	return fallback
+}
+
+func union(ids, more []int) []int {
+	for _, id := range more {
+		if !containsInt(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func subtract(ids, remove []int) []int {
+	kept := ids[:0]
+	for _, id := range ids {
+		if !containsInt(remove, id) {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+func containsInt(ids []int, id int) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, existing := range values {
+		if existing == value {
+			return true
+		}
+	}
+	return false
+}
+
+// isSystemField reports whether a title is reserved for the fields the mock maintains
+func isSystemField(title string) bool {
+	switch strings.ToLower(title) {
+	case "id", "createdat", "updatedat":
+		return true
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("[MOCK ERROR] Failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]string{"error": code, "message": message})
+}
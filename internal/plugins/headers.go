@@ -0,0 +1,71 @@
+package plugins
+
+import (
+	"fmt"
+	"net/http"
+)
+
+func init() {
+	Register(&headers{})
+}
+
+// headers sets fixed headers on upstream requests and client responses:
+//
+//	plugins:
+//	  - name: headers
+//	    config:
+//	      request:  { X-Source: gateway }
+//	      response: { Cache-Control: no-store }
+type headers struct {
+	request  map[string]string
+	response map[string]string
+}
+
+func (h *headers) Name() string { return "headers" }
+
+func (h *headers) Configure(settings map[string]interface{}) error {
+	var err error
+	if h.request, err = stringMap(settings, "request"); err != nil {
+		return err
+	}
+	if h.response, err = stringMap(settings, "response"); err != nil {
+		return err
+	}
+	for key := range settings {
+		if key != "request" && key != "response" {
+			return fmt.Errorf("unknown setting '%s' (expected request or response)", key)
+		}
+	}
+	return nil
+}
+
+func (h *headers) PreProxy(r *http.Request, info RequestInfo) error {
+	for name, value := range h.request {
+		r.Header.Set(name, value)
+	}
+	return nil
+}
+
+func (h *headers) PostProxy(resp *Response, info RequestInfo) error {
+	for name, value := range h.response {
+		resp.Header.Set(name, value)
+	}
+	return nil
+}
+
+// stringMap reads a header name -> value setting
+func stringMap(settings map[string]interface{}, key string) (map[string]string, error) {
+	raw, ok := settings[key]
+	if !ok {
+		return nil, nil
+	}
+	values, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("'%s' must map header names to values", key)
+	}
+	result := make(map[string]string, len(values))
+	for name, value := range values {
+		result[name] = fmt.Sprintf("%v", value)
+	}
+	return result, nil
+}
@@ -0,0 +1,222 @@
+// Package plugins lets deployments add request/response hooks without forking
+// the gateway. Plugins are compiled in: a plugin registers itself from an init
+// function, and proxy.yaml enables it under `plugins:`.
+//
+//	func init() { plugins.Register(&billing{}) }
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+// Plugin is a named extension. It implements any of PreAuthHook, PreProxyHook
+// and PostProxyHook, and optionally Configurable.
+type Plugin interface {
+	Name() string
+}
+
+// Configurable plugins receive their `config:` block from proxy.yaml at startup
+type Configurable interface {
+	Configure(settings map[string]interface{}) error
+}
+
+// PreAuthHook runs on /proxy/* requests before the JWT is checked. It may modify
+// the request headers; returning an error rejects the request.
+type PreAuthHook interface {
+	PreAuth(r *http.Request) error
+}
+
+// PreProxyHook runs after authentication and validation, before the request is
+// sent to NocoDB. It may modify headers, query and body; returning an error
+// rejects the request.
+type PreProxyHook interface {
+	PreProxy(r *http.Request, info RequestInfo) error
+}
+
+// PostProxyHook runs on NocoDB's response before it is returned to the client.
+// It may modify the status, headers and body; returning an error replaces the
+// response with an error.
+type PostProxyHook interface {
+	PostProxy(resp *Response, info RequestInfo) error
+}
+
+// RequestInfo describes an authenticated data request
+type RequestInfo struct {
+	Method  string
+	Path    string // path below /proxy/ as sent by the client
+	Table   string // proxy.yaml table key (table name in legacy mode)
+	TableID string
+	UserID  string
+	Role    string
+	Tenant  string
+}
+
+// Response is an upstream response that post-proxy hooks may rewrite
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// RejectError stops a request with the given status and message
+type RejectError struct {
+	Status  int
+	Message string
+}
+
+func (e *RejectError) Error() string { return e.Message }
+
+// Reject returns an error that answers the request with status and message
+func Reject(status int, message string) error {
+	return &RejectError{Status: status, Message: message}
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]Plugin)
+)
+
+// Register makes a plugin available to proxy.yaml. It is meant to be called from
+// init and panics when two plugins share a name.
+func Register(plugin Plugin) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, exists := registry[plugin.Name()]; exists {
+		panic(fmt.Sprintf("plugins: '%s' registered twice", plugin.Name()))
+	}
+	registry[plugin.Name()] = plugin
+}
+
+// Registered returns the names of all compiled-in plugins
+func Registered() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Chain runs the hooks of the enabled plugins in configuration order. A nil
+// Chain runs nothing.
+type Chain struct {
+	plugins []Plugin
+}
+
+// Load configures the plugins enabled in proxy.yaml
+func Load(enabled []config.PluginConfig) (*Chain, error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	chain := &Chain{}
+	for _, entry := range enabled {
+		plugin, ok := registry[entry.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown plugin '%s'", entry.Name)
+		}
+		if configurable, ok := plugin.(Configurable); ok {
+			if err := configurable.Configure(entry.Config); err != nil {
+				return nil, fmt.Errorf("plugin '%s': %w", entry.Name, err)
+			}
+		} else if len(entry.Config) > 0 {
+			return nil, fmt.Errorf("plugin '%s' takes no config", entry.Name)
+		}
+		chain.plugins = append(chain.plugins, plugin)
+	}
+	return chain, nil
+}
+
+// Names returns the enabled plugins in order
+func (c *Chain) Names() []string {
+	if c == nil {
+		return nil
+	}
+	names := make([]string, len(c.plugins))
+	for i, plugin := range c.plugins {
+		names[i] = plugin.Name()
+	}
+	return names
+}
+
+// PreAuth wraps a handler with the pre-auth hooks
+func (c *Chain) PreAuth(next http.Handler) http.Handler {
+	if c == nil || len(c.plugins) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, plugin := range c.plugins {
+			if hook, ok := plugin.(PreAuthHook); ok {
+				if err := hook.PreAuth(r); err != nil {
+					WriteError(w, plugin.Name(), err)
+					return
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// PreProxy runs the pre-proxy hooks, stopping at the first error
+func (c *Chain) PreProxy(r *http.Request, info RequestInfo) (string, error) {
+	if c == nil {
+		return "", nil
+	}
+	for _, plugin := range c.plugins {
+		if hook, ok := plugin.(PreProxyHook); ok {
+			if err := hook.PreProxy(r, info); err != nil {
+				return plugin.Name(), err
+			}
+		}
+	}
+	return "", nil
+}
+
+// PostProxy runs the post-proxy hooks, stopping at the first error
+func (c *Chain) PostProxy(resp *Response, info RequestInfo) (string, error) {
+	if c == nil {
+		return "", nil
+	}
+	for _, plugin := range c.plugins {
+		if hook, ok := plugin.(PostProxyHook); ok {
+			if err := hook.PostProxy(resp, info); err != nil {
+				return plugin.Name(), err
+			}
+		}
+	}
+	return "", nil
+}
+
+// HasPostProxy reports whether a plugin reads or rewrites responses
+func (c *Chain) HasPostProxy() bool {
+	if c == nil {
+		return false
+	}
+	for _, plugin := range c.plugins {
+		if _, ok := plugin.(PostProxyHook); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteError answers a request a plugin hook failed. Rejections keep their
+// status and message; other errors are logged and reported as 500.
+func WriteError(w http.ResponseWriter, plugin string, err error) {
+	var reject *RejectError
+	if errors.As(err, &reject) {
+		log.Printf("[PLUGIN] '%s' rejected request: %d %s", plugin, reject.Status, reject.Message)
+		http.Error(w, reject.Message, reject.Status)
+		return
+	}
+	log.Printf("[PLUGIN ERROR] '%s' failed: %v", plugin, err)
+	http.Error(w, "plugin failed", http.StatusInternalServerError)
+}
@@ -0,0 +1,303 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/events"
+	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/grove/generic-proxy/internal/proxy"
+)
+
+// eraseBatchSize is the number of rows updated or deleted per upstream request
+const eraseBatchSize = 100
+
+// ErasureReport lists what deleting an account touches. A dry run returns it
+// without changing anything.
+type ErasureReport struct {
+	UserID  string                  `json:"user_id"`
+	Email   string                  `json:"email"`
+	DryRun  bool                    `json:"dry_run"`
+	Tables  map[string]TableErasure `json:"tables"`  // table key -> owned rows handled per erase config
+	Tenants []string                `json:"tenants"` // memberships removed
+	Writes  int                     `json:"writes"`  // outbox entries deleted or detached
+	Done    bool                    `json:"done"`    // account deleted and tokens revoked
+}
+
+// TableErasure is what happens to the owned rows of one table
+type TableErasure struct {
+	Mode   string   `json:"mode"`
+	Rows   int      `json:"rows"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+// Eraser deletes accounts along with the data the gateway holds about them
+type Eraser struct {
+	database *db.Database
+	upstream *proxy.UpstreamClient
+	resolved *config.ResolvedConfig
+	events   *events.Bus
+}
+
+// NewEraser creates an eraser. Owned rows are handled in the tables with an
+// erase block in proxy.yaml.
+func NewEraser(database *db.Database, upstream *proxy.UpstreamClient, resolved *config.ResolvedConfig) *Eraser {
+	return &Eraser{database: database, upstream: upstream, resolved: resolved}
+}
+
+// SetEventBus sets the bus that account deletions are published to
+func (e *Eraser) SetEventBus(bus *events.Bus) {
+	e.events = bus
+}
+
+type deleteAccountRequest struct {
+	Confirm string `json:"confirm"` // the account's email
+	DryRun  bool   `json:"dry_run"`
+}
+
+// ServeDeleteAccount handles POST /auth/me/delete. The body must repeat the
+// account's email as {"confirm": "..."}, unless it is a {"dry_run": true}.
+func (e *Eraser) ServeDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	var req deleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	user, status, err := e.account(userID)
+	if err != nil {
+		respondError(w, status, err.Error())
+		return
+	}
+	if !req.DryRun && !strings.EqualFold(req.Confirm, user.Email) {
+		respondError(w, http.StatusBadRequest, "confirm must be the account's email")
+		return
+	}
+	e.serveErase(w, r, user, req.DryRun, userID)
+}
+
+type adminDeleteRequest struct {
+	UserID string `json:"user_id"`
+	DryRun bool   `json:"dry_run"`
+}
+
+// ServeAdminDelete handles POST /admin/users/delete with {"user_id": "...",
+// "dry_run": bool}. Must run after middleware.AuthMiddleware.
+func (e *Eraser) ServeAdminDelete(w http.ResponseWriter, r *http.Request) {
+	if role, _ := r.Context().Value(middleware.RoleKey).(string); role != "admin" {
+		respondError(w, http.StatusForbidden, "admin role required")
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req adminDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID == "" {
+		respondError(w, http.StatusBadRequest, "user_id is required")
+		return
+	}
+	user, status, err := e.account(req.UserID)
+	if err != nil {
+		respondError(w, status, err.Error())
+		return
+	}
+	adminID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	e.serveErase(w, r, user, req.DryRun, adminID)
+}
+
+func (e *Eraser) serveErase(w http.ResponseWriter, r *http.Request, user *db.User, dryRun bool, actor string) {
+	if user.Role == "admin" {
+		admins, err := e.database.CountUsersWithRole("admin")
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to count admins")
+			return
+		}
+		if admins <= 1 {
+			respondError(w, http.StatusConflict, "cannot delete the last admin account")
+			return
+		}
+	}
+
+	report, err := e.Erase(r.Context(), user, dryRun)
+	if err != nil {
+		log.Printf("[PRIVACY ERROR] Deleting account %d (by %s) failed: %v", user.ID, actor, err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error(), "report": report})
+		return
+	}
+	if !dryRun {
+		log.Printf("[PRIVACY] Account %d deleted by %s", user.ID, actor)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// account loads the database account of a user ID, with the status to report when it cannot
+func (e *Eraser) account(userID string) (*db.User, int, error) {
+	id, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("account '%s' is not a database account and cannot be deleted", userID)
+	}
+	user, err := e.database.GetUserByID(id)
+	if err != nil || user == nil {
+		return nil, http.StatusNotFound, fmt.Errorf("no account with id %s", userID)
+	}
+	return user, 0, nil
+}
+
+// Erase deletes an account. Owned rows are handled first, so a failing
+// upstream leaves the account in place and the deletion can be retried. Then
+// the user's tokens are revoked, tenant memberships, outbox, usage and login
+// history removed and the user record deleted.
+func (e *Eraser) Erase(ctx context.Context, user *db.User, dryRun bool) (*ErasureReport, error) {
+	userID := strconv.FormatInt(user.ID, 10)
+	report := &ErasureReport{
+		UserID: userID,
+		Email:  user.Email,
+		DryRun: dryRun,
+		Tables: map[string]TableErasure{},
+	}
+
+	tenants, err := e.database.GetUserTenants(userID)
+	if err != nil {
+		return report, fmt.Errorf("failed to read tenant memberships: %w", err)
+	}
+	report.Tenants = tenants
+
+	for _, key := range e.eraseTables() {
+		table := e.resolved.Tables[key]
+		records, _, err := ownedRecords(ctx, e.upstream, table, userID, 0)
+		if err != nil {
+			return report, fmt.Errorf("failed to read table '%s': %w", key, err)
+		}
+		erasure := TableErasure{Mode: table.Erase.Mode, Rows: len(records)}
+		for field := range table.Erase.Fields {
+			erasure.Fields = append(erasure.Fields, field)
+		}
+		sort.Strings(erasure.Fields)
+		report.Tables[key] = erasure
+
+		if dryRun || len(records) == 0 {
+			continue
+		}
+		if err := e.eraseRows(ctx, key, table, records); err != nil {
+			return report, fmt.Errorf("failed to %s rows of table '%s': %w", table.Erase.Mode, key, err)
+		}
+	}
+
+	if dryRun {
+		writes, err := e.database.ListUserOutbox(userID)
+		if err != nil {
+			return report, fmt.Errorf("failed to read write history: %w", err)
+		}
+		report.Writes = len(writes)
+		return report, nil
+	}
+
+	if err := e.database.RevokeUserTokens(userID); err != nil {
+		return report, fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+	if err := e.database.RemoveAllUserTenants(userID); err != nil {
+		return report, fmt.Errorf("failed to remove tenant memberships: %w", err)
+	}
+	if report.Writes, err = e.database.ScrubUserOutbox(userID); err != nil {
+		return report, fmt.Errorf("failed to remove write history: %w", err)
+	}
+	if err := e.database.DeleteUserUsage(userID); err != nil {
+		return report, fmt.Errorf("failed to remove usage history: %w", err)
+	}
+	if err := e.database.DeleteUserLogins(userID); err != nil {
+		return report, fmt.Errorf("failed to remove login history: %w", err)
+	}
+	if err := e.database.DeleteUserDeviceCodes(userID); err != nil {
+		return report, fmt.Errorf("failed to remove device authorizations: %w", err)
+	}
+	if err := e.database.DeleteUser(user.ID); err != nil {
+		return report, fmt.Errorf("failed to delete user record: %w", err)
+	}
+	report.Done = true
+
+	if e.events != nil {
+		// The deleted account's email is deliberately not part of the event
+		e.events.Publish(events.Event{Type: events.TypeAuthDeleted, Source: events.SourceAuth, UserID: userID})
+	}
+	return report, nil
+}
+
+// eraseRows anonymizes or deletes owned rows in batches. Each handled row is
+// published as a record event, so the caches and the mirror drop it.
+func (e *Eraser) eraseRows(ctx context.Context, key string, table config.ResolvedTable, records []map[string]interface{}) error {
+	ids := make([]string, 0, len(records))
+	for _, record := range records {
+		if id := proxy.RecordID(record); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	for start := 0; start < len(ids); start += eraseBatchSize {
+		batch := ids[start:min(start+eraseBatchSize, len(ids))]
+		var err error
+		if table.Erase.Mode == config.EraseDelete {
+			err = e.upstream.DeleteRecords(ctx, table.TableID, batch)
+		} else {
+			err = e.upstream.UpdateRecords(ctx, table.TableID, batch, table.Erase.Fields)
+		}
+		if err != nil {
+			return err
+		}
+		e.publishErased(key, table, batch)
+	}
+	return nil
+}
+
+// publishErased publishes an update or delete event per erased row. The
+// events carry no user, so they do not tie the rows to the deleted account.
+func (e *Eraser) publishErased(key string, table config.ResolvedTable, ids []string) {
+	if e.events == nil {
+		return
+	}
+	eventType := events.TypeRecordUpdated
+	if table.Erase.Mode == config.EraseDelete {
+		eventType = events.TypeRecordDeleted
+	}
+	for _, id := range ids {
+		e.events.Publish(events.Event{Type: eventType, Source: events.SourceProxy, TableID: table.TableID, TableName: key, RecordID: id})
+	}
+}
+
+// eraseTables returns the keys of the tables with an erase block, sorted
+func (e *Eraser) eraseTables() []string {
+	if e.resolved == nil || e.upstream == nil {
+		return nil
+	}
+	var keys []string
+	for key, table := range e.resolved.Tables {
+		if table.Erase != nil && table.OwnerField != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func respondError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
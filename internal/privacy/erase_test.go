@@ -0,0 +1,112 @@
+package privacy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/events"
+	"github.com/grove/generic-proxy/internal/mocknocodb"
+	"github.com/grove/generic-proxy/internal/proxy"
+)
+
+func TestEraseInvalidatesCachedReads(t *testing.T) {
+	database, err := db.NewDatabase(filepath.Join(t.TempDir(), "gateway.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	user, err := database.CreateUser("leaving@example.com", "local", "Leaving", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := strconv.FormatInt(user.ID, 10)
+
+	mock, err := mocknocodb.New(&mocknocodb.Fixtures{
+		BaseID: "nbase",
+		Tables: []mocknocodb.TableFixture{{
+			Title:  "Notes",
+			Fields: []mocknocodb.FieldFixture{{Title: "Title"}, {Title: "Owner"}},
+			Records: []map[string]interface{}{
+				{"Title": "leaving note", "Owner": userID},
+				{"Title": "staying note", "Owner": "someone-else"},
+			},
+		}},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nocodb := httptest.NewServer(mock)
+	t.Cleanup(nocodb.Close)
+
+	meta := proxy.NewMetaCache(nocodb.URL+"/api/v2/", "nbase", "")
+	if err := meta.LoadInitial(); err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := config.NewResolver(meta).Resolve(&config.ProxyConfig{
+		NocoDB: config.NocoDBConfig{BaseID: "nbase"},
+		Tables: map[string]config.TableConfig{"notes": {
+			Name:       "Notes",
+			Operations: []string{"read"},
+			OwnerField: "Owner",
+			Erase:      &config.EraseConfig{Mode: config.EraseDelete},
+			Cache:      &config.CacheConfig{TTL: "1h"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	bus := events.NewBus()
+	cache := proxy.NewResponseCache()
+	cache.Watch(ctx, bus)
+	handler := proxy.NewProxyHandler(nocodb.URL+"/api/v3/data/nbase/", "", meta)
+	handler.SetResolvedConfig(resolved)
+	handler.SetResponseCache(cache)
+
+	read := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/proxy/notes/records", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("read: got %d: %s", w.Code, w.Body.String())
+		}
+		return w
+	}
+	read()
+	if w := read(); w.Header().Get("X-Gateway-Cache") != "hit" || !strings.Contains(w.Body.String(), "leaving note") {
+		t.Fatalf("second read was not served from the cache: %s %s", w.Header().Get("X-Gateway-Cache"), w.Body.String())
+	}
+
+	eraser := NewEraser(database, proxy.NewUpstreamClient(nocodb.URL+"/api/v3/data/nbase/", "nbase", ""), resolved)
+	eraser.SetEventBus(bus)
+	report, err := eraser.Erase(ctx, user, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Tables["notes"].Rows != 1 {
+		t.Fatalf("erased %d rows; want 1", report.Tables["notes"].Rows)
+	}
+
+	// The cache watches the bus in the background
+	var body string
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if body = read().Body.String(); !strings.Contains(body, "leaving note") {
+			break
+		}
+	}
+	if strings.Contains(body, "leaving note") {
+		t.Fatalf("erased row still served from the cache: %s", body)
+	}
+	if !strings.Contains(body, "staying note") {
+		t.Fatalf("other rows missing after erase: %s", body)
+	}
+}
@@ -0,0 +1,267 @@
+// Package privacy implements the self-service data protection endpoints.
+package privacy
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/grove/generic-proxy/internal/proxy"
+)
+
+const (
+	exportPageSize = 100
+	// maxExportRecords bounds the rows exported per table
+	maxExportRecords = 10000
+)
+
+// Export is everything the gateway holds about a user
+type Export struct {
+	ExportedAt time.Time                           `json:"exported_at"`
+	Account    Account                             `json:"account"`
+	Tenants    []string                            `json:"tenants"`
+	Writes     []Write                             `json:"writes"`
+	Logins     []db.KnownLogin                     `json:"logins"`              // devices and locations signed in from
+	Records    map[string][]map[string]interface{} `json:"records"`             // table key -> owned rows
+	Truncated  []string                            `json:"truncated,omitempty"` // tables with more than maxExportRecords owned rows
+}
+
+// Account is the user's account record, without credentials
+type Account struct {
+	ID          string     `json:"id"`
+	Email       string     `json:"email,omitempty"`
+	Name        string     `json:"name,omitempty"`
+	AvatarURL   string     `json:"avatar_url,omitempty"`
+	Provider    string     `json:"provider,omitempty"`
+	Role        string     `json:"role"`
+	HasPassword bool       `json:"has_password"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+}
+
+// Write is a write the user made through the outbox
+type Write struct {
+	ID             string    `json:"id"`
+	Table          string    `json:"table"`
+	Method         string    `json:"method"`
+	Path           string    `json:"path"`
+	Body           string    `json:"body,omitempty"`
+	Status         string    `json:"status"`
+	ResponseStatus int       `json:"response_status,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Exporter gathers a user's data for /auth/me/export
+type Exporter struct {
+	database *db.Database
+	upstream *proxy.UpstreamClient
+	resolved *config.ResolvedConfig
+}
+
+// NewExporter creates an exporter. Owned rows are read through upstream from
+// the tables marked export: true in proxy.yaml.
+func NewExporter(database *db.Database, upstream *proxy.UpstreamClient, resolved *config.ResolvedConfig) *Exporter {
+	return &Exporter{database: database, upstream: upstream, resolved: resolved}
+}
+
+// ServeExport handles GET /auth/me/export. ?format=zip returns one JSON file
+// per section instead of a single document.
+func (e *Exporter) ServeExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		http.Error(w, "format must be json or zip", http.StatusBadRequest)
+		return
+	}
+
+	export, err := e.Collect(r.Context(), userID, role)
+	if err != nil {
+		log.Printf("[PRIVACY ERROR] Export for user %s failed: %v", userID, err)
+		http.Error(w, "export failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	log.Printf("[PRIVACY] Exported data of user %s (%d writes, %d tables)", userID, len(export.Writes), len(export.Records))
+
+	filename := fmt.Sprintf("export-%s-%s", userID, export.ExportedAt.Format("20060102"))
+	w.Header().Set("Cache-Control", "no-store")
+	if format == "zip" {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, filename))
+		if err := writeZip(w, export); err != nil {
+			log.Printf("[PRIVACY ERROR] Failed to write export archive: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(export); err != nil {
+		log.Printf("[PRIVACY ERROR] Failed to encode export: %v", err)
+	}
+}
+
+// Collect gathers the export of one user
+func (e *Exporter) Collect(ctx context.Context, userID, role string) (*Export, error) {
+	export := &Export{
+		ExportedAt: time.Now().UTC(),
+		Account:    Account{ID: userID, Role: role},
+		Records:    map[string][]map[string]interface{}{},
+	}
+
+	// Demo users have no database account; their export holds the token's identity only
+	if id, err := strconv.ParseInt(userID, 10, 64); err == nil {
+		user, err := e.database.GetUserByID(id)
+		if err == nil && user != nil {
+			created := user.CreatedAt.UTC()
+			export.Account = Account{
+				ID:          userID,
+				Email:       user.Email,
+				Name:        user.Name,
+				AvatarURL:   user.AvatarURL,
+				Provider:    user.Provider,
+				Role:        user.Role,
+				HasPassword: user.PasswordHash != "",
+				CreatedAt:   &created,
+			}
+		}
+	}
+
+	tenants, err := e.database.GetUserTenants(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenant memberships: %w", err)
+	}
+	export.Tenants = tenants
+
+	entries, err := e.database.ListUserOutbox(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read write history: %w", err)
+	}
+	export.Writes = make([]Write, 0, len(entries))
+	for _, entry := range entries {
+		export.Writes = append(export.Writes, Write{
+			ID:             entry.ID,
+			Table:          entry.TableKey,
+			Method:         entry.Method,
+			Path:           entry.Path,
+			Body:           string(entry.Body),
+			Status:         entry.Status,
+			ResponseStatus: entry.ResponseStatus,
+			CreatedAt:      entry.CreatedAt.UTC(),
+		})
+	}
+
+	if export.Logins, err = e.database.ListKnownLogins(userID); err != nil {
+		return nil, fmt.Errorf("failed to read login history: %w", err)
+	}
+
+	for _, key := range e.exportTables() {
+		records, truncated, err := ownedRecords(ctx, e.upstream, e.resolved.Tables[key], userID, maxExportRecords)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read table '%s': %w", key, err)
+		}
+		export.Records[key] = records
+		if truncated {
+			export.Truncated = append(export.Truncated, key)
+		}
+	}
+	return export, nil
+}
+
+// exportTables returns the keys of the tables marked export: true, sorted
+func (e *Exporter) exportTables() []string {
+	if e.resolved == nil || e.upstream == nil {
+		return nil
+	}
+	var keys []string
+	for key, table := range e.resolved.Tables {
+		if table.Export && table.OwnerField != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ownedRecords pages through the rows of a table whose owner field holds
+// userID, stopping after limit rows (0 for no limit). It reports whether rows
+// were left out.
+func ownedRecords(ctx context.Context, upstream *proxy.UpstreamClient, table config.ResolvedTable, userID string, limit int) ([]map[string]interface{}, bool, error) {
+	params := url.Values{}
+	params.Set("where", fmt.Sprintf("(%s,eq,%s)", table.OwnerField, userID))
+
+	records := []map[string]interface{}{}
+	for page := 1; ; page++ {
+		batch, hasMore, err := upstream.ListRecords(ctx, table.TableID, params, page, exportPageSize)
+		if err != nil {
+			return nil, false, err
+		}
+		records = append(records, batch...)
+		if limit > 0 && len(records) >= limit {
+			return records[:limit], hasMore || len(records) > limit, nil
+		}
+		if !hasMore || len(batch) == 0 {
+			return records, false, nil
+		}
+	}
+}
+
+// writeZip writes the export as account.json, tenants.json, writes.json,
+// logins.json and one records/<table>.json per exported table
+func writeZip(w http.ResponseWriter, export *Export) error {
+	type zipFile struct {
+		name    string
+		content interface{}
+	}
+	archive := zip.NewWriter(w)
+	files := []zipFile{
+		{"account.json", export.Account},
+		{"tenants.json", export.Tenants},
+		{"writes.json", export.Writes},
+		{"logins.json", export.Logins},
+	}
+	for _, key := range sortedKeys(export.Records) {
+		files = append(files, zipFile{"records/" + key + ".json", export.Records[key]})
+	}
+	if len(export.Truncated) > 0 {
+		files = append(files, zipFile{"truncated.json", export.Truncated})
+	}
+
+	for _, file := range files {
+		header := &zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: export.ExportedAt}
+		entry, err := archive.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(entry)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func sortedKeys(records map[string][]map[string]interface{}) []string {
+	keys := make([]string, 0, len(records))
+	for key := range records {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
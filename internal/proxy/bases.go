@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// BaseRouter routes /proxy/{base}/{table}/... to the handler of an additional
+// NocoDB base. Requests whose first path segment is not a known base name fall
+// through to the default base, so /proxy/{table}/... keeps working.
+type BaseRouter struct {
+	primary http.Handler
+	bases   map[string]http.Handler
+}
+
+// NewBaseRouter creates a router with the default base handler
+func NewBaseRouter(primary http.Handler) *BaseRouter {
+	return &BaseRouter{
+		primary: primary,
+		bases:   make(map[string]http.Handler),
+	}
+}
+
+// AddBase registers the handler for an additional base
+func (b *BaseRouter) AddBase(name string, handler http.Handler) {
+	b.bases[name] = handler
+}
+
+// Bases returns the names of the additional bases
+func (b *BaseRouter) Bases() []string {
+	names := make([]string, 0, len(b.bases))
+	for name := range b.bases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ServeHTTP dispatches the request to the matching base
+func (b *BaseRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/proxy/")
+	baseName, rest, _ := strings.Cut(path, "/")
+
+	handler, ok := b.bases[baseName]
+	if !ok {
+		b.primary.ServeHTTP(w, r)
+		return
+	}
+
+	log.Printf("[PROXY] Routing to base '%s': /%s", baseName, rest)
+
+	// The base handler sees the path as if it were the only base
+	routed := r.Clone(r.Context())
+	routed.URL.Path = "/proxy/" + rest
+	routed.URL.RawPath = ""
+	handler.ServeHTTP(w, routed)
+}
@@ -0,0 +1,262 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/usage"
+)
+
+const (
+	// maxBatchOperations bounds the operations of one POST /proxy/_batch
+	maxBatchOperations = 50
+	// maxBatchBytes bounds the body of one POST /proxy/_batch
+	maxBatchBytes = 4 << 20
+)
+
+// batchOperation is one request of a batch. References like "$0.id" in id,
+// query and body are replaced by a field of an earlier operation's result.
+type batchOperation struct {
+	Method string            `json:"method"`
+	Table  string            `json:"table"`
+	ID     string            `json:"id,omitempty"`    // record ID for reads, updates and deletes of one record
+	Query  map[string]string `json:"query,omitempty"` // query parameters, e.g. where for list reads
+	Body   interface{}       `json:"body,omitempty"`
+}
+
+// batchResult is the outcome of one operation. Skipped operations follow a
+// failed one and were not sent.
+type batchResult struct {
+	Status  int         `json:"status,omitempty"`
+	Body    interface{} `json:"body,omitempty"`
+	Skipped bool        `json:"skipped,omitempty"`
+
+	record map[string]interface{} // the returned record, for references
+}
+
+// serveBatch handles POST /proxy/_batch: {"operations": [...]}. Operations run
+// in order, each through the full pipeline of a request to /proxy/{table}/records
+// (validation, rules, plugins, encryption, events), and stop at the first one
+// that fails. Completed operations are not rolled back.
+func (p *ProxyHandler) serveBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		respondJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var batch struct {
+		Operations []batchOperation `json:"operations"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxBatchBytes)).Decode(&batch); err != nil {
+		respondJSONError(w, http.StatusBadRequest, `expected {"operations": [...]}`)
+		return
+	}
+	if len(batch.Operations) == 0 || len(batch.Operations) > maxBatchOperations {
+		respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("a batch holds 1 to %d operations", maxBatchOperations))
+		return
+	}
+	for i, op := range batch.Operations {
+		switch op.Method = strings.ToUpper(op.Method); op.Method {
+		case http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodPut, http.MethodDelete:
+		default:
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("operation %d: unsupported method '%s'", i, op.Method))
+			return
+		}
+		if op.Table == "" || strings.ContainsAny(op.Table, "/?#") {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("operation %d: invalid table '%s'", i, op.Table))
+			return
+		}
+		batch.Operations[i] = op
+	}
+
+	results := make([]batchResult, len(batch.Operations))
+	completed := 0
+	for i, op := range batch.Operations {
+		result, err := p.runBatchOperation(r, op, results[:i])
+		if err != nil {
+			result = batchResult{Status: http.StatusBadRequest, Body: map[string]string{"error": err.Error()}}
+		}
+		results[i] = result
+		if result.Status >= 300 {
+			log.Printf("[BATCH] Operation %d (%s %s) failed with %d; skipping the remaining %d", i, op.Method, op.Table, result.Status, len(results)-i-1)
+			for j := i + 1; j < len(results); j++ {
+				results[j].Skipped = true
+			}
+			break
+		}
+		completed++
+	}
+
+	log.Printf("[BATCH] Completed %d of %d operations", completed, len(results))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"completed": completed, "results": results})
+}
+
+// batchQuota counts operation i of a batch against the caller's usage quota.
+// The batch request itself was counted by the meter as its first operation.
+func batchQuota(r *http.Request, i int) *usage.Limit {
+	if i == 0 {
+		return nil
+	}
+	return usage.CountOperation(r.Context())
+}
+
+// runBatchOperation resolves the references of an operation and sends it
+// through ServeHTTP with the caller's identity and headers
+func (p *ProxyHandler) runBatchOperation(r *http.Request, op batchOperation, previous []batchResult) (batchResult, error) {
+	if exceeded := batchQuota(r, len(previous)); exceeded != nil {
+		return batchResult{Status: http.StatusTooManyRequests, Body: map[string]interface{}{
+			"error": fmt.Sprintf("%s quota exceeded", exceeded.Name),
+			"quota": exceeded,
+		}}, nil
+	}
+	id, err := resolveBatchRefs(op.ID, previous)
+	if err != nil {
+		return batchResult{}, err
+	}
+	path := "/proxy/" + url.PathEscape(op.Table) + "/records"
+	if recordID := batchString(id); recordID != "" {
+		path += "/" + url.PathEscape(recordID)
+	}
+
+	params := url.Values{}
+	for name, value := range op.Query {
+		resolved, err := resolveBatchRefs(value, previous)
+		if err != nil {
+			return batchResult{}, err
+		}
+		params.Set(name, batchString(resolved))
+	}
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+
+	var body io.Reader
+	if op.Body != nil {
+		resolved, err := resolveBatchRefs(op.Body, previous)
+		if err != nil {
+			return batchResult{}, err
+		}
+		encoded, err := json.Marshal(resolved)
+		if err != nil {
+			return batchResult{}, fmt.Errorf("invalid body: %w", err)
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), op.Method, path, body)
+	if err != nil {
+		return batchResult{}, fmt.Errorf("invalid operation: %w", err)
+	}
+	req.Header = r.Header.Clone()
+	req.Header.Del("Content-Length")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.RemoteAddr = r.RemoteAddr
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(recorder, req)
+
+	result := batchResult{Status: recorder.Code}
+	answer := recorder.Body.Bytes()
+	var decoded interface{}
+	if err := json.Unmarshal(answer, &decoded); err != nil {
+		result.Body = strings.TrimSpace(string(answer))
+		return result, nil
+	}
+	result.Body = decoded
+	result.record = batchRecord(decoded)
+	return result, nil
+}
+
+// batchString formats a resolved reference for a path or query
+func batchString(value interface{}) string {
+	if number, ok := value.(float64); ok {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// batchRecord finds the record in an operation's response: the object itself,
+// its data field (data envelope) or the only entry of a records or list array
+func batchRecord(decoded interface{}) map[string]interface{} {
+	object, ok := decoded.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if data, ok := object["data"].(map[string]interface{}); ok {
+		return data
+	}
+	for _, key := range []string{"records", "list"} {
+		if list, ok := object[key].([]interface{}); ok {
+			if len(list) != 1 {
+				return nil
+			}
+			record, _ := list[0].(map[string]interface{})
+			return FlattenRecord(record)
+		}
+	}
+	return FlattenRecord(object)
+}
+
+// resolveBatchRefs replaces strings of the form "$N.field" with the field of
+// the record returned by operation N, keeping its JSON type. Field names match
+// case-insensitively when there is no exact match, so "$0.id" finds "Id".
+func resolveBatchRefs(value interface{}, previous []batchResult) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		ref, ok := strings.CutPrefix(v, "$")
+		if !ok {
+			return v, nil
+		}
+		index, field, found := strings.Cut(ref, ".")
+		n, err := strconv.Atoi(index)
+		if !found || err != nil {
+			return v, nil // not a reference, e.g. a price like "$5"
+		}
+		if n < 0 || n >= len(previous) {
+			return nil, fmt.Errorf("reference '%s' does not point to an earlier operation", v)
+		}
+		record := previous[n].record
+		if resolved, ok := record[field]; ok {
+			return resolved, nil
+		}
+		for key, resolved := range record {
+			if strings.EqualFold(key, field) {
+				return resolved, nil
+			}
+		}
+		return nil, fmt.Errorf("reference '%s': operation %d returned no field '%s'", v, n, field)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			resolved, err := resolveBatchRefs(item, previous)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = resolved
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved, err := resolveBatchRefs(item, previous)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	}
+	return value, nil
+}
@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+const (
+	// defaultBodyLogSampleRate is the share of bodies logged in sampled mode
+	defaultBodyLogSampleRate = 0.01
+	// defaultBodyLogMaxBytes cuts logged bodies
+	defaultBodyLogMaxBytes = 2048
+)
+
+// logResponseBody writes a NocoDB response body to the log as the table's
+// body_logging policy says: off, sampled or in full, per status class, with
+// the configured fields redacted and cut at max_bytes
+func (p *ProxyHandler) logResponseBody(tableKey string, status int, body []byte) {
+	policy, ok := p.bodyLogPolicy(tableKey, status)
+	if !ok || len(body) == 0 {
+		return
+	}
+	text := truncateBody(redactBody(body, policy.Redact), bodyLogMaxBytes(policy))
+	if status >= 400 {
+		log.Printf("[PROXY ERROR] NocoDB error body (status %d): %s", status, text)
+	} else {
+		log.Printf("[PROXY] Response body: %s", text)
+	}
+}
+
+// bodyLogPolicy returns the body_logging policy of a table and whether a
+// response with the status is to be logged
+func (p *ProxyHandler) bodyLogPolicy(tableKey string, status int) (config.BodyLoggingConfig, bool) {
+	var policy config.BodyLoggingConfig
+	if p.ResolvedConfig != nil {
+		if table, ok := p.ResolvedConfig.Tables[tableKey]; ok && table.BodyLogging != nil {
+			policy = *table.BodyLogging
+		}
+	}
+
+	switch bodyLogMode(policy, status) {
+	case config.BodyLogFull:
+		return policy, true
+	case config.BodyLogSampled:
+		rate := policy.SampleRate
+		if rate == 0 {
+			rate = defaultBodyLogSampleRate
+		}
+		return policy, rand.Float64() < rate
+	}
+	return policy, false
+}
+
+func bodyLogMaxBytes(policy config.BodyLoggingConfig) int {
+	if policy.MaxBytes == 0 {
+		return defaultBodyLogMaxBytes
+	}
+	return policy.MaxBytes
+}
+
+// bodyLogMode returns the mode of a status's class: the class's own mode, the
+// policy's mode, or by default full for errors and off otherwise
+func bodyLogMode(policy config.BodyLoggingConfig, status int) string {
+	if mode, ok := policy.Status[fmt.Sprintf("%dxx", status/100)]; ok && mode != "" {
+		return mode
+	}
+	if policy.Mode != "" {
+		return policy.Mode
+	}
+	if status >= 400 {
+		return config.BodyLogFull
+	}
+	return config.BodyLogOff
+}
+
+// redactBody masks the values of the listed fields (case-insensitive, at any
+// depth) of a JSON body. Bodies that are not JSON are returned unchanged.
+func redactBody(body []byte, fields []string) string {
+	if len(fields) == 0 {
+		return string(body)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return string(body)
+	}
+	redacted, err := json.Marshal(redactValue(decoded, fields))
+	if err != nil {
+		return string(body)
+	}
+	return string(redacted)
+}
+
+func redactValue(value interface{}, fields []string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			masked := false
+			for _, field := range fields {
+				masked = masked || strings.EqualFold(key, field)
+			}
+			if masked {
+				v[key] = "[REDACTED]"
+			} else {
+				v[key] = redactValue(item, fields)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item, fields)
+		}
+	}
+	return value
+}
+
+// truncateBody cuts text to maxBytes without splitting a character
+func truncateBody(text string, maxBytes int) string {
+	return truncatePrefix(text, maxBytes, int64(len(text)))
+}
+
+// truncatePrefix cuts the first bytes of a body of total bytes to maxBytes
+// without splitting a character. head must hold more than maxBytes bytes
+// when the body does.
+func truncatePrefix(head string, maxBytes int, total int64) string {
+	if total <= int64(maxBytes) {
+		return head
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(head[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s... (%d more bytes)", head[:cut], total-int64(cut))
+}
@@ -0,0 +1,241 @@
+package proxy
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+const (
+	defaultBreakerMinRequests = 20
+	defaultBreakerWindow      = 30 * time.Second
+	defaultBreakerOpenFor     = 15 * time.Second
+)
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"    // requests pass
+	BreakerOpen     = "open"      // requests fail fast
+	BreakerHalfOpen = "half_open" // one trial request passes
+)
+
+// BreakerStats reports the breaker of one upstream host
+type BreakerStats struct {
+	State     string  `json:"state"`
+	Requests  int     `json:"requests"`             // in the current window
+	Failures  int     `json:"failures"`             // in the current window
+	ErrorRate float64 `json:"error_rate"`           // percent, in the current window
+	Trips     int     `json:"trips"`                // times the breaker opened
+	Rejected  int     `json:"rejected"`             // requests answered 503 while open
+	RetryIn   float64 `json:"retry_in_s,omitempty"` // seconds until a trial request is let through
+}
+
+// CircuitBreaker stops sending requests to a NocoDB host whose requests fail
+// at a high rate, so that a dead or very slow upstream does not tie up the
+// gateway's connections and goroutines. Requests fail fast while it is open.
+// After open_for one trial request is let through: its success closes the
+// breaker, its failure opens it again. Failures are connection errors, 5xx
+// outage statuses (502, 503, 504) and, with slow_call set, slow answers.
+// Each host (the default NocoDB, its standby, other upstreams) has its own
+// breaker.
+type CircuitBreaker struct {
+	errorRate   float64
+	minRequests int
+	window      time.Duration
+	openFor     time.Duration
+	slowCall    time.Duration // 0: duration does not count
+
+	mu    sync.Mutex
+	hosts map[string]*hostBreaker
+}
+
+// hostBreaker is the state of one host
+type hostBreaker struct {
+	state       string
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	trial       bool // a half-open trial request is in flight
+	trips       int
+	rejected    int
+}
+
+// NewCircuitBreaker creates the breaker of the circuit_breaker block of
+// proxy.yaml, nil when there is none
+func NewCircuitBreaker(cfg *config.CircuitBreakerConfig) *CircuitBreaker {
+	if cfg == nil {
+		return nil
+	}
+	b := &CircuitBreaker{
+		errorRate:   cfg.ErrorRate,
+		minRequests: cfg.MinRequests,
+		window:      defaultBreakerWindow,
+		openFor:     defaultBreakerOpenFor,
+		hosts:       make(map[string]*hostBreaker),
+	}
+	if b.minRequests == 0 {
+		b.minRequests = defaultBreakerMinRequests
+	}
+	// Durations are validated by the config loader
+	if cfg.Window != "" {
+		b.window, _ = time.ParseDuration(cfg.Window)
+	}
+	if cfg.OpenFor != "" {
+		b.openFor, _ = time.ParseDuration(cfg.OpenFor)
+	}
+	if cfg.SlowCall != "" {
+		b.slowCall, _ = time.ParseDuration(cfg.SlowCall)
+	}
+	return b
+}
+
+// host returns the state of a host. Callers must hold mu.
+func (b *CircuitBreaker) host(name string, now time.Time) *hostBreaker {
+	h, ok := b.hosts[name]
+	if !ok {
+		h = &hostBreaker{state: BreakerClosed, windowStart: now}
+		b.hosts[name] = h
+	}
+	if h.state == BreakerClosed && now.Sub(h.windowStart) >= b.window {
+		h.windowStart, h.requests, h.failures = now, 0, 0
+	}
+	return h
+}
+
+// Allow reports whether a request may be sent to a host, and otherwise how
+// long until the breaker lets a trial request through. An allowed request
+// must be reported with Record.
+func (b *CircuitBreaker) Allow(host string) (bool, time.Duration) {
+	if b == nil {
+		return true, 0
+	}
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h := b.host(host, now)
+	switch h.state {
+	case BreakerOpen:
+		if wait := h.openedAt.Add(b.openFor).Sub(now); wait > 0 {
+			h.rejected++
+			return false, wait
+		}
+		h.state = BreakerHalfOpen
+		fallthrough
+	case BreakerHalfOpen:
+		if h.trial {
+			h.rejected++
+			return false, time.Second
+		}
+		h.trial = true
+	}
+	return true, 0
+}
+
+// Record reports the outcome of an allowed request: a transport error
+// (err), or NocoDB's status and how long it took to answer
+func (b *CircuitBreaker) Record(host string, err error, status int, took time.Duration) {
+	if b == nil {
+		return
+	}
+	failed := err != nil || isUpstreamOutage(status) || (b.slowCall > 0 && took >= b.slowCall)
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h := b.host(host, now)
+
+	if h.state == BreakerHalfOpen {
+		h.trial = false
+		if failed {
+			h.state, h.openedAt = BreakerOpen, now
+			h.trips++
+			return
+		}
+		h.state, h.windowStart, h.requests, h.failures = BreakerClosed, now, 0, 0
+		return
+	}
+	if h.state != BreakerClosed {
+		// Sent before the breaker opened
+		return
+	}
+	h.requests++
+	if failed {
+		h.failures++
+	}
+	if h.requests >= b.minRequests && float64(h.failures)*100 >= b.errorRate*float64(h.requests) {
+		h.state, h.openedAt = BreakerOpen, now
+		h.trips++
+	}
+}
+
+// Release gives back a trial request that was allowed but never sent (the
+// client went away, its latency budget ran out), so that another may be tried
+func (b *CircuitBreaker) Release(host string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if h, ok := b.hosts[host]; ok && h.state == BreakerHalfOpen {
+		h.trial = false
+	}
+}
+
+// Stats returns the state of every host the gateway has sent requests to
+func (b *CircuitBreaker) Stats() map[string]BreakerStats {
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := make(map[string]BreakerStats, len(b.hosts))
+	for name := range b.hosts {
+		h := b.host(name, now)
+		s := BreakerStats{State: h.state, Requests: h.requests, Failures: h.failures, Trips: h.trips, Rejected: h.rejected}
+		if h.requests > 0 {
+			s.ErrorRate = math.Round(float64(h.failures)*1000/float64(h.requests)) / 10
+		}
+		if h.state == BreakerOpen {
+			if wait := h.openedAt.Add(b.openFor).Sub(now); wait > 0 {
+				s.RetryIn = math.Round(wait.Seconds()*10) / 10
+			}
+		}
+		stats[name] = s
+	}
+	return stats
+}
+
+// Describe summarizes the thresholds for the startup log
+func (b *CircuitBreaker) Describe() string {
+	text := fmt.Sprintf("opens at %g%% errors of at least %d requests in %v, for %v", b.errorRate, b.minRequests, b.window, b.openFor)
+	if b.slowCall > 0 {
+		text += fmt.Sprintf("; answers slower than %v count as errors", b.slowCall)
+	}
+	return text
+}
+
+// SetCircuitBreaker fails requests fast while their NocoDB host is failing.
+// Handlers of several bases or tenants share one breaker.
+func (p *ProxyHandler) SetCircuitBreaker(breaker *CircuitBreaker) {
+	p.breaker = breaker
+}
+
+// upstreamHost returns the host:port a breaker keys an upstream URL by
+func upstreamHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return hostAddr(u)
+}
+
+// rejectOpenCircuit answers a request its breaker stopped with 503 and when
+// to try again
+func rejectOpenCircuit(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, "NocoDB is failing; the gateway is not sending requests to it for now, retry later", http.StatusServiceUnavailable)
+}
@@ -0,0 +1,191 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+const (
+	// maxBulkLinkIDs bounds the target records of one bulk link or unlink
+	maxBulkLinkIDs = 5000
+	// maxBulkLinkBytes bounds the body of a bulk link or unlink
+	maxBulkLinkBytes = 1 << 20
+	// bulkLinkChunk is the number of target records sent to NocoDB at once
+	bulkLinkChunk = 100
+)
+
+// bulkLinkFailure is a target record that could not be linked or unlinked
+type bulkLinkFailure struct {
+	ID     interface{} `json:"id"`
+	Status int         `json:"status"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// serveBulkLinks handles POST and DELETE /proxy/{table}/links/{alias}/{id}
+// with a body of bare target record IDs ([1, 2, 3]), as sent to import many
+// relationships at once. The IDs go to NocoDB in chunks, each through the
+// full pipeline of a link request. NocoDB refuses a chunk as a whole, so the
+// records of a refused chunk are retried one by one to find the ones at fault.
+// The answer reports what was done: 200 when every record was linked, 207
+// when some were not. It returns false, having read nothing for good, when
+// the body is not a list of IDs; such requests take the usual way.
+func (p *ProxyHandler) serveBulkLinks(w http.ResponseWriter, r *http.Request, route Route) bool {
+	if r.Body == nil {
+		return false
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBulkLinkBytes+1))
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, "failed to read request body")
+		return true
+	}
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+
+	ids, ok := bulkLinkIDs(body)
+	if !ok {
+		return false
+	}
+	if len(body) > maxBulkLinkBytes || len(ids) > maxBulkLinkIDs {
+		respondJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("a bulk link request holds at most %d records", maxBulkLinkIDs))
+		return true
+	}
+
+	var failures []bulkLinkFailure
+	done, skipped := 0, 0
+chunks:
+	for start := 0; start < len(ids); start += bulkLinkChunk {
+		chunk := ids[start:min(start+bulkLinkChunk, len(ids))]
+		status, message := p.sendLinkChunk(r, route, chunk)
+		switch {
+		case status < 300:
+			done += len(chunk)
+			continue
+		case !retryableLinkStatus(status) || len(chunk) == 1:
+			for _, id := range chunk {
+				failures = append(failures, bulkLinkFailure{ID: id, Status: status, Error: message})
+			}
+			if !retryableLinkStatus(status) {
+				// The request as a whole is refused (permissions, quotas, NocoDB down): stop
+				skipped = len(ids) - start - len(chunk)
+				log.Printf("[PROXY] Bulk %s of %s/%s stopped with %d; %d record(s) not sent", route.Operation(r.Method), route.Table, route.RecordID, status, skipped)
+				break chunks
+			}
+			continue
+		}
+		for _, id := range chunk {
+			status, message := p.sendLinkChunk(r, route, []interface{}{id})
+			if status < 300 {
+				done++
+				continue
+			}
+			failures = append(failures, bulkLinkFailure{ID: id, Status: status, Error: message})
+		}
+	}
+
+	verb := "linked"
+	if r.Method == http.MethodDelete {
+		verb = "unlinked"
+	}
+	log.Printf("[PROXY] Bulk %s: %d of %d record(s) %s", route.Operation(r.Method), done, len(ids), verb)
+
+	status := http.StatusOK
+	switch {
+	case done == 0 && len(failures) > 0:
+		// Nothing was done: the first failure tells why
+		status = failures[0].Status
+	case len(failures) > 0:
+		status = http.StatusMultiStatus
+	}
+	result := map[string]interface{}{"requested": len(ids), verb: done, "failed": failures}
+	if failures == nil {
+		result["failed"] = []bulkLinkFailure{}
+	}
+	if skipped > 0 {
+		result["skipped"] = skipped
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+	return true
+}
+
+// bulkLinkIDs reads a body of bare record IDs (numbers or strings); ok is false
+// for anything else, such as NocoDB's own [{"Id": 1}]
+func bulkLinkIDs(body []byte) ([]interface{}, bool) {
+	var items []interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&items); err != nil || len(items) == 0 {
+		return nil, false
+	}
+	for _, item := range items {
+		switch id := item.(type) {
+		case json.Number:
+		case string:
+			if id == "" {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+	}
+	return items, true
+}
+
+// retryableLinkStatus reports whether a refused chunk may owe its refusal to
+// some of its records (an unknown or duplicate ID), so that they are worth
+// trying one by one
+func retryableLinkStatus(status int) bool {
+	switch status {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity:
+		return true
+	}
+	return false
+}
+
+// sendLinkChunk links or unlinks target records through ServeHTTP with the
+// caller's identity and headers, and returns the status and error message
+func (p *ProxyHandler) sendLinkChunk(r *http.Request, route Route, ids []interface{}) (int, string) {
+	idKey := p.recordIDKey()
+	items := make([]map[string]interface{}, len(ids))
+	for i, id := range ids {
+		items[i] = map[string]interface{}{idKey: id}
+	}
+	encoded, err := json.Marshal(items)
+	if err != nil {
+		return http.StatusBadRequest, err.Error()
+	}
+
+	req := r.Clone(r.Context())
+	req.Body = io.NopCloser(bytes.NewReader(encoded))
+	req.ContentLength = int64(len(encoded))
+	req.Header = r.Header.Clone()
+	req.Header.Del("Content-Length")
+	req.Header.Set("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(recorder, req)
+	if recorder.Code < 300 {
+		return recorder.Code, ""
+	}
+	return recorder.Code, errorMessage(recorder.Body.Bytes())
+}
+
+// errorMessage reads the message of an error body: the gateway's and NocoDB's
+// JSON errors, or plain text
+func errorMessage(body []byte) string {
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(body, &decoded); err == nil {
+		for _, key := range []string{"error", "msg", "message"} {
+			if message, ok := decoded[key].(string); ok && message != "" {
+				return message
+			}
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
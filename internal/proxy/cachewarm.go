@@ -0,0 +1,153 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/grove/generic-proxy/internal/scheduler"
+)
+
+// warmDelay lets a burst of writes settle before invalidated queries are refetched
+const warmDelay = time.Second
+
+// warmUserID identifies the gateway's own warming requests in logs and rules
+const warmUserID = "cache-warmer"
+
+type contextKey string
+
+// cacheRefreshKey marks warming requests, which always go to NocoDB and
+// replace the cached response
+const cacheRefreshKey contextKey = "cache_refresh"
+
+// cacheWarmer refetches the warm queries of a table after it was invalidated
+type cacheWarmer struct {
+	mu      sync.Mutex
+	pending map[string]bool // table keys with a refetch scheduled
+}
+
+// ScheduleCacheWarming adds a job per table with cache.warm queries that
+// fetches them at startup and whenever their TTL runs out, and refetches them
+// after the table is invalidated. It returns the number of warmed tables.
+func (p *ProxyHandler) ScheduleCacheWarming(ctx context.Context, jobs *scheduler.Scheduler, scope string) (int, error) {
+	if p.responses == nil || p.ResolvedConfig == nil {
+		return 0, nil
+	}
+	warmed := 0
+	for tableKey, table := range p.ResolvedConfig.Tables {
+		if table.Cache == nil || len(table.Cache.Warm) == 0 {
+			continue
+		}
+		tableKey := tableKey
+		err := jobs.Add(scheduler.Job{
+			Name:       fmt.Sprintf("cache-warm:%s:%s", scope, tableKey),
+			Schedule:   scheduler.Every(p.cacheTTL(http.MethodGet, tableKey)),
+			RunAtStart: true,
+			Run: func(ctx context.Context) error {
+				return p.warmTable(ctx, tableKey)
+			},
+		})
+		if err != nil {
+			return warmed, err
+		}
+		warmed++
+	}
+	if warmed == 0 {
+		return 0, nil
+	}
+
+	warmer := &cacheWarmer{pending: make(map[string]bool)}
+	p.responses.OnInvalidate(func(tableID string) {
+		tableKey, ok := p.warmedTable(tableID)
+		if !ok {
+			return
+		}
+		warmer.mu.Lock()
+		defer warmer.mu.Unlock()
+		if warmer.pending[tableKey] {
+			return
+		}
+		warmer.pending[tableKey] = true
+		time.AfterFunc(warmDelay, func() {
+			warmer.mu.Lock()
+			delete(warmer.pending, tableKey)
+			warmer.mu.Unlock()
+			if ctx.Err() == nil {
+				p.warmTable(ctx, tableKey)
+			}
+		})
+	})
+	return warmed, nil
+}
+
+// warmedTable returns the key of the table with warm queries that has tableID
+func (p *ProxyHandler) warmedTable(tableID string) (string, bool) {
+	if p.ResolvedConfig == nil {
+		return "", false
+	}
+	for tableKey, table := range p.ResolvedConfig.Tables {
+		if table.TableID == tableID && table.Cache != nil && len(table.Cache.Warm) > 0 {
+			return tableKey, true
+		}
+	}
+	return "", false
+}
+
+// warmTable fetches the warm queries of a table through the handler, as an
+// admin, so they resolve to the same upstream URLs as client requests
+func (p *ProxyHandler) warmTable(ctx context.Context, tableKey string) error {
+	table, ok := p.ResolvedConfig.Tables[tableKey]
+	if !ok || table.Cache == nil {
+		return nil
+	}
+	ctx = context.WithValue(ctx, middleware.UserIDKey, warmUserID)
+	ctx = context.WithValue(ctx, middleware.RoleKey, "admin")
+	ctx = context.WithValue(ctx, cacheRefreshKey, true)
+
+	failed := 0
+	for _, query := range table.Cache.Warm {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/proxy/"+tableKey+"/"+strings.TrimPrefix(query, "/"), nil)
+		if err != nil {
+			log.Printf("[CACHE WARN] Invalid warm query '%s' of table '%s': %v", query, tableKey, err)
+			failed++
+			continue
+		}
+		rec := &discardResponse{header: make(http.Header)}
+		p.ServeHTTP(rec, req)
+		if rec.status != http.StatusOK {
+			log.Printf("[CACHE WARN] Warming '%s' of table '%s' returned status %d", query, tableKey, rec.status)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d warm queries failed", failed, len(table.Cache.Warm))
+	}
+	log.Printf("[CACHE] Warmed table '%s' (%d queries)", tableKey, len(table.Cache.Warm))
+	return nil
+}
+
+// discardResponse is the ResponseWriter of warming requests: only the status matters
+type discardResponse struct {
+	header http.Header
+	status int
+}
+
+func (d *discardResponse) Header() http.Header { return d.header }
+
+func (d *discardResponse) Write(b []byte) (int, error) {
+	if d.status == 0 {
+		d.status = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (d *discardResponse) WriteHeader(status int) {
+	if d.status == 0 {
+		d.status = status
+	}
+}
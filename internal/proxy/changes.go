@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+const (
+	defaultChangesLimit = 500
+	maxChangesLimit     = 5000
+)
+
+// Change is a single entry of a table's change log
+type Change struct {
+	Seq       int64                  `json:"-"`
+	Type      string                 `json:"type"` // created, updated, deleted
+	RecordID  string                 `json:"id"`
+	Record    map[string]interface{} `json:"record,omitempty"`
+	ChangedAt time.Time              `json:"changed_at"`
+}
+
+// ChangeLog provides cursor-based access to recorded table changes
+type ChangeLog interface {
+	Changes(tableKey string, since int64, limit int) ([]Change, bool, error)
+}
+
+// ChangesResponse is returned by GET /proxy/{table}/changes
+type ChangesResponse struct {
+	Changes []Change `json:"changes"`
+	Cursor  string   `json:"cursor"`
+	HasMore bool     `json:"has_more"`
+}
+
+// SetChangeLog sets the change log backing the delta-sync endpoint
+func (p *ProxyHandler) SetChangeLog(changeLog ChangeLog) {
+	p.ChangeLog = changeLog
+}
+
+// serveChanges handles GET /proxy/{table}/changes?since=<cursor>&limit=<n>
+func (p *ProxyHandler) serveChanges(w http.ResponseWriter, r *http.Request, tableKey string) {
+	_, table, err := p.resolveTable(http.MethodGet, tableKey)
+	if err != nil {
+		log.Printf("[CHANGES ERROR] Cannot read changes for '%s': %v", tableKey, err)
+		respondJSONError(w, http.StatusForbidden, "forbidden: "+err.Error())
+		return
+	}
+
+	if p.ChangeLog == nil || table == nil || table.Mirror == nil || !table.Mirror.Enabled {
+		respondJSONError(w, http.StatusNotFound, "delta sync is not enabled for table '"+tableKey+"'")
+		return
+	}
+
+	since := int64(0)
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		since, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil || since < 0 {
+			respondJSONError(w, http.StatusBadRequest, "invalid since cursor")
+			return
+		}
+	}
+
+	limit := defaultChangesLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			limit = n
+		}
+	}
+	if limit > maxChangesLimit {
+		limit = maxChangesLimit
+	}
+
+	changes, expired, err := p.ChangeLog.Changes(tableKey, since, limit)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, "failed to read changes")
+		return
+	}
+	if expired {
+		log.Printf("[CHANGES] Cursor %d for '%s' has expired, client must resync", since, tableKey)
+		respondJSONError(w, http.StatusGone, "cursor expired, perform a full resync")
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+
+	// The cursor advances past changes the caller is not allowed to see
+	response := ChangesResponse{
+		Changes: make([]Change, 0, len(changes)),
+		Cursor:  strconv.FormatInt(since, 10),
+		HasMore: len(changes) == limit,
+	}
+	for _, change := range changes {
+		response.Cursor = strconv.FormatInt(change.Seq, 10)
+		if !CanSeeRecord(table, role, userID, change.Record) {
+			continue
+		}
+		if change.Type == "deleted" {
+			change.Record = nil
+		}
+		change.Record = TransformRecord(table, role, change.Record)
+		response.Changes = append(response.Changes, change)
+	}
+
+	log.Printf("[CHANGES] Returned %d change(s) for '%s' since %d (cursor: %s)", len(response.Changes), tableKey, since, response.Cursor)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// respondJSONError writes a JSON error body with the given status
+func respondJSONError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
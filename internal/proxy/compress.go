@@ -0,0 +1,248 @@
+package proxy
+
+import (
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CompressionConfig decides which /proxy/ responses are sent gzip-compressed
+type CompressionConfig struct {
+	MinBytes int      // smaller bodies are sent as they are
+	Types    []string // media types worth compressing; a trailing * matches a prefix
+	Level    int      // gzip level, 1 (fastest) to 9 (smallest)
+}
+
+// Compress gzips responses for clients that accept it. A response is
+// compressed when its media type is listed, it is not encoded already and its
+// body reaches MinBytes; bodies of unknown length are held back until that
+// many bytes were written. Compressible responses vary on Accept-Encoding.
+func Compress(cfg CompressionConfig) func(http.Handler) http.Handler {
+	pool := &sync.Pool{New: func() interface{} {
+		zw, _ := gzip.NewWriterLevel(nil, cfg.Level)
+		return zw
+	}}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead || !acceptsGzip(r.Header) {
+				if r.Method != http.MethodHead {
+					w = &varyWriter{ResponseWriter: w, cfg: &cfg}
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			cw := &compressWriter{ResponseWriter: w, cfg: &cfg, pool: pool}
+			next.ServeHTTP(cw, r)
+			// Not deferred: an aborted response must not get a valid gzip trailer
+			cw.close()
+		})
+	}
+}
+
+// acceptsGzip reports whether Accept-Encoding allows gzip (RFC 9110, section 12.5.3)
+func acceptsGzip(header http.Header) bool {
+	accepted := false
+	for _, value := range header.Values("Accept-Encoding") {
+		for _, coding := range strings.Split(value, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(coding), ";")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name != "gzip" && name != "x-gzip" && name != "*" {
+				continue
+			}
+			q := 1.0
+			if weight, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+				if parsed, err := strconv.ParseFloat(weight, 64); err == nil {
+					q = parsed
+				}
+			}
+			// An explicit gzip;q=0 wins over *
+			if name != "*" && q == 0 {
+				return false
+			}
+			accepted = accepted || q > 0
+		}
+	}
+	return accepted
+}
+
+// compressible reports whether a response would be compressed for a client
+// that accepts gzip, leaving its length aside
+func (cfg *CompressionConfig) compressible(status int, header http.Header) bool {
+	if status < 200 || status == http.StatusNoContent || status == http.StatusNotModified || status == http.StatusPartialContent {
+		return false
+	}
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return matchesHeader(cfg.Types, mediaType)
+}
+
+// addVary adds Accept-Encoding to the Vary header, once (NocoDB may have named it)
+func addVary(header http.Header) {
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name == "*" || strings.EqualFold(name, "Accept-Encoding") {
+				return
+			}
+		}
+	}
+	header.Add("Vary", "Accept-Encoding")
+}
+
+// varyWriter adds Vary: Accept-Encoding to compressible responses sent
+// uncompressed, so that shared caches keep both versions apart
+type varyWriter struct {
+	http.ResponseWriter
+	cfg         *CompressionConfig
+	wroteHeader bool
+}
+
+func (v *varyWriter) WriteHeader(status int) {
+	if !v.wroteHeader {
+		v.wroteHeader = true
+		if v.cfg.compressible(status, v.Header()) {
+			addVary(v.Header())
+		}
+	}
+	v.ResponseWriter.WriteHeader(status)
+}
+
+func (v *varyWriter) Write(p []byte) (int, error) {
+	if !v.wroteHeader {
+		v.WriteHeader(http.StatusOK)
+	}
+	return v.ResponseWriter.Write(p)
+}
+
+func (v *varyWriter) Flush() {
+	if !v.wroteHeader {
+		v.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := v.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// compressWriter holds the status back until it knows whether the body is
+// compressed: at once when the length is declared or the body is not
+// compressible, otherwise after MinBytes or at the end of the response
+type compressWriter struct {
+	http.ResponseWriter
+	cfg  *CompressionConfig
+	pool *sync.Pool
+
+	status  int // 0: WriteHeader not called yet
+	decided bool
+	pending []byte // body held back while undecided
+	zw      *gzip.Writer
+}
+
+func (c *compressWriter) WriteHeader(status int) {
+	if c.status != 0 {
+		return
+	}
+	c.status = status
+	if !c.cfg.compressible(status, c.Header()) {
+		c.send(false)
+		return
+	}
+	addVary(c.Header())
+	if length := c.Header().Get("Content-Length"); length != "" {
+		n, err := strconv.Atoi(length)
+		c.send(err == nil && n >= c.cfg.MinBytes)
+	}
+}
+
+func (c *compressWriter) Write(p []byte) (int, error) {
+	if c.status == 0 {
+		c.WriteHeader(http.StatusOK)
+	}
+	if !c.decided {
+		c.pending = append(c.pending, p...)
+		if len(c.pending) < c.cfg.MinBytes {
+			return len(p), nil
+		}
+		c.send(true)
+		return len(p), c.flushPending()
+	}
+	if c.zw != nil {
+		return c.zw.Write(p)
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+// Flush sends what was written so far: a streamed body is compressed, since
+// the rest of it is unknown
+func (c *compressWriter) Flush() {
+	if c.status == 0 {
+		c.WriteHeader(http.StatusOK)
+	}
+	if !c.decided {
+		c.send(true)
+		if c.flushPending() != nil {
+			return
+		}
+	}
+	if c.zw != nil {
+		c.zw.Flush()
+	}
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// send writes the status, compressed or not
+func (c *compressWriter) send(compressed bool) {
+	c.decided = true
+	if compressed {
+		header := c.Header()
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		// The encoded body is not the one a strong validator describes
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		c.zw = c.pool.Get().(*gzip.Writer)
+		c.zw.Reset(c.ResponseWriter)
+	}
+	c.ResponseWriter.WriteHeader(c.status)
+}
+
+func (c *compressWriter) flushPending() error {
+	pending := c.pending
+	c.pending = nil
+	if len(pending) == 0 {
+		return nil
+	}
+	if c.zw != nil {
+		_, err := c.zw.Write(pending)
+		return err
+	}
+	_, err := c.ResponseWriter.Write(pending)
+	return err
+}
+
+// close ends the response: a body shorter than MinBytes is sent as it is, and
+// the gzip stream is terminated
+func (c *compressWriter) close() {
+	if c.status == 0 {
+		// Nothing was written; net/http sends 200 without a body
+		return
+	}
+	if !c.decided {
+		c.send(false)
+		c.flushPending()
+	}
+	if c.zw != nil {
+		c.zw.Close()
+		c.pool.Put(c.zw)
+		c.zw = nil
+	}
+}
@@ -0,0 +1,190 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+const (
+	defaultMaxQueue     = 100
+	defaultQueueTimeout = 5 * time.Second
+)
+
+// ErrOverloaded is returned when a request can not get an upstream slot
+// because the wait queue is full or the wait timed out
+var ErrOverloaded = errors.New("upstream overloaded")
+
+// ConcurrencyStats reports the limiter's slots and what happened to requests
+type ConcurrencyStats struct {
+	MaxInFlight int            `json:"max_in_flight,omitempty"`
+	InFlight    int            `json:"in_flight"`
+	Queued      int            `json:"queued"`
+	Tables      map[string]int `json:"tables,omitempty"` // table -> requests in flight
+	Waited      int            `json:"waited"`           // requests that got a slot after queueing
+	Shed        int            `json:"shed"`             // requests answered 503
+}
+
+// ConcurrencyLimiter caps the requests in flight to NocoDB, in total and per
+// table. A request that finds no free slot waits in a bounded queue; when the
+// queue is full or the wait times out it is shed.
+type ConcurrencyLimiter struct {
+	global  chan struct{}            // nil when only tables are limited
+	tables  map[string]chan struct{} // table key -> slots
+	queue   chan struct{}            // one entry per waiting request
+	timeout time.Duration
+
+	mu     sync.Mutex
+	waited int
+	shed   int
+}
+
+// NewConcurrencyLimiter creates the limiter of the concurrency block of
+// proxy.yaml, nil when there is none
+func NewConcurrencyLimiter(cfg *config.ConcurrencyConfig) *ConcurrencyLimiter {
+	if cfg == nil {
+		return nil
+	}
+	maxQueue := cfg.MaxQueue
+	if maxQueue == 0 {
+		maxQueue = defaultMaxQueue
+	}
+	timeout := defaultQueueTimeout
+	if cfg.QueueTimeout != "" {
+		timeout, _ = time.ParseDuration(cfg.QueueTimeout) // validated by the config loader
+	}
+	l := &ConcurrencyLimiter{
+		tables:  make(map[string]chan struct{}, len(cfg.Tables)),
+		queue:   make(chan struct{}, maxQueue),
+		timeout: timeout,
+	}
+	if cfg.MaxInFlight > 0 {
+		l.global = make(chan struct{}, cfg.MaxInFlight)
+	}
+	for table, limit := range cfg.Tables {
+		l.tables[table] = make(chan struct{}, limit)
+	}
+	return l
+}
+
+// Acquire takes a slot of the table and a global one, waiting in the queue
+// when either is taken. The returned function gives the slots back; calling it
+// again does nothing.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context, table string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	var slots []chan struct{}
+	if sem, ok := l.tables[table]; ok {
+		slots = append(slots, sem)
+	}
+	if l.global != nil {
+		slots = append(slots, l.global)
+	}
+
+	// Slots are always taken table first, so waiters cannot deadlock
+	acquired := 0
+	release := func() {
+		for _, sem := range slots[:acquired] {
+			<-sem
+		}
+	}
+	var expired <-chan time.Time
+	for _, sem := range slots {
+		select {
+		case sem <- struct{}{}:
+			acquired++
+			continue
+		default:
+		}
+
+		if expired == nil {
+			select {
+			case l.queue <- struct{}{}:
+				defer func() { <-l.queue }()
+			default:
+				release()
+				l.count(&l.shed)
+				return nil, fmt.Errorf("%w: wait queue is full", ErrOverloaded)
+			}
+			timer := time.NewTimer(l.timeout)
+			defer timer.Stop()
+			expired = timer.C
+		}
+		select {
+		case sem <- struct{}{}:
+			acquired++
+		case <-expired:
+			release()
+			l.count(&l.shed)
+			return nil, fmt.Errorf("%w: no slot within %v", ErrOverloaded, l.timeout)
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	if expired != nil {
+		l.count(&l.waited)
+	}
+	var once sync.Once
+	return func() { once.Do(release) }, nil
+}
+
+func (l *ConcurrencyLimiter) count(counter *int) {
+	l.mu.Lock()
+	*counter++
+	l.mu.Unlock()
+}
+
+// Stats returns the current usage and counters
+func (l *ConcurrencyLimiter) Stats() ConcurrencyStats {
+	stats := ConcurrencyStats{
+		MaxInFlight: cap(l.global),
+		InFlight:    len(l.global),
+		Queued:      len(l.queue),
+	}
+	if len(l.tables) > 0 {
+		stats.Tables = make(map[string]int, len(l.tables))
+		for table, sem := range l.tables {
+			stats.Tables[table] = len(sem)
+		}
+	}
+	l.mu.Lock()
+	stats.Waited, stats.Shed = l.waited, l.shed
+	l.mu.Unlock()
+	return stats
+}
+
+// Describe summarizes the limits for the startup log
+func (l *ConcurrencyLimiter) Describe() string {
+	var parts []string
+	if l.global != nil {
+		parts = append(parts, fmt.Sprintf("%d in flight", cap(l.global)))
+	}
+	tables := make([]string, 0, len(l.tables))
+	for table, sem := range l.tables {
+		tables = append(tables, fmt.Sprintf("%s: %d", table, cap(sem)))
+	}
+	sort.Strings(tables)
+	parts = append(parts, tables...)
+	return fmt.Sprintf("%s (queue %d, wait up to %v)", strings.Join(parts, ", "), cap(l.queue), l.timeout)
+}
+
+// SetConcurrencyLimiter caps the requests this handler has in flight to NocoDB.
+// Handlers of several bases or tenants share one limiter.
+func (p *ProxyHandler) SetConcurrencyLimiter(limiter *ConcurrencyLimiter) {
+	p.concurrency = limiter
+}
+
+// rejectOverloaded answers a shed request with 503 and a hint when to retry
+func rejectOverloaded(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "1")
+	http.Error(w, "upstream busy, retry later", http.StatusServiceUnavailable)
+}
@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+// conditionalHeaders make a read conditional on the client's cached copy
+var conditionalHeaders = []string{"If-None-Match", "If-Modified-Since"}
+
+// localValidators reports whether the gateway answers the conditional headers
+// of a read itself, with an ETag of the body it sends: when the read may be
+// served from a cache, or when the gateway rewrites the body (envelope,
+// decryption, filters, plugins, computed fields, response steps), so that
+// NocoDB's validators do not describe what the client gets. Other reads pass
+// the headers on, and a 304 from NocoDB goes back to the client without a body.
+func (p *ProxyHandler) localValidators(r *http.Request, tableKey string, cached bool) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	return cached || len(p.tableFilters(tableKey)) > 0 || p.plugins.HasPostProxy() || p.hasResponseSteps(tableKey) ||
+		p.envelope(r, tableKey) != config.EnvelopeNocoDB || p.tableEncryption(tableKey) != nil
+}
+
+// bodyETag returns a weak entity tag of a response body. It is weak because
+// the body may still be compressed on its way to the client.
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified reports whether a request's If-None-Match matches an entity tag,
+// compared weakly as RFC 9110 asks for
+func notModified(r *http.Request, etag string) bool {
+	for _, value := range r.Header.Values("If-None-Match") {
+		for _, candidate := range strings.Split(value, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+	}
+	return false
+}
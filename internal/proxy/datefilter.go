@@ -0,0 +1,244 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateFieldTypes are the NocoDB field types date filters apply to, and whether
+// they carry a time of day
+var dateFieldTypes = map[string]bool{"Date": false, "DateTime": true, "CreatedTime": true, "LastModifiedTime": true}
+
+// relativeRange matches last_7_days, next_2_weeks, last_3_months
+var relativeRange = regexp.MustCompile(`^(last|next)_(\d+)_(days|weeks|months|years)$`)
+
+// maxRelativeRange bounds N of last_N_days and its kind
+const maxRelativeRange = 3650
+
+// dateRange is a span of time from (inclusive) to to (exclusive); a zero
+// bound is open
+type dateRange struct {
+	from, to time.Time
+}
+
+// expandDateFilters turns date filter shorthands of a read into where clauses:
+// a query parameter named after a date field of the table, such as
+// ?created_at=last_7_days or ?due=between:2024-01-01,2024-02-01, becomes the
+// comparisons NocoDB understands, ANDed to the read's where clause. Days are
+// those of the client's X-Timezone (an IANA name, default UTC). Parameters
+// that are not date fields are left alone.
+func (p *ProxyHandler) expandDateFilters(x *exchange) error {
+	r := x.r
+	if r.Method != http.MethodGet || (x.route.Kind != RouteRecords && x.route.Kind != RouteCount) || x.tableID == "" {
+		return nil
+	}
+	meta := p.Meta
+	if x.validation != nil {
+		meta = p.Validator.metaFor(p.ResolvedConfig.Tables[x.tableKey].Upstream)
+	}
+	if meta == nil {
+		return nil
+	}
+
+	query := r.URL.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	// A stable order keeps the where clause, and so cache keys, the same
+	sort.Strings(names)
+
+	var clauses []string
+	var location *time.Location
+	for _, name := range names {
+		if name == "where" || strings.Contains(name, "[") {
+			continue
+		}
+		field, ok := meta.FieldMetadata(x.tableID, name)
+		if !ok {
+			continue
+		}
+		withTime, ok := dateFieldTypes[field.Type]
+		if !ok {
+			continue
+		}
+		if location == nil {
+			loc, err := requestLocation(r)
+			if err != nil {
+				return err
+			}
+			location = loc
+		}
+		for _, value := range query[name] {
+			span, err := parseDateFilter(value, time.Now().In(location))
+			if err != nil {
+				return fmt.Errorf("invalid date filter '%s=%s': %w", name, value, err)
+			}
+			clauses = append(clauses, span.where(field.Title, withTime)...)
+		}
+		query.Del(name)
+	}
+	if len(clauses) == 0 {
+		return nil
+	}
+
+	where := strings.Join(clauses, "~and")
+	if existing := query.Get("where"); existing != "" {
+		where = "(" + existing + ")~and" + where
+	}
+	query.Set("where", where)
+	r.URL.RawQuery = query.Encode()
+	return nil
+}
+
+// requestLocation returns the time zone of the client's X-Timezone header
+func requestLocation(r *http.Request) (*time.Location, error) {
+	name := r.Header.Get("X-Timezone")
+	if name == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil || name == "Local" {
+		return nil, fmt.Errorf("unknown time zone '%s' in X-Timezone", name)
+	}
+	return location, nil
+}
+
+// parseDateFilter reads a date filter relative to now, whose location is the
+// client's: today, yesterday, tomorrow, this_week, last_month and the like,
+// last_N_days (today included) and next_N_days (today included) with days,
+// weeks, months or years, a date or timestamp (the day, or the instant),
+// before:, after:, and between:{from},{to} (both included)
+func parseDateFilter(value string, now time.Time) (dateRange, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	week := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7)) // weeks start on Monday
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	year := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+
+	switch value {
+	case "today":
+		return dateRange{today, today.AddDate(0, 0, 1)}, nil
+	case "yesterday":
+		return dateRange{today.AddDate(0, 0, -1), today}, nil
+	case "tomorrow":
+		return dateRange{today.AddDate(0, 0, 1), today.AddDate(0, 0, 2)}, nil
+	case "this_week":
+		return dateRange{week, week.AddDate(0, 0, 7)}, nil
+	case "last_week":
+		return dateRange{week.AddDate(0, 0, -7), week}, nil
+	case "next_week":
+		return dateRange{week.AddDate(0, 0, 7), week.AddDate(0, 0, 14)}, nil
+	case "this_month":
+		return dateRange{month, month.AddDate(0, 1, 0)}, nil
+	case "last_month":
+		return dateRange{month.AddDate(0, -1, 0), month}, nil
+	case "next_month":
+		return dateRange{month.AddDate(0, 1, 0), month.AddDate(0, 2, 0)}, nil
+	case "this_year":
+		return dateRange{year, year.AddDate(1, 0, 0)}, nil
+	case "last_year":
+		return dateRange{year.AddDate(-1, 0, 0), year}, nil
+	case "next_year":
+		return dateRange{year.AddDate(1, 0, 0), year.AddDate(2, 0, 0)}, nil
+	}
+
+	if match := relativeRange.FindStringSubmatch(value); match != nil {
+		n, err := strconv.Atoi(match[2])
+		if err != nil || n < 1 || n > maxRelativeRange {
+			return dateRange{}, fmt.Errorf("the number of %s must be from 1 to %d", match[3], maxRelativeRange)
+		}
+		var years, months, days int
+		switch match[3] {
+		case "days":
+			days = n
+		case "weeks":
+			days = 7 * n
+		case "months":
+			months = n
+		case "years":
+			years = n
+		}
+		if match[1] == "last" {
+			end := today.AddDate(0, 0, 1)
+			return dateRange{end.AddDate(-years, -months, -days), end}, nil
+		}
+		return dateRange{today, today.AddDate(years, months, days)}, nil
+	}
+
+	if bound, ok := strings.CutPrefix(value, "before:"); ok {
+		from, _, err := parseDateBound(bound, now.Location())
+		return dateRange{to: from}, err
+	}
+	if bound, ok := strings.CutPrefix(value, "after:"); ok {
+		_, to, err := parseDateBound(bound, now.Location())
+		return dateRange{from: to}, err
+	}
+	if bounds, ok := strings.CutPrefix(value, "between:"); ok {
+		first, last, found := strings.Cut(bounds, ",")
+		if !found {
+			return dateRange{}, fmt.Errorf("between takes two dates separated by a comma")
+		}
+		from, _, err := parseDateBound(first, now.Location())
+		if err != nil {
+			return dateRange{}, err
+		}
+		_, to, err := parseDateBound(last, now.Location())
+		if err != nil {
+			return dateRange{}, err
+		}
+		if !from.Before(to) {
+			return dateRange{}, fmt.Errorf("the first date of between must not be after the second")
+		}
+		return dateRange{from, to}, nil
+	}
+
+	from, to, err := parseDateBound(value, now.Location())
+	if err != nil {
+		return dateRange{}, fmt.Errorf("expected a date, a timestamp, today, last_7_days, this_month, before:, after: or between:")
+	}
+	return dateRange{from, to}, nil
+}
+
+// parseDateBound reads a date (2024-01-31, a whole day in the client's time
+// zone) or an RFC 3339 timestamp (an instant), and returns when it starts and
+// ends
+func parseDateBound(value string, location *time.Location) (time.Time, time.Time, error) {
+	value = strings.TrimSpace(value)
+	if day, err := time.ParseInLocation("2006-01-02", value, location); err == nil {
+		return day, day.AddDate(0, 0, 1), nil
+	}
+	if instant, err := time.Parse(time.RFC3339, value); err == nil {
+		instant = instant.In(location)
+		return instant, instant.Add(time.Second), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("'%s' is not a date (2024-01-31) or an RFC 3339 timestamp", value)
+}
+
+// where returns the comparisons of the range on a field. Date fields compare
+// the client's calendar days; fields with a time of day compare UTC instants.
+func (d dateRange) where(field string, withTime bool) []string {
+	format := func(t time.Time) string {
+		if withTime {
+			return t.UTC().Format("2006-01-02 15:04:05-07:00")
+		}
+		return t.Format("2006-01-02")
+	}
+	var clauses []string
+	if !d.from.IsZero() {
+		clauses = append(clauses, fmt.Sprintf("(%s,gte,exactDate,%s)", field, format(d.from)))
+	}
+	if !d.to.IsZero() {
+		operator := "lt"
+		if !withTime && (d.to.Hour() != 0 || d.to.Minute() != 0 || d.to.Second() != 0) {
+			// An instant inside a day: the day is included
+			operator = "lte"
+		}
+		clauses = append(clauses, fmt.Sprintf("(%s,%s,exactDate,%s)", field, operator, format(d.to)))
+	}
+	return clauses
+}
@@ -0,0 +1,186 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// APIVersion identifies a NocoDB data API dialect
+type APIVersion string
+
+const (
+	// APIv2 serves records at /api/v2/tables/{tableId}/records
+	APIv2 APIVersion = "v2"
+	// APIv3 serves records at /api/v3/data/{baseId}/{tableId}/records
+	APIv3 APIVersion = "v3"
+)
+
+// Dialect builds upstream data API URLs for one NocoDB instance. All record and
+// link paths are constructed here so the rest of the gateway does not need to
+// know which API version the upstream speaks.
+type Dialect struct {
+	root    string // URL up to (not including) /api/, e.g. http://host:8090
+	version APIVersion
+}
+
+// NewDialect creates a dialect for the instance behind dataURL
+func NewDialect(dataURL string, version APIVersion) *Dialect {
+	return &Dialect{root: apiRoot(dataURL), version: version}
+}
+
+// InferAPIVersion guesses the API version from the configured data URL
+func InferAPIVersion(dataURL string) APIVersion {
+	if strings.Contains(dataURL, "/api/v3/") {
+		return APIv3
+	}
+	return APIv2
+}
+
+// ParseAPIVersion validates an API version setting ("v2" or "v3")
+func ParseAPIVersion(value string) (APIVersion, error) {
+	switch APIVersion(strings.ToLower(value)) {
+	case APIv2:
+		return APIv2, nil
+	case APIv3:
+		return APIv3, nil
+	default:
+		return "", fmt.Errorf("unsupported NocoDB API version '%s' (expected v2 or v3)", value)
+	}
+}
+
+// apiRoot strips the /api/... path from a NocoDB URL
+func apiRoot(dataURL string) string {
+	if i := strings.Index(dataURL, "/api/"); i >= 0 {
+		return dataURL[:i]
+	}
+	return strings.TrimRight(dataURL, "/")
+}
+
+// Version returns the API version
+func (d *Dialect) Version() APIVersion {
+	return d.version
+}
+
+// IsV3 reports whether the dialect uses the v3 data API
+func (d *Dialect) IsV3() bool {
+	return d.version == APIv3
+}
+
+// DataPrefix returns the URL that "{tableId}/{rest}" is appended to
+func (d *Dialect) DataPrefix(baseID string) string {
+	if d.version == APIv3 {
+		return d.root + "/api/v3/data/" + baseID + "/"
+	}
+	return d.root + "/api/v2/tables/"
+}
+
+// RecordsURL returns the list endpoint of a table
+func (d *Dialect) RecordsURL(baseID, tableID string) string {
+	return d.DataPrefix(baseID) + tableID + "/records"
+}
+
+// SharedViewRowsURL returns the public rows endpoint of a shared view. Shared
+// views are served by the v2 public API on v2 and v3 instances alike.
+func (d *Dialect) SharedViewRowsURL(uuid string) string {
+	return d.root + "/api/v2/public/shared-view/" + url.PathEscape(uuid) + "/rows"
+}
+
+// DetectAPIVersion probes the upstream to find the newest data API it serves.
+// It returns the detected version and the server's reported NocoDB version (if any).
+func DetectAPIVersion(ctx context.Context, dataURL, baseID, token string) (APIVersion, string, error) {
+	root := apiRoot(dataURL)
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	get := func(path string) (int, []byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, root+path, nil)
+		if err != nil {
+			return 0, nil, err
+		}
+		req.Header.Set("xc-token", token)
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, nil, err
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return resp.StatusCode, body, nil
+	}
+
+	// The version endpoint is informational; older servers may not expose it
+	serverVersion := ""
+	if status, body, err := get("/api/v1/version"); err == nil && status == http.StatusOK {
+		var info struct {
+			CurrentVersion string `json:"currentVersion"`
+		}
+		if json.Unmarshal(body, &info) == nil {
+			serverVersion = info.CurrentVersion
+		}
+	}
+
+	// Probe the meta routes of each dialect, newest first
+	probes := []struct {
+		version APIVersion
+		path    string
+	}{
+		{APIv3, "/api/v3/meta/bases/" + baseID + "/tables"},
+		{APIv2, "/api/v2/meta/bases/" + baseID + "/tables"},
+	}
+	var lastErr error
+	for _, probe := range probes {
+		status, _, err := get(probe.path)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if status == http.StatusOK {
+			return probe.version, serverVersion, nil
+		}
+		lastErr = fmt.Errorf("%s returned status %d", probe.path, status)
+	}
+	return "", serverVersion, fmt.Errorf("no supported data API found: %w", lastErr)
+}
+
+// ResolveDialect picks the dialect for an upstream. "auto" probes the upstream and
+// falls back to the version in the URL if probing fails.
+func ResolveDialect(ctx context.Context, setting, dataURL, baseID, token string) *Dialect {
+	if setting != "" && setting != "auto" {
+		version, err := ParseAPIVersion(setting)
+		if err == nil {
+			return NewDialect(dataURL, version)
+		}
+		log.Printf("[DIALECT WARN] %v, detecting instead", err)
+	}
+
+	inferred := InferAPIVersion(dataURL)
+	version, serverVersion, err := DetectAPIVersion(ctx, dataURL, baseID, token)
+	if err != nil {
+		log.Printf("[DIALECT WARN] API detection failed (%v), using %s from the configured URL", err, inferred)
+		return NewDialect(dataURL, inferred)
+	}
+	if serverVersion != "" {
+		log.Printf("[DIALECT] NocoDB %s detected, using data API %s", serverVersion, version)
+	} else {
+		log.Printf("[DIALECT] Using data API %s", version)
+	}
+	if version != inferred {
+		log.Printf("[DIALECT] Configured URL suggests %s but the upstream serves %s", inferred, version)
+	}
+	return NewDialect(dataURL, version)
+}
+
+// SetDialect sets the API dialect used to build upstream URLs
+func (p *ProxyHandler) SetDialect(dialect *Dialect) {
+	p.dialect = dialect
+}
+
+// SetDialect sets the API dialect used to build record URLs
+func (c *UpstreamClient) SetDialect(dialect *Dialect) {
+	c.dialect = dialect
+}
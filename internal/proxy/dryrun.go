@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+// Reasons a request would have been refused in dry-run mode
+const (
+	dryRunValidation = "validation" // unknown table, operation or mode (403)
+	dryRunQuery      = "query"      // query parameters outside the table's query block (400)
+	dryRunRule       = "rule"       // an expression rule rejected it
+)
+
+// DryRunStats counts the requests dry-run mode would have refused
+type DryRunStats struct {
+	WouldRefuse int            `json:"would_refuse"`
+	ByTable     map[string]int `json:"by_table"`
+	ByReason    map[string]int `json:"by_reason"` // validation, query or rule
+}
+
+// DryRun runs proxy.yaml validation without enforcing it (the dry_run flag),
+// so legacy deployments can see what enforcement would refuse before turning
+// it on. Handlers of several bases or tenants share one DryRun.
+type DryRun struct {
+	mu    sync.Mutex
+	stats DryRunStats
+}
+
+// NewDryRun creates an empty dry-run counter
+func NewDryRun() *DryRun {
+	return &DryRun{stats: DryRunStats{ByTable: make(map[string]int), ByReason: make(map[string]int)}}
+}
+
+// Stats returns the counters
+func (d *DryRun) Stats() DryRunStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	stats := DryRunStats{WouldRefuse: d.stats.WouldRefuse, ByTable: make(map[string]int), ByReason: make(map[string]int)}
+	for table, count := range d.stats.ByTable {
+		stats.ByTable[table] = count
+	}
+	for reason, count := range d.stats.ByReason {
+		stats.ByReason[reason] = count
+	}
+	return stats
+}
+
+// report logs and counts a request that enforcement would have refused
+func (d *DryRun) report(method, path, table, reason string, status int, err error) {
+	log.Printf("[DRY RUN] Would have refused %s %s with %d (%s): %v", method, path, status, reason, err)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stats.WouldRefuse++
+	d.stats.ByTable[table]++
+	d.stats.ByReason[reason]++
+}
+
+// SetDryRun runs validation without enforcing it; nil enforces it
+func (p *ProxyHandler) SetDryRun(dryRun *DryRun) {
+	p.dryRun = dryRun
+}
+
+// dryRunValidate runs validation, query checks and rules on a copy of the
+// request and records what would have been refused. The request itself is
+// left unchanged.
+func (p *ProxyHandler) dryRunValidate(r *http.Request, path, table string) {
+	trial := r.Clone(r.Context())
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			log.Printf("[DRY RUN] Skipped %s %s: failed to read request body: %v", r.Method, path, err)
+			return
+		}
+		trial.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	status, reason := http.StatusForbidden, dryRunValidation
+	validation, err := p.Validator.ValidateRequest(trial.Method, path)
+	if err == nil {
+		table = validation.TableKey
+		status, reason = http.StatusBadRequest, dryRunQuery
+		if err = p.Validator.SanitizeQuery(trial, validation); err == nil {
+			reason = dryRunRule
+			err = p.Validator.ApplyRules(trial, validation)
+			var ruleErr *config.RuleError
+			if errors.As(err, &ruleErr) {
+				status = ruleErr.Status
+			}
+		}
+	}
+	if err != nil {
+		p.dryRun.report(r.Method, path, table, reason, status, err)
+	}
+}
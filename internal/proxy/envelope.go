@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+// rawEnvelopeKey marks the gateway's own reads through ServeHTTP, which parse
+// NocoDB's shape and must not see a normalized envelope
+const rawEnvelopeKey contextKey = "raw_envelope"
+
+// upstreamList is a list of records in any NocoDB API version: v2 answers
+// {"list": [...], "pageInfo": {...}}, v3 answers {"records": [...], "next": "..."}
+type upstreamList struct {
+	List     []map[string]interface{} `json:"list"`
+	Records  []map[string]interface{} `json:"records"`
+	Next     *string                  `json:"next"`
+	PageInfo *struct {
+		TotalRows  *int64 `json:"totalRows"`
+		Page       *int   `json:"page"`
+		PageSize   *int   `json:"pageSize"`
+		IsLastPage *bool  `json:"isLastPage"`
+	} `json:"pageInfo"`
+}
+
+// envelopeMeta describes the page of a list in the data envelope. Fields the
+// upstream does not report (v3 has no total) are left out.
+type envelopeMeta struct {
+	Total    *int64 `json:"total,omitempty"`
+	Page     *int   `json:"page,omitempty"`
+	PageSize *int   `json:"page_size,omitempty"`
+	HasMore  bool   `json:"has_more"`
+}
+
+// envelope returns the response envelope configured for a table
+func (p *ProxyHandler) envelope(r *http.Request, tableKey string) string {
+	if raw, _ := r.Context().Value(rawEnvelopeKey).(bool); raw || p.ResolvedConfig == nil {
+		return config.EnvelopeNocoDB
+	}
+	table, ok := p.ResolvedConfig.Tables[tableKey]
+	if !ok || table.Envelope == "" {
+		return config.EnvelopeNocoDB
+	}
+	return table.Envelope
+}
+
+// normalizeEnvelope reshapes a successful read of records ({table}/records,
+// {table}/records/{id} and {table}/links/{field}/{id}) into the data or array
+// envelope. Records are flattened to one object with their ID. Other responses,
+// and bodies that are not in a known NocoDB shape, are returned unchanged.
+func normalizeEnvelope(envelope, method string, route Route, status int, header http.Header, body []byte) []byte {
+	if envelope == "" || envelope == config.EnvelopeNocoDB || method != http.MethodGet || status != http.StatusOK {
+		return body
+	}
+
+	switch {
+	case route.IsList():
+		return normalizeList(envelope, header, body)
+	case route.IsRecord():
+		var record map[string]interface{}
+		if err := json.Unmarshal(body, &record); err != nil || record == nil {
+			return body
+		}
+		var out interface{} = FlattenRecord(record)
+		if envelope == config.EnvelopeData {
+			out = map[string]interface{}{"data": out}
+		}
+		return encodeEnvelope(body, out)
+	}
+	return body
+}
+
+func normalizeList(envelope string, header http.Header, body []byte) []byte {
+	records, meta, ok := parseUpstreamList(body)
+	if !ok {
+		return body
+	}
+	if envelope == config.EnvelopeArray {
+		setPageHeaders(header, meta)
+		return encodeEnvelope(body, records)
+	}
+	return encodeEnvelope(body, map[string]interface{}{"data": records, "meta": meta})
+}
+
+// parseUpstreamList returns the flattened records of a NocoDB list and what is
+// known about its page; ok is false when the body is not a list
+func parseUpstreamList(body []byte) ([]map[string]interface{}, envelopeMeta, bool) {
+	var list upstreamList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, envelopeMeta{}, false
+	}
+
+	var meta envelopeMeta
+	switch {
+	case list.Records != nil:
+		records := make([]map[string]interface{}, 0, len(list.Records))
+		for _, record := range list.Records {
+			records = append(records, FlattenRecord(record))
+		}
+		meta.HasMore = list.Next != nil && *list.Next != ""
+		return records, meta, true
+	case list.List != nil:
+		if info := list.PageInfo; info != nil {
+			meta.Total, meta.Page, meta.PageSize = info.TotalRows, info.Page, info.PageSize
+			meta.HasMore = info.IsLastPage != nil && !*info.IsLastPage
+		}
+		return list.List, meta, true
+	}
+	return nil, envelopeMeta{}, false
+}
+
+// setPageHeaders reports a list's page in X-Total-Count (when known) and X-Has-More
+func setPageHeaders(header http.Header, meta envelopeMeta) {
+	if meta.Total != nil {
+		header.Set("X-Total-Count", strconv.FormatInt(*meta.Total, 10))
+	}
+	header.Set("X-Has-More", strconv.FormatBool(meta.HasMore))
+}
+
+// encodeEnvelope marshals the normalized response, keeping the original body
+// if that fails
+func encodeEnvelope(original []byte, out interface{}) []byte {
+	encoded, err := json.Marshal(out)
+	if err != nil {
+		return original
+	}
+	return encoded
+}
@@ -0,0 +1,74 @@
+package proxy
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/plugins"
+)
+
+// errorText returns the message proxy.yaml configures for an error status of a
+// table, in the client's preferred language, and that language ("" for the
+// default). Unknown tables use the top-level messages. ok is false when no
+// message is configured.
+func (p *ProxyHandler) errorText(r *http.Request, tableKey string, status int) (text, language string, ok bool) {
+	if p.ResolvedConfig == nil {
+		return "", "", false
+	}
+	messages := p.ResolvedConfig.Errors
+	if table, found := p.ResolvedConfig.Tables[tableKey]; found {
+		messages = table.Errors
+	}
+	text, language = messages[config.ErrorKind(status)].Localize(r.Header.Get("Accept-Language"))
+	return text, language, text != ""
+}
+
+// httpError answers with the configured message for the status, or with the
+// gateway's own text when there is none
+func (p *ProxyHandler) httpError(w http.ResponseWriter, r *http.Request, tableKey string, status int, message string) {
+	if text, language, ok := p.errorText(r, tableKey, status); ok {
+		log.Printf("[PROXY] Answering '%s' with the configured %s message", message, config.ErrorKind(status))
+		setContentLanguage(w.Header(), language)
+		message = text
+	}
+	http.Error(w, message, status)
+}
+
+// ruleError answers a request refused by a reject rule with the rule's own
+// message, else the table's message for the status
+func (p *ProxyHandler) ruleError(w http.ResponseWriter, r *http.Request, tableKey string, err *config.RuleError) {
+	if err.Translations == nil {
+		p.httpError(w, r, tableKey, err.Status, err.Message)
+		return
+	}
+	text, language := err.Translations.Localize(r.Header.Get("Accept-Language"))
+	setContentLanguage(w.Header(), language)
+	http.Error(w, text, err.Status)
+}
+
+// replaceUpstreamError swaps the body of a NocoDB error for the configured
+// message, as {"error": "..."}. NocoDB's text stays in the log.
+func (p *ProxyHandler) replaceUpstreamError(r *http.Request, tableKey string, response *plugins.Response) {
+	if response.StatusCode < 400 {
+		return
+	}
+	text, language, ok := p.errorText(r, tableKey, response.StatusCode)
+	if !ok {
+		return
+	}
+	body, err := json.Marshal(map[string]string{"error": text})
+	if err != nil {
+		return
+	}
+	response.Body = body
+	response.Header.Set("Content-Type", "application/json")
+	setContentLanguage(response.Header, language)
+}
+
+func setContentLanguage(header http.Header, language string) {
+	if language != "" {
+		header.Set("Content-Language", language)
+	}
+}
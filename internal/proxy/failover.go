@@ -0,0 +1,246 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// failoverThreshold is the number of consecutive failed checks before leaving an upstream
+	failoverThreshold = 3
+	// failbackThreshold is the number of consecutive healthy checks of the primary before
+	// traffic returns to it, so a flapping primary does not bounce traffic back and forth
+	failbackThreshold = 6
+)
+
+// UpstreamHealth is the health-check state of one upstream
+type UpstreamHealth struct {
+	URL                  string    `json:"url"`
+	Healthy              bool      `json:"healthy"`
+	ConsecutiveFailures  int       `json:"consecutive_failures"`
+	ConsecutiveSuccesses int       `json:"consecutive_successes"`
+	LastCheck            time.Time `json:"last_check"`
+	LastError            string    `json:"last_error,omitempty"`
+}
+
+// UpstreamStatus reports which upstream is serving traffic
+type UpstreamStatus struct {
+	Active       string         `json:"active"` // primary or standby
+	ActiveURL    string         `json:"active_url"`
+	Primary      UpstreamHealth `json:"primary"`
+	Standby      UpstreamHealth `json:"standby"`
+	Failovers    int            `json:"failovers"`
+	LastSwitchAt *time.Time     `json:"last_switch_at,omitempty"`
+}
+
+// Failover health-checks a primary and a standby NocoDB instance and rewrites
+// upstream URLs to whichever is active. Both instances must expose the same API paths.
+type Failover struct {
+	primaryOrigin string
+	standbyOrigin string
+	healthPath    string
+	interval      time.Duration
+	httpClient    *http.Client
+
+	mu           sync.RWMutex
+	onStandby    bool
+	primary      UpstreamHealth
+	standby      UpstreamHealth
+	failovers    int
+	lastSwitchAt time.Time
+}
+
+// NewFailover creates a failover controller for the given primary and standby URLs
+func NewFailover(primaryURL, standbyURL, healthPath string, interval time.Duration) (*Failover, error) {
+	primaryOrigin, err := origin(primaryURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid primary URL: %w", err)
+	}
+	standbyOrigin, err := origin(standbyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid standby URL: %w", err)
+	}
+
+	return &Failover{
+		primaryOrigin: primaryOrigin,
+		standbyOrigin: standbyOrigin,
+		healthPath:    "/" + strings.TrimLeft(healthPath, "/"),
+		interval:      interval,
+		httpClient:    &http.Client{Timeout: 5 * time.Second},
+		// Assume the primary is healthy until a check says otherwise
+		primary: UpstreamHealth{URL: primaryOrigin, Healthy: true},
+		standby: UpstreamHealth{URL: standbyOrigin, Healthy: true},
+	}, nil
+}
+
+// origin returns the scheme://host[:port] part of a URL
+func origin(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("missing scheme or host in '%s'", raw)
+	}
+	return u.Scheme + "://" + u.Host, nil
+}
+
+// Rewrite points a URL on the primary instance at the active instance
+func (f *Failover) Rewrite(target string) string {
+	if f == nil {
+		return target
+	}
+	f.mu.RLock()
+	onStandby := f.onStandby
+	f.mu.RUnlock()
+
+	if onStandby && strings.HasPrefix(target, f.primaryOrigin) {
+		return f.standbyOrigin + strings.TrimPrefix(target, f.primaryOrigin)
+	}
+	return target
+}
+
+// Start runs health checks until the context is cancelled
+func (f *Failover) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(f.interval)
+		defer ticker.Stop()
+		for {
+			f.check(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// check probes both upstreams and switches the active one if needed
+func (f *Failover) check(ctx context.Context) {
+	primaryErr := probeHealth(ctx, f.httpClient, f.primaryOrigin+f.healthPath)
+	standbyErr := probeHealth(ctx, f.httpClient, f.standbyOrigin+f.healthPath)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	record(&f.primary, primaryErr)
+	record(&f.standby, standbyErr)
+
+	switch {
+	case !f.onStandby && f.primary.ConsecutiveFailures >= failoverThreshold && f.standby.Healthy:
+		f.onStandby = true
+		f.failovers++
+		f.lastSwitchAt = time.Now()
+		log.Printf("[FAILOVER] Primary %s unhealthy (%s), switching to standby %s", f.primaryOrigin, f.primary.LastError, f.standbyOrigin)
+	case f.onStandby && f.primary.ConsecutiveSuccesses >= failbackThreshold:
+		f.onStandby = false
+		f.lastSwitchAt = time.Now()
+		log.Printf("[FAILOVER] Primary %s healthy for %d checks, switching back from standby", f.primaryOrigin, failbackThreshold)
+	case f.onStandby && f.standby.ConsecutiveFailures >= failoverThreshold && f.primary.Healthy:
+		// Standby failed while the primary is recovering: return early rather than serve errors
+		f.onStandby = false
+		f.lastSwitchAt = time.Now()
+		log.Printf("[FAILOVER] Standby %s unhealthy, switching back to primary", f.standbyOrigin)
+	}
+}
+
+// probeHealth performs a single health check
+func probeHealth(ctx context.Context, client *http.Client, healthURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("health check returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ProbeUpstream checks the health endpoint of the NocoDB instance serving
+// traffic: the active one when failover is configured, otherwise baseURL's
+func ProbeUpstream(ctx context.Context, baseURL, healthPath string, failover *Failover) error {
+	primary, err := origin(baseURL)
+	if err != nil {
+		return err
+	}
+	return probeHealth(ctx, http.DefaultClient, failover.Rewrite(primary+healthPath))
+}
+
+// record updates the health state with the result of a probe
+func record(health *UpstreamHealth, err error) {
+	health.LastCheck = time.Now()
+	if err != nil {
+		health.ConsecutiveFailures++
+		health.ConsecutiveSuccesses = 0
+		health.LastError = err.Error()
+		if health.ConsecutiveFailures >= failoverThreshold {
+			health.Healthy = false
+		}
+		return
+	}
+	health.ConsecutiveFailures = 0
+	health.ConsecutiveSuccesses++
+	health.LastError = ""
+	health.Healthy = true
+}
+
+// Status returns the current failover state
+func (f *Failover) Status() UpstreamStatus {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	status := UpstreamStatus{
+		Active:    "primary",
+		ActiveURL: f.primaryOrigin,
+		Primary:   f.primary,
+		Standby:   f.standby,
+		Failovers: f.failovers,
+	}
+	if f.onStandby {
+		status.Active = "standby"
+		status.ActiveURL = f.standbyOrigin
+	}
+	if !f.lastSwitchAt.IsZero() {
+		switchedAt := f.lastSwitchAt
+		status.LastSwitchAt = &switchedAt
+	}
+	return status
+}
+
+// Available reports whether any upstream instance passed its last health check.
+// Without failover configured the upstream is assumed to be available.
+func (f *Failover) Available() bool {
+	if f == nil {
+		return true
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.primary.Healthy || f.standby.Healthy
+}
+
+// SetFailover routes proxied requests to the active upstream instance
+func (p *ProxyHandler) SetFailover(failover *Failover) {
+	p.failover = failover
+}
+
+// SetFailover routes metadata requests to the active upstream instance
+func (m *MetaCache) SetFailover(failover *Failover) {
+	m.failover = failover
+}
+
+// SetFailover routes record queries to the active upstream instance
+func (c *UpstreamClient) SetFailover(failover *Failover) {
+	c.failover = failover
+}
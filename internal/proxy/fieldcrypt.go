@@ -0,0 +1,141 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/fieldcrypt"
+)
+
+// SetFieldCipher enables the encrypt blocks of tables in proxy.yaml
+func (p *ProxyHandler) SetFieldCipher(cipher *fieldcrypt.Cipher) {
+	p.fieldCipher = cipher
+}
+
+// tableEncryption returns the encrypt block of a table, nil when none applies
+func (p *ProxyHandler) tableEncryption(tableKey string) *config.EncryptConfig {
+	if p.fieldCipher == nil || p.ResolvedConfig == nil {
+		return nil
+	}
+	return p.ResolvedConfig.Tables[tableKey].Encrypt
+}
+
+// encryptRequest encrypts the encrypted fields of written records. Reads may
+// not filter or sort on encrypted fields: NocoDB only sees ciphertext.
+func (p *ProxyHandler) encryptRequest(r *http.Request, tableKey string) error {
+	encrypt := p.tableEncryption(tableKey)
+	if encrypt == nil {
+		return nil
+	}
+
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		for _, field := range encrypt.Fields {
+			if strings.Contains(query.Get("where"), "("+field+",") {
+				return fmt.Errorf("cannot filter on encrypted field '%s'", field)
+			}
+			for _, sort := range strings.Split(query.Get("sort"), ",") {
+				if strings.TrimPrefix(strings.TrimSpace(sort), "-") == field {
+					return fmt.Errorf("cannot sort on encrypted field '%s'", field)
+				}
+			}
+		}
+		return nil
+	}
+
+	if (r.Method != http.MethodPost && r.Method != http.MethodPatch && r.Method != http.MethodPut) || r.Body == nil {
+		return nil
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		// Not JSON: NocoDB reports the error
+		return nil
+	}
+	changed := false
+	for _, record := range ruleRecords(payload) {
+		for _, field := range encrypt.Fields {
+			value, ok := record[field]
+			if !ok {
+				continue
+			}
+			if record[field], err = p.fieldCipher.Encrypt(value); err != nil {
+				return fmt.Errorf("failed to encrypt '%s': %w", field, err)
+			}
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	if body, err = json.Marshal(payload); err != nil {
+		return err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	return nil
+}
+
+// decryptResponse decrypts the encrypted fields of returned records for roles
+// allowed to read them and replaces them with null for everyone else
+func (p *ProxyHandler) decryptResponse(tableKey, role string, body []byte) []byte {
+	encrypt := p.tableEncryption(tableKey)
+	if encrypt == nil {
+		return body
+	}
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return body
+	}
+
+	fields := make(map[string]bool, len(encrypt.Fields))
+	for _, field := range encrypt.Fields {
+		fields[field] = true
+	}
+	p.decryptValues(payload, fields, encrypt.CanDecrypt(role))
+
+	decrypted, err := json.Marshal(payload)
+	if err != nil {
+		return body
+	}
+	return decrypted
+}
+
+// decryptValues walks a response (v2 list or record, v3 records with fields)
+// and rewrites every encrypted value of the given fields
+func (p *ProxyHandler) decryptValues(node interface{}, fields map[string]bool, reveal bool) {
+	switch value := node.(type) {
+	case []interface{}:
+		for _, item := range value {
+			p.decryptValues(item, fields, reveal)
+		}
+	case map[string]interface{}:
+		for key, item := range value {
+			if fields[key] && fieldcrypt.IsEncrypted(item) {
+				value[key] = nil
+				if reveal {
+					plaintext, err := p.fieldCipher.Decrypt(item)
+					if err != nil {
+						log.Printf("[PROXY ERROR] Failed to decrypt field '%s': %v", key, err)
+						continue
+					}
+					value[key] = plaintext
+				}
+				continue
+			}
+			p.decryptValues(item, fields, reveal)
+		}
+	}
+}
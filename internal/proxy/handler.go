@@ -1,13 +1,23 @@
 package proxy
 
 import (
-	"fmt"
+	"bytes"
+	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/events"
+	"github.com/grove/generic-proxy/internal/fieldcrypt"
+	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/grove/generic-proxy/internal/plugins"
+	"github.com/grove/generic-proxy/internal/ratelimit"
+	"github.com/grove/generic-proxy/internal/wasmfilter"
 )
 
 type ProxyHandler struct {
@@ -16,6 +26,27 @@ type ProxyHandler struct {
 	Meta           *MetaCache
 	ResolvedConfig *config.ResolvedConfig
 	Validator      *Validator
+	Events         *events.Bus
+	Mirror         MirrorReader
+	ChangeLog      ChangeLog
+	Outbox         *db.Database
+	outboxNotify   chan struct{}
+	tokenSource    TokenSource
+	roleTokens     map[string]TokenSource
+	failover       *Failover
+	replica        *ReadReplica
+	shadow         *Shadow
+	dialect        *Dialect
+	upstreams      map[string]*Upstream
+	plugins        *plugins.Chain
+	wasm           *wasmfilter.Runtime
+	fieldCipher    *fieldcrypt.Cipher
+	concurrency    *ConcurrencyLimiter
+	breaker        *CircuitBreaker
+	tableRates     *TableRateLimiter
+	dryRun         *DryRun
+	responses      *ResponseCache
+	records        *RecordCache
 }
 
 // NewProxyHandler creates a new proxy handler
@@ -24,6 +55,7 @@ func NewProxyHandler(nocoDBURL, nocoDBToken string, meta *MetaCache) *ProxyHandl
 		NocoDBURL:   nocoDBURL,
 		NocoDBToken: nocoDBToken,
 		Meta:        meta,
+		dialect:     NewDialect(nocoDBURL, InferAPIVersion(nocoDBURL)),
 	}
 }
 
@@ -31,9 +63,20 @@ func NewProxyHandler(nocoDBURL, nocoDBToken string, meta *MetaCache) *ProxyHandl
 func (p *ProxyHandler) SetResolvedConfig(config *config.ResolvedConfig) {
 	p.ResolvedConfig = config
 	p.Validator = NewValidator(config, p.Meta)
+	p.Validator.upstreams = p.upstreams
 	log.Printf("[PROXY] Resolved configuration set with %d tables", len(config.Tables))
 }
 
+// SetEventBus sets the bus that successful write operations are published to
+func (p *ProxyHandler) SetEventBus(bus *events.Bus) {
+	p.Events = bus
+}
+
+// SetPlugins sets the plugin hooks run around every upstream request
+func (p *ProxyHandler) SetPlugins(chain *plugins.Chain) {
+	p.plugins = chain
+}
+
 // ServeHTTP handles proxying requests to NocoDB
 func (p *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[PROXY] Incoming request: %s %s", r.Method, r.URL.Path)
@@ -42,178 +85,421 @@ func (p *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/proxy/")
 	log.Printf("[PROXY] Extracted path: %s", path)
 
-	var resolvedPath string
+	// Table and field IDs may be outdated while metadata is served from a snapshot
+	if p.Meta.IsStale() {
+		w.Header().Set("X-Gateway-Metadata-Stale", "true")
+	}
 
-	// If we have a validator (config-driven mode), use it
-	if p.Validator != nil && p.ResolvedConfig != nil {
-		log.Printf("[PROXY] Using config-driven validation")
+	route := ParseRoute(path)
 
-		validation, err := p.Validator.ValidateRequest(r.Method, path)
-		if err != nil {
-			log.Printf("[PROXY ERROR] Validation failed: %v", err)
-			http.Error(w, "forbidden: "+err.Error(), http.StatusForbidden)
+	// Bodies beyond the table's size limit are refused before anything reads them
+	if !p.limitTableBody(w, r, route) {
+		return
+	}
+
+	// GET /proxy/{table}/{id}/linked/{alias} is served as the link route
+	if route.Kind == RouteLinked && r.Method != http.MethodOptions {
+		p.serveLinked(w, r, route)
+		return
+	}
+
+	// OPTIONS advertises the methods a route allows; HEAD answers like GET without a body
+	switch r.Method {
+	case http.MethodOptions:
+		p.serveOptions(w, r, route)
+		return
+	case http.MethodHead:
+		p.serveHead(w, r, route)
+		return
+	}
+
+	switch {
+	// Several operations in one request: POST /proxy/_batch
+	case route.Kind == RouteBatch:
+		p.serveBatch(w, r)
+		return
+	// Gateway-served sub-resources: GET /proxy/{table}/events (SSE) and /proxy/{table}/changes (delta sync)
+	case route.Kind == RouteEvents && r.Method == http.MethodGet:
+		p.serveEvents(w, r, route.Table)
+		return
+	case route.Kind == RouteChanges && r.Method == http.MethodGet:
+		p.serveChanges(w, r, route.Table)
+		return
+	// Bare target record IDs to link or unlink in bulk: [1, 2, 3]
+	case route.Kind == RouteLinks && (r.Method == http.MethodPost || r.Method == http.MethodDelete):
+		if p.serveBulkLinks(w, r, route) {
 			return
 		}
+	// Record comments and history from NocoDB's meta API: /proxy/{table}/{id}/comments and /history
+	case route.Kind == RouteRecordMeta:
+		p.serveRecordMeta(w, r, route.Table, route.RecordID, route.Resource)
+		return
+	}
 
-		resolvedPath = validation.ResolvedPath
-		log.Printf("[PROXY] Validated and resolved: %s -> %s", path, resolvedPath)
-	} else {
-		// Fallback to MetaCache-only resolution (legacy mode)
-		log.Printf("[PROXY] Using legacy MetaCache-only mode")
-
-		if p.Meta != nil {
-			parts := strings.SplitN(path, "/", 2)
-			if len(parts) > 0 && parts[0] != "" {
-				tableName := parts[0]
-				if tableID, ok := p.Meta.Resolve(tableName); ok {
-					log.Printf("[META] Resolved table '%s' -> '%s'", tableName, tableID)
-
-					// Check if this is a link request and resolve link field alias
-					if len(parts) == 2 {
-						remainingPath := parts[1]
-						resolvedRemainingPath, err := p.resolveLinkFieldInPath(tableID, tableName, remainingPath)
-						if err != nil {
-							log.Printf("[PROXY ERROR] Link field resolution failed: %v", err)
-							http.Error(w, "bad request: "+err.Error(), http.StatusBadRequest)
-							return
-						}
-						resolvedPath = tableID + "/" + resolvedRemainingPath
-					} else {
-						resolvedPath = tableID
-					}
-				} else {
-					log.Printf("[META] No mapping found for table '%s', using raw name", tableName)
-					resolvedPath = path
-				}
-			} else {
-				resolvedPath = path
+	p.runPipeline(&exchange{w: w, r: r, path: path, route: route})
+}
+
+// forward answers a request from the response or record cache, or sends it to
+// NocoDB. Writes may be queued in the outbox and reads served from the mirror
+// when NocoDB is unavailable. Responses the gateway passes through unchanged
+// are streamed; others are read for transform-response.
+func (p *ProxyHandler) forward(x *exchange) bool {
+	w, r, path, tableKey, tableID := x.w, x.r, x.path, x.tableKey, x.tableID
+
+	// Construct the target URL
+	upstreamPath := x.resolvedPath
+	if r.URL.RawQuery != "" {
+		upstreamPath += "?" + r.URL.RawQuery
+	}
+	targetURL := p.tablePrefix(tableKey) + upstreamPath
+	log.Printf("[PROXY] Target URL: %s", targetURL)
+	cacheKey := p.cacheKey(r, targetURL)
+
+	// Reads of cached tables are answered from the response cache without asking NocoDB
+	cacheTTL := p.cacheTTL(r.Method, tableKey)
+	recordID := p.cachedRecord(r.Method, tableKey, x.route)
+	x.localETag = p.localValidators(r, tableKey, cacheTTL > 0 || recordID != "")
+	var cacheVersion responseVersion
+	if cacheTTL > 0 {
+		refreshing, _ := r.Context().Value(cacheRefreshKey).(bool)
+		cached, version, ok := p.responses.lookup(r.Context(), tableID, cacheKey, refreshing)
+		if ok {
+			log.Printf("[PROXY] Serving %s from the response cache", path)
+			w.Header().Set("X-Gateway-Cache", "hit")
+			x.response = &plugins.Response{StatusCode: cached.status, Header: cached.header, Body: cached.body}
+			return true
+		}
+		cacheVersion = version
+	}
+
+	// Reads of single records are answered from the record cache
+	var recordVersion uint64
+	if recordID != "" {
+		recordVersion = p.records.version(tableID)
+		if cached, ok := p.records.get(cacheKey); ok {
+			log.Printf("[PROXY] Serving %s from the record cache", path)
+			w.Header().Set("X-Gateway-Cache", "hit")
+			x.response = &plugins.Response{StatusCode: cached.status, Header: cached.header, Body: cached.body}
+			return true
+		}
+	}
+
+	// Outbox mode: persist writes locally and forward them to NocoDB asynchronously
+	outboxMode := p.outboxMode(r.Method, tableKey)
+	var outboxBody []byte
+	if outboxMode != "" {
+		buffered, err := io.ReadAll(r.Body)
+		if err != nil {
+			log.Printf("[PROXY ERROR] Failed to read request body: %v", err)
+			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			return false
+		}
+		outboxBody = buffered
+		if outboxMode == config.OutboxAlways {
+			p.enqueueWrite(w, r, tableKey, tableID, path, upstreamPath, outboxBody)
+			return false
+		}
+		r.Body = io.NopCloser(bytes.NewReader(outboxBody))
+	}
+
+	// Shadow mode: keep a copy of sampled requests to replay against the shadow instance
+	shadowed := p.shadow.sample(r.Method)
+	var shadowBody []byte
+	if shadowed {
+		if outboxBody != nil {
+			shadowBody = outboxBody
+		} else if r.Body != nil {
+			buffered, err := io.ReadAll(r.Body)
+			if err != nil {
+				log.Printf("[PROXY ERROR] Failed to read request body: %v", err)
+				http.Error(w, "failed to read request body", http.StatusBadRequest)
+				return false
 			}
-		} else {
-			resolvedPath = path
+			shadowBody = buffered
+			r.Body = io.NopCloser(bytes.NewReader(shadowBody))
 		}
 	}
 
-	// Construct the target URL
-	targetURL := p.NocoDBURL
-	if !strings.HasSuffix(targetURL, "/") {
-		targetURL += "/"
+	// Tables with a request budget refuse requests beyond it, whoever sends them
+	if wait := p.tableRates.Take(tableKey); wait > 0 {
+		log.Printf("[PROXY] Not forwarding %s %s: request budget of table '%s' is spent", r.Method, path, tableKey)
+		if p.serveFromMirror(w, r, tableKey, x.route) {
+			return false
+		}
+		ratelimit.Reject(w, wait)
+		return false
 	}
-	targetURL += p.ResolvedConfig.BaseID + "/" + resolvedPath
-	if r.URL.RawQuery != "" {
-		targetURL += "?" + r.URL.RawQuery
+
+	// A role mapped to its own NocoDB token never falls back to the default one
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+	token, ok := p.roleToken(role, tableKey)
+	if !ok {
+		log.Printf("[PROXY] Not forwarding %s %s: no NocoDB token is available for role '%s'", r.Method, path, role)
+		p.httpError(w, r, tableKey, http.StatusServiceUnavailable, "no NocoDB token is available for your role")
+		return false
 	}
-	log.Printf("[PROXY] Target URL: %s", targetURL)
 
-	// Create a new request to NocoDB
-	proxyReq, err := http.NewRequest(r.Method, targetURL, r.Body)
+	// Fail fast while NocoDB is failing, instead of waiting for it
+	host := upstreamHost(targetURL)
+	if allowed, wait := p.breaker.Allow(host); !allowed {
+		log.Printf("[PROXY] Not forwarding %s %s: the circuit breaker of %s is open", r.Method, path, host)
+		if p.serveFromMirror(w, r, tableKey, x.route) {
+			return false
+		}
+		if outboxMode == config.OutboxOnOutage {
+			p.enqueueWrite(w, r, tableKey, tableID, path, upstreamPath, outboxBody)
+			return false
+		}
+		rejectOpenCircuit(w, wait)
+		return false
+	}
+
+	// Backpressure: wait for an upstream slot, or shed the request when NocoDB is saturated
+	release, err := p.concurrency.Acquire(r.Context(), tableKey)
+	if err != nil {
+		p.breaker.Release(host)
+		log.Printf("[PROXY] Not forwarding %s %s: %v", r.Method, path, err)
+		if errors.Is(err, ErrOverloaded) && p.serveFromMirror(w, r, tableKey, x.route) {
+			return false
+		}
+		rejectOverloaded(w)
+		return false
+	}
+	defer release()
+
+	// Create a new request to NocoDB, bounded by the client's latency budget and the table's timeout
+	timeout := p.upstreamTimeout(r, x.route, tableKey)
+	upstreamCtx, cancel := upstreamContext(r, timeout)
+	defer cancel()
+	proxyReq, err := http.NewRequestWithContext(upstreamCtx, r.Method, targetURL, r.Body)
 	if err != nil {
+		p.breaker.Release(host)
 		log.Printf("[PROXY ERROR] Failed to create proxy request: %v", err)
 		http.Error(w, "failed to create proxy request", http.StatusInternalServerError)
-		return
+		return false
 	}
 	log.Printf("[PROXY] Created proxy request successfully")
 
-	// Copy headers from original request (except Authorization)
-	for key, values := range r.Header {
-		if key != "Authorization" {
-			for _, value := range values {
-				proxyReq.Header.Add(key, value)
-			}
+	// Copy headers from the original request (except credentials, hop-by-hop headers and the table's denied ones)
+	p.copyRequestHeaders(proxyReq.Header, r.Header, tableKey)
+	if x.localETag {
+		// NocoDB's answer is cached or rewritten: it must come with a body
+		for _, name := range conditionalHeaders {
+			proxyReq.Header.Del(name)
 		}
 	}
 
 	// Add NocoDB authentication token
-	proxyReq.Header.Set("xc-token", p.NocoDBToken)
+	proxyReq.Header.Set("xc-token", token)
 	log.Printf("[PROXY] Added xc-token header")
 
-	// Execute the request
+	// Execute the request; idempotent ones are retried under the table's retry policy
 	log.Printf("[PROXY] Executing request to NocoDB...")
-	client := &http.Client{}
-	resp, err := client.Do(proxyReq)
+	resp, err := p.doUpstreamRetried(upstreamClient, proxyReq, tableKey)
 	if err != nil {
 		log.Printf("[PROXY ERROR] Failed to execute proxy request: %v", err)
-		http.Error(w, "failed to proxy request", http.StatusBadGateway)
-		return
+		if p.serveFromMirror(w, r, tableKey, x.route) {
+			return false
+		}
+		// A spent budget or timeout is not an outage: the write may have been applied
+		if message, ok := deadlineExceeded(r, timeout, err); ok {
+			p.httpError(w, r, tableKey, http.StatusGatewayTimeout, message)
+			return false
+		}
+		if outboxMode == config.OutboxOnOutage {
+			p.enqueueWrite(w, r, tableKey, tableID, path, upstreamPath, outboxBody)
+			return false
+		}
+		p.httpError(w, r, tableKey, http.StatusBadGateway, "failed to proxy request")
+		return false
 	}
 	defer resp.Body.Close()
 	log.Printf("[PROXY] NocoDB responded with status: %d %s", resp.StatusCode, resp.Status)
 
-	// Copy response headers (excluding CORS headers to prevent duplicates)
-	for key, values := range resp.Header {
-		// Skip CORS headers - these are handled by CORSMiddleware
-		if strings.HasPrefix(key, "Access-Control-") {
-			continue
-		}
-		for _, value := range values {
-			w.Header().Add(key, value)
-		}
+	// Serve reads from the local mirror while NocoDB is unavailable
+	if isUpstreamOutage(resp.StatusCode) && p.serveFromMirror(w, r, tableKey, x.route) {
+		return false
+	}
+
+	// Queue writes while NocoDB is unavailable
+	if isUpstreamOutage(resp.StatusCode) && outboxMode == config.OutboxOnOutage {
+		p.enqueueWrite(w, r, tableKey, tableID, path, upstreamPath, outboxBody)
+		return false
+	}
+
+	// Reads the gateway passes through unchanged are streamed, not held in memory
+	if p.streamable(r, tableKey, resp, cacheTTL > 0 || recordID != "" || shadowed) {
+		p.streamResponse(w, tableKey, resp)
+		return false
 	}
 
-	// Read response body for logging
-	body, err := io.ReadAll(resp.Body)
+	// Read response body for logging, up to the table's size limit
+	body, err := p.readUpstreamBody(resp, tableKey)
+	release() // writing to a slow client does not hold up NocoDB
+	if errors.Is(err, errResponseTooLarge) {
+		log.Printf("[PROXY ERROR] Aborted %s %s: %v", r.Method, path, err)
+		p.httpError(w, r, tableKey, http.StatusBadGateway, err.Error()+"; request fewer records per page with limit and offset")
+		return false
+	}
+	if message, ok := deadlineExceeded(r, timeout, err); ok {
+		log.Printf("[PROXY ERROR] Aborted %s %s: %s", r.Method, path, message)
+		p.httpError(w, r, tableKey, http.StatusGatewayTimeout, message)
+		return false
+	}
 	if err != nil {
 		log.Printf("[PROXY ERROR] Failed to read response body: %v", err)
 		http.Error(w, "failed to read response", http.StatusInternalServerError)
-		return
+		return false
 	}
 
-	// Log response details
+	// Log response details; the body only as the table's body_logging policy allows
 	if resp.StatusCode >= 400 {
-		log.Printf("[PROXY ERROR] NocoDB error response (status %d): %s", resp.StatusCode, string(body))
+		log.Printf("[PROXY ERROR] NocoDB error response (status %d, %d bytes)", resp.StatusCode, len(body))
 	} else {
 		log.Printf("[PROXY] Response body length: %d bytes", len(body))
-		if len(body) < 500 {
-			log.Printf("[PROXY] Response body: %s", string(body))
+	}
+	p.logResponseBody(tableKey, resp.StatusCode, body)
+
+	if cacheTTL > 0 && resp.StatusCode == http.StatusOK {
+		p.responses.put(r.Context(), tableID, cacheKey, cacheVersion, cacheTTL, resp.StatusCode, resp.Header, body)
+		w.Header().Set("X-Gateway-Cache", "miss")
+	}
+	if recordID != "" && resp.StatusCode == http.StatusOK {
+		p.records.put(tableID, recordID, cacheKey, recordVersion, resp.StatusCode, resp.Header, body)
+		w.Header().Set("X-Gateway-Cache", "miss")
+	}
+	x.response = &plugins.Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: body}
+	x.upstream = true
+	x.targetURL, x.sent = targetURL, proxyReq.Header
+	x.shadowed, x.shadow = shadowed, shadowBody
+	return true
+}
+
+// respond sends an upstream (or cached) response to the client. WebAssembly
+// filters and plugins may rewrite it first; it returns false when one of
+// them rejected it.
+func (p *ProxyHandler) respond(x *exchange, status int, header http.Header, body []byte) bool {
+	w, r, tableKey, hookInfo := x.w, x.r, x.tableKey, x.hookInfo
+	// Filters and plugins see decrypted columns; events and shadow comparisons keep the ciphertext
+	response := &plugins.Response{StatusCode: status, Header: header, Body: p.decryptResponse(tableKey, hookInfo.Role, body)}
+	if filter, err := p.filterResponse(r, response, hookInfo); err != nil {
+		plugins.WriteError(w, filter, err)
+		return false
+	}
+	if plugin, err := p.plugins.PostProxy(response, hookInfo); err != nil {
+		plugins.WriteError(w, plugin, err)
+		return false
+	}
+	// The table's own computed fields and steps run last, closest to the client
+	response.Body = p.applyComputed(r, x.route, tableKey, response.StatusCode, response.Body)
+	response.Body = p.applyResponseSteps(tableKey, hookInfo.Role, response.Body)
+
+	// Plugins and filters see NocoDB's shape; clients get the table's envelope and error messages
+	response.Body = normalizeEnvelope(p.envelope(r, tableKey), r.Method, x.route, response.StatusCode, response.Header, response.Body)
+	p.replaceUpstreamError(r, tableKey, response)
+	if response.Header.Get("Content-Length") != "" {
+		response.Header.Set("Content-Length", strconv.Itoa(len(response.Body)))
+	}
+
+	// The client's copy is still current: no body
+	if x.localETag && response.StatusCode == http.StatusOK {
+		etag := bodyETag(response.Body)
+		response.Header.Set("ETag", etag)
+		if notModified(r, etag) {
+			response.Header.Del("Content-Length")
+			p.copyResponseHeaders(w.Header(), response.Header, tableKey)
+			w.WriteHeader(http.StatusNotModified)
+			return true
 		}
 	}
 
+	// Copy response headers (excluding CORS headers to prevent duplicates, hop-by-hop headers and cookies)
+	p.copyResponseHeaders(w.Header(), response.Header, tableKey)
+
 	// Set status code
-	w.WriteHeader(resp.StatusCode)
+	w.WriteHeader(response.StatusCode)
 
 	// Write response body
-	_, err = w.Write(body)
-	if err != nil {
+	if _, err := w.Write(response.Body); err != nil {
 		log.Printf("[PROXY ERROR] Failed to write response: %v", err)
 	}
-	log.Printf("[PROXY] Request completed successfully")
+	return true
 }
 
-// resolveLinkFieldInPath detects link requests and resolves link field aliases to field IDs
-// Handles paths like: links/{linkAlias}/{recordId} -> links/{linkFieldID}/{recordId}
-func (p *ProxyHandler) resolveLinkFieldInPath(tableID, tableName, remainingPath string) (string, error) {
-	// Split the remaining path to check if it's a link request
-	parts := strings.Split(remainingPath, "/")
-
-	// Check if this is a link request: links/{linkAlias}/{recordId}
-	// Pattern: parts[0] = "links", parts[1] = linkAlias, parts[2] = recordId
-	if len(parts) >= 3 && parts[0] == "links" {
-		linkAlias := parts[1]
-		log.Printf("[LINK RESOLVER] Detected link request for table '%s', alias '%s'", tableName, linkAlias)
-
-		// Try to resolve the link field alias to field ID using MetaCache
-		if p.Meta != nil {
-			// Try direct match first
-			linkFieldID, ok := p.Meta.ResolveLinkField(tableID, linkAlias)
-			if !ok {
-				// Try normalized version (replace underscores with spaces)
-				normalizedAlias := strings.ReplaceAll(linkAlias, "_", " ")
-				linkFieldID, ok = p.Meta.ResolveLinkField(tableID, normalizedAlias)
-			}
+// requestInfo describes an authenticated request for plugin hooks
+func requestInfo(r *http.Request, path, tableKey, tableID string) plugins.RequestInfo {
+	info := plugins.RequestInfo{Method: r.Method, Path: path, Table: tableKey, TableID: tableID}
+	info.UserID, _ = r.Context().Value(middleware.UserIDKey).(string)
+	info.Role, _ = r.Context().Value(middleware.RoleKey).(string)
+	info.Tenant, _ = r.Context().Value(middleware.TenantKey).(string)
+	return info
+}
 
-			if ok {
-				log.Printf("[LINK RESOLVER] %s.%s → %s", tableName, linkAlias, linkFieldID)
-				// Replace the alias with the resolved field ID
-				parts[1] = linkFieldID
-				return strings.Join(parts, "/"), nil
-			}
+// publishWriteEvent emits a record event for a successful POST/PATCH/PUT/DELETE
+func (p *ProxyHandler) publishWriteEvent(method, userID, tableKey, tableID string, route Route, body []byte) {
+	if p.Events == nil || tableKey == "" {
+		return
+	}
 
-			// Link field not found in cache
-			return "", fmt.Errorf("unknown link field '%s' for table '%s'", linkAlias, tableName)
-		}
+	var eventType string
+	switch method {
+	case http.MethodPost:
+		eventType = events.TypeRecordCreated
+	case http.MethodPatch, http.MethodPut:
+		eventType = events.TypeRecordUpdated
+	case http.MethodDelete:
+		eventType = events.TypeRecordDeleted
+	default:
+		return
+	}
+
+	// Link operations are not record writes
+	if route.Kind == RouteLinks {
+		return
+	}
 
-		log.Printf("[LINK RESOLVER WARNING] MetaCache not available, using alias as-is")
+	recordID := route.RecordID
+	if recordID == "" {
+		recordID = recordIDFromBody(body)
 	}
 
-	// Not a link request or MetaCache unavailable, return path as-is
-	return remainingPath, nil
+	p.Events.Publish(events.Event{
+		Type:      eventType,
+		Source:    events.SourceProxy,
+		TableID:   tableID,
+		TableName: tableKey,
+		RecordID:  recordID,
+		UserID:    userID,
+	})
+	log.Printf("[PROXY] Published %s event for table '%s' (record: %s)", eventType, tableKey, recordID)
+}
+
+// recordIDFromBody extracts the record ID from a NocoDB write response.
+// Handles both {"Id": 1} (v2) and {"records": [{"id": 1}]} (v3) shapes.
+func recordIDFromBody(body []byte) string {
+	var single map[string]interface{}
+	if err := json.Unmarshal(body, &single); err != nil {
+		return ""
+	}
+
+	if records, ok := single["records"].([]interface{}); ok && len(records) == 1 {
+		if record, ok := records[0].(map[string]interface{}); ok {
+			single = record
+		}
+	}
+
+	return RecordID(single)
+}
+
+// upstreamPrefix returns the NocoDB data URL including the base ID and a trailing slash
+func (p *ProxyHandler) upstreamPrefix() string {
+	if p.ResolvedConfig == nil {
+		// Legacy mode: the client path already carries the base ID
+		prefix := p.NocoDBURL
+		if !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		return p.failover.Rewrite(prefix)
+	}
+	return p.failover.Rewrite(p.dialect.DataPrefix(p.ResolvedConfig.BaseID))
 }
@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+// hopByHopHeaders describe a single connection (RFC 9110, section 7.6.1) and
+// are never forwarded, in either direction
+var hopByHopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// credentialHeaders carry the client's credentials for the gateway, or NocoDB
+// credentials the client must not choose; the gateway sets xc-token itself
+var credentialHeaders = []string{"Authorization", "Cookie", "Xc-Auth", "Xc-Token"}
+
+// gatewayRequestHeaders are answered by the gateway, not NocoDB: the transport
+// asks NocoDB for gzip itself and decodes it, so bodies can be read, and the
+// gateway compresses for the client (see Compress)
+var gatewayRequestHeaders = append([]string{"Accept-Encoding"}, credentialHeaders...)
+
+// upstreamCookieHeaders would set NocoDB's cookies on the gateway's domain
+var upstreamCookieHeaders = []string{"Set-Cookie", "Set-Cookie2"}
+
+// headerPolicy returns the header policy of a table, nil when there is none
+func (p *ProxyHandler) headerPolicy(tableKey string) *config.HeadersConfig {
+	if p.ResolvedConfig == nil {
+		return nil
+	}
+	if table, ok := p.ResolvedConfig.Tables[tableKey]; ok {
+		return table.Headers
+	}
+	return nil
+}
+
+// copyRequestHeaders copies the client's headers to the upstream request,
+// leaving out hop-by-hop, credential and encoding headers and those the
+// table's policy removes
+func (p *ProxyHandler) copyRequestHeaders(dst, src http.Header, tableKey string) {
+	var rules *config.HeaderRules
+	if policy := p.headerPolicy(tableKey); policy != nil {
+		rules = policy.Request
+	}
+	copyHeaders(dst, src, rules, gatewayRequestHeaders)
+}
+
+// copyResponseHeaders copies NocoDB's headers to the client's response,
+// leaving out hop-by-hop headers, cookies, CORS headers (set by
+// CORSMiddleware) and those the table's policy removes
+func (p *ProxyHandler) copyResponseHeaders(dst, src http.Header, tableKey string) {
+	var rules *config.HeaderRules
+	if policy := p.headerPolicy(tableKey); policy != nil {
+		rules = policy.Response
+	}
+	copyHeaders(dst, src, rules, upstreamCookieHeaders)
+}
+
+func copyHeaders(dst, src http.Header, rules *config.HeaderRules, never []string) {
+	// Headers named in Connection are hop-by-hop as well
+	connection := make(map[string]bool)
+	for _, value := range src.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				connection[http.CanonicalHeaderKey(name)] = true
+			}
+		}
+	}
+
+	for key, values := range src {
+		name := http.CanonicalHeaderKey(key)
+		if connection[name] || matchesHeader(hopByHopHeaders, name) || matchesHeader(never, name) || strings.HasPrefix(name, "Access-Control-") {
+			continue
+		}
+		if rules != nil && (matchesHeader(rules.Deny, name) || (len(rules.Allow) > 0 && !matchesHeader(rules.Allow, name))) {
+			continue
+		}
+		for _, value := range values {
+			dst.Add(key, value)
+		}
+	}
+}
+
+// matchesHeader reports whether a header name is listed; a trailing * matches a prefix
+func matchesHeader(names []string, name string) bool {
+	for _, listed := range names {
+		if prefix, ok := strings.CutSuffix(listed, "*"); ok {
+			if len(name) >= len(prefix) && strings.EqualFold(name[:len(prefix)], prefix) {
+				return true
+			}
+		} else if strings.EqualFold(listed, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// maxLinkedPageSize bounds page_size of GET /proxy/{table}/{id}/linked/{alias}
+const maxLinkedPageSize = 1000
+
+// serveLinked answers GET (and HEAD) /proxy/{table}/{id}/linked/{alias}, the
+// records linked to a record through a link field. The alias is looked up in
+// the relation graph, and the request is then served as the link route
+// {table}/links/{alias}/{id}, with its validation, rules, caches and envelope.
+// limit and offset, or page and page_size, page through the records; fields,
+// sort and where are passed on.
+func (p *ProxyHandler) serveLinked(w http.ResponseWriter, r *http.Request, route Route) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		if methods, ok := p.allowedMethods(route); ok {
+			w.Header().Set("Allow", strings.Join(methods, ", "))
+		}
+		respondJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	tableID, table, err := p.resolveTable(http.MethodGet, route.Table)
+	if err != nil {
+		log.Printf("[PROXY] Cannot read links of '%s': %v", route.Table, err)
+		p.httpError(w, r, route.Table, http.StatusForbidden, "forbidden: "+err.Error())
+		return
+	}
+
+	meta := p.Meta
+	field := route.LinkField
+	if table != nil {
+		if p.Validator != nil {
+			meta = p.Validator.metaFor(table.Upstream)
+		}
+		if link, ok := table.Links[route.LinkField]; ok {
+			field = link.FieldID
+		}
+	}
+	if meta != nil && tableID != "" {
+		relation, ok := meta.ResolveLinkTarget(tableID, field)
+		if !ok {
+			relation, ok = meta.ResolveLinkTarget(tableID, strings.ReplaceAll(field, "_", " "))
+		}
+		if !ok {
+			p.httpError(w, r, route.Table, http.StatusNotFound, "table '"+route.Table+"' has no link '"+route.LinkField+"'")
+			return
+		}
+		w.Header().Set("X-Linked-Table", p.linkedTableName(relation))
+	}
+
+	query, err := linkedQuery(r.URL.Query())
+	if err != nil {
+		p.httpError(w, r, route.Table, http.StatusBadRequest, err.Error())
+		return
+	}
+	links := r.Clone(r.Context())
+	links.URL.Path = "/proxy/" + route.Table + "/links/" + route.LinkField + "/" + route.RecordID
+	links.URL.RawPath = ""
+	links.URL.RawQuery = query.Encode()
+	links.RequestURI = links.URL.RequestURI()
+	p.ServeHTTP(w, links)
+}
+
+// linkedTableName names the target of a relation by its proxy.yaml table key
+// when it has one, by its NocoDB title otherwise
+func (p *ProxyHandler) linkedTableName(relation Relation) string {
+	if p.ResolvedConfig != nil {
+		for key, table := range p.ResolvedConfig.Tables {
+			if table.TableID == relation.TargetTableID {
+				return key
+			}
+		}
+	}
+	if relation.TargetTable != "" {
+		return relation.TargetTable
+	}
+	return relation.TargetTableID
+}
+
+// linkedQuery turns page and page_size into NocoDB's limit and offset
+func linkedQuery(query url.Values) (url.Values, error) {
+	if !query.Has("page") && !query.Has("page_size") {
+		return query, nil
+	}
+	if query.Has("limit") || query.Has("offset") {
+		return nil, errors.New("bad request: page and page_size cannot be combined with limit and offset")
+	}
+	page, size := 1, 25
+	if value := query.Get("page"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return nil, errors.New("bad request: page must be a positive number")
+		}
+		page = n
+	}
+	if value := query.Get("page_size"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxLinkedPageSize {
+			return nil, fmt.Errorf("bad request: page_size must be a number from 1 to %d", maxLinkedPageSize)
+		}
+		size = n
+	}
+	query.Del("page")
+	query.Del("page_size")
+	query.Set("limit", strconv.Itoa(size))
+	query.Set("offset", strconv.Itoa((page-1)*size))
+	return query, nil
+}
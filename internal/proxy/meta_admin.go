@@ -0,0 +1,143 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+// maxMetaBodyBytes bounds the body of a schema change
+const maxMetaBodyBytes = 1 << 20
+
+// metaViewKinds maps the view kinds of /admin/meta to NocoDB's meta API collections
+var metaViewKinds = map[string]string{
+	"grid":    "grids",
+	"form":    "forms",
+	"gallery": "galleries",
+	"kanban":  "kanbans",
+}
+
+// MetaAdmin lets admins change the schema of a base through the gateway. It
+// forwards a few NocoDB meta API operations and refreshes the base's MetaCache
+// afterwards, so the new tables, fields and views resolve right away:
+//
+//	POST /admin/meta/tables                         create a table
+//	POST /admin/meta/tables/{table}/columns         add a field
+//	POST /admin/meta/tables/{table}/views/{kind}    create a grid, form, gallery or kanban view
+//
+// Bodies are passed to NocoDB unchanged. ?base={name} addresses an additional base.
+type MetaAdmin struct {
+	meta  *MetaCache
+	bases map[string]*MetaCache
+}
+
+// NewMetaAdmin creates the handler for the default base
+func NewMetaAdmin(meta *MetaCache) *MetaAdmin {
+	return &MetaAdmin{meta: meta, bases: make(map[string]*MetaCache)}
+}
+
+// AddBase makes an additional base addressable with ?base={name}
+func (a *MetaAdmin) AddBase(name string, meta *MetaCache) {
+	a.bases[name] = meta
+}
+
+// ServeHTTP handles /admin/meta/*. Must run after middleware.AuthMiddleware.
+func (a *MetaAdmin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if role, _ := r.Context().Value(middleware.RoleKey).(string); role != "admin" {
+		respondJSONError(w, http.StatusForbidden, "admin role required")
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		respondJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	meta := a.meta
+	if name := r.URL.Query().Get("base"); name != "" {
+		var ok bool
+		if meta, ok = a.bases[name]; !ok {
+			respondJSONError(w, http.StatusNotFound, fmt.Sprintf("unknown base '%s'", name))
+			return
+		}
+	}
+	if meta == nil {
+		respondJSONError(w, http.StatusServiceUnavailable, "metadata is not available")
+		return
+	}
+
+	target, description, err := a.route(meta, strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/meta"), "/"))
+	if err != nil {
+		respondJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxMetaBodyBytes))
+	if err != nil || !json.Valid(body) {
+		respondJSONError(w, http.StatusBadRequest, "expected a JSON body")
+		return
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, meta.failover.Rewrite(target), bytes.NewReader(body))
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, "failed to create upstream request")
+		return
+	}
+	req.Header.Set("xc-token", meta.currentToken())
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := meta.httpClient.Do(req)
+	if err != nil {
+		log.Printf("[META ADMIN ERROR] Failed to %s: %v", description, err)
+		respondJSONError(w, http.StatusBadGateway, "failed to reach NocoDB")
+		return
+	}
+	defer resp.Body.Close()
+	answer, _ := io.ReadAll(resp.Body)
+
+	adminID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	log.Printf("[META ADMIN] User %s: %s in base %s (NocoDB returned %d)", adminID, description, meta.baseID, resp.StatusCode)
+
+	// New tables, fields and views must resolve before the next request uses them
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if err := meta.Refresh(); err != nil {
+			log.Printf("[META ADMIN WARNING] Schema changed but the metadata refresh failed: %v", err)
+			w.Header().Set("X-Gateway-Metadata-Stale", "true")
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.StatusCode)
+	w.Write(answer)
+}
+
+// route maps a path below /admin/meta to its NocoDB meta API URL and a
+// description for the log
+func (a *MetaAdmin) route(meta *MetaCache, path string) (string, string, error) {
+	parts := strings.Split(path, "/")
+	if len(parts) == 0 || parts[0] != "tables" {
+		return "", "", fmt.Errorf("unknown meta operation '%s'", path)
+	}
+	if len(parts) == 1 {
+		return fmt.Sprintf("%smeta/bases/%s/tables", meta.metaBaseURL, meta.baseID), "create a table", nil
+	}
+
+	tableID, ok := meta.Resolve(parts[1])
+	if !ok {
+		return "", "", fmt.Errorf("table '%s' not found", parts[1])
+	}
+	switch {
+	case len(parts) == 3 && parts[2] == "columns":
+		return fmt.Sprintf("%smeta/tables/%s/columns", meta.metaBaseURL, tableID), fmt.Sprintf("add a field to table '%s'", parts[1]), nil
+	case len(parts) == 4 && parts[2] == "views":
+		if collection, ok := metaViewKinds[parts[3]]; ok {
+			return fmt.Sprintf("%smeta/tables/%s/%s", meta.metaBaseURL, tableID, collection), fmt.Sprintf("create a %s view of table '%s'", parts[3], parts[1]), nil
+		}
+		return "", "", fmt.Errorf("unknown view kind '%s' (expected grid, form, gallery or kanban)", parts[3])
+	}
+	return "", "", fmt.Errorf("unknown meta operation '%s'", path)
+}
@@ -1,21 +1,40 @@
 package proxy
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"math/rand"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/grove/generic-proxy/internal/events"
+	"github.com/grove/generic-proxy/internal/scheduler"
 )
 
+// refreshBackoffBase is the first retry delay after a failed refresh
+const refreshBackoffBase = 5 * time.Second
+
 // FieldMeta represents metadata for a single field/column
 type FieldMeta struct {
-	ID    string `json:"id"`
-	Title string `json:"title"`
-	Type  string `json:"type"`
+	ID           string                 `json:"id"`
+	Title        string                 `json:"title"`
+	Type         string                 `json:"type"`
+	DataType     string                 `json:"data_type,omitempty"` // database column type, e.g. varchar
+	Required     bool                   `json:"required,omitempty"`
+	PrimaryKey   bool                   `json:"primary_key,omitempty"`
+	Unique       bool                   `json:"unique,omitempty"`
+	DisplayValue bool                   `json:"display_value,omitempty"` // the table's display (primary value) column
+	DefaultValue interface{}            `json:"default_value,omitempty"`
+	Choices      []string               `json:"choices,omitempty"`    // allowed values of select fields
+	Options      map[string]interface{} `json:"options,omitempty"`    // v3 field options (related_table_id for links)
+	ColOptions   map[string]interface{} `json:"colOptions,omitempty"` // v2 column options (fk_related_model_id for links)
 }
 
 // TableMeta represents metadata for a single NocoDB table
@@ -25,6 +44,8 @@ type TableMeta struct {
 	TableName string      `json:"table_name"`
 	Columns   []FieldMeta `json:"columns,omitempty"`
 	Fields    []FieldMeta `json:"fields,omitempty"`
+	// DisplayFieldID is the display column of v3 table details
+	DisplayFieldID string `json:"display_field_id,omitempty"`
 }
 
 // TablesResponse represents the response from NocoDB meta API
@@ -38,12 +59,36 @@ type MetaCache struct {
 	tableByName       map[string]string            // lowercase friendly title -> table ID
 	fieldsByTable     map[string]map[string]string // table ID -> (lowercase field name -> field ID)
 	linkFieldsByTable map[string]map[string]string // table ID -> (lowercase link field name -> field ID)
+	tableNameByID     map[string]string            // table ID -> table title
+	fieldNameByID     map[string]map[string]string // table ID -> (field ID -> field title), incl. link fields
+	relations         map[string][]Relation        // table ID -> link fields and the tables they point at
 	metaBaseURL       string                       // e.g. http://100.103.198.65:8090/api/v2/
 	baseID            string                       // NocoDB base ID
 	token             string                       // NOCODB_TOKEN
+	tokenSource       TokenSource                  // overrides token when set (token vault)
+	failover          *Failover                    // redirects requests to a standby instance
 	httpClient        *http.Client
 	lastLoadedAt      time.Time
 	refreshInterval   time.Duration
+	aliases           *aliasMaps           // public names from proxy.yaml (nil if none)
+	lookupMu          sync.Mutex           // serializes on-demand lookups of unknown tables
+	misses            map[string]time.Time // lowercase name -> when a lookup last failed to find it
+	missTTL           time.Duration
+	detailConcurrency int           // parallel table detail requests during Refresh
+	detailTimeout     time.Duration // timeout of each table detail request
+	lastDuration      time.Duration // how long the last full Refresh took
+	store             MetadataStore // where the cache is persisted and shared (optional)
+	instanceID        string        // identifies this instance for refresh leadership
+	stale             bool          // loaded from a snapshot, not yet refreshed from NocoDB
+	schemaHash        string        // content hash of the cached mappings
+	refreshFailures   int           // consecutive failed refreshes
+	lastRefreshError  string
+	changeListeners   []func()
+	refreshListeners  []func()
+	schema            map[string]TableSchema // table ID -> titles and field types, for diffing
+	schemaDiffs       []SchemaDiff           // most recent last
+	events            *events.Bus
+	stats             resolutionStats // per-table hit/miss counters
 }
 
 // NewMetaCache creates a new MetaCache instance
@@ -52,27 +97,53 @@ func NewMetaCache(metaBaseURL, baseID, token string) *MetaCache {
 		tableByName:       make(map[string]string),
 		fieldsByTable:     make(map[string]map[string]string),
 		linkFieldsByTable: make(map[string]map[string]string),
+		tableNameByID:     make(map[string]string),
+		fieldNameByID:     make(map[string]map[string]string),
+		relations:         make(map[string][]Relation),
 		metaBaseURL:       strings.TrimRight(metaBaseURL, "/") + "/",
 		baseID:            baseID,
 		token:             token,
 		httpClient:        &http.Client{Timeout: 10 * time.Second},
 		refreshInterval:   10 * time.Minute,
+		misses:            make(map[string]time.Time),
+		missTTL:           time.Minute,
+		detailConcurrency: 8,
+		detailTimeout:     10 * time.Second,
+	}
+}
+
+// SetDetailFetching sets how many table detail requests Refresh runs in parallel
+// and the timeout of each request
+func (m *MetaCache) SetDetailFetching(concurrency int, timeout time.Duration) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	m.detailConcurrency = concurrency
+	if timeout > 0 {
+		m.detailTimeout = timeout
 	}
 }
 
 // fetchTableDetails fetches detailed metadata for a specific table including fields
 func (m *MetaCache) fetchTableDetails(tableID string) (*TableMeta, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.detailTimeout)
+	defer cancel()
+	return m.fetchTableDetailsContext(ctx, tableID)
+}
+
+// fetchTableDetailsContext fetches detailed metadata for a table, bounded by ctx
+func (m *MetaCache) fetchTableDetailsContext(ctx context.Context, tableID string) (*TableMeta, error) {
 	// Construct v3 API URL for table details
 	url := fmt.Sprintf("%sapi/v3/meta/bases/%s/tables/%s", strings.TrimSuffix(m.metaBaseURL, "api/v2/"), m.baseID, tableID)
 
 	// Create request
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", m.failover.Rewrite(url), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create table details request: %w", err)
 	}
 
 	// Add authentication header
-	req.Header.Set("xc-token", m.token)
+	req.Header.Set("xc-token", m.currentToken())
 
 	// Execute request
 	resp, err := m.httpClient.Do(req)
@@ -97,26 +168,52 @@ func (m *MetaCache) fetchTableDetails(tableID string) (*TableMeta, error) {
 	if err := json.Unmarshal(body, &tableMeta); err != nil {
 		return nil, fmt.Errorf("failed to parse table details JSON: %w", err)
 	}
+	tableMeta.markDisplayField()
 
 	return &tableMeta, nil
 }
 
-// Refresh fetches table metadata from NocoDB and updates the cache
+// Refresh fetches table metadata from NocoDB and updates the cache.
+// Failures leave the previous cache in place and are counted for backoff and status.
 func (m *MetaCache) Refresh() error {
+	err := m.refresh()
+
+	m.mu.Lock()
+	if err != nil {
+		m.refreshFailures++
+		m.lastRefreshError = err.Error()
+	} else {
+		m.refreshFailures = 0
+		m.lastRefreshError = ""
+	}
+	listeners := append([]func(){}, m.refreshListeners...)
+	m.mu.Unlock()
+
+	if err == nil {
+		for _, listener := range listeners {
+			listener()
+		}
+	}
+	return err
+}
+
+// refresh performs one metadata fetch and cache rebuild
+func (m *MetaCache) refresh() error {
 	log.Printf("[META] Fetching table metadata from NocoDB...")
+	started := time.Now()
 
 	// Build the metadata API URL
 	url := fmt.Sprintf("%smeta/bases/%s/tables", m.metaBaseURL, m.baseID)
 	log.Printf("[META] Metadata URL: %s", url)
 
 	// Create request
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequest("GET", m.failover.Rewrite(url), nil)
 	if err != nil {
 		return fmt.Errorf("failed to create metadata request: %w", err)
 	}
 
 	// Add authentication header
-	req.Header.Set("xc-token", m.token)
+	req.Header.Set("xc-token", m.currentToken())
 
 	// Execute request
 	resp, err := m.httpClient.Do(req)
@@ -151,11 +248,9 @@ func (m *MetaCache) Refresh() error {
 		// Map both lowercase title and table_name to ID
 		if table.Title != "" {
 			newMapping[strings.ToLower(table.Title)] = table.ID
-			log.Printf("[META] Mapped table '%s' -> '%s'", table.Title, table.ID)
 		}
 		if table.TableName != "" && table.TableName != table.Title {
 			newMapping[strings.ToLower(table.TableName)] = table.ID
-			log.Printf("[META] Mapped table '%s' -> '%s'", table.TableName, table.ID)
 		}
 
 		// Map fields for this table
@@ -164,35 +259,19 @@ func (m *MetaCache) Refresh() error {
 			for _, field := range table.Columns {
 				if field.Title != "" {
 					fieldMap[strings.ToLower(field.Title)] = field.ID
-					log.Printf("[META] Mapped field '%s.%s' -> '%s'", table.Title, field.Title, field.ID)
 				}
 			}
 			newFieldMappings[table.ID] = fieldMap
 		}
+	}
 
-		// Fetch detailed table metadata to get link fields
-		log.Printf("[META] Fetching field metadata for table '%s' (%s)...", table.Title, table.ID)
-		tableDetails, err := m.fetchTableDetails(table.ID)
-		if err != nil {
-			log.Printf("[META WARNING] Failed to fetch field details for table '%s': %v", table.Title, err)
-			continue
-		}
-
-		// Extract link fields from the detailed metadata
-		linkFieldMap := make(map[string]string)
-		for _, field := range tableDetails.Fields {
-			if field.Type == "Links" || field.Type == "LinkToAnotherRecord" {
-				if field.Title != "" {
-					linkFieldMap[strings.ToLower(field.Title)] = field.ID
-					log.Printf("[META] ✓ Found link field '%s.%s' (ID: %s, Type: %s)", table.Title, field.Title, field.ID, field.Type)
-				}
-			}
-		}
-
-		if len(linkFieldMap) > 0 {
-			newLinkFieldMappings[table.ID] = linkFieldMap
-			log.Printf("[META] Cached %d link field(s) for table '%s'", len(linkFieldMap), table.Title)
-		}
+	// Fetch detailed table metadata (link fields) in parallel; a failed table only loses its link fields
+	linkFields, details, failed := m.fetchLinkFields(tablesResp.List)
+	for tableID, linkFieldMap := range linkFields {
+		newLinkFieldMappings[tableID] = linkFieldMap
+	}
+	if failed > 0 {
+		log.Printf("[META WARNING] Field details unavailable for %d of %d table(s)", failed, len(tablesResp.List))
 	}
 
 	// Count total link fields
@@ -201,20 +280,185 @@ func (m *MetaCache) Refresh() error {
 		totalLinkFields += len(linkFields)
 	}
 
+	// Skip rebuilding the cache (and re-logging every mapping) when nothing changed
+	newSchema := buildSchema(tablesResp.List, details)
+	hash := schemaHash(newMapping, newFieldMappings, newLinkFieldMappings, newSchema)
+	m.mu.RLock()
+	previousHash := m.schemaHash
+	m.mu.RUnlock()
+	if hash == previousHash {
+		m.mu.Lock()
+		m.lastLoadedAt = time.Now()
+		m.lastDuration = time.Since(started)
+		m.stale = false
+		m.mu.Unlock()
+		// Re-save so instances sharing the store see that the schema was confirmed
+		if err := m.saveSnapshot(); err != nil {
+			log.Printf("[META WARNING] Failed to save metadata snapshot: %v", err)
+		}
+		log.Printf("[META] Schema unchanged (%d tables, %d link fields) in %v", len(tablesResp.List), totalLinkFields, time.Since(started).Round(time.Millisecond))
+		return nil
+	}
+	logMappings(tablesResp.List, newLinkFieldMappings)
+
 	// Update cache atomically
 	m.mu.Lock()
+	previousSchema := m.schema
+	m.schema = newSchema
+	m.schemaHash = hash
 	m.tableByName = newMapping
 	m.fieldsByTable = newFieldMappings
 	m.linkFieldsByTable = newLinkFieldMappings
+	m.tableNameByID, m.fieldNameByID = reverseMappings(newSchema)
+	m.relations = buildRelations(newSchema)
+	m.misses = make(map[string]time.Time)
 	m.lastLoadedAt = time.Now()
+	m.lastDuration = time.Since(started)
+	m.stale = false
 	m.mu.Unlock()
 
-	log.Printf("[META] ✅ Successfully loaded %d tables and %d link field mappings", len(tablesResp.List), totalLinkFields)
+	m.publishSnapshot()
+
+	log.Printf("[META] ✅ Successfully loaded %d tables and %d link field mappings in %v", len(tablesResp.List), totalLinkFields, time.Since(started).Round(time.Millisecond))
+
+	// Listeners are only told about changes after the initial load
+	if previousHash != "" {
+		m.schemaChanged(previousHash, hash, previousSchema, newSchema, true)
+	}
 	return nil
 }
 
-// Resolve looks up a table ID by its friendly name
+// schemaHash returns a content hash of the table, field and link field mappings
+func schemaHash(tables map[string]string, fields, linkFields map[string]map[string]string, schema map[string]TableSchema) string {
+	// encoding/json sorts map keys, so equal mappings always encode identically
+	data, _ := json.Marshal([]interface{}{tables, fields, linkFields, schema})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// logMappings logs every table, field and link field mapping of a changed schema
+func logMappings(tables []TableMeta, linkFields map[string]map[string]string) {
+	for _, table := range tables {
+		if table.Title != "" {
+			log.Printf("[META] Mapped table '%s' -> '%s'", table.Title, table.ID)
+		}
+		if table.TableName != "" && table.TableName != table.Title {
+			log.Printf("[META] Mapped table '%s' -> '%s'", table.TableName, table.ID)
+		}
+		for _, field := range table.Columns {
+			if field.Title != "" {
+				log.Printf("[META] Mapped field '%s.%s' -> '%s'", table.Title, field.Title, field.ID)
+			}
+		}
+		for title, fieldID := range linkFields[table.ID] {
+			log.Printf("[META] ✓ Found link field '%s.%s' (ID: %s)", table.Title, title, fieldID)
+		}
+	}
+}
+
+// OnSchemaChange registers a function called after a refresh finds a changed schema
+func (m *MetaCache) OnSchemaChange(listener func()) {
+	m.mu.Lock()
+	m.changeListeners = append(m.changeListeners, listener)
+	m.mu.Unlock()
+}
+
+// OnRefresh registers a function called after every successful refresh,
+// whether or not the schema changed
+func (m *MetaCache) OnRefresh(listener func()) {
+	m.mu.Lock()
+	m.refreshListeners = append(m.refreshListeners, listener)
+	m.mu.Unlock()
+}
+
+// listeners returns a copy of the registered schema change listeners
+func (m *MetaCache) listeners() []func() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]func(){}, m.changeListeners...)
+}
+
+// SchemaHash returns the content hash of the currently cached schema
+func (m *MetaCache) SchemaHash() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.schemaHash
+}
+
+// fetchLinkFields fetches table details with a bounded worker pool and returns the
+// link fields and all detailed fields per table ID, and the number of tables whose
+// details could not be fetched
+func (m *MetaCache) fetchLinkFields(tables []TableMeta) (map[string]map[string]string, map[string][]FieldMeta, int) {
+	var (
+		mu         sync.Mutex
+		wg         sync.WaitGroup
+		linkFields = make(map[string]map[string]string)
+		details    = make(map[string][]FieldMeta)
+		failed     int
+	)
+	jobs := make(chan TableMeta)
+
+	workers := m.detailConcurrency
+	if workers > len(tables) {
+		workers = len(tables)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for table := range jobs {
+				tableDetails, err := m.fetchTableDetails(table.ID)
+				if err != nil {
+					log.Printf("[META WARNING] Failed to fetch field details for table '%s': %v", table.Title, err)
+					mu.Lock()
+					failed++
+					mu.Unlock()
+					continue
+				}
+
+				// Extract link fields from the detailed metadata
+				linkFieldMap := make(map[string]string)
+				for _, field := range tableDetails.Fields {
+					if isLinkType(field.Type) {
+						if field.Title != "" {
+							linkFieldMap[strings.ToLower(field.Title)] = field.ID
+						}
+					}
+				}
+
+				mu.Lock()
+				details[table.ID] = tableDetails.Fields
+				if len(linkFieldMap) > 0 {
+					linkFields[table.ID] = linkFieldMap
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, table := range tables {
+		jobs <- table
+	}
+	close(jobs)
+	wg.Wait()
+	return linkFields, details, failed
+}
+
+// Resolve looks up a table ID by its friendly name. Tables missing from the cache
+// (e.g. created since the last refresh) are looked up in NocoDB before giving up.
 func (m *MetaCache) Resolve(name string) (string, bool) {
+	requested := name
+	name = m.tableTitle(name)
+	id, ok := m.cachedTable(name)
+	if !ok {
+		id, ok = m.lookupTable(name)
+	}
+	m.recordTable(requested, ok)
+	return id, ok
+}
+
+// cachedTable looks up a table ID in the cache only
+func (m *MetaCache) cachedTable(name string) (string, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -245,10 +489,57 @@ func (m *MetaCache) ResolveField(tableID, fieldName string) (string, bool) {
 		return "", false
 	}
 
-	fieldID, ok := fieldMap[strings.ToLower(fieldName)]
+	fieldID, ok := fieldMap[strings.ToLower(m.fieldTitle(tableID, fieldName))]
+	m.recordField(tableID, fieldName, ok)
 	return fieldID, ok
 }
 
+// TableTitles returns the title of every cached table, keyed by table ID
+func (m *MetaCache) TableTitles() map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	titles := make(map[string]string, len(m.tableNameByID))
+	for tableID, title := range m.tableNameByID {
+		titles[tableID] = title
+	}
+	return titles
+}
+
+// ResolveTableName returns the title of a table by its ID
+func (m *MetaCache) ResolveTableName(tableID string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	name, ok := m.tableNameByID[tableID]
+	return name, ok
+}
+
+// ResolveFieldName returns the title of a field (or link field) by its ID within a table
+func (m *MetaCache) ResolveFieldName(tableID, fieldID string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	fields, ok := m.fieldNameByID[tableID]
+	if !ok {
+		return "", false
+	}
+	name, ok := fields[fieldID]
+	return name, ok
+}
+
+// reverseMappings builds the ID -> name maps from the schema
+func reverseMappings(schema map[string]TableSchema) (map[string]string, map[string]map[string]string) {
+	tableNames := make(map[string]string, len(schema))
+	fieldNames := make(map[string]map[string]string, len(schema))
+	for tableID, table := range schema {
+		tableNames[tableID] = table.Title
+		fields := make(map[string]string, len(table.Fields))
+		for fieldID, field := range table.Fields {
+			fields[fieldID] = field.Title
+		}
+		fieldNames[tableID] = fields
+	}
+	return tableNames, fieldNames
+}
+
 // ResolveLinkField looks up a link field ID by its name within a specific table
 func (m *MetaCache) ResolveLinkField(tableID, fieldName string) (string, bool) {
 	m.mu.RLock()
@@ -265,7 +556,7 @@ func (m *MetaCache) ResolveLinkField(tableID, fieldName string) (string, bool) {
 		return "", false
 	}
 
-	fieldID, ok := linkFieldMap[strings.ToLower(fieldName)]
+	fieldID, ok := linkFieldMap[strings.ToLower(m.fieldTitle(tableID, fieldName))]
 	if !ok {
 		log.Printf("[META DEBUG] Link field '%s' not found in table %s", fieldName, tableID)
 	}
@@ -294,30 +585,124 @@ func (m *MetaCache) GetLastRefreshTime() time.Time {
 // LoadInitial performs an initial synchronous metadata fetch
 func (m *MetaCache) LoadInitial() error {
 	log.Printf("[META] Performing initial synchronous metadata load...")
+	if m.store != nil && !m.isLeader() {
+		// Another instance refreshes the shared cache; start from its snapshot
+		err := m.loadSnapshot(false)
+		if err == nil {
+			log.Printf("[META] Initial metadata load complete from shared store: %d tables cached", m.GetTableCount())
+			return nil
+		}
+		log.Printf("[META WARNING] Shared metadata unavailable (%v), loading from NocoDB", err)
+	}
 	if err := m.Refresh(); err != nil {
-		return fmt.Errorf("initial metadata load failed: %w", err)
+		// Fall back to the last snapshot so a NocoDB blip during deploy is not fatal
+		if snapErr := m.loadSnapshot(true); snapErr != nil {
+			if m.store != nil {
+				log.Printf("[META WARNING] Snapshot fallback unavailable: %v", snapErr)
+			}
+			return fmt.Errorf("initial metadata load failed: %w", err)
+		}
+		log.Printf("[META WARNING] Initial metadata load failed (%v); using snapshot until NocoDB is reachable", err)
+		return nil
 	}
 	log.Printf("[META] Initial metadata load complete: %d tables cached", m.GetTableCount())
 	return nil
 }
 
-// StartAutoRefresh starts a background goroutine that periodically refreshes the cache
-func (m *MetaCache) StartAutoRefresh() {
-	go func() {
-		log.Printf("[META] Starting auto-refresh goroutine (interval: %v)", m.refreshInterval)
+// SetRefreshInterval sets how often the cache is refreshed from NocoDB
+func (m *MetaCache) SetRefreshInterval(interval time.Duration) {
+	if interval > 0 {
+		m.refreshInterval = interval
+	}
+}
+
+// ScheduleRefresh registers the periodic refresh of the cache as a scheduler job
+// and starts watching the shared store for invalidations
+func (m *MetaCache) ScheduleRefresh(ctx context.Context, jobs *scheduler.Scheduler, name string) error {
+	// After failures keep serving the old cache and retry with backoff
+	err := jobs.Add(scheduler.Job{
+		Name:     name,
+		Schedule: scheduler.Dynamic(fmt.Sprintf("every %v (backoff after failures)", m.refreshInterval), m.nextRefreshDelay),
+		Run:      func(context.Context) error { return m.autoRefresh() },
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("[META] Scheduled auto-refresh '%s' (interval: %v)", name, m.refreshInterval)
+	m.watchStore(ctx)
+	return nil
+}
+
+// autoRefresh performs one scheduled refresh
+func (m *MetaCache) autoRefresh() error {
+	// Followers reload the leader's snapshot instead of querying NocoDB
+	if !m.isLeader() {
+		if err := m.loadSnapshot(false); err != nil {
+			log.Printf("[META WARNING] Failed to reload shared metadata: %v", err)
+			return err
+		}
+		return nil
+	}
 
-		// Periodic refresh
-		ticker := time.NewTicker(m.refreshInterval)
-		defer ticker.Stop()
+	// Don't add load to an upstream the health checks report as down
+	if !m.failover.Available() {
+		log.Printf("[META] NocoDB reported down by health checks, skipping auto-refresh")
+		return nil
+	}
 
-		for range ticker.C {
-			log.Printf("[META] Auto-refreshing metadata cache...")
-			if err := m.Refresh(); err != nil {
-				log.Printf("[META ERROR] Auto-refresh failed: %v", err)
-				// Don't crash - keep the old cache
-			}
+	log.Printf("[META] Auto-refreshing metadata cache...")
+	if err := m.Refresh(); err != nil {
+		log.Printf("[META ERROR] Auto-refresh failed: %v", err)
+		// Don't crash - keep the old cache
+		return err
+	}
+	return nil
+}
+
+// nextRefreshDelay returns the wait before the next auto-refresh: the regular
+// interval when healthy, exponential backoff with jitter after failures
+func (m *MetaCache) nextRefreshDelay() time.Duration {
+	m.mu.RLock()
+	failures, stale := m.refreshFailures, m.stale
+	m.mu.RUnlock()
+
+	if failures == 0 {
+		if stale {
+			return staleRetryInterval
 		}
-	}()
+		return m.refreshInterval
+	}
+
+	delay := refreshBackoffBase
+	for i := 1; i < failures && delay < m.refreshInterval; i++ {
+		delay *= 2
+	}
+	if delay > m.refreshInterval {
+		delay = m.refreshInterval
+	}
+	// Up to 20% jitter so several gateways do not retry in lockstep
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
+
+// RefreshHealth describes recent metadata refresh failures
+type RefreshHealth struct {
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	LastError           string `json:"last_error,omitempty"`
+	StalenessSeconds    int    `json:"staleness_seconds"` // age of the cached metadata
+}
+
+// GetRefreshHealth returns the refresh failure count and the age of the cache
+func (m *MetaCache) GetRefreshHealth() RefreshHealth {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	health := RefreshHealth{
+		ConsecutiveFailures: m.refreshFailures,
+		LastError:           m.lastRefreshError,
+	}
+	if !m.lastLoadedAt.IsZero() {
+		health.StalenessSeconds = int(time.Since(m.lastLoadedAt).Seconds())
+	}
+	return health
 }
 
 // IsReady returns true if the cache has been loaded at least once
@@ -334,6 +719,26 @@ func (m *MetaCache) GetTableCount() int {
 	return len(m.tableByName)
 }
 
+// GetLastRefreshDuration returns how long the last full refresh took
+func (m *MetaCache) GetLastRefreshDuration() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastDuration
+}
+
+// GetFieldCount returns the number of cached field and link field mappings
+func (m *MetaCache) GetFieldCount() (fields, linkFields int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, fieldMap := range m.fieldsByTable {
+		fields += len(fieldMap)
+	}
+	for _, linkFieldMap := range m.linkFieldsByTable {
+		linkFields += len(linkFieldMap)
+	}
+	return fields, linkFields
+}
+
 // GetLinkFieldTableCount returns the number of tables with cached link field mappings
 func (m *MetaCache) GetLinkFieldTableCount() int {
 	m.mu.RLock()
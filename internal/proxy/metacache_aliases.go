@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"log"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+// aliasMaps holds the alias configuration indexed for lookups in both directions.
+// All keys are lowercase; field maps are keyed by the NocoDB table title.
+type aliasMaps struct {
+	tableTitles  map[string]string            // public name -> table title
+	tablePublic  map[string]string            // table title -> public name
+	fieldTitles  map[string]map[string]string // table title -> public field name -> field title
+	fieldsPublic map[string]map[string]string // table title -> field title -> public field name
+}
+
+// SetAliases exposes tables and fields under the public names from proxy.yaml
+func (m *MetaCache) SetAliases(aliases config.AliasConfig) {
+	if len(aliases.Tables) == 0 && len(aliases.Fields) == 0 {
+		return
+	}
+
+	maps := &aliasMaps{
+		tableTitles:  make(map[string]string, len(aliases.Tables)),
+		tablePublic:  make(map[string]string, len(aliases.Tables)),
+		fieldTitles:  make(map[string]map[string]string, len(aliases.Fields)),
+		fieldsPublic: make(map[string]map[string]string, len(aliases.Fields)),
+	}
+	for alias, title := range aliases.Tables {
+		maps.tableTitles[strings.ToLower(alias)] = title
+		maps.tablePublic[strings.ToLower(title)] = alias
+	}
+	for table, fields := range aliases.Fields {
+		// Field aliases may be keyed by the table's public name or its title
+		title := table
+		if aliased, ok := maps.tableTitles[strings.ToLower(table)]; ok {
+			title = aliased
+		}
+		key := strings.ToLower(title)
+		if maps.fieldTitles[key] == nil {
+			maps.fieldTitles[key] = make(map[string]string)
+			maps.fieldsPublic[key] = make(map[string]string)
+		}
+		for alias, fieldTitle := range fields {
+			maps.fieldTitles[key][strings.ToLower(alias)] = fieldTitle
+			maps.fieldsPublic[key][strings.ToLower(fieldTitle)] = alias
+		}
+	}
+
+	m.mu.Lock()
+	m.aliases = maps
+	m.mu.Unlock()
+	log.Printf("[META] Loaded %d table aliases and field aliases for %d tables", len(aliases.Tables), len(aliases.Fields))
+}
+
+// tableTitle translates a public table name to its NocoDB title
+func (m *MetaCache) tableTitle(name string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.aliases == nil {
+		return name
+	}
+	if title, ok := m.aliases.tableTitles[strings.ToLower(name)]; ok {
+		return title
+	}
+	return name
+}
+
+// fieldTitle translates a public field name to its NocoDB title. Callers must hold mu.
+func (m *MetaCache) fieldTitle(tableID, name string) string {
+	if m.aliases == nil {
+		return name
+	}
+	fields, ok := m.aliases.fieldTitles[strings.ToLower(m.tableNameByID[tableID])]
+	if !ok {
+		return name
+	}
+	if title, ok := fields[strings.ToLower(name)]; ok {
+		return title
+	}
+	return name
+}
+
+// PublicTableName returns the name a table is exposed under: its alias if one is
+// configured, otherwise its NocoDB title
+func (m *MetaCache) PublicTableName(tableID string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	title, ok := m.tableNameByID[tableID]
+	if !ok {
+		return "", false
+	}
+	if m.aliases != nil {
+		if alias, ok := m.aliases.tablePublic[strings.ToLower(title)]; ok {
+			return alias, true
+		}
+	}
+	return title, true
+}
+
+// PublicFieldName returns the name a field is exposed under: its alias if one is
+// configured, otherwise its NocoDB title
+func (m *MetaCache) PublicFieldName(tableID, fieldID string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	title, ok := m.fieldNameByID[tableID][fieldID]
+	if !ok {
+		return "", false
+	}
+	if m.aliases != nil {
+		if alias, ok := m.aliases.fieldsPublic[strings.ToLower(m.tableNameByID[tableID])][strings.ToLower(title)]; ok {
+			return alias, true
+		}
+	}
+	return title, true
+}
@@ -0,0 +1,123 @@
+package proxy
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// UnmarshalJSON reads field metadata from either the v3 field shape
+// (default_value, options.choices) or the v2 column shape (dt, rqd, cdf, pk, pv,
+// colOptions.options), as well as the gateway's own snapshot format
+func (f *FieldMeta) UnmarshalJSON(data []byte) error {
+	type fieldMeta FieldMeta
+	var field fieldMeta
+	if err := json.Unmarshal(data, &field); err != nil {
+		return err
+	}
+
+	var v2 struct {
+		DataType   string      `json:"dt"`
+		Required   interface{} `json:"rqd"`
+		Default    interface{} `json:"cdf"`
+		PrimaryKey interface{} `json:"pk"`
+		Unique     interface{} `json:"un"`
+		Display    interface{} `json:"pv"`
+	}
+	if err := json.Unmarshal(data, &v2); err != nil {
+		return err
+	}
+	if field.DataType == "" {
+		field.DataType = v2.DataType
+	}
+	field.Required = field.Required || truthy(v2.Required)
+	field.PrimaryKey = field.PrimaryKey || truthy(v2.PrimaryKey)
+	field.Unique = field.Unique || truthy(v2.Unique)
+	field.DisplayValue = field.DisplayValue || truthy(v2.Display)
+	if field.DefaultValue == nil {
+		field.DefaultValue = v2.Default
+	}
+
+	// v3 reports required-ness and validation rules inside options
+	if required, ok := field.Options["required"]; ok {
+		field.Required = field.Required || truthy(required)
+	}
+	if len(field.Choices) == 0 {
+		field.Choices = choiceTitles(field.Options["choices"])
+	}
+	if len(field.Choices) == 0 {
+		field.Choices = choiceTitles(field.ColOptions["options"])
+	}
+
+	*f = FieldMeta(field)
+	return nil
+}
+
+// truthy interprets NocoDB's boolean flags, which older versions send as 0/1 or strings
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v == "1" || strings.EqualFold(v, "true")
+	default:
+		return false
+	}
+}
+
+// choiceTitles extracts the titles of select options
+func choiceTitles(value interface{}) []string {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	titles := make([]string, 0, len(list))
+	for _, item := range list {
+		switch choice := item.(type) {
+		case string:
+			titles = append(titles, choice)
+		case map[string]interface{}:
+			if title, ok := choice["title"].(string); ok {
+				titles = append(titles, title)
+			}
+		}
+	}
+	return titles
+}
+
+// FieldMetadata returns the cached metadata of a field, looked up by name or ID
+func (m *MetaCache) FieldMetadata(tableID, field string) (FieldMeta, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	table, ok := m.schema[tableID]
+	if !ok {
+		return FieldMeta{}, false
+	}
+	if meta, ok := table.Fields[field]; ok {
+		return meta, true
+	}
+	for _, meta := range table.Fields {
+		if strings.EqualFold(meta.Title, field) {
+			return meta, true
+		}
+	}
+	return FieldMeta{}, false
+}
+
+// TableFields returns the cached metadata of every field of a table
+func (m *MetaCache) TableFields(tableID string) []FieldMeta {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	table, ok := m.schema[tableID]
+	if !ok {
+		return nil
+	}
+	fields := make([]FieldMeta, 0, len(table.Fields))
+	for _, field := range table.Fields {
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Title < fields[j].Title })
+	return fields
+}
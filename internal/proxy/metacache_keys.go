@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"sort"
+	"strings"
+)
+
+// markDisplayField flags the field named by a v3 table's display_field_id
+func (t *TableMeta) markDisplayField() {
+	if t.DisplayFieldID == "" {
+		return
+	}
+	for i := range t.Fields {
+		if t.Fields[i].ID == t.DisplayFieldID {
+			t.Fields[i].DisplayValue = true
+		}
+	}
+}
+
+// sortedFields returns a table's fields ordered by title, so detection is deterministic
+func sortedFields(table TableSchema) []FieldMeta {
+	fields := make([]FieldMeta, 0, len(table.Fields))
+	for _, field := range table.Fields {
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Title < fields[j].Title })
+	return fields
+}
+
+// primaryKey finds the column that identifies a row: the field flagged as primary
+// key, or else the field of NocoDB's ID type
+func primaryKey(table TableSchema) (FieldMeta, bool) {
+	fields := sortedFields(table)
+	for _, field := range fields {
+		if field.PrimaryKey {
+			return field, true
+		}
+	}
+	for _, field := range fields {
+		if field.Type == "ID" {
+			return field, true
+		}
+	}
+	return FieldMeta{}, false
+}
+
+// displayField finds the column NocoDB shows as a row's label: the field flagged as
+// display value, or else NocoDB's default "Title" column
+func displayField(table TableSchema) (FieldMeta, bool) {
+	fields := sortedFields(table)
+	for _, field := range fields {
+		if field.DisplayValue {
+			return field, true
+		}
+	}
+	for _, field := range fields {
+		if strings.EqualFold(field.Title, "Title") {
+			return field, true
+		}
+	}
+	return FieldMeta{}, false
+}
+
+// PrimaryKey returns the primary key column of a table
+func (m *MetaCache) PrimaryKey(tableID string) (FieldMeta, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	table, ok := m.schema[tableID]
+	if !ok {
+		return FieldMeta{}, false
+	}
+	return primaryKey(table)
+}
+
+// DisplayField returns the display value column of a table
+func (m *MetaCache) DisplayField(tableID string) (FieldMeta, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	table, ok := m.schema[tableID]
+	if !ok {
+		return FieldMeta{}, false
+	}
+	return displayField(table)
+}
@@ -0,0 +1,152 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// lookupTable fetches the table list from NocoDB to find a table missing from the
+// cache, and adds it (with its fields and link fields) if found. Names that are
+// still unknown are remembered for missTTL so repeated requests do not hit NocoDB.
+func (m *MetaCache) lookupTable(name string) (string, bool) {
+	key := strings.ToLower(name)
+	if key == "" {
+		return "", false
+	}
+
+	m.lookupMu.Lock()
+	defer m.lookupMu.Unlock()
+
+	// Another request may have found the table while we waited
+	if id, ok := m.cachedTable(name); ok {
+		return id, true
+	}
+	m.mu.RLock()
+	missedAt, missed := m.misses[key]
+	m.mu.RUnlock()
+	if missed && time.Since(missedAt) < m.missTTL {
+		return "", false
+	}
+
+	log.Printf("[META] Table '%s' not in cache, looking it up in NocoDB...", name)
+	tableID, err := m.loadTable(key)
+	if err != nil {
+		log.Printf("[META WARNING] Lookup of table '%s' failed: %v", name, err)
+		m.mu.Lock()
+		m.misses[key] = time.Now()
+		m.mu.Unlock()
+		return "", false
+	}
+	log.Printf("[META] ✓ Found new table '%s' -> '%s'", name, tableID)
+	m.publishSnapshot()
+	return tableID, true
+}
+
+// RefreshTable reloads the metadata of a single table
+func (m *MetaCache) RefreshTable(name string) (string, error) {
+	m.lookupMu.Lock()
+	tableID, err := m.loadTable(strings.ToLower(name))
+	m.lookupMu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	m.publishSnapshot()
+	return tableID, nil
+}
+
+// loadTable fetches one table (by lowercase name) with its fields and link fields
+// and stores it in the cache. Callers must hold lookupMu.
+func (m *MetaCache) loadTable(key string) (string, error) {
+	table, err := m.findTable(key)
+	if err != nil {
+		return "", err
+	}
+	if table == nil {
+		return "", fmt.Errorf("table '%s' does not exist in NocoDB", key)
+	}
+
+	fieldMap := make(map[string]string)
+	for _, field := range table.Columns {
+		if field.Title != "" {
+			fieldMap[strings.ToLower(field.Title)] = field.ID
+		}
+	}
+	linkFieldMap := make(map[string]string)
+	var detailFields []FieldMeta
+	if details, err := m.fetchTableDetails(table.ID); err != nil {
+		log.Printf("[META WARNING] Failed to fetch field details for table '%s': %v", table.Title, err)
+	} else {
+		detailFields = details.Fields
+		for _, field := range details.Fields {
+			if isLinkType(field.Type) && field.Title != "" {
+				linkFieldMap[strings.ToLower(field.Title)] = field.ID
+			}
+		}
+	}
+
+	m.mu.Lock()
+	if table.Title != "" {
+		m.tableByName[strings.ToLower(table.Title)] = table.ID
+	}
+	if table.TableName != "" {
+		m.tableByName[strings.ToLower(table.TableName)] = table.ID
+	}
+	m.fieldsByTable[table.ID] = fieldMap
+	m.linkFieldsByTable[table.ID] = linkFieldMap
+	tableSchema := buildSchema([]TableMeta{*table}, map[string][]FieldMeta{table.ID: detailFields})[table.ID]
+	if m.schema != nil {
+		m.schema[table.ID] = tableSchema
+	}
+	m.tableNameByID[table.ID] = tableSchema.Title
+	fieldNames := make(map[string]string, len(tableSchema.Fields))
+	for fieldID, field := range tableSchema.Fields {
+		fieldNames[fieldID] = field.Title
+	}
+	m.fieldNameByID[table.ID] = fieldNames
+	m.relations[table.ID] = tableRelations(tableSchema)
+	m.schemaHash = schemaHash(m.tableByName, m.fieldsByTable, m.linkFieldsByTable, m.schema)
+	delete(m.misses, key)
+	m.mu.Unlock()
+
+	return table.ID, nil
+}
+
+// findTable fetches the table list and returns the table matching a lowercase name (nil if none)
+func (m *MetaCache) findTable(key string) (*TableMeta, error) {
+	url := fmt.Sprintf("%smeta/bases/%s/tables", m.metaBaseURL, m.baseID)
+	req, err := http.NewRequest("GET", m.failover.Rewrite(url), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metadata request: %w", err)
+	}
+	req.Header.Set("xc-token", m.currentToken())
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch metadata: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metadata API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var tablesResp TablesResponse
+	if err := json.Unmarshal(body, &tablesResp); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata JSON: %w", err)
+	}
+	for i, table := range tablesResp.List {
+		if strings.ToLower(table.Title) == key || strings.ToLower(table.TableName) == key {
+			return &tablesResp.List[i], nil
+		}
+	}
+	return nil, nil
+}
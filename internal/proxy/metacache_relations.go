@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"sort"
+	"strings"
+)
+
+// Relation describes a link field and the table it points at
+type Relation struct {
+	FieldID       string `json:"field_id"`
+	Field         string `json:"field"`
+	TargetTableID string `json:"target_table_id"`
+	TargetTable   string `json:"target_table,omitempty"`
+	Type          string `json:"type,omitempty"` // hm, bt, mm or oo
+}
+
+// isLinkType reports whether a field type is a link to another table
+func isLinkType(fieldType string) bool {
+	return fieldType == "Links" || fieldType == "LinkToAnotherRecord"
+}
+
+// linkTarget reads the target table ID and relation type from a link field's options
+func linkTarget(field FieldMeta) (string, string) {
+	if id, ok := field.Options["related_table_id"].(string); ok && id != "" {
+		relationType, _ := field.Options["relation_type"].(string)
+		return id, relationType
+	}
+	if id, ok := field.ColOptions["fk_related_model_id"].(string); ok && id != "" {
+		relationType, _ := field.ColOptions["type"].(string)
+		return id, relationType
+	}
+	return "", ""
+}
+
+// tableRelations collects the link fields of a table that have a known target
+func tableRelations(table TableSchema) []Relation {
+	var relations []Relation
+	for fieldID, field := range table.Fields {
+		if !isLinkType(field.Type) {
+			continue
+		}
+		targetID, relationType := linkTarget(field)
+		if targetID == "" {
+			continue
+		}
+		relations = append(relations, Relation{
+			FieldID:       fieldID,
+			Field:         field.Title,
+			TargetTableID: targetID,
+			Type:          relationType,
+		})
+	}
+	sort.Slice(relations, func(i, j int) bool { return relations[i].Field < relations[j].Field })
+	return relations
+}
+
+// buildRelations builds the relation graph of every table in the schema
+func buildRelations(schema map[string]TableSchema) map[string][]Relation {
+	relations := make(map[string][]Relation, len(schema))
+	for tableID, table := range schema {
+		if tableRelations := tableRelations(table); len(tableRelations) > 0 {
+			relations[tableID] = tableRelations
+		}
+	}
+	return relations
+}
+
+// withTargetTitles fills in target table titles. Callers must hold mu.
+func (m *MetaCache) withTargetTitles(relations []Relation) []Relation {
+	result := make([]Relation, len(relations))
+	for i, relation := range relations {
+		relation.TargetTable = m.tableNameByID[relation.TargetTableID]
+		result[i] = relation
+	}
+	return result
+}
+
+// Relations returns the link fields of a table and the tables they point at
+func (m *MetaCache) Relations(tableID string) []Relation {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.withTargetTitles(m.relations[tableID])
+}
+
+// AllRelations returns the relation graph keyed by source table ID
+func (m *MetaCache) AllRelations() map[string][]Relation {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	graph := make(map[string][]Relation, len(m.relations))
+	for tableID, relations := range m.relations {
+		graph[tableID] = m.withTargetTitles(relations)
+	}
+	return graph
+}
+
+// ResolveLinkTarget returns the relation of a link field, looked up by field name or ID
+func (m *MetaCache) ResolveLinkTarget(tableID, field string) (Relation, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, relation := range m.relations[tableID] {
+		if relation.FieldID == field || strings.EqualFold(relation.Field, field) {
+			return m.withTargetTitles([]Relation{relation})[0], true
+		}
+	}
+	return Relation{}, false
+}
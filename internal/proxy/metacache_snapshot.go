@@ -0,0 +1,191 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// staleRetryInterval is how often a cache loaded from a snapshot retries NocoDB
+const staleRetryInterval = 30 * time.Second
+
+// FileStore keeps the MetaCache snapshot in a local file. It serves a single
+// gateway instance, which always refreshes from NocoDB itself.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore creates a store persisting snapshots to path
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load reads the snapshot file (nil if it does not exist yet)
+func (s *FileStore) Load(ctx context.Context) ([]byte, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	return data, nil
+}
+
+// Save replaces the snapshot file
+func (s *FileStore) Save(ctx context.Context, data []byte) error {
+	// Write to a temporary file first so a crash never leaves a truncated snapshot
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".metacache-*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+	return nil
+}
+
+// TryLead always succeeds: a file is never shared between instances
+func (s *FileStore) TryLead(ctx context.Context, instanceID string, ttl time.Duration) (bool, error) {
+	return true, nil
+}
+
+// Invalidate is a no-op for a local file
+func (s *FileStore) Invalidate(ctx context.Context, hash string) error {
+	return nil
+}
+
+// Watch is a no-op for a local file
+func (s *FileStore) Watch(ctx context.Context, fn func(hash string)) error {
+	return nil
+}
+
+// SetSnapshotPath enables persisting the cache to a local file after each successful
+// refresh. LoadInitial falls back to the snapshot when NocoDB is unreachable.
+func (m *MetaCache) SetSnapshotPath(path string) {
+	if path == "" {
+		return
+	}
+	m.SetStore(NewFileStore(path))
+}
+
+// IsStale reports whether the cache was loaded from a snapshot and has not been
+// refreshed from NocoDB since
+func (m *MetaCache) IsStale() bool {
+	if m == nil {
+		return false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.stale
+}
+
+// saveSnapshot writes the current cache to the store
+func (m *MetaCache) saveSnapshot() error {
+	if m.store == nil {
+		return nil
+	}
+
+	m.mu.RLock()
+	data, err := json.Marshal(MetadataSnapshot{
+		BaseID:            m.baseID,
+		SavedAt:           m.lastLoadedAt,
+		Hash:              m.schemaHash,
+		TableByName:       m.tableByName,
+		FieldsByTable:     m.fieldsByTable,
+		LinkFieldsByTable: m.linkFieldsByTable,
+		Schema:            m.schema,
+	})
+	m.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	return m.store.Save(ctx, data)
+}
+
+// loadSnapshot fills the cache from the store. A snapshot loaded because NocoDB
+// is unreachable is marked stale; one shared by the refresh leader is not.
+func (m *MetaCache) loadSnapshot(stale bool) error {
+	if m.store == nil {
+		return fmt.Errorf("no snapshot configured")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	data, err := m.store.Load(ctx)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return fmt.Errorf("no snapshot saved yet")
+	}
+	var snapshot MetadataSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to parse snapshot: %w", err)
+	}
+	if snapshot.BaseID != m.baseID {
+		return fmt.Errorf("snapshot is for base '%s', not '%s'", snapshot.BaseID, m.baseID)
+	}
+	if snapshot.TableByName == nil {
+		return fmt.Errorf("snapshot contains no tables")
+	}
+	if snapshot.FieldsByTable == nil {
+		snapshot.FieldsByTable = make(map[string]map[string]string)
+	}
+	if snapshot.LinkFieldsByTable == nil {
+		snapshot.LinkFieldsByTable = make(map[string]map[string]string)
+	}
+	if snapshot.Hash == "" {
+		snapshot.Hash = schemaHash(snapshot.TableByName, snapshot.FieldsByTable, snapshot.LinkFieldsByTable, snapshot.Schema)
+	}
+
+	m.mu.Lock()
+	previousHash, previousSchema := m.schemaHash, m.schema
+	if snapshot.Hash == previousHash {
+		// Same schema; only note that the leader has confirmed it recently
+		m.lastLoadedAt = snapshot.SavedAt
+		m.stale = stale
+		m.mu.Unlock()
+		return nil
+	}
+	m.tableByName = snapshot.TableByName
+	m.fieldsByTable = snapshot.FieldsByTable
+	m.linkFieldsByTable = snapshot.LinkFieldsByTable
+	m.lastLoadedAt = snapshot.SavedAt
+	m.schema = snapshot.Schema
+	m.tableNameByID, m.fieldNameByID = reverseMappings(snapshot.Schema)
+	m.relations = buildRelations(snapshot.Schema)
+	m.schemaHash = snapshot.Hash
+	m.misses = make(map[string]time.Time)
+	m.stale = stale
+	m.mu.Unlock()
+
+	if stale {
+		log.Printf("[META WARNING] Serving metadata from snapshot saved %v ago (%d tables); it may be stale",
+			time.Since(snapshot.SavedAt).Round(time.Second), len(snapshot.TableByName))
+		return nil
+	}
+	log.Printf("[META] Loaded shared metadata (%d tables, hash %s)", len(snapshot.TableByName), shortHash(snapshot.Hash))
+	if previousHash != "" {
+		// The leader already published the change; only keep the diff and notify local listeners
+		m.schemaChanged(previousHash, snapshot.Hash, previousSchema, snapshot.Schema, false)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxResolutionStats bounds the number of names counters are kept for, so
+// requests for arbitrary unknown names cannot grow the map without limit
+const maxResolutionStats = 1000
+
+// ResolutionStats counts how lookups of one table name were resolved
+type ResolutionStats struct {
+	Hits        int64     `json:"hits"`
+	Misses      int64     `json:"misses"`
+	Fallbacks   int64     `json:"fallbacks"` // requests forwarded with the raw name after a miss
+	FieldHits   int64     `json:"field_hits"`
+	FieldMisses int64     `json:"field_misses"`
+	LastMiss    time.Time `json:"last_miss,omitempty"`
+	LastMissed  string    `json:"last_missed_field,omitempty"` // last field name that did not resolve
+}
+
+// resolutionStats holds the counters of a MetaCache, keyed by lowercase table name
+type resolutionStats struct {
+	mu     sync.Mutex
+	byName map[string]*ResolutionStats
+}
+
+// entry returns the counters of a name, or nil once the map is full. Callers must hold mu.
+func (s *resolutionStats) entry(name string) *ResolutionStats {
+	key := strings.ToLower(name)
+	if s.byName == nil {
+		s.byName = make(map[string]*ResolutionStats)
+	}
+	stats, ok := s.byName[key]
+	if !ok {
+		if len(s.byName) >= maxResolutionStats {
+			return nil
+		}
+		stats = &ResolutionStats{}
+		s.byName[key] = stats
+	}
+	return stats
+}
+
+// recordTable counts a table lookup
+func (m *MetaCache) recordTable(name string, hit bool) {
+	m.stats.mu.Lock()
+	defer m.stats.mu.Unlock()
+	stats := m.stats.entry(name)
+	if stats == nil {
+		return
+	}
+	if hit {
+		stats.Hits++
+		return
+	}
+	stats.Misses++
+	stats.LastMiss = time.Now()
+}
+
+// recordField counts a field or link field lookup. Callers hold mu, so the
+// table title is read directly.
+func (m *MetaCache) recordField(tableID, fieldName string, hit bool) {
+	name := m.tableNameByID[tableID]
+	if name == "" {
+		name = tableID
+	}
+	m.stats.mu.Lock()
+	defer m.stats.mu.Unlock()
+	stats := m.stats.entry(name)
+	if stats == nil {
+		return
+	}
+	if hit {
+		stats.FieldHits++
+		return
+	}
+	stats.FieldMisses++
+	stats.LastMiss = time.Now()
+	stats.LastMissed = fieldName
+}
+
+// RecordFallback counts a request forwarded upstream with an unresolved table name
+func (m *MetaCache) RecordFallback(name string) {
+	m.stats.mu.Lock()
+	defer m.stats.mu.Unlock()
+	if stats := m.stats.entry(name); stats != nil {
+		stats.Fallbacks++
+	}
+}
+
+// ResolutionStats returns a copy of the per-table resolution counters
+func (m *MetaCache) ResolutionStats() map[string]ResolutionStats {
+	m.stats.mu.Lock()
+	defer m.stats.mu.Unlock()
+	result := make(map[string]ResolutionStats, len(m.stats.byName))
+	for name, stats := range m.stats.byName {
+		result[name] = *stats
+	}
+	return result
+}
+
+// Mappings returns a copy of the cached name -> ID mappings
+func (m *MetaCache) Mappings() MetadataSnapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return MetadataSnapshot{
+		BaseID:            m.baseID,
+		SavedAt:           m.lastLoadedAt,
+		Hash:              m.schemaHash,
+		TableByName:       copyMapping(m.tableByName),
+		FieldsByTable:     copyNestedMapping(m.fieldsByTable),
+		LinkFieldsByTable: copyNestedMapping(m.linkFieldsByTable),
+	}
+}
+
+func copyMapping(mapping map[string]string) map[string]string {
+	result := make(map[string]string, len(mapping))
+	for key, value := range mapping {
+		result[key] = value
+	}
+	return result
+}
+
+func copyNestedMapping(mapping map[string]map[string]string) map[string]map[string]string {
+	result := make(map[string]map[string]string, len(mapping))
+	for key, value := range mapping {
+		result[key] = copyMapping(value)
+	}
+	return result
+}
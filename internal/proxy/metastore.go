@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+)
+
+// storeTimeout bounds each call to the metadata store
+const storeTimeout = 5 * time.Second
+
+// MetadataSnapshot is the serialized form of the MetaCache kept in a MetadataStore
+type MetadataSnapshot struct {
+	BaseID            string                       `json:"base_id"`
+	SavedAt           time.Time                    `json:"saved_at"`
+	Hash              string                       `json:"hash,omitempty"`
+	TableByName       map[string]string            `json:"tables"`
+	FieldsByTable     map[string]map[string]string `json:"fields"`
+	LinkFieldsByTable map[string]map[string]string `json:"link_fields"`
+	Schema            map[string]TableSchema       `json:"schema,omitempty"`
+}
+
+// MetadataStore persists encoded MetadataSnapshots. A shared store (Redis) lets
+// several gateway instances use one cache: only the instance holding leadership
+// refreshes from NocoDB, and the others reload the snapshot when it is invalidated.
+type MetadataStore interface {
+	// Load returns the stored snapshot, or nil if none was saved yet
+	Load(ctx context.Context) ([]byte, error)
+	// Save replaces the stored snapshot
+	Save(ctx context.Context, data []byte) error
+	// TryLead claims or renews refresh leadership for ttl and reports whether this instance leads
+	TryLead(ctx context.Context, instanceID string, ttl time.Duration) (bool, error)
+	// Invalidate tells other instances that the snapshot changed
+	Invalidate(ctx context.Context, hash string) error
+	// Watch calls fn with the hash of every invalidation until ctx is cancelled
+	Watch(ctx context.Context, fn func(hash string)) error
+}
+
+// SetStore sets where the cache is persisted and shared
+func (m *MetaCache) SetStore(store MetadataStore) {
+	m.store = store
+	if m.instanceID == "" {
+		host, _ := os.Hostname()
+		m.instanceID = fmt.Sprintf("%s-%d-%d", host, os.Getpid(), rand.Int63())
+	}
+}
+
+// leaderTTL is how long refresh leadership lasts without renewal. It outlives a
+// regular refresh interval so a healthy leader keeps it between refreshes.
+func (m *MetaCache) leaderTTL() time.Duration {
+	return m.refreshInterval + staleRetryInterval
+}
+
+// isLeader reports whether this instance should refresh from NocoDB. Without a
+// store, or when the store is unreachable, every instance refreshes on its own.
+func (m *MetaCache) isLeader() bool {
+	if m.store == nil {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	leader, err := m.store.TryLead(ctx, m.instanceID, m.leaderTTL())
+	if err != nil {
+		log.Printf("[META WARNING] Refresh leadership check failed, refreshing locally: %v", err)
+		return true
+	}
+	return leader
+}
+
+// publishSnapshot saves the cache to the store and tells other instances about it
+func (m *MetaCache) publishSnapshot() {
+	if m.store == nil {
+		return
+	}
+	if err := m.saveSnapshot(); err != nil {
+		log.Printf("[META WARNING] Failed to save metadata snapshot: %v", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if err := m.store.Invalidate(ctx, m.SchemaHash()); err != nil {
+		log.Printf("[META WARNING] Failed to publish metadata invalidation: %v", err)
+	}
+}
+
+// watchStore reloads the shared snapshot whenever another instance invalidates it
+func (m *MetaCache) watchStore(ctx context.Context) {
+	if m.store == nil {
+		return
+	}
+	err := m.store.Watch(ctx, func(hash string) {
+		if hash == m.SchemaHash() {
+			return
+		}
+		log.Printf("[META] Metadata invalidated by another instance (hash %s)", shortHash(hash))
+		if err := m.loadSnapshot(false); err != nil {
+			log.Printf("[META WARNING] Failed to reload shared metadata: %v", err)
+		}
+	})
+	if err != nil {
+		log.Printf("[META WARNING] Cannot watch metadata invalidations, relying on periodic reloads: %v", err)
+	}
+}
+
+// schemaChanged records the diff between two schema versions and notifies listeners.
+// Only the instance that detected the change publishes it on the event bus.
+func (m *MetaCache) schemaChanged(previousHash, hash string, previous, current map[string]TableSchema, publish bool) {
+	log.Printf("[META] Schema changed (hash %s -> %s)", shortHash(previousHash), shortHash(hash))
+	if previous != nil {
+		m.recordSchemaDiff(previousHash, hash, DiffSchemas(previous, current), publish)
+	}
+	for _, listener := range m.listeners() {
+		listener()
+	}
+}
+
+// shortHash abbreviates a schema hash for logs
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
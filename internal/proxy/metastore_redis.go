@@ -0,0 +1,123 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// leadScript claims leadership if it is free, or renews it if this instance already holds it
+var leadScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+return 0
+`)
+
+// RedisStore shares the MetaCache of one base between gateway instances through
+// Redis: the snapshot is a plain key, leadership a key with a TTL, and
+// invalidations a pub/sub channel
+type RedisStore struct {
+	client    *redis.Client
+	key       string
+	leaderKey string
+	channel   string
+}
+
+// NewRedisStore creates a store for one base. prefix namespaces the keys, e.g. "nocodb-gateway:meta:".
+func NewRedisStore(client *redis.Client, prefix, baseID string) *RedisStore {
+	key := prefix + baseID
+	return &RedisStore{
+		client:    client,
+		key:       key + ":snapshot",
+		leaderKey: key + ":leader",
+		channel:   key + ":invalidate",
+	}
+}
+
+// NewRedisClient connects to Redis at a redis:// or rediss:// URL
+func NewRedisClient(url string) (*redis.Client, error) {
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	client := redis.NewClient(options)
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	return client, nil
+}
+
+// Load returns the stored snapshot (nil if none)
+func (s *RedisStore) Load(ctx context.Context) ([]byte, error) {
+	data, err := s.client.Get(ctx, s.key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot from Redis: %w", err)
+	}
+	return data, nil
+}
+
+// Save replaces the stored snapshot
+func (s *RedisStore) Save(ctx context.Context, data []byte) error {
+	if err := s.client.Set(ctx, s.key, data, 0).Err(); err != nil {
+		return fmt.Errorf("failed to write snapshot to Redis: %w", err)
+	}
+	return nil
+}
+
+// TryLead claims or renews refresh leadership
+func (s *RedisStore) TryLead(ctx context.Context, instanceID string, ttl time.Duration) (bool, error) {
+	result, err := leadScript.Run(ctx, s.client, []string{s.leaderKey}, instanceID, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to check refresh leadership: %w", err)
+	}
+	return result == 1, nil
+}
+
+// Invalidate publishes the new snapshot hash to every instance
+func (s *RedisStore) Invalidate(ctx context.Context, hash string) error {
+	return s.client.Publish(ctx, s.channel, hash).Err()
+}
+
+// Watch subscribes to invalidations and calls fn for each in the background
+func (s *RedisStore) Watch(ctx context.Context, fn func(hash string)) error {
+	pubsub := s.client.Subscribe(ctx, s.channel)
+	// Wait for the subscription to be confirmed so errors surface to the caller
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return fmt.Errorf("failed to subscribe to %s: %w", s.channel, err)
+	}
+
+	go func() {
+		defer pubsub.Close()
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					log.Printf("[META WARNING] Redis invalidation channel %s closed", s.channel)
+					return
+				}
+				fn(msg.Payload)
+			}
+		}
+	}()
+	return nil
+}
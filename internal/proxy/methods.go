@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// tableMethods are the methods a table route may allow, in the order of the Allow header
+var tableMethods = []string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodPut, http.MethodDelete}
+
+// headable reports whether HEAD is served for a route: record lists, single
+// records and linked records
+func headable(route Route) bool {
+	return route.IsList() || route.IsRecord()
+}
+
+// allowedMethods returns the methods a route accepts. Table routes follow the
+// table's operations in proxy.yaml; ok is false for unknown tables.
+func (p *ProxyHandler) allowedMethods(route Route) (methods []string, ok bool) {
+	switch route.Kind {
+	case RouteBatch:
+		return []string{http.MethodPost, http.MethodOptions}, true
+	case RouteEvents, RouteChanges:
+		return []string{http.MethodGet, http.MethodOptions}, true
+	case RouteRecordMeta:
+		return append(append([]string{}, recordMetaResources[route.Resource]...), http.MethodOptions), true
+	}
+
+	var table func(method string) bool
+	if p.Validator != nil && p.ResolvedConfig != nil {
+		resolved, found := p.ResolvedConfig.Tables[route.Table]
+		if !found {
+			return nil, false
+		}
+		table = func(method string) bool {
+			return p.Validator.isOperationAllowed(resolved, route.Operation(method))
+		}
+	} else {
+		// Legacy mode leaves authorization to NocoDB
+		table = func(string) bool { return true }
+	}
+
+	candidates := tableMethods
+	if route.Kind == RouteLinked {
+		// Linked records are only read
+		candidates = []string{http.MethodGet}
+	}
+	for _, method := range candidates {
+		if !table(method) {
+			continue
+		}
+		methods = append(methods, method)
+		if method == http.MethodGet && headable(route) {
+			methods = append(methods, http.MethodHead)
+		}
+	}
+	return append(methods, http.MethodOptions), true
+}
+
+// serveOptions answers OPTIONS (other than CORS preflight requests, which
+// CORSMiddleware answers) with the methods the route allows, without asking NocoDB
+func (p *ProxyHandler) serveOptions(w http.ResponseWriter, r *http.Request, route Route) {
+	methods, ok := p.allowedMethods(route)
+	if !ok {
+		p.httpError(w, r, route.Table, http.StatusForbidden, fmt.Sprintf("forbidden: table '%s' not found in configuration", route.Table))
+		return
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveHead answers HEAD like the matching GET, through the same validation,
+// rules and caches, but without a body. Lists report their size in
+// X-Total-Count (when NocoDB knows it) and X-Has-More.
+func (p *ProxyHandler) serveHead(w http.ResponseWriter, r *http.Request, route Route) {
+	if !headable(route) {
+		if methods, ok := p.allowedMethods(route); ok {
+			w.Header().Set("Allow", strings.Join(methods, ", "))
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// NocoDB's shape tells the page, whatever envelope the table uses
+	get := r.Clone(context.WithValue(r.Context(), rawEnvelopeKey, true))
+	get.Method = http.MethodGet
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(recorder, get)
+
+	for key, values := range recorder.Header() {
+		w.Header()[key] = values
+	}
+	w.Header().Del("Content-Length")
+	if recorder.Code == http.StatusOK && route.IsList() {
+		if _, meta, ok := parseUpstreamList(recorder.Body.Bytes()); ok {
+			setPageHeaders(w.Header(), meta)
+		}
+	}
+	w.WriteHeader(recorder.Code)
+}
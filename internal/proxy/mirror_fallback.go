@@ -0,0 +1,191 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+// MirrorReader serves locally mirrored records when NocoDB is unreachable
+type MirrorReader interface {
+	Get(tableKey, recordID string) (map[string]interface{}, time.Time, bool)
+	List(tableKey string) ([]map[string]interface{}, time.Time, bool)
+}
+
+// SetMirror sets the local mirror used as a fallback for reads during upstream outages
+func (p *ProxyHandler) SetMirror(mirror MirrorReader) {
+	p.Mirror = mirror
+}
+
+// isUpstreamOutage reports whether an upstream status indicates NocoDB is unavailable
+func isUpstreamOutage(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// serveFromMirror answers a GET from the local mirror. It returns false if the
+// request cannot be served from the mirror (table not mirrored, unsupported path or filter).
+func (p *ProxyHandler) serveFromMirror(w http.ResponseWriter, r *http.Request, tableKey string, route Route) bool {
+	if p.Mirror == nil || p.ResolvedConfig == nil || r.Method != http.MethodGet {
+		return false
+	}
+
+	table, ok := p.ResolvedConfig.Tables[tableKey]
+	if !ok || table.Mirror == nil || !table.Mirror.Enabled {
+		return false
+	}
+
+	if route.Kind != RouteRecords {
+		return false
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+
+	var payload interface{}
+	var lastSync time.Time
+
+	if route.IsRecord() {
+		record, synced, ok := p.Mirror.Get(tableKey, route.RecordID)
+		if !ok {
+			return false
+		}
+		if !CanSeeRecord(&table, role, userID, record) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return true
+		}
+		lastSync = synced
+		payload = p.mirrorRecord(record)
+	} else {
+		records, synced, ok := p.Mirror.List(tableKey)
+		if !ok {
+			return false
+		}
+		filter, ok := parseEqualityWhere(r.URL.Query().Get("where"))
+		if !ok {
+			log.Printf("[MIRROR] Cannot evaluate where clause from mirror for '%s'", tableKey)
+			return false
+		}
+
+		visible := make([]map[string]interface{}, 0, len(records))
+		for _, record := range records {
+			if CanSeeRecord(&table, role, userID, record) && recordMatches(record, filter) {
+				visible = append(visible, record)
+			}
+		}
+		lastSync = synced
+		payload = p.mirrorList(visible, r)
+	}
+
+	staleness := time.Since(lastSync).Round(time.Second)
+	log.Printf("[MIRROR] Served %s from local mirror (last sync %v ago)", r.URL.Path, staleness)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Gateway-Source", "mirror")
+	w.Header().Set("X-Gateway-Stale-Seconds", strconv.Itoa(int(staleness.Seconds())))
+	w.Header().Set("Warning", `110 - "Response is Stale"`)
+	body, _ := json.Marshal(payload)
+	body = p.applyComputed(r, route, tableKey, http.StatusOK, body)
+	body = p.applyResponseSteps(tableKey, role, body)
+	body = normalizeEnvelope(p.envelope(r, tableKey), r.Method, route, http.StatusOK, w.Header(), body)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+	return true
+}
+
+// mirrorRecord shapes a single record like the upstream's NocoDB API version
+func (p *ProxyHandler) mirrorRecord(record map[string]interface{}) interface{} {
+	if !p.dialect.IsV3() {
+		return record
+	}
+	return map[string]interface{}{"id": record["id"], "fields": record}
+}
+
+// mirrorList paginates records and shapes them like the upstream's NocoDB API version
+func (p *ProxyHandler) mirrorList(records []map[string]interface{}, r *http.Request) interface{} {
+	query := r.URL.Query()
+	v3 := p.dialect.IsV3()
+
+	pageSize, offset := 25, 0
+	if v3 {
+		if n, err := strconv.Atoi(query.Get("pageSize")); err == nil && n > 0 {
+			pageSize = n
+		}
+		if n, err := strconv.Atoi(query.Get("page")); err == nil && n > 1 {
+			offset = (n - 1) * pageSize
+		}
+	} else {
+		if n, err := strconv.Atoi(query.Get("limit")); err == nil && n > 0 {
+			pageSize = n
+		}
+		if n, err := strconv.Atoi(query.Get("offset")); err == nil && n > 0 {
+			offset = n
+		}
+	}
+
+	total := len(records)
+	end := offset + pageSize
+	if offset > total {
+		offset = total
+	}
+	if end > total {
+		end = total
+	}
+	page := records[offset:end]
+
+	if v3 {
+		out := make([]interface{}, 0, len(page))
+		for _, record := range page {
+			out = append(out, p.mirrorRecord(record))
+		}
+		return map[string]interface{}{"records": out, "next": nil}
+	}
+
+	return map[string]interface{}{
+		"list": page,
+		"pageInfo": map[string]interface{}{
+			"totalRows":   total,
+			"page":        offset/pageSize + 1,
+			"pageSize":    pageSize,
+			"isFirstPage": offset == 0,
+			"isLastPage":  end >= total,
+		},
+	}
+}
+
+// parseEqualityWhere parses simple where clauses like (a,eq,1)~and(b,eq,x).
+// Any other operator makes the clause unsupported for mirror evaluation.
+func parseEqualityWhere(where string) (map[string]string, bool) {
+	filter := make(map[string]string)
+	if where == "" {
+		return filter, true
+	}
+
+	for _, clause := range strings.Split(where, "~and") {
+		clause = strings.TrimSpace(clause)
+		if !strings.HasPrefix(clause, "(") || !strings.HasSuffix(clause, ")") {
+			return nil, false
+		}
+		parts := strings.SplitN(clause[1:len(clause)-1], ",", 3)
+		if len(parts) != 3 || parts[1] != "eq" {
+			return nil, false
+		}
+		filter[parts[0]] = parts[2]
+	}
+	return filter, true
+}
+
+// recordMatches reports whether a record satisfies an equality filter
+func recordMatches(record map[string]interface{}, filter map[string]string) bool {
+	for field, want := range filter {
+		if fmt.Sprintf("%v", record[field]) != want {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,249 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/events"
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+const (
+	outboxPollInterval = 2 * time.Second
+	outboxMaxBackoff   = 5 * time.Minute
+	outboxTimeout      = 30 * time.Second
+)
+
+// OutboxStatus is the response of GET /outbox/{id}
+type OutboxStatus struct {
+	ID             string          `json:"id"`
+	Status         string          `json:"status"`
+	Table          string          `json:"table"`
+	Method         string          `json:"method"`
+	Attempts       int             `json:"attempts"`
+	LastError      string          `json:"last_error,omitempty"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	Response       json.RawMessage `json:"response,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// SetOutbox sets the database used to queue writes for outbox-enabled tables
+func (p *ProxyHandler) SetOutbox(store *db.Database) {
+	p.Outbox = store
+	p.outboxNotify = make(chan struct{}, 1)
+}
+
+// outboxMode returns the outbox mode for a write to the given table, or "" if
+// the request should be proxied synchronously
+func (p *ProxyHandler) outboxMode(method, tableKey string) string {
+	if p.Outbox == nil || p.ResolvedConfig == nil || tableKey == "" {
+		return ""
+	}
+	switch method {
+	case http.MethodPost, http.MethodPatch, http.MethodPut, http.MethodDelete:
+	default:
+		return ""
+	}
+
+	table, ok := p.ResolvedConfig.Tables[tableKey]
+	if !ok || table.Outbox == nil || !table.Outbox.Enabled {
+		return ""
+	}
+	if table.Outbox.Mode == "" {
+		return config.OutboxAlways
+	}
+	return table.Outbox.Mode
+}
+
+// enqueueWrite persists a write in the outbox and acknowledges it with 202 Accepted
+func (p *ProxyHandler) enqueueWrite(w http.ResponseWriter, r *http.Request, tableKey, tableID, path, upstreamPath string, body []byte) {
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+
+	entry := &db.OutboxEntry{
+		ID:          events.NewID(),
+		TableKey:    tableKey,
+		TableID:     tableID,
+		Method:      r.Method,
+		Path:        path,
+		TargetPath:  upstreamPath,
+		ContentType: r.Header.Get("Content-Type"),
+		Body:        body,
+		UserID:      userID,
+		Role:        role,
+	}
+	if err := p.Outbox.EnqueueOutbox(entry); err != nil {
+		respondJSONError(w, http.StatusInternalServerError, "failed to queue write")
+		return
+	}
+
+	select {
+	case p.outboxNotify <- struct{}{}:
+	default:
+	}
+
+	statusURL := "/outbox/" + entry.ID
+	log.Printf("[OUTBOX] Queued %s %s as %s (seq %d)", r.Method, path, entry.ID, entry.Seq)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", statusURL)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"id":         entry.ID,
+		"status":     db.OutboxPending,
+		"status_url": statusURL,
+	})
+}
+
+// StartOutbox launches the worker that replays queued writes to NocoDB in order.
+// Writes are delivered one at a time so their upstream order matches acceptance order.
+func (p *ProxyHandler) StartOutbox(ctx context.Context) {
+	if p.Outbox == nil {
+		return
+	}
+
+	if pending, err := p.Outbox.CountPendingOutbox(); err == nil && pending > 0 {
+		log.Printf("[OUTBOX] Resuming delivery of %d pending write(s)", pending)
+	}
+
+	go func() {
+		client := &http.Client{Timeout: outboxTimeout}
+		for {
+			wait := p.deliverNext(ctx, client)
+			select {
+			case <-ctx.Done():
+				return
+			case <-p.outboxNotify:
+			case <-time.After(wait):
+			}
+		}
+	}()
+}
+
+// deliverNext attempts the oldest pending write and returns how long to wait before the next attempt
+func (p *ProxyHandler) deliverNext(ctx context.Context, client *http.Client) time.Duration {
+	entry, err := p.Outbox.NextPendingOutbox()
+	if err != nil {
+		log.Printf("[OUTBOX ERROR] Failed to read queue: %v", err)
+		return outboxPollInterval
+	}
+	if entry == nil {
+		return outboxPollInterval
+	}
+	if wait := time.Until(entry.NextAttemptAt); wait > 0 {
+		return wait
+	}
+
+	req, err := http.NewRequestWithContext(ctx, entry.Method, p.tablePrefix(entry.TableKey)+entry.TargetPath, bytes.NewReader(entry.Body))
+	if err != nil {
+		p.Outbox.CompleteOutbox(entry.ID, db.OutboxFailed, 0, "", err.Error())
+		return 0
+	}
+	token, ok := p.roleToken(entry.Role, entry.TableKey)
+	if !ok {
+		p.retryOutbox(entry, fmt.Sprintf("no NocoDB token is available for role '%s'", entry.Role))
+		return outboxPollInterval
+	}
+	req.Header.Set("xc-token", token)
+	if entry.ContentType != "" {
+		req.Header.Set("Content-Type", entry.ContentType)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		p.retryOutbox(entry, err.Error())
+		return outboxPollInterval
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		if err := p.Outbox.CompleteOutbox(entry.ID, db.OutboxDelivered, resp.StatusCode, string(body), ""); err != nil {
+			log.Printf("[OUTBOX ERROR] Failed to mark %s delivered: %v", entry.ID, err)
+		}
+		log.Printf("[OUTBOX] Delivered %s %s (%s) after %d attempt(s)", entry.Method, entry.Path, entry.ID, entry.Attempts+1)
+		p.publishWriteEvent(entry.Method, entry.UserID, entry.TableKey, entry.TableID, ParseRoute(entry.Path), body)
+		return 0
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		p.retryOutbox(entry, fmt.Sprintf("upstream returned %d", resp.StatusCode))
+		return outboxPollInterval
+	default:
+		// NocoDB rejected the write; retrying will not help
+		log.Printf("[OUTBOX] Write %s rejected by NocoDB with %d", entry.ID, resp.StatusCode)
+		if err := p.Outbox.CompleteOutbox(entry.ID, db.OutboxFailed, resp.StatusCode, string(body), "rejected by upstream"); err != nil {
+			log.Printf("[OUTBOX ERROR] Failed to mark %s failed: %v", entry.ID, err)
+		}
+		return 0
+	}
+}
+
+// retryOutbox schedules another attempt with exponential backoff
+func (p *ProxyHandler) retryOutbox(entry *db.OutboxEntry, reason string) {
+	backoff := outboxMaxBackoff
+	if entry.Attempts < 9 {
+		backoff = time.Second << uint(entry.Attempts)
+		if backoff > outboxMaxBackoff {
+			backoff = outboxMaxBackoff
+		}
+	}
+	log.Printf("[OUTBOX] Delivery of %s failed (attempt %d): %s, retrying in %v", entry.ID, entry.Attempts+1, reason, backoff)
+	if err := p.Outbox.RetryOutbox(entry.ID, reason, time.Now().UTC().Add(backoff)); err != nil {
+		log.Printf("[OUTBOX ERROR] Failed to reschedule %s: %v", entry.ID, err)
+	}
+}
+
+// ServeOutboxStatus handles GET /outbox/{id}. Only the user who made the write
+// (or an admin) may see its status.
+func (p *ProxyHandler) ServeOutboxStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if p.Outbox == nil {
+		respondJSONError(w, http.StatusNotFound, "outbox not enabled")
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/outbox/"), "/")
+	entry, err := p.Outbox.GetOutboxEntry(id)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, "failed to read outbox")
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+	if entry == nil || (role != "admin" && entry.UserID != userID) {
+		respondJSONError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	status := OutboxStatus{
+		ID:             entry.ID,
+		Status:         entry.Status,
+		Table:          entry.TableKey,
+		Method:         entry.Method,
+		Attempts:       entry.Attempts,
+		LastError:      entry.LastError,
+		ResponseStatus: entry.ResponseStatus,
+		CreatedAt:      entry.CreatedAt,
+		UpdatedAt:      entry.UpdatedAt,
+	}
+	if entry.ResponseBody != "" && json.Valid([]byte(entry.ResponseBody)) {
+		status.Response = json.RawMessage(entry.ResponseBody)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
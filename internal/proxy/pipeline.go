@@ -0,0 +1,258 @@
+package proxy
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/grove/generic-proxy/internal/plugins"
+)
+
+// exchange is a request to a table on its way through the pipeline, with what
+// the stages learned about it
+type exchange struct {
+	w     http.ResponseWriter
+	r     *http.Request
+	path  string // below /proxy/, as sent by the client
+	route Route
+
+	tableKey     string
+	tableID      string
+	resolvedPath string            // NocoDB path below the table prefix
+	validation   *ValidationResult // config-driven mode only
+	hookInfo     plugins.RequestInfo
+
+	// Set by forward for transform-response
+	response  *plugins.Response
+	upstream  bool   // the response comes from NocoDB, not a cache
+	targetURL string // for shadow traffic
+	sent      http.Header
+	shadowed  bool
+	shadow    []byte
+	localETag bool // the gateway answers If-None-Match with an ETag of its own
+}
+
+// stage is a step of the pipeline. It returns false when it answered the
+// request itself (a refusal, an error, a queued write, a streamed or mirrored
+// response), which ends the pipeline.
+type stage struct {
+	name string
+	run  func(p *ProxyHandler, x *exchange) bool
+}
+
+// pipeline is the way of every request to a table, in order. Tables add
+// steps to transform and transform-response in proxy.yaml (`pipeline:`).
+var pipeline = []stage{
+	{"resolve", (*ProxyHandler).resolve},     // table, operation and NocoDB IDs
+	{"authorize", (*ProxyHandler).authorize}, // the table's mode and operations
+	{"validate", (*ProxyHandler).validate},   // date filters, links, query parameters and expression rules
+	{"transform", (*ProxyHandler).transform}, // plugins, filters, the table's request steps, link keys and encryption
+	{"forward", (*ProxyHandler).forward},     // caches, outbox, budgets and the request to NocoDB
+	{"transform-response", (*ProxyHandler).transformResponse},
+}
+
+// runPipeline takes a request through the stages until one answers it
+func (p *ProxyHandler) runPipeline(x *exchange) {
+	for _, stage := range pipeline {
+		if !stage.run(p, x) {
+			log.Printf("[PROXY] %s %s answered in stage '%s'", x.r.Method, x.path, stage.name)
+			return
+		}
+	}
+	log.Printf("[PROXY] Request completed successfully")
+}
+
+// configDriven reports whether proxy.yaml is enforced (dry-run mode only reports)
+func (p *ProxyHandler) configDriven() bool {
+	return p.Validator != nil && p.ResolvedConfig != nil && p.dryRun == nil
+}
+
+// resolve finds the table of a request: in proxy.yaml when it is enforced,
+// through the MetaCache otherwise (legacy mode)
+func (p *ProxyHandler) resolve(x *exchange) bool {
+	// In dry-run mode validation only reports what it would refuse, and the
+	// request continues as in legacy mode
+	if p.dryRun != nil && p.Validator != nil && p.ResolvedConfig != nil {
+		p.dryRunValidate(x.r, x.path, x.route.Table)
+	}
+
+	if p.configDriven() {
+		log.Printf("[PROXY] Using config-driven validation")
+		validation, err := p.Validator.Resolve(x.r.Method, x.route)
+		if err != nil {
+			log.Printf("[PROXY ERROR] Validation failed: %v", err)
+			p.httpError(x.w, x.r, x.route.Table, http.StatusForbidden, "forbidden: "+err.Error())
+			return false
+		}
+		x.validation = validation
+		x.tableKey = validation.TableKey
+		x.tableID = validation.TableID
+		return true
+	}
+
+	// Fallback to MetaCache-only resolution (legacy mode)
+	log.Printf("[PROXY] Using legacy MetaCache-only mode")
+	x.resolvedPath = x.path
+	if p.Meta == nil || x.route.Table == "" {
+		return true
+	}
+	tableName := x.route.Table
+	x.tableKey = tableName
+	resolvedID, ok := p.Meta.Resolve(tableName)
+	if !ok {
+		log.Printf("[META] No mapping found for table '%s', using raw name", tableName)
+		p.Meta.RecordFallback(tableName)
+		return true
+	}
+	x.tableID = resolvedID
+	log.Printf("[META] Resolved table '%s' -> '%s'", tableName, x.tableID)
+
+	resolvedPath, err := resolvePath(p.Meta, x.tableID, tableName, x.route)
+	if err != nil {
+		log.Printf("[PROXY ERROR] Link field resolution failed: %v", err)
+		p.httpError(x.w, x.r, x.tableKey, http.StatusBadRequest, "bad request: "+err.Error())
+		return false
+	}
+	x.resolvedPath = resolvedPath
+	return true
+}
+
+// authorize checks the request against the table's mode and operations.
+// Legacy mode leaves authorization to NocoDB.
+func (p *ProxyHandler) authorize(x *exchange) bool {
+	if x.validation == nil {
+		return true
+	}
+	if err := p.Validator.Authorize(x.validation); err != nil {
+		log.Printf("[PROXY ERROR] Validation failed: %v", err)
+		p.httpError(x.w, x.r, x.tableKey, http.StatusForbidden, "forbidden: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// validate expands date filters, checks published fields, resolves the NocoDB
+// path (links), checks the query parameters, applies the role's default fields and the table's
+// expression rules
+func (p *ProxyHandler) validate(x *exchange) bool {
+	// Date filter shorthands (?due=last_7_days) become where clauses, checked like any other
+	if err := p.expandDateFilters(x); err != nil {
+		log.Printf("[PROXY] Rejected date filter: %v", err)
+		p.httpError(x.w, x.r, x.tableKey, http.StatusBadRequest, "bad request: "+err.Error())
+		return false
+	}
+	// Callers limited to some fields (anonymous reads) cannot filter or sort on others
+	if err := checkPublishedFields(x.r); err != nil {
+		log.Printf("[PROXY] Rejected query: %v", err)
+		p.httpError(x.w, x.r, x.tableKey, http.StatusBadRequest, "bad request: "+err.Error())
+		return false
+	}
+	if x.validation == nil {
+		return true
+	}
+	w, r, validation := x.w, x.r, x.validation
+
+	if err := p.Validator.ResolvePath(validation); err != nil {
+		log.Printf("[PROXY ERROR] Validation failed: %v", err)
+		p.httpError(w, r, x.tableKey, http.StatusForbidden, "forbidden: "+err.Error())
+		return false
+	}
+
+	// Query parameters outside the table's query block are rejected or dropped
+	if err := p.Validator.SanitizeQuery(r, validation); err != nil {
+		log.Printf("[PROXY] Rejected query: %v", err)
+		p.httpError(w, r, x.tableKey, http.StatusBadRequest, "bad request: "+err.Error())
+		return false
+	}
+
+	// Reads without fields get the role's default field set
+	p.Validator.ApplyDefaultFields(r, validation)
+
+	// Expression rules from proxy.yaml may reject the request or rewrite body and query
+	if err := p.Validator.ApplyRules(r, validation); err != nil {
+		var ruleErr *config.RuleError
+		if errors.As(err, &ruleErr) {
+			log.Printf("[PROXY] Rejected by rule: %s", ruleErr.Message)
+			p.ruleError(w, r, x.tableKey, ruleErr)
+			return false
+		}
+		log.Printf("[PROXY ERROR] Rule evaluation failed: %v", err)
+		p.httpError(w, r, x.tableKey, http.StatusBadRequest, "bad request: "+err.Error())
+		return false
+	}
+
+	x.resolvedPath = validation.ResolvedPath
+	log.Printf("[PROXY] Validated and resolved: %s -> %s", x.path, x.resolvedPath)
+	return true
+}
+
+// transform lets plugins, WebAssembly filters and the table's request steps
+// change the request, looks up link targets named by key, then encrypts the
+// table's encrypted columns
+func (p *ProxyHandler) transform(x *exchange) bool {
+	w, r := x.w, x.r
+
+	// Plugins and WebAssembly filters may add headers, rewrite the query or reject the request
+	x.hookInfo = requestInfo(r, x.path, x.tableKey, x.tableID)
+	if plugin, err := p.plugins.PreProxy(r, x.hookInfo); err != nil {
+		plugins.WriteError(w, plugin, err)
+		return false
+	}
+	if filter, err := p.filterRequest(r, x.hookInfo); err != nil {
+		plugins.WriteError(w, filter, err)
+		return false
+	}
+
+	// The table's own steps run last, closest to NocoDB
+	if err := p.applyRequestSteps(r, x.route, x.tableKey, x.hookInfo.Role); err != nil {
+		log.Printf("[PROXY] Rejected by the pipeline of table '%s': %v", x.tableKey, err)
+		p.httpError(w, r, x.tableKey, http.StatusBadRequest, "bad request: "+err.Error())
+		return false
+	}
+
+	// Link bodies may name target records by the link's key_field
+	if status, err := p.resolveLinkKeys(r, x); err != nil {
+		log.Printf("[PROXY] Link target lookup failed: %v", err)
+		p.httpError(w, r, x.tableKey, status, err.Error())
+		return false
+	}
+
+	// Encrypted columns leave the gateway as ciphertext (also in the outbox and shadow copies)
+	if err := p.encryptRequest(r, x.tableKey); err != nil {
+		log.Printf("[PROXY ERROR] Field encryption failed: %v", err)
+		p.httpError(w, r, x.tableKey, http.StatusBadRequest, "bad request: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// transformResponse sends the response forward got to the client, through
+// decryption, filters, plugins, the table's response steps and its envelope.
+// Successful writes then invalidate the caches and are published as events.
+func (p *ProxyHandler) transformResponse(x *exchange) bool {
+	r, response := x.r, x.response
+	body := response.Body
+	if !p.respond(x, response.StatusCode, response.Header.Clone(), body) {
+		return false
+	}
+	if !x.upstream {
+		return true
+	}
+
+	if x.shadowed {
+		p.shadow.mirror(r.Method, x.targetURL, x.sent, x.shadow, response.StatusCode, body)
+	}
+
+	// Notify subscribers (outbound webhooks, event publishers) about successful writes
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		if r.Method != http.MethodGet {
+			p.responses.Invalidate(x.tableID)
+			p.records.Invalidate(x.tableID, writtenRecord(x.route, body))
+		}
+		userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+		p.publishWriteEvent(r.Method, userID, x.tableKey, x.tableID, x.route, body)
+	}
+	return true
+}
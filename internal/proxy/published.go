@@ -0,0 +1,51 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// publishedFieldsKey holds the only fields the where and sort of a request may name
+type publishedFieldsKey struct{}
+
+// WithPublishedFields limits the fields a request may filter and sort on, such
+// as the fields an anonymous table publishes: terms on other fields would
+// reveal their values. The check runs once date filter shorthands are where
+// clauses, and before rules add their own.
+func WithPublishedFields(ctx context.Context, fields []string) context.Context {
+	published := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		published[field] = true
+	}
+	return context.WithValue(ctx, publishedFieldsKey{}, published)
+}
+
+// checkPublishedFields refuses where and sort terms on fields outside those
+// set by WithPublishedFields
+func checkPublishedFields(r *http.Request) error {
+	published, ok := r.Context().Value(publishedFieldsKey{}).(map[string]bool)
+	if !ok {
+		return nil
+	}
+	query := r.URL.Query()
+	for _, where := range query["where"] {
+		fields, err := WhereFields(where)
+		if err != nil {
+			return err
+		}
+		for _, field := range fields {
+			if !published[field] {
+				return fmt.Errorf("cannot filter on field '%s'", field)
+			}
+		}
+	}
+	for _, sort := range query["sort"] {
+		for _, field := range SortFields(sort) {
+			if !published[field] {
+				return fmt.Errorf("cannot sort on field '%s'", field)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package proxy
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+// SanitizeQuery checks the query of a validated request. Every where clause
+// must have balanced parentheses, so a rule filter ANDed to it cannot be
+// escaped. Tables with a query block accept only the listed parameters, each at
+// most once, and only the listed where operators; other parameters are
+// rejected, or dropped with unknown: strip. It may replace the request query.
+func (v *Validator) SanitizeQuery(r *http.Request, result *ValidationResult) error {
+	query := r.URL.Query()
+	for _, where := range query["where"] {
+		if _, err := whereOperators(where); err != nil {
+			return err
+		}
+	}
+
+	policy := v.config.Tables[result.TableKey].Query
+	if policy == nil {
+		return nil
+	}
+
+	stripped := false
+	for name, values := range query {
+		if !queryParamAllowed(policy.Allow, name) {
+			if policy.Unknown == config.QueryUnknownStrip {
+				log.Printf("[VALIDATOR] Dropping query parameter '%s' for table '%s'", name, result.TableKey)
+				query.Del(name)
+				stripped = true
+				continue
+			}
+			return fmt.Errorf("query parameter '%s' is not allowed for table '%s'", name, result.TableKey)
+		}
+		if len(values) > 1 {
+			return fmt.Errorf("query parameter '%s' is given more than once", name)
+		}
+	}
+
+	if where := query.Get("where"); where != "" && len(policy.Operators) > 0 {
+		operators, _ := whereOperators(where)
+		for _, operator := range operators {
+			if !containsString(policy.Operators, operator) {
+				return fmt.Errorf("where operator '%s' is not allowed for table '%s'", operator, result.TableKey)
+			}
+		}
+	}
+
+	if stripped {
+		r.URL.RawQuery = query.Encode()
+	}
+	return nil
+}
+
+// queryParamAllowed reports whether a parameter is listed; "nested" covers
+// NocoDB's nested[{field}][...] parameters
+func queryParamAllowed(allow []string, name string) bool {
+	if base, _, found := strings.Cut(name, "["); found {
+		name = base
+	}
+	return containsString(allow, name)
+}
+
+// whereOperators returns the comparison operators of a where clause such as
+// (Status,eq,Sent)~and((Total,gt,100)~or(Total,lt,10)), and an error when its
+// parentheses are not balanced
+func whereOperators(where string) ([]string, error) {
+	terms, err := whereTerms(where)
+	operators := make([]string, len(terms))
+	for i, term := range terms {
+		operators[i] = term[1]
+	}
+	return operators, err
+}
+
+// WhereFields returns the fields compared by a where clause, and an error when
+// its parentheses are not balanced
+func WhereFields(where string) ([]string, error) {
+	terms, err := whereTerms(where)
+	fields := make([]string, len(terms))
+	for i, term := range terms {
+		fields[i] = term[0]
+	}
+	return fields, err
+}
+
+// SortFields returns the fields of a sort parameter such as Title,-CreatedAt
+func SortFields(sort string) []string {
+	var fields []string
+	for _, field := range strings.Split(sort, ",") {
+		if field = strings.TrimPrefix(strings.TrimSpace(field), "-"); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// whereTerms returns the field and operator of each comparison of a where
+// clause, and an error when its parentheses are not balanced
+func whereTerms(where string) ([][2]string, error) {
+	var terms [][2]string
+	var open []int
+	for i, c := range where {
+		switch c {
+		case '(':
+			open = append(open, i)
+		case ')':
+			if len(open) == 0 {
+				return nil, fmt.Errorf("unbalanced parentheses in where clause")
+			}
+			group := where[open[len(open)-1]+1 : i]
+			open = open[:len(open)-1]
+			// Groups of groups combine comparisons with ~and, ~or and ~not
+			if strings.HasPrefix(group, "(") || strings.HasPrefix(group, "~") {
+				continue
+			}
+			if parts := strings.SplitN(group, ",", 3); len(parts) >= 2 {
+				terms = append(terms, [2]string{strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])})
+			}
+		}
+	}
+	if len(open) > 0 {
+		return nil, fmt.Errorf("unbalanced parentheses in where clause")
+	}
+	return terms, nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,293 @@
+package proxy
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/events"
+)
+
+const (
+	defaultRecordCacheMB  = 32
+	defaultRecordCacheTTL = 5 * time.Minute
+)
+
+// RecordCacheStats reports the record cache's memory use and effectiveness
+type RecordCacheStats struct {
+	Entries       int     `json:"entries"`
+	Bytes         int64   `json:"bytes"`
+	MaxBytes      int64   `json:"max_bytes"`
+	Hits          int     `json:"hits"`
+	Misses        int     `json:"misses"`
+	HitRate       float64 `json:"hit_rate"`  // hits / (hits + misses)
+	Evictions     int     `json:"evictions"` // entries dropped to stay under max_bytes
+	Invalidations int     `json:"invalidations"`
+}
+
+type recordEntry struct {
+	url     string
+	record  string // tableID/recordID
+	status  int
+	header  http.Header
+	body    []byte
+	size    int64
+	expires time.Time
+}
+
+// RecordCache keeps NocoDB's answers to reads of single records in a least
+// recently used list bounded by memory. Entries are keyed by the upstream URL
+// like the ResponseCache, but a write only drops the reads of the record it
+// changed, so detail pages of other records stay cached.
+type RecordCache struct {
+	ttl      time.Duration
+	maxBytes int64
+	tables   map[string]bool // table keys; nil caches every table
+
+	mu       sync.Mutex
+	lru      *list.List                 // *recordEntry, most recently used first
+	entries  map[string]*list.Element   // upstream URL -> entry
+	records  map[string]map[string]bool // tableID/recordID -> upstream URLs
+	versions map[string]uint64          // table ID -> invalidations so far
+	bytes    int64
+	stats    RecordCacheStats
+}
+
+// NewRecordCache creates the cache of the record_cache block of proxy.yaml,
+// nil when there is none
+func NewRecordCache(cfg *config.RecordCacheConfig) *RecordCache {
+	if cfg == nil {
+		return nil
+	}
+	maxMB := cfg.MaxMemoryMB
+	if maxMB == 0 {
+		maxMB = defaultRecordCacheMB
+	}
+	ttl := defaultRecordCacheTTL
+	if cfg.TTL != "" {
+		ttl, _ = time.ParseDuration(cfg.TTL) // validated by the config loader
+	}
+	c := &RecordCache{
+		ttl:      ttl,
+		maxBytes: int64(maxMB) << 20,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+		records:  make(map[string]map[string]bool),
+		versions: make(map[string]uint64),
+	}
+	if len(cfg.Tables) > 0 {
+		c.tables = make(map[string]bool, len(cfg.Tables))
+		for _, table := range cfg.Tables {
+			c.tables[table] = true
+		}
+	}
+	return c
+}
+
+// covers reports whether reads of the table's records are cached
+func (c *RecordCache) covers(tableKey string) bool {
+	return c != nil && (c.tables == nil || c.tables[tableKey])
+}
+
+// version returns a token that put compares, so a record fetched while its
+// table was written to is not stored
+func (c *RecordCache) version(tableID string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.versions[tableID]
+}
+
+func (c *RecordCache) get(url string) (*recordEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[url]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	entry := element.Value.(*recordEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(element)
+		c.stats.Misses++
+		return nil, false
+	}
+	c.lru.MoveToFront(element)
+	c.stats.Hits++
+	return entry, true
+}
+
+// put stores a fetched record. Reads of a table whose ID is not resolved are
+// not kept, since no event could invalidate them.
+func (c *RecordCache) put(tableID, recordID, url string, version uint64, status int, header http.Header, body []byte) {
+	if tableID == "" {
+		return
+	}
+	entry := &recordEntry{
+		url:     url,
+		record:  tableID + "/" + recordID,
+		status:  status,
+		header:  header.Clone(),
+		body:    body,
+		expires: time.Now().Add(c.ttl),
+	}
+	entry.size = int64(len(url) + len(body))
+	for name, values := range entry.header {
+		entry.size += int64(len(name))
+		for _, value := range values {
+			entry.size += int64(len(value))
+		}
+	}
+	if entry.size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.versions[tableID] != version {
+		return
+	}
+	if element, ok := c.entries[url]; ok {
+		c.remove(element)
+	}
+	for c.bytes+entry.size > c.maxBytes {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+	c.entries[url] = c.lru.PushFront(entry)
+	if c.records[entry.record] == nil {
+		c.records[entry.record] = make(map[string]bool)
+	}
+	c.records[entry.record][url] = true
+	c.bytes += entry.size
+}
+
+// remove drops one entry; the caller holds the lock
+func (c *RecordCache) remove(element *list.Element) {
+	entry := c.lru.Remove(element).(*recordEntry)
+	delete(c.entries, entry.url)
+	if urls := c.records[entry.record]; urls != nil {
+		delete(urls, entry.url)
+		if len(urls) == 0 {
+			delete(c.records, entry.record)
+		}
+	}
+	c.bytes -= entry.size
+}
+
+// Invalidate drops the cached reads of a record, or of every record of the
+// table when recordID is empty (bulk writes, changes of unknown records)
+func (c *RecordCache) Invalidate(tableID, recordID string) {
+	if c == nil || tableID == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.versions[tableID]++
+	c.stats.Invalidations++
+	if recordID != "" {
+		for url := range c.records[tableID+"/"+recordID] {
+			c.remove(c.entries[url])
+		}
+		return
+	}
+	for record, urls := range c.records {
+		if strings.HasPrefix(record, tableID+"/") {
+			for url := range urls {
+				c.remove(c.entries[url])
+			}
+		}
+	}
+}
+
+// Watch invalidates records on record events from the bus, so writes that did
+// not pass through this handler (CDC, NocoDB webhooks, other bases) are seen
+func (c *RecordCache) Watch(ctx context.Context, bus *events.Bus) {
+	if c == nil || bus == nil {
+		return
+	}
+	// A full queue invalidates right away rather than serve stale entries
+	ch, cancel := bus.SubscribeOverflow(cacheInvalidateQueue, func(event events.Event) {
+		switch event.Type {
+		case events.TypeRecordCreated, events.TypeRecordUpdated, events.TypeRecordDeleted:
+			c.Invalidate(event.TableID, event.RecordID)
+		}
+	})
+	go func() {
+		defer cancel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-ch:
+				if !ok {
+					return
+				}
+				switch event.Type {
+				case events.TypeRecordCreated, events.TypeRecordUpdated, events.TypeRecordDeleted:
+					c.Invalidate(event.TableID, event.RecordID)
+				}
+			}
+		}
+	}()
+}
+
+// Stats returns the current memory use and counters
+func (c *RecordCache) Stats() RecordCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	stats.Bytes = c.bytes
+	stats.MaxBytes = c.maxBytes
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(lookups)
+	}
+	return stats
+}
+
+// Describe summarizes the settings for the startup log
+func (c *RecordCache) Describe() string {
+	tables := "every table"
+	if c.tables != nil {
+		names := make([]string, 0, len(c.tables))
+		for table := range c.tables {
+			names = append(names, table)
+		}
+		sort.Strings(names)
+		tables = strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s (up to %d MB, ttl %v)", tables, c.maxBytes>>20, c.ttl)
+}
+
+// SetRecordCache enables caching reads of single records. Handlers of several
+// bases or tenants may share one cache.
+func (p *ProxyHandler) SetRecordCache(cache *RecordCache) {
+	p.records = cache
+}
+
+// cachedRecord returns the record ID of a read the record cache answers, ""
+// for other requests. Tables with a cache block use the response cache.
+func (p *ProxyHandler) cachedRecord(method, tableKey string, route Route) string {
+	if method != http.MethodGet || !p.records.covers(tableKey) || p.cacheTTL(method, tableKey) > 0 {
+		return ""
+	}
+	if !route.IsRecord() {
+		return ""
+	}
+	return route.RecordID
+}
+
+// writtenRecord returns the record a successful write changed, "" when it is
+// not known (bulk writes)
+func writtenRecord(route Route, body []byte) string {
+	if route.RecordID != "" {
+		return route.RecordID
+	}
+	return recordIDFromBody(body)
+}
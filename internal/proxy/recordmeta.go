@@ -0,0 +1,135 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+// maxCommentBytes bounds the body of POST /proxy/{table}/{id}/comments
+const maxCommentBytes = 64 * 1024
+
+// recordMetaResources are the record sub-resources served from NocoDB's meta
+// API, with the methods each one accepts
+var recordMetaResources = map[string][]string{
+	"comments": {http.MethodGet, http.MethodPost},
+	"history":  {http.MethodGet},
+}
+
+// serveRecordMeta handles /proxy/{table}/{id}/comments (GET lists, POST adds
+// {"comment": "..."}) and GET /proxy/{table}/{id}/history. NocoDB serves both
+// through its meta API, keyed by table ID and row ID, on v2 and v3 instances
+// alike. Callers need to be able to read the record itself.
+func (p *ProxyHandler) serveRecordMeta(w http.ResponseWriter, r *http.Request, tableKey, recordID, resource string) {
+	allowed := false
+	for _, method := range recordMetaResources[resource] {
+		allowed = allowed || r.Method == method
+	}
+	if !allowed {
+		w.Header().Set("Allow", strings.Join(recordMetaResources[resource], ", "))
+		respondJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	tableID, table, err := p.resolveTable(http.MethodGet, tableKey)
+	if err != nil {
+		log.Printf("[PROXY] Cannot read %s of '%s': %v", resource, tableKey, err)
+		respondJSONError(w, http.StatusForbidden, "forbidden: "+err.Error())
+		return
+	}
+
+	// Reading the record runs the same rules, plugins and filters as GET .../records/{id}
+	check := httptest.NewRecorder()
+	read, err := http.NewRequestWithContext(context.WithValue(r.Context(), rawEnvelopeKey, true), http.MethodGet, "/proxy/"+tableKey+"/records/"+url.PathEscape(recordID), nil)
+	if err != nil {
+		respondJSONError(w, http.StatusBadRequest, "invalid record ID")
+		return
+	}
+	p.ServeHTTP(check, read)
+	if check.Code != http.StatusOK {
+		log.Printf("[PROXY] Refusing %s of %s/%s: reading the record returned %d", resource, tableKey, recordID, check.Code)
+		respondJSONError(w, check.Code, "cannot read record '"+recordID+"'")
+		return
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal(check.Body.Bytes(), &record); err != nil {
+		respondJSONError(w, http.StatusBadGateway, "unexpected record response from NocoDB")
+		return
+	}
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+	if !CanSeeRecord(table, role, userID, FlattenRecord(record)) {
+		respondJSONError(w, http.StatusNotFound, "record '"+recordID+"' not found")
+		return
+	}
+
+	metaURL := apiRoot(p.tablePrefix(tableKey)) + "/api/v2/meta/"
+	params := url.Values{}
+	params.Set("fk_model_id", tableID)
+	params.Set("row_id", recordID)
+	var body io.Reader
+	switch resource {
+	case "comments":
+		metaURL += "comments"
+		if r.Method == http.MethodPost {
+			var input struct {
+				Comment string `json:"comment"`
+			}
+			if err := json.NewDecoder(io.LimitReader(r.Body, maxCommentBytes)).Decode(&input); err != nil || strings.TrimSpace(input.Comment) == "" {
+				respondJSONError(w, http.StatusBadRequest, `expected {"comment": "..."}`)
+				return
+			}
+			// The row is taken from the path, never from the client's body
+			payload, _ := json.Marshal(map[string]string{"fk_model_id": tableID, "row_id": recordID, "comment": input.Comment})
+			body = bytes.NewReader(payload)
+			params = nil
+		}
+	case "history":
+		metaURL += "audits"
+		for _, name := range []string{"limit", "offset"} {
+			if value := r.URL.Query().Get(name); value != "" {
+				params.Set(name, value)
+			}
+		}
+	}
+	if params != nil {
+		metaURL += "?" + params.Encode()
+	}
+
+	upstreamReq, err := http.NewRequestWithContext(r.Context(), r.Method, metaURL, body)
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, "failed to create upstream request")
+		return
+	}
+	token, ok := p.roleToken(role, tableKey)
+	if !ok {
+		respondJSONError(w, http.StatusServiceUnavailable, "no NocoDB token is available for your role")
+		return
+	}
+	upstreamReq.Header.Set("xc-token", token)
+	if body != nil {
+		upstreamReq.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := p.doUpstream(upstreamClient, upstreamReq)
+	if err != nil {
+		log.Printf("[PROXY ERROR] Failed to fetch %s of %s/%s: %v", resource, tableKey, recordID, err)
+		respondJSONError(w, http.StatusBadGateway, "failed to reach NocoDB")
+		return
+	}
+	defer resp.Body.Close()
+
+	log.Printf("[PROXY] %s %s of %s/%s: NocoDB returned %d", r.Method, resource, tableKey, recordID, resp.StatusCode)
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
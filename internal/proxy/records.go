@@ -0,0 +1,219 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// UpstreamClient performs direct record queries against the NocoDB data API.
+// It is used by background subsystems (CDC, mirroring) that bypass the proxy pipeline.
+type UpstreamClient struct {
+	dialect     *Dialect
+	baseID      string
+	token       string
+	tokenSource TokenSource
+	failover    *Failover
+	routes      map[string]*UpstreamClient // table ID -> client of another upstream
+	httpClient  *http.Client
+}
+
+// NewUpstreamClient creates a new upstream record client
+func NewUpstreamClient(dataURL, baseID, token string) *UpstreamClient {
+	return &UpstreamClient{
+		dialect:    NewDialect(dataURL, InferAPIVersion(dataURL)),
+		baseID:     baseID,
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// isV3 reports whether the upstream speaks the v3 data API
+func (c *UpstreamClient) isV3() bool {
+	return c.dialect.IsV3()
+}
+
+// ListRecords fetches one page (1-based) of records from a table.
+// Records are normalized to flat maps; hasMore reports whether another page exists.
+func (c *UpstreamClient) ListRecords(ctx context.Context, tableID string, params url.Values, page, pageSize int) ([]map[string]interface{}, bool, error) {
+	if route, ok := c.routes[tableID]; ok {
+		return route.ListRecords(ctx, tableID, params, page, pageSize)
+	}
+
+	query := url.Values{}
+	for k, v := range params {
+		query[k] = v
+	}
+	if c.isV3() {
+		query.Set("page", strconv.Itoa(page))
+		query.Set("pageSize", strconv.Itoa(pageSize))
+	} else {
+		query.Set("limit", strconv.Itoa(pageSize))
+		query.Set("offset", strconv.Itoa((page-1)*pageSize))
+	}
+
+	target := c.dialect.RecordsURL(c.baseID, tableID) + "?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.failover.Rewrite(target), nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create records request: %w", err)
+	}
+	req.Header.Set("xc-token", c.currentToken())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to fetch records: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read records response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("records API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	records, hasMore, err := ParseRecordList(body)
+	if err != nil {
+		return nil, false, err
+	}
+	if !hasMore && len(records) == pageSize && c.isV3() {
+		hasMore = true
+	}
+	return records, hasMore, nil
+}
+
+// UpdateRecords sets fields on the records with the given IDs
+func (c *UpstreamClient) UpdateRecords(ctx context.Context, tableID string, ids []string, fields map[string]interface{}) error {
+	if route, ok := c.routes[tableID]; ok {
+		return route.UpdateRecords(ctx, tableID, ids, fields)
+	}
+	items := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		if c.isV3() {
+			items = append(items, map[string]interface{}{"id": recordKey(id), "fields": fields})
+			continue
+		}
+		item := map[string]interface{}{"Id": recordKey(id)}
+		for k, v := range fields {
+			item[k] = v
+		}
+		items = append(items, item)
+	}
+	return c.writeRecords(ctx, http.MethodPatch, tableID, items)
+}
+
+// DeleteRecords deletes the records with the given IDs
+func (c *UpstreamClient) DeleteRecords(ctx context.Context, tableID string, ids []string) error {
+	if route, ok := c.routes[tableID]; ok {
+		return route.DeleteRecords(ctx, tableID, ids)
+	}
+	key := "Id"
+	if c.isV3() {
+		key = "id"
+	}
+	items := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, map[string]interface{}{key: recordKey(id)})
+	}
+	return c.writeRecords(ctx, http.MethodDelete, tableID, items)
+}
+
+// writeRecords sends a bulk write to a table's records endpoint
+func (c *UpstreamClient) writeRecords(ctx context.Context, method, tableID string, items []map[string]interface{}) error {
+	body, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	target := c.dialect.RecordsURL(c.baseID, tableID)
+	req, err := http.NewRequestWithContext(ctx, method, c.failover.Rewrite(target), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create records request: %w", err)
+	}
+	req.Header.Set("xc-token", c.currentToken())
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to write records: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("records API returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// recordKey sends numeric record IDs as numbers, as NocoDB expects for its
+// auto-increment primary keys
+func recordKey(id string) interface{} {
+	if n, err := strconv.ParseInt(id, 10, 64); err == nil {
+		return n
+	}
+	return id
+}
+
+// ParseRecordList normalizes a NocoDB list response into flat records.
+// Handles v2 ({"list": [...], "pageInfo": {...}}) and v3 ({"records": [{"id", "fields"}], "next": ...}).
+func ParseRecordList(body []byte) ([]map[string]interface{}, bool, error) {
+	var raw struct {
+		List     []map[string]interface{} `json:"list"`
+		Records  []map[string]interface{} `json:"records"`
+		Next     *string                  `json:"next"`
+		PageInfo struct {
+			IsLastPage *bool `json:"isLastPage"`
+		} `json:"pageInfo"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, false, fmt.Errorf("failed to parse records JSON: %w", err)
+	}
+
+	if raw.Records != nil {
+		records := make([]map[string]interface{}, 0, len(raw.Records))
+		for _, rec := range raw.Records {
+			records = append(records, FlattenRecord(rec))
+		}
+		return records, raw.Next != nil && *raw.Next != "", nil
+	}
+
+	hasMore := raw.PageInfo.IsLastPage != nil && !*raw.PageInfo.IsLastPage
+	return raw.List, hasMore, nil
+}
+
+// FlattenRecord merges a v3 {"id": ..., "fields": {...}} record into a single map
+func FlattenRecord(rec map[string]interface{}) map[string]interface{} {
+	fields, ok := rec["fields"].(map[string]interface{})
+	if !ok {
+		return rec
+	}
+	flat := make(map[string]interface{}, len(fields)+1)
+	for k, v := range fields {
+		flat[k] = v
+	}
+	if id, ok := rec["id"]; ok {
+		flat["id"] = id
+	}
+	return flat
+}
+
+// RecordID extracts the primary key value from a NocoDB row
+func RecordID(row map[string]interface{}) string {
+	for _, key := range []string{"Id", "id", "ID"} {
+		if v, ok := row[key]; ok && v != nil {
+			switch id := v.(type) {
+			case float64:
+				return strconv.FormatFloat(id, 'f', -1, 64)
+			default:
+				return fmt.Sprintf("%v", id)
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReplicaStatus reports the health of the read replica and how reads were served
+type ReplicaStatus struct {
+	Active    bool           `json:"active"` // reads are currently sent to the replica
+	Replica   UpstreamHealth `json:"replica"`
+	Reads     int            `json:"reads"`
+	Fallbacks int            `json:"fallbacks"`
+}
+
+// ReadReplica sends GET traffic to a read replica (a NocoDB instance backed by a
+// replicated database) while it is healthy. Writes always go to the primary.
+// The replica must expose the same API paths as the primary.
+type ReadReplica struct {
+	primaryOrigin string
+	replicaOrigin string
+	healthPath    string
+	interval      time.Duration
+	httpClient    *http.Client
+
+	mu        sync.RWMutex
+	health    UpstreamHealth
+	reads     int
+	fallbacks int
+}
+
+// NewReadReplica creates a read replica router for the given primary and replica URLs
+func NewReadReplica(primaryURL, replicaURL, healthPath string, interval time.Duration) (*ReadReplica, error) {
+	primaryOrigin, err := origin(primaryURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid primary URL: %w", err)
+	}
+	replicaOrigin, err := origin(replicaURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid replica URL: %w", err)
+	}
+
+	return &ReadReplica{
+		primaryOrigin: primaryOrigin,
+		replicaOrigin: replicaOrigin,
+		healthPath:    "/" + strings.TrimLeft(healthPath, "/"),
+		interval:      interval,
+		httpClient:    &http.Client{Timeout: 5 * time.Second},
+		health:        UpstreamHealth{URL: replicaOrigin, Healthy: true},
+	}, nil
+}
+
+// rewrite points a URL on the primary instance at the replica. It returns false if
+// the replica is unhealthy or the URL does not target the primary.
+func (rr *ReadReplica) rewrite(target string) (string, bool) {
+	if rr == nil {
+		return "", false
+	}
+	rr.mu.RLock()
+	healthy := rr.health.Healthy
+	rr.mu.RUnlock()
+
+	if !healthy || !strings.HasPrefix(target, rr.primaryOrigin) {
+		return "", false
+	}
+	return rr.replicaOrigin + strings.TrimPrefix(target, rr.primaryOrigin), true
+}
+
+// Start runs health checks until the context is cancelled
+func (rr *ReadReplica) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(rr.interval)
+		defer ticker.Stop()
+		for {
+			err := probeHealth(ctx, rr.httpClient, rr.replicaOrigin+rr.healthPath)
+			rr.mu.Lock()
+			wasHealthy := rr.health.Healthy
+			record(&rr.health, err)
+			if wasHealthy != rr.health.Healthy {
+				if rr.health.Healthy {
+					log.Printf("[REPLICA] Replica %s healthy again, sending reads to it", rr.replicaOrigin)
+				} else {
+					log.Printf("[REPLICA] Replica %s unhealthy (%s), sending reads to the primary", rr.replicaOrigin, rr.health.LastError)
+				}
+			}
+			rr.mu.Unlock()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Status returns the current replica state
+func (rr *ReadReplica) Status() ReplicaStatus {
+	rr.mu.RLock()
+	defer rr.mu.RUnlock()
+	return ReplicaStatus{
+		Active:    rr.health.Healthy,
+		Replica:   rr.health,
+		Reads:     rr.reads,
+		Fallbacks: rr.fallbacks,
+	}
+}
+
+// SetReadReplica sends proxied GET requests to a read replica
+func (p *ProxyHandler) SetReadReplica(replica *ReadReplica) {
+	p.replica = replica
+}
+
+// doUpstream executes a proxied request. Reads go to the read replica when it is
+// healthy and are retried against the primary if the replica fails.
+func (p *ProxyHandler) doUpstream(client *http.Client, req *http.Request) (*http.Response, error) {
+	defer trackUpstream(req.Context(), time.Now())
+	if req.Method != http.MethodGet {
+		return client.Do(req)
+	}
+	replicaURL, ok := p.replica.rewrite(req.URL.String())
+	if !ok {
+		return client.Do(req)
+	}
+
+	target, err := url.Parse(replicaURL)
+	if err != nil {
+		return client.Do(req)
+	}
+	replicaReq := req.Clone(req.Context())
+	replicaReq.URL = target
+	replicaReq.Host = ""
+
+	resp, err := client.Do(replicaReq)
+	if err == nil && !isUpstreamOutage(resp.StatusCode) {
+		p.replica.mu.Lock()
+		p.replica.reads++
+		p.replica.mu.Unlock()
+		return resp, nil
+	}
+	if err == nil {
+		resp.Body.Close()
+		err = fmt.Errorf("replica returned status %d", resp.StatusCode)
+	}
+
+	// Count the failure towards the replica's health so persistent errors stop routing reads to it
+	p.replica.mu.Lock()
+	p.replica.fallbacks++
+	record(&p.replica.health, err)
+	p.replica.mu.Unlock()
+	log.Printf("[REPLICA] Read from replica failed (%v), falling back to primary", err)
+
+	return client.Do(req)
+}
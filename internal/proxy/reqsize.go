@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+)
+
+// LimitRequestBody refuses /proxy/ requests whose body is larger than maxBytes
+// with 413, whatever their table (0: no limit). The body is read here, so
+// that the stages after it never see more than the limit.
+func LimitRequestBody(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if maxBytes <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := readLimitedBody(w, r, maxBytes); err != nil {
+				log.Printf("[PROXY] Refused %s %s: %v", r.Method, r.URL.Path, err)
+				status := http.StatusBadRequest
+				if errors.Is(err, errRequestTooLarge) {
+					status = http.StatusRequestEntityTooLarge
+				}
+				respondJSONError(w, status, err.Error())
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// errRequestTooLarge is returned for request bodies beyond a limit
+var errRequestTooLarge = errors.New("request body too large")
+
+// readLimitedBody reads a request body of at most maxBytes and puts it back
+// for the handlers after it. A body declared or found to be larger is an
+// errRequestTooLarge; the connection is then closed after the answer.
+func readLimitedBody(w http.ResponseWriter, r *http.Request, maxBytes int64) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	tooLarge := fmt.Errorf("%w: at most %s allowed", errRequestTooLarge, formatKB(maxBytes))
+	if r.ContentLength > maxBytes {
+		return tooLarge
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return tooLarge
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return nil
+}
+
+// limitTableBody applies the table's max_request_kb (or the top-level one) to
+// a request, and answers 413 when its body is larger. It returns false when
+// it answered the request.
+func (p *ProxyHandler) limitTableBody(w http.ResponseWriter, r *http.Request, route Route) bool {
+	if p.ResolvedConfig == nil {
+		return true
+	}
+	limitKB := p.ResolvedConfig.MaxRequest
+	if table, ok := p.ResolvedConfig.Tables[route.Table]; ok {
+		limitKB = table.MaxRequest
+	}
+	if limitKB == 0 {
+		return true
+	}
+	err := readLimitedBody(w, r, int64(limitKB)<<10)
+	switch {
+	case errors.Is(err, errRequestTooLarge):
+		log.Printf("[PROXY] Refused %s %s: %v", r.Method, r.URL.Path, err)
+		p.httpError(w, r, route.Table, http.StatusRequestEntityTooLarge, err.Error())
+		return false
+	case err != nil:
+		log.Printf("[PROXY ERROR] %v", err)
+		p.httpError(w, r, route.Table, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
+// formatKB writes a byte count in KB, or in MB when it is a whole number of them
+func formatKB(n int64) string {
+	if n >= 1<<20 && n%(1<<20) == 0 {
+		return fmt.Sprintf("%d MB", n>>20)
+	}
+	return fmt.Sprintf("%d KB", (n+1023)>>10)
+}
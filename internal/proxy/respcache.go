@@ -0,0 +1,330 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/events"
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+const (
+	defaultResponseTTL   = 60 * time.Second
+	maxResponseEntries   = 10000
+	cacheInvalidateQueue = 1000
+)
+
+// ResponseCacheStats reports the response cache's size and effectiveness
+type ResponseCacheStats struct {
+	Entries       int  `json:"entries"`
+	Hits          int  `json:"hits"`
+	Misses        int  `json:"misses"`
+	Invalidations int  `json:"invalidations"`
+	Shared        bool `json:"shared,omitempty"` // responses are shared through a ResponseStore
+}
+
+// ResponseStore shares cached responses between gateway instances. Every table
+// has a generation that invalidations increment; entries are only readable in
+// the generation they were stored in, so one write hides them on every instance.
+type ResponseStore interface {
+	// Get returns the table's generation and the entry stored for key in it (nil if none)
+	Get(ctx context.Context, tableID, key string) (uint64, []byte, error)
+	// Set stores an entry for ttl unless the table moved past generation
+	Set(ctx context.Context, tableID, key string, generation uint64, data []byte, ttl time.Duration) error
+	// Invalidate starts a new generation of the table and tells other instances
+	Invalidate(ctx context.Context, tableID string) error
+	// Watch calls fn with the table of every invalidation made by another
+	// instance until ctx is cancelled
+	Watch(ctx context.Context, fn func(tableID string)) error
+}
+
+type cachedResponse struct {
+	tableID string
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// sharedResponse is the encoding of a cachedResponse in a ResponseStore
+type sharedResponse struct {
+	Status  int         `json:"status"`
+	Header  http.Header `json:"header"`
+	Body    []byte      `json:"body"`
+	Expires time.Time   `json:"expires"`
+}
+
+// responseVersion is read before a response is fetched and compared when it is
+// stored, so a response fetched while its table was written to is dropped
+type responseVersion struct {
+	local  uint64 // invalidations seen by this instance
+	shared uint64 // generation in the ResponseStore
+	stored bool   // the generation could be read, so the store may be written
+}
+
+// ResponseCache keeps NocoDB's answers to GET requests on tables with a cache
+// block in proxy.yaml. Entries are keyed by the upstream URL, which carries
+// everything that makes a read differ between users (filters added by rules,
+// the tenant's base), and are dropped when their table is written to. With a
+// ResponseStore the memory entries are a local copy of the shared ones.
+type ResponseCache struct {
+	store     ResponseStore // nil when responses stay in this instance
+	mu        sync.Mutex
+	entries   map[string]cachedResponse  // upstream URL -> response
+	tables    map[string]map[string]bool // table ID -> upstream URLs
+	versions  map[string]uint64          // table ID -> invalidations so far
+	listeners []func(tableID string)
+	stats     ResponseCacheStats
+}
+
+// NewResponseCache creates an empty response cache
+func NewResponseCache() *ResponseCache {
+	return &ResponseCache{
+		entries:  make(map[string]cachedResponse),
+		tables:   make(map[string]map[string]bool),
+		versions: make(map[string]uint64),
+	}
+}
+
+// SetStore shares the cached responses with other instances through store
+func (c *ResponseCache) SetStore(store ResponseStore) {
+	c.store = store
+}
+
+// lookup returns the cached response of url, and the version to pass to put
+// when the response is fetched instead. Refreshes skip the lookup.
+func (c *ResponseCache) lookup(ctx context.Context, tableID, url string, refresh bool) (cachedResponse, responseVersion, bool) {
+	now := time.Now()
+	c.mu.Lock()
+	version := responseVersion{local: c.versions[tableID]}
+	if entry, ok := c.entries[url]; ok && !refresh && now.Before(entry.expires) {
+		c.stats.Hits++
+		c.mu.Unlock()
+		return entry, version, true
+	}
+	c.mu.Unlock()
+
+	var data []byte
+	if c.store != nil {
+		storeCtx, cancel := context.WithTimeout(ctx, storeTimeout)
+		generation, stored, err := c.store.Get(storeCtx, tableID, url)
+		cancel()
+		if err != nil {
+			log.Printf("[CACHE WARN] Shared response cache unavailable: %v", err)
+		} else {
+			version.shared, version.stored = generation, true
+			data = stored
+		}
+	}
+	if refresh {
+		return cachedResponse{}, version, false
+	}
+
+	var shared sharedResponse
+	if data != nil && json.Unmarshal(data, &shared) == nil && now.Before(shared.Expires) {
+		entry := cachedResponse{tableID: tableID, status: shared.Status, header: shared.Header, body: shared.Body, expires: shared.Expires}
+		c.mu.Lock()
+		if c.versions[tableID] == version.local {
+			c.keep(url, entry)
+		}
+		c.stats.Hits++
+		c.mu.Unlock()
+		return entry, version, true
+	}
+	c.mu.Lock()
+	c.stats.Misses++
+	c.mu.Unlock()
+	return cachedResponse{}, version, false
+}
+
+// put stores a fetched response, locally and in the shared store
+func (c *ResponseCache) put(ctx context.Context, tableID, url string, version responseVersion, ttl time.Duration, status int, header http.Header, body []byte) {
+	entry := cachedResponse{tableID: tableID, status: status, header: header.Clone(), body: body, expires: time.Now().Add(ttl)}
+	c.mu.Lock()
+	if c.versions[tableID] != version.local {
+		c.mu.Unlock()
+		return
+	}
+	c.keep(url, entry)
+	c.mu.Unlock()
+
+	if c.store == nil || !version.stored {
+		return
+	}
+	data, err := json.Marshal(sharedResponse{Status: status, Header: entry.header, Body: body, Expires: entry.expires})
+	if err != nil {
+		return
+	}
+	storeCtx, cancel := context.WithTimeout(ctx, storeTimeout)
+	defer cancel()
+	if err := c.store.Set(storeCtx, tableID, url, version.shared, data, ttl); err != nil {
+		log.Printf("[CACHE WARN] Failed to share cached response: %v", err)
+	}
+}
+
+// keep adds an entry to memory; the caller holds the lock
+func (c *ResponseCache) keep(url string, entry cachedResponse) {
+	if len(c.entries) >= maxResponseEntries {
+		now := time.Now()
+		for key, old := range c.entries {
+			if now.After(old.expires) {
+				c.remove(key)
+			}
+		}
+		// Every entry is live: start over rather than track recency
+		if len(c.entries) >= maxResponseEntries {
+			c.entries = make(map[string]cachedResponse)
+			c.tables = make(map[string]map[string]bool)
+		}
+	}
+	c.entries[url] = entry
+	if c.tables[entry.tableID] == nil {
+		c.tables[entry.tableID] = make(map[string]bool)
+	}
+	c.tables[entry.tableID][url] = true
+}
+
+// remove drops one entry; the caller holds the lock
+func (c *ResponseCache) remove(url string) {
+	entry := c.entries[url]
+	delete(c.entries, url)
+	if urls := c.tables[entry.tableID]; urls != nil {
+		delete(urls, url)
+		if len(urls) == 0 {
+			delete(c.tables, entry.tableID)
+		}
+	}
+}
+
+// Invalidate drops the cached responses of a table, on every instance when
+// they are shared, and notifies the listeners
+func (c *ResponseCache) Invalidate(tableID string) {
+	if c == nil || tableID == "" {
+		return
+	}
+	c.drop(tableID)
+	if c.store != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+		if err := c.store.Invalidate(ctx, tableID); err != nil {
+			log.Printf("[CACHE WARN] Failed to invalidate shared responses of table %s: %v", tableID, err)
+		}
+		cancel()
+	}
+
+	c.mu.Lock()
+	listeners := c.listeners
+	c.mu.Unlock()
+	for _, listener := range listeners {
+		listener(tableID)
+	}
+}
+
+// drop removes the memory entries of a table
+func (c *ResponseCache) drop(tableID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for url := range c.tables[tableID] {
+		delete(c.entries, url)
+	}
+	delete(c.tables, tableID)
+	c.versions[tableID]++
+	c.stats.Invalidations++
+}
+
+// OnInvalidate registers a function called after a table's entries were dropped
+func (c *ResponseCache) OnInvalidate(fn func(tableID string)) {
+	c.mu.Lock()
+	c.listeners = append(c.listeners, fn)
+	c.mu.Unlock()
+}
+
+// Watch invalidates tables on record events from the bus, so writes that did
+// not pass through this handler (CDC, NocoDB webhooks, other bases) are seen.
+// With a store, the memory copies of tables invalidated by other instances are
+// dropped too; those instances refetch their warm queries themselves.
+func (c *ResponseCache) Watch(ctx context.Context, bus *events.Bus) {
+	if c == nil {
+		return
+	}
+	if c.store != nil {
+		if err := c.store.Watch(ctx, c.drop); err != nil {
+			log.Printf("[CACHE WARN] Cannot watch shared invalidations, memory copies live until they expire: %v", err)
+		}
+	}
+	if bus == nil {
+		return
+	}
+	// A full queue invalidates right away rather than serve stale entries
+	ch, cancel := bus.SubscribeOverflow(cacheInvalidateQueue, func(event events.Event) {
+		switch event.Type {
+		case events.TypeRecordCreated, events.TypeRecordUpdated, events.TypeRecordDeleted:
+			c.Invalidate(event.TableID)
+		}
+	})
+	go func() {
+		defer cancel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-ch:
+				if !ok {
+					return
+				}
+				switch event.Type {
+				case events.TypeRecordCreated, events.TypeRecordUpdated, events.TypeRecordDeleted:
+					c.Invalidate(event.TableID)
+				}
+			}
+		}
+	}()
+}
+
+// Stats returns the current size and counters
+func (c *ResponseCache) Stats() ResponseCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	stats.Shared = c.store != nil
+	return stats
+}
+
+// SetResponseCache enables caching the reads of tables with a cache block.
+// Handlers of several bases or tenants may share one cache.
+func (p *ProxyHandler) SetResponseCache(cache *ResponseCache) {
+	p.responses = cache
+}
+
+// cacheKey returns the key of a read in the response and record caches: the
+// NocoDB URL, which carries the table, the query and the filters added by
+// rules. Roles sent with a NocoDB token of their own get entries of their own,
+// since their token may not see what the default token sees.
+func (p *ProxyHandler) cacheKey(r *http.Request, targetURL string) string {
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+	if _, mapped := p.roleTokens[role]; mapped {
+		return "role=" + role + " " + targetURL
+	}
+	return targetURL
+}
+
+// cacheTTL returns how long a response to the request may be cached, 0 when
+// it may not
+func (p *ProxyHandler) cacheTTL(method, tableKey string) time.Duration {
+	if p.responses == nil || method != http.MethodGet || p.ResolvedConfig == nil {
+		return 0
+	}
+	table, ok := p.ResolvedConfig.Tables[tableKey]
+	if !ok || table.Cache == nil {
+		return 0
+	}
+	if table.Cache.TTL == "" {
+		return defaultResponseTTL
+	}
+	ttl, _ := time.ParseDuration(table.Cache.TTL) // validated by the config loader
+	return ttl
+}
@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// responseGetScript reads a table's generation and the entry stored in it
+var responseGetScript = redis.NewScript(`
+local generation = redis.call("GET", KEYS[1]) or "0"
+return {generation, redis.call("GET", ARGV[1] .. generation .. ":" .. ARGV[2])}
+`)
+
+// responseSetScript stores an entry only while its generation is current, so a
+// response fetched during a write on another instance is not shared
+var responseSetScript = redis.NewScript(`
+if (redis.call("GET", KEYS[1]) or "0") ~= ARGV[1] then
+	return 0
+end
+redis.call("SET", KEYS[2], ARGV[2], "PX", ARGV[3])
+return 1
+`)
+
+// RedisResponseStore shares cached responses between gateway instances through
+// Redis: entries are keys that expire with their TTL, generations are counters,
+// and invalidations are also published so instances drop their memory copies
+type RedisResponseStore struct {
+	client     *redis.Client
+	prefix     string
+	channel    string
+	instanceID string
+}
+
+// NewRedisResponseStore creates a response store. prefix namespaces the keys, e.g. "nocodb-gateway:responses:".
+func NewRedisResponseStore(client *redis.Client, prefix string) *RedisResponseStore {
+	host, _ := os.Hostname()
+	return &RedisResponseStore{
+		client:     client,
+		prefix:     prefix,
+		channel:    prefix + "invalidate",
+		instanceID: fmt.Sprintf("%s-%d-%d", host, os.Getpid(), rand.Int63()),
+	}
+}
+
+func (s *RedisResponseStore) generationKey(tableID string) string {
+	return s.prefix + tableID + ":generation"
+}
+
+// entryPrefix is completed with the generation, a colon and the hashed key
+func (s *RedisResponseStore) entryPrefix(tableID string) string {
+	return s.prefix + tableID + ":"
+}
+
+// hashKey keeps long upstream URLs out of Redis key names
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns the table's generation and the entry stored for key (nil if none)
+func (s *RedisResponseStore) Get(ctx context.Context, tableID, key string) (uint64, []byte, error) {
+	result, err := responseGetScript.Run(ctx, s.client, []string{s.generationKey(tableID)}, s.entryPrefix(tableID), hashKey(key)).Slice()
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read cached response from Redis: %w", err)
+	}
+	if len(result) != 2 {
+		return 0, nil, fmt.Errorf("unexpected reply from Redis: %v", result)
+	}
+	generationText, _ := result[0].(string)
+	generation, err := strconv.ParseUint(generationText, 10, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid cache generation '%v' in Redis", result[0])
+	}
+	data, _ := result[1].(string)
+	if data == "" {
+		return generation, nil, nil
+	}
+	return generation, []byte(data), nil
+}
+
+// Set stores an entry unless the table was invalidated since generation was read
+func (s *RedisResponseStore) Set(ctx context.Context, tableID, key string, generation uint64, data []byte, ttl time.Duration) error {
+	entryKey := s.entryPrefix(tableID) + strconv.FormatUint(generation, 10) + ":" + hashKey(key)
+	err := responseSetScript.Run(ctx, s.client, []string{s.generationKey(tableID), entryKey}, generation, data, ttl.Milliseconds()).Err()
+	if err != nil {
+		return fmt.Errorf("failed to write cached response to Redis: %w", err)
+	}
+	return nil
+}
+
+// Invalidate starts a new generation of the table, which hides the stored
+// entries until they expire, and publishes the table to other instances
+func (s *RedisResponseStore) Invalidate(ctx context.Context, tableID string) error {
+	if err := s.client.Incr(ctx, s.generationKey(tableID)).Err(); err != nil {
+		return err
+	}
+	return s.client.Publish(ctx, s.channel, s.instanceID+" "+tableID).Err()
+}
+
+// Watch subscribes to invalidations and calls fn for those of other instances
+// in the background
+func (s *RedisResponseStore) Watch(ctx context.Context, fn func(tableID string)) error {
+	pubsub := s.client.Subscribe(ctx, s.channel)
+	// Wait for the subscription to be confirmed so errors surface to the caller
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return fmt.Errorf("failed to subscribe to %s: %w", s.channel, err)
+	}
+
+	go func() {
+		defer pubsub.Close()
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					log.Printf("[CACHE WARN] Redis invalidation channel %s closed", s.channel)
+					return
+				}
+				instanceID, tableID, found := strings.Cut(msg.Payload, " ")
+				if found && instanceID != s.instanceID {
+					fn(tableID)
+				}
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// errResponseTooLarge is returned for upstream responses beyond a table's max_response_mb
+var errResponseTooLarge = errors.New("upstream response too large")
+
+// readUpstreamBody reads a NocoDB response, refusing it once it grows beyond
+// the table's max_response_mb so a huge page cannot exhaust the gateway's memory.
+// A Content-Length beyond the limit is refused without reading the body.
+func (p *ProxyHandler) readUpstreamBody(resp *http.Response, tableKey string) ([]byte, error) {
+	if resp.Request != nil {
+		defer trackUpstream(resp.Request.Context(), time.Now())
+	}
+	var limitMB int
+	if p.ResolvedConfig != nil {
+		if table, ok := p.ResolvedConfig.Tables[tableKey]; ok {
+			limitMB = table.MaxResponse
+		}
+	}
+	if limitMB == 0 {
+		return io.ReadAll(resp.Body)
+	}
+
+	limit := int64(limitMB) << 20
+	tooLarge := fmt.Errorf("%w: table '%s' allows at most %d MB", errResponseTooLarge, tableKey, limitMB)
+	if resp.ContentLength > limit {
+		return nil, tooLarge
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, tooLarge
+	}
+	return body, nil
+}
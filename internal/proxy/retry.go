@@ -0,0 +1,172 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+const (
+	defaultRetryAttempts   = 3
+	defaultRetryBackoff    = 100 * time.Millisecond
+	defaultRetryMaxBackoff = 2 * time.Second
+)
+
+// defaultRetryStatus are the NocoDB statuses retried when a policy lists none
+var defaultRetryStatus = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// idempotentMethods may be sent to NocoDB again without changing the outcome
+var idempotentMethods = map[string]bool{http.MethodGet: true, http.MethodPut: true, http.MethodDelete: true}
+
+// retryPolicy is a table's retry block with its defaults applied
+type retryPolicy struct {
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	status     []int
+}
+
+// retryPolicy returns the retry policy of a request, nil when it is not retried
+func (p *ProxyHandler) retryPolicy(method, tableKey string) *retryPolicy {
+	if !idempotentMethods[method] || p.ResolvedConfig == nil {
+		return nil
+	}
+	table, ok := p.ResolvedConfig.Tables[tableKey]
+	if !ok || table.Retry == nil {
+		return nil
+	}
+	return newRetryPolicy(table.Retry)
+}
+
+func newRetryPolicy(cfg *config.RetryConfig) *retryPolicy {
+	policy := &retryPolicy{attempts: cfg.Attempts, backoff: defaultRetryBackoff, maxBackoff: defaultRetryMaxBackoff, status: cfg.Status}
+	if policy.attempts == 0 {
+		policy.attempts = defaultRetryAttempts
+	}
+	// Validated by the config loader
+	if cfg.Backoff != "" {
+		policy.backoff, _ = time.ParseDuration(cfg.Backoff)
+	}
+	if cfg.MaxBackoff != "" {
+		policy.maxBackoff, _ = time.ParseDuration(cfg.MaxBackoff)
+	}
+	if len(policy.status) == 0 {
+		policy.status = defaultRetryStatus
+	}
+	if policy.attempts < 2 {
+		return nil
+	}
+	return policy
+}
+
+// retryable reports whether a failed try is worth repeating: NocoDB answered
+// with a listed status, or could not be reached. A spent latency budget or a
+// client that went away is not retried.
+func (r *retryPolicy) retryable(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+	for _, status := range r.status {
+		if resp.StatusCode == status {
+			return true
+		}
+	}
+	return false
+}
+
+// wait returns the pause before the next try: the backoff doubled for every
+// try made, capped and jittered so that gateways do not retry in step. A
+// Retry-After from NocoDB is honored up to the cap.
+func (r *retryPolicy) wait(attempt int, resp *http.Response) time.Duration {
+	wait := r.backoff << (attempt - 1)
+	if wait > r.maxBackoff || wait <= 0 {
+		wait = r.maxBackoff
+	}
+	wait = wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			if after := time.Duration(seconds) * time.Second; after > wait {
+				wait = min(after, r.maxBackoff)
+			}
+		}
+	}
+	return wait
+}
+
+// doUpstreamRetried sends a request to NocoDB, and again under the table's
+// retry policy while it fails transiently and the circuit breaker allows it.
+// The body is kept to be sent again. The last response or error is returned.
+// The first try must have been allowed by the breaker.
+func (p *ProxyHandler) doUpstreamRetried(client *http.Client, req *http.Request, tableKey string) (*http.Response, error) {
+	policy := p.retryPolicy(req.Method, tableKey)
+	if policy == nil {
+		return p.doUpstreamTracked(client, req)
+	}
+
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		buffered, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			p.breaker.Release(hostAddr(req.URL))
+			return nil, err
+		}
+		body = buffered
+	}
+
+	for attempt := 1; ; attempt++ {
+		if body != nil {
+			req.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		resp, err := p.doUpstreamTracked(client, req)
+		if attempt >= policy.attempts || !policy.retryable(req, resp, err) {
+			return resp, err
+		}
+		if allowed, _ := p.breaker.Allow(hostAddr(req.URL)); !allowed {
+			log.Printf("[PROXY] Not retrying %s %s: the circuit breaker of %s is open", req.Method, req.URL.Path, hostAddr(req.URL))
+			return resp, err
+		}
+
+		wait := policy.wait(attempt, resp)
+		reason := "no connection"
+		if err == nil {
+			reason = "status " + strconv.Itoa(resp.StatusCode)
+			// Drained, so the connection can be reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		log.Printf("[PROXY] %s %s failed (%s); try %d of %d in %v", req.Method, req.URL.Path, reason, attempt+1, policy.attempts, wait.Round(time.Millisecond))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			p.breaker.Release(hostAddr(req.URL))
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// doUpstreamTracked sends one try to NocoDB and reports its outcome to the
+// circuit breaker. Tries cut short by the client or its latency budget say
+// nothing about NocoDB and are not counted.
+func (p *ProxyHandler) doUpstreamTracked(client *http.Client, req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := p.doUpstream(client, req)
+	switch {
+	case err != nil && req.Context().Err() != nil:
+		p.breaker.Release(hostAddr(req.URL))
+	case err != nil:
+		p.breaker.Record(hostAddr(req.URL), err, 0, time.Since(start))
+	default:
+		p.breaker.Record(hostAddr(req.URL), nil, resp.StatusCode, time.Since(start))
+	}
+	return resp, err
+}
@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Kinds of paths below /proxy/
+const (
+	RouteTable      = "table"       // {table}
+	RouteRecords    = "records"     // {table}/records and {table}/records/{id}
+	RouteCount      = "count"       // {table}/records/count
+	RouteLinks      = "links"       // {table}/links/{field}/{id}
+	RouteEvents     = "events"      // {table}/events
+	RouteChanges    = "changes"     // {table}/changes
+	RouteRecordMeta = "record_meta" // {table}/{id}/comments and {table}/{id}/history
+	RouteLinked     = "linked"      // {table}/{id}/linked/{alias}
+	RouteBatch      = "batch"       // _batch
+	RouteOther      = "other"       // anything else, passed to NocoDB as it is
+)
+
+// Route is a path below /proxy/, parsed once: the table it addresses, the
+// record, the link field of link operations and the sub-resource. Every part
+// of the gateway that looks at paths (validation, authorization, caches,
+// envelopes, events) reads it instead of splitting the path itself.
+type Route struct {
+	Kind      string
+	Table     string // proxy.yaml table key (table name in legacy mode)
+	RecordID  string // records/{id}, the record of a link operation, of linked records or of comments and history
+	LinkField string // link field alias, as sent by the client
+	Resource  string // comments or history
+	Segments  []string
+}
+
+// ParseRoute parses a path below /proxy/, as sent by the client
+func ParseRoute(path string) Route {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	route := Route{Kind: RouteOther, Table: segments[0], Segments: segments}
+
+	switch n := len(segments); {
+	case n == 1 && segments[0] == "_batch":
+		route.Kind, route.Table = RouteBatch, ""
+	case n == 1:
+		route.Kind = RouteTable
+	case segments[1] == "records" && n == 2:
+		route.Kind = RouteRecords
+	case segments[1] == "records" && n == 3 && segments[2] == "count":
+		route.Kind = RouteCount
+	case segments[1] == "records" && n == 3:
+		route.Kind, route.RecordID = RouteRecords, segments[2]
+	case segments[1] == "links" && n >= 4:
+		route.Kind, route.LinkField, route.RecordID = RouteLinks, segments[2], segments[3]
+	case n == 2 && (segments[1] == "events" || segments[1] == "changes"):
+		route.Kind = segments[1]
+	case n == 4 && segments[2] == "linked" && segments[1] != "links" && segments[1] != "records":
+		route.Kind, route.RecordID, route.LinkField = RouteLinked, segments[1], segments[3]
+	case n == 3 && segments[1] != "links":
+		if _, ok := recordMetaResources[segments[2]]; ok {
+			route.Kind, route.RecordID, route.Resource = RouteRecordMeta, segments[1], segments[2]
+		}
+	}
+	return route
+}
+
+// Rest returns the segments below the table
+func (r Route) Rest() []string {
+	if len(r.Segments) < 2 {
+		return nil
+	}
+	return r.Segments[1:]
+}
+
+// IsList reports whether a read of the route returns a list of records
+func (r Route) IsList() bool {
+	return (r.Kind == RouteRecords && r.RecordID == "") || r.Kind == RouteLinks || r.Kind == RouteLinked
+}
+
+// IsRecord reports whether the route addresses one record of the table
+func (r Route) IsRecord() bool {
+	return r.Kind == RouteRecords && r.RecordID != ""
+}
+
+// Operation returns the proxy.yaml operation a request asks for: writes to
+// links/{field}/{id} are link operations, whatever their method
+func (r Route) Operation(method string) string {
+	switch method {
+	case http.MethodGet:
+		return "read"
+	case http.MethodPost, http.MethodDelete:
+		if r.Kind == RouteLinks {
+			return "link"
+		}
+		if method == http.MethodPost {
+			return "create"
+		}
+		return "delete"
+	case http.MethodPatch, http.MethodPut:
+		return "update"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,210 @@
+package proxy
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/events"
+)
+
+// maxSchemaDiffs is the number of schema diffs kept for /__proxy/schema/changes
+const maxSchemaDiffs = 50
+
+// SourceMetaCache identifies events emitted by the metadata cache
+const SourceMetaCache = "metacache"
+
+// Schema change kinds
+const (
+	ChangeTableAdded       = "table_added"
+	ChangeTableRemoved     = "table_removed"
+	ChangeTableRenamed     = "table_renamed"
+	ChangeFieldAdded       = "field_added"
+	ChangeFieldRemoved     = "field_removed"
+	ChangeFieldRenamed     = "field_renamed"
+	ChangeFieldTypeChanged = "field_type_changed"
+)
+
+// TableSchema is the part of a table's metadata that schema diffs are computed over
+type TableSchema struct {
+	Title  string               `json:"title"`
+	Fields map[string]FieldMeta `json:"fields"` // field ID -> field
+}
+
+// SchemaChange is a single difference between two schema versions
+type SchemaChange struct {
+	Kind     string `json:"kind"`
+	TableID  string `json:"table_id"`
+	Table    string `json:"table"`
+	FieldID  string `json:"field_id,omitempty"`
+	Field    string `json:"field,omitempty"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	Breaking bool   `json:"breaking"` // may break clients using the old schema
+}
+
+// SchemaDiff is the set of changes found by one refresh
+type SchemaDiff struct {
+	DetectedAt   time.Time      `json:"detected_at"`
+	PreviousHash string         `json:"previous_hash"`
+	Hash         string         `json:"hash"`
+	Breaking     bool           `json:"breaking"`
+	Changes      []SchemaChange `json:"changes"`
+}
+
+// buildSchema collects table titles and field types from the table list and details
+func buildSchema(tables []TableMeta, details map[string][]FieldMeta) map[string]TableSchema {
+	schema := make(map[string]TableSchema, len(tables))
+	for _, table := range tables {
+		fields := make(map[string]FieldMeta)
+		for _, field := range table.Columns {
+			fields[field.ID] = field
+		}
+		// Detailed metadata is more complete (link fields, v3 types)
+		for _, field := range details[table.ID] {
+			fields[field.ID] = field
+		}
+		schema[table.ID] = TableSchema{Title: table.Title, Fields: fields}
+	}
+	return schema
+}
+
+// DiffSchemas lists the tables and fields added, removed, renamed or retyped between two schemas
+func DiffSchemas(previous, current map[string]TableSchema) []SchemaChange {
+	var changes []SchemaChange
+
+	for tableID, old := range previous {
+		table, ok := current[tableID]
+		if !ok {
+			changes = append(changes, SchemaChange{Kind: ChangeTableRemoved, TableID: tableID, Table: old.Title, Breaking: true})
+			continue
+		}
+		if table.Title != old.Title {
+			changes = append(changes, SchemaChange{Kind: ChangeTableRenamed, TableID: tableID, Table: table.Title, From: old.Title, To: table.Title, Breaking: true})
+		}
+
+		for fieldID, oldField := range old.Fields {
+			field, ok := table.Fields[fieldID]
+			switch {
+			case !ok:
+				changes = append(changes, SchemaChange{Kind: ChangeFieldRemoved, TableID: tableID, Table: table.Title, FieldID: fieldID, Field: oldField.Title, Breaking: true})
+			case field.Title != oldField.Title:
+				changes = append(changes, SchemaChange{Kind: ChangeFieldRenamed, TableID: tableID, Table: table.Title, FieldID: fieldID, Field: field.Title, From: oldField.Title, To: field.Title, Breaking: true})
+			}
+			if ok && field.Type != oldField.Type && oldField.Type != "" && field.Type != "" {
+				changes = append(changes, SchemaChange{Kind: ChangeFieldTypeChanged, TableID: tableID, Table: table.Title, FieldID: fieldID, Field: field.Title, From: oldField.Type, To: field.Type, Breaking: true})
+			}
+		}
+		for fieldID, field := range table.Fields {
+			if _, ok := old.Fields[fieldID]; !ok {
+				changes = append(changes, SchemaChange{Kind: ChangeFieldAdded, TableID: tableID, Table: table.Title, FieldID: fieldID, Field: field.Title, To: field.Type})
+			}
+		}
+	}
+	for tableID, table := range current {
+		if _, ok := previous[tableID]; !ok {
+			changes = append(changes, SchemaChange{Kind: ChangeTableAdded, TableID: tableID, Table: table.Title})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Table != changes[j].Table {
+			return changes[i].Table < changes[j].Table
+		}
+		if changes[i].Kind != changes[j].Kind {
+			return changes[i].Kind < changes[j].Kind
+		}
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
+
+// describe renders a change for the log
+func (c SchemaChange) describe() string {
+	switch c.Kind {
+	case ChangeTableAdded, ChangeTableRemoved:
+		return fmt.Sprintf("%s '%s'", c.Kind, c.Table)
+	case ChangeTableRenamed:
+		return fmt.Sprintf("%s '%s' -> '%s'", c.Kind, c.From, c.To)
+	case ChangeFieldRenamed:
+		return fmt.Sprintf("%s '%s.%s' -> '%s'", c.Kind, c.Table, c.From, c.To)
+	case ChangeFieldTypeChanged:
+		return fmt.Sprintf("%s '%s.%s' %s -> %s", c.Kind, c.Table, c.Field, c.From, c.To)
+	default:
+		return fmt.Sprintf("%s '%s.%s'", c.Kind, c.Table, c.Field)
+	}
+}
+
+// SetEventBus publishes schema change events on the bus
+func (m *MetaCache) SetEventBus(bus *events.Bus) {
+	m.events = bus
+}
+
+// recordSchemaDiff logs a diff, keeps it for /__proxy/schema/changes and optionally publishes it
+func (m *MetaCache) recordSchemaDiff(previousHash, hash string, changes []SchemaChange, publish bool) {
+	if len(changes) == 0 {
+		return
+	}
+
+	diff := SchemaDiff{
+		DetectedAt:   time.Now(),
+		PreviousHash: previousHash,
+		Hash:         hash,
+		Changes:      changes,
+	}
+	for _, change := range changes {
+		if change.Breaking {
+			diff.Breaking = true
+		}
+		log.Printf("[META] Schema change: %s", change.describe())
+	}
+
+	m.mu.Lock()
+	m.schemaDiffs = append(m.schemaDiffs, diff)
+	if len(m.schemaDiffs) > maxSchemaDiffs {
+		m.schemaDiffs = m.schemaDiffs[len(m.schemaDiffs)-maxSchemaDiffs:]
+	}
+	m.mu.Unlock()
+
+	if m.events == nil || !publish {
+		return
+	}
+	records := make([]map[string]interface{}, 0, len(changes))
+	for _, change := range changes {
+		records = append(records, map[string]interface{}{
+			"kind":     change.Kind,
+			"table_id": change.TableID,
+			"table":    change.Table,
+			"field_id": change.FieldID,
+			"field":    change.Field,
+			"from":     change.From,
+			"to":       change.To,
+			"breaking": change.Breaking,
+		})
+	}
+	m.events.Publish(events.Event{
+		Type:    events.TypeSchemaChanged,
+		Source:  SourceMetaCache,
+		Records: records,
+		Metadata: map[string]string{
+			"base_id":       m.baseID,
+			"hash":          hash,
+			"previous_hash": previousHash,
+			"changes":       strconv.Itoa(len(changes)),
+			"breaking":      strconv.FormatBool(diff.Breaking),
+		},
+	})
+}
+
+// SchemaDiffs returns the most recent schema diffs, newest first
+func (m *MetaCache) SchemaDiffs() []SchemaDiff {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	diffs := make([]SchemaDiff, 0, len(m.schemaDiffs))
+	for i := len(m.schemaDiffs) - 1; i >= 0; i-- {
+		diffs = append(diffs, m.schemaDiffs[i])
+	}
+	return diffs
+}
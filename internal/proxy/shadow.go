@@ -0,0 +1,234 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxShadowDiffs caps the number of differing paths logged per request
+const maxShadowDiffs = 10
+
+// ShadowStats counts mirrored requests and how their responses compared
+type ShadowStats struct {
+	Target   string  `json:"target"`
+	Percent  float64 `json:"percent"`
+	Writes   bool    `json:"writes"`
+	Mirrored int     `json:"mirrored"`
+	Matched  int     `json:"matched"`
+	Diffs    int     `json:"diffs"`
+	Errors   int     `json:"errors"`
+	Dropped  int     `json:"dropped"` // skipped because too many shadow requests were in flight
+}
+
+// Shadow mirrors a percentage of proxied requests to a second NocoDB instance.
+// Shadow responses are discarded; differences from the primary response are logged.
+// It is meant for validating an upgrade or migration with real traffic before cutover.
+type Shadow struct {
+	primaryOrigin string
+	shadowOrigin  string
+	token         string
+	percent       float64
+	writes        bool
+	httpClient    *http.Client
+	inflight      chan struct{}
+
+	mu    sync.Mutex
+	stats ShadowStats
+}
+
+// NewShadow creates a shadow mirror. percent is 0-100; writes are only mirrored if
+// writes is true, since they modify the shadow instance's data.
+func NewShadow(primaryURL, shadowURL, token string, percent float64, writes bool) (*Shadow, error) {
+	primaryOrigin, err := origin(primaryURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid primary URL: %w", err)
+	}
+	shadowOrigin, err := origin(shadowURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid shadow URL: %w", err)
+	}
+	if percent < 0 || percent > 100 {
+		return nil, fmt.Errorf("shadow percentage must be between 0 and 100, got %v", percent)
+	}
+
+	return &Shadow{
+		primaryOrigin: primaryOrigin,
+		shadowOrigin:  shadowOrigin,
+		token:         token,
+		percent:       percent,
+		writes:        writes,
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+		inflight:      make(chan struct{}, 32),
+		stats:         ShadowStats{Target: shadowOrigin, Percent: percent, Writes: writes},
+	}, nil
+}
+
+// SetShadow mirrors a sample of proxied requests to a shadow instance
+func (p *ProxyHandler) SetShadow(shadow *Shadow) {
+	p.shadow = shadow
+}
+
+// sample decides whether a request is mirrored
+func (s *Shadow) sample(method string) bool {
+	if s == nil || s.percent <= 0 {
+		return false
+	}
+	if method != http.MethodGet && !s.writes {
+		return false
+	}
+	return rand.Float64()*100 < s.percent
+}
+
+// mirror replays a request against the shadow instance in the background and
+// compares its response with the primary's
+func (s *Shadow) mirror(method, target string, header http.Header, body []byte, primaryStatus int, primaryBody []byte) {
+	if !strings.HasPrefix(target, s.primaryOrigin) {
+		// Requests to other upstreams (or the failover standby) are not shadowed
+		return
+	}
+	shadowURL := s.shadowOrigin + strings.TrimPrefix(target, s.primaryOrigin)
+
+	select {
+	case s.inflight <- struct{}{}:
+	default:
+		s.count(func(stats *ShadowStats) { stats.Dropped++ })
+		return
+	}
+
+	go func() {
+		defer func() { <-s.inflight }()
+
+		req, err := http.NewRequest(method, shadowURL, bytes.NewReader(body))
+		if err != nil {
+			s.count(func(stats *ShadowStats) { stats.Errors++ })
+			return
+		}
+		req.Header = header.Clone()
+		if s.token != "" {
+			req.Header.Set("xc-token", s.token)
+		}
+
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			log.Printf("[SHADOW] %s %s failed: %v", method, shadowURL, err)
+			s.count(func(stats *ShadowStats) { stats.Mirrored++; stats.Errors++ })
+			return
+		}
+		defer resp.Body.Close()
+		shadowBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			s.count(func(stats *ShadowStats) { stats.Mirrored++; stats.Errors++ })
+			return
+		}
+
+		diffs := diffResponses(primaryStatus, primaryBody, resp.StatusCode, shadowBody)
+		if len(diffs) == 0 {
+			s.count(func(stats *ShadowStats) { stats.Mirrored++; stats.Matched++ })
+			return
+		}
+		s.count(func(stats *ShadowStats) { stats.Mirrored++; stats.Diffs++ })
+		log.Printf("[SHADOW] %s %s differs: %s", method, strings.TrimPrefix(target, s.primaryOrigin), strings.Join(diffs, "; "))
+	}()
+}
+
+// count updates the stats under the lock
+func (s *Shadow) count(update func(*ShadowStats)) {
+	s.mu.Lock()
+	update(&s.stats)
+	s.mu.Unlock()
+}
+
+// Stats returns the shadow counters
+func (s *Shadow) Stats() ShadowStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// diffResponses describes how a shadow response differs from the primary response
+func diffResponses(primaryStatus int, primaryBody []byte, shadowStatus int, shadowBody []byte) []string {
+	var diffs []string
+	if primaryStatus != shadowStatus {
+		diffs = append(diffs, fmt.Sprintf("status %d != %d", primaryStatus, shadowStatus))
+	}
+
+	var primaryJSON, shadowJSON interface{}
+	if json.Unmarshal(primaryBody, &primaryJSON) != nil || json.Unmarshal(shadowBody, &shadowJSON) != nil {
+		if !bytes.Equal(primaryBody, shadowBody) {
+			diffs = append(diffs, fmt.Sprintf("body differs (%d vs %d bytes)", len(primaryBody), len(shadowBody)))
+		}
+		return diffs
+	}
+	return diffJSON("$", primaryJSON, shadowJSON, diffs)
+}
+
+// diffJSON appends the paths at which two decoded JSON values differ
+func diffJSON(path string, a, b interface{}, diffs []string) []string {
+	if len(diffs) >= maxShadowDiffs {
+		return diffs
+	}
+
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			return append(diffs, path+": type differs")
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, ok := av[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			aChild, inA := av[k]
+			bChild, inB := bv[k]
+			switch {
+			case !inB:
+				diffs = append(diffs, path+"."+k+": missing in shadow")
+			case !inA:
+				diffs = append(diffs, path+"."+k+": only in shadow")
+			default:
+				diffs = diffJSON(path+"."+k, aChild, bChild, diffs)
+			}
+			if len(diffs) >= maxShadowDiffs {
+				return diffs
+			}
+		}
+		return diffs
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok {
+			return append(diffs, path+": type differs")
+		}
+		if len(av) != len(bv) {
+			return append(diffs, fmt.Sprintf("%s: length %d != %d", path, len(av), len(bv)))
+		}
+		for i := range av {
+			diffs = diffJSON(fmt.Sprintf("%s[%d]", path, i), av[i], bv[i], diffs)
+			if len(diffs) >= maxShadowDiffs {
+				return diffs
+			}
+		}
+		return diffs
+	default:
+		if !reflect.DeepEqual(a, b) {
+			return append(diffs, fmt.Sprintf("%s: %v != %v", path, a, b))
+		}
+		return diffs
+	}
+}
@@ -0,0 +1,273 @@
+package proxy
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/geoip"
+	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/grove/generic-proxy/internal/ratelimit"
+)
+
+const (
+	// maxShareRequestBytes bounds the body of POST /share
+	maxShareRequestBytes = 16 * 1024
+	// shareRateLimit and shareBurst limit GET /shared/{token} per client
+	shareRateLimit = 60 // per minute
+	shareBurst     = 20
+)
+
+// shareClaims is the signed content of a share link. The record is read as
+// the user who created the link, with the role they had then.
+type shareClaims struct {
+	Table   string   `json:"t"`
+	ID      string   `json:"id"`
+	Fields  []string `json:"f,omitempty"`
+	UserID  string   `json:"u"`
+	Role    string   `json:"r"`
+	Expires int64    `json:"exp"`
+}
+
+// ShareLinks creates and serves signed, expiring links to single records of
+// tables with share_links enabled:
+//
+//	POST /share             {"table": "...", "id": "...", "fields": [...], "ttl": "1h"}
+//	GET  /shared/{token}    the record, without authentication
+//
+// Links are stateless: they cannot be revoked one by one, only all at once by
+// changing the secret.
+type ShareLinks struct {
+	proxy   *ProxyHandler
+	key     []byte
+	geo     *geoip.Filter
+	limiter *ratelimit.Limiter
+}
+
+// NewShareLinks creates the handler for the tables of a proxy handler. The
+// signing key is derived from secret, so the JWT secret can be reused.
+func NewShareLinks(proxy *ProxyHandler, secret string, geo *geoip.Filter) *ShareLinks {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("share-links"))
+	return &ShareLinks{
+		proxy:   proxy,
+		key:     mac.Sum(nil),
+		geo:     geo,
+		limiter: ratelimit.New(shareRateLimit, shareBurst),
+	}
+}
+
+// ServeCreate handles POST /share. Must run after middleware.AuthMiddleware.
+// The caller must be able to read the record through the gateway.
+func (s *ShareLinks) ServeCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		respondJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req struct {
+		Table  string   `json:"table"`
+		ID     string   `json:"id"`
+		Fields []string `json:"fields,omitempty"`
+		TTL    string   `json:"ttl,omitempty"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxShareRequestBytes)).Decode(&req); err != nil || req.Table == "" || req.ID == "" {
+		respondJSONError(w, http.StatusBadRequest, `expected {"table": "...", "id": "..."}`)
+		return
+	}
+
+	table, ok := s.table(req.Table)
+	if !ok {
+		respondJSONError(w, http.StatusForbidden, fmt.Sprintf("share links are not enabled for table '%s'", req.Table))
+		return
+	}
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+	if !table.ShareLinks.CanShare(role) {
+		respondJSONError(w, http.StatusForbidden, fmt.Sprintf("role '%s' cannot share records of table '%s'", role, req.Table))
+		return
+	}
+
+	maxTTL := table.ShareLinks.MaxLifetime()
+	ttl := maxTTL
+	if req.TTL != "" {
+		parsed, err := time.ParseDuration(req.TTL)
+		if err != nil || parsed <= 0 || parsed > maxTTL {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("ttl must be a duration up to %s", maxTTL))
+			return
+		}
+		ttl = parsed
+	}
+	for _, field := range req.Fields {
+		if !shareableField(table, field) {
+			respondJSONError(w, http.StatusBadRequest, fmt.Sprintf("field '%s' cannot be shared", field))
+			return
+		}
+	}
+
+	// Only records the caller can read may be shared
+	if status := s.read(r.Context(), req.Table, req.ID, nil); status != http.StatusOK {
+		log.Printf("[SHARE] Refusing to share %s/%s for user %s: reading the record returned %d", req.Table, req.ID, userID, status)
+		respondJSONError(w, status, "cannot read record '"+req.ID+"'")
+		return
+	}
+
+	expires := time.Now().Add(ttl).UTC().Truncate(time.Second)
+	token, err := s.sign(shareClaims{Table: req.Table, ID: req.ID, Fields: req.Fields, UserID: userID, Role: role, Expires: expires.Unix()})
+	if err != nil {
+		respondJSONError(w, http.StatusInternalServerError, "failed to sign link")
+		return
+	}
+	log.Printf("[SHARE] User %s shared %s/%s until %s", userID, req.Table, req.ID, expires.Format(time.RFC3339))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":      token,
+		"url":        "/shared/" + token,
+		"expires_at": expires,
+	})
+}
+
+// ServeShared handles GET /shared/{token}. It answers {"table", "record",
+// "expires_at"}; invalid links and records that can no longer be read are 404.
+func (s *ShareLinks) ServeShared(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		respondJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	client := ratelimit.ClientAddr(r, s.geo)
+	if wait := s.limiter.Take(client); wait > 0 {
+		log.Printf("[SHARE] Rate limit exceeded by %s", client)
+		ratelimit.Reject(w, wait)
+		return
+	}
+
+	claims, err := s.verify(strings.Trim(strings.TrimPrefix(r.URL.Path, "/shared/"), "/"))
+	if err != nil {
+		respondJSONError(w, http.StatusNotFound, "invalid share link")
+		return
+	}
+	expires := time.Unix(claims.Expires, 0).UTC()
+	if time.Now().After(expires) {
+		respondJSONError(w, http.StatusGone, "share link has expired")
+		return
+	}
+	table, ok := s.table(claims.Table)
+	if !ok {
+		respondJSONError(w, http.StatusNotFound, "invalid share link")
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), middleware.UserIDKey, claims.UserID)
+	ctx = context.WithValue(ctx, middleware.RoleKey, claims.Role)
+	var record map[string]interface{}
+	if status := s.read(ctx, claims.Table, claims.ID, &record); status != http.StatusOK || record == nil {
+		log.Printf("[SHARE] Shared record %s/%s is not available (%d)", claims.Table, claims.ID, status)
+		respondJSONError(w, http.StatusNotFound, "record not found")
+		return
+	}
+
+	shared := make(map[string]interface{}, len(record))
+	for field, value := range FlattenRecord(record) {
+		if len(claims.Fields) > 0 && !containsString(claims.Fields, field) {
+			continue
+		}
+		if shareableField(table, field) {
+			shared[field] = value
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"table":      claims.Table,
+		"record":     shared,
+		"expires_at": expires,
+	})
+}
+
+// table returns a table with share links enabled
+func (s *ShareLinks) table(tableKey string) (config.ResolvedTable, bool) {
+	if s.proxy.ResolvedConfig == nil {
+		return config.ResolvedTable{}, false
+	}
+	table, ok := s.proxy.ResolvedConfig.Tables[tableKey]
+	if !ok || table.ShareLinks == nil || !table.ShareLinks.Enabled {
+		return config.ResolvedTable{}, false
+	}
+	return table, true
+}
+
+// read fetches a record through the full pipeline of GET .../records/{id} as
+// the user in ctx and returns the status; record is filled when not nil
+func (s *ShareLinks) read(ctx context.Context, tableKey, recordID string, record *map[string]interface{}) int {
+	req, err := http.NewRequestWithContext(context.WithValue(ctx, rawEnvelopeKey, true), http.MethodGet, "/proxy/"+url.PathEscape(tableKey)+"/records/"+url.PathEscape(recordID), nil)
+	if err != nil {
+		return http.StatusBadRequest
+	}
+	recorder := httptest.NewRecorder()
+	s.proxy.ServeHTTP(recorder, req)
+	if recorder.Code == http.StatusOK && record != nil {
+		if err := json.Unmarshal(recorder.Body.Bytes(), record); err != nil {
+			return http.StatusBadGateway
+		}
+	}
+	return recorder.Code
+}
+
+// shareableField reports whether a field may be shown through a link of a
+// table: listed in share_links.fields (when set) and never encrypted
+func shareableField(table config.ResolvedTable, field string) bool {
+	if table.Encrypt != nil && containsString(table.Encrypt.Fields, field) {
+		return false
+	}
+	return len(table.ShareLinks.Fields) == 0 || containsString(table.ShareLinks.Fields, field)
+}
+
+// sign encodes claims as "<payload>.<signature>", both base64url
+func (s *ShareLinks) sign(claims shareClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.signature(encoded), nil
+}
+
+// verify checks a token's signature and returns its claims
+func (s *ShareLinks) verify(token string) (shareClaims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.signature(encoded))) {
+		return shareClaims{}, fmt.Errorf("bad signature")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return shareClaims{}, err
+	}
+	var claims shareClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return shareClaims{}, err
+	}
+	return claims, nil
+}
+
+func (s *ShareLinks) signature(encoded string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
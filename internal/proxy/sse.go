@@ -0,0 +1,212 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/events"
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+// sseHeartbeatInterval keeps idle SSE connections alive through intermediaries
+const sseHeartbeatInterval = 15 * time.Second
+
+// serveEvents handles GET /proxy/{table}/events as a Server-Sent Events stream
+func (p *ProxyHandler) serveEvents(w http.ResponseWriter, r *http.Request, tableKey string) {
+	if p.Events == nil {
+		http.Error(w, "event streaming not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	tableID, table, err := p.resolveTable(http.MethodGet, tableKey)
+	if err != nil {
+		log.Printf("[SSE ERROR] Cannot stream table '%s': %v", tableKey, err)
+		http.Error(w, "forbidden: "+err.Error(), http.StatusForbidden)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+	tenant, _ := r.Context().Value(middleware.TenantKey).(string)
+	rows := StreamFilter(table, tableKey, userID, role, tenant)
+
+	ch, cancel := p.Events.Subscribe(100)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, ": streaming changes for %s\n\n", tableKey)
+	flusher.Flush()
+
+	log.Printf("[SSE] User %s subscribed to changes for table '%s' (%s)", userID, tableKey, tableID)
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			log.Printf("[SSE] User %s disconnected from table '%s'", userID, tableKey)
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			if !eventMatchesTable(event, tableKey, tableID) {
+				continue
+			}
+			event, visible := FilterEvent(event, table, rows, role, userID)
+			if !visible {
+				continue
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.Printf("[SSE ERROR] Failed to encode event %s: %v", event.ID, err)
+				continue
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// resolveTable maps a table key to its NocoDB ID, checking permissions in schema-driven mode.
+// The returned table configuration is nil in legacy mode.
+func (p *ProxyHandler) resolveTable(method, tableKey string) (string, *config.ResolvedTable, error) {
+	if p.Validator != nil && p.ResolvedConfig != nil {
+		validation, err := p.Validator.ValidateRequest(method, tableKey)
+		if err == nil {
+			table := p.ResolvedConfig.Tables[tableKey]
+			return validation.TableID, &table, nil
+		}
+		if p.dryRun == nil {
+			return "", nil, err
+		}
+		p.dryRun.report(method, tableKey, tableKey, dryRunValidation, http.StatusForbidden, err)
+	}
+
+	if p.Meta != nil {
+		if tableID, ok := p.Meta.Resolve(tableKey); ok {
+			return tableID, nil, nil
+		}
+	}
+	return "", nil, fmt.Errorf("unknown table '%s'", tableKey)
+}
+
+// eventMatchesTable reports whether an event concerns the given table. Bases
+// and tenants share the bus and may use the same table keys, so events that
+// carry a NocoDB table ID match on it alone.
+func eventMatchesTable(event events.Event, tableKey, tableID string) bool {
+	if event.TableID != "" {
+		return event.TableID == tableID
+	}
+	return event.TableName == tableKey
+}
+
+// RowFilter is a table's read rules (proxy.yaml `rules:`) for one subscriber
+// of its events. Event records cannot be sent to NocoDB, so a rule filter is
+// evaluated locally when it is a conjunction of eq clauses; events of a table
+// whose read rules refuse the subscriber, or whose filter uses anything else,
+// are withheld from them.
+type RowFilter struct {
+	equal    map[string]string
+	withhold bool
+}
+
+// StreamFilter evaluates a table's read rules for a subscriber, once for the
+// life of their subscription
+func StreamFilter(table *config.ResolvedTable, tableKey, userID, role, tenant string) RowFilter {
+	if table == nil || table.Rules == nil {
+		return RowFilter{}
+	}
+	env := config.RuleEnv{
+		UserID:    userID,
+		Role:      role,
+		Tenant:    tenant,
+		Method:    http.MethodGet,
+		Operation: "read",
+		Table:     tableKey,
+	}
+	if err := table.Rules.Check(env); err != nil {
+		return RowFilter{withhold: true}
+	}
+	where, err := table.Rules.Filter(env)
+	if err != nil {
+		log.Printf("[EVENTS WARN] Withholding '%s' events from user %s: %v", tableKey, userID, err)
+		return RowFilter{withhold: true}
+	}
+	equal, ok := parseEqualityWhere(where)
+	if !ok {
+		return RowFilter{withhold: true}
+	}
+	return RowFilter{equal: equal}
+}
+
+// FilterEvent applies row-level permissions to an event for the given caller.
+// Admins and tables without an owner_field see every owner's records; other
+// users only see records whose owner field matches their user ID. The table's
+// rule filter applies to everyone, as it does to REST reads. The records the
+// caller sees get the table's computed fields and response steps.
+func FilterEvent(event events.Event, table *config.ResolvedTable, rows RowFilter, role, userID string) (events.Event, bool) {
+	if rows.withhold {
+		return event, false
+	}
+	if table == nil {
+		return event, true
+	}
+	records := visibleRecords(event.Records, table, rows, role, userID)
+	previous := visibleRecords(event.Previous, table, rows, role, userID)
+
+	// Events without visible record data (e.g. bare deletes) are withheld
+	restricted := (role != "admin" && table.OwnerField != "") || len(rows.equal) > 0
+	if restricted && len(records) == 0 && len(previous) == 0 {
+		return event, false
+	}
+
+	event.Records = records
+	event.Previous = previous
+	return event, true
+}
+
+// visibleRecords returns transformed copies of the records a caller may see
+func visibleRecords(records []map[string]interface{}, table *config.ResolvedTable, rows RowFilter, role, userID string) []map[string]interface{} {
+	if records == nil {
+		return nil
+	}
+	visible := make([]map[string]interface{}, 0, len(records))
+	for _, record := range records {
+		if CanSeeRecord(table, role, userID, record) && recordMatches(record, rows.equal) {
+			visible = append(visible, TransformRecord(table, role, record))
+		}
+	}
+	return visible
+}
+
+// CanSeeRecord reports whether a user may see a single record under row-level rules
+func CanSeeRecord(table *config.ResolvedTable, role, userID string, record map[string]interface{}) bool {
+	if role == "admin" || table == nil || table.OwnerField == "" {
+		return true
+	}
+	owner, ok := record[table.OwnerField]
+	if !ok || owner == nil {
+		return false
+	}
+	return fmt.Sprintf("%v", owner) == userID
+}
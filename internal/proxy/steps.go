@@ -0,0 +1,248 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+// tableSteps returns the pipeline steps of a table that apply to a role
+func (p *ProxyHandler) tableSteps(tableKey, role string, response bool) []config.PipelineStep {
+	if p.ResolvedConfig == nil {
+		return nil
+	}
+	table := p.ResolvedConfig.Tables[tableKey]
+	return pipelineSteps(&table, role, response)
+}
+
+func pipelineSteps(table *config.ResolvedTable, role string, response bool) []config.PipelineStep {
+	var steps []config.PipelineStep
+	for _, step := range table.Pipeline {
+		if step.IsResponseStep() == response && step.AppliesTo(role) {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// hasResponseSteps reports whether a table masks, removes or computes fields
+// for anyone
+func (p *ProxyHandler) hasResponseSteps(tableKey string) bool {
+	if p.ResolvedConfig == nil {
+		return false
+	}
+	if p.ResolvedConfig.Tables[tableKey].Computed != nil {
+		return true
+	}
+	for _, step := range p.ResolvedConfig.Tables[tableKey].Pipeline {
+		if step.IsResponseStep() {
+			return true
+		}
+	}
+	return false
+}
+
+// applyRequestSteps runs a table's request steps on the records of a write.
+// Reads may not filter or sort on fields the role gets masked or removed:
+// the results would reveal their values.
+func (p *ProxyHandler) applyRequestSteps(r *http.Request, route Route, tableKey, role string) error {
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		for _, step := range p.tableSteps(tableKey, role, true) {
+			for _, field := range step.Fields {
+				if strings.Contains(query.Get("where"), "("+field+",") {
+					return fmt.Errorf("cannot filter on field '%s'", field)
+				}
+				for _, sort := range strings.Split(query.Get("sort"), ",") {
+					if strings.TrimPrefix(strings.TrimSpace(sort), "-") == field {
+						return fmt.Errorf("cannot sort on field '%s'", field)
+					}
+				}
+			}
+		}
+		return nil
+	}
+
+	steps := p.tableSteps(tableKey, role, false)
+	if len(steps) == 0 || (r.Method != http.MethodPost && r.Method != http.MethodPatch && r.Method != http.MethodPut) || r.Body == nil {
+		return nil
+	}
+	// Link operations carry record IDs, not records
+	if route.Kind == RouteLinks {
+		return nil
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		// Not JSON: NocoDB reports the error
+		return nil
+	}
+	records := ruleRecords(payload)
+	changed := false
+	for _, step := range steps {
+		if step.Step == config.StepDefaults && r.Method != http.MethodPost {
+			continue
+		}
+		for _, record := range records {
+			for field, value := range step.Values {
+				if _, ok := record[field]; ok && step.Step == config.StepDefaults {
+					continue
+				}
+				record[field] = value
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	updated, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(updated))
+	r.ContentLength = int64(len(updated))
+	return nil
+}
+
+// applyResponseSteps runs a table's response steps on a response body (v2 list
+// or record, v3 records with fields). Fields are matched at any depth, so
+// records nested in links are covered too. Bodies that are not JSON are
+// returned unchanged.
+func (p *ProxyHandler) applyResponseSteps(tableKey, role string, body []byte) []byte {
+	steps := p.tableSteps(tableKey, role, true)
+	if len(steps) == 0 {
+		return body
+	}
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return body
+	}
+	for _, step := range steps {
+		applyResponseStep(payload, step)
+	}
+	transformed, err := json.Marshal(payload)
+	if err != nil {
+		return body
+	}
+	return transformed
+}
+
+// applyComputed adds a table's computed fields to the records of a read of
+// its records (v2 list or record, v3 records with fields). Records of other
+// tables (links, nested records) are left alone. Bodies that are not JSON are
+// returned unchanged.
+func (p *ProxyHandler) applyComputed(r *http.Request, route Route, tableKey string, status int, body []byte) []byte {
+	if p.ResolvedConfig == nil || r.Method != http.MethodGet || route.Kind != RouteRecords || status != http.StatusOK {
+		return body
+	}
+	computed := p.ResolvedConfig.Tables[tableKey].Computed
+	if computed == nil {
+		return body
+	}
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return body
+	}
+	var records []map[string]interface{}
+	if list, ok := payload.(map[string]interface{}); ok && route.IsList() {
+		for _, key := range []string{"list", "records"} {
+			if items, ok := list[key]; ok {
+				records = ruleRecords(items)
+				break
+			}
+		}
+	} else {
+		records = ruleRecords(payload)
+	}
+	if len(records) == 0 {
+		return body
+	}
+	for _, record := range records {
+		computed.Apply(record)
+	}
+	transformed, err := json.Marshal(payload)
+	if err != nil {
+		return body
+	}
+	return transformed
+}
+
+// TransformRecord adds a table's computed fields to a copy of a record and
+// runs its response steps on it, for endpoints that return records without
+// going through the pipeline (delta sync, search)
+func TransformRecord(table *config.ResolvedTable, role string, record map[string]interface{}) map[string]interface{} {
+	steps := pipelineSteps(table, role, true)
+	if (len(steps) == 0 && table.Computed == nil) || record == nil {
+		return record
+	}
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return nil
+	}
+	var copied map[string]interface{}
+	if err := json.Unmarshal(encoded, &copied); err != nil {
+		return nil
+	}
+	if table.Computed != nil {
+		table.Computed.Apply(copied)
+	}
+	for _, step := range steps {
+		applyResponseStep(copied, step)
+	}
+	return copied
+}
+
+func applyResponseStep(value interface{}, step config.PipelineStep) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if !containsString(step.Fields, key) {
+				applyResponseStep(item, step)
+				continue
+			}
+			if step.Step == config.StepRemove {
+				delete(v, key)
+			} else if item != nil {
+				v[key] = maskValue(item, step.KeepLast)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			applyResponseStep(item, step)
+		}
+	}
+}
+
+// maskValue replaces a value by asterisks, one per character, keeping the
+// last keepLast characters of values long enough to stay hidden
+func maskValue(value interface{}, keepLast int) string {
+	var text []rune
+	switch v := value.(type) {
+	case string:
+		text = []rune(v)
+	case float64:
+		text = []rune(strconv.FormatFloat(v, 'f', -1, 64))
+	case map[string]interface{}, []interface{}:
+		return "****"
+	default:
+		text = []rune(fmt.Sprint(v))
+	}
+	keep := keepLast
+	if keep >= len(text) {
+		keep = 0
+	}
+	return strings.Repeat("*", len(text)-keep) + string(text[len(text)-keep:])
+}
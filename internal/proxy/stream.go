@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/grove/generic-proxy/internal/config"
+)
+
+// streamable reports whether a NocoDB response can be copied to the client as
+// it arrives instead of being read into memory first: a successful read that
+// nothing in the gateway rewrites, caches or compares. JSON bodies pass
+// through unchanged unless the table has an envelope or encrypted fields;
+// other bodies (attachments, exports) always do. Response filters, post-proxy
+// plugins and the table's response steps see every body, so they keep all
+// responses buffered. A 304 from NocoDB passes through too.
+func (p *ProxyHandler) streamable(r *http.Request, tableKey string, resp *http.Response, buffered bool) bool {
+	if buffered || r.Method != http.MethodGet || resp.StatusCode == http.StatusNoContent {
+		return false
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		return true
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return false
+	}
+	// The gateway's own reads parse the body
+	if raw, _ := r.Context().Value(rawEnvelopeKey).(bool); raw {
+		return false
+	}
+	if len(p.tableFilters(tableKey)) > 0 || p.plugins.HasPostProxy() || p.hasResponseSteps(tableKey) {
+		return false
+	}
+	if !isJSON(resp.Header) {
+		return true
+	}
+	return p.envelope(r, tableKey) == config.EnvelopeNocoDB && p.tableEncryption(tableKey) == nil
+}
+
+// streamResponse copies a NocoDB response to the client as it arrives. Bodies
+// of unknown length are flushed after every read, so the client sees them as
+// NocoDB produces them. Only the first bytes are kept, for body logging. The
+// table's max_response_mb does not apply: nothing is held in memory.
+func (p *ProxyHandler) streamResponse(w http.ResponseWriter, tableKey string, resp *http.Response) {
+	p.copyResponseHeaders(w.Header(), resp.Header, tableKey)
+	w.WriteHeader(resp.StatusCode)
+
+	policy, logged := p.bodyLogPolicy(tableKey, resp.StatusCode)
+	var head prefixBuffer
+	if logged {
+		// One more character than logged, to cut the logged part at a character
+		head.max = bodyLogMaxBytes(policy) + utf8.UTFMax
+	}
+	var dst io.Writer = w
+	if flusher, ok := w.(http.Flusher); ok && resp.ContentLength < 0 {
+		dst = &flushWriter{w: w, flusher: flusher}
+	}
+
+	written, err := io.Copy(io.MultiWriter(dst, &head), resp.Body)
+	if err != nil {
+		// The status is sent: abort the connection so the client does not take a cut body as complete
+		log.Printf("[PROXY ERROR] Streaming the response failed after %d bytes: %v", written, err)
+		panic(http.ErrAbortHandler)
+	}
+	log.Printf("[PROXY] Streamed response body: %d bytes", written)
+
+	if !logged || written == 0 {
+		return
+	}
+	maxBytes := bodyLogMaxBytes(policy)
+	var text string
+	switch {
+	case written <= int64(len(head.data)):
+		text = truncateBody(redactBody(head.data, policy.Redact), maxBytes)
+	case len(policy.Redact) > 0:
+		// Fields of a cut JSON body cannot be found to redact them
+		log.Printf("[PROXY] Response body: not logged, %d bytes streamed and the table redacts fields", written)
+		return
+	default:
+		text = truncatePrefix(string(head.data), maxBytes, written)
+	}
+	if !utf8.ValidString(text) {
+		log.Printf("[PROXY] Response body: %d bytes of %s", written, resp.Header.Get("Content-Type"))
+		return
+	}
+	log.Printf("[PROXY] Response body: %s", text)
+}
+
+// isJSON reports whether a response declares a JSON body
+func isJSON(header http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		// NocoDB answers JSON; an unreadable or missing type is not trusted to be anything else
+		return true
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// prefixBuffer keeps the first max bytes written to it
+type prefixBuffer struct {
+	max  int
+	data []byte
+}
+
+func (b *prefixBuffer) Write(p []byte) (int, error) {
+	if room := b.max - len(b.data); room > 0 {
+		b.data = append(b.data, p[:min(room, len(p))]...)
+	}
+	return len(p), nil
+}
+
+// flushWriter flushes after every write
+type flushWriter struct {
+	w       io.Writer
+	flusher http.Flusher
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	f.flusher.Flush()
+	return n, err
+}
@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/ratelimit"
+)
+
+// TableRateStats reports a table's budget and how many requests exceeded it
+type TableRateStats struct {
+	RateLimit int `json:"rate_limit"` // requests per minute
+	Burst     int `json:"burst"`
+	Limited   int `json:"limited"` // requests answered 429
+}
+
+// TableRateLimiter gives tables a request budget shared by all their users,
+// so one heavy consumer of an expensive table cannot starve NocoDB. Only
+// requests that would reach NocoDB take from it; cache hits are free.
+type TableRateLimiter struct {
+	limiters map[string]*ratelimit.Limiter // table key -> one bucket
+
+	mu    sync.Mutex
+	stats map[string]*TableRateStats
+}
+
+// NewTableRateLimiter creates the limiter of the table_rate_limits block of
+// proxy.yaml, nil when there is none
+func NewTableRateLimiter(rates map[string]config.TableRate) *TableRateLimiter {
+	if len(rates) == 0 {
+		return nil
+	}
+	l := &TableRateLimiter{
+		limiters: make(map[string]*ratelimit.Limiter, len(rates)),
+		stats:    make(map[string]*TableRateStats, len(rates)),
+	}
+	for table, rate := range rates {
+		burst := rate.Burst
+		if burst == 0 {
+			burst = (rate.RateLimit + 59) / 60
+		}
+		l.limiters[table] = ratelimit.New(rate.RateLimit, burst)
+		l.stats[table] = &TableRateStats{RateLimit: rate.RateLimit, Burst: burst}
+	}
+	return l
+}
+
+// Take uses one request of the table's budget. It returns how long to wait
+// when the budget is spent, 0 when the request may pass. Tables without a
+// budget always pass.
+func (l *TableRateLimiter) Take(table string) time.Duration {
+	if l == nil {
+		return 0
+	}
+	limiter, ok := l.limiters[table]
+	if !ok {
+		return 0
+	}
+	wait := limiter.Take(table)
+	if wait > 0 {
+		l.mu.Lock()
+		l.stats[table].Limited++
+		l.mu.Unlock()
+	}
+	return wait
+}
+
+// Stats returns each table's budget and counter
+func (l *TableRateLimiter) Stats() map[string]TableRateStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats := make(map[string]TableRateStats, len(l.stats))
+	for table, s := range l.stats {
+		stats[table] = *s
+	}
+	return stats
+}
+
+// Describe summarizes the budgets for the startup log
+func (l *TableRateLimiter) Describe() string {
+	tables := make([]string, 0, len(l.stats))
+	for table, s := range l.stats {
+		tables = append(tables, fmt.Sprintf("%s: %d/min, burst %d", table, s.RateLimit, s.Burst))
+	}
+	sort.Strings(tables)
+	return strings.Join(tables, "; ")
+}
+
+// SetTableRateLimiter sets the request budgets of tables. Handlers of several
+// bases or tenants share one limiter.
+func (p *ProxyHandler) SetTableRateLimiter(limiter *TableRateLimiter) {
+	p.tableRates = limiter
+}
@@ -0,0 +1,152 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// timingKey holds the *requestTiming of a request to /proxy/
+const timingKey contextKey = "timing"
+
+// requestTiming collects the time a request spent waiting for NocoDB and
+// carries the latency budget its client asked for. Internal requests (batch
+// operations, HEAD, record checks) share their caller's timing.
+type requestTiming struct {
+	start  time.Time
+	budget time.Duration // 0: no budget
+
+	mu       sync.Mutex
+	upstream time.Duration
+}
+
+// LatencyBudget reads the X-Budget-Ms header, the milliseconds a client allows
+// NocoDB for its request, capped at max (0: no cap). Every response carries
+// Server-Timing with the time spent in the gateway and waiting for NocoDB.
+func LatencyBudget(max time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timing := &requestTiming{start: time.Now()}
+			if header := r.Header.Get("X-Budget-Ms"); header != "" {
+				ms, err := strconv.Atoi(header)
+				if err != nil || ms <= 0 {
+					respondJSONError(w, http.StatusBadRequest, "X-Budget-Ms must be a positive number of milliseconds")
+					return
+				}
+				timing.budget = time.Duration(ms) * time.Millisecond
+				if max > 0 && timing.budget > max {
+					timing.budget = max
+				}
+			}
+			ctx := context.WithValue(r.Context(), timingKey, timing)
+			next.ServeHTTP(&timingWriter{ResponseWriter: w, timing: timing}, r.WithContext(ctx))
+		})
+	}
+}
+
+// upstreamContext returns the context of a request to NocoDB: the values of
+// the client's request, bounded by its latency budget or the table's timeout,
+// whichever is shorter, but not cancelled when the client goes away (a write
+// NocoDB has started is completed)
+func upstreamContext(r *http.Request, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx := context.WithoutCancel(r.Context())
+	if limit, _ := upstreamLimit(r, timeout); limit > 0 {
+		return context.WithTimeout(ctx, limit)
+	}
+	return context.WithCancel(ctx)
+}
+
+// upstreamLimit returns how long a request may wait for NocoDB (0: no limit),
+// and whether the limit is the client's latency budget
+func upstreamLimit(r *http.Request, timeout time.Duration) (time.Duration, bool) {
+	timing, ok := r.Context().Value(timingKey).(*requestTiming)
+	if ok && timing.budget > 0 && (timeout == 0 || timing.budget <= timeout) {
+		return timing.budget, true
+	}
+	return timeout, false
+}
+
+// deadlineExceeded reports whether an upstream error is the latency budget or
+// the table's timeout running out, and the message the client gets
+func deadlineExceeded(r *http.Request, timeout time.Duration, err error) (string, bool) {
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return "", false
+	}
+	limit, budget := upstreamLimit(r, timeout)
+	switch {
+	case limit == 0:
+		return "", false
+	case budget:
+		return fmt.Sprintf("NocoDB did not answer within the latency budget of %d ms", limit.Milliseconds()), true
+	}
+	return fmt.Sprintf("NocoDB did not answer within the timeout of %v", limit), true
+}
+
+// upstreamTimeout returns the table's timeout for the operation of a request,
+// 0 for none
+func (p *ProxyHandler) upstreamTimeout(r *http.Request, route Route, tableKey string) time.Duration {
+	if p.ResolvedConfig == nil {
+		return 0
+	}
+	return p.ResolvedConfig.Tables[tableKey].Timeouts.For(route.Operation(r.Method))
+}
+
+// trackUpstream adds the time since start to the upstream time of the request
+// that ctx belongs to
+func trackUpstream(ctx context.Context, start time.Time) {
+	if timing, ok := ctx.Value(timingKey).(*requestTiming); ok {
+		timing.mu.Lock()
+		timing.upstream += time.Since(start)
+		timing.mu.Unlock()
+	}
+}
+
+// timingWriter adds Server-Timing when the response header is written
+type timingWriter struct {
+	http.ResponseWriter
+	timing      *requestTiming
+	wroteHeader bool
+}
+
+func (t *timingWriter) WriteHeader(status int) {
+	if !t.wroteHeader {
+		t.wroteHeader = true
+		t.timing.mu.Lock()
+		upstream := t.timing.upstream
+		t.timing.mu.Unlock()
+		gateway := time.Since(t.timing.start) - upstream
+		if gateway < 0 {
+			gateway = 0
+		}
+		t.Header().Set("Server-Timing", fmt.Sprintf("gateway;dur=%.1f, upstream;dur=%.1f", milliseconds(gateway), milliseconds(upstream)))
+		if t.timing.budget > 0 {
+			t.Header().Set("X-Budget-Ms", strconv.FormatInt(t.timing.budget.Milliseconds(), 10))
+		}
+	}
+	t.ResponseWriter.WriteHeader(status)
+}
+
+func (t *timingWriter) Write(p []byte) (int, error) {
+	if !t.wroteHeader {
+		t.WriteHeader(http.StatusOK)
+	}
+	return t.ResponseWriter.Write(p)
+}
+
+// Flush keeps streaming responses (server-sent events) working
+func (t *timingWriter) Flush() {
+	if !t.wroteHeader {
+		t.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := t.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,190 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+// tokenFilePollInterval is how often a watched token file is checked for changes
+const tokenFilePollInterval = 15 * time.Second
+
+// TokenReloadStatus describes the current default token without revealing it
+type TokenReloadStatus struct {
+	Hint      string    `json:"hint"`
+	Source    string    `json:"source"` // env, file or admin
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+	File      string    `json:"file,omitempty"`
+}
+
+// TokenReloader holds the default NocoDB token and lets it be swapped at runtime,
+// either through the admin endpoint or by rewriting a watched token file
+type TokenReloader struct {
+	mu        sync.RWMutex
+	token     string
+	source    string
+	updatedBy string
+	updatedAt time.Time
+	file      string
+	fileMod   time.Time
+}
+
+// NewTokenReloader creates a reloader starting with the token from the environment
+func NewTokenReloader(token string) *TokenReloader {
+	return &TokenReloader{token: token, source: "env", updatedAt: time.Now()}
+}
+
+// Token returns the current token
+func (t *TokenReloader) Token() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.token
+}
+
+// Source returns a TokenSource backed by the reloader
+func (t *TokenReloader) Source() TokenSource {
+	return t.Token
+}
+
+// Set swaps the token
+func (t *TokenReloader) Set(token, source, updatedBy string) {
+	t.mu.Lock()
+	t.token = token
+	t.source = source
+	t.updatedBy = updatedBy
+	t.updatedAt = time.Now()
+	t.mu.Unlock()
+	log.Printf("[TOKEN] Upstream token reloaded from %s (%s)", source, tokenHint(token))
+}
+
+// Status returns the current token state
+func (t *TokenReloader) Status() TokenReloadStatus {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return TokenReloadStatus{
+		Hint:      tokenHint(t.token),
+		Source:    t.source,
+		UpdatedBy: t.updatedBy,
+		UpdatedAt: t.updatedAt,
+		File:      t.file,
+	}
+}
+
+// WatchFile loads the token from a file and reloads it whenever the file changes,
+// until the context is cancelled. The file holds the bare token (surrounding whitespace is ignored).
+func (t *TokenReloader) WatchFile(ctx context.Context, path string) error {
+	t.mu.Lock()
+	t.file = path
+	t.mu.Unlock()
+	if err := t.reloadFile(); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(tokenFilePollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := t.reloadFile(); err != nil {
+					log.Printf("[TOKEN ERROR] Failed to reload token file %s: %v", path, err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// reloadFile reads the watched file if it changed since the last read
+func (t *TokenReloader) reloadFile() error {
+	t.mu.RLock()
+	path, lastMod := t.file, t.fileMod
+	t.mu.RUnlock()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.ModTime().After(lastMod) {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.fileMod = info.ModTime()
+	t.mu.Unlock()
+
+	token := strings.TrimSpace(string(data))
+	if token == "" || token == t.Token() {
+		return nil
+	}
+	t.Set(token, "file", "")
+	return nil
+}
+
+// ServeHTTP handles GET /admin/upstream-token (current token hint) and
+// PUT /admin/upstream-token ({"token": "..."}). Must run after middleware.AuthMiddleware.
+func (t *TokenReloader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if role, _ := r.Context().Value(middleware.RoleKey).(string); role != "admin" {
+		respondJSONError(w, http.StatusForbidden, "admin role required")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t.Status())
+
+	case http.MethodPut:
+		var req struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Token) == "" {
+			respondJSONError(w, http.StatusBadRequest, "token is required")
+			return
+		}
+		adminID, _ := r.Context().Value(middleware.UserIDKey).(string)
+		t.Set(strings.TrimSpace(req.Token), "admin", adminID)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t.Status())
+
+	default:
+		respondJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// FirstToken returns a TokenSource that yields the first non-empty token of the given sources
+func FirstToken(sources ...TokenSource) TokenSource {
+	return func() string {
+		for _, source := range sources {
+			if source == nil {
+				continue
+			}
+			if token := source(); token != "" {
+				return token
+			}
+		}
+		return ""
+	}
+}
+
+// tokenHint returns the last four characters of a token for display
+func tokenHint(token string) string {
+	if len(token) <= 8 {
+		return "****"
+	}
+	return "****" + token[len(token)-4:]
+}
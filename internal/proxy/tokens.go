@@ -0,0 +1,70 @@
+package proxy
+
+// TokenSource returns the current NocoDB API token. It lets tokens be rotated
+// (e.g. through the token vault) without restarting the gateway. An empty
+// result falls back to the token the component was created with.
+type TokenSource func() string
+
+// SetTokenSource sets the source of the upstream token for proxied requests
+func (p *ProxyHandler) SetTokenSource(source TokenSource) {
+	p.tokenSource = source
+}
+
+// upstreamToken returns the token to send to NocoDB
+func (p *ProxyHandler) upstreamToken() string {
+	if p.tokenSource != nil {
+		if token := p.tokenSource(); token != "" {
+			return token
+		}
+	}
+	return p.NocoDBToken
+}
+
+// SetTokenSource sets the source of the token used for metadata requests
+func (m *MetaCache) SetTokenSource(source TokenSource) {
+	m.tokenSource = source
+}
+
+// currentToken returns the token to send to the NocoDB meta API
+func (m *MetaCache) currentToken() string {
+	if m.tokenSource != nil {
+		if token := m.tokenSource(); token != "" {
+			return token
+		}
+	}
+	return m.token
+}
+
+// SetTokenSource sets the source of the token used for record queries
+func (c *UpstreamClient) SetTokenSource(source TokenSource) {
+	c.tokenSource = source
+}
+
+// currentToken returns the token to send to the NocoDB data API
+func (c *UpstreamClient) currentToken() string {
+	if c.tokenSource != nil {
+		if token := c.tokenSource(); token != "" {
+			return token
+		}
+	}
+	return c.token
+}
+
+// SetRoleTokens maps roles to the source of the NocoDB token their requests
+// are sent with, in place of the default token. Tables routed to a named
+// upstream keep that upstream's token.
+func (p *ProxyHandler) SetRoleTokens(tokens map[string]TokenSource) {
+	p.roleTokens = tokens
+}
+
+// roleToken returns the token for a role's requests to a table. ok is false
+// when the role is mapped but its token is not available: such requests are
+// refused rather than sent with the more capable default token.
+func (p *ProxyHandler) roleToken(role, tableKey string) (string, bool) {
+	source, mapped := p.roleTokens[role]
+	if !mapped || p.upstreamFor(tableKey) != nil {
+		return p.tableToken(tableKey), true
+	}
+	token := source()
+	return token, token != ""
+}
@@ -0,0 +1,193 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// upstreamClient sends every proxied request. It has no transport of its own:
+// requests go through http.DefaultTransport, which main replaces with a
+// PooledTransport (wrapped for upstream signing when that is on), so
+// connections to NocoDB are kept and reused across requests.
+var upstreamClient = &http.Client{}
+
+// TransportConfig tunes the connection pool to NocoDB. Zero values keep
+// net/http's defaults, except MaxConnsPerHost and ResponseHeaderTimeout, for
+// which zero means no limit.
+type TransportConfig struct {
+	MaxIdleConns          int
+	MaxIdleConnsPerHost   int
+	MaxConnsPerHost       int
+	IdleConnTimeout       time.Duration
+	DialTimeout           time.Duration
+	KeepAlive             time.Duration // TCP keep-alive probes; negative disables them
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+}
+
+// HostPoolStats counts the connections to one host
+type HostPoolStats struct {
+	Open       int   `json:"open"`        // connections dialed and not yet closed
+	Dials      int64 `json:"dials"`       // connections dialed
+	DialErrors int64 `json:"dial_errors"` // dials that failed
+	Requests   int64 `json:"requests"`    // requests that got a connection
+	Reused     int64 `json:"reused"`      // of these, on a kept-alive connection
+}
+
+// PoolStats reports the pool's settings and its connections by host
+type PoolStats struct {
+	MaxIdleConnsPerHost int                      `json:"max_idle_conns_per_host"`
+	MaxConnsPerHost     int                      `json:"max_conns_per_host,omitempty"`
+	Hosts               map[string]HostPoolStats `json:"hosts"`
+}
+
+// PooledTransport is the tuned http.Transport of upstream requests. It counts
+// dials, closed connections and reused connections per host, so a pool too
+// small for the traffic (many dials, little reuse) shows up in the metrics.
+type PooledTransport struct {
+	transport *http.Transport
+
+	mu    sync.Mutex
+	hosts map[string]*HostPoolStats
+}
+
+// NewPooledTransport creates a transport with the given pool settings
+func NewPooledTransport(cfg TransportConfig) *PooledTransport {
+	t := &PooledTransport{hosts: make(map[string]*HostPoolStats)}
+	// net/http's defaults, which a wrapped http.DefaultTransport no longer shows
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if cfg.DialTimeout > 0 {
+		dialer.Timeout = cfg.DialTimeout
+	}
+	if cfg.KeepAlive != 0 {
+		dialer.KeepAlive = cfg.KeepAlive
+	}
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		t.count(addr, func(s *HostPoolStats) {
+			s.Dials++
+			if err != nil {
+				s.DialErrors++
+			} else {
+				s.Open++
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &pooledConn{Conn: conn, closed: func() { t.count(addr, func(s *HostPoolStats) { s.Open-- }) }}, nil
+	}
+	if cfg.MaxIdleConns > 0 {
+		transport.MaxIdleConns = cfg.MaxIdleConns
+	}
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	transport.MaxConnsPerHost = cfg.MaxConnsPerHost
+	if cfg.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+	if cfg.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = cfg.TLSHandshakeTimeout
+	}
+	transport.ResponseHeaderTimeout = cfg.ResponseHeaderTimeout
+	t.transport = transport
+	return t
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *PooledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	addr := hostAddr(req.URL)
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.count(addr, func(s *HostPoolStats) {
+				s.Requests++
+				if info.Reused {
+					s.Reused++
+				}
+			})
+		},
+	}
+	return t.transport.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+}
+
+// CloseIdleConnections closes the kept-alive connections that are not in use
+func (t *PooledTransport) CloseIdleConnections() {
+	t.transport.CloseIdleConnections()
+}
+
+// Stats returns the pool's settings and counters
+func (t *PooledTransport) Stats() PoolStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := PoolStats{
+		MaxIdleConnsPerHost: t.transport.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     t.transport.MaxConnsPerHost,
+		Hosts:               make(map[string]HostPoolStats, len(t.hosts)),
+	}
+	if stats.MaxIdleConnsPerHost == 0 {
+		stats.MaxIdleConnsPerHost = http.DefaultMaxIdleConnsPerHost
+	}
+	for host, counts := range t.hosts {
+		stats.Hosts[host] = *counts
+	}
+	return stats
+}
+
+// HostNames returns the hosts the pool has connected to, sorted
+func (s PoolStats) HostNames() []string {
+	names := make([]string, 0, len(s.Hosts))
+	for host := range s.Hosts {
+		names = append(names, host)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (t *PooledTransport) count(addr string, update func(*HostPoolStats)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats, ok := t.hosts[addr]
+	if !ok {
+		stats = &HostPoolStats{}
+		t.hosts[addr] = stats
+	}
+	update(stats)
+}
+
+// hostAddr returns host:port of a URL, as the transport dials it
+func hostAddr(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return u.Host
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+// pooledConn reports when a connection of the pool is closed
+type pooledConn struct {
+	net.Conn
+	once   sync.Once
+	closed func()
+}
+
+func (c *pooledConn) Close() error {
+	c.once.Do(c.closed)
+	return c.Conn.Close()
+}
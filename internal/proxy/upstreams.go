@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"strings"
+)
+
+// Upstream is a separate NocoDB instance or base that individual tables are routed to
+type Upstream struct {
+	Name        string
+	DataURL     string // e.g. http://analytics:8090/api/v3/data/
+	BaseID      string
+	Token       string
+	TokenSource TokenSource
+	Meta        *MetaCache
+	Dialect     *Dialect
+}
+
+// NewUpstream creates a named upstream with its own MetaCache
+func NewUpstream(name, dataURL, baseID, token string, meta *MetaCache) *Upstream {
+	return &Upstream{
+		Name:    name,
+		DataURL: strings.TrimRight(dataURL, "/") + "/",
+		BaseID:  baseID,
+		Token:   token,
+		Meta:    meta,
+		Dialect: NewDialect(dataURL, InferAPIVersion(dataURL)),
+	}
+}
+
+// currentToken returns the token to send to this upstream
+func (u *Upstream) currentToken() string {
+	if u.TokenSource != nil {
+		if token := u.TokenSource(); token != "" {
+			return token
+		}
+	}
+	return u.Token
+}
+
+// Client returns a record client for this upstream
+func (u *Upstream) Client() *UpstreamClient {
+	client := NewUpstreamClient(u.DataURL, u.BaseID, u.Token)
+	client.SetTokenSource(u.TokenSource)
+	client.SetDialect(u.Dialect)
+	return client
+}
+
+// AddUpstream registers a named upstream for tables configured with `upstream: <name>`
+func (p *ProxyHandler) AddUpstream(upstream *Upstream) {
+	if p.upstreams == nil {
+		p.upstreams = make(map[string]*Upstream)
+	}
+	p.upstreams[upstream.Name] = upstream
+	if p.Validator != nil {
+		p.Validator.upstreams = p.upstreams
+	}
+}
+
+// upstreamFor returns the upstream a table is routed to (nil for the default upstream)
+func (p *ProxyHandler) upstreamFor(tableKey string) *Upstream {
+	if p.ResolvedConfig == nil || tableKey == "" {
+		return nil
+	}
+	table, ok := p.ResolvedConfig.Tables[tableKey]
+	if !ok || table.Upstream == "" {
+		return nil
+	}
+	return p.upstreams[table.Upstream]
+}
+
+// tablePrefix returns the data URL (with base ID) that requests for a table are sent to
+func (p *ProxyHandler) tablePrefix(tableKey string) string {
+	if upstream := p.upstreamFor(tableKey); upstream != nil {
+		return upstream.Dialect.DataPrefix(upstream.BaseID)
+	}
+	return p.upstreamPrefix()
+}
+
+// tableToken returns the NocoDB token used for requests to a table
+func (p *ProxyHandler) tableToken(tableKey string) string {
+	if upstream := p.upstreamFor(tableKey); upstream != nil {
+		return upstream.currentToken()
+	}
+	return p.upstreamToken()
+}
+
+// metaFor returns the MetaCache of a named upstream ("" for the default upstream)
+func (v *Validator) metaFor(upstreamName string) *MetaCache {
+	if upstream, ok := v.upstreams[upstreamName]; ok && upstream.Meta != nil {
+		return upstream.Meta
+	}
+	return v.metaCache
+}
+
+// AddRoute sends record queries for a table ID to another upstream's client
+func (c *UpstreamClient) AddRoute(tableID string, client *UpstreamClient) {
+	if c.routes == nil {
+		c.routes = make(map[string]*UpstreamClient)
+	}
+	c.routes[tableID] = client
+}
@@ -1,18 +1,23 @@
 package proxy
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strings"
 
 	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/middleware"
 )
 
 // Validator validates requests against the resolved configuration
 type Validator struct {
 	config    *config.ResolvedConfig
 	metaCache *MetaCache
+	upstreams map[string]*Upstream
 }
 
 // NewValidator creates a new validator with the given resolved configuration
@@ -23,51 +28,90 @@ func NewValidator(config *config.ResolvedConfig, metaCache *MetaCache) *Validato
 	}
 }
 
-// ValidateRequest validates an incoming proxy request
+// ValidateRequest validates an incoming proxy request: Resolve, Authorize and
+// ResolvePath in one call
 func (v *Validator) ValidateRequest(method, path string) (*ValidationResult, error) {
 	log.Printf("[VALIDATOR] Validating request: %s %s", method, path)
 
-	// Parse the path to extract table identifier and operation
-	parts := strings.Split(strings.Trim(path, "/"), "/")
-	if len(parts) == 0 {
-		return nil, fmt.Errorf("invalid path: empty")
+	result, err := v.Resolve(method, ParseRoute(path))
+	if err != nil {
+		return nil, err
+	}
+	if err := v.Authorize(result); err != nil {
+		return nil, err
+	}
+	if err := v.ResolvePath(result); err != nil {
+		return nil, err
 	}
 
-	tableKey := parts[0]
-	log.Printf("[VALIDATOR] Table key: %s", tableKey)
+	log.Printf("[VALIDATOR] Validation successful: %+v", result)
+	return result, nil
+}
+
+// Resolve finds the table of a request in proxy.yaml and the operation it asks for
+func (v *Validator) Resolve(method string, route Route) (*ValidationResult, error) {
+	if route.Table == "" {
+		return nil, fmt.Errorf("invalid path: empty")
+	}
+	log.Printf("[VALIDATOR] Table key: %s", route.Table)
 
 	// Find the table in resolved config
-	table, ok := v.config.Tables[tableKey]
+	table, ok := v.config.Tables[route.Table]
 	if !ok {
-		return nil, fmt.Errorf("table '%s' not found in configuration", tableKey)
+		return nil, fmt.Errorf("table '%s' not found in configuration", route.Table)
 	}
 
-	// Determine the operation from HTTP method and path
-	operation := v.determineOperation(method, parts)
+	operation := route.Operation(method)
 	log.Printf("[VALIDATOR] Operation: %s", operation)
 
-	// Check if operation is allowed
-	if !v.isOperationAllowed(table, operation) {
-		return nil, fmt.Errorf("operation '%s' not allowed for table '%s'", operation, tableKey)
+	return &ValidationResult{
+		TableKey:  route.Table,
+		TableID:   table.TableID,
+		TableName: table.Name,
+		Operation: operation,
+		Route:     route,
+	}, nil
+}
+
+// Authorize checks that the table's mode and operations allow a resolved request
+func (v *Validator) Authorize(result *ValidationResult) error {
+	table := v.config.Tables[result.TableKey]
+
+	// A table's mode freezes it regardless of its operations
+	if !config.ModeAllows(table.Mode, result.Operation) {
+		return fmt.Errorf("table '%s' is %s", result.TableKey, strings.ReplaceAll(table.Mode, "_", "-"))
 	}
 
-	// Build resolved path with link field resolution if needed
-	resolvedPath, err := v.buildResolvedPath(table.TableID, table.Name, parts[1:])
-	if err != nil {
-		return nil, err
+	// Check if operation is allowed
+	if !v.isOperationAllowed(table, result.Operation) {
+		return fmt.Errorf("operation '%s' not allowed for table '%s'", result.Operation, result.TableKey)
 	}
+	result.Allowed = true
+	return nil
+}
+
+// ResolvePath builds the NocoDB path of a resolved request, with link field
+// resolution if needed. In strict mode only the links declared in proxy.yaml
+// may be used.
+func (v *Validator) ResolvePath(result *ValidationResult) error {
+	table := v.config.Tables[result.TableKey]
+	route := result.Route
 
-	result := &ValidationResult{
-		TableKey:     tableKey,
-		TableID:      table.TableID,
-		TableName:    table.Name,
-		Operation:    operation,
-		Allowed:      true,
-		ResolvedPath: resolvedPath,
+	if v.config.Strict && route.Kind == RouteLinks {
+		link, ok := table.Links[route.LinkField]
+		if !ok {
+			return fmt.Errorf("link '%s' is not declared for table '%s'", route.LinkField, result.TableKey)
+		}
+		result.ResolvedPath = table.TableID + "/links/" + link.FieldID + "/" + strings.Join(route.Segments[3:], "/")
+		return nil
 	}
 
-	log.Printf("[VALIDATOR] Validation successful: %+v", result)
-	return result, nil
+	resolvedPath, err := resolvePath(v.metaFor(table.Upstream), table.TableID, table.Name, route)
+	if err != nil {
+		return err
+	}
+	result.ResolvedPath = resolvedPath
+	return nil
 }
 
 // ValidationResult contains the result of request validation
@@ -78,29 +122,14 @@ type ValidationResult struct {
 	Operation    string
 	Allowed      bool
 	ResolvedPath string
-}
-
-// determineOperation determines the operation type from HTTP method and path
-func (v *Validator) determineOperation(method string, parts []string) string {
-	switch method {
-	case http.MethodGet:
-		return "read"
-	case http.MethodPost:
-		if len(parts) > 2 && parts[2] == "links" {
-			return "link"
-		}
-		return "create"
-	case http.MethodPatch, http.MethodPut:
-		return "update"
-	case http.MethodDelete:
-		return "delete"
-	default:
-		return "unknown"
-	}
+	Route        Route
 }
 
 // isOperationAllowed checks if an operation is allowed for a table
 func (v *Validator) isOperationAllowed(table config.ResolvedTable, operation string) bool {
+	if !config.ModeAllows(table.Mode, operation) {
+		return false
+	}
 	for _, allowedOp := range table.Operations {
 		if allowedOp == operation {
 			return true
@@ -109,41 +138,170 @@ func (v *Validator) isOperationAllowed(table config.ResolvedTable, operation str
 	return false
 }
 
-// buildResolvedPath constructs the resolved path with table ID and resolves link field aliases
-// Path format: {tableID}/links/{linkAlias}/{recordId} -> {tableID}/links/{linkFieldID}/{recordId}
-func (v *Validator) buildResolvedPath(tableID, tableName string, remainingParts []string) (string, error) {
-	if len(remainingParts) == 0 {
+// resolvePath builds the NocoDB path of a route below the table prefix, with
+// the table ID in place of its key and the link field ID in place of its alias:
+// {table}/links/{alias}/{recordId} -> {tableID}/links/{linkFieldID}/{recordId}
+func resolvePath(metaCache *MetaCache, tableID, tableName string, route Route) (string, error) {
+	rest := append([]string(nil), route.Rest()...)
+	if len(rest) == 0 {
 		return tableID, nil
 	}
 
-	// Check if this is a link request: /links/{linkAlias}/{recordId}
-	// Pattern: parts[0] = "links", parts[1] = linkAlias, parts[2] = recordId
-	if len(remainingParts) >= 3 && remainingParts[0] == "links" {
-		linkAlias := remainingParts[1]
-		log.Printf("[LINK RESOLVER] Detected link request for table '%s', alias '%s'", tableName, linkAlias)
+	if route.Kind == RouteLinks {
+		log.Printf("[LINK RESOLVER] Detected link request for table '%s', alias '%s'", tableName, route.LinkField)
 
 		// Try to resolve the link field alias to field ID using MetaCache
-		if v.metaCache != nil {
+		if metaCache != nil {
 			// Try direct match first
-			linkFieldID, ok := v.metaCache.ResolveLinkField(tableID, linkAlias)
+			linkFieldID, ok := metaCache.ResolveLinkField(tableID, route.LinkField)
 			if !ok {
-				// Try normalized version (replace spaces/underscores)
-				normalizedAlias := strings.ReplaceAll(linkAlias, "_", " ")
-				linkFieldID, ok = v.metaCache.ResolveLinkField(tableID, normalizedAlias)
+				// Try normalized version (replace underscores with spaces)
+				linkFieldID, ok = metaCache.ResolveLinkField(tableID, strings.ReplaceAll(route.LinkField, "_", " "))
 			}
-
-			if ok {
-				log.Printf("[LINK RESOLVER] %s.%s → %s", tableName, linkAlias, linkFieldID)
-				// Replace the alias with the resolved field ID
-				remainingParts[1] = linkFieldID
-			} else {
-				// Link field not found in cache
-				return "", fmt.Errorf("unknown link field '%s' for table '%s'", linkAlias, tableName)
+			if !ok {
+				return "", fmt.Errorf("unknown link field '%s' for table '%s'", route.LinkField, tableName)
 			}
+			log.Printf("[LINK RESOLVER] %s.%s → %s", tableName, route.LinkField, linkFieldID)
+			rest[1] = linkFieldID
 		} else {
 			log.Printf("[LINK RESOLVER WARNING] MetaCache not available, using alias as-is")
 		}
 	}
 
-	return tableID + "/" + strings.Join(remainingParts, "/"), nil
+	return tableID + "/" + strings.Join(rest, "/"), nil
+}
+
+// ApplyDefaultFields limits a read of the table's records to the fields
+// default_fields lists for the caller's role, when the client asks for none.
+// Roles without an entry get the "*" entry, or every field when there is none;
+// a role listing "*" gets every field too.
+func (v *Validator) ApplyDefaultFields(r *http.Request, result *ValidationResult) {
+	defaults := v.config.Tables[result.TableKey].DefaultFields
+	if len(defaults) == 0 || result.Operation != "read" || result.Route.Kind != RouteRecords {
+		return
+	}
+	query := r.URL.Query()
+	if query.Get("fields") != "" {
+		return
+	}
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+	fields, ok := defaults[role]
+	if !ok {
+		fields = defaults["*"]
+	}
+	if len(fields) == 0 || containsString(fields, "*") {
+		return
+	}
+	query.Set("fields", strings.Join(fields, ","))
+	r.URL.RawQuery = query.Encode()
+}
+
+// ApplyRules evaluates the table's rules block (proxy.yaml `rules:`) for a
+// validated request: reject rules are checked, computed defaults are filled into
+// created records and the rule filter is ANDed to the where clause of reads.
+// It may replace the request body and query. Rejections are *config.RuleError.
+func (v *Validator) ApplyRules(r *http.Request, result *ValidationResult) error {
+	rules := v.config.Tables[result.TableKey].Rules
+	if rules == nil {
+		return nil
+	}
+
+	env := config.RuleEnv{
+		Method:    r.Method,
+		Operation: result.Operation,
+		Table:     result.TableKey,
+		Query:     make(map[string]string),
+	}
+	env.UserID, _ = r.Context().Value(middleware.UserIDKey).(string)
+	env.Role, _ = r.Context().Value(middleware.RoleKey).(string)
+	env.Tenant, _ = r.Context().Value(middleware.TenantKey).(string)
+	for key, values := range r.URL.Query() {
+		env.Query[key] = values[0]
+	}
+
+	if result.Operation == "read" {
+		if err := rules.Check(env); err != nil {
+			return err
+		}
+		where, err := rules.Filter(env)
+		if err != nil || where == "" {
+			return err
+		}
+		query := r.URL.Query()
+		if existing := query.Get("where"); existing != "" {
+			where = "(" + existing + ")~and(" + where + ")"
+		}
+		query.Set("where", where)
+		r.URL.RawQuery = query.Encode()
+		return nil
+	}
+
+	if (result.Operation != "create" && result.Operation != "update") || r.Body == nil {
+		return rules.Check(env)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		// Not JSON: rules see an empty body and NocoDB reports the error
+		return rules.Check(env)
+	}
+	records := ruleRecords(payload)
+	if len(records) == 0 {
+		return rules.Check(env)
+	}
+
+	fillDefaults := result.Operation == "create" && rules.HasDefaults()
+	for _, record := range records {
+		env.Body = record
+		if fillDefaults {
+			if err := rules.ApplyDefaults(env); err != nil {
+				return err
+			}
+		}
+		if err := rules.Check(env); err != nil {
+			return err
+		}
+	}
+
+	if fillDefaults {
+		updated, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(updated))
+		r.ContentLength = int64(len(updated))
+	}
+	return nil
+}
+
+// ruleRecords returns the field maps of a write payload: a record or a list of
+// records, each either flat (v2) or wrapped in "fields" (v3). The maps are
+// shared with payload, so changes show up when it is re-encoded.
+func ruleRecords(payload interface{}) []map[string]interface{} {
+	var items []interface{}
+	switch value := payload.(type) {
+	case []interface{}:
+		items = value
+	case map[string]interface{}:
+		items = []interface{}{value}
+	}
+
+	records := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		record, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if fields, ok := record["fields"].(map[string]interface{}); ok {
+			record = fields
+		}
+		records = append(records, record)
+	}
+	return records
 }
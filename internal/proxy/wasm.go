@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/plugins"
+	"github.com/grove/generic-proxy/internal/wasmfilter"
+)
+
+// SetWasmRuntime enables the wasm_filters declared on tables in proxy.yaml
+func (p *ProxyHandler) SetWasmRuntime(runtime *wasmfilter.Runtime) {
+	p.wasm = runtime
+}
+
+// tableFilters returns the WebAssembly filters of a table
+func (p *ProxyHandler) tableFilters(tableKey string) []config.WasmFilter {
+	if p.wasm == nil || p.ResolvedConfig == nil {
+		return nil
+	}
+	return p.ResolvedConfig.Tables[tableKey].Filters
+}
+
+// filterRequest runs the request phase of a table's filters, applying their
+// header, query and body changes to r. It returns the failing module and a
+// plugins.RejectError when a filter refuses the request.
+func (p *ProxyHandler) filterRequest(r *http.Request, info plugins.RequestInfo) (string, error) {
+	filters := p.tableFilters(info.Table)
+	if len(filters) == 0 {
+		return "", nil
+	}
+
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return "", fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+
+	for _, filter := range filters {
+		msg := filterMessage(wasmfilter.PhaseRequest, info, filter)
+		msg.Query = r.URL.Query()
+		// The client's token never reaches a filter (it is not forwarded upstream either)
+		msg.Headers = r.Header.Clone()
+		msg.Headers.Del("Authorization")
+		msg.Body = string(body)
+
+		verdict, err := p.wasm.Run(r.Context(), filter.Module, wasmfilter.PhaseRequest, msg)
+		if err != nil {
+			return "wasm:" + filter.Module, err
+		}
+		if verdict == nil {
+			continue
+		}
+		if verdict.Rejected() {
+			return "wasm:" + filter.Module, rejection(verdict)
+		}
+		verdict.ApplyHeaders(r.Header)
+		if len(verdict.Query) > 0 {
+			query := r.URL.Query()
+			verdict.ApplyQuery(query)
+			r.URL.RawQuery = query.Encode()
+		}
+		if verdict.Body != nil {
+			body = []byte(*verdict.Body)
+		}
+	}
+
+	if r.Body != nil || len(body) > 0 {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+	}
+	return "", nil
+}
+
+// filterResponse runs the response phase of a table's filters on an upstream response
+func (p *ProxyHandler) filterResponse(r *http.Request, resp *plugins.Response, info plugins.RequestInfo) (string, error) {
+	for _, filter := range p.tableFilters(info.Table) {
+		msg := filterMessage(wasmfilter.PhaseResponse, info, filter)
+		msg.Headers = resp.Header
+		msg.Body = string(resp.Body)
+		msg.Status = resp.StatusCode
+
+		verdict, err := p.wasm.Run(r.Context(), filter.Module, wasmfilter.PhaseResponse, msg)
+		if err != nil {
+			return "wasm:" + filter.Module, err
+		}
+		if verdict == nil {
+			continue
+		}
+		if verdict.Rejected() {
+			return "wasm:" + filter.Module, rejection(verdict)
+		}
+		verdict.ApplyHeaders(resp.Header)
+		if verdict.Status != 0 {
+			resp.StatusCode = verdict.Status
+		}
+		if verdict.Body != nil {
+			resp.Body = []byte(*verdict.Body)
+		}
+	}
+	return "", nil
+}
+
+func filterMessage(phase wasmfilter.Phase, info plugins.RequestInfo, filter config.WasmFilter) *wasmfilter.Message {
+	return &wasmfilter.Message{
+		Phase:  phase,
+		Table:  info.Table,
+		Method: info.Method,
+		Path:   info.Path,
+		User:   wasmfilter.User{ID: info.UserID, Role: info.Role, Tenant: info.Tenant},
+		Config: filter.Config,
+	}
+}
+
+// rejection turns a reject verdict into the error plugins.WriteError reports
+func rejection(verdict *wasmfilter.Verdict) error {
+	status := verdict.Status
+	if status == 0 {
+		status = http.StatusForbidden
+	}
+	message := verdict.Message
+	if message == "" {
+		message = "request rejected by filter"
+	}
+	return plugins.Reject(status, message)
+}
@@ -0,0 +1,376 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/events"
+	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/grove/generic-proxy/internal/utils"
+)
+
+const (
+	wsWriteTimeout    = 10 * time.Second
+	wsPingInterval    = 30 * time.Second
+	wsSnapshotDefault = 100
+)
+
+// WSMessage is a client-to-server message on the /ws endpoint
+type WSMessage struct {
+	Action   string                 `json:"action"` // subscribe or unsubscribe
+	ID       string                 `json:"id"`
+	Table    string                 `json:"table,omitempty"`
+	Filter   map[string]interface{} `json:"filter,omitempty"`
+	Snapshot bool                   `json:"snapshot,omitempty"`
+	Limit    int                    `json:"limit,omitempty"`
+}
+
+// WSReply is a server-to-client message on the /ws endpoint
+type WSReply struct {
+	Type    string                   `json:"type"` // subscribed, unsubscribed, snapshot, event, error
+	ID      string                   `json:"id,omitempty"`
+	Records []map[string]interface{} `json:"records,omitempty"`
+	Event   *events.Event            `json:"event,omitempty"`
+	Error   string                   `json:"error,omitempty"`
+}
+
+// wsSubscription is one active subscription on a connection
+type wsSubscription struct {
+	tableKey string
+	tableID  string
+	table    *config.ResolvedTable
+	rows     RowFilter
+	filter   map[string]interface{}
+}
+
+// WebSocketHandler serves push subscriptions to table changes over WebSockets
+type WebSocketHandler struct {
+	proxy     *ProxyHandler
+	jwtSecret string
+	upgrader  websocket.Upgrader
+}
+
+// NewWebSocketHandler creates a new WebSocket subscription handler
+func NewWebSocketHandler(proxy *ProxyHandler, jwtSecret string) *WebSocketHandler {
+	return &WebSocketHandler{
+		proxy:     proxy,
+		jwtSecret: jwtSecret,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  4096,
+			WriteBufferSize: 4096,
+			// Origin policy is enforced by CORSMiddleware; tokens are required regardless
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// ServeHTTP handles GET /ws. Browsers cannot set headers on WebSocket requests,
+// so the JWT may be passed either as a Bearer header or a ?token= query parameter.
+func (h *WebSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.proxy.Events == nil {
+		http.Error(w, "event streaming not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+		token = strings.TrimPrefix(authHeader, "Bearer ")
+	}
+	if token == "" {
+		http.Error(w, "missing token", http.StatusUnauthorized)
+		return
+	}
+
+	claims, err := utils.ValidateJWT(token, h.jwtSecret)
+	if err != nil {
+		log.Printf("[WS ERROR] JWT validation failed: %v", err)
+		http.Error(w, "invalid or expired token", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("[WS ERROR] Upgrade failed: %v", err)
+		return
+	}
+
+	log.Printf("[WS] Connection opened for user %s (role: %s)", claims.UserID, claims.Role)
+	c := &wsConn{
+		handler: h,
+		conn:    conn,
+		userID:  claims.UserID,
+		role:    claims.Role,
+		tenant:  claims.Tenant,
+		subs:    make(map[string]*wsSubscription),
+		send:    make(chan WSReply, 64),
+	}
+	c.run(r.Context())
+	log.Printf("[WS] Connection closed for user %s", claims.UserID)
+}
+
+// wsConn holds the state of a single WebSocket connection
+type wsConn struct {
+	handler *WebSocketHandler
+	conn    *websocket.Conn
+	userID  string
+	role    string
+	tenant  string
+
+	mu   sync.RWMutex
+	subs map[string]*wsSubscription
+	send chan WSReply
+}
+
+// run pumps bus events and client messages until the connection closes
+func (c *wsConn) run(parent context.Context) {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	defer c.conn.Close()
+
+	ch, unsubscribe := c.handler.proxy.Events.Subscribe(256)
+	defer unsubscribe()
+
+	go c.writeLoop(ctx)
+	go func() {
+		defer cancel()
+		c.readLoop(ctx)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			c.dispatch(event)
+		}
+	}
+}
+
+// readLoop processes subscribe/unsubscribe messages from the client
+func (c *wsConn) readLoop(ctx context.Context) {
+	for {
+		var msg WSMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("[WS] Read error for user %s: %v", c.userID, err)
+			}
+			return
+		}
+
+		switch msg.Action {
+		case "subscribe":
+			c.subscribe(ctx, msg)
+		case "unsubscribe":
+			c.mu.Lock()
+			delete(c.subs, msg.ID)
+			c.mu.Unlock()
+			c.reply(WSReply{Type: "unsubscribed", ID: msg.ID})
+		default:
+			c.reply(WSReply{Type: "error", ID: msg.ID, Error: fmt.Sprintf("unknown action '%s'", msg.Action)})
+		}
+	}
+}
+
+// writeLoop serializes all writes to the connection and sends keep-alive pings
+func (c *wsConn) writeLoop(ctx context.Context) {
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(wsWriteTimeout))
+			return
+		case <-ping.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := c.conn.WriteJSON(msg); err != nil {
+				log.Printf("[WS] Write error for user %s: %v", c.userID, err)
+				return
+			}
+		}
+	}
+}
+
+// reply queues a message for the client, dropping it if the client is too slow
+func (c *wsConn) reply(msg WSReply) {
+	select {
+	case c.send <- msg:
+	default:
+		log.Printf("[WS WARN] Send buffer full for user %s, dropping %s message", c.userID, msg.Type)
+	}
+}
+
+// subscribe authorizes and registers a subscription, optionally sending a snapshot
+func (c *wsConn) subscribe(ctx context.Context, msg WSMessage) {
+	if msg.ID == "" || msg.Table == "" {
+		c.reply(WSReply{Type: "error", ID: msg.ID, Error: "id and table are required"})
+		return
+	}
+
+	// Filters become where clauses of snapshots: they may not add clauses of their own
+	for field, value := range msg.Filter {
+		if err := filterTerm(field, value); err != nil {
+			c.reply(WSReply{Type: "error", ID: msg.ID, Error: "bad request: " + err.Error()})
+			return
+		}
+	}
+
+	// Same validator as REST reads
+	tableID, table, err := c.handler.proxy.resolveTable(http.MethodGet, msg.Table)
+	if err != nil {
+		log.Printf("[WS] Subscription denied for user %s on '%s': %v", c.userID, msg.Table, err)
+		c.reply(WSReply{Type: "error", ID: msg.ID, Error: "forbidden: " + err.Error()})
+		return
+	}
+
+	c.mu.Lock()
+	c.subs[msg.ID] = &wsSubscription{
+		tableKey: msg.Table,
+		tableID:  tableID,
+		table:    table,
+		rows:     StreamFilter(table, msg.Table, c.userID, c.role, c.tenant),
+		filter:   msg.Filter,
+	}
+	c.mu.Unlock()
+
+	log.Printf("[WS] User %s subscribed '%s' to table '%s'", c.userID, msg.ID, msg.Table)
+	c.reply(WSReply{Type: "subscribed", ID: msg.ID})
+
+	if msg.Snapshot {
+		c.sendSnapshot(ctx, msg, table)
+	}
+}
+
+// filterTerm checks a field and value of a subscription filter, which must not
+// contain the characters of NocoDB's where syntax
+func filterTerm(field string, value interface{}) error {
+	if field == "" || strings.ContainsAny(field, "(),~") {
+		return fmt.Errorf("filter field '%s' is not valid", field)
+	}
+	if strings.ContainsAny(fmt.Sprint(value), "(),~") {
+		return fmt.Errorf("filter value of '%s' cannot contain '(', ')', ',' or '~'", field)
+	}
+	return nil
+}
+
+// sendSnapshot reads the current matching records as a REST read of the
+// table by the connection's user, through ServeHTTP: the table's query policy,
+// rules, role token, default fields, decryption and response steps apply as
+// they do to GET /proxy/{table}/records
+func (c *wsConn) sendSnapshot(ctx context.Context, msg WSMessage, table *config.ResolvedTable) {
+	limit := msg.Limit
+	if limit <= 0 || limit > 1000 {
+		limit = wsSnapshotDefault
+	}
+
+	fields := make([]string, 0, len(msg.Filter))
+	for field := range msg.Filter {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	var clauses []string
+	for _, field := range fields {
+		clauses = append(clauses, fmt.Sprintf("(%s,eq,%v)", field, msg.Filter[field]))
+	}
+	if table != nil && table.OwnerField != "" && c.role != "admin" {
+		if err := filterTerm(table.OwnerField, c.userID); err != nil {
+			c.reply(WSReply{Type: "error", ID: msg.ID, Error: "snapshot not available"})
+			return
+		}
+		clauses = append(clauses, fmt.Sprintf("(%s,eq,%s)", table.OwnerField, c.userID))
+	}
+	query := url.Values{}
+	if len(clauses) > 0 {
+		query.Set("where", strings.Join(clauses, "~and"))
+	}
+	query.Set("limit", strconv.Itoa(limit))
+
+	ctx = context.WithValue(ctx, middleware.UserIDKey, c.userID)
+	ctx = context.WithValue(ctx, middleware.RoleKey, c.role)
+	ctx = context.WithValue(ctx, middleware.TenantKey, c.tenant)
+	req, err := http.NewRequestWithContext(context.WithValue(ctx, rawEnvelopeKey, true), http.MethodGet, "/proxy/"+url.PathEscape(msg.Table)+"/records?"+query.Encode(), nil)
+	if err != nil {
+		c.reply(WSReply{Type: "error", ID: msg.ID, Error: "snapshot failed"})
+		return
+	}
+	recorder := httptest.NewRecorder()
+	c.handler.proxy.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		log.Printf("[WS ERROR] Snapshot for '%s' failed with %d", msg.Table, recorder.Code)
+		c.reply(WSReply{Type: "error", ID: msg.ID, Error: "snapshot failed: " + errorMessage(recorder.Body.Bytes())})
+		return
+	}
+	records, _, err := ParseRecordList(recorder.Body.Bytes())
+	if err != nil {
+		log.Printf("[WS ERROR] Snapshot for '%s' failed: %v", msg.Table, err)
+		c.reply(WSReply{Type: "error", ID: msg.ID, Error: "snapshot failed"})
+		return
+	}
+
+	visible := make([]map[string]interface{}, 0, len(records))
+	for _, record := range records {
+		if CanSeeRecord(table, c.role, c.userID, record) {
+			visible = append(visible, record)
+		}
+	}
+	c.reply(WSReply{Type: "snapshot", ID: msg.ID, Records: visible})
+}
+
+// dispatch delivers a bus event to every matching subscription
+func (c *wsConn) dispatch(event events.Event) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for id, sub := range c.subs {
+		if !eventMatchesTable(event, sub.tableKey, sub.tableID) {
+			continue
+		}
+		filtered, visible := FilterEvent(event, sub.table, sub.rows, c.role, c.userID)
+		if !visible || !matchesFilter(filtered, sub.filter) {
+			continue
+		}
+		c.reply(WSReply{Type: "event", ID: id, Event: &filtered})
+	}
+}
+
+// matchesFilter reports whether any record in the event satisfies the equality filter.
+// Events without record data (e.g. bare deletes) pass so clients can evict cached rows.
+func matchesFilter(event events.Event, filter map[string]interface{}) bool {
+	if len(filter) == 0 || (len(event.Records) == 0 && len(event.Previous) == 0) {
+		return true
+	}
+	records := make([]map[string]interface{}, 0, len(event.Records)+len(event.Previous))
+	records = append(records, event.Records...)
+	records = append(records, event.Previous...)
+	for _, record := range records {
+		matched := true
+		for field, want := range filter {
+			if fmt.Sprintf("%v", record[field]) != fmt.Sprintf("%v", want) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,301 @@
+// Package publicview serves NocoDB shared views at /public/{alias} without
+// gateway authentication. The gateway keeps the share UUID and password to
+// itself, caches responses, rate limits clients and projects the returned
+// fields.
+package publicview
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/geoip"
+	"github.com/grove/generic-proxy/internal/proxy"
+	"github.com/grove/generic-proxy/internal/ratelimit"
+)
+
+const (
+	defaultCacheTTL  = 60 * time.Second
+	defaultRateLimit = 60 // per minute
+	defaultBurst     = 20
+	maxCacheEntries  = 1000
+	maxResponseBytes = 10 << 20
+)
+
+// forwardedParams are the query parameters passed on to NocoDB; everything
+// else, fields in particular, is dropped
+var forwardedParams = []string{"limit", "offset", "where", "sort"}
+
+type view struct {
+	rowsURL  string
+	password string
+	fields   map[string]bool
+	ttl      time.Duration
+	limiter  *ratelimit.Limiter
+}
+
+type cacheEntry struct {
+	status  int
+	body    []byte
+	expires time.Time
+}
+
+// Handler serves the public_views of proxy.yaml
+type Handler struct {
+	views      map[string]*view
+	geo        *geoip.Filter
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry // alias + "?" + forwarded query -> response
+}
+
+// New creates the handler, nil when no public views are configured. Passwords
+// are read from their env vars here; a missing one is an error.
+func New(views map[string]config.PublicView, dialect *proxy.Dialect, geo *geoip.Filter) (*Handler, error) {
+	if len(views) == 0 {
+		return nil, nil
+	}
+	h := &Handler{
+		views:      make(map[string]*view, len(views)),
+		geo:        geo,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		cache:      make(map[string]cacheEntry),
+	}
+	for alias, cfg := range views {
+		v := &view{rowsURL: dialect.SharedViewRowsURL(cfg.SharedView), ttl: defaultCacheTTL}
+		if cfg.PasswordEnv != "" {
+			if v.password = os.Getenv(cfg.PasswordEnv); v.password == "" {
+				return nil, fmt.Errorf("public view '%s': %s is not set", alias, cfg.PasswordEnv)
+			}
+		}
+		if len(cfg.Fields) > 0 {
+			v.fields = make(map[string]bool, len(cfg.Fields))
+			for _, field := range cfg.Fields {
+				v.fields[field] = true
+			}
+		}
+		if cfg.CacheTTL != "" {
+			v.ttl, _ = time.ParseDuration(cfg.CacheTTL) // validated by the config loader
+		}
+		perMinute, burst := cfg.RateLimit, cfg.Burst
+		if perMinute == 0 {
+			perMinute = defaultRateLimit
+		}
+		if burst == 0 {
+			burst = defaultBurst
+		}
+		v.limiter = ratelimit.New(perMinute, burst)
+		h.views[alias] = v
+	}
+	return h, nil
+}
+
+// Aliases returns the served aliases
+func (h *Handler) Aliases() []string {
+	aliases := make([]string, 0, len(h.views))
+	for alias := range h.views {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// ServeHTTP handles GET /public/{alias}
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	alias := strings.Trim(strings.TrimPrefix(r.URL.Path, "/public/"), "/")
+	v, ok := h.views[alias]
+	if !ok {
+		respondError(w, http.StatusNotFound, "unknown public view")
+		return
+	}
+
+	client := ratelimit.ClientAddr(r, h.geo)
+	if wait := v.limiter.Take(client); wait > 0 {
+		log.Printf("[PUBLIC] Rate limit exceeded by %s on %s", client, alias)
+		ratelimit.Reject(w, wait)
+		return
+	}
+
+	query := url.Values{}
+	for _, param := range forwardedParams {
+		if value := r.URL.Query().Get(param); value != "" {
+			query.Set(param, value)
+		}
+	}
+	if err := v.checkProjection(query); err != nil {
+		log.Printf("[PUBLIC] Refused query on '%s' from %s: %v", alias, client, err)
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	key := alias + "?" + query.Encode()
+
+	if entry, ok := h.cached(key); ok {
+		writeResponse(w, entry, v.ttl, "hit")
+		return
+	}
+
+	entry, err := h.fetch(r, v, query)
+	if err != nil {
+		log.Printf("[PUBLIC ERROR] Shared view '%s': %v", alias, err)
+		respondError(w, http.StatusBadGateway, "failed to load view")
+		return
+	}
+	if entry.status == http.StatusOK && v.ttl > 0 {
+		h.store(key, entry, v.ttl)
+	}
+	writeResponse(w, entry, v.ttl, "miss")
+}
+
+// checkProjection refuses where and sort terms on fields left out of the
+// view's fields, whose values they would otherwise reveal
+func (v *view) checkProjection(query url.Values) error {
+	if v.fields == nil {
+		return nil
+	}
+	if where := query.Get("where"); where != "" {
+		fields, err := proxy.WhereFields(where)
+		if err != nil {
+			return err
+		}
+		for _, field := range fields {
+			if !v.fields[field] {
+				return fmt.Errorf("cannot filter on field '%s'", field)
+			}
+		}
+	}
+	for _, field := range proxy.SortFields(query.Get("sort")) {
+		if !v.fields[field] {
+			return fmt.Errorf("cannot sort on field '%s'", field)
+		}
+	}
+	return nil
+}
+
+// fetch loads the rows of a shared view and drops the fields not listed
+func (h *Handler) fetch(r *http.Request, v *view, query url.Values) (cacheEntry, error) {
+	target := v.rowsURL
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, target, nil)
+	if err != nil {
+		return cacheEntry{}, err
+	}
+	if v.password != "" {
+		req.Header.Set("xc-password", v.password)
+	}
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return cacheEntry{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return cacheEntry{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		// Upstream details (such as a wrong share password) are not for the public
+		log.Printf("[PUBLIC] NocoDB answered %d: %s", resp.StatusCode, body)
+		if resp.StatusCode == http.StatusNotFound {
+			return cacheEntry{status: http.StatusNotFound, body: errorBody("view not found")}, nil
+		}
+		return cacheEntry{}, fmt.Errorf("NocoDB answered %d", resp.StatusCode)
+	}
+
+	if v.fields != nil {
+		if body, err = project(body, v.fields); err != nil {
+			return cacheEntry{}, err
+		}
+	}
+	return cacheEntry{status: http.StatusOK, body: body}, nil
+}
+
+// project keeps only the listed fields of every row in the list of a rows response
+func project(body []byte, fields map[string]bool) ([]byte, error) {
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("unexpected response: %w", err)
+	}
+	var rows []map[string]json.RawMessage
+	if err := json.Unmarshal(payload["list"], &rows); err != nil {
+		return nil, fmt.Errorf("unexpected response: %w", err)
+	}
+	for _, row := range rows {
+		for field := range row {
+			if !fields[field] {
+				delete(row, field)
+			}
+		}
+	}
+	list, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+	payload["list"] = list
+	return json.Marshal(payload)
+}
+
+func (h *Handler) cached(key string) (cacheEntry, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entry, ok := h.cache[key]
+	if !ok || time.Now().After(entry.expires) {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (h *Handler) store(key string, entry cacheEntry, ttl time.Duration) {
+	now := time.Now()
+	entry.expires = now.Add(ttl)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.cache) >= maxCacheEntries {
+		for k, e := range h.cache {
+			if now.After(e.expires) {
+				delete(h.cache, k)
+			}
+		}
+		// Every entry is live: start over rather than track recency
+		if len(h.cache) >= maxCacheEntries {
+			h.cache = make(map[string]cacheEntry)
+		}
+	}
+	h.cache[key] = entry
+}
+
+func writeResponse(w http.ResponseWriter, entry cacheEntry, ttl time.Duration, cache string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Gateway-Cache", cache)
+	if entry.status == http.StatusOK && ttl > 0 {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(ttl.Seconds())))
+	}
+	w.WriteHeader(entry.status)
+	w.Write(entry.body)
+}
+
+func errorBody(message string) []byte {
+	body, _ := json.Marshal(map[string]string{"error": message})
+	return body
+}
+
+func respondError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(errorBody(message))
+}
@@ -0,0 +1,99 @@
+// Package ratelimit limits unauthenticated traffic per client address with
+// token buckets.
+package ratelimit
+
+import (
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/geoip"
+)
+
+// Limiter gives every client a bucket of burst tokens that refills at the
+// configured rate. A request takes one token.
+type Limiter struct {
+	rate  float64 // tokens per second
+	burst float64
+
+	mu      sync.Mutex
+	clients map[string]*bucket
+	pruned  time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// New creates a limiter allowing perMinute requests per client on average
+// and burst at once
+func New(perMinute, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(burst),
+		clients: make(map[string]*bucket),
+	}
+}
+
+// Take uses one token of a client's bucket. It returns how long the client
+// has to wait when the bucket is empty, 0 when the request may pass.
+func (l *Limiter) Take(client string) time.Duration {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Full buckets carry no state, so idle clients are forgotten
+	if now.Sub(l.pruned) > time.Minute {
+		full := time.Duration(l.burst / l.rate * float64(time.Second))
+		for addr, b := range l.clients {
+			if now.Sub(b.updated) > full {
+				delete(l.clients, addr)
+			}
+		}
+		l.pruned = now
+	}
+
+	b, ok := l.clients[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, updated: now}
+		l.clients[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return 0
+}
+
+// Reject answers 429 with a Retry-After of wait
+func Reject(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]string{"error": "rate limit exceeded"})
+}
+
+// ClientAddr returns the address requests are limited by. The geo-IP filter,
+// when set, resolves clients behind its trusted proxies.
+func ClientAddr(r *http.Request, geo *geoip.Filter) string {
+	if geo != nil {
+		if ip := geo.ClientIP(r); ip != nil {
+			return ip.String()
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,156 @@
+// Package readiness answers GET /readyz from weighted component checks.
+//
+// Each component (metadata, upstream, database, failover, ...) is checked on
+// every request and contributes its weight to a score of 0-100 when it passes.
+// The gateway is ready while the score reaches the minimum score, so
+// deployments decide which failures take an instance out of rotation. The
+// response names the failing components and their errors.
+package readiness
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// checkTimeout bounds each component check
+const checkTimeout = 3 * time.Second
+
+// Check returns an error describing why a component is not healthy
+type Check func(ctx context.Context) error
+
+// ComponentStatus is the outcome of one component's check
+type ComponentStatus struct {
+	Healthy bool   `json:"healthy"`
+	Weight  int    `json:"weight"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Status is the body of GET /readyz
+type Status struct {
+	Ready      bool                       `json:"ready"`
+	Score      int                        `json:"score"` // weight of healthy components, in percent of the total
+	MinScore   int                        `json:"min_score"`
+	Failing    []string                   `json:"failing,omitempty"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+type component struct {
+	name   string
+	weight int
+	check  Check
+}
+
+// Checker runs the component checks of GET /readyz
+type Checker struct {
+	minScore   int
+	weights    map[string]int
+	components []component
+}
+
+// New creates a checker that is ready at minScore (0-100). weights overrides
+// the default weight of components by name; 0 leaves a component out.
+func New(minScore int, weights map[string]int) *Checker {
+	return &Checker{minScore: minScore, weights: weights}
+}
+
+// Add registers a component with its default weight
+func (c *Checker) Add(name string, weight int, check Check) {
+	if override, ok := c.weights[name]; ok {
+		weight = override
+	}
+	if weight <= 0 {
+		log.Printf("[READY] Component '%s' left out of readiness", name)
+		return
+	}
+	c.components = append(c.components, component{name: name, weight: weight, check: check})
+}
+
+// Describe summarizes the components and their weights for the startup log
+func (c *Checker) Describe() string {
+	parts := make([]string, len(c.components))
+	for i, component := range c.components {
+		parts[i] = fmt.Sprintf("%s=%d", component.name, component.weight)
+	}
+	return fmt.Sprintf("%s (ready at score %d)", strings.Join(parts, ", "), c.minScore)
+}
+
+// Evaluate checks every component concurrently and scores the result
+func (c *Checker) Evaluate(ctx context.Context) Status {
+	status := Status{MinScore: c.minScore, Components: make(map[string]ComponentStatus, len(c.components))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, comp := range c.components {
+		wg.Add(1)
+		go func(comp component) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			result := ComponentStatus{Healthy: true, Weight: comp.weight}
+			if err := comp.check(checkCtx); err != nil {
+				result = ComponentStatus{Weight: comp.weight, Error: err.Error()}
+			}
+			mu.Lock()
+			status.Components[comp.name] = result
+			mu.Unlock()
+		}(comp)
+	}
+	wg.Wait()
+
+	total, healthy := 0, 0
+	for name, result := range status.Components {
+		total += result.Weight
+		if result.Healthy {
+			healthy += result.Weight
+		} else {
+			status.Failing = append(status.Failing, name)
+		}
+	}
+	sort.Strings(status.Failing)
+	status.Score = 100
+	if total > 0 {
+		status.Score = healthy * 100 / total
+	}
+	status.Ready = status.Score >= c.minScore
+	return status
+}
+
+// ServeHTTP handles GET /readyz: 200 when ready, 503 otherwise
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status := c.Evaluate(r.Context())
+	code := http.StatusOK
+	if !status.Ready {
+		code = http.StatusServiceUnavailable
+		log.Printf("[READY] Not ready (score %d, minimum %d); failing: %s", status.Score, status.MinScore, strings.Join(status.Failing, ", "))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(status)
+}
+
+// ParseWeights parses "name=weight,name=weight" (READY_WEIGHTS)
+func ParseWeights(raw string) (map[string]int, error) {
+	weights := make(map[string]int)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, found := strings.Cut(part, "=")
+		weight, err := strconv.Atoi(strings.TrimSpace(value))
+		if !found || err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight '%s' (expected name=weight)", part)
+		}
+		weights[strings.TrimSpace(name)] = weight
+	}
+	return weights, nil
+}
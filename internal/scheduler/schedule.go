@@ -0,0 +1,88 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schedule decides when a job runs next
+type Schedule interface {
+	// Next returns the next run time after a run that finished at last
+	Next(last time.Time) time.Time
+	String() string
+}
+
+type every struct {
+	interval time.Duration
+}
+
+// Every runs a job at a fixed interval, measured from the end of the previous run
+func Every(interval time.Duration) Schedule {
+	return every{interval: interval}
+}
+
+func (e every) Next(last time.Time) time.Time { return last.Add(e.interval) }
+func (e every) String() string                { return "every " + e.interval.String() }
+
+type daily struct {
+	hour, minute int
+}
+
+// Daily runs a job once a day at the given local time
+func Daily(hour, minute int) Schedule {
+	return daily{hour: hour, minute: minute}
+}
+
+func (d daily) Next(last time.Time) time.Time {
+	next := time.Date(last.Year(), last.Month(), last.Day(), d.hour, d.minute, 0, 0, last.Location())
+	if !next.After(last) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+func (d daily) String() string { return fmt.Sprintf("daily %02d:%02d", d.hour, d.minute) }
+
+type dynamic struct {
+	label string
+	delay func() time.Duration
+}
+
+// Dynamic asks delay for the wait before each run, for jobs that back off after
+// failures or adapt their pace at runtime
+func Dynamic(label string, delay func() time.Duration) Schedule {
+	return dynamic{label: label, delay: delay}
+}
+
+func (d dynamic) Next(last time.Time) time.Time { return last.Add(d.delay()) }
+func (d dynamic) String() string                { return d.label }
+
+// Parse reads a schedule spec: a Go duration ("15m"), "@every 15m", "@hourly",
+// "@daily" (midnight) or "daily HH:MM"
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	switch {
+	case spec == "@hourly":
+		return Every(time.Hour), nil
+	case spec == "@daily":
+		return Daily(0, 0), nil
+	case strings.HasPrefix(spec, "@every "):
+		spec = strings.TrimSpace(strings.TrimPrefix(spec, "@every "))
+	case strings.HasPrefix(spec, "daily "):
+		at, err := time.Parse("15:04", strings.TrimSpace(strings.TrimPrefix(spec, "daily ")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid time in schedule '%s', expected HH:MM", spec)
+		}
+		return Daily(at.Hour(), at.Minute()), nil
+	}
+
+	interval, err := time.ParseDuration(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule '%s'", spec)
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("schedule interval must be positive, got '%s'", spec)
+	}
+	return Every(interval), nil
+}
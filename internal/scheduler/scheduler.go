@@ -0,0 +1,168 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job is a named periodic task
+type Job struct {
+	Name       string
+	Schedule   Schedule
+	RunAtStart bool // run once immediately instead of waiting for the first scheduled time
+	Run        func(ctx context.Context) error
+}
+
+// JobStatus describes a job and the result of its last run
+type JobStatus struct {
+	Name           string     `json:"name"`
+	Schedule       string     `json:"schedule"`
+	Running        bool       `json:"running"`
+	Runs           int        `json:"runs"`
+	Failures       int        `json:"failures"`
+	LastRun        *time.Time `json:"last_run,omitempty"`
+	LastDurationMS int64      `json:"last_duration_ms,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	NextRun        *time.Time `json:"next_run,omitempty"`
+}
+
+// Scheduler runs periodic jobs and records their results. Each job runs in its
+// own goroutine, so a slow job never delays the others; a job never overlaps
+// with itself.
+type Scheduler struct {
+	mu      sync.Mutex
+	ctx     context.Context // set by Start
+	jobs    map[string]*entry
+	ordered []string
+}
+
+type entry struct {
+	job    Job
+	status JobStatus
+}
+
+// New creates an empty scheduler
+func New() *Scheduler {
+	return &Scheduler{jobs: make(map[string]*entry)}
+}
+
+// Add registers a job. Jobs added after Start begin immediately. Job names must
+// be unique; a second job with the same name is rejected.
+func (s *Scheduler) Add(job Job) error {
+	if job.Name == "" || job.Schedule == nil || job.Run == nil {
+		return fmt.Errorf("job needs a name, a schedule and a run function")
+	}
+
+	s.mu.Lock()
+	if _, exists := s.jobs[job.Name]; exists {
+		s.mu.Unlock()
+		return fmt.Errorf("job '%s' is already scheduled", job.Name)
+	}
+	e := &entry{job: job, status: JobStatus{Name: job.Name, Schedule: job.Schedule.String()}}
+	s.jobs[job.Name] = e
+	s.ordered = append(s.ordered, job.Name)
+	ctx := s.ctx
+	s.mu.Unlock()
+
+	if ctx != nil {
+		go s.loop(ctx, e)
+	}
+	return nil
+}
+
+// Start runs every registered job until the context is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	if s.ctx != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.ctx = ctx
+	entries := make([]*entry, 0, len(s.ordered))
+	for _, name := range s.ordered {
+		entries = append(entries, s.jobs[name])
+	}
+	s.mu.Unlock()
+
+	for _, e := range entries {
+		go s.loop(ctx, e)
+	}
+	log.Printf("[SCHEDULER] Started with %d job(s)", len(entries))
+}
+
+// Status returns the state of every job, sorted by name
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, e := range s.jobs {
+		statuses = append(statuses, e.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// loop waits for each scheduled time and runs the job
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	next := time.Now()
+	if !e.job.RunAtStart {
+		next = e.job.Schedule.Next(next)
+	}
+
+	for {
+		s.setNextRun(e, next)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.run(ctx, e)
+		next = e.job.Schedule.Next(time.Now())
+	}
+}
+
+// run executes one run of a job, turning panics into failures. Jobs log their
+// own errors; the scheduler only records them.
+func (s *Scheduler) run(ctx context.Context, e *entry) {
+	started := time.Now()
+	s.mu.Lock()
+	e.status.Running = true
+	e.status.NextRun = nil
+	s.mu.Unlock()
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+				log.Printf("[SCHEDULER] Job '%s' panicked: %v", e.job.Name, r)
+			}
+		}()
+		return e.job.Run(ctx)
+	}()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.status.Running = false
+	e.status.Runs++
+	e.status.LastRun = &started
+	e.status.LastDurationMS = time.Since(started).Milliseconds()
+	e.status.LastError = ""
+	if err != nil {
+		e.status.Failures++
+		e.status.LastError = err.Error()
+	}
+}
+
+func (s *Scheduler) setNextRun(e *entry, next time.Time) {
+	s.mu.Lock()
+	e.status.NextRun = &next
+	s.mu.Unlock()
+}
@@ -0,0 +1,144 @@
+package search
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/grove/generic-proxy/internal/proxy"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+	// overfetch leaves room for hits removed by row-level filtering
+	overfetch = 5
+)
+
+// HitResponse is a single result of GET /search
+type HitResponse struct {
+	Table  string                 `json:"table"`
+	ID     string                 `json:"id"`
+	Score  float64                `json:"score"`
+	Record map[string]interface{} `json:"record"`
+}
+
+// Handler serves full-text search over mirrored tables
+type Handler struct {
+	index  *Index
+	mirror proxy.MirrorReader
+	config *config.ResolvedConfig
+}
+
+// NewHandler creates a new search handler
+func NewHandler(index *Index, mirror proxy.MirrorReader, resolvedConfig *config.ResolvedConfig) *Handler {
+	return &Handler{
+		index:  index,
+		mirror: mirror,
+		config: resolvedConfig,
+	}
+}
+
+// ServeHTTP handles GET /search?q=...&table=a,b&limit=20
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	text := strings.TrimSpace(query.Get("q"))
+	if text == "" {
+		respondError(w, http.StatusBadRequest, "missing q parameter")
+		return
+	}
+
+	limit := defaultLimit
+	if n, err := strconv.Atoi(query.Get("limit")); err == nil && n > 0 {
+		limit = n
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	tables := h.index.Tables()
+	if requested := query.Get("table"); requested != "" {
+		tables = nil
+		for _, tableKey := range strings.Split(requested, ",") {
+			tableKey = strings.TrimSpace(tableKey)
+			if _, ok := h.index.tables[tableKey]; !ok {
+				respondError(w, http.StatusBadRequest, "table '"+tableKey+"' is not searchable")
+				return
+			}
+			tables = append(tables, tableKey)
+		}
+	}
+	tables = h.readableTables(tables)
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+
+	hits, err := h.index.Search(text, tables, limit*overfetch)
+	if err != nil {
+		log.Printf("[SEARCH ERROR] Query '%s' failed: %v", text, err)
+		respondError(w, http.StatusInternalServerError, "search failed")
+		return
+	}
+
+	results := make([]HitResponse, 0, limit)
+	for _, hit := range hits {
+		if len(results) >= limit {
+			break
+		}
+		record, _, ok := h.mirror.Get(hit.TableKey, hit.RecordID)
+		if !ok {
+			continue
+		}
+		table := h.config.Tables[hit.TableKey]
+		if !proxy.CanSeeRecord(&table, role, userID, record) {
+			continue
+		}
+		results = append(results, HitResponse{
+			Table:  hit.TableKey,
+			ID:     hit.RecordID,
+			Score:  hit.Score,
+			Record: proxy.TransformRecord(&table, role, record),
+		})
+	}
+
+	log.Printf("[SEARCH] User %s searched '%s' in %v: %d result(s)", userID, text, tables, len(results))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"query": text,
+		"hits":  results,
+	})
+}
+
+// readableTables keeps only the tables that allow read operations
+func (h *Handler) readableTables(tables []string) []string {
+	readable := make([]string, 0, len(tables))
+	for _, tableKey := range tables {
+		if !config.ModeAllows(h.config.Tables[tableKey].Mode, "read") {
+			continue
+		}
+		for _, op := range h.config.Tables[tableKey].Operations {
+			if op == "read" {
+				readable = append(readable, tableKey)
+				break
+			}
+		}
+	}
+	return readable
+}
+
+// respondError writes a JSON error response
+func respondError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,229 @@
+package search
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/proxy"
+)
+
+// tableField holds the table key of each indexed document
+const tableField = "gateway_table"
+
+// Hit is a single ranked search result
+type Hit struct {
+	TableKey string
+	RecordID string
+	Score    float64
+}
+
+// Index is an in-memory full-text index over mirrored tables. It is rebuilt from
+// the local mirror at startup and kept current by the mirror syncer.
+type Index struct {
+	index  bleve.Index
+	tables map[string]config.ResolvedTable
+
+	mu  sync.Mutex
+	ids map[string]map[string]struct{} // table key -> indexed record IDs
+}
+
+// NewIndex creates an index for every table with search.enabled in the resolved config
+func NewIndex(resolvedConfig *config.ResolvedConfig) (*Index, error) {
+	tables := make(map[string]config.ResolvedTable)
+	if resolvedConfig != nil {
+		for tableKey, table := range resolvedConfig.Tables {
+			if table.Search != nil && table.Search.Enabled {
+				tables[tableKey] = table
+			}
+		}
+	}
+
+	tableMapping := bleve.NewKeywordFieldMapping()
+	tableMapping.IncludeInAll = false
+	docMapping := bleve.NewDocumentMapping()
+	docMapping.AddFieldMappingsAt(tableField, tableMapping)
+
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = docMapping
+
+	index, err := bleve.NewMemOnly(indexMapping)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create search index: %w", err)
+	}
+
+	return &Index{
+		index:  index,
+		tables: tables,
+		ids:    make(map[string]map[string]struct{}),
+	}, nil
+}
+
+// Tables returns the keys of all searchable tables
+func (i *Index) Tables() []string {
+	keys := make([]string, 0, len(i.tables))
+	for tableKey := range i.tables {
+		keys = append(keys, tableKey)
+	}
+	return keys
+}
+
+// Load indexes the current contents of the mirror for every searchable table
+func (i *Index) Load(mirror proxy.MirrorReader) {
+	for tableKey := range i.tables {
+		records, _, ok := mirror.List(tableKey)
+		if !ok {
+			continue
+		}
+		byID := make(map[string]map[string]interface{}, len(records))
+		for _, record := range records {
+			if id := proxy.RecordID(record); id != "" {
+				byID[id] = record
+			}
+		}
+		i.IndexTable(tableKey, byID)
+	}
+}
+
+// IndexTable replaces the indexed documents of a table with a full snapshot
+func (i *Index) IndexTable(tableKey string, records map[string]map[string]interface{}) {
+	table, ok := i.tables[tableKey]
+	if !ok {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	batch := i.index.NewBatch()
+	for id := range i.ids[tableKey] {
+		if _, ok := records[id]; !ok {
+			batch.Delete(docID(tableKey, id))
+		}
+	}
+
+	ids := make(map[string]struct{}, len(records))
+	for id, record := range records {
+		if err := batch.Index(docID(tableKey, id), document(tableKey, &table, record)); err != nil {
+			log.Printf("[SEARCH ERROR] Failed to index %s/%s: %v", tableKey, id, err)
+			continue
+		}
+		ids[id] = struct{}{}
+	}
+
+	if err := i.index.Batch(batch); err != nil {
+		log.Printf("[SEARCH ERROR] Failed to index table '%s': %v", tableKey, err)
+		return
+	}
+	i.ids[tableKey] = ids
+	log.Printf("[SEARCH] Indexed %d record(s) for table '%s'", len(ids), tableKey)
+}
+
+// IndexRecord adds or replaces a single document
+func (i *Index) IndexRecord(tableKey, recordID string, record map[string]interface{}) {
+	table, ok := i.tables[tableKey]
+	if !ok {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if err := i.index.Index(docID(tableKey, recordID), document(tableKey, &table, record)); err != nil {
+		log.Printf("[SEARCH ERROR] Failed to index %s/%s: %v", tableKey, recordID, err)
+		return
+	}
+	if i.ids[tableKey] == nil {
+		i.ids[tableKey] = make(map[string]struct{})
+	}
+	i.ids[tableKey][recordID] = struct{}{}
+}
+
+// DeleteRecord removes a single document
+func (i *Index) DeleteRecord(tableKey, recordID string) {
+	if _, ok := i.tables[tableKey]; !ok {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if err := i.index.Delete(docID(tableKey, recordID)); err != nil {
+		log.Printf("[SEARCH ERROR] Failed to remove %s/%s from index: %v", tableKey, recordID, err)
+		return
+	}
+	delete(i.ids[tableKey], recordID)
+}
+
+// Search runs a relevance-ranked, typo-tolerant query restricted to the given tables
+func (i *Index) Search(text string, tables []string, size int) ([]Hit, error) {
+	words := strings.Fields(strings.ToLower(text))
+	if len(tables) == 0 || len(words) == 0 {
+		return nil, nil
+	}
+
+	// Exact matches rank above fuzzy (one edit away) matches
+	exact := bleve.NewMatchQuery(text)
+	exact.SetBoost(2)
+	fuzzy := bleve.NewMatchQuery(text)
+	fuzzy.SetFuzziness(1)
+	// Prefix-match the last word so partially typed queries still find results
+	prefix := bleve.NewPrefixQuery(words[len(words)-1])
+
+	tableQueries := make([]query.Query, 0, len(tables))
+	for _, tableKey := range tables {
+		term := bleve.NewTermQuery(tableKey)
+		term.SetField(tableField)
+		tableQueries = append(tableQueries, term)
+	}
+
+	q := bleve.NewConjunctionQuery(
+		bleve.NewDisjunctionQuery(exact, fuzzy, prefix),
+		bleve.NewDisjunctionQuery(tableQueries...),
+	)
+
+	result, err := i.index.Search(bleve.NewSearchRequestOptions(q, size, 0, false))
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]Hit, 0, len(result.Hits))
+	for _, match := range result.Hits {
+		parts := strings.SplitN(match.ID, "/", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		hits = append(hits, Hit{TableKey: parts[0], RecordID: parts[1], Score: match.Score})
+	}
+	return hits, nil
+}
+
+// docID builds the index document ID for a record
+func docID(tableKey, recordID string) string {
+	return tableKey + "/" + recordID
+}
+
+// document extracts the searchable text of a record
+func document(tableKey string, table *config.ResolvedTable, record map[string]interface{}) map[string]interface{} {
+	doc := map[string]interface{}{tableField: tableKey}
+
+	if len(table.Search.Fields) > 0 {
+		for _, field := range table.Search.Fields {
+			if value, ok := record[field]; ok && value != nil {
+				doc[field] = fmt.Sprintf("%v", value)
+			}
+		}
+		return doc
+	}
+
+	for field, value := range record {
+		if text, ok := value.(string); ok && field != tableField {
+			doc[field] = text
+		}
+	}
+	return doc
+}
@@ -0,0 +1,83 @@
+package tenancy
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+// MembershipAdmin manages tenant memberships
+type MembershipAdmin interface {
+	MembershipStore
+	AddUserTenant(userID, tenant string) error
+	RemoveUserTenant(userID, tenant string) error
+	GetUserTenants(userID string) ([]string, error)
+}
+
+// membershipRequest is the body of POST/DELETE /admin/tenants/members
+type membershipRequest struct {
+	UserID string `json:"user_id"`
+	Tenant string `json:"tenant"`
+}
+
+// AdminHandler lets admins list, grant and revoke tenant memberships
+type AdminHandler struct {
+	store MembershipAdmin
+}
+
+// NewAdminHandler creates a new membership admin handler
+func NewAdminHandler(store MembershipAdmin) *AdminHandler {
+	return &AdminHandler{store: store}
+}
+
+// ServeHTTP handles GET ?user_id=, POST and DELETE on /admin/tenants/members.
+// Must run after middleware.AuthMiddleware.
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if role, _ := r.Context().Value(middleware.RoleKey).(string); role != "admin" {
+		respondError(w, http.StatusForbidden, "admin role required")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		userID := r.URL.Query().Get("user_id")
+		if userID == "" {
+			respondError(w, http.StatusBadRequest, "user_id is required")
+			return
+		}
+		tenants, err := h.store.GetUserTenants(userID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to list tenants")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"user_id": userID, "tenants": tenants})
+
+	case http.MethodPost, http.MethodDelete:
+		var req membershipRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID == "" || req.Tenant == "" {
+			respondError(w, http.StatusBadRequest, "user_id and tenant are required")
+			return
+		}
+
+		var err error
+		if r.Method == http.MethodPost {
+			err = h.store.AddUserTenant(req.UserID, req.Tenant)
+		} else {
+			err = h.store.RemoveUserTenant(req.UserID, req.Tenant)
+		}
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to update membership")
+			return
+		}
+
+		adminID, _ := r.Context().Value(middleware.UserIDKey).(string)
+		log.Printf("[TENANCY] Admin %s %s membership of user %s in tenant '%s'", adminID, r.Method, req.UserID, req.Tenant)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
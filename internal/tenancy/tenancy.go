@@ -0,0 +1,106 @@
+package tenancy
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+// HeaderTenant selects a tenant for tokens issued without a tenant claim
+const HeaderTenant = "X-Tenant-ID"
+
+// MembershipStore checks which tenants a user belongs to
+type MembershipStore interface {
+	UserHasTenant(userID, tenant string) (bool, error)
+}
+
+// Middleware determines the tenant of a request. A tenant claim in the JWT takes
+// precedence; the X-Tenant-ID header may only repeat it or, for tokens without a
+// claim, select a tenant the user is a member of. Requests without a tenant are rejected.
+// Must run after middleware.AuthMiddleware.
+func Middleware(store MembershipStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+			claim, _ := r.Context().Value(middleware.TenantKey).(string)
+			header := r.Header.Get(HeaderTenant)
+
+			tenant := claim
+			switch {
+			case claim != "" && header != "" && header != claim:
+				log.Printf("[TENANCY ERROR] User %s sent tenant header '%s' but token is scoped to '%s'", userID, header, claim)
+				respondError(w, http.StatusForbidden, "tenant mismatch")
+				return
+			case claim == "" && header != "":
+				member, err := store.UserHasTenant(userID, header)
+				if err != nil {
+					respondError(w, http.StatusInternalServerError, "failed to verify tenant")
+					return
+				}
+				if !member {
+					log.Printf("[TENANCY ERROR] User %s is not a member of tenant '%s'", userID, header)
+					respondError(w, http.StatusForbidden, "not a member of tenant")
+					return
+				}
+				tenant = header
+			}
+
+			if tenant == "" {
+				respondError(w, http.StatusForbidden, "tenant required")
+				return
+			}
+
+			log.Printf("[TENANCY] Request from user %s scoped to tenant '%s'", userID, tenant)
+			ctx := context.WithValue(r.Context(), middleware.TenantKey, tenant)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Router dispatches requests to the handler of the tenant selected by Middleware
+type Router struct {
+	tenants map[string]http.Handler
+}
+
+// NewRouter creates an empty tenant router
+func NewRouter() *Router {
+	return &Router{tenants: make(map[string]http.Handler)}
+}
+
+// Add registers the handler for a tenant
+func (t *Router) Add(tenant string, handler http.Handler) {
+	t.tenants[tenant] = handler
+}
+
+// Tenants returns the names of all registered tenants
+func (t *Router) Tenants() []string {
+	names := make([]string, 0, len(t.tenants))
+	for name := range t.tenants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ServeHTTP forwards the request to its tenant's handler. Unknown tenants are
+// rejected rather than falling back to a shared upstream.
+func (t *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tenant, _ := r.Context().Value(middleware.TenantKey).(string)
+	handler, ok := t.tenants[tenant]
+	if !ok {
+		log.Printf("[TENANCY ERROR] No upstream configured for tenant '%s'", tenant)
+		respondError(w, http.StatusForbidden, "unknown tenant")
+		return
+	}
+	handler.ServeHTTP(w, r)
+}
+
+func respondError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
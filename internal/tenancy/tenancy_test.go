@@ -0,0 +1,320 @@
+package tenancy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/events"
+	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/grove/generic-proxy/internal/mocknocodb"
+	"github.com/grove/generic-proxy/internal/proxy"
+)
+
+// memberships is a MembershipStore of user ID -> tenants
+type memberships map[string][]string
+
+func (m memberships) UserHasTenant(userID, tenant string) (bool, error) {
+	for _, t := range m[userID] {
+		if t == tenant {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// tenantRequest builds a request as AuthMiddleware leaves it: claim is the
+// token's tenant claim ("" for none), header the X-Tenant-ID header
+func tenantRequest(userID, claim, header string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/proxy/quotes/records", nil)
+	if header != "" {
+		r.Header.Set(HeaderTenant, header)
+	}
+	ctx := context.WithValue(r.Context(), middleware.UserIDKey, userID)
+	ctx = context.WithValue(ctx, middleware.RoleKey, "user")
+	if claim != "" {
+		ctx = context.WithValue(ctx, middleware.TenantKey, claim)
+	}
+	return r.WithContext(ctx)
+}
+
+// serveTenant runs a request through Middleware and returns the status and the
+// tenant the next handler saw ("" when it was not called)
+func serveTenant(store MembershipStore, r *http.Request) (int, string) {
+	var seen string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = r.Context().Value(middleware.TenantKey).(string)
+	})
+	w := httptest.NewRecorder()
+	Middleware(store)(next).ServeHTTP(w, r)
+	return w.Code, seen
+}
+
+func TestHeaderCannotSelectOtherTenant(t *testing.T) {
+	store := memberships{"u1": {"acme"}}
+
+	status, tenant := serveTenant(store, tenantRequest("u1", "", "beta"))
+	if status != http.StatusForbidden || tenant != "" {
+		t.Fatalf("header for a tenant the user is not a member of: got %d, tenant '%s'; want 403", status, tenant)
+	}
+
+	status, tenant = serveTenant(store, tenantRequest("u1", "", "acme"))
+	if status != http.StatusOK || tenant != "acme" {
+		t.Fatalf("header for the user's tenant: got %d, tenant '%s'; want 200, 'acme'", status, tenant)
+	}
+
+	status, tenant = serveTenant(store, tenantRequest("u1", "", ""))
+	if status != http.StatusForbidden || tenant != "" {
+		t.Fatalf("no claim and no header: got %d, tenant '%s'; want 403", status, tenant)
+	}
+}
+
+func TestHeaderConflictingWithClaim(t *testing.T) {
+	// Membership of the other tenant does not matter: the claim wins
+	store := memberships{"u1": {"acme", "beta"}}
+
+	status, tenant := serveTenant(store, tenantRequest("u1", "acme", "beta"))
+	if status != http.StatusForbidden || tenant != "" {
+		t.Fatalf("header conflicting with the claim: got %d, tenant '%s'; want 403", status, tenant)
+	}
+
+	status, tenant = serveTenant(store, tenantRequest("u1", "acme", "acme"))
+	if status != http.StatusOK || tenant != "acme" {
+		t.Fatalf("header repeating the claim: got %d, tenant '%s'; want 200, 'acme'", status, tenant)
+	}
+}
+
+// upstreamCall is a request that reached the NocoDB server
+type upstreamCall struct {
+	base, token, path string
+}
+
+// tenantUpstream is one NocoDB host serving a base per tenant, each checking
+// its own token, that records the requests it gets
+type tenantUpstream struct {
+	url    string
+	tokens map[string]string // base ID -> token
+
+	mu    sync.Mutex
+	calls []upstreamCall
+}
+
+// takeCalls returns the recorded requests and forgets them
+func (u *tenantUpstream) takeCalls() []upstreamCall {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	calls := u.calls
+	u.calls = nil
+	return calls
+}
+
+// newTenantUpstream serves the bases acme_base and beta_base, each with a
+// Quotes table holding one record titled after the base
+func newTenantUpstream(t *testing.T) *tenantUpstream {
+	t.Helper()
+	u := &tenantUpstream{tokens: map[string]string{"acme_base": "acme-token", "beta_base": "beta-token"}}
+	bases := make(map[string]*mocknocodb.Server)
+	for base, token := range u.tokens {
+		mock, err := mocknocodb.New(&mocknocodb.Fixtures{
+			BaseID: base,
+			Tables: []mocknocodb.TableFixture{{
+				ID:      base + "_quotes",
+				Title:   "Quotes",
+				Fields:  []mocknocodb.FieldFixture{{Title: "Title"}},
+				Records: []map[string]interface{}{{"Title": base + " quote"}},
+			}},
+		}, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bases[base] = mock
+	}
+
+	nocodb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		base := ""
+		switch {
+		case len(segments) > 4 && segments[2] == "meta" && segments[3] == "bases":
+			base = segments[4]
+		case len(segments) > 3 && segments[2] == "data":
+			base = segments[3]
+		}
+		u.mu.Lock()
+		u.calls = append(u.calls, upstreamCall{base: base, token: r.Header.Get("xc-token"), path: r.URL.Path})
+		u.mu.Unlock()
+		mock, ok := bases[base]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		mock.ServeHTTP(w, r)
+	}))
+	t.Cleanup(nocodb.Close)
+	u.url = nocodb.URL
+	return u
+}
+
+// tenantProxyConfig has the tenants acme and beta, sharing the quotes table
+var tenantProxyConfig = &config.ProxyConfig{
+	Tables: map[string]config.TableConfig{"quotes": {Name: "Quotes", Operations: []string{"read"}}},
+	Tenants: map[string]config.TenantConfig{
+		"acme": {BaseID: "acme_base", TokenEnv: "ACME_NOCODB_TOKEN"},
+		"beta": {BaseID: "beta_base", TokenEnv: "BETA_NOCODB_TOKEN"},
+	},
+}
+
+// newTenantRouter builds a proxy handler per tenant, as main does, all of
+// them publishing to bus
+func newTenantRouter(t *testing.T, u *tenantUpstream, bus *events.Bus) *Router {
+	t.Helper()
+	router := NewRouter()
+	for name, tenant := range tenantProxyConfig.Tenants {
+		token := u.tokens[tenant.BaseID]
+		meta := proxy.NewMetaCache(u.url+"/api/v2/", tenant.BaseID, token)
+		if err := meta.LoadInitial(); err != nil {
+			t.Fatalf("tenant '%s': %v", name, err)
+		}
+		tenantConfig, _ := tenantProxyConfig.ForTenant(name)
+		resolved, err := config.NewResolver(meta).Resolve(tenantConfig)
+		if err != nil {
+			t.Fatalf("tenant '%s': %v", name, err)
+		}
+		handler := proxy.NewProxyHandler(u.url+"/api/v3/data/"+tenant.BaseID+"/", token, meta)
+		handler.SetEventBus(bus)
+		handler.SetResolvedConfig(resolved)
+		router.Add(name, handler)
+	}
+	return router
+}
+
+func TestTenantsReachOwnBaseAndToken(t *testing.T) {
+	upstream := newTenantUpstream(t)
+	router := newTenantRouter(t, upstream, events.NewBus())
+	store := memberships{"u1": {"acme"}, "u2": {"beta"}}
+	gateway := Middleware(store)(router)
+	upstream.takeCalls()
+
+	for _, tc := range []struct {
+		userID, claim, header, tenant string
+	}{
+		{"u1", "acme", "", "acme"},
+		{"u2", "", "beta", "beta"},
+	} {
+		w := httptest.NewRecorder()
+		gateway.ServeHTTP(w, tenantRequest(tc.userID, tc.claim, tc.header))
+		if w.Code != http.StatusOK {
+			t.Fatalf("tenant '%s': got %d: %s", tc.tenant, w.Code, w.Body.String())
+		}
+
+		base := tenantProxyConfig.Tenants[tc.tenant].BaseID
+		for other := range upstream.tokens {
+			if other != base && strings.Contains(w.Body.String(), other+" quote") {
+				t.Errorf("tenant '%s' read a record of base '%s': %s", tc.tenant, other, w.Body.String())
+			}
+		}
+		if !strings.Contains(w.Body.String(), base+" quote") {
+			t.Errorf("tenant '%s' did not read its own record: %s", tc.tenant, w.Body.String())
+		}
+
+		calls := upstream.takeCalls()
+		if len(calls) == 0 {
+			t.Errorf("tenant '%s': no request reached NocoDB", tc.tenant)
+		}
+		for _, call := range calls {
+			if call.base != base || call.token != upstream.tokens[base] {
+				t.Errorf("tenant '%s' sent %s to base '%s' with token '%s'; want base '%s' with '%s'",
+					tc.tenant, call.path, call.base, call.token, base, upstream.tokens[base])
+			}
+		}
+	}
+
+	// A tenant without a handler is refused, not sent to another tenant's base
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, tenantRequest("u3", "gamma", ""))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("unknown tenant: got %d; want 403", w.Code)
+	}
+}
+
+// streamRecorder is a ResponseWriter for event streams that can be read while
+// the handler writes to it
+type streamRecorder struct {
+	header http.Header
+
+	mu   sync.Mutex
+	body strings.Builder
+	code int
+}
+
+func (s *streamRecorder) Header() http.Header { return s.header }
+
+func (s *streamRecorder) WriteHeader(code int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.code = code
+}
+
+func (s *streamRecorder) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.body.Write(p)
+}
+
+func (s *streamRecorder) Flush() {}
+
+func (s *streamRecorder) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.body.String()
+}
+
+// waitFor polls the stream until it contains text
+func (s *streamRecorder) waitFor(text string) bool {
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if strings.Contains(s.String(), text) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTenantEventStreamsAreIsolated(t *testing.T) {
+	upstream := newTenantUpstream(t)
+	bus := events.NewBus()
+	router := newTenantRouter(t, upstream, bus)
+	gateway := Middleware(memberships{})(router)
+
+	// A user of acme streams the changes of quotes
+	r := tenantRequest("u1", "acme", "")
+	r.URL.Path = "/proxy/quotes/events"
+	ctx, cancel := context.WithCancel(r.Context())
+	stream := &streamRecorder{header: make(http.Header)}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		gateway.ServeHTTP(stream, r.WithContext(ctx))
+	}()
+	if !stream.waitFor("streaming changes") {
+		cancel()
+		t.Fatalf("stream did not start: %s", stream.String())
+	}
+
+	// Both tenants write to their own quotes table; the beta write comes first
+	bus.Publish(events.Event{Type: events.TypeRecordUpdated, Source: events.SourceProxy, TableID: "beta_base_quotes", TableName: "quotes", RecordID: "beta-1", UserID: "u2"})
+	bus.Publish(events.Event{Type: events.TypeRecordUpdated, Source: events.SourceProxy, TableID: "acme_base_quotes", TableName: "quotes", RecordID: "acme-1", UserID: "u1"})
+	received := stream.waitFor("acme-1")
+	cancel()
+	<-done
+
+	if !received {
+		t.Fatalf("acme's event did not reach its subscriber: %s", stream.String())
+	}
+	if strings.Contains(stream.String(), "beta-1") || strings.Contains(stream.String(), "u2") {
+		t.Fatalf("beta's event reached an acme subscriber: %s", stream.String())
+	}
+}
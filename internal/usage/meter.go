@@ -0,0 +1,291 @@
+// Package usage meters data API traffic per user and enforces the quotas
+// configured in proxy.yaml.
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/grove/generic-proxy/internal/scheduler"
+)
+
+// FlushInterval is how often counted traffic is written to the database
+const FlushInterval = 30 * time.Second
+
+const (
+	dayFormat   = "2006-01-02"
+	monthFormat = "2006-01"
+)
+
+type counts struct {
+	requests, bytesIn, bytesOut int64
+}
+
+func (c *counts) add(o counts) {
+	c.requests += o.requests
+	c.bytesIn += o.bytesIn
+	c.bytesOut += o.bytesOut
+}
+
+// userUsage holds a user's totals for the current day and month, including
+// traffic that has not been flushed yet
+type userUsage struct {
+	day, month string
+	today      counts
+	thisMonth  counts
+}
+
+// Meter counts requests and body bytes per user and UTC day. Counts are kept
+// in memory and flushed to the database periodically, so quotas are checked
+// without a database round trip. With several gateway instances each enforces
+// the quota against its own traffic plus what the others have flushed.
+type Meter struct {
+	database *db.Database
+	quotas   *config.QuotaConfig
+
+	flushMu  sync.Mutex // serializes Flush
+	mu       sync.Mutex
+	users    map[string]*userUsage
+	pending  map[dayKey]counts // not yet flushed
+	flushing map[dayKey]counts // being written by Flush
+}
+
+type dayKey struct {
+	userID, day string
+}
+
+// NewMeter creates a meter. quotas may be nil to only meter.
+func NewMeter(database *db.Database, quotas *config.QuotaConfig) *Meter {
+	return &Meter{
+		database: database,
+		quotas:   quotas,
+		users:    make(map[string]*userUsage),
+		pending:  make(map[dayKey]counts),
+	}
+}
+
+// Schedule registers the periodic flush as a scheduler job
+func (m *Meter) Schedule(jobs *scheduler.Scheduler) error {
+	return jobs.Add(scheduler.Job{
+		Name:     "usage:flush",
+		Schedule: scheduler.Every(FlushInterval),
+		Run:      func(context.Context) error { return m.Flush() },
+	})
+}
+
+// Middleware meters the requests of authenticated users and rejects them with
+// 429 once a quota is used up. Must run after middleware.AuthMiddleware.
+func (m *Meter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+		role, _ := r.Context().Value(middleware.RoleKey).(string)
+		if userID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if exceeded := m.Exceeded(userID, role); exceeded != nil {
+			log.Printf("[USAGE] User %s exceeded %s quota (%d/%d)", userID, exceeded.Name, exceeded.Used, exceeded.Max)
+			retryAfter := int(time.Until(exceeded.ResetsAt).Seconds()) + 1
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error": fmt.Sprintf("%s quota exceeded", exceeded.Name),
+				"quota": exceeded,
+			})
+			return
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), meterKey{}, m))
+		body := &countingReader{ReadCloser: r.Body}
+		if r.Body != nil {
+			r.Body = body
+		}
+		cw := &countingWriter{ResponseWriter: w}
+		next.ServeHTTP(cw, r)
+		m.Record(userID, counts{requests: 1, bytesIn: body.n, bytesOut: cw.n})
+	})
+}
+
+type meterKey struct{}
+
+// CountOperation counts one more request against the quota of the user of a
+// metered request, for handlers that run several operations per request
+// (POST /proxy/_batch). It returns the limit that refuses the operation, nil
+// when it was counted or the request is not metered.
+func CountOperation(ctx context.Context) *Limit {
+	m, ok := ctx.Value(meterKey{}).(*Meter)
+	if !ok {
+		return nil
+	}
+	userID, _ := ctx.Value(middleware.UserIDKey).(string)
+	role, _ := ctx.Value(middleware.RoleKey).(string)
+	if exceeded := m.Exceeded(userID, role); exceeded != nil {
+		return exceeded
+	}
+	m.Record(userID, counts{requests: 1})
+	return nil
+}
+
+// Record adds traffic to a user's counters for today
+func (m *Meter) Record(userID string, c counts) {
+	now := time.Now().UTC()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u := m.current(userID, now)
+	u.today.add(c)
+	u.thisMonth.add(c)
+	key := dayKey{userID, now.Format(dayFormat)}
+	p := m.pending[key]
+	p.add(c)
+	m.pending[key] = p
+}
+
+// Exceeded returns the first quota limit a user has used up, nil when the
+// user may make another request
+func (m *Meter) Exceeded(userID, role string) *Limit {
+	quota, ok := m.quotas.For(userID, role)
+	if !ok {
+		return nil
+	}
+	now := time.Now().UTC()
+
+	m.mu.Lock()
+	u := m.current(userID, now)
+	limits := limitsOf(quota, u.today, u.thisMonth, now)
+	m.mu.Unlock()
+
+	for i := range limits {
+		if limits[i].Used >= limits[i].Max {
+			return &limits[i]
+		}
+	}
+	return nil
+}
+
+// current returns a user's totals for the day and month of now, loading them
+// from the database when the period changed. Must be called with m.mu held.
+func (m *Meter) current(userID string, now time.Time) *userUsage {
+	day, month := now.Format(dayFormat), now.Format(monthFormat)
+	u, ok := m.users[userID]
+	if ok && u.day == day && u.month == month {
+		return u
+	}
+
+	u = &userUsage{day: day, month: month}
+	m.users[userID] = u
+	from, to := monthRange(now)
+	if stored, err := m.database.UserUsageTotal(userID, from, to); err == nil {
+		u.thisMonth = counts{stored.Requests, stored.BytesIn, stored.BytesOut}
+	}
+	tomorrow := now.AddDate(0, 0, 1).Format(dayFormat)
+	if stored, err := m.database.UserUsageTotal(userID, day, tomorrow); err == nil {
+		u.today = counts{stored.Requests, stored.BytesIn, stored.BytesOut}
+	}
+	for _, unflushed := range []map[dayKey]counts{m.pending, m.flushing} {
+		for key, c := range unflushed {
+			if key.userID != userID || key.day < from || key.day >= to {
+				continue
+			}
+			u.thisMonth.add(c)
+			if key.day == day {
+				u.today.add(c)
+			}
+		}
+	}
+	return u
+}
+
+// Flush writes the counted traffic to the database. Counts that fail to be
+// written are kept for the next flush.
+func (m *Meter) Flush() error {
+	m.flushMu.Lock()
+	defer m.flushMu.Unlock()
+
+	m.mu.Lock()
+	m.flushing = m.pending
+	m.pending = make(map[dayKey]counts)
+	m.mu.Unlock()
+
+	failed := make(map[dayKey]counts)
+	var firstErr error
+	for key, c := range m.flushing {
+		err := m.database.AddUsage(db.Usage{UserID: key.userID, Day: key.day, Requests: c.requests, BytesIn: c.bytesIn, BytesOut: c.bytesOut})
+		if err != nil {
+			failed[key] = c
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	m.mu.Lock()
+	m.flushing = nil
+	for key, c := range failed {
+		p := m.pending[key]
+		p.add(c)
+		m.pending[key] = p
+	}
+	// Totals of earlier days are reloaded on the user's next request
+	today := time.Now().UTC().Format(dayFormat)
+	for userID, u := range m.users {
+		if u.day != today {
+			delete(m.users, userID)
+		}
+	}
+	m.mu.Unlock()
+
+	if firstErr != nil {
+		return fmt.Errorf("failed to flush usage: %w", firstErr)
+	}
+	return nil
+}
+
+// monthRange returns the first day of t's month and of the following month
+func monthRange(t time.Time) (string, string) {
+	first := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return first.Format(dayFormat), first.AddDate(0, 1, 0).Format(dayFormat)
+}
+
+// countingReader counts the bytes of a request body read by the handler
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// countingWriter counts the bytes of a response body
+type countingWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Flush keeps streaming responses (server-sent events) working
+func (c *countingWriter) Flush() {
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
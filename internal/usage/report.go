@@ -0,0 +1,178 @@
+package usage
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+// Limit is one quota limit with the usage it is checked against
+type Limit struct {
+	Name      string    `json:"name"` // daily_requests, monthly_requests, daily_bytes or monthly_bytes
+	Max       int64     `json:"max"`
+	Used      int64     `json:"used"`
+	Remaining int64     `json:"remaining"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+// limitsOf returns the limits set in a quota, daily limits first
+func limitsOf(quota config.Quota, today, thisMonth counts, now time.Time) []Limit {
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	nextMonth := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+
+	candidates := []Limit{
+		{Name: "daily_requests", Max: quota.DailyRequests, Used: today.requests, ResetsAt: tomorrow},
+		{Name: "daily_bytes", Max: quota.DailyBytes, Used: today.bytesIn + today.bytesOut, ResetsAt: tomorrow},
+		{Name: "monthly_requests", Max: quota.MonthlyRequests, Used: thisMonth.requests, ResetsAt: nextMonth},
+		{Name: "monthly_bytes", Max: quota.MonthlyBytes, Used: thisMonth.bytesIn + thisMonth.bytesOut, ResetsAt: nextMonth},
+	}
+	limits := []Limit{}
+	for _, limit := range candidates {
+		if limit.Max <= 0 {
+			continue
+		}
+		limit.Remaining = max(limit.Max-limit.Used, 0)
+		limits = append(limits, limit)
+	}
+	return limits
+}
+
+// Report is a user's usage for one month
+type Report struct {
+	UserID string     `json:"user_id"`
+	Month  string     `json:"month"` // YYYY-MM
+	Total  db.Usage   `json:"total"`
+	Days   []db.Usage `json:"days"`
+	Quota  []Limit    `json:"quota,omitempty"` // current month only; absent when unlimited
+}
+
+// UserReport returns a user's usage for a month
+func (m *Meter) UserReport(userID, role string, month time.Time) (*Report, error) {
+	if err := m.Flush(); err != nil {
+		log.Printf("[USAGE ERROR] %v", err)
+	}
+	from, to := monthRange(month)
+	days, err := m.database.ListUserUsage(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{UserID: userID, Month: month.Format(monthFormat), Days: days}
+	for _, day := range days {
+		report.Total.Requests += day.Requests
+		report.Total.BytesIn += day.BytesIn
+		report.Total.BytesOut += day.BytesOut
+	}
+
+	now := time.Now().UTC()
+	if quota, ok := m.quotas.For(userID, role); ok && report.Month == now.Format(monthFormat) {
+		var today counts
+		for _, day := range days {
+			if day.Day == now.Format(dayFormat) {
+				today = counts{day.Requests, day.BytesIn, day.BytesOut}
+			}
+		}
+		thisMonth := counts{report.Total.Requests, report.Total.BytesIn, report.Total.BytesOut}
+		report.Quota = limitsOf(quota, today, thisMonth, now)
+	}
+	return report, nil
+}
+
+// ServeMyUsage handles GET /auth/me/usage?month=YYYY-MM (default: this month)
+func (m *Meter) ServeMyUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	month, ok := parseMonth(w, r)
+	if !ok {
+		return
+	}
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+
+	report, err := m.UserReport(userID, role, month)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to read usage")
+		return
+	}
+	respondJSON(w, report)
+}
+
+// ServeAdminUsage handles GET /admin/usage?month=YYYY-MM. Without user_id it
+// lists the monthly totals of every user; with ?user_id= it returns that
+// user's report. The quota shown is the one of the account's role, or of
+// &role= for users without a database account. Must run after
+// middleware.AuthMiddleware.
+func (m *Meter) ServeAdminUsage(w http.ResponseWriter, r *http.Request) {
+	if role, _ := r.Context().Value(middleware.RoleKey).(string); role != "admin" {
+		respondError(w, http.StatusForbidden, "admin role required")
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	month, ok := parseMonth(w, r)
+	if !ok {
+		return
+	}
+
+	if userID := r.URL.Query().Get("user_id"); userID != "" {
+		role := r.URL.Query().Get("role")
+		if id, err := strconv.ParseInt(userID, 10, 64); err == nil {
+			if user, err := m.database.GetUserByID(id); err == nil && user != nil {
+				role = user.Role
+			}
+		}
+		report, err := m.UserReport(userID, role, month)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to read usage")
+			return
+		}
+		respondJSON(w, report)
+		return
+	}
+
+	if err := m.Flush(); err != nil {
+		log.Printf("[USAGE ERROR] %v", err)
+	}
+	from, to := monthRange(month)
+	users, err := m.database.UsageByUser(from, to)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to read usage")
+		return
+	}
+	respondJSON(w, map[string]interface{}{"month": month.Format(monthFormat), "users": users})
+}
+
+// parseMonth reads ?month=YYYY-MM, defaulting to the current month
+func parseMonth(w http.ResponseWriter, r *http.Request) (time.Time, bool) {
+	value := r.URL.Query().Get("month")
+	if value == "" {
+		return time.Now().UTC(), true
+	}
+	month, err := time.Parse(monthFormat, value)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "month must be YYYY-MM")
+		return time.Time{}, false
+	}
+	return month, true
+}
+
+func respondJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func respondError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -7,19 +7,35 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// TokenTTL is the lifetime of a gateway JWT, unless a longer one was asked
+// for (remember-me logins)
+const TokenTTL = 24 * time.Hour
+
 type Claims struct {
 	UserID string `json:"user_id"`
 	Role   string `json:"role"`
+	Tenant string `json:"tenant,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // GenerateJWT creates a new JWT token with user claims
 func GenerateJWT(userID, role, secret string) (string, error) {
+	return GenerateTenantJWT(userID, role, "", secret)
+}
+
+// GenerateTenantJWT creates a new JWT token scoped to a tenant
+func GenerateTenantJWT(userID, role, tenant, secret string) (string, error) {
+	return GenerateTenantJWTWithTTL(userID, role, tenant, secret, TokenTTL)
+}
+
+// GenerateTenantJWTWithTTL creates a JWT token scoped to a tenant that is valid for ttl
+func GenerateTenantJWTWithTTL(userID, role, tenant, secret string, ttl time.Duration) (string, error) {
 	claims := Claims{
 		UserID: userID,
 		Role:   role,
+		Tenant: tenant,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
@@ -42,8 +58,35 @@ func ValidateJWT(tokenString, secret string) (*Claims, error) {
 	}
 
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		if TokenRevoked(claims.UserID, claims.IssuedAt) {
+			return nil, ErrTokenRevoked
+		}
 		return claims, nil
 	}
 
 	return nil, errors.New("invalid token")
 }
+
+// ErrTokenRevoked is returned for a valid token whose user's tokens were revoked
+var ErrTokenRevoked = errors.New("token revoked")
+
+var revocationCheck func(userID string, issuedAt time.Time) bool
+
+// SetRevocationCheck installs the check that rejects revoked tokens (e.g. of
+// deleted accounts) in ValidateJWT
+func SetRevocationCheck(check func(userID string, issuedAt time.Time) bool) {
+	revocationCheck = check
+}
+
+// TokenRevoked reports whether a token issued to userID at issuedAt was revoked.
+// Tokens without an issue time count as issued at the epoch.
+func TokenRevoked(userID string, issuedAt *jwt.NumericDate) bool {
+	if revocationCheck == nil {
+		return false
+	}
+	var issued time.Time
+	if issuedAt != nil {
+		issued = issuedAt.Time
+	}
+	return revocationCheck(userID, issued)
+}
@@ -0,0 +1,78 @@
+package vault
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/grove/generic-proxy/internal/middleware"
+)
+
+// tokenRequest is the body of PUT /admin/tokens/{scope}
+type tokenRequest struct {
+	Token string `json:"token"`
+}
+
+// Handler exposes admin endpoints to list, rotate and revoke upstream tokens
+type Handler struct {
+	vault *Vault
+}
+
+// NewHandler creates a new token admin handler
+func NewHandler(vault *Vault) *Handler {
+	return &Handler{vault: vault}
+}
+
+// ServeHTTP handles GET /admin/tokens, PUT /admin/tokens/{scope} and
+// DELETE /admin/tokens/{scope}. Must run after middleware.AuthMiddleware.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if role, _ := r.Context().Value(middleware.RoleKey).(string); role != "admin" {
+		respondError(w, http.StatusForbidden, "admin role required")
+		return
+	}
+	adminID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	scope := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/tokens"), "/")
+
+	switch {
+	case r.Method == http.MethodGet && scope == "":
+		infos, err := h.vault.List()
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to list tokens")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"tokens": infos})
+
+	case r.Method == http.MethodPut && scope != "":
+		var req tokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+			respondError(w, http.StatusBadRequest, "token is required")
+			return
+		}
+		if err := h.vault.Set(scope, req.Token, adminID); err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to store token")
+			return
+		}
+		log.Printf("[VAULT] Admin %s rotated token for '%s'", adminID, scope)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"scope": scope, "hint": hint(req.Token)})
+
+	case r.Method == http.MethodDelete && scope != "":
+		if err := h.vault.Delete(scope); err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to delete token")
+			return
+		}
+		log.Printf("[VAULT] Admin %s revoked token for '%s'", adminID, scope)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		respondError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func respondError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
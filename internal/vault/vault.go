@@ -0,0 +1,186 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/db"
+)
+
+// ScopeDefault is the scope of the token used for the default base
+const ScopeDefault = "default"
+
+// TenantScope returns the vault scope of a tenant's token
+func TenantScope(tenant string) string {
+	return "tenant:" + tenant
+}
+
+// BaseScope returns the vault scope of an additional base's token
+func BaseScope(base string) string {
+	return "base:" + base
+}
+
+// UpstreamScope returns the vault scope of a named upstream's token
+func UpstreamScope(upstream string) string {
+	return "upstream:" + upstream
+}
+
+// RoleScope returns the vault scope of the token sent for a role's requests
+func RoleScope(role string) string {
+	return "role:" + role
+}
+
+// TokenInfo describes a stored token without revealing it
+type TokenInfo struct {
+	Scope     string    `json:"scope"`
+	Hint      string    `json:"hint"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Vault stores NocoDB tokens encrypted (AES-256-GCM) in the gateway database and
+// keeps the decrypted tokens in memory so rotations apply without a restart
+type Vault struct {
+	store *db.Database
+	aead  cipher.AEAD
+
+	mu     sync.RWMutex
+	tokens map[string]string
+}
+
+// New creates a vault using a key derived from the given secret and loads all stored tokens
+func New(store *db.Database, secret string) (*Vault, error) {
+	if secret == "" {
+		return nil, errors.New("vault key is required")
+	}
+
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Vault{
+		store:  store,
+		aead:   aead,
+		tokens: make(map[string]string),
+	}
+
+	stored, err := store.ListUpstreamTokens()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tokens: %w", err)
+	}
+	for _, token := range stored {
+		plaintext, err := v.decrypt(token.Ciphertext)
+		if err != nil {
+			log.Printf("[VAULT ERROR] Cannot decrypt token for '%s' (wrong TOKEN_VAULT_KEY?): %v", token.Scope, err)
+			continue
+		}
+		v.tokens[token.Scope] = plaintext
+	}
+	log.Printf("[VAULT] Loaded %d upstream token(s)", len(v.tokens))
+	return v, nil
+}
+
+// Get returns the token for a scope ("" if none is stored)
+func (v *Vault) Get(scope string) string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.tokens[scope]
+}
+
+// Source returns a function that always yields the current token for a scope.
+// It can be used as a proxy.TokenSource.
+func (v *Vault) Source(scope string) func() string {
+	return func() string {
+		return v.Get(scope)
+	}
+}
+
+// Set stores (or rotates) the token for a scope
+func (v *Vault) Set(scope, token, updatedBy string) error {
+	ciphertext, err := v.encrypt(token)
+	if err != nil {
+		return err
+	}
+	if err := v.store.SetUpstreamToken(scope, ciphertext, hint(token), updatedBy); err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	v.tokens[scope] = token
+	v.mu.Unlock()
+	return nil
+}
+
+// Delete removes the token for a scope
+func (v *Vault) Delete(scope string) error {
+	if err := v.store.DeleteUpstreamToken(scope); err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	delete(v.tokens, scope)
+	v.mu.Unlock()
+	return nil
+}
+
+// List describes all stored tokens
+func (v *Vault) List() ([]TokenInfo, error) {
+	stored, err := v.store.ListUpstreamTokens()
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]TokenInfo, 0, len(stored))
+	for _, token := range stored {
+		infos = append(infos, TokenInfo{
+			Scope:     token.Scope,
+			Hint:      token.Hint,
+			UpdatedBy: token.UpdatedBy,
+			UpdatedAt: token.UpdatedAt,
+		})
+	}
+	return infos, nil
+}
+
+// encrypt seals a token, prefixing the random nonce
+func (v *Vault) encrypt(plaintext string) ([]byte, error) {
+	nonce := make([]byte, v.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return v.aead.Seal(nonce, nonce, []byte(plaintext), nil), nil
+}
+
+// decrypt opens a token sealed by encrypt
+func (v *Vault) decrypt(ciphertext []byte) (string, error) {
+	size := v.aead.NonceSize()
+	if len(ciphertext) < size {
+		return "", errors.New("ciphertext too short")
+	}
+	plaintext, err := v.aead.Open(nil, ciphertext[:size], ciphertext[size:], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// hint returns the last characters of a token so admins can tell tokens apart
+func hint(token string) string {
+	if len(token) <= 8 {
+		return "****"
+	}
+	return "****" + token[len(token)-4:]
+}
@@ -0,0 +1,65 @@
+package wasmfilter
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// Message is the JSON document a filter receives
+type Message struct {
+	Phase   Phase                  `json:"phase"`
+	Table   string                 `json:"table"` // proxy.yaml table key
+	Method  string                 `json:"method"`
+	Path    string                 `json:"path"` // path below /proxy/ as sent by the client
+	Query   url.Values             `json:"query,omitempty"`
+	Headers http.Header            `json:"headers"`
+	Body    string                 `json:"body"`
+	Status  int                    `json:"status,omitempty"` // response phase only
+	User    User                   `json:"user"`
+	Config  map[string]interface{} `json:"config,omitempty"` // the filter's config block from proxy.yaml
+}
+
+// User identifies the caller
+type User struct {
+	ID     string `json:"id"`
+	Role   string `json:"role"`
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// Verdict is the JSON document a filter returns. Omitted fields leave the
+// request or response unchanged.
+type Verdict struct {
+	Action  string             `json:"action,omitempty"`  // continue (default) or reject
+	Status  int                `json:"status,omitempty"`  // reject: status sent to the client (default 403); response: new status
+	Message string             `json:"message,omitempty"` // reject: error message
+	Headers map[string]*string `json:"headers,omitempty"` // header -> value; null removes the header
+	Query   map[string]*string `json:"query,omitempty"`   // request phase: parameter -> value; null removes it
+	Body    *string            `json:"body,omitempty"`    // replacement body
+}
+
+// Rejected reports whether the filter refused the request
+func (v *Verdict) Rejected() bool {
+	return v != nil && v.Action == "reject"
+}
+
+// ApplyHeaders sets and removes the headers named by the verdict
+func (v *Verdict) ApplyHeaders(header http.Header) {
+	for name, value := range v.Headers {
+		if value == nil {
+			header.Del(name)
+		} else {
+			header.Set(name, *value)
+		}
+	}
+}
+
+// ApplyQuery sets and removes the query parameters named by the verdict
+func (v *Verdict) ApplyQuery(query url.Values) {
+	for name, value := range v.Query {
+		if value == nil {
+			query.Del(name)
+		} else {
+			query.Set(name, *value)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+// Package wasmfilter runs WebAssembly filter modules on proxied requests and
+// responses in a sandbox.
+//
+// A filter module exports its linear memory and, as in proxy-wasm,
+// proxy_on_memory_allocate(size i32) -> i32 so the host can hand it data. It
+// implements one or both phases:
+//
+//	filter_on_request(ptr i32, len i32) -> i64
+//	filter_on_response(ptr i32, len i32) -> i64
+//
+// The host writes a JSON Message into guest memory and calls the phase
+// function. The result packs the location of a JSON Verdict as ptr<<32 | len;
+// 0 lets the request continue unchanged. Every call runs in a fresh instance
+// with no filesystem, network, environment or clock beyond WASI defaults, a
+// memory cap and a deadline.
+package wasmfilter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+const (
+	allocateExport = "proxy_on_memory_allocate"
+	requestExport  = "filter_on_request"
+	responseExport = "filter_on_response"
+
+	// memoryLimitPages caps guest memory at 64 MiB
+	memoryLimitPages = 1024
+	// reloadCheckInterval is how often a module file is checked for changes
+	reloadCheckInterval = 2 * time.Second
+)
+
+// Phase names the point at which a filter runs
+type Phase string
+
+const (
+	PhaseRequest  Phase = "request"
+	PhaseResponse Phase = "response"
+)
+
+// Runtime compiles filter modules and runs them. Modules are reloaded when
+// their file changes, so filters can be updated without a restart.
+type Runtime struct {
+	runtime wazero.Runtime
+	timeout time.Duration
+
+	mu      sync.Mutex
+	modules map[string]*module
+}
+
+type module struct {
+	compiled  wazero.CompiledModule
+	modTime   time.Time
+	checkedAt time.Time
+	phases    map[Phase]bool
+}
+
+// NewRuntime creates a sandboxed runtime; each filter call may run for at most timeout
+func NewRuntime(ctx context.Context, timeout time.Duration) *Runtime {
+	config := wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(memoryLimitPages).
+		WithCloseOnContextDone(true)
+	runtime := wazero.NewRuntimeWithConfig(ctx, config)
+	// Filters built with WASI toolchains (Go, Rust, TinyGo) import it even when unused
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+
+	return &Runtime{
+		runtime: runtime,
+		timeout: timeout,
+		modules: make(map[string]*module),
+	}
+}
+
+// Close releases all compiled modules
+func (rt *Runtime) Close(ctx context.Context) error {
+	return rt.runtime.Close(ctx)
+}
+
+// Load compiles a module and checks its exports
+func (rt *Runtime) Load(ctx context.Context, path string) error {
+	_, err := rt.module(ctx, path)
+	return err
+}
+
+// Run calls a filter for one phase. It returns nil when the module does not
+// implement the phase or lets the request continue unchanged.
+func (rt *Runtime) Run(ctx context.Context, path string, phase Phase, msg *Message) (*Verdict, error) {
+	mod, err := rt.module(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if !mod.phases[phase] {
+		return nil, nil
+	}
+
+	input, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, rt.timeout)
+	defer cancel()
+
+	// A fresh instance per call: filters cannot keep state between requests
+	instance, err := rt.runtime.InstantiateModule(ctx, mod.compiled,
+		wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate: %w", err)
+	}
+	defer instance.Close(context.Background())
+
+	results, err := instance.ExportedFunction(allocateExport).Call(ctx, uint64(len(input)))
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", allocateExport, err)
+	}
+	ptr := uint32(results[0])
+	if !instance.Memory().Write(ptr, input) {
+		return nil, fmt.Errorf("%s returned an out-of-range pointer", allocateExport)
+	}
+
+	results, err = instance.ExportedFunction(exportFor(phase)).Call(ctx, uint64(ptr), uint64(len(input)))
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", exportFor(phase), err)
+	}
+	if results[0] == 0 {
+		return nil, nil
+	}
+
+	output, ok := instance.Memory().Read(uint32(results[0]>>32), uint32(results[0]))
+	if !ok {
+		return nil, fmt.Errorf("%s returned an out-of-range result", exportFor(phase))
+	}
+	var verdict Verdict
+	if err := json.Unmarshal(output, &verdict); err != nil {
+		return nil, fmt.Errorf("invalid verdict: %w", err)
+	}
+	return &verdict, nil
+}
+
+// module returns the compiled module for path, recompiling it when the file changed
+func (rt *Runtime) module(ctx context.Context, path string) (*module, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	cached := rt.modules[path]
+	if cached != nil && time.Since(cached.checkedAt) < reloadCheckInterval {
+		return cached, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if cached != nil {
+			// Keep serving the last good version while the file is being replaced
+			cached.checkedAt = time.Now()
+			return cached, nil
+		}
+		return nil, fmt.Errorf("cannot read filter module: %w", err)
+	}
+	if cached != nil && info.ModTime().Equal(cached.modTime) {
+		cached.checkedAt = time.Now()
+		return cached, nil
+	}
+
+	loaded, err := rt.compile(ctx, path, info.ModTime())
+	if err != nil {
+		if cached != nil {
+			log.Printf("[WASM ERROR] Reloading %s failed, keeping previous version: %v", path, err)
+			cached.checkedAt = time.Now()
+			return cached, nil
+		}
+		return nil, err
+	}
+	if cached != nil {
+		log.Printf("[WASM] Reloaded filter module %s", path)
+		cached.compiled.Close(ctx)
+	}
+	rt.modules[path] = loaded
+	return loaded, nil
+}
+
+func (rt *Runtime) compile(ctx context.Context, path string, modTime time.Time) (*module, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read filter module: %w", err)
+	}
+	compiled, err := rt.runtime.CompileModule(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter module %s: %w", path, err)
+	}
+
+	exports := compiled.ExportedFunctions()
+	mod := &module{compiled: compiled, modTime: modTime, checkedAt: time.Now(), phases: make(map[Phase]bool)}
+	for _, phase := range []Phase{PhaseRequest, PhaseResponse} {
+		_, mod.phases[phase] = exports[exportFor(phase)]
+	}
+	if _, ok := exports[allocateExport]; !ok {
+		compiled.Close(ctx)
+		return nil, fmt.Errorf("filter module %s does not export %s", path, allocateExport)
+	}
+	if len(compiled.ExportedMemories()) == 0 {
+		compiled.Close(ctx)
+		return nil, fmt.Errorf("filter module %s does not export its memory", path)
+	}
+	if !mod.phases[PhaseRequest] && !mod.phases[PhaseResponse] {
+		compiled.Close(ctx)
+		return nil, fmt.Errorf("filter module %s exports neither %s nor %s", path, requestExport, responseExport)
+	}
+	return mod, nil
+}
+
+func exportFor(phase Phase) string {
+	if phase == PhaseRequest {
+		return requestExport
+	}
+	return responseExport
+}
@@ -0,0 +1,371 @@
+package webhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/config"
+	"github.com/grove/generic-proxy/internal/events"
+	"github.com/grove/generic-proxy/internal/proxy"
+	"github.com/grove/generic-proxy/internal/signing"
+)
+
+const (
+	defaultMaxRetries     = 5
+	initialRetryBackoff   = 1 * time.Second
+	maxConcurrentDelivery = 10
+)
+
+// DeadLetter is a delivery that exhausted all retries
+type DeadLetter struct {
+	URL       string       `json:"url"`
+	Event     events.Event `json:"event"`
+	Attempts  int          `json:"attempts"`
+	LastError string       `json:"last_error"`
+	FailedAt  time.Time    `json:"failed_at"`
+}
+
+// Dispatcher delivers proxy write events to the webhook targets configured per table
+type Dispatcher struct {
+	config         *config.ResolvedConfig
+	bus            *events.Bus
+	httpClient     *http.Client
+	deadLetterPath string
+	deadLetterMu   sync.Mutex
+	sem            chan struct{}
+	schemaTargets  []config.WebhookTarget
+	authTargets    []config.WebhookTarget
+}
+
+// NewDispatcher creates a new outbound webhook dispatcher
+func NewDispatcher(resolvedConfig *config.ResolvedConfig, bus *events.Bus, deadLetterPath string) *Dispatcher {
+	return &Dispatcher{
+		config:         resolvedConfig,
+		bus:            bus,
+		httpClient:     &http.Client{Timeout: 10 * time.Second},
+		deadLetterPath: deadLetterPath,
+		sem:            make(chan struct{}, maxConcurrentDelivery),
+	}
+}
+
+// SetSchemaTargets sets URLs that are notified when the upstream schema changes
+func (d *Dispatcher) SetSchemaTargets(urls []string, secretEnv string) {
+	d.schemaTargets = nil
+	for _, url := range urls {
+		d.schemaTargets = append(d.schemaTargets, config.WebhookTarget{URL: url, SecretEnv: secretEnv})
+	}
+}
+
+// SetAuthTargets sets the auth_webhooks targets of proxy.yaml, notified of
+// signups, logins, lockouts, role changes and account deletions
+func (d *Dispatcher) SetAuthTargets(targets []config.WebhookTarget) {
+	d.authTargets = targets
+}
+
+// Start subscribes to the event bus and delivers events in the background
+func (d *Dispatcher) Start() {
+	if d.bus == nil || (d.config == nil && len(d.schemaTargets) == 0 && len(d.authTargets) == 0) {
+		log.Printf("[WEBHOOK] Outbound webhooks disabled (no resolved configuration)")
+		return
+	}
+
+	targets := len(d.schemaTargets) + len(d.authTargets)
+	if d.config != nil {
+		for _, table := range d.config.Tables {
+			targets += len(table.Webhooks)
+		}
+	}
+	if targets == 0 {
+		log.Printf("[WEBHOOK] No outbound webhook targets configured")
+		return
+	}
+
+	ch, _ := d.bus.Subscribe(1000)
+	go func() {
+		log.Printf("[WEBHOOK] Outbound dispatcher started with %d target(s)", targets)
+		for event := range ch {
+			d.dispatch(event)
+		}
+	}()
+}
+
+// dispatch fans a single event out to all matching targets
+func (d *Dispatcher) dispatch(event events.Event) {
+	d.deliverAll(d.targetsFor(event), event)
+}
+
+// Deliver sends an event to its targets and waits until each delivery
+// succeeded or was dead-lettered. It is meant for short-lived processes such
+// as the CLI, which publish the odd event without running the dispatcher.
+func (d *Dispatcher) Deliver(event events.Event) {
+	if event.ID == "" {
+		event.ID = events.NewID()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	var wg sync.WaitGroup
+	for _, target := range d.targetsFor(event) {
+		wg.Add(1)
+		go func(target config.WebhookTarget) {
+			defer wg.Done()
+			d.deliver(target, event)
+		}(target)
+	}
+	wg.Wait()
+}
+
+// targetsFor returns the targets subscribed to an event
+func (d *Dispatcher) targetsFor(event events.Event) []config.WebhookTarget {
+	if event.Type == events.TypeSchemaChanged && event.Source == proxy.SourceMetaCache {
+		return d.schemaTargets
+	}
+
+	var targets []config.WebhookTarget
+	if event.Source == events.SourceAuth {
+		names := authEventNames(event)
+		for _, target := range d.authTargets {
+			for _, name := range names {
+				if targetWants(target, name) {
+					targets = append(targets, target)
+					break
+				}
+			}
+		}
+		return targets
+	}
+
+	if d.config == nil || (event.Source != events.SourceProxy && event.Source != events.SourceCDC) {
+		return nil
+	}
+	// Bases and tenants reuse table keys; their events carry the ID of a
+	// table other than the default base's and have no targets here
+	table, ok := d.config.Tables[event.TableName]
+	if !ok || (event.TableID != "" && event.TableID != table.TableID) {
+		return nil
+	}
+	operation := operationForEvent(event.Type)
+	for _, target := range table.Webhooks {
+		if targetWants(target, operation) {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// deliverAll sends an event to each target concurrently (bounded by sem)
+func (d *Dispatcher) deliverAll(targets []config.WebhookTarget, event events.Event) {
+	for _, target := range targets {
+		target := target
+		go func() {
+			d.sem <- struct{}{}
+			defer func() { <-d.sem }()
+			d.deliver(target, event)
+		}()
+	}
+}
+
+// deliver sends the event to one target, retrying with exponential backoff
+func (d *Dispatcher) deliver(target config.WebhookTarget, event events.Event) {
+	var body []byte
+	var err error
+	if target.Format == config.WebhookFormatSlack {
+		body, err = json.Marshal(map[string]string{"text": slackText(event)})
+	} else {
+		body, err = json.Marshal(event)
+	}
+	if err != nil {
+		log.Printf("[WEBHOOK ERROR] Failed to encode event %s: %v", event.ID, err)
+		return
+	}
+
+	maxRetries := target.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+
+	secret := ""
+	if target.SecretEnv != "" {
+		secret = os.Getenv(target.SecretEnv)
+	}
+
+	backoff := initialRetryBackoff
+	var lastErr error
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		lastErr = d.send(target.URL, secret, event, body)
+		if lastErr == nil {
+			log.Printf("[WEBHOOK] Delivered %s (%s) to %s on attempt %d", event.Type, event.ID, target.URL, attempt)
+			return
+		}
+
+		log.Printf("[WEBHOOK WARN] Delivery of %s to %s failed (attempt %d/%d): %v", event.ID, target.URL, attempt, maxRetries, lastErr)
+		if attempt < maxRetries {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	d.writeDeadLetter(DeadLetter{
+		URL:       target.URL,
+		Event:     event,
+		Attempts:  maxRetries,
+		LastError: lastErr.Error(),
+		FailedAt:  time.Now().UTC(),
+	})
+}
+
+// send performs a single signed delivery attempt
+func (d *Dispatcher) send(url, secret string, event events.Event, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gateway-Event", event.Type)
+	req.Header.Set("X-Gateway-Delivery", event.ID)
+	if secret != "" {
+		req.Header.Set("X-Gateway-Signature", "sha256="+Sign(secret, body))
+		if err := signing.SignBody(req.Header, secret, body); err != nil {
+			return fmt.Errorf("failed to sign request: %w", err)
+		}
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("target returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// writeDeadLetter appends a failed delivery to the dead-letter log as a JSON line
+func (d *Dispatcher) writeDeadLetter(entry DeadLetter) {
+	log.Printf("[WEBHOOK ERROR] Giving up on %s to %s after %d attempts, writing to dead-letter log", entry.Event.ID, entry.URL, entry.Attempts)
+
+	if d.deadLetterPath == "" {
+		return
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("[WEBHOOK ERROR] Failed to encode dead letter: %v", err)
+		return
+	}
+
+	d.deadLetterMu.Lock()
+	defer d.deadLetterMu.Unlock()
+
+	f, err := os.OpenFile(d.deadLetterPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Printf("[WEBHOOK ERROR] Failed to open dead-letter log %s: %v", d.deadLetterPath, err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		log.Printf("[WEBHOOK ERROR] Failed to write dead letter: %v", err)
+	}
+}
+
+// operationForEvent maps an event type to the proxy.yaml operation name
+func operationForEvent(eventType string) string {
+	switch eventType {
+	case events.TypeRecordCreated:
+		return "create"
+	case events.TypeRecordUpdated:
+		return "update"
+	case events.TypeRecordDeleted:
+		return "delete"
+	default:
+		return ""
+	}
+}
+
+// authEventNames maps an auth event to the auth_webhooks event names it
+// matches; a login by an admin is both a login and an admin_login
+func authEventNames(event events.Event) []string {
+	switch event.Type {
+	case events.TypeAuthSignup:
+		return []string{config.AuthEventSignup}
+	case events.TypeAuthLogin:
+		if event.Metadata["role"] == "admin" {
+			return []string{config.AuthEventLogin, config.AuthEventAdminLogin}
+		}
+		return []string{config.AuthEventLogin}
+	case events.TypeAuthLoginFailed:
+		return []string{config.AuthEventLoginFailed}
+	case events.TypeAuthLoginChallenged:
+		return []string{config.AuthEventLoginChallenged}
+	case events.TypeAuthLockout:
+		return []string{config.AuthEventLockout}
+	case events.TypeAuthRoleChanged:
+		return []string{config.AuthEventRoleChange}
+	case events.TypeAuthDeleted:
+		return []string{config.AuthEventAccountDeleted}
+	default:
+		return nil
+	}
+}
+
+// slackText summarizes an event in one Slack message, e.g.
+//
+//	*auth.login* admin@example.com (user 1)
+//	country: DE · remote_addr: 203.0.113.7:51234 · role: admin
+func slackText(event events.Event) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "*%s*", event.Type)
+	if event.TableName != "" {
+		fmt.Fprintf(&text, " on %s", event.TableName)
+	}
+	if event.RecordID != "" {
+		fmt.Fprintf(&text, " record %s", event.RecordID)
+	}
+	if email := event.Metadata["email"]; email != "" {
+		fmt.Fprintf(&text, " %s", email)
+	}
+	if event.UserID != "" {
+		fmt.Fprintf(&text, " (user %s)", event.UserID)
+	}
+
+	var details []string
+	for key, value := range event.Metadata {
+		if key != "email" && key != "user_agent" && value != "" {
+			details = append(details, key+": "+value)
+		}
+	}
+	sort.Strings(details)
+	if len(details) > 0 {
+		text.WriteString("\n" + strings.Join(details, " · "))
+	}
+	return text.String()
+}
+
+// targetWants reports whether a target subscribed to the given operation
+func targetWants(target config.WebhookTarget, operation string) bool {
+	if operation == "" {
+		return false
+	}
+	if len(target.Events) == 0 {
+		return true
+	}
+	for _, e := range target.Events {
+		if e == operation {
+			return true
+		}
+	}
+	return false
+}
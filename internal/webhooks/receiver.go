@@ -0,0 +1,209 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grove/generic-proxy/internal/events"
+	"github.com/grove/generic-proxy/internal/proxy"
+	"github.com/grove/generic-proxy/internal/signing"
+)
+
+// maxWebhookBodyBytes caps the size of an incoming NocoDB webhook payload
+const maxWebhookBodyBytes = 5 << 20
+
+// NocoDBPayload is the body NocoDB sends for table webhooks
+type NocoDBPayload struct {
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Version string `json:"version"`
+	Data    struct {
+		TableID      string                   `json:"table_id"`
+		TableName    string                   `json:"table_name"`
+		Rows         []map[string]interface{} `json:"rows"`
+		PreviousRows []map[string]interface{} `json:"previous_rows"`
+	} `json:"data"`
+}
+
+// Receiver accepts webhooks from NocoDB, invalidates caches and fans events out
+type Receiver struct {
+	secret     string
+	fanoutURLs []string
+	metaCache  *proxy.MetaCache
+	bus        *events.Bus
+	httpClient *http.Client
+}
+
+// NewReceiver creates a new NocoDB webhook receiver
+func NewReceiver(secret string, fanoutURLs []string, metaCache *proxy.MetaCache, bus *events.Bus) *Receiver {
+	return &Receiver{
+		secret:     secret,
+		fanoutURLs: fanoutURLs,
+		metaCache:  metaCache,
+		bus:        bus,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// ServeHTTP handles POST /__proxy/webhooks/nocodb
+func (rc *Receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if rc.secret == "" {
+		log.Printf("[WEBHOOK ERROR] Webhook received but NOCODB_WEBHOOK_SECRET is not configured")
+		http.Error(w, "webhook receiver not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodyBytes))
+	if err != nil {
+		log.Printf("[WEBHOOK ERROR] Failed to read webhook body: %v", err)
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	if !rc.verify(r, body) {
+		log.Printf("[WEBHOOK ERROR] Invalid webhook signature from %s", r.RemoteAddr)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var payload NocoDBPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		log.Printf("[WEBHOOK ERROR] Failed to parse webhook payload: %v", err)
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("[WEBHOOK] Received NocoDB event '%s' for table '%s' (%s), %d row(s)",
+		payload.Type, payload.Data.TableName, payload.Data.TableID, len(payload.Data.Rows))
+
+	event := toEvent(payload)
+
+	if event.Type == events.TypeSchemaChanged && rc.metaCache != nil {
+		log.Printf("[WEBHOOK] Schema event received, refreshing MetaCache in background")
+		go func() {
+			if err := rc.metaCache.Refresh(); err != nil {
+				log.Printf("[WEBHOOK ERROR] MetaCache refresh after schema event failed: %v", err)
+			}
+		}()
+	}
+
+	// Cache layers and streaming consumers subscribe to the bus for invalidation
+	if rc.bus != nil {
+		rc.bus.Publish(event)
+	}
+
+	for _, target := range rc.fanoutURLs {
+		go rc.forward(target, payload.Type, body)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":   "accepted",
+		"event_id": event.ID,
+	})
+}
+
+// verify checks the webhook signature against the shared secret.
+// NocoDB can only send static custom headers, so a plain shared-secret header
+// is accepted alongside an HMAC-SHA256 signature of the body.
+func (rc *Receiver) verify(r *http.Request, body []byte) bool {
+	if sig := r.Header.Get("X-Nocodb-Signature"); sig != "" {
+		expected := Sign(rc.secret, body)
+		sig = strings.TrimPrefix(sig, "sha256=")
+		return hmac.Equal([]byte(sig), []byte(expected))
+	}
+
+	if secret := r.Header.Get("X-Webhook-Secret"); secret != "" {
+		return subtle.ConstantTimeCompare([]byte(secret), []byte(rc.secret)) == 1
+	}
+
+	return false
+}
+
+// forward re-delivers the raw webhook body to a downstream URL
+func (rc *Receiver) forward(target, eventType string, body []byte) {
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("[WEBHOOK ERROR] Failed to create fan-out request for %s: %v", target, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gateway-Event", eventType)
+	req.Header.Set("X-Gateway-Signature", "sha256="+Sign(rc.secret, body))
+	if err := signing.SignBody(req.Header, rc.secret, body); err != nil {
+		log.Printf("[WEBHOOK ERROR] Failed to sign fan-out request for %s: %v", target, err)
+		return
+	}
+
+	resp, err := rc.httpClient.Do(req)
+	if err != nil {
+		log.Printf("[WEBHOOK ERROR] Fan-out to %s failed: %v", target, err)
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		log.Printf("[WEBHOOK WARN] Fan-out to %s returned status %d", target, resp.StatusCode)
+		return
+	}
+	log.Printf("[WEBHOOK] Fanned out '%s' to %s", eventType, target)
+}
+
+// Sign computes the hex-encoded HMAC-SHA256 of body using secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// toEvent converts a NocoDB webhook payload into a gateway event
+func toEvent(payload NocoDBPayload) events.Event {
+	event := events.Event{
+		ID:        payload.ID,
+		Type:      mapEventType(payload.Type),
+		Source:    events.SourceNocoDBWebhook,
+		TableID:   payload.Data.TableID,
+		TableName: payload.Data.TableName,
+		Records:   payload.Data.Rows,
+		Previous:  payload.Data.PreviousRows,
+	}
+
+	if len(payload.Data.Rows) == 1 {
+		event.RecordID = proxy.RecordID(payload.Data.Rows[0])
+	}
+
+	return event
+}
+
+// mapEventType translates NocoDB event names (e.g. records.after.insert) to gateway event types
+func mapEventType(nocoType string) string {
+	t := strings.ToLower(nocoType)
+	switch {
+	case strings.HasPrefix(t, "records.") && strings.Contains(t, "insert"):
+		return events.TypeRecordCreated
+	case strings.HasPrefix(t, "records.") && strings.Contains(t, "update"):
+		return events.TypeRecordUpdated
+	case strings.HasPrefix(t, "records.") && strings.Contains(t, "delete"):
+		return events.TypeRecordDeleted
+	case strings.HasPrefix(t, "table"), strings.HasPrefix(t, "column"),
+		strings.HasPrefix(t, "field"), strings.HasPrefix(t, "meta"):
+		return events.TypeSchemaChanged
+	default:
+		return nocoType
+	}
+}
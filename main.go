@@ -1,33 +1,58 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/sessions"
+	"github.com/grove/generic-proxy/internal/anonymous"
 	"github.com/grove/generic-proxy/internal/auth"
+	"github.com/grove/generic-proxy/internal/backup"
+	"github.com/grove/generic-proxy/internal/cdc"
 	"github.com/grove/generic-proxy/internal/config"
 	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/events"
+	"github.com/grove/generic-proxy/internal/fieldcrypt"
 	"github.com/grove/generic-proxy/internal/introspect"
 	"github.com/grove/generic-proxy/internal/middleware"
+	"github.com/grove/generic-proxy/internal/mirror"
+	"github.com/grove/generic-proxy/internal/plugins"
+	"github.com/grove/generic-proxy/internal/privacy"
 	"github.com/grove/generic-proxy/internal/proxy"
+	"github.com/grove/generic-proxy/internal/publicview"
+	"github.com/grove/generic-proxy/internal/scheduler"
+	"github.com/grove/generic-proxy/internal/search"
+	"github.com/grove/generic-proxy/internal/tenancy"
+	"github.com/grove/generic-proxy/internal/usage"
 	"github.com/grove/generic-proxy/internal/utils"
+	"github.com/grove/generic-proxy/internal/vault"
+	"github.com/grove/generic-proxy/internal/wasmfilter"
+	"github.com/grove/generic-proxy/internal/webhooks"
 	"github.com/markbates/goth/gothic"
 )
 
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	Tenant     string `json:"tenant,omitempty"`
+	RememberMe bool   `json:"remember_me,omitempty"`
 }
 
 type LoginResponse struct {
 	Token  string `json:"token"`
 	UserID string `json:"user_id"`
 	Role   string `json:"role"`
+	Tenant string `json:"tenant,omitempty"`
 }
 
 // Demo users for testing
@@ -48,29 +73,51 @@ var demoUsers = map[string]struct {
 	},
 }
 
-func main() {
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
+// serve runs the gateway until it receives SIGINT or SIGTERM
+func serve() {
 	log.Println("[STARTUP] Initializing Generic Proxy Server with OAuth...")
 
 	// Load environment configuration
 	cfg := config.Load()
 
+	// Background workers stop when the process is asked to shut down
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Periodic background jobs (metadata refresh, mirror sync, CDC polling, ...)
+	jobs := scheduler.New()
+
+	// Development: replace the upstream with an in-memory NocoDB built from fixtures
+	if cfg.MockNocoDBFixtures != "" {
+		if err := startMockNocoDB(ctx, cfg); err != nil {
+			log.Fatalf("[STARTUP ERROR] Failed to start mock NocoDB: %v", err)
+		}
+	}
+
+	// Decisions made during startup, served at /__proxy/startup
+	startup := introspect.NewStartupReport()
+
 	// Load proxy configuration (optional - for config-driven mode)
 	var proxyConfig *config.ProxyConfig
 	var resolvedConfig *config.ResolvedConfig
-	proxyConfigPath := os.Getenv("PROXY_CONFIG_PATH")
-	if proxyConfigPath == "" {
-		proxyConfigPath = "./config/proxy.yaml"
-	}
+	proxyConfigPath := defaultProxyConfigPath()
+	startup.Config.Path = proxyConfigPath
 	if _, err := os.Stat(proxyConfigPath); err == nil {
+		startup.Config.Found = true
 		log.Printf("[STARTUP] Loading proxy configuration from: %s", proxyConfigPath)
 		proxyConfig, err = config.LoadProxyConfig(proxyConfigPath)
 		if err != nil {
 			log.Printf("[STARTUP WARN] Failed to load proxy config: %v", err)
 			log.Printf("[STARTUP] Continuing in legacy mode without config-driven schema")
+			startup.Config.Error = err.Error()
+			startup.Legacy("proxy.yaml failed to load: " + err.Error())
+		} else {
+			startup.Config.Loaded = true
+			startup.Config.Tables = len(proxyConfig.Tables)
 		}
 	} else {
 		log.Printf("[STARTUP] No proxy config found at %s, using legacy mode", proxyConfigPath)
+		startup.Legacy("no proxy.yaml at " + proxyConfigPath)
 	}
 
 	log.Printf("[STARTUP] Configuration loaded:")
@@ -80,22 +127,107 @@ func main() {
 	log.Printf("  - JWT Secret: %s", cfg.MaskSecret(cfg.JWTSecret))
 	log.Printf("  - Database Path: %s", cfg.DatabasePath)
 
+	// One tuned connection pool for every upstream client, which all use the
+	// default transport; signing wraps it
+	upstreamPool := newUpstreamTransport(cfg)
+	http.DefaultTransport = upstreamPool
+
+	// Optionally sign every request to NocoDB (UPSTREAM_SIGNING_SECRET)
+	installUpstreamSigning(cfg, proxyConfig)
+
+	// Feature switches from the flags block of proxy.yaml
+	flags := config.ResolveFlags(proxyConfig, cfg)
+	log.Printf("  - Flags: strict_mode=%v demo_users=%v signup=%v introspection_auth=%v",
+		flags.StrictMode, flags.DemoUsers, flags.Signup, flags.IntrospectionAuth)
+
+	// Compiled-in plugins enabled under plugins: in proxy.yaml
+	var pluginChain *plugins.Chain
+	if proxyConfig != nil {
+		var err error
+		if pluginChain, err = plugins.Load(proxyConfig.Plugins); err != nil {
+			log.Fatalf("[STARTUP ERROR] Failed to load plugins: %v", err)
+		}
+		if names := pluginChain.Names(); len(names) > 0 {
+			log.Printf("  - Plugins: %s", strings.Join(names, ", "))
+		}
+	}
+
+	// Sandboxed WebAssembly filters declared with wasm_filters on tables
+	var wasmRuntime *wasmfilter.Runtime
+	if proxyConfig != nil {
+		var err error
+		if wasmRuntime, err = newWasmRuntime(ctx, cfg, proxyConfig); err != nil {
+			log.Fatalf("[STARTUP ERROR] Failed to load WebAssembly filters: %v", err)
+		}
+		if wasmRuntime != nil {
+			defer wasmRuntime.Close(context.Background())
+		}
+	}
+
+	// Columns listed under encrypt on tables are stored in NocoDB as ciphertext
+	var fieldCipher *fieldcrypt.Cipher
+	if proxyConfig != nil {
+		var err error
+		if fieldCipher, err = newFieldCipher(cfg, proxyConfig); err != nil {
+			log.Fatalf("[STARTUP ERROR] Field encryption: %v", err)
+		}
+	}
+
 	// Initialize SQLite database for user storage
 	database, err := db.NewDatabase(cfg.DatabasePath)
 	if err != nil {
 		log.Fatalf("[STARTUP ERROR] Failed to initialize database: %v", err)
 	}
 	defer database.Close()
+	// Tokens of deleted accounts stop working immediately
+	utils.SetRevocationCheck(database.TokenRevoked)
+
+	bootstrapAdmin(cfg, database)
+	if !flags.DemoUsers {
+		demoUsers = nil
+		log.Printf("[STARTUP] Demo users disabled")
+	}
+
+	// Optional encrypted vault for per-tenant/per-base NocoDB tokens
+	var tokenVault *vault.Vault
+	if cfg.TokenVaultKey != "" {
+		tokenVault, err = vault.New(database, cfg.TokenVaultKey)
+		if err != nil {
+			log.Fatalf("[STARTUP ERROR] Failed to open token vault: %v", err)
+		}
+	}
+
+	// Default NocoDB token, swappable at runtime via /admin/upstream-token or NOCODB_TOKEN_FILE
+	tokenReloader := proxy.NewTokenReloader(cfg.NocoDBToken)
+	if cfg.NocoDBTokenFile != "" {
+		if err := tokenReloader.WatchFile(ctx, cfg.NocoDBTokenFile); err != nil {
+			log.Fatalf("[STARTUP ERROR] Failed to read NOCODB_TOKEN_FILE: %v", err)
+		}
+		log.Printf("[STARTUP] Watching %s for NocoDB token changes", cfg.NocoDBTokenFile)
+	}
+	// A default token stored in the vault takes precedence over the reloadable one
+	defaultToken := tokenReloader.Source()
+	if tokenVault != nil {
+		defaultToken = proxy.FirstToken(tokenVault.Source(vault.ScopeDefault), tokenReloader.Source())
+	}
+	// Roles mapped to their own (e.g. read-only) NocoDB token, in place of the default one
+	roleTokens := newRoleTokens(proxyConfig, tokenVault, startup)
 
 	// Initialize Goth OAuth providers
 	initializeGothProviders(cfg)
 
 	// Setup gothic session store
+	cookieOptions, sessionMaxAge, err := newSessionOptions(cfg)
+	if err != nil {
+		log.Fatalf("[STARTUP ERROR] %v", err)
+	}
 	store := sessions.NewCookieStore([]byte(cfg.SessionSecret))
-	store.MaxAge(86400 * 30) // 30 days
+	store.MaxAge(int(sessionMaxAge.Seconds()))
 	store.Options.Path = "/"
+	store.Options.Domain = cookieOptions.Domain
 	store.Options.HttpOnly = true
-	store.Options.Secure = false // Set to true in production with HTTPS
+	store.Options.Secure = cookieOptions.Secure
+	store.Options.SameSite = cookieOptions.SameSite
 	gothic.Store = store
 
 	// Ensure NocoDB URL ends with /
@@ -104,69 +236,543 @@ func main() {
 		nocoDBURL += "/"
 	}
 
+	healthInterval, err := time.ParseDuration(cfg.NocoDBHealthInterval)
+	if err != nil {
+		log.Printf("[STARTUP WARN] Invalid NOCODB_HEALTH_INTERVAL '%s', using 10s", cfg.NocoDBHealthInterval)
+		healthInterval = 10 * time.Second
+	}
+
+	// Optional standby NocoDB instance with health-based failover
+	var failover *proxy.Failover
+	if cfg.NocoDBStandbyURL != "" {
+		failover, err = proxy.NewFailover(nocoDBURL, cfg.NocoDBStandbyURL, cfg.NocoDBHealthPath, healthInterval)
+		if err != nil {
+			log.Fatalf("[STARTUP ERROR] Failed to configure standby upstream: %v", err)
+		}
+		failover.Start(ctx)
+		log.Printf("[STARTUP] Standby upstream configured: %s (health check every %v)", cfg.NocoDBStandbyURL, healthInterval)
+	}
+
+	// Optional read replica for GET traffic, with fallback to the primary
+	var readReplica *proxy.ReadReplica
+	if cfg.ReadNocoDBURL != "" {
+		readReplica, err = proxy.NewReadReplica(nocoDBURL, cfg.ReadNocoDBURL, cfg.NocoDBHealthPath, healthInterval)
+		if err != nil {
+			log.Fatalf("[STARTUP ERROR] Failed to configure read replica: %v", err)
+		}
+		readReplica.Start(ctx)
+		log.Printf("[STARTUP] Read replica configured: %s", cfg.ReadNocoDBURL)
+	}
+
+	// Optional shadow instance for validating upgrades or migrations with real traffic
+	var shadow *proxy.Shadow
+	if cfg.ShadowNocoDBURL != "" {
+		percent, err := strconv.ParseFloat(cfg.ShadowPercent, 64)
+		if err != nil {
+			log.Fatalf("[STARTUP ERROR] Invalid SHADOW_PERCENT '%s': %v", cfg.ShadowPercent, err)
+		}
+		shadowToken := cfg.ShadowNocoDBToken
+		if shadowToken == "" {
+			shadowToken = cfg.NocoDBToken
+		}
+		shadowWrites := cfg.ShadowWrites == "true"
+		shadow, err = proxy.NewShadow(nocoDBURL, cfg.ShadowNocoDBURL, shadowToken, percent, shadowWrites)
+		if err != nil {
+			log.Fatalf("[STARTUP ERROR] Failed to configure shadow upstream: %v", err)
+		}
+		log.Printf("[STARTUP] Shadowing %.1f%% of requests to %s (writes: %v)", percent, cfg.ShadowNocoDBURL, shadowWrites)
+	}
+
+	// Detect which data API the upstream speaks; all record paths are built from it
+	dialect := proxy.ResolveDialect(ctx, cfg.NocoDBAPIVersion, nocoDBURL, cfg.NocoDBBaseID, defaultToken())
+
+	// Refresh interval and shared store (META_STORE=redis) of every MetaCache
+	metaSetup := newMetaCacheSetup(cfg, jobs)
+
+	// Drift of proxy.yaml from NocoDB's schema, per scope, for /metrics
+	driftMonitor := introspect.NewDriftMonitor()
+
 	// Initialize MetaCache for table name resolution
 	var metaCache *proxy.MetaCache
+	var upstreams []*proxy.Upstream
 	if cfg.NocoDBBaseID != "" {
 		metaBaseURL := deriveMetaBaseURL(nocoDBURL)
 		log.Printf("[STARTUP] Meta Base URL: %s", metaBaseURL)
 
 		metaCache = proxy.NewMetaCache(metaBaseURL, cfg.NocoDBBaseID, cfg.NocoDBToken)
+		metaCache.SetFailover(failover)
+		metaCache.SetTokenSource(defaultToken)
+		fetchConcurrency, err := strconv.Atoi(cfg.MetaFetchConcurrency)
+		if err != nil {
+			log.Printf("[STARTUP WARN] Invalid META_FETCH_CONCURRENCY '%s', using 8", cfg.MetaFetchConcurrency)
+			fetchConcurrency = 8
+		}
+		fetchTimeout, err := time.ParseDuration(cfg.MetaFetchTimeout)
+		if err != nil {
+			log.Printf("[STARTUP WARN] Invalid META_FETCH_TIMEOUT '%s', using 10s", cfg.MetaFetchTimeout)
+			fetchTimeout = 10 * time.Second
+		}
+		metaCache.SetDetailFetching(fetchConcurrency, fetchTimeout)
+		metaCache.SetSnapshotPath(cfg.MetaSnapshotPath)
+		metaSetup.apply(metaCache, cfg.NocoDBBaseID)
+		if proxyConfig != nil {
+			metaCache.SetAliases(proxyConfig.Aliases)
+		}
 
 		// Perform initial synchronous metadata load
 		if err := metaCache.LoadInitial(); err != nil {
 			log.Fatalf("[STARTUP FATAL] MetaCache initial load failed: %v", err)
 		}
+		startup.Metadata = introspect.StartupMetadata{Enabled: true, Loaded: true, Stale: metaCache.IsStale(), BaseID: cfg.NocoDBBaseID}
 
-		// Start background auto-refresh
-		metaCache.StartAutoRefresh()
+		// Schedule background auto-refresh
+		if err := metaCache.ScheduleRefresh(ctx, jobs, "metadata"); err != nil {
+			log.Fatalf("[STARTUP FATAL] Cannot schedule metadata refresh: %v", err)
+		}
 
 		// If we have a proxy config, resolve it using MetaCache (only after MetaCache is ready)
 		if proxyConfig != nil {
+			// Tables routed to other NocoDB instances resolve against their upstream's MetaCache
+			for name, upstreamCfg := range proxyConfig.Upstreams {
+				var source proxy.TokenSource
+				if tokenVault != nil {
+					source = tokenVault.Source(vault.UpstreamScope(name))
+				}
+				if upstreamCfg.TokenEnv == "" {
+					source = proxy.FirstToken(source, tokenReloader.Source())
+				}
+				upstream, err := newUpstream(ctx, name, upstreamCfg, cfg.NocoDBToken, cfg.NocoDBAPIVersion, source, metaSetup)
+				if err != nil {
+					log.Fatalf("[STARTUP FATAL] Upstream '%s': %v", name, err)
+				}
+				upstream.Meta.SetAliases(proxyConfig.Aliases)
+				upstreams = append(upstreams, upstream)
+				log.Printf("[STARTUP] Upstream '%s' → %s (base %s)", name, upstream.DataURL, upstream.BaseID)
+			}
+
 			log.Printf("[STARTUP] Resolving proxy configuration using loaded MetaCache...")
 			resolver := config.NewResolver(metaCache)
+			for _, upstream := range upstreams {
+				resolver.SetUpstreamMetaCache(upstream.Name, upstream.Meta)
+			}
+			watchDrift(driftMonitor, "default", resolver, metaCache, proxyConfig)
 			resolvedConfig, err = resolver.Resolve(proxyConfig)
+			startup.Resolution = introspect.StartupResolution{Attempted: true, Drift: driftMonitor.Drift()["default"]}
 			if err != nil {
 				log.Printf("[STARTUP ERROR] ❌ Failed to resolve proxy configuration: %v", err)
 				log.Printf("[STARTUP ERROR] This means the proxy.yaml references tables/fields not found in NocoDB")
 				log.Printf("[STARTUP] Falling back to legacy mode (no schema validation)")
 				resolvedConfig = nil
+				startup.Resolution.Error = err.Error()
+				startup.Legacy("proxy.yaml does not resolve against NocoDB's metadata: " + err.Error())
 			} else {
 				log.Printf("[STARTUP] ✅ Successfully resolved proxy configuration")
 				log.Printf("[STARTUP] Schema-driven mode ACTIVE with %d tables", len(resolvedConfig.Tables))
+				startup.Resolution.Resolved = true
+				startup.Resolution.Tables = len(resolvedConfig.Tables)
+				startup.Mode = "schema-driven"
 			}
 		}
 	} else {
 		log.Println("[STARTUP WARN] NOCODB_BASE_ID not set - MetaCache disabled")
+		startup.Legacy("NOCODB_BASE_ID is not set, so no metadata is loaded")
+	}
+
+	// Event bus shared by webhook receivers, caches and streaming consumers
+	eventBus := events.NewBus()
+	if metaCache != nil {
+		metaCache.SetEventBus(eventBus)
+	}
+
+	// Direct upstream client for background subsystems (CDC, snapshots)
+	upstreamBaseID := cfg.NocoDBBaseID
+	if resolvedConfig != nil {
+		upstreamBaseID = resolvedConfig.BaseID
+	}
+	upstreamClient := proxy.NewUpstreamClient(nocoDBURL, upstreamBaseID, cfg.NocoDBToken)
+	upstreamClient.SetFailover(failover)
+	upstreamClient.SetDialect(dialect)
+	upstreamClient.SetTokenSource(defaultToken)
+	if resolvedConfig != nil && len(upstreams) > 0 {
+		clients := make(map[string]*proxy.UpstreamClient)
+		for _, upstream := range upstreams {
+			clients[upstream.Name] = upstream.Client()
+		}
+		for _, table := range resolvedConfig.Tables {
+			if client, ok := clients[table.Upstream]; ok {
+				upstreamClient.AddRoute(table.TableID, client)
+			}
+		}
+	}
+
+	// Readiness of this instance, scored from weighted component checks
+	readinessChecker, err := newReadiness(cfg, metaCache, database, nocoDBURL, failover)
+	if err != nil {
+		log.Fatalf("[STARTUP ERROR] %v", err)
+	}
+	log.Printf("[STARTUP] Readiness components: %s", readinessChecker.Describe())
+
+	// Optional caps on the requests in flight to NocoDB, shared by every base and tenant
+	var concurrencyLimiter *proxy.ConcurrencyLimiter
+	if proxyConfig != nil {
+		concurrencyLimiter = proxy.NewConcurrencyLimiter(proxyConfig.Concurrency)
+	}
+	if concurrencyLimiter != nil {
+		log.Printf("[STARTUP] Upstream concurrency limited: %s", concurrencyLimiter.Describe())
+	}
+
+	// Optional circuit breaker: requests to a failing NocoDB host fail fast, shared by every base and tenant
+	var circuitBreaker *proxy.CircuitBreaker
+	if proxyConfig != nil {
+		circuitBreaker = proxy.NewCircuitBreaker(proxyConfig.Breaker)
+	}
+	if circuitBreaker != nil {
+		log.Printf("[STARTUP] Circuit breaker: %s", circuitBreaker.Describe())
+	}
+
+	// The dry_run flag reports what proxy.yaml validation would refuse instead of refusing it
+	var dryRun *proxy.DryRun
+	if flags.DryRun {
+		dryRun = proxy.NewDryRun()
+		log.Printf("[STARTUP] Validation dry run: requests proxy.yaml would refuse are logged and forwarded")
+	}
+
+	// Optional request budgets of expensive tables, shared by every user, base and tenant
+	var tableRateLimiter *proxy.TableRateLimiter
+	if proxyConfig != nil {
+		tableRateLimiter = proxy.NewTableRateLimiter(proxyConfig.TableRates)
+	}
+	if tableRateLimiter != nil {
+		log.Printf("[STARTUP] Table request budgets: %s", tableRateLimiter.Describe())
+	}
+
+	// Response cache for tables with a cache block, emptied per table by writes
+	responseCache := newResponseCache(ctx, cfg, proxyConfig, eventBus)
+
+	// LRU of single-record reads, emptied per record by writes
+	var recordCache *proxy.RecordCache
+	if proxyConfig != nil {
+		recordCache = proxy.NewRecordCache(proxyConfig.RecordCache)
+	}
+	if recordCache != nil {
+		recordCache.Watch(ctx, eventBus)
+		log.Printf("[STARTUP] Record cache enabled for %s", recordCache.Describe())
 	}
 
 	// Create proxy handler
 	proxyHandler := proxy.NewProxyHandler(nocoDBURL, cfg.NocoDBToken, metaCache)
+	proxyHandler.SetEventBus(eventBus)
+	proxyHandler.SetFailover(failover)
+	proxyHandler.SetDialect(dialect)
+	proxyHandler.SetReadReplica(readReplica)
+	proxyHandler.SetShadow(shadow)
+	proxyHandler.SetTokenSource(defaultToken)
+	proxyHandler.SetRoleTokens(roleTokens)
+	proxyHandler.SetPlugins(pluginChain)
+	proxyHandler.SetConcurrencyLimiter(concurrencyLimiter)
+	proxyHandler.SetCircuitBreaker(circuitBreaker)
+	proxyHandler.SetTableRateLimiter(tableRateLimiter)
+	proxyHandler.SetDryRun(dryRun)
+	proxyHandler.SetResponseCache(responseCache)
+	proxyHandler.SetRecordCache(recordCache)
+	proxyHandler.SetWasmRuntime(wasmRuntime)
+	proxyHandler.SetFieldCipher(fieldCipher)
+	for _, upstream := range upstreams {
+		proxyHandler.AddUpstream(upstream)
+	}
 
 	// Set resolved configuration if available (config-driven mode)
 	if resolvedConfig != nil {
 		proxyHandler.SetResolvedConfig(resolvedConfig)
 		log.Printf("[STARTUP] Proxy handler configured in schema-driven mode")
+		scheduleCacheWarming(ctx, jobs, "default", proxyHandler)
+
+		// Re-resolve proxy.yaml only when a refresh finds a changed schema
+		metaCache.OnSchemaChange(func() {
+			resolver := config.NewResolver(metaCache)
+			for _, upstream := range upstreams {
+				resolver.SetUpstreamMetaCache(upstream.Name, upstream.Meta)
+			}
+			updated, err := resolver.Resolve(proxyConfig)
+			if err != nil {
+				log.Printf("[META WARNING] Schema changed but proxy.yaml no longer resolves, keeping current configuration: %v", err)
+				return
+			}
+			proxyHandler.SetResolvedConfig(updated)
+		})
 	} else {
 		log.Printf("[STARTUP] Proxy handler configured in legacy mode")
 	}
 
+	// Additional bases are served under /proxy/{base}/..., each with its own MetaCache
+	if proxyConfig != nil && resolvedConfig == nil && (len(proxyConfig.Bases) > 0 || len(proxyConfig.Tenants) > 0) {
+		startup.Warn("additional bases and tenants are disabled in legacy mode")
+	}
+	baseRouter := proxy.NewBaseRouter(proxyHandler)
+	metaAdmin := proxy.NewMetaAdmin(metaCache)
+	if proxyConfig != nil && resolvedConfig != nil {
+		for name := range proxyConfig.Bases {
+			var source proxy.TokenSource
+			if tokenVault != nil {
+				source = tokenVault.Source(vault.BaseScope(name))
+			}
+			source = proxy.FirstToken(source, tokenReloader.Source())
+			baseConfig, _ := proxyConfig.ForBase(name)
+			baseHandler, err := newScopedProxy(ctx, "base:"+name, baseConfig, nocoDBURL, cfg.NocoDBToken, source, failover, eventBus, metaSetup)
+			if err != nil {
+				log.Printf("[STARTUP WARN] Base '%s' disabled: %v", name, err)
+				startup.Bases[name] = introspect.StartupScope{Error: err.Error()}
+				continue
+			}
+			startup.Bases[name] = introspect.StartupScope{Enabled: true}
+			baseHandler.SetRoleTokens(roleTokens)
+			baseHandler.SetDialect(dialect)
+			baseHandler.SetReadReplica(readReplica)
+			baseHandler.SetPlugins(pluginChain)
+			baseHandler.SetConcurrencyLimiter(concurrencyLimiter)
+			baseHandler.SetCircuitBreaker(circuitBreaker)
+			baseHandler.SetTableRateLimiter(tableRateLimiter)
+			baseHandler.SetDryRun(dryRun)
+			baseHandler.SetResponseCache(responseCache)
+			baseHandler.SetRecordCache(recordCache)
+			scheduleCacheWarming(ctx, jobs, "base:"+name, baseHandler)
+			baseHandler.SetWasmRuntime(wasmRuntime)
+			baseHandler.SetFieldCipher(fieldCipher)
+			watchDrift(driftMonitor, "base:"+name, config.NewResolver(baseHandler.Meta), baseHandler.Meta, baseConfig)
+			baseRouter.AddBase(name, baseHandler)
+			metaAdmin.AddBase(name, baseHandler.Meta)
+			log.Printf("[STARTUP] Base '%s' available at /proxy/%s/", name, name)
+		}
+	}
+
+	// Multi-tenant mode: each tenant gets its own base, token and table overlay
+	var tenantRouter *tenancy.Router
+	if proxyConfig != nil && resolvedConfig != nil && len(proxyConfig.Tenants) > 0 {
+		tenantRouter = tenancy.NewRouter()
+		for name, tenant := range proxyConfig.Tenants {
+			// A token in the vault takes precedence; it never falls back to the default token
+			var source proxy.TokenSource
+			inVault := tokenVault != nil && tokenVault.Get(vault.TenantScope(name)) != ""
+			if tokenVault != nil {
+				source = tokenVault.Source(vault.TenantScope(name))
+			}
+
+			token := cfg.NocoDBToken
+			sharesDefault := tenant.TokenEnv == "" && !inVault
+			if sharesDefault && len(proxyConfig.Tenants) > 1 {
+				// The default token can usually reach every base: tenants would not be isolated
+				log.Printf("[STARTUP WARN] Tenant '%s' disabled: it has no token_env or vault token of its own", name)
+				startup.Tenants[name] = introspect.StartupScope{Error: "no token_env or vault token; several tenants cannot share the default token"}
+				continue
+			}
+			if !sharesDefault {
+				token = ""
+				if tenant.TokenEnv != "" {
+					token = os.Getenv(tenant.TokenEnv)
+				}
+				if token == "" && !inVault {
+					log.Printf("[STARTUP WARN] Tenant '%s' disabled: %s is not set", name, tenant.TokenEnv)
+					startup.Tenants[name] = introspect.StartupScope{Error: tenant.TokenEnv + " is not set"}
+					continue
+				}
+			} else {
+				log.Printf("[STARTUP WARN] Tenant '%s' shares the default NocoDB token", name)
+				startup.Warn(fmt.Sprintf("tenant '%s' shares the default NocoDB token", name))
+				source = proxy.FirstToken(source, tokenReloader.Source())
+			}
+
+			tenantConfig, _ := proxyConfig.ForTenant(name)
+			tenantHandler, err := newScopedProxy(ctx, "tenant:"+name, tenantConfig, nocoDBURL, token, source, failover, eventBus, metaSetup)
+			if err != nil {
+				log.Printf("[STARTUP WARN] Tenant '%s' disabled: %v", name, err)
+				startup.Tenants[name] = introspect.StartupScope{Error: err.Error()}
+				continue
+			}
+			startup.Tenants[name] = introspect.StartupScope{Enabled: true}
+			if sharesDefault {
+				// Role tokens stand in for the default token, not for a tenant's own
+				tenantHandler.SetRoleTokens(roleTokens)
+			}
+			tenantHandler.SetDialect(dialect)
+			tenantHandler.SetReadReplica(readReplica)
+			tenantHandler.SetPlugins(pluginChain)
+			tenantHandler.SetConcurrencyLimiter(concurrencyLimiter)
+			tenantHandler.SetCircuitBreaker(circuitBreaker)
+			tenantHandler.SetTableRateLimiter(tableRateLimiter)
+			tenantHandler.SetDryRun(dryRun)
+			tenantHandler.SetResponseCache(responseCache)
+			tenantHandler.SetRecordCache(recordCache)
+			scheduleCacheWarming(ctx, jobs, "tenant:"+name, tenantHandler)
+			tenantHandler.SetWasmRuntime(wasmRuntime)
+			tenantHandler.SetFieldCipher(fieldCipher)
+			watchDrift(driftMonitor, "tenant:"+name, config.NewResolver(tenantHandler.Meta), tenantHandler.Meta, tenantConfig)
+			tenantRouter.Add(name, tenantHandler)
+		}
+	}
+
 	// Create auth handler
-	authHandler := auth.NewHandler(database, cfg.JWTSecret, "http://localhost:4321")
+	authHandler := auth.NewHandler(database, cfg.JWTSecret, cfg.FrontendURL)
+	if err := authHandler.SetRedirectAllowlist(cfg.FrontendRedirectAllowlist); err != nil {
+		log.Fatalf("[STARTUP ERROR] Invalid FRONTEND_REDIRECT_ALLOWLIST: %v", err)
+	}
+	authHandler.SetEventBus(eventBus)
+	authHandler.SetSessionOptions(cookieOptions, sessionMaxAge)
+
+	// Bot challenge on /login and /signup
+	captchaGuard, err := newCaptchaGuard(cfg)
+	if err != nil {
+		log.Fatalf("[STARTUP ERROR] Invalid captcha configuration: %v", err)
+	}
+
+	// Country allow/deny lists (geoip in proxy.yaml, database at GEOIP_DATABASE)
+	geoFilter, err := newGeoIPFilter(cfg, proxyConfig)
+	if err != nil {
+		log.Fatalf("[STARTUP ERROR] Geo-IP: %v", err)
+	}
+	if geoFilter != nil {
+		defer geoFilter.Close()
+	}
+
+	// Logins from new devices or locations (SUSPICIOUS_LOGIN)
+	loginMonitor, err := newLoginMonitor(cfg, database, geoFilter)
+	if err != nil {
+		log.Fatalf("[STARTUP ERROR] Suspicious login detection: %v", err)
+	}
+	authHandler.SetLoginMonitor(loginMonitor)
 
 	// Create introspection handler
 	introspectHandler := introspect.NewHandler(metaCache, resolvedConfig, proxyConfigPath)
+	introspectHandler.SetFailover(failover)
+	introspectHandler.SetReadReplica(readReplica)
+	introspectHandler.SetShadow(shadow)
+	introspectHandler.SetConcurrencyLimiter(concurrencyLimiter)
+	introspectHandler.SetCircuitBreaker(circuitBreaker)
+	introspectHandler.SetUpstreamPool(upstreamPool)
+	introspectHandler.SetTableRateLimiter(tableRateLimiter)
+	introspectHandler.SetDryRun(dryRun)
+	introspectHandler.SetResponseCache(responseCache)
+	introspectHandler.SetRecordCache(recordCache)
+	introspectHandler.SetEventBus(eventBus)
+	introspectHandler.SetFlags(flags)
+	introspectHandler.SetScheduler(jobs)
+	introspectHandler.SetDriftMonitor(driftMonitor)
+	reportFeatures(startup, cfg, proxyConfig, pluginChain)
+	introspectHandler.SetStartupReport(startup)
+
+	// Create NocoDB webhook receiver
+	webhookReceiver := webhooks.NewReceiver(cfg.NocoDBWebhookSecret, cfg.WebhookFanoutURLs, metaCache, eventBus)
+
+	// Start outbound webhook delivery for per-table targets in proxy.yaml
+	webhookDispatcher := webhooks.NewDispatcher(resolvedConfig, eventBus, cfg.WebhookDeadLetterPath)
+	webhookDispatcher.SetSchemaTargets(cfg.SchemaWebhookURLs, "SCHEMA_WEBHOOK_SECRET")
+	if proxyConfig != nil {
+		webhookDispatcher.SetAuthTargets(proxyConfig.AuthWebhooks)
+	}
+	webhookDispatcher.Start()
+
+	// Start change-data-capture polling for tables with cdc.enabled in proxy.yaml
+	if resolvedConfig != nil {
+		cdcPoller := cdc.NewPoller(upstreamClient, database, eventBus, resolvedConfig)
+		cdcPoller.Start(jobs)
+	}
+
+	// Start the local read-through mirror for tables with mirror.enabled in proxy.yaml
+	var searchHandler http.Handler
+	if resolvedConfig != nil {
+		mirrorStore, err := mirror.NewStore(cfg.MirrorDatabasePath)
+		if err != nil {
+			log.Printf("[STARTUP WARN] Local mirror disabled: %v", err)
+		} else {
+			defer mirrorStore.Close()
+			mirrorSyncer := mirror.NewSyncer(mirrorStore, upstreamClient, eventBus, resolvedConfig)
+			if retention, err := time.ParseDuration(cfg.MirrorChangeRetention); err == nil {
+				mirrorSyncer.SetChangeRetention(retention)
+			} else {
+				log.Printf("[STARTUP WARN] Invalid MIRROR_CHANGE_RETENTION '%s', using default", cfg.MirrorChangeRetention)
+			}
+
+			// Full-text search over mirrored tables with search.enabled
+			searchIndex, err := search.NewIndex(resolvedConfig)
+			if err != nil {
+				log.Printf("[STARTUP WARN] Search disabled: %v", err)
+			} else if len(searchIndex.Tables()) > 0 {
+				searchIndex.Load(mirrorStore)
+				mirrorSyncer.SetIndexer(searchIndex)
+				searchHandler = search.NewHandler(searchIndex, mirrorStore, resolvedConfig)
+			}
+
+			if mirrorSyncer.Start(ctx, jobs) > 0 {
+				proxyHandler.SetMirror(mirrorStore)
+				proxyHandler.SetChangeLog(mirrorStore)
+			}
+		}
+	}
+
+	// Start the durable write outbox for tables with outbox.enabled in proxy.yaml
+	if resolvedConfig != nil {
+		proxyHandler.SetOutbox(database)
+		proxyHandler.StartOutbox(ctx)
+	}
+
+	// Optionally forward all events to NATS JetStream or Kafka
+	if cfg.EventsBackend != "" {
+		publisherConfig := events.PublisherConfig{
+			Backend:       cfg.EventsBackend,
+			URL:           cfg.EventsURL,
+			TopicTemplate: cfg.EventsTopicTemplate,
+			Schema:        cfg.EventsSchema,
+		}
+		publisher, err := events.NewPublisher(publisherConfig)
+		if err != nil {
+			log.Printf("[STARTUP WARN] Event publishing disabled: %v", err)
+		} else {
+			forwarder := events.NewForwarder(eventBus, publisher, publisherConfig)
+			forwarder.Start()
+			defer forwarder.Stop()
+		}
+	}
+
+	// Run the periodic jobs registered above; features set up later may still add jobs
+	jobs.Start(ctx)
 
 	// Create router
 	mux := http.NewServeMux()
 
 	// Public endpoints
-	mux.HandleFunc("/login", loginHandler(database, cfg.JWTSecret))
-	mux.HandleFunc("/signup", signupHandler(database, cfg.JWTSecret))
+	mux.HandleFunc("/login", captchaGuard.Protect("login", loginHandler(database, cfg.JWTSecret, sessionMaxAge, eventBus, loginMonitor)))
+	if loginMonitor.Action() == auth.SuspiciousVerify {
+		mux.HandleFunc("/login/verify", loginVerifyHandler(database, cfg.JWTSecret, sessionMaxAge, eventBus, loginMonitor))
+	}
+	if flags.Signup {
+		mux.HandleFunc("/signup", captchaGuard.Protect("signup", signupHandler(database, cfg.JWTSecret, eventBus, newBreachChecker(cfg))))
+	} else {
+		mux.HandleFunc("/signup", func(w http.ResponseWriter, r *http.Request) {
+			respondWithError(w, http.StatusForbidden, "signup is disabled")
+		})
+	}
+	mux.HandleFunc("/auth/challenge", captchaGuard.ServeChallenge)
 	mux.HandleFunc("/health", healthHandler)
+	mux.Handle("/readyz", readinessChecker)
 
 	// Introspection endpoints (read-only, no auth required for ops visibility)
-	mux.HandleFunc("/__proxy/status", introspectHandler.ServeStatus)
-	mux.HandleFunc("/__proxy/schema", introspectHandler.ServeSchema)
+	// Status and schema are public unless the introspection_auth flag is set
+	introspection := func(handler http.HandlerFunc) http.Handler {
+		if flags.IntrospectionAuth {
+			return middleware.AuthMiddleware(cfg.JWTSecret)(middleware.RequireAdmin(handler))
+		}
+		return handler
+	}
+	mux.Handle("/__proxy/status", introspection(introspectHandler.ServeStatus))
+	mux.Handle("/__proxy/schema", introspection(introspectHandler.ServeSchema))
+	mux.Handle("/__proxy/startup", introspection(introspectHandler.ServeStartup))
+	mux.Handle("/__proxy/schema/changes", introspection(introspectHandler.ServeSchemaChanges))
+	mux.Handle("/__proxy/cache", middleware.AuthMiddleware(cfg.JWTSecret)(middleware.RequireAdmin(http.HandlerFunc(introspectHandler.ServeCache))))
+	mux.Handle("/__proxy/cache/refresh", middleware.AuthMiddleware(cfg.JWTSecret)(middleware.RequireAdmin(http.HandlerFunc(introspectHandler.ServeCacheRefresh))))
+	mux.Handle("/metrics", introspection(introspectHandler.ServeMetrics))
+	mux.Handle("/__proxy/jobs", middleware.AuthMiddleware(cfg.JWTSecret)(middleware.RequireAdmin(http.HandlerFunc(introspectHandler.ServeJobs))))
+
+	// NocoDB webhook receiver (authenticated by shared-secret signature)
+	mux.Handle("/__proxy/webhooks/nocodb", webhookReceiver)
 
 	// OAuth endpoints
 	mux.HandleFunc("/auth/google", authHandler.BeginAuth)
@@ -175,12 +781,33 @@ func main() {
 	mux.HandleFunc("/auth/github/callback", authHandler.CallbackAuth)
 	mux.HandleFunc("/auth/logout", authHandler.Logout)
 
+	// OAuth 2.0 device authorization grant for CLI tools and headless devices
+	verificationURL := cfg.DeviceVerificationURL
+	if verificationURL == "" {
+		verificationURL = strings.TrimRight(cfg.FrontendURL, "/") + "/device"
+	}
+	deviceFlow := auth.NewDeviceFlow(database, cfg.JWTSecret, verificationURL)
+	deviceFlow.SetEventBus(eventBus)
+	mux.HandleFunc("/auth/device/code", deviceFlow.ServeCode)
+	mux.HandleFunc("/auth/device/token", deviceFlow.ServeToken)
+	mux.Handle("/auth/device/approve", middleware.AuthMiddleware(cfg.JWTSecret)(http.HandlerFunc(deviceFlow.ServeApprove)))
+
 	// Protected auth endpoints
 	protectedUserHandler := auth.AuthMiddleware(cfg.JWTSecret)(
 		http.HandlerFunc(authHandler.GetCurrentUser),
 	)
 	mux.Handle("/auth/me", protectedUserHandler)
 
+	// Self-service data export (account, tenants, write history, owned rows)
+	exporter := privacy.NewExporter(database, upstreamClient, resolvedConfig)
+	mux.Handle("/auth/me/export", middleware.AuthMiddleware(cfg.JWTSecret)(http.HandlerFunc(exporter.ServeExport)))
+
+	// Account deletion (self-service with confirmation, or by an admin)
+	eraser := privacy.NewEraser(database, upstreamClient, resolvedConfig)
+	eraser.SetEventBus(eventBus)
+	mux.Handle("/auth/me/delete", middleware.AuthMiddleware(cfg.JWTSecret)(http.HandlerFunc(eraser.ServeDeleteAccount)))
+	mux.Handle("/admin/users/delete", middleware.AuthMiddleware(cfg.JWTSecret)(http.HandlerFunc(eraser.ServeAdminDelete)))
+
 	// Protected secure ping endpoint (example)
 	protectedPingHandler := auth.AuthMiddleware(cfg.JWTSecret)(
 		http.HandlerFunc(securePingHandler(database)),
@@ -188,13 +815,111 @@ func main() {
 	mux.Handle("/api/secure/ping", protectedPingHandler)
 
 	// Protected proxy endpoints (ONLY data access path)
-	protectedHandler := middleware.AuthMiddleware(cfg.JWTSecret)(
-		middleware.AuthorizeMiddleware(proxyHandler),
-	)
-	mux.Handle("/proxy/", protectedHandler)
+	var dataHandler http.Handler = middleware.AuthorizeMiddleware(baseRouter)
+	if tenantRouter != nil {
+		dataHandler = tenancy.Middleware(database)(middleware.AuthorizeMiddleware(tenantRouter))
+		mux.Handle("/admin/tenants/members", middleware.AuthMiddleware(cfg.JWTSecret)(tenancy.NewAdminHandler(database)))
+	}
+
+	// Per-user metering of the data API, with the quotas of proxy.yaml
+	var quotas *config.QuotaConfig
+	if proxyConfig != nil {
+		quotas = proxyConfig.Quotas
+	}
+	meter := usage.NewMeter(database, quotas)
+	if err := meter.Schedule(jobs); err != nil {
+		log.Fatalf("[STARTUP FATAL] Cannot schedule usage flush: %v", err)
+	}
+	mux.Handle("/auth/me/usage", middleware.AuthMiddleware(cfg.JWTSecret)(http.HandlerFunc(meter.ServeMyUsage)))
+	mux.Handle("/admin/usage", middleware.AuthMiddleware(cfg.JWTSecret)(http.HandlerFunc(meter.ServeAdminUsage)))
+
+	meteredHandler := meter.Middleware(dataHandler)
+	protectedHandler := middleware.AuthMiddleware(cfg.JWTSecret)(meteredHandler)
+
+	// Reads of the tables in the anonymous block of proxy.yaml need no token
+	var anonymousConfig *config.AnonymousConfig
+	if proxyConfig != nil {
+		anonymousConfig = proxyConfig.Anonymous
+	}
+	anonymousAccess := anonymous.New(anonymousConfig, geoFilter)
+	// Clients may bound the wait for NocoDB with X-Budget-Ms; every response reports its timing
+	budgetMax, err := time.ParseDuration(cfg.LatencyBudgetMax)
+	if err != nil || budgetMax < 0 {
+		log.Fatalf("[STARTUP ERROR] Invalid LATENCY_BUDGET_MAX '%s'", cfg.LatencyBudgetMax)
+	}
+	latencyBudget := proxy.LatencyBudget(budgetMax)
+	var dataAPI http.Handler = latencyBudget(pluginChain.PreAuth(anonymousAccess.Handler(protectedHandler, meteredHandler)))
+	// Request bodies beyond MAX_REQUEST_KB are refused with 413, whatever their table
+	dataAPI = proxy.LimitRequestBody(maxRequestBytes(cfg))(dataAPI)
+	// Clients sending Accept-Encoding: gzip get large responses compressed
+	if compress := newCompression(cfg); compress != nil {
+		dataAPI = compress(dataAPI)
+	}
+	mux.Handle("/proxy/", dataAPI)
+
+	// NocoDB shared views of proxy.yaml at /public/{alias}, without gateway auth
+	var publicViews map[string]config.PublicView
+	if proxyConfig != nil {
+		publicViews = proxyConfig.PublicViews
+	}
+	publicHandler, err := publicview.New(publicViews, dialect, geoFilter)
+	if err != nil {
+		log.Fatalf("[STARTUP ERROR] Public views: %v", err)
+	}
+	if publicHandler != nil {
+		mux.Handle("/public/", publicHandler)
+	}
+
+	// Full-text search and WebSocket subscriptions read the default base directly,
+	// so they are not exposed when tenants are isolated from each other
+	if tenantRouter != nil {
+		searchHandler = nil
+	}
+
+	// Full-text search over mirrored tables
+	if searchHandler != nil {
+		mux.Handle("/search", middleware.AuthMiddleware(cfg.JWTSecret)(searchHandler))
+	}
+
+	// Upstream token rotation (admin only)
+	if tokenVault != nil {
+		tokenAdmin := middleware.AuthMiddleware(cfg.JWTSecret)(vault.NewHandler(tokenVault))
+		mux.Handle("/admin/tokens", tokenAdmin)
+		mux.Handle("/admin/tokens/", tokenAdmin)
+	}
+	mux.Handle("/admin/upstream-token", middleware.AuthMiddleware(cfg.JWTSecret)(tokenReloader))
+
+	// Encrypted archive of the gateway's state for disaster recovery (admin only)
+	if cfg.StateArchiveKey != "" {
+		mux.Handle("/admin/state/export", middleware.AuthMiddleware(cfg.JWTSecret)(backup.NewHandler(database, proxyConfigPath, cfg.StateArchiveKey)))
+	}
+
+	// Schema changes through NocoDB's meta API (admin only), followed by a metadata refresh
+	mux.Handle("/admin/meta/", middleware.AuthMiddleware(cfg.JWTSecret)(metaAdmin))
+
+	// Outbox status for queued writes (owner or admin only)
+	mux.Handle("/outbox/", middleware.AuthMiddleware(cfg.JWTSecret)(
+		http.HandlerFunc(proxyHandler.ServeOutboxStatus),
+	))
+
+	// Signed, expiring links to single records of the default base, readable without authentication
+	if resolvedConfig != nil && tenantRouter == nil {
+		shareSecret := cfg.ShareLinkSecret
+		if shareSecret == "" {
+			shareSecret = cfg.JWTSecret
+		}
+		shareLinks := proxy.NewShareLinks(proxyHandler, shareSecret, geoFilter)
+		mux.Handle("/share", middleware.AuthMiddleware(cfg.JWTSecret)(http.HandlerFunc(shareLinks.ServeCreate)))
+		mux.HandleFunc("/shared/", shareLinks.ServeShared)
+	}
+
+	// WebSocket subscriptions (authenticates via Bearer header or ?token=)
+	if tenantRouter == nil {
+		mux.Handle("/ws", proxy.NewWebSocketHandler(proxyHandler, cfg.JWTSecret))
+	}
 
 	// Apply CORS middleware (outermost layer to prevent duplicates)
-	handler := middleware.CORSMiddleware(mux)
+	handler := middleware.CORSMiddleware(geoFilter.Middleware(mux))
 
 	// Start server
 	addr := ":" + cfg.Port
@@ -210,6 +935,12 @@ func main() {
 		log.Printf("[STARTUP]    Config: %s", proxyConfigPath)
 		log.Printf("[STARTUP]    Tables: %d configured", len(resolvedConfig.Tables))
 		log.Printf("[STARTUP]    Validation: ENABLED")
+		if bases := baseRouter.Bases(); len(bases) > 0 {
+			log.Printf("[STARTUP]    Additional bases: %s", strings.Join(bases, ", "))
+		}
+		if tenantRouter != nil {
+			log.Printf("[STARTUP]    Tenants: %s (tenant required on /proxy/*)", strings.Join(tenantRouter.Tenants(), ", "))
+		}
 	} else {
 		log.Printf("\n[STARTUP] 🔓 PROXY MODE: Legacy (No Validation)")
 		log.Printf("[STARTUP]    All operations allowed")
@@ -217,8 +948,39 @@ func main() {
 
 	log.Printf("\n[STARTUP] Endpoints:")
 	log.Printf("  - Data Access:    /proxy/*")
+	if anonymousAccess != nil {
+		log.Printf("  - Anonymous Read: %s (no token)", strings.Join(anonymousAccess.Tables(), ", "))
+	}
+	if publicHandler != nil {
+		log.Printf("  - Public Views:   /public/{%s}", strings.Join(publicHandler.Aliases(), ","))
+	}
+	if tenantRouter == nil {
+		log.Printf("  - Subscriptions:  /ws")
+	}
+	log.Printf("  - Write Status:   /outbox/{id}")
+	if tokenVault != nil {
+		log.Printf("  - Token Vault:    /admin/tokens (admin)")
+	}
+	log.Printf("  - Upstream Token: /admin/upstream-token (admin)")
+	log.Printf("  - Schema Changes: /admin/meta/* (admin)")
+	if searchHandler != nil {
+		log.Printf("  - Search:         /search?q=")
+	}
 	log.Printf("  - Status:         /__proxy/status")
 	log.Printf("  - Schema Info:    /__proxy/schema")
+	log.Printf("  - Schema Changes: /__proxy/schema/changes")
+	log.Printf("  - Cache Contents: /__proxy/cache (admin)")
+	log.Printf("  - Cache Refresh:  POST /__proxy/cache/refresh (admin)")
+	log.Printf("  - Jobs:           /__proxy/jobs (admin)")
+	log.Printf("  - NocoDB Webhook: /__proxy/webhooks/nocodb")
+	log.Printf("  - Auth Challenge: /auth/challenge")
+	if loginMonitor.Action() == auth.SuspiciousVerify {
+		log.Printf("  - Login Verify:   POST /login/verify")
+	}
+	log.Printf("  - Device Login:   POST /auth/device/code, /auth/device/token, /auth/device/approve")
+	log.Printf("  - Data Export:    /auth/me/export")
+	log.Printf("  - Delete Account: POST /auth/me/delete, POST /admin/users/delete (admin)")
+	log.Printf("  - Usage:          /auth/me/usage, /admin/usage (admin)")
 	log.Printf("  - Health Check:   /health")
 
 	log.Printf("\n[STARTUP] OAuth Providers:")
@@ -235,19 +997,37 @@ func main() {
 		log.Printf("  ✗ GitHub OAuth disabled (set GITHUB_CLIENT_ID)")
 	}
 
-	log.Printf("\n[STARTUP] Demo users (legacy login):")
-	log.Printf("  - admin@example.com / admin123 (role: admin)")
-	log.Printf("  - user@example.com / user123 (role: user)")
+	if demoUsers != nil {
+		log.Printf("\n[STARTUP] Demo users (legacy login, disable with flags.demo_users: false):")
+		log.Printf("  - admin@example.com / admin123 (role: admin)")
+		log.Printf("  - user@example.com / user123 (role: user)")
+	}
 	log.Printf("\n[STARTUP] ========================================")
 	log.Println("[STARTUP] ✅ Server ready!")
 	log.Printf("[STARTUP] ========================================\n")
 
-	if err := http.ListenAndServe(addr, handler); err != nil {
+	server := &http.Server{Addr: addr, Handler: handler}
+	go func() {
+		<-ctx.Done()
+		log.Printf("[SHUTDOWN] Signal received, draining connections...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("[SHUTDOWN ERROR] %v", err)
+		}
+	}()
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatal(err)
 	}
+	if err := meter.Flush(); err != nil {
+		log.Printf("[SHUTDOWN ERROR] %v", err)
+	}
+	log.Printf("[SHUTDOWN] Server stopped")
 }
 
-func loginHandler(database *db.Database, jwtSecret string) http.HandlerFunc {
+// loginHandler issues a token valid for a day, or for rememberTTL when the
+// request asks to be remembered
+func loginHandler(database *db.Database, jwtSecret string, rememberTTL time.Duration, bus *events.Bus, monitor *auth.LoginMonitor) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("[LOGIN] Login attempt from %s", r.RemoteAddr)
 
@@ -270,8 +1050,40 @@ func loginHandler(database *db.Database, jwtSecret string) http.HandlerFunc {
 		if err == nil && dbUser != nil {
 			log.Printf("[LOGIN] Database user authenticated: %s (role: %s)", dbUser.Email, dbUser.Role)
 
+			if !tenantAllowed(database, fmt.Sprintf("%d", dbUser.ID), req.Tenant) {
+				log.Printf("[LOGIN ERROR] User %s is not a member of tenant '%s'", dbUser.Email, req.Tenant)
+				respondWithError(w, http.StatusForbidden, "not a member of tenant")
+				return
+			}
+
+			// Logins from a new device or location are flagged, reported to the user or held back
+			assessment := monitor.Assess(r, fmt.Sprintf("%d", dbUser.ID))
+			if assessment.Suspicious() {
+				log.Printf("[LOGIN] Suspicious login for %s (%s)", dbUser.Email, assessment.Flags())
+				switch monitor.Action() {
+				case auth.SuspiciousVerify:
+					challengeID, err := monitor.Challenge(dbUser.Email, req.Tenant, assessment)
+					if err != nil {
+						log.Printf("[LOGIN ERROR] Failed to send login verification to %s: %v", dbUser.Email, err)
+						respondWithError(w, http.StatusServiceUnavailable, "failed to send verification code")
+						return
+					}
+					publishAuthEvent(bus, events.TypeAuthLoginChallenged, assessment.UserID, dbUser.Email, r, suspiciousMetadata(assessment))
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusAccepted)
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"verification_required": true,
+						"verification_id":       challengeID,
+					})
+					return
+				case auth.SuspiciousNotify:
+					monitor.Notify(dbUser.Email, assessment, r)
+				}
+			}
+			monitor.Remember(assessment)
+
 			// Generate JWT
-			token, err := utils.GenerateJWT(fmt.Sprintf("%d", dbUser.ID), dbUser.Role, jwtSecret)
+			token, err := utils.GenerateTenantJWTWithTTL(fmt.Sprintf("%d", dbUser.ID), dbUser.Role, req.Tenant, jwtSecret, tokenTTL(req.RememberMe, rememberTTL))
 			if err != nil {
 				log.Printf("[LOGIN ERROR] Failed to generate JWT: %v", err)
 				respondWithError(w, http.StatusInternalServerError, "failed to generate token")
@@ -284,8 +1096,10 @@ func loginHandler(database *db.Database, jwtSecret string) http.HandlerFunc {
 				Token:  token,
 				UserID: fmt.Sprintf("%d", dbUser.ID),
 				Role:   dbUser.Role,
+				Tenant: req.Tenant,
 			}
 			json.NewEncoder(w).Encode(response)
+			publishAuthEvent(bus, events.TypeAuthLogin, response.UserID, dbUser.Email, r, loginMetadata(dbUser.Role, assessment))
 			log.Printf("[LOGIN] Login successful for database user: %s", dbUser.Email)
 			return
 		}
@@ -294,14 +1108,21 @@ func loginHandler(database *db.Database, jwtSecret string) http.HandlerFunc {
 		user, exists := demoUsers[req.Email]
 		if !exists || user.Password != req.Password {
 			log.Printf("[LOGIN ERROR] Invalid credentials for email: %s", req.Email)
+			publishAuthEvent(bus, events.TypeAuthLoginFailed, "", req.Email, r, nil)
 			respondWithError(w, http.StatusUnauthorized, "invalid credentials")
 			return
 		}
 		log.Printf("[LOGIN] Credentials validated for demo user: %s (role: %s)", user.UserID, user.Role)
 
+		if !tenantAllowed(database, user.UserID, req.Tenant) {
+			log.Printf("[LOGIN ERROR] User %s is not a member of tenant '%s'", user.UserID, req.Tenant)
+			respondWithError(w, http.StatusForbidden, "not a member of tenant")
+			return
+		}
+
 		// Generate JWT
 		log.Printf("[LOGIN] Generating JWT token...")
-		token, err := utils.GenerateJWT(user.UserID, user.Role, jwtSecret)
+		token, err := utils.GenerateTenantJWTWithTTL(user.UserID, user.Role, req.Tenant, jwtSecret, tokenTTL(req.RememberMe, rememberTTL))
 		if err != nil {
 			log.Printf("[LOGIN ERROR] Failed to generate JWT: %v", err)
 			respondWithError(w, http.StatusInternalServerError, "failed to generate token")
@@ -315,22 +1136,97 @@ func loginHandler(database *db.Database, jwtSecret string) http.HandlerFunc {
 			Token:  token,
 			UserID: user.UserID,
 			Role:   user.Role,
+			Tenant: req.Tenant,
 		}
 		if err := json.NewEncoder(w).Encode(response); err != nil {
 			log.Printf("[LOGIN ERROR] Failed to encode response: %v", err)
 			return
 		}
+		publishAuthEvent(bus, events.TypeAuthLogin, user.UserID, req.Email, r, map[string]string{"role": user.Role})
 		log.Printf("[LOGIN] Login successful for demo user: %s", user.UserID)
 	}
 }
 
+type LoginVerifyRequest struct {
+	VerificationID string `json:"verification_id"`
+	Code           string `json:"code"`
+	RememberMe     bool   `json:"remember_me,omitempty"`
+}
+
+// loginVerifyHandler completes a login held back by SUSPICIOUS_LOGIN=verify.
+// The client repeats remember_me here, the challenge does not keep it.
+func loginVerifyHandler(database *db.Database, jwtSecret string, rememberTTL time.Duration, bus *events.Bus, monitor *auth.LoginMonitor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req LoginVerifyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.VerificationID == "" || req.Code == "" {
+			respondWithError(w, http.StatusBadRequest, "verification_id and code are required")
+			return
+		}
+
+		challenge, err := monitor.CompleteChallenge(req.VerificationID, strings.TrimSpace(req.Code), r)
+		if errors.Is(err, auth.ErrChallengeLocked) {
+			email := ""
+			id, _ := strconv.ParseInt(challenge.UserID, 10, 64)
+			if dbUser, _ := database.GetUserByID(id); dbUser != nil {
+				email = dbUser.Email
+			}
+			log.Printf("[LOGIN ERROR] Login verification for user %s locked after too many wrong codes (from %s)", challenge.UserID, r.RemoteAddr)
+			publishAuthEvent(bus, events.TypeAuthLockout, challenge.UserID, email, r, map[string]string{
+				"reason":     "verification_attempts",
+				"suspicious": challenge.Flags,
+			})
+			respondWithError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		if err != nil {
+			log.Printf("[LOGIN ERROR] Login verification failed from %s: %v", r.RemoteAddr, err)
+			respondWithError(w, http.StatusUnauthorized, auth.ErrChallengeFailed.Error())
+			return
+		}
+
+		id, _ := strconv.ParseInt(challenge.UserID, 10, 64)
+		dbUser, err := database.GetUserByID(id)
+		if err != nil || dbUser == nil {
+			respondWithError(w, http.StatusUnauthorized, "account no longer exists")
+			return
+		}
+
+		token, err := utils.GenerateTenantJWTWithTTL(challenge.UserID, dbUser.Role, challenge.Tenant, jwtSecret, tokenTTL(req.RememberMe, rememberTTL))
+		if err != nil {
+			log.Printf("[LOGIN ERROR] Failed to generate JWT: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "failed to generate token")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		response := LoginResponse{
+			Token:  token,
+			UserID: challenge.UserID,
+			Role:   dbUser.Role,
+			Tenant: challenge.Tenant,
+		}
+		json.NewEncoder(w).Encode(response)
+		publishAuthEvent(bus, events.TypeAuthLogin, challenge.UserID, dbUser.Email, r, map[string]string{
+			"role":       dbUser.Role,
+			"suspicious": challenge.Flags,
+			"verified":   "true",
+		})
+		log.Printf("[LOGIN] Login verified for database user: %s", dbUser.Email)
+	}
+}
+
 type SignupRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 	Name     string `json:"name"`
 }
 
-func signupHandler(database *db.Database, jwtSecret string) http.HandlerFunc {
+func signupHandler(database *db.Database, jwtSecret string, bus *events.Bus, breaches *auth.BreachChecker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("[SIGNUP] Signup attempt from %s", r.RemoteAddr)
 
@@ -360,6 +1256,17 @@ func signupHandler(database *db.Database, jwtSecret string) http.HandlerFunc {
 			return
 		}
 
+		// Lookup failures let the signup through: the check hardens signups but must not block them
+		rejected, err := breaches.CheckPassword(r.Context(), req.Password)
+		if err != nil {
+			log.Printf("[SIGNUP WARN] Breached password check failed: %v", err)
+		}
+		if rejected != nil {
+			log.Printf("[SIGNUP ERROR] Password of %s found in known breaches", req.Email)
+			respondWithError(w, http.StatusBadRequest, rejected.Error())
+			return
+		}
+
 		log.Printf("[SIGNUP] Creating user: email=%s, name=%s", req.Email, req.Name)
 
 		// Check if user already exists (from OAuth or previous signup)
@@ -397,6 +1304,7 @@ func signupHandler(database *db.Database, jwtSecret string) http.HandlerFunc {
 			Role:   user.Role,
 		}
 		json.NewEncoder(w).Encode(response)
+		publishAuthEvent(bus, events.TypeAuthSignup, response.UserID, user.Email, r, nil)
 		log.Printf("[SIGNUP] Signup successful for user: %s", user.Email)
 	}
 }
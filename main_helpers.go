@@ -1,14 +1,38 @@
 package main
 
 import (
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/grove/generic-proxy/internal/auth"
+	"github.com/grove/generic-proxy/internal/captcha"
 	"github.com/grove/generic-proxy/internal/config"
 	"github.com/grove/generic-proxy/internal/db"
+	"github.com/grove/generic-proxy/internal/events"
+	"github.com/grove/generic-proxy/internal/fieldcrypt"
+	"github.com/grove/generic-proxy/internal/geoip"
+	"github.com/grove/generic-proxy/internal/introspect"
+	"github.com/grove/generic-proxy/internal/mailer"
+	"github.com/grove/generic-proxy/internal/mocknocodb"
+	"github.com/grove/generic-proxy/internal/plugins"
+	"github.com/grove/generic-proxy/internal/proxy"
+	"github.com/grove/generic-proxy/internal/readiness"
+	"github.com/grove/generic-proxy/internal/scheduler"
+	"github.com/grove/generic-proxy/internal/signing"
+	"github.com/grove/generic-proxy/internal/utils"
+	"github.com/grove/generic-proxy/internal/vault"
+	"github.com/grove/generic-proxy/internal/wasmfilter"
 	"github.com/markbates/goth"
 	"github.com/markbates/goth/providers/github"
 	"github.com/markbates/goth/providers/google"
@@ -107,6 +131,31 @@ func securePingHandler(database *db.Database) http.HandlerFunc {
 	}
 }
 
+// publishAuthEvent emits an authentication event on the bus (if configured).
+// extra is added to the event's metadata and may be nil.
+func publishAuthEvent(bus *events.Bus, eventType, userID, email string, r *http.Request, extra map[string]string) {
+	if bus == nil {
+		return
+	}
+	metadata := map[string]string{
+		"email":       email,
+		"remote_addr": r.RemoteAddr,
+		"user_agent":  r.UserAgent(),
+	}
+	if country := geoip.Country(r.Context()); country != "" {
+		metadata["country"] = country
+	}
+	for key, value := range extra {
+		metadata[key] = value
+	}
+	bus.Publish(events.Event{
+		Type:     eventType,
+		Source:   events.SourceAuth,
+		UserID:   userID,
+		Metadata: metadata,
+	})
+}
+
 func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
@@ -164,3 +213,723 @@ func deriveMetaBaseURL(nocoDBURL string) string {
 	baseURL := nocoDBURL[:apiIndex]
 	return baseURL + "/api/v2/"
 }
+
+// newScopedProxy builds a proxy handler with its own MetaCache for a single-base
+// configuration (an additional base or a tenant)
+func newScopedProxy(ctx context.Context, scope string, scoped *config.ProxyConfig, nocoDBURL, token string, source proxy.TokenSource, failover *proxy.Failover, bus *events.Bus, setup metaCacheSetup) (*proxy.ProxyHandler, error) {
+	metaCache := proxy.NewMetaCache(deriveMetaBaseURL(nocoDBURL), scoped.NocoDB.BaseID, token)
+	metaCache.SetFailover(failover)
+	setup.apply(metaCache, scoped.NocoDB.BaseID)
+	metaCache.SetAliases(scoped.Aliases)
+	if source != nil {
+		metaCache.SetTokenSource(source)
+	}
+	if err := metaCache.LoadInitial(); err != nil {
+		return nil, fmt.Errorf("metadata load failed: %w", err)
+	}
+	if err := metaCache.ScheduleRefresh(ctx, setup.jobs, "metadata:"+scope); err != nil {
+		return nil, err
+	}
+
+	resolvedConfig, err := config.NewResolver(metaCache).Resolve(scoped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve configuration: %w", err)
+	}
+
+	handler := proxy.NewProxyHandler(nocoDBURL, token, metaCache)
+	handler.SetFailover(failover)
+	if source != nil {
+		handler.SetTokenSource(source)
+	}
+	handler.SetEventBus(bus)
+	handler.SetResolvedConfig(resolvedConfig)
+	return handler, nil
+}
+
+// newUpstream builds a named upstream (with its own MetaCache) for tables routed
+// away from the default NocoDB instance
+func newUpstream(ctx context.Context, name string, upstreamCfg config.UpstreamConfig, defaultToken, apiVersion string, source proxy.TokenSource, setup metaCacheSetup) (*proxy.Upstream, error) {
+	token := defaultToken
+	if upstreamCfg.TokenEnv != "" {
+		if envToken := os.Getenv(upstreamCfg.TokenEnv); envToken != "" {
+			token = envToken
+		}
+	}
+
+	metaCache := proxy.NewMetaCache(deriveMetaBaseURL(upstreamCfg.URL), upstreamCfg.BaseID, token)
+	setup.apply(metaCache, upstreamCfg.BaseID)
+	if source != nil {
+		metaCache.SetTokenSource(source)
+	}
+	if err := metaCache.LoadInitial(); err != nil {
+		return nil, fmt.Errorf("metadata load failed: %w", err)
+	}
+	if err := metaCache.ScheduleRefresh(ctx, setup.jobs, "metadata:upstream:"+name); err != nil {
+		return nil, err
+	}
+
+	upstream := proxy.NewUpstream(name, upstreamCfg.URL, upstreamCfg.BaseID, token, metaCache)
+	upstream.TokenSource = source
+	upstream.Dialect = proxy.ResolveDialect(ctx, apiVersion, upstreamCfg.URL, upstreamCfg.BaseID, token)
+	return upstream, nil
+}
+
+// defaultProxyConfigPath returns PROXY_CONFIG_PATH or ./config/proxy.yaml
+func defaultProxyConfigPath() string {
+	if path := os.Getenv("PROXY_CONFIG_PATH"); path != "" {
+		return path
+	}
+	return "./config/proxy.yaml"
+}
+
+// redisMetaPrefix namespaces the metadata keys of every base in Redis
+const redisMetaPrefix = "nocodb-gateway:meta:"
+
+// metaCacheSetup holds the MetaCache settings shared by every base
+type metaCacheSetup struct {
+	refreshInterval time.Duration
+	store           func(baseID string) proxy.MetadataStore // nil when metadata is not shared
+	jobs            *scheduler.Scheduler                    // runs the periodic refreshes
+}
+
+// newMetaCacheSetup parses META_REFRESH_INTERVAL and selects the metadata store
+// backend from META_STORE. Unknown backends and an unreachable Redis fall back
+// to per-instance caches.
+func newMetaCacheSetup(cfg *config.Config, jobs *scheduler.Scheduler) metaCacheSetup {
+	setup := metaCacheSetup{jobs: jobs}
+	interval, err := time.ParseDuration(cfg.MetaRefreshInterval)
+	if err != nil || interval <= 0 {
+		log.Printf("[STARTUP WARN] Invalid META_REFRESH_INTERVAL '%s', using 10m", cfg.MetaRefreshInterval)
+		interval = 10 * time.Minute
+	}
+	setup.refreshInterval = interval
+
+	switch strings.ToLower(cfg.MetaStore) {
+	case "", "file":
+	case "redis":
+		client, err := proxy.NewRedisClient(cfg.RedisURL)
+		if err != nil {
+			log.Printf("[STARTUP WARN] Redis metadata store unavailable, each instance keeps its own cache: %v", err)
+			break
+		}
+		log.Printf("[STARTUP] Sharing metadata through Redis (single refresher per base)")
+		setup.store = func(baseID string) proxy.MetadataStore {
+			return proxy.NewRedisStore(client, redisMetaPrefix, baseID)
+		}
+	default:
+		log.Printf("[STARTUP WARN] Unknown META_STORE '%s' (expected file or redis), using file", cfg.MetaStore)
+	}
+	return setup
+}
+
+// apply configures a MetaCache for a base
+func (s metaCacheSetup) apply(metaCache *proxy.MetaCache, baseID string) {
+	metaCache.SetRefreshInterval(s.refreshInterval)
+	if s.store != nil {
+		metaCache.SetStore(s.store(baseID))
+	}
+}
+
+// tenantAllowed reports whether a login may be scoped to the requested tenant
+func tenantAllowed(database *db.Database, userID, tenant string) bool {
+	if tenant == "" {
+		return true
+	}
+	member, err := database.UserHasTenant(userID, tenant)
+	return err == nil && member
+}
+
+// bootstrapAdmin creates the BOOTSTRAP_ADMIN_EMAIL account on first start, when the
+// user database has no admin yet. Existing admins are never touched.
+func bootstrapAdmin(cfg *config.Config, database *db.Database) {
+	if cfg.BootstrapAdminEmail == "" {
+		return
+	}
+	admins, err := database.CountUsersWithRole("admin")
+	if err != nil {
+		log.Printf("[STARTUP WARN] Cannot check for existing admins, skipping bootstrap: %v", err)
+		return
+	}
+	if admins > 0 {
+		log.Printf("[STARTUP] %d admin account(s) exist, BOOTSTRAP_ADMIN_EMAIL ignored", admins)
+		return
+	}
+	if cfg.BootstrapAdminPassword == "" {
+		log.Printf("[STARTUP WARN] BOOTSTRAP_ADMIN_EMAIL is set without BOOTSTRAP_ADMIN_PASSWORD, no admin created")
+		return
+	}
+	user, err := createAdmin(database, cfg.BootstrapAdminEmail, cfg.BootstrapAdminPassword, "")
+	if err != nil {
+		log.Printf("[STARTUP WARN] Bootstrap admin not created: %v", err)
+		return
+	}
+	log.Printf("[STARTUP] ✓ Bootstrap admin created: %s (id %d)", user.Email, user.ID)
+}
+
+// startMockNocoDB serves the MOCK_NOCODB_FIXTURES tables on a local port until ctx
+// is done and points cfg at it, so the whole gateway runs without a NocoDB instance.
+// The mock accepts any token.
+func startMockNocoDB(ctx context.Context, cfg *config.Config) error {
+	fixtures, err := mocknocodb.LoadFixtures(cfg.MockNocoDBFixtures)
+	if err != nil {
+		return err
+	}
+	mock, err := mocknocodb.New(fixtures, "")
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	server := &http.Server{Handler: mock}
+	go server.Serve(listener)
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	cfg.NocoDBURL = fmt.Sprintf("http://%s/api/v3/data/%s/", listener.Addr(), mock.BaseID())
+	cfg.NocoDBBaseID = mock.BaseID()
+	log.Printf("[STARTUP] 🧪 Mock NocoDB serving %d table(s) from %s at http://%s", len(fixtures.Tables), cfg.MockNocoDBFixtures, listener.Addr())
+	return nil
+}
+
+// wasmModules lists the filter modules used by any table of proxy.yaml, including
+// additional bases and tenants
+func wasmModules(proxyConfig *config.ProxyConfig) []string {
+	seen := make(map[string]bool)
+	var modules []string
+	collect := func(tables map[string]config.TableConfig) {
+		for _, table := range tables {
+			for _, filter := range table.Filters {
+				if !seen[filter.Module] {
+					seen[filter.Module] = true
+					modules = append(modules, filter.Module)
+				}
+			}
+		}
+	}
+	collect(proxyConfig.Tables)
+	for _, base := range proxyConfig.Bases {
+		collect(base.Tables)
+	}
+	for _, tenant := range proxyConfig.Tenants {
+		collect(tenant.Tables)
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// newWasmRuntime compiles every filter module of proxy.yaml. It returns nil when
+// no table uses WebAssembly filters.
+func newWasmRuntime(ctx context.Context, cfg *config.Config, proxyConfig *config.ProxyConfig) (*wasmfilter.Runtime, error) {
+	modules := wasmModules(proxyConfig)
+	if len(modules) == 0 {
+		return nil, nil
+	}
+
+	timeout, err := time.ParseDuration(cfg.WasmFilterTimeout)
+	if err != nil || timeout <= 0 {
+		log.Printf("[STARTUP WARN] Invalid WASM_FILTER_TIMEOUT '%s', using 100ms", cfg.WasmFilterTimeout)
+		timeout = 100 * time.Millisecond
+	}
+	runtime := wasmfilter.NewRuntime(ctx, timeout)
+	for _, module := range modules {
+		if err := runtime.Load(ctx, module); err != nil {
+			runtime.Close(ctx)
+			return nil, err
+		}
+	}
+	log.Printf("[STARTUP] Loaded %d WebAssembly filter module(s) (timeout %v)", len(modules), timeout)
+	return runtime, nil
+}
+
+// encryptedTables counts the tables of proxy.yaml, including those of additional
+// bases and tenants, that encrypt columns
+func encryptedTables(proxyConfig *config.ProxyConfig) int {
+	count := 0
+	collect := func(tables map[string]config.TableConfig) {
+		for _, table := range tables {
+			if table.Encrypt != nil {
+				count++
+			}
+		}
+	}
+	collect(proxyConfig.Tables)
+	for _, base := range proxyConfig.Bases {
+		collect(base.Tables)
+	}
+	for _, tenant := range proxyConfig.Tenants {
+		collect(tenant.Tables)
+	}
+	return count
+}
+
+// newFieldCipher creates the cipher of encrypted columns. It returns nil when no
+// table encrypts columns and fails when one does but FIELD_ENCRYPTION_KEY is unset.
+func newFieldCipher(cfg *config.Config, proxyConfig *config.ProxyConfig) (*fieldcrypt.Cipher, error) {
+	tables := encryptedTables(proxyConfig)
+	if tables == 0 {
+		return nil, nil
+	}
+	if cfg.FieldEncryptionKey == "" {
+		return nil, fmt.Errorf("%d table(s) encrypt columns but FIELD_ENCRYPTION_KEY is not set", tables)
+	}
+	cipher, err := fieldcrypt.New(cfg.FieldEncryptionKey, cfg.FieldEncryptionPreviousKeys)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[STARTUP] Field encryption enabled for %d table(s) (%d previous key(s))", tables, len(cfg.FieldEncryptionPreviousKeys))
+	return cipher, nil
+}
+
+// newGeoIPFilter opens the country database at GEOIP_DATABASE. It returns nil
+// when none is set; a geoip block in proxy.yaml then is a configuration error.
+// Without a geoip block countries are only resolved for the audit trail.
+func newGeoIPFilter(cfg *config.Config, proxyConfig *config.ProxyConfig) (*geoip.Filter, error) {
+	rules := &config.GeoIPConfig{}
+	configured := proxyConfig != nil && proxyConfig.GeoIP != nil
+	if configured {
+		rules = proxyConfig.GeoIP
+	}
+	if cfg.GeoIPDatabase == "" {
+		if configured {
+			return nil, fmt.Errorf("proxy.yaml restricts access by country but GEOIP_DATABASE is not set")
+		}
+		return nil, nil
+	}
+
+	filter, err := geoip.Open(cfg.GeoIPDatabase, rules)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[STARTUP] Geo-IP: %s, %d route group rule(s)", filter.Description(), len(rules.Groups))
+	return filter, nil
+}
+
+// newLoginMonitor sets up the detection of logins from new devices and
+// locations (SUSPICIOUS_LOGIN). It returns nil when detection is off.
+func newLoginMonitor(cfg *config.Config, database *db.Database, geo *geoip.Filter) (*auth.LoginMonitor, error) {
+	m, err := mailer.New(mailer.Config{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.SMTPFrom,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP configuration: %w", err)
+	}
+	monitor, err := auth.NewLoginMonitor(database, cfg.SuspiciousLogin, m, geo)
+	if err != nil || monitor == nil {
+		return nil, err
+	}
+	if m != nil {
+		log.Printf("[STARTUP] Suspicious logins: %s (mail via %s)", monitor.Action(), m.Addr())
+	} else {
+		log.Printf("[STARTUP] Suspicious logins: %s", monitor.Action())
+	}
+	return monitor, nil
+}
+
+// suspiciousMetadata returns the event metadata flagging a suspicious login, nil for an ordinary one
+func suspiciousMetadata(a auth.LoginAssessment) map[string]string {
+	if !a.Suspicious() {
+		return nil
+	}
+	return map[string]string{"suspicious": a.Flags()}
+}
+
+// loginMetadata returns the event metadata of a successful login: the role,
+// which tells admin logins apart, and the flags of a suspicious login
+func loginMetadata(role string, a auth.LoginAssessment) map[string]string {
+	metadata := map[string]string{"role": role}
+	if a.Suspicious() {
+		metadata["suspicious"] = a.Flags()
+	}
+	return metadata
+}
+
+// redisResponsePrefix namespaces the shared response cache in Redis
+const redisResponsePrefix = "nocodb-gateway:responses:"
+
+// newResponseCache creates the response cache when a table of proxy.yaml has a
+// cache block, nil otherwise. Record events from the bus invalidate it. With
+// RESPONSE_CACHE_STORE=redis the responses are shared; an unreachable Redis
+// falls back to a cache per instance.
+func newResponseCache(ctx context.Context, cfg *config.Config, proxyConfig *config.ProxyConfig, bus *events.Bus) *proxy.ResponseCache {
+	if proxyConfig == nil {
+		return nil
+	}
+	tableSets := []map[string]config.TableConfig{proxyConfig.Tables}
+	for _, base := range proxyConfig.Bases {
+		tableSets = append(tableSets, base.Tables)
+	}
+	for _, tenant := range proxyConfig.Tenants {
+		tableSets = append(tableSets, tenant.Tables)
+	}
+	var cached []string
+	for _, tables := range tableSets {
+		for name, table := range tables {
+			if table.Cache != nil {
+				cached = append(cached, name)
+			}
+		}
+	}
+	if len(cached) == 0 {
+		return nil
+	}
+	sort.Strings(cached)
+	cache := proxy.NewResponseCache()
+	switch strings.ToLower(cfg.ResponseCacheStore) {
+	case "", "memory":
+	case "redis":
+		client, err := proxy.NewRedisClient(cfg.RedisURL)
+		if err != nil {
+			log.Printf("[STARTUP WARN] Redis response cache unavailable, each instance caches its own responses: %v", err)
+			break
+		}
+		cache.SetStore(proxy.NewRedisResponseStore(client, redisResponsePrefix))
+		log.Printf("[STARTUP] Sharing cached responses through Redis")
+	default:
+		log.Printf("[STARTUP WARN] Unknown RESPONSE_CACHE_STORE '%s' (expected memory or redis), using memory", cfg.ResponseCacheStore)
+	}
+	cache.Watch(ctx, bus)
+	log.Printf("[STARTUP] Response cache enabled for: %s", strings.Join(cached, ", "))
+	return cache
+}
+
+// scheduleCacheWarming registers the warm queries of a handler's cached tables
+func scheduleCacheWarming(ctx context.Context, jobs *scheduler.Scheduler, scope string, handler *proxy.ProxyHandler) {
+	warmed, err := handler.ScheduleCacheWarming(ctx, jobs, scope)
+	if err != nil {
+		log.Printf("[STARTUP WARN] Cache warming for %s: %v", scope, err)
+	}
+	if warmed > 0 {
+		log.Printf("[STARTUP] Warming the response cache of %d table(s) (%s)", warmed, scope)
+	}
+}
+
+// newUpstreamTransport builds the connection pool of upstream requests from the
+// UPSTREAM_* settings. net/http's default transport keeps only two idle
+// connections per host, so under load most requests to NocoDB dialed a new
+// connection and left the old ones in TIME_WAIT.
+func newUpstreamTransport(cfg *config.Config) *proxy.PooledTransport {
+	count := func(name, value string, fallback int) int {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			log.Printf("[STARTUP WARN] Invalid %s '%s', using %d", name, value, fallback)
+			return fallback
+		}
+		return n
+	}
+	duration := func(name, value string, fallback time.Duration) time.Duration {
+		d, err := time.ParseDuration(value)
+		if err != nil || (d < 0 && name != "UPSTREAM_KEEPALIVE") {
+			log.Printf("[STARTUP WARN] Invalid %s '%s', using %v", name, value, fallback)
+			return fallback
+		}
+		return d
+	}
+	transportCfg := proxy.TransportConfig{
+		MaxIdleConns:          count("UPSTREAM_MAX_IDLE_CONNS", cfg.UpstreamMaxIdleConns, 256),
+		MaxIdleConnsPerHost:   count("UPSTREAM_MAX_IDLE_CONNS_PER_HOST", cfg.UpstreamMaxIdleConnsPerHost, 64),
+		MaxConnsPerHost:       count("UPSTREAM_MAX_CONNS_PER_HOST", cfg.UpstreamMaxConnsPerHost, 0),
+		IdleConnTimeout:       duration("UPSTREAM_IDLE_CONN_TIMEOUT", cfg.UpstreamIdleConnTimeout, 90*time.Second),
+		DialTimeout:           duration("UPSTREAM_DIAL_TIMEOUT", cfg.UpstreamDialTimeout, 10*time.Second),
+		KeepAlive:             duration("UPSTREAM_KEEPALIVE", cfg.UpstreamKeepAlive, 30*time.Second),
+		TLSHandshakeTimeout:   duration("UPSTREAM_TLS_HANDSHAKE_TIMEOUT", cfg.UpstreamTLSHandshakeTimeout, 10*time.Second),
+		ResponseHeaderTimeout: duration("UPSTREAM_RESPONSE_HEADER_TIMEOUT", cfg.UpstreamResponseHeaderTimeout, 0),
+	}
+	maxConns := "unlimited"
+	if transportCfg.MaxConnsPerHost > 0 {
+		maxConns = strconv.Itoa(transportCfg.MaxConnsPerHost)
+	}
+	log.Printf("  - Upstream pool: %d idle connections per host (%d in total), %s per host, idle timeout %v",
+		transportCfg.MaxIdleConnsPerHost, transportCfg.MaxIdleConns, maxConns, transportCfg.IdleConnTimeout)
+	return proxy.NewPooledTransport(transportCfg)
+}
+
+// maxRequestBytes returns the gateway-wide request body limit of
+// MAX_REQUEST_KB in bytes, 0 for none
+func maxRequestBytes(cfg *config.Config) int64 {
+	kb, err := strconv.Atoi(cfg.MaxRequestKB)
+	if err != nil || kb < 0 {
+		log.Fatalf("[STARTUP ERROR] Invalid MAX_REQUEST_KB '%s'", cfg.MaxRequestKB)
+	}
+	if kb == 0 {
+		log.Printf("[STARTUP] Request bodies on /proxy/ are not limited")
+		return 0
+	}
+	log.Printf("[STARTUP] Request bodies on /proxy/ are limited to %d KB", kb)
+	return int64(kb) << 10
+}
+
+// newCompression returns the middleware that gzips /proxy/ responses, or nil
+// when COMPRESSION is off
+func newCompression(cfg *config.Config) func(http.Handler) http.Handler {
+	if cfg.Compression != "true" {
+		log.Printf("[STARTUP] Response compression is off")
+		return nil
+	}
+	compression := proxy.CompressionConfig{MinBytes: 1024, Level: 5}
+	if n, err := strconv.Atoi(cfg.CompressionMinBytes); err == nil && n >= 0 {
+		compression.MinBytes = n
+	} else {
+		log.Printf("[STARTUP WARN] Invalid COMPRESSION_MIN_BYTES '%s', using %d", cfg.CompressionMinBytes, compression.MinBytes)
+	}
+	if n, err := strconv.Atoi(cfg.CompressionLevel); err == nil && n >= gzip.BestSpeed && n <= gzip.BestCompression {
+		compression.Level = n
+	} else {
+		log.Printf("[STARTUP WARN] Invalid COMPRESSION_LEVEL '%s', using %d", cfg.CompressionLevel, compression.Level)
+	}
+	for _, mediaType := range strings.Split(cfg.CompressionTypes, ",") {
+		if mediaType = strings.ToLower(strings.TrimSpace(mediaType)); mediaType != "" {
+			compression.Types = append(compression.Types, mediaType)
+		}
+	}
+	log.Printf("[STARTUP] Response compression: gzip level %d, %d bytes and more of %s",
+		compression.Level, compression.MinBytes, strings.Join(compression.Types, ", "))
+	return proxy.Compress(compression)
+}
+
+// installUpstreamSigning signs the requests to every configured NocoDB
+// instance with UPSTREAM_SIGNING_SECRET, for topologies where another gateway
+// or an authenticating proxy sits in front of NocoDB
+func installUpstreamSigning(cfg *config.Config, proxyConfig *config.ProxyConfig) {
+	if cfg.UpstreamSigningSecret == "" {
+		return
+	}
+	urls := []string{cfg.NocoDBURL, cfg.NocoDBStandbyURL, cfg.ReadNocoDBURL, cfg.ShadowNocoDBURL}
+	if proxyConfig != nil {
+		for _, upstream := range proxyConfig.Upstreams {
+			urls = append(urls, upstream.URL)
+		}
+	}
+	hosts := make(map[string]bool)
+	var names []string
+	for _, raw := range urls {
+		parsed, err := url.Parse(raw)
+		if err != nil || parsed.Host == "" || hosts[parsed.Host] {
+			continue
+		}
+		hosts[parsed.Host] = true
+		names = append(names, parsed.Host)
+	}
+
+	// Every upstream client uses the default transport
+	http.DefaultTransport = &signing.Transport{Base: http.DefaultTransport, Secret: cfg.UpstreamSigningSecret, Hosts: hosts}
+	log.Printf("  - Upstream signing: %s", strings.Join(names, ", "))
+}
+
+// newCaptchaGuard sets up the bot challenge on the public auth endpoints. It
+// returns nil when CAPTCHA_PROVIDER is not set.
+func newCaptchaGuard(cfg *config.Config) (*captcha.Guard, error) {
+	endpoints := cfg.CaptchaEndpoints
+	if len(endpoints) == 0 {
+		endpoints = []string{"login", "signup"}
+	}
+	difficulty, err := strconv.Atoi(cfg.CaptchaDifficulty)
+	if err != nil {
+		log.Printf("[STARTUP WARN] Invalid CAPTCHA_POW_DIFFICULTY '%s', using %d", cfg.CaptchaDifficulty, captcha.DefaultDifficulty)
+		difficulty = captcha.DefaultDifficulty
+	}
+
+	guard, err := captcha.New(captcha.Config{
+		Provider:   cfg.CaptchaProvider,
+		Secret:     cfg.CaptchaSecret,
+		SiteKey:    cfg.CaptchaSiteKey,
+		Endpoints:  endpoints,
+		Difficulty: difficulty,
+	})
+	if err != nil || guard == nil {
+		return nil, err
+	}
+	log.Printf("[STARTUP] Captcha (%s) required on: %s", guard.Provider(), strings.Join(guard.Endpoints(), ", "))
+	return guard, nil
+}
+
+// newBreachChecker returns the compromised-password check, or nil when
+// PASSWORD_BREACH_CHECK is not enabled
+func newBreachChecker(cfg *config.Config) *auth.BreachChecker {
+	if cfg.PasswordBreachCheck != "true" {
+		return nil
+	}
+	log.Printf("[STARTUP] Breached password check enabled (%s)", cfg.PwnedPasswordsURL)
+	return auth.NewBreachChecker(cfg.PwnedPasswordsURL)
+}
+
+// newReadiness builds the checks of GET /readyz: metadata freshness (when
+// proxy.yaml is used), the upstream's health endpoint, the user database and,
+// with a standby, whether any NocoDB instance passes its health checks
+func newReadiness(cfg *config.Config, metaCache *proxy.MetaCache, database *db.Database, nocoDBURL string, failover *proxy.Failover) (*readiness.Checker, error) {
+	weights, err := readiness.ParseWeights(cfg.ReadyWeights)
+	if err != nil {
+		return nil, fmt.Errorf("invalid READY_WEIGHTS: %w", err)
+	}
+	minScore, err := strconv.Atoi(cfg.ReadyMinScore)
+	if err != nil || minScore < 0 || minScore > 100 {
+		return nil, fmt.Errorf("invalid READY_MIN_SCORE '%s' (expected 0-100)", cfg.ReadyMinScore)
+	}
+	maxAge, err := time.ParseDuration(cfg.ReadyMetaMaxAge)
+	if err != nil || maxAge <= 0 {
+		return nil, fmt.Errorf("invalid READY_META_MAX_AGE '%s'", cfg.ReadyMetaMaxAge)
+	}
+
+	checker := readiness.New(minScore, weights)
+	if metaCache != nil {
+		checker.Add("metadata", 3, func(ctx context.Context) error {
+			if !metaCache.IsReady() {
+				return fmt.Errorf("metadata not loaded yet")
+			}
+			health := metaCache.GetRefreshHealth()
+			age := time.Duration(health.StalenessSeconds) * time.Second
+			if age > maxAge && health.ConsecutiveFailures > 0 {
+				return fmt.Errorf("metadata is %v old (limit %v) after %d failed refreshes: %s", age, maxAge, health.ConsecutiveFailures, health.LastError)
+			}
+			if age > maxAge {
+				return fmt.Errorf("metadata is %v old (limit %v)", age, maxAge)
+			}
+			return nil
+		})
+	}
+	checker.Add("upstream", 3, func(ctx context.Context) error {
+		return proxy.ProbeUpstream(ctx, nocoDBURL, cfg.NocoDBHealthPath, failover)
+	})
+	checker.Add("database", 2, database.Ping)
+	if failover != nil {
+		checker.Add("failover", 2, func(ctx context.Context) error {
+			if !failover.Available() {
+				return fmt.Errorf("neither the primary nor the standby passed its last health check")
+			}
+			return nil
+		})
+	}
+	return checker, nil
+}
+
+// watchDrift computes how far a scope's configuration has drifted from NocoDB's
+// schema now and after every metadata refresh, and logs what is missing
+func watchDrift(monitor *introspect.DriftMonitor, scope string, resolver *config.Resolver, metaCache *proxy.MetaCache, scoped *config.ProxyConfig) {
+	update := func() {
+		drift := resolver.Drift(scoped)
+		if len(drift.MissingTables) > 0 || len(drift.MissingFields) > 0 {
+			log.Printf("[DRIFT] %s: proxy.yaml references %d table(s) and %d field(s) missing in NocoDB: tables %v, fields %v", scope, len(drift.MissingTables), drift.FieldCount(), drift.MissingTables, drift.MissingFields)
+		}
+		monitor.Update(scope, drift)
+	}
+	update()
+	metaCache.OnRefresh(update)
+}
+
+// reportFeatures lists the optional providers and subsystems enabled by the
+// environment and proxy.yaml in the startup report
+func reportFeatures(startup *introspect.StartupReport, cfg *config.Config, proxyConfig *config.ProxyConfig, pluginChain *plugins.Chain) {
+	enabled := map[string]bool{
+		"mock_nocodb":                 cfg.MockNocoDBFixtures != "",
+		"oauth_google":                cfg.GoogleClientID != "" && cfg.GoogleClientSecret != "",
+		"oauth_github":                cfg.GitHubClientID != "" && cfg.GitHubClientSecret != "",
+		"failover":                    cfg.NocoDBStandbyURL != "",
+		"read_replica":                cfg.ReadNocoDBURL != "",
+		"shadow":                      cfg.ShadowNocoDBURL != "",
+		"captcha":                     cfg.CaptchaProvider != "",
+		"breach_check":                cfg.PasswordBreachCheck == "true",
+		"email":                       cfg.SMTPHost != "",
+		"token_vault":                 cfg.TokenVaultKey != "",
+		"state_export":                cfg.StateArchiveKey != "",
+		"field_encryption":            cfg.FieldEncryptionKey != "",
+		"upstream_signing":            cfg.UpstreamSigningSecret != "",
+		"nocodb_webhooks":             cfg.NocoDBWebhookSecret != "",
+		"events_" + cfg.EventsBackend: cfg.EventsBackend != "",
+		"meta_store_" + cfg.MetaStore: cfg.MetaStore != "" && cfg.MetaStore != "file",
+		"response_cache_redis":        cfg.ResponseCacheStore == "redis",
+	}
+	if proxyConfig != nil {
+		enabled["geoip"] = proxyConfig.GeoIP != nil
+		enabled["anonymous_access"] = proxyConfig.Anonymous != nil
+		enabled["public_views"] = len(proxyConfig.PublicViews) > 0
+		enabled["quotas"] = proxyConfig.Quotas != nil
+		enabled["concurrency_limits"] = proxyConfig.Concurrency != nil
+		enabled["table_rate_limits"] = len(proxyConfig.TableRates) > 0
+		enabled["role_tokens"] = len(proxyConfig.RoleTokens) > 0
+		for _, table := range proxyConfig.Tables {
+			enabled["share_links"] = enabled["share_links"] || (table.ShareLinks != nil && table.ShareLinks.Enabled)
+			enabled["table_pipelines"] = enabled["table_pipelines"] || len(table.Pipeline) > 0
+		}
+		enabled["record_cache"] = proxyConfig.RecordCache != nil
+		enabled["wasm_filters"] = len(wasmModules(proxyConfig)) > 0
+		enabled["validation_dry_run"] = config.ResolveFlags(proxyConfig, cfg).DryRun
+	}
+	for _, name := range pluginChain.Names() {
+		enabled["plugin_"+name] = true
+	}
+
+	for feature, on := range enabled {
+		if on {
+			startup.Enable(feature)
+		}
+	}
+}
+
+// newRoleTokens returns the token source of each role in role_tokens: the
+// vault's "role:{name}" token, else the role's token_env. A role without a
+// token stays mapped, so its requests are refused instead of falling back to
+// the default token.
+func newRoleTokens(proxyConfig *config.ProxyConfig, tokenVault *vault.Vault, startup *introspect.StartupReport) map[string]proxy.TokenSource {
+	if proxyConfig == nil || len(proxyConfig.RoleTokens) == 0 {
+		return nil
+	}
+	tokens := make(map[string]proxy.TokenSource, len(proxyConfig.RoleTokens))
+	for role, roleToken := range proxyConfig.RoleTokens {
+		var sources []proxy.TokenSource
+		if tokenVault != nil {
+			sources = append(sources, tokenVault.Source(vault.RoleScope(role)))
+		}
+		if roleToken.TokenEnv != "" {
+			token := os.Getenv(roleToken.TokenEnv)
+			sources = append(sources, func() string { return token })
+		}
+		tokens[role] = proxy.FirstToken(sources...)
+		if tokens[role]() == "" {
+			log.Printf("[STARTUP WARN] Role '%s' has no NocoDB token; its requests will be refused", role)
+			startup.Warn(fmt.Sprintf("role '%s' has no NocoDB token; its requests are refused", role))
+			continue
+		}
+		log.Printf("[STARTUP] Role '%s' uses its own NocoDB token", role)
+	}
+	return tokens
+}
+
+// newSessionOptions reads the session cookie attributes and SESSION_MAX_AGE,
+// which is also the token lifetime of remember-me logins
+func newSessionOptions(cfg *config.Config) (auth.CookieOptions, time.Duration, error) {
+	sameSite, err := auth.ParseSameSite(cfg.SessionCookieSameSite)
+	if err != nil {
+		return auth.CookieOptions{}, 0, fmt.Errorf("invalid SESSION_COOKIE_SAMESITE: %w", err)
+	}
+	options := auth.CookieOptions{
+		Secure:   cfg.SessionCookieSecure == "true",
+		SameSite: sameSite,
+		Domain:   cfg.SessionCookieDomain,
+	}
+	if err := options.Validate(); err != nil {
+		return auth.CookieOptions{}, 0, fmt.Errorf("invalid session cookie settings: %w", err)
+	}
+	maxAge, err := time.ParseDuration(cfg.SessionMaxAge)
+	if err != nil || maxAge <= 0 {
+		return auth.CookieOptions{}, 0, fmt.Errorf("invalid SESSION_MAX_AGE '%s'", cfg.SessionMaxAge)
+	}
+	if sameSite == http.SameSiteStrictMode {
+		log.Printf("[STARTUP WARN] SESSION_COOKIE_SAMESITE=strict: browsers drop the session cookie on the OAuth callback, so OAuth logins fail")
+	}
+	return options, maxAge, nil
+}
+
+// tokenTTL is the lifetime of the token of a password login
+func tokenTTL(remember bool, rememberTTL time.Duration) time.Duration {
+	if remember {
+		return rememberTTL
+	}
+	return utils.TokenTTL
+}